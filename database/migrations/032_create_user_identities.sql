@@ -0,0 +1,36 @@
+-- +goose Up
+-- External identities (Google, Microsoft, generic OIDC) linked to global users
+CREATE TABLE IF NOT EXISTS user_identities (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL,
+    provider VARCHAR(50) NOT NULL,  -- google, microsoft, or the configured OIDC provider name
+    subject VARCHAR(255) NOT NULL,  -- Stable provider user identifier ("sub" claim)
+    email VARCHAR(255),
+    email_verified BOOLEAN DEFAULT false,
+    last_login_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT fk_identity_user FOREIGN KEY (user_id)
+        REFERENCES global_users(id) ON DELETE CASCADE,
+    CONSTRAINT unique_provider_subject UNIQUE(provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities(user_id);
+
+-- Domain-based auto-join: verified users with a matching email domain join automatically
+ALTER TABLE organizations
+ADD COLUMN IF NOT EXISTS auto_join_domain VARCHAR(255),
+ADD COLUMN IF NOT EXISTS auto_join_role VARCHAR(100) DEFAULT 'inspector';
+
+CREATE INDEX IF NOT EXISTS idx_organizations_auto_join_domain ON organizations(auto_join_domain);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_organizations_auto_join_domain;
+
+ALTER TABLE organizations
+DROP COLUMN IF EXISTS auto_join_role,
+DROP COLUMN IF EXISTS auto_join_domain;
+
+DROP INDEX IF EXISTS idx_user_identities_user_id;
+DROP TABLE IF EXISTS user_identities;
@@ -0,0 +1,127 @@
+-- +goose Up
+-- Row-level security for tenant tables.
+--
+-- Tenant-scoped requests run in a transaction that does SET LOCAL ROLE app_tenant and
+-- set_config('app.current_organization_id', <org>, true). Both are transaction-local,
+-- so nothing leaks to the next request that picks up the pooled connection.
+-- app_tenant cannot bypass RLS; the owning role keeps full access for system jobs.
+
+-- +goose StatementBegin
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'app_tenant') THEN
+        CREATE ROLE app_tenant NOLOGIN NOSUPERUSER NOBYPASSRLS;
+    END IF;
+    -- Allow the application role to SET ROLE app_tenant
+    EXECUTE format('GRANT app_tenant TO %I', current_user);
+END $$;
+-- +goose StatementEnd
+
+GRANT USAGE ON SCHEMA public TO app_tenant;
+GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public TO app_tenant;
+
+-- Shared lookup tables are readable but not tenant-filtered
+GRANT SELECT ON organizations, global_users, organization_members TO app_tenant;
+
+-- Current tenant, NULL when unset so policies match nothing
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION app_current_organization_id() RETURNS UUID AS $$
+    SELECT NULLIF(current_setting('app.current_organization_id', true), '')::UUID
+$$ LANGUAGE sql STABLE;
+-- +goose StatementEnd
+
+-- Enable RLS on a table with an organization_id column. Later migrations call this
+-- for every new tenant table. Missing tables are skipped.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION enable_tenant_rls(target_table TEXT) RETURNS VOID AS $$
+BEGIN
+    IF to_regclass(target_table) IS NULL THEN
+        RETURN;
+    END IF;
+
+    EXECUTE format('ALTER TABLE %I ENABLE ROW LEVEL SECURITY', target_table);
+    EXECUTE format('DROP POLICY IF EXISTS tenant_isolation ON %I', target_table);
+    EXECUTE format(
+        'CREATE POLICY tenant_isolation ON %I TO app_tenant
+            USING (organization_id = app_current_organization_id())
+            WITH CHECK (organization_id = app_current_organization_id())',
+        target_table);
+    EXECUTE format('GRANT SELECT, INSERT, UPDATE, DELETE ON %I TO app_tenant', target_table);
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- Enable RLS on a child table without organization_id, scoped through its parent
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION enable_child_tenant_rls(target_table TEXT, parent_table TEXT, parent_key TEXT) RETURNS VOID AS $$
+BEGIN
+    IF to_regclass(target_table) IS NULL OR to_regclass(parent_table) IS NULL THEN
+        RETURN;
+    END IF;
+
+    EXECUTE format('ALTER TABLE %I ENABLE ROW LEVEL SECURITY', target_table);
+    EXECUTE format('DROP POLICY IF EXISTS tenant_isolation ON %I', target_table);
+    EXECUTE format(
+        'CREATE POLICY tenant_isolation ON %1$I TO app_tenant
+            USING (EXISTS (SELECT 1 FROM %2$I p WHERE p.id = %1$I.%3$I AND p.organization_id = app_current_organization_id()))
+            WITH CHECK (EXISTS (SELECT 1 FROM %2$I p WHERE p.id = %1$I.%3$I AND p.organization_id = app_current_organization_id()))',
+        target_table, parent_table, parent_key);
+    EXECUTE format('GRANT SELECT, INSERT, UPDATE, DELETE ON %I TO app_tenant', target_table);
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- Core tenant tables
+SELECT enable_tenant_rls('templates');
+SELECT enable_tenant_rls('inspections');
+SELECT enable_tenant_rls('sites');
+SELECT enable_tenant_rls('attachments');
+SELECT enable_tenant_rls('notifications');
+
+-- Workflow tables
+SELECT enable_tenant_rls('inspection_projects');
+SELECT enable_tenant_rls('inspection_assignments');
+SELECT enable_tenant_rls('inspection_reviews');
+SELECT enable_tenant_rls('inspector_workloads');
+SELECT enable_tenant_rls('workflow_alerts');
+
+-- Child tables scoped through their parent
+SELECT enable_child_tenant_rls('inspection_data', 'inspections', 'inspection_id');
+SELECT enable_child_tenant_rls('workflow_steps', 'inspection_projects', 'project_id');
+SELECT enable_child_tenant_rls('step_executions', 'inspection_assignments', 'assignment_id');
+
+-- +goose Down
+-- +goose StatementBegin
+DO $$
+DECLARE
+    t TEXT;
+BEGIN
+    FOREACH t IN ARRAY ARRAY[
+        'templates', 'inspections', 'sites', 'attachments', 'notifications',
+        'inspection_projects', 'inspection_assignments', 'inspection_reviews',
+        'inspector_workloads', 'workflow_alerts',
+        'inspection_data', 'workflow_steps', 'step_executions'
+    ] LOOP
+        IF to_regclass(t) IS NOT NULL THEN
+            EXECUTE format('DROP POLICY IF EXISTS tenant_isolation ON %I', t);
+            EXECUTE format('ALTER TABLE %I DISABLE ROW LEVEL SECURITY', t);
+        END IF;
+    END LOOP;
+END $$;
+-- +goose StatementEnd
+
+DROP FUNCTION IF EXISTS enable_child_tenant_rls(TEXT, TEXT, TEXT);
+DROP FUNCTION IF EXISTS enable_tenant_rls(TEXT);
+DROP FUNCTION IF EXISTS app_current_organization_id();
+
+-- +goose StatementBegin
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'app_tenant') THEN
+        REVOKE ALL ON ALL TABLES IN SCHEMA public FROM app_tenant;
+        REVOKE ALL ON ALL SEQUENCES IN SCHEMA public FROM app_tenant;
+        REVOKE USAGE ON SCHEMA public FROM app_tenant;
+        DROP ROLE app_tenant;
+    END IF;
+END $$;
+-- +goose StatementEnd
@@ -0,0 +1,63 @@
+-- +goose Up
+-- Tamper-evident audit trail.
+--
+-- Entries of each organization form a hash chain: hash covers the entry content and
+-- previous_hash, the hash of the entry with the preceding sequence number. Events
+-- outside any organization (e.g. failed logins) use an empty organization_id.
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id VARCHAR(36) NOT NULL,
+    sequence BIGINT NOT NULL,
+    user_id VARCHAR(36) NOT NULL,
+    target_user_id VARCHAR(36),
+    action VARCHAR(100) NOT NULL,
+    resource_type VARCHAR(50) NOT NULL,
+    resource_id VARCHAR(36),
+    details JSONB,
+    changes JSONB,  -- field -> {before, after}
+    ip_address VARCHAR(45),
+    user_agent VARCHAR(500),
+    success BOOLEAN NOT NULL DEFAULT true,
+    error_message TEXT,
+    previous_hash VARCHAR(64),
+    hash VARCHAR(64) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    -- Concurrent writers racing for the same position fail here and retry
+    CONSTRAINT idx_audit_logs_org_sequence UNIQUE (organization_id, sequence)
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_organization_id ON audit_logs(organization_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_user_id ON audit_logs(user_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_target_user_id ON audit_logs(target_user_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_action ON audit_logs(action);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_resource ON audit_logs(resource_type, resource_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs(created_at);
+
+-- Entries are append-only
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION prevent_audit_log_modification() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_logs is append-only';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+DROP TRIGGER IF EXISTS audit_logs_append_only ON audit_logs;
+CREATE TRIGGER audit_logs_append_only
+    BEFORE UPDATE OR DELETE ON audit_logs
+    FOR EACH ROW EXECUTE FUNCTION prevent_audit_log_modification();
+
+-- organization_id is text here, so the generic enable_tenant_rls policy doesn't apply
+ALTER TABLE audit_logs ENABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON audit_logs;
+CREATE POLICY tenant_isolation ON audit_logs TO app_tenant
+    USING (organization_id = app_current_organization_id()::TEXT)
+    WITH CHECK (organization_id = app_current_organization_id()::TEXT);
+GRANT SELECT, INSERT ON audit_logs TO app_tenant;
+
+-- +goose Down
+DROP POLICY IF EXISTS tenant_isolation ON audit_logs;
+DROP TRIGGER IF EXISTS audit_logs_append_only ON audit_logs;
+DROP FUNCTION IF EXISTS prevent_audit_log_modification();
+DROP TABLE IF EXISTS audit_logs;
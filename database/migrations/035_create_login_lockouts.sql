@@ -0,0 +1,20 @@
+-- +goose Up
+-- Failed login tracking for brute-force protection (LOGIN_LOCKOUT_STORE=db).
+-- Keys are "account:<email>" or "ip:<address>"; the table is global, not tenant-scoped.
+CREATE TABLE IF NOT EXISTS login_lockouts (
+    key VARCHAR(320) PRIMARY KEY,
+    failures INTEGER NOT NULL DEFAULT 0,
+    lockouts INTEGER NOT NULL DEFAULT 0,  -- consecutive lockouts, drives exponential duration
+    accounts JSONB,                       -- ip keys: distinct accounts that failed in the window
+    window_start TIMESTAMPTZ,
+    last_failure_at TIMESTAMPTZ,
+    locked_until TIMESTAMPTZ,
+    alerted_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_lockouts_locked_until ON login_lockouts(locked_until);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_login_lockouts_locked_until;
+DROP TABLE IF EXISTS login_lockouts;
@@ -0,0 +1,58 @@
+-- +goose Up
+-- Location hierarchy within sites: regions > campuses > buildings > floors > rooms.
+-- path holds the node IDs from the root down to the node, each followed by '/',
+-- so a subtree is selected with path LIKE '<root path>%'.
+CREATE TABLE IF NOT EXISTS location_nodes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    site_id UUID NOT NULL REFERENCES sites(id),
+    parent_id UUID REFERENCES location_nodes(id),
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(50) NOT NULL, -- region, campus, building, floor, room, area
+    code VARCHAR(100),
+    path VARCHAR(2000) NOT NULL,
+    depth INTEGER NOT NULL DEFAULT 0,
+    sort_order INTEGER DEFAULT 0,
+    metadata JSONB DEFAULT '{}',
+    created_by UUID,
+    updated_by UUID,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    deleted_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_location_nodes_organization_id ON location_nodes(organization_id);
+CREATE INDEX IF NOT EXISTS idx_location_nodes_site_id ON location_nodes(site_id);
+CREATE INDEX IF NOT EXISTS idx_location_nodes_parent_id ON location_nodes(parent_id);
+CREATE INDEX IF NOT EXISTS idx_location_nodes_path ON location_nodes(path varchar_pattern_ops);
+CREATE INDEX IF NOT EXISTS idx_location_nodes_deleted_at ON location_nodes(deleted_at);
+
+-- Subtree moves, kept so the previous position of a location can be traced
+CREATE TABLE IF NOT EXISTS location_node_moves (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    node_id UUID NOT NULL REFERENCES location_nodes(id),
+    site_id UUID NOT NULL REFERENCES sites(id),
+    from_parent_id UUID,
+    to_parent_id UUID,
+    from_path VARCHAR(2000),
+    to_path VARCHAR(2000),
+    moved_by UUID,
+    moved_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_location_node_moves_organization_id ON location_node_moves(organization_id);
+CREATE INDEX IF NOT EXISTS idx_location_node_moves_node_id ON location_node_moves(node_id);
+
+-- Inspections can optionally be recorded against a location within their site
+ALTER TABLE inspections ADD COLUMN IF NOT EXISTS location_node_id UUID REFERENCES location_nodes(id);
+CREATE INDEX IF NOT EXISTS idx_inspections_location_node_id ON inspections(location_node_id);
+
+SELECT enable_tenant_rls('location_nodes');
+SELECT enable_tenant_rls('location_node_moves');
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_inspections_location_node_id;
+ALTER TABLE inspections DROP COLUMN IF EXISTS location_node_id;
+DROP TABLE IF EXISTS location_node_moves;
+DROP TABLE IF EXISTS location_nodes;
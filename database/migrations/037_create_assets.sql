@@ -0,0 +1,47 @@
+-- +goose Up
+-- Asset registry: equipment inspected at sites (extinguishers, ladders, harnesses, HVAC units).
+-- Assets are retired rather than deleted once they have inspection history.
+CREATE TABLE IF NOT EXISTS assets (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    site_id UUID NOT NULL REFERENCES sites(id),
+    location_node_id UUID REFERENCES location_nodes(id),
+    name VARCHAR(255) NOT NULL,
+    asset_tag VARCHAR(100),
+    type VARCHAR(100) NOT NULL, -- fire_extinguisher, ladder, harness, hvac, etc.
+    serial_number VARCHAR(255),
+    manufacturer VARCHAR(255),
+    model_number VARCHAR(255),
+    install_date TIMESTAMPTZ,
+    status VARCHAR(50) DEFAULT 'active', -- active, out_of_service, retired
+    inspection_interval_days INTEGER DEFAULT 0,
+    custom_fields JSONB DEFAULT '{}',
+    notes TEXT,
+    retired_at TIMESTAMPTZ,
+    retired_by UUID,
+    retired_reason TEXT,
+    created_by UUID,
+    updated_by UUID,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    deleted_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_assets_organization_id ON assets(organization_id);
+CREATE INDEX IF NOT EXISTS idx_assets_site_id ON assets(site_id);
+CREATE INDEX IF NOT EXISTS idx_assets_location_node_id ON assets(location_node_id);
+CREATE INDEX IF NOT EXISTS idx_assets_type ON assets(type);
+CREATE INDEX IF NOT EXISTS idx_assets_status ON assets(status);
+CREATE INDEX IF NOT EXISTS idx_assets_asset_tag ON assets(asset_tag);
+CREATE INDEX IF NOT EXISTS idx_assets_deleted_at ON assets(deleted_at);
+
+-- Inspections can be recorded against a single asset
+ALTER TABLE inspections ADD COLUMN IF NOT EXISTS asset_id UUID REFERENCES assets(id);
+CREATE INDEX IF NOT EXISTS idx_inspections_asset_id ON inspections(asset_id);
+
+SELECT enable_tenant_rls('assets');
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_inspections_asset_id;
+ALTER TABLE inspections DROP COLUMN IF EXISTS asset_id;
+DROP TABLE IF EXISTS assets;
@@ -0,0 +1,30 @@
+-- +goose Up
+-- Printed QR tags. The code on a tag carries only the organization and tag IDs plus an
+-- HMAC signature; the site or asset it points at is stored here so blank tags can be
+-- printed in advance and bound once they are on the wall.
+CREATE TABLE IF NOT EXISTS scan_tags (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    entity_type VARCHAR(20), -- site, asset, or NULL while unbound
+    entity_id UUID,
+    label VARCHAR(255),
+    status VARCHAR(20) NOT NULL DEFAULT 'unbound', -- unbound, bound, revoked
+    bound_by UUID,
+    bound_at TIMESTAMPTZ,
+    last_scanned_at TIMESTAMPTZ,
+    scan_count INTEGER DEFAULT 0,
+    created_by UUID,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    deleted_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_scan_tags_organization_id ON scan_tags(organization_id);
+CREATE INDEX IF NOT EXISTS idx_scan_tags_entity ON scan_tags(entity_type, entity_id);
+CREATE INDEX IF NOT EXISTS idx_scan_tags_status ON scan_tags(status);
+CREATE INDEX IF NOT EXISTS idx_scan_tags_deleted_at ON scan_tags(deleted_at);
+
+SELECT enable_tenant_rls('scan_tags');
+
+-- +goose Down
+DROP TABLE IF EXISTS scan_tags;
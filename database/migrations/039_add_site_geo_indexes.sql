@@ -0,0 +1,14 @@
+-- +goose Up
+-- Proximity and map viewport queries narrow sites with a latitude/longitude box
+-- before computing exact distances, so index the coordinates of located sites.
+CREATE INDEX IF NOT EXISTS idx_sites_org_coordinates
+    ON sites(organization_id, latitude, longitude)
+    WHERE latitude IS NOT NULL AND longitude IS NOT NULL AND deleted_at IS NULL;
+
+-- "Due near me" looks up an inspector's open inspections at nearby sites
+CREATE INDEX IF NOT EXISTS idx_inspections_inspector_site
+    ON inspections(organization_id, inspector_id, site_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_inspections_inspector_site;
+DROP INDEX IF EXISTS idx_sites_org_coordinates;
@@ -0,0 +1,47 @@
+-- +goose Up
+-- GPS check-in/check-out for inspections. Each site can override the organization's
+-- geofence radius; the policy (off, warn, block) lives in organizations.settings.
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS geofence_radius_meters INTEGER;
+
+ALTER TABLE inspections ADD COLUMN IF NOT EXISTS checked_in_at TIMESTAMPTZ;
+ALTER TABLE inspections ADD COLUMN IF NOT EXISTS checked_out_at TIMESTAMPTZ;
+ALTER TABLE inspections ADD COLUMN IF NOT EXISTS time_on_site_seconds INTEGER;
+ALTER TABLE inspections ADD COLUMN IF NOT EXISTS off_site BOOLEAN DEFAULT FALSE;
+CREATE INDEX IF NOT EXISTS idx_inspections_off_site ON inspections(organization_id, off_site) WHERE off_site;
+
+CREATE TABLE IF NOT EXISTS inspection_check_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    inspection_id UUID NOT NULL REFERENCES inspections(id),
+    site_id UUID NOT NULL REFERENCES sites(id),
+    user_id UUID NOT NULL,
+    event_type VARCHAR(20) NOT NULL, -- check_in, check_out
+    latitude DOUBLE PRECISION,
+    longitude DOUBLE PRECISION,
+    accuracy_meters DOUBLE PRECISION,
+    distance_meters DOUBLE PRECISION,
+    geofence_radius_meters INTEGER,
+    within_geofence BOOLEAN,
+    flagged BOOLEAN DEFAULT FALSE,
+    flag_reasons VARCHAR(255),
+    time_on_site_seconds INTEGER,
+    recorded_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_inspection_check_events_organization_id ON inspection_check_events(organization_id);
+CREATE INDEX IF NOT EXISTS idx_inspection_check_events_inspection_id ON inspection_check_events(inspection_id);
+CREATE INDEX IF NOT EXISTS idx_inspection_check_events_site_id ON inspection_check_events(site_id);
+CREATE INDEX IF NOT EXISTS idx_inspection_check_events_flagged ON inspection_check_events(flagged);
+CREATE INDEX IF NOT EXISTS idx_inspection_check_events_recorded_at ON inspection_check_events(recorded_at);
+
+SELECT enable_tenant_rls('inspection_check_events');
+
+-- +goose Down
+DROP TABLE IF EXISTS inspection_check_events;
+DROP INDEX IF EXISTS idx_inspections_off_site;
+ALTER TABLE inspections DROP COLUMN IF EXISTS off_site;
+ALTER TABLE inspections DROP COLUMN IF EXISTS time_on_site_seconds;
+ALTER TABLE inspections DROP COLUMN IF EXISTS checked_out_at;
+ALTER TABLE inspections DROP COLUMN IF EXISTS checked_in_at;
+ALTER TABLE sites DROP COLUMN IF EXISTS geofence_radius_meters;
@@ -0,0 +1,33 @@
+-- +goose Up
+-- Bulk site import. external_ref is the caller's own key for a site, so re-importing
+-- the same file updates the sites it created instead of duplicating them.
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS external_ref VARCHAR(100);
+CREATE INDEX IF NOT EXISTS idx_sites_external_ref ON sites(organization_id, external_ref);
+
+CREATE TABLE IF NOT EXISTS site_import_jobs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, running, completed, failed
+    format VARCHAR(10),
+    file_name VARCHAR(255),
+    total_rows INTEGER DEFAULT 0,
+    processed_rows INTEGER DEFAULT 0,
+    created_count INTEGER DEFAULT 0,
+    updated_count INTEGER DEFAULT 0,
+    errors JSONB,
+    error_message TEXT,
+    created_by UUID,
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_site_import_jobs_organization_id ON site_import_jobs(organization_id);
+
+SELECT enable_tenant_rls('site_import_jobs');
+
+-- +goose Down
+DROP TABLE IF EXISTS site_import_jobs;
+DROP INDEX IF EXISTS idx_sites_external_ref;
+ALTER TABLE sites DROP COLUMN IF EXISTS external_ref;
@@ -0,0 +1,51 @@
+-- +goose Up
+-- Site geocoding. coordinates_source records whether a site's coordinates were entered
+-- by hand (manual, never replaced) or looked up from its address (geocoded).
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS coordinates_source VARCHAR(20);
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS geocoded_at TIMESTAMPTZ;
+
+-- Coordinates already on file were entered by people
+UPDATE sites SET coordinates_source = 'manual'
+WHERE latitude IS NOT NULL AND longitude IS NOT NULL AND coordinates_source IS NULL;
+
+-- Shared by all organizations: addresses are not tenant data, so no RLS
+CREATE TABLE IF NOT EXISTS geocode_cache (
+    id BIGSERIAL PRIMARY KEY,
+    provider VARCHAR(50) NOT NULL,
+    address_key VARCHAR(500) NOT NULL,
+    found BOOLEAN NOT NULL DEFAULT FALSE,
+    latitude DOUBLE PRECISION,
+    longitude DOUBLE PRECISION,
+    display_name VARCHAR(500),
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_geocode_cache_key ON geocode_cache(provider, address_key);
+
+CREATE TABLE IF NOT EXISTS site_geocode_jobs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, running, completed
+    force BOOLEAN DEFAULT FALSE,
+    total_sites INTEGER DEFAULT 0,
+    processed_sites INTEGER DEFAULT 0,
+    geocoded_count INTEGER DEFAULT 0,
+    not_found_count INTEGER DEFAULT 0,
+    failed_count INTEGER DEFAULT 0,
+    created_by UUID,
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_site_geocode_jobs_organization_id ON site_geocode_jobs(organization_id);
+
+SELECT enable_tenant_rls('site_geocode_jobs');
+
+-- +goose Down
+DROP TABLE IF EXISTS site_geocode_jobs;
+DROP TABLE IF EXISTS geocode_cache;
+ALTER TABLE sites DROP COLUMN IF EXISTS geocoded_at;
+ALTER TABLE sites DROP COLUMN IF EXISTS coordinates_source;
@@ -0,0 +1,45 @@
+-- +goose Up
+-- Organization-defined schemas for sites.metadata, and the sites flagged as not
+-- conforming after a schema change
+CREATE TABLE IF NOT EXISTS site_custom_fields (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    key VARCHAR(64) NOT NULL,
+    label VARCHAR(255) NOT NULL,
+    type VARCHAR(20) NOT NULL, -- text, number, boolean, date, select, multi_select, email, url
+    description TEXT,
+    required BOOLEAN DEFAULT FALSE,
+    "unique" BOOLEAN DEFAULT FALSE,
+    options JSONB,
+    position INTEGER DEFAULT 0,
+    created_by UUID,
+    updated_by UUID,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (organization_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_site_custom_fields_organization_id ON site_custom_fields(organization_id);
+
+CREATE TABLE IF NOT EXISTS site_field_violations (
+    id BIGSERIAL PRIMARY KEY,
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    site_id UUID NOT NULL REFERENCES sites(id) ON DELETE CASCADE,
+    field_key VARCHAR(64) NOT NULL,
+    message VARCHAR(500),
+    detected_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_site_field_violations_organization_id ON site_field_violations(organization_id, field_key);
+CREATE INDEX IF NOT EXISTS idx_site_field_violations_site_id ON site_field_violations(site_id);
+
+-- Custom field filters and uniqueness checks look inside metadata
+CREATE INDEX IF NOT EXISTS idx_sites_metadata ON sites USING GIN (metadata jsonb_path_ops);
+
+SELECT enable_tenant_rls('site_custom_fields');
+SELECT enable_tenant_rls('site_field_violations');
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_sites_metadata;
+DROP TABLE IF EXISTS site_field_violations;
+DROP TABLE IF EXISTS site_custom_fields;
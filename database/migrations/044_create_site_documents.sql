@@ -0,0 +1,51 @@
+-- +goose Up
+-- Site-level documents (permits, insurance certificates, floor plans) with version
+-- history and expiry tracking
+CREATE TABLE IF NOT EXISTS site_documents (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    site_id UUID NOT NULL REFERENCES sites(id) ON DELETE CASCADE,
+    category VARCHAR(50) NOT NULL, -- permit, insurance, certificate, floor_plan, other
+    title VARCHAR(255) NOT NULL,
+    description TEXT,
+    issued_on DATE,
+    expires_on DATE,
+    reminder_days INTEGER NOT NULL DEFAULT 30,
+    allowed_roles JSONB, -- roles that may view; NULL for every member
+    current_version INTEGER NOT NULL DEFAULT 1,
+    reminder_sent_for DATE, -- expiry date managers were last reminded about
+    created_by UUID,
+    updated_by UUID,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_site_documents_organization_id ON site_documents(organization_id);
+CREATE INDEX IF NOT EXISTS idx_site_documents_site_id ON site_documents(site_id);
+CREATE INDEX IF NOT EXISTS idx_site_documents_expires_on ON site_documents(expires_on) WHERE expires_on IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS site_document_versions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    document_id UUID NOT NULL REFERENCES site_documents(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    file_name VARCHAR(255) NOT NULL,
+    storage_path VARCHAR(500) NOT NULL,
+    file_size BIGINT,
+    mime_type VARCHAR(100),
+    issued_on DATE,
+    expires_on DATE,
+    notes TEXT,
+    uploaded_by UUID,
+    uploaded_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (document_id, version)
+);
+
+CREATE INDEX IF NOT EXISTS idx_site_document_versions_organization_id ON site_document_versions(organization_id);
+
+SELECT enable_tenant_rls('site_documents');
+SELECT enable_tenant_rls('site_document_versions');
+
+-- +goose Down
+DROP TABLE IF EXISTS site_document_versions;
+DROP TABLE IF EXISTS site_documents;
@@ -0,0 +1,26 @@
+-- +goose Up
+-- Duplicate sites merged into a survivor, with what was moved so the merge can be undone
+CREATE TABLE IF NOT EXISTS site_merges (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    survivor_id UUID NOT NULL REFERENCES sites(id),
+    merged_id UUID NOT NULL REFERENCES sites(id),
+    status VARCHAR(20) NOT NULL, -- merged, undone
+    counts JSONB,
+    moved_rows JSONB,
+    previous_site_ids JSONB,
+    merged_by UUID,
+    merged_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    undo_deadline TIMESTAMPTZ NOT NULL,
+    undone_by UUID,
+    undone_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_site_merges_organization_id ON site_merges(organization_id, merged_at DESC);
+CREATE INDEX IF NOT EXISTS idx_site_merges_survivor_id ON site_merges(survivor_id);
+CREATE INDEX IF NOT EXISTS idx_site_merges_merged_id ON site_merges(merged_id);
+
+SELECT enable_tenant_rls('site_merges');
+
+-- +goose Down
+DROP TABLE IF EXISTS site_merges;
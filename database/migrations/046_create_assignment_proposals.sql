@@ -0,0 +1,38 @@
+-- +goose Up
+-- Where an inspector's travel distance is measured from
+ALTER TABLE inspector_workloads
+ADD COLUMN IF NOT EXISTS base_latitude DOUBLE PRECISION,
+ADD COLUMN IF NOT EXISTS base_longitude DOUBLE PRECISION;
+
+-- Solver proposals splitting a batch of sites across inspectors, pending supervisor review
+CREATE TABLE IF NOT EXISTS assignment_proposals (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    project_id UUID REFERENCES inspection_projects(id) ON DELETE SET NULL,
+    template_id UUID NOT NULL,
+    status VARCHAR(20) NOT NULL, -- proposed, accepted, discarded
+    scheduled_for TIMESTAMPTZ NOT NULL,
+    due_date TIMESTAMPTZ,
+    request JSONB,
+    items JSONB,
+    assigned INTEGER NOT NULL DEFAULT 0,
+    unassigned INTEGER NOT NULL DEFAULT 0,
+    batch_id VARCHAR(255),
+    created_by UUID,
+    accepted_by UUID,
+    accepted_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_assignment_proposals_organization_id ON assignment_proposals(organization_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_assignment_proposals_project_id ON assignment_proposals(project_id);
+
+SELECT enable_tenant_rls('assignment_proposals');
+
+-- +goose Down
+DROP TABLE IF EXISTS assignment_proposals;
+
+ALTER TABLE inspector_workloads
+DROP COLUMN IF EXISTS base_latitude,
+DROP COLUMN IF EXISTS base_longitude;
@@ -0,0 +1,58 @@
+-- +goose Up
+-- Weekly working pattern per inspector; weekdays without a row are days off
+CREATE TABLE IF NOT EXISTS inspector_working_hours (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    inspector_id UUID NOT NULL,
+    weekday SMALLINT NOT NULL CHECK (weekday BETWEEN 0 AND 6), -- 0 is Sunday
+    start_time VARCHAR(5) NOT NULL,
+    end_time VARCHAR(5) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_inspector_working_hours_day ON inspector_working_hours(organization_id, inspector_id, weekday);
+
+-- Time-off requests; only approved ones make the inspector unavailable
+CREATE TABLE IF NOT EXISTS inspector_time_off (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    inspector_id UUID NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    type VARCHAR(20) NOT NULL, -- vacation, sick, training, other
+    status VARCHAR(20) NOT NULL, -- pending, approved, rejected, cancelled
+    reason TEXT,
+    requested_by UUID,
+    reviewed_by UUID,
+    reviewed_at TIMESTAMPTZ,
+    review_note TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (end_date >= start_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_inspector_time_off_inspector ON inspector_time_off(organization_id, inspector_id, start_date);
+CREATE INDEX IF NOT EXISTS idx_inspector_time_off_status ON inspector_time_off(organization_id, status);
+
+-- Organization holiday calendar; recurring holidays repeat on the same day every year
+CREATE TABLE IF NOT EXISTS organization_holidays (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    date DATE NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    recurring BOOLEAN NOT NULL DEFAULT FALSE,
+    created_by UUID,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_organization_holidays_organization_id ON organization_holidays(organization_id, date);
+
+SELECT enable_tenant_rls('inspector_working_hours');
+SELECT enable_tenant_rls('inspector_time_off');
+SELECT enable_tenant_rls('organization_holidays');
+
+-- +goose Down
+DROP TABLE IF EXISTS organization_holidays;
+DROP TABLE IF EXISTS inspector_time_off;
+DROP TABLE IF EXISTS inspector_working_hours;
@@ -0,0 +1,45 @@
+-- +goose Up
+-- Running totals behind the workload performance metrics, maintained as inspections and
+-- reviews change
+ALTER TABLE inspector_workloads
+ADD COLUMN IF NOT EXISTS due_completions INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS on_time_completions INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS timed_inspections INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS inspection_minutes DOUBLE PRECISION NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS scored_reviews INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS quality_score_total DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+-- Daily workload snapshots per inspector, written by the nightly reconciliation
+CREATE TABLE IF NOT EXISTS inspector_workload_history (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    inspector_id UUID NOT NULL,
+    date DATE NOT NULL,
+    daily_load INTEGER NOT NULL DEFAULT 0,
+    weekly_load INTEGER NOT NULL DEFAULT 0,
+    max_daily_inspections INTEGER NOT NULL DEFAULT 0,
+    max_weekly_inspections INTEGER NOT NULL DEFAULT 0,
+    utilization DOUBLE PRECISION NOT NULL DEFAULT 0, -- weekly load as a percentage of capacity
+    active_projects INTEGER NOT NULL DEFAULT 0,
+    pending_assignments INTEGER NOT NULL DEFAULT 0,
+    overdue_inspections INTEGER NOT NULL DEFAULT 0,
+    completion_rate DOUBLE PRECISION NOT NULL DEFAULT 0,
+    average_inspection_time INTEGER NOT NULL DEFAULT 0, -- minutes
+    quality_score DOUBLE PRECISION NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_inspector_workload_history_day ON inspector_workload_history(organization_id, inspector_id, date);
+
+SELECT enable_tenant_rls('inspector_workload_history');
+
+-- +goose Down
+DROP TABLE IF EXISTS inspector_workload_history;
+
+ALTER TABLE inspector_workloads
+DROP COLUMN IF EXISTS due_completions,
+DROP COLUMN IF EXISTS on_time_completions,
+DROP COLUMN IF EXISTS timed_inspections,
+DROP COLUMN IF EXISTS inspection_minutes,
+DROP COLUMN IF EXISTS scored_reviews,
+DROP COLUMN IF EXISTS quality_score_total;
@@ -0,0 +1,39 @@
+-- +goose Up
+-- Work published for inspectors to claim: an unassigned inspection or a batch of sites
+CREATE TABLE IF NOT EXISTS open_pool_items (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    project_id UUID REFERENCES inspection_projects(id) ON DELETE SET NULL,
+    template_id UUID NOT NULL,
+    inspection_id UUID REFERENCES inspections(id) ON DELETE CASCADE,
+    site_ids JSONB NOT NULL DEFAULT '[]',
+    title VARCHAR(255) NOT NULL,
+    description TEXT,
+    priority VARCHAR(50),
+    scheduled_for TIMESTAMPTZ,
+    due_date TIMESTAMPTZ,
+    estimated_hours INTEGER NOT NULL DEFAULT 0,
+    requires_confirmation BOOLEAN NOT NULL DEFAULT FALSE,
+    status VARCHAR(20) NOT NULL, -- open, claimed, assigned, withdrawn
+    published_by UUID NOT NULL,
+    claimed_by UUID,
+    claimed_at TIMESTAMPTZ,
+    confirmed_by UUID,
+    confirmed_at TIMESTAMPTZ,
+    assignment_id UUID,
+    escalated_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_open_pool_items_organization_id ON open_pool_items(organization_id, status);
+CREATE INDEX IF NOT EXISTS idx_open_pool_items_project_id ON open_pool_items(project_id);
+CREATE INDEX IF NOT EXISTS idx_open_pool_items_due_date ON open_pool_items(due_date) WHERE status = 'open';
+
+-- An inspection can only be in the pool once at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_open_pool_items_inspection ON open_pool_items(inspection_id) WHERE status IN ('open', 'claimed');
+
+SELECT enable_tenant_rls('open_pool_items');
+
+-- +goose Down
+DROP TABLE IF EXISTS open_pool_items;
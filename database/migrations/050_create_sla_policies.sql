@@ -0,0 +1,82 @@
+-- +goose Up
+-- Response targets per priority and inspection type, with an escalation ladder
+CREATE TABLE IF NOT EXISTS sla_policies (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    name VARCHAR(255) NOT NULL,
+    priority VARCHAR(50) NOT NULL DEFAULT '',         -- empty matches any priority
+    inspection_type VARCHAR(100) NOT NULL DEFAULT '', -- template category; empty matches any
+    time_to_accept_minutes INTEGER NOT NULL DEFAULT 0,
+    time_to_start_minutes INTEGER NOT NULL DEFAULT 0,
+    time_to_complete_minutes INTEGER NOT NULL DEFAULT 0,
+    time_to_review_minutes INTEGER NOT NULL DEFAULT 0,
+    escalation JSONB NOT NULL DEFAULT '[]',
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by UUID,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sla_policies_scope ON sla_policies(organization_id, priority, inspection_type);
+
+-- One clock per stage of an assignment or inspection
+CREATE TABLE IF NOT EXISTS sla_clocks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    policy_id UUID NOT NULL REFERENCES sla_policies(id),
+    target_type VARCHAR(20) NOT NULL, -- assignment, inspection
+    target_id UUID NOT NULL,
+    stage VARCHAR(20) NOT NULL,       -- accept, start, complete, review
+    status VARCHAR(20) NOT NULL,      -- running, paused, met, cancelled
+    priority VARCHAR(50),
+    inspection_type VARCHAR(100),
+    started_at TIMESTAMPTZ NOT NULL,
+    deadline_at TIMESTAMPTZ NOT NULL,
+    paused_at TIMESTAMPTZ,
+    pause_reason TEXT,
+    paused_seconds BIGINT NOT NULL DEFAULT 0,
+    escalation_level INTEGER NOT NULL DEFAULT 0,
+    breached_at TIMESTAMPTZ,
+    stopped_at TIMESTAMPTZ,
+    -- Escalation recipients; empty when nobody holds the role
+    assignee_id VARCHAR(36) NOT NULL DEFAULT '',
+    supervisor_id VARCHAR(36) NOT NULL DEFAULT '',
+    project_manager_id VARCHAR(36) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sla_clocks_target ON sla_clocks(target_type, target_id, stage);
+CREATE INDEX IF NOT EXISTS idx_sla_clocks_organization_id ON sla_clocks(organization_id);
+CREATE INDEX IF NOT EXISTS idx_sla_clocks_running ON sla_clocks(deadline_at) WHERE status = 'running';
+
+-- Deadlines missed, kept for reporting after the clock stops
+CREATE TABLE IF NOT EXISTS sla_breaches (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    clock_id UUID NOT NULL REFERENCES sla_clocks(id),
+    policy_id UUID NOT NULL,
+    target_type VARCHAR(20) NOT NULL,
+    target_id UUID NOT NULL,
+    stage VARCHAR(20) NOT NULL,
+    priority VARCHAR(50),
+    inspection_type VARCHAR(100),
+    assignee_id VARCHAR(36) NOT NULL DEFAULT '',
+    deadline_at TIMESTAMPTZ NOT NULL,
+    breached_at TIMESTAMPTZ NOT NULL,
+    met_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_sla_breaches_organization_id ON sla_breaches(organization_id, breached_at);
+CREATE INDEX IF NOT EXISTS idx_sla_breaches_clock_id ON sla_breaches(clock_id);
+CREATE INDEX IF NOT EXISTS idx_sla_breaches_assignee_id ON sla_breaches(assignee_id);
+
+SELECT enable_tenant_rls('sla_policies');
+SELECT enable_tenant_rls('sla_clocks');
+SELECT enable_tenant_rls('sla_breaches');
+
+-- +goose Down
+DROP TABLE IF EXISTS sla_breaches;
+DROP TABLE IF EXISTS sla_clocks;
+DROP TABLE IF EXISTS sla_policies;
@@ -0,0 +1,66 @@
+-- +goose Up
+-- IANA time zones; a site without one uses its organization's
+ALTER TABLE organizations ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT '';
+
+-- Due and scheduled times are instants; the existing wall-clock values were written in UTC.
+-- site_statistics reads both columns, so it's rebuilt around the change.
+DROP VIEW IF EXISTS site_statistics;
+ALTER TABLE inspections ALTER COLUMN due_date TYPE TIMESTAMPTZ USING due_date AT TIME ZONE 'UTC';
+ALTER TABLE inspections ALTER COLUMN scheduled_for TYPE TIMESTAMPTZ USING scheduled_for AT TIME ZONE 'UTC';
+ALTER TABLE inspection_assignments ALTER COLUMN due_date TYPE TIMESTAMPTZ USING due_date AT TIME ZONE 'UTC';
+CREATE OR REPLACE VIEW site_statistics AS
+SELECT
+    s.id as site_id,
+    s.name as site_name,
+    s.organization_id,
+    COUNT(i.id) as total_inspections,
+    COUNT(CASE WHEN i.status IN ('completed', 'approved') THEN 1 END) as completed_inspections,
+    COUNT(CASE WHEN i.status IN ('draft', 'in_progress', 'requires_review') THEN 1 END) as pending_inspections,
+    COUNT(CASE WHEN i.due_date < NOW() AND i.status NOT IN ('completed', 'approved') THEN 1 END) as overdue_inspections,
+    MAX(i.completed_at) as last_inspection_date,
+    MIN(CASE WHEN i.scheduled_for > NOW() THEN i.scheduled_for END) as next_inspection_date
+FROM sites s
+LEFT JOIN inspections i ON s.id = i.site_id
+WHERE s.deleted_at IS NULL
+GROUP BY s.id, s.name, s.organization_id;
+
+-- Weekly opening hours per organization; weekdays without a row are closed, and an
+-- organization without any rows is open around the clock
+CREATE TABLE IF NOT EXISTS organization_business_hours (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    weekday SMALLINT NOT NULL CHECK (weekday BETWEEN 0 AND 6), -- 0 is Sunday
+    start_time VARCHAR(5) NOT NULL,
+    end_time VARCHAR(5) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_organization_business_hours_day ON organization_business_hours(organization_id, weekday);
+
+SELECT enable_tenant_rls('organization_business_hours');
+
+-- +goose Down
+DROP TABLE IF EXISTS organization_business_hours;
+DROP VIEW IF EXISTS site_statistics;
+ALTER TABLE inspection_assignments ALTER COLUMN due_date TYPE TIMESTAMP USING due_date AT TIME ZONE 'UTC';
+ALTER TABLE inspections ALTER COLUMN scheduled_for TYPE TIMESTAMP USING scheduled_for AT TIME ZONE 'UTC';
+ALTER TABLE inspections ALTER COLUMN due_date TYPE TIMESTAMP USING due_date AT TIME ZONE 'UTC';
+CREATE OR REPLACE VIEW site_statistics AS
+SELECT
+    s.id as site_id,
+    s.name as site_name,
+    s.organization_id,
+    COUNT(i.id) as total_inspections,
+    COUNT(CASE WHEN i.status IN ('completed', 'approved') THEN 1 END) as completed_inspections,
+    COUNT(CASE WHEN i.status IN ('draft', 'in_progress', 'requires_review') THEN 1 END) as pending_inspections,
+    COUNT(CASE WHEN i.due_date < NOW() AND i.status NOT IN ('completed', 'approved') THEN 1 END) as overdue_inspections,
+    MAX(i.completed_at) as last_inspection_date,
+    MIN(CASE WHEN i.scheduled_for > NOW() THEN i.scheduled_for END) as next_inspection_date
+FROM sites s
+LEFT JOIN inspections i ON s.id = i.site_id
+WHERE s.deleted_at IS NULL
+GROUP BY s.id, s.name, s.organization_id;
+ALTER TABLE sites DROP COLUMN IF EXISTS timezone;
+ALTER TABLE organizations DROP COLUMN IF EXISTS timezone;
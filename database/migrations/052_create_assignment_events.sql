@@ -0,0 +1,72 @@
+-- +goose Up
+-- Append-only history of every assignment: who created, accepted, rejected, moved,
+-- started and completed it, and why
+CREATE TABLE IF NOT EXISTS assignment_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    assignment_id UUID NOT NULL REFERENCES inspection_assignments(id) ON DELETE CASCADE,
+    inspection_id UUID,
+    event_type VARCHAR(50) NOT NULL,
+    actor_id VARCHAR(36) NOT NULL DEFAULT '', -- empty for system changes
+    from_user_id UUID,
+    to_user_id UUID,
+    reason_code VARCHAR(50) NOT NULL DEFAULT '',
+    reason TEXT,
+    metadata JSONB,
+    occurred_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_assignment_events_assignment_id ON assignment_events(assignment_id, occurred_at);
+CREATE INDEX IF NOT EXISTS idx_assignment_events_organization_id ON assignment_events(organization_id, event_type, occurred_at);
+
+-- Inspectors' requests to hand their work to a peer, approved by a supervisor
+CREATE TABLE IF NOT EXISTS assignment_delegations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    assignment_id UUID NOT NULL REFERENCES inspection_assignments(id) ON DELETE CASCADE,
+    from_inspector_id UUID NOT NULL,
+    to_inspector_id UUID NOT NULL,
+    reason TEXT,
+    status VARCHAR(20) NOT NULL, -- pending, approved, declined, cancelled
+    reviewed_by UUID,
+    reviewed_at TIMESTAMPTZ,
+    review_note TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_assignment_delegations_assignment_id ON assignment_delegations(assignment_id);
+CREATE INDEX IF NOT EXISTS idx_assignment_delegations_status ON assignment_delegations(organization_id, status);
+-- At most one open request per assignment
+CREATE UNIQUE INDEX IF NOT EXISTS idx_assignment_delegations_pending ON assignment_delegations(assignment_id) WHERE status = 'pending';
+
+-- Rebuild what the assignments remember: their creation, acceptance, and the last
+-- rejection or reassignment, which used to be kept in metadata
+INSERT INTO assignment_events (organization_id, assignment_id, event_type, actor_id, to_user_id, occurred_at)
+SELECT organization_id, id, 'created', assigned_by::text, COALESCE(delegated_from, assigned_to), assigned_at
+FROM inspection_assignments;
+
+INSERT INTO assignment_events (organization_id, assignment_id, event_type, actor_id, to_user_id, occurred_at)
+SELECT organization_id, id, 'accepted', assigned_to::text, assigned_to, accepted_at
+FROM inspection_assignments
+WHERE accepted_at IS NOT NULL;
+
+INSERT INTO assignment_events (organization_id, assignment_id, event_type, actor_id, from_user_id, to_user_id, reason, occurred_at)
+SELECT organization_id, id, 'reassigned', COALESCE(metadata->>'reassigned_by', ''), delegated_from, assigned_to,
+       metadata->>'reassignment_reason', COALESCE((metadata->>'reassigned_at')::timestamptz, updated_at)
+FROM inspection_assignments
+WHERE delegated_from IS NOT NULL;
+
+INSERT INTO assignment_events (organization_id, assignment_id, event_type, actor_id, from_user_id, reason_code, reason, occurred_at)
+SELECT organization_id, id, 'rejected', assigned_to::text, assigned_to, 'other',
+       metadata->>'rejection_reason', COALESCE((metadata->>'rejected_at')::timestamptz, updated_at)
+FROM inspection_assignments
+WHERE status = 'rejected';
+
+SELECT enable_tenant_rls('assignment_events');
+SELECT enable_tenant_rls('assignment_delegations');
+
+-- +goose Down
+DROP TABLE IF EXISTS assignment_delegations;
+DROP TABLE IF EXISTS assignment_events;
@@ -0,0 +1,93 @@
+-- +goose Up
+-- Certification types an organization tracks, and the certificates its inspectors hold
+CREATE TABLE IF NOT EXISTS certification_types (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    name VARCHAR(255) NOT NULL,
+    issuer VARCHAR(255),
+    description TEXT,
+    validity_months INTEGER NOT NULL DEFAULT 0, -- default validity; 0 for none
+    reminder_days INTEGER NOT NULL DEFAULT 30,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by UUID,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (organization_id, name)
+);
+
+-- Certificates are revoked rather than deleted, so past inspections stay provable
+CREATE TABLE IF NOT EXISTS inspector_certifications (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    user_id UUID NOT NULL,
+    certification_type_id UUID NOT NULL REFERENCES certification_types(id),
+    certificate_number VARCHAR(100),
+    issued_on DATE NOT NULL,
+    expires_on DATE, -- valid through this day; NULL for certificates that don't expire
+    revoked_on DATE, -- no longer valid from this day
+    revoked_by UUID,
+    revoke_reason TEXT,
+    notes TEXT,
+    reminder_sent_for DATE, -- expiry date the holder was last warned about
+    created_by UUID,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    CHECK (expires_on IS NULL OR expires_on >= issued_on)
+);
+
+CREATE INDEX IF NOT EXISTS idx_inspector_certifications_user_id ON inspector_certifications(organization_id, user_id, certification_type_id);
+CREATE INDEX IF NOT EXISTS idx_inspector_certifications_expires_on ON inspector_certifications(expires_on) WHERE expires_on IS NOT NULL AND revoked_on IS NULL;
+
+CREATE TABLE IF NOT EXISTS certification_evidence (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    certification_id UUID NOT NULL REFERENCES inspector_certifications(id),
+    file_name VARCHAR(255) NOT NULL,
+    storage_path VARCHAR(500) NOT NULL,
+    file_size BIGINT,
+    mime_type VARCHAR(100),
+    uploaded_by UUID,
+    uploaded_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_certification_evidence_certification_id ON certification_evidence(certification_id);
+
+-- Certifications needed to perform a template's inspections, kept on its first version
+CREATE TABLE IF NOT EXISTS template_qualifications (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    template_id UUID NOT NULL REFERENCES templates(id) ON DELETE CASCADE,
+    certification_type_id UUID NOT NULL REFERENCES certification_types(id),
+    created_by UUID,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (template_id, certification_type_id)
+);
+
+-- The certificates an inspector held on the day each inspection was completed
+CREATE TABLE IF NOT EXISTS inspection_qualifications (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    inspection_id UUID NOT NULL REFERENCES inspections(id) ON DELETE CASCADE,
+    inspector_id UUID NOT NULL,
+    certification_type_id UUID NOT NULL REFERENCES certification_types(id),
+    certification_id UUID REFERENCES inspector_certifications(id), -- NULL when none was valid
+    certificate_number VARCHAR(100),
+    issued_on DATE,
+    expires_on DATE,
+    inspected_on DATE NOT NULL,
+    recorded_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (inspection_id, certification_type_id)
+);
+
+SELECT enable_tenant_rls('certification_types');
+SELECT enable_tenant_rls('inspector_certifications');
+SELECT enable_tenant_rls('certification_evidence');
+SELECT enable_tenant_rls('template_qualifications');
+SELECT enable_tenant_rls('inspection_qualifications');
+
+-- +goose Down
+DROP TABLE IF EXISTS inspection_qualifications;
+DROP TABLE IF EXISTS template_qualifications;
+DROP TABLE IF EXISTS certification_evidence;
+DROP TABLE IF EXISTS inspector_certifications;
+DROP TABLE IF EXISTS certification_types;
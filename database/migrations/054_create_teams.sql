@@ -0,0 +1,46 @@
+-- +goose Up
+-- Teams of an organization's members, with a lead who sees and dispatches their work
+CREATE TABLE IF NOT EXISTS teams (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    lead_id UUID REFERENCES global_users(id) ON DELETE SET NULL,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by UUID,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (organization_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS team_members (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    team_id UUID NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES global_users(id) ON DELETE CASCADE,
+    added_by UUID,
+    joined_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (team_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_team_members_user_id ON team_members(organization_id, user_id);
+
+-- Work queued for a team has no assignee until a member takes it
+ALTER TABLE inspection_assignments ALTER COLUMN assigned_to DROP NOT NULL;
+ALTER TABLE inspection_assignments ADD COLUMN IF NOT EXISTS team_id UUID REFERENCES teams(id);
+ALTER TABLE inspections ADD COLUMN IF NOT EXISTS team_id UUID REFERENCES teams(id);
+
+CREATE INDEX IF NOT EXISTS idx_inspection_assignments_team_id ON inspection_assignments(team_id) WHERE team_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_inspections_team_id ON inspections(team_id) WHERE team_id IS NOT NULL;
+
+SELECT enable_tenant_rls('teams');
+SELECT enable_tenant_rls('team_members');
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_inspections_team_id;
+DROP INDEX IF EXISTS idx_inspection_assignments_team_id;
+ALTER TABLE inspections DROP COLUMN IF EXISTS team_id;
+ALTER TABLE inspection_assignments DROP COLUMN IF EXISTS team_id;
+-- Queued assignments have no assignee, so assigned_to stays nullable
+DROP TABLE IF EXISTS team_members;
+DROP TABLE IF EXISTS teams;
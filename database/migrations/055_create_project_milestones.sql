@@ -0,0 +1,50 @@
+-- +goose Up
+-- Project milestones, the work tied to them and the dependencies between project assignments
+CREATE TABLE IF NOT EXISTS project_milestones (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    project_id UUID NOT NULL REFERENCES inspection_projects(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    target_date DATE NOT NULL,
+    created_by UUID,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_milestones_project_id ON project_milestones(project_id, target_date);
+
+-- Each item is either an assignment or a workflow step
+CREATE TABLE IF NOT EXISTS project_milestone_items (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    milestone_id UUID NOT NULL REFERENCES project_milestones(id) ON DELETE CASCADE,
+    assignment_id UUID REFERENCES inspection_assignments(id) ON DELETE CASCADE,
+    workflow_step_id UUID REFERENCES workflow_steps(id) ON DELETE CASCADE,
+    CHECK ((assignment_id IS NULL) <> (workflow_step_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_milestone_items_milestone_id ON project_milestone_items(milestone_id);
+
+CREATE TABLE IF NOT EXISTS project_task_dependencies (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    project_id UUID NOT NULL REFERENCES inspection_projects(id) ON DELETE CASCADE,
+    assignment_id UUID NOT NULL REFERENCES inspection_assignments(id) ON DELETE CASCADE,
+    depends_on_id UUID NOT NULL REFERENCES inspection_assignments(id) ON DELETE CASCADE,
+    created_by UUID,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (assignment_id, depends_on_id),
+    CHECK (assignment_id <> depends_on_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_task_dependencies_project_id ON project_task_dependencies(project_id);
+
+SELECT enable_tenant_rls('project_milestones');
+SELECT enable_tenant_rls('project_milestone_items');
+SELECT enable_tenant_rls('project_task_dependencies');
+
+-- +goose Down
+DROP TABLE IF EXISTS project_task_dependencies;
+DROP TABLE IF EXISTS project_milestone_items;
+DROP TABLE IF EXISTS project_milestones;
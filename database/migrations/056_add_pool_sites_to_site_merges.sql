@@ -0,0 +1,6 @@
+-- +goose Up
+-- Open pool items list sites too; a merge keeps their lists as they were, for undo
+ALTER TABLE site_merges ADD COLUMN IF NOT EXISTS previous_pool_site_ids JSONB;
+
+-- +goose Down
+ALTER TABLE site_merges DROP COLUMN IF EXISTS previous_pool_site_ids;
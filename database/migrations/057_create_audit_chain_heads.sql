@@ -0,0 +1,25 @@
+-- +goose Up
+-- Newest entry of each organization's audit chain, kept outside audit_logs so that
+-- entries deleted from the end of the chain are detectable. Audit hashes are now
+-- keyed with AUDIT_CHAIN_SECRET (or JWT_SECRET); signature keys the head row itself.
+CREATE TABLE IF NOT EXISTS audit_chain_heads (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id VARCHAR(36) NOT NULL,
+    sequence BIGINT NOT NULL,
+    hash VARCHAR(64) NOT NULL,
+    signature VARCHAR(64) NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT idx_audit_chain_heads_organization_id UNIQUE (organization_id)
+);
+
+ALTER TABLE audit_chain_heads ENABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON audit_chain_heads;
+CREATE POLICY tenant_isolation ON audit_chain_heads TO app_tenant
+    USING (organization_id = app_current_organization_id()::TEXT)
+    WITH CHECK (organization_id = app_current_organization_id()::TEXT);
+GRANT SELECT, INSERT, UPDATE ON audit_chain_heads TO app_tenant;
+
+-- +goose Down
+DROP POLICY IF EXISTS tenant_isolation ON audit_chain_heads;
+DROP TABLE IF EXISTS audit_chain_heads;
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Asset statuses
+const (
+	AssetStatusActive       = "active"
+	AssetStatusOutOfService = "out_of_service"
+	AssetStatusRetired      = "retired"
+)
+
+// Asset is a piece of equipment inspected at a site, e.g. a fire extinguisher,
+// ladder, harness or HVAC unit. Retired assets are kept so their inspection
+// history stays available.
+type Asset struct {
+	ID                     string         `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
+	OrganizationID         string         `json:"organization_id" gorm:"not null;index"`
+	SiteID                 string         `json:"site_id" gorm:"type:uuid;not null;index"`
+	LocationNodeID         *string        `json:"location_node_id" gorm:"type:uuid;index"` // Optional location within the site
+	Name                   string         `json:"name" gorm:"size:255;not null"`
+	AssetTag               string         `json:"asset_tag" gorm:"size:100;index"`
+	Type                   string         `json:"type" gorm:"size:100;not null;index"` // fire_extinguisher, ladder, harness, hvac, etc.
+	SerialNumber           string         `json:"serial_number" gorm:"size:255"`
+	Manufacturer           string         `json:"manufacturer" gorm:"size:255"`
+	ModelNumber            string         `json:"model_number" gorm:"size:255"`
+	InstallDate            *time.Time     `json:"install_date"`
+	Status                 string         `json:"status" gorm:"size:50;default:'active';index"` // active, out_of_service, retired
+	InspectionIntervalDays int            `json:"inspection_interval_days" gorm:"default:0"`    // 0 means no recurring inspection
+	CustomFields           datatypes.JSON `json:"custom_fields" gorm:"type:jsonb"`
+	Notes                  string         `json:"notes" gorm:"type:text"`
+	RetiredAt              *time.Time     `json:"retired_at"`
+	RetiredBy              *string        `json:"retired_by"`
+	RetiredReason          string         `json:"retired_reason" gorm:"type:text"`
+	CreatedBy              string         `json:"created_by"`
+	UpdatedBy              string         `json:"updated_by"`
+	CreatedAt              time.Time      `json:"created_at"`
+	UpdatedAt              time.Time      `json:"updated_at"`
+	DeletedAt              gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+
+	// Relationships
+	Site Site `json:"site,omitempty" gorm:"foreignKey:SiteID"`
+}
+
+// TableName specifies the table name for Asset model
+func (Asset) TableName() string {
+	return "assets"
+}
+
+// IsRetired checks if the asset has been retired
+func (a *Asset) IsRetired() bool {
+	return a.Status == AssetStatusRetired
+}
+
+// AssetDueItem describes when an asset is next due for inspection
+type AssetDueItem struct {
+	AssetID          string     `json:"asset_id"`
+	AssetName        string     `json:"asset_name"`
+	AssetType        string     `json:"asset_type"`
+	SiteID           string     `json:"site_id"`
+	LastInspectedAt  *time.Time `json:"last_inspected_at"`
+	NextDueAt        time.Time  `json:"next_due_at"`
+	Overdue          bool       `json:"overdue"`
+	OpenInspectionID *string    `json:"open_inspection_id"` // An inspection already scheduled for the asset, if any
+}
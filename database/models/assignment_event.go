@@ -0,0 +1,121 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Assignment event types
+const (
+	AssignmentEventCreated             = "created"
+	AssignmentEventAccepted            = "accepted"
+	AssignmentEventRejected            = "rejected"
+	AssignmentEventReassigned          = "reassigned"           // Moved by a supervisor
+	AssignmentEventDelegationRequested = "delegation_requested" // The inspector asked to hand it to a peer
+	AssignmentEventDelegationDeclined  = "delegation_declined"
+	AssignmentEventDelegationCancelled = "delegation_cancelled"
+	AssignmentEventDelegated           = "delegated" // A delegation request was approved and the work moved
+	AssignmentEventQueued              = "queued"    // Put in a team's queue
+	AssignmentEventTaken               = "taken"     // Taken from a team's queue
+	AssignmentEventStarted             = "started"   // One of its inspections started
+	AssignmentEventCompleted           = "completed" // One of its inspections was completed
+)
+
+// Delegation request statuses
+const (
+	DelegationPending   = "pending"
+	DelegationApproved  = "approved"
+	DelegationDeclined  = "declined"
+	DelegationCancelled = "cancelled"
+)
+
+// Assignment rejection reason codes, for reporting why inspectors refuse work
+const (
+	RejectionScheduleConflict = "schedule_conflict"
+	RejectionWorkload         = "workload"
+	RejectionQualification    = "qualification"
+	RejectionDistance         = "distance"
+	RejectionSafety           = "safety"
+	RejectionOther            = "other"
+)
+
+// AssignmentEvent is one entry in an assignment's history. Events are only ever added, so
+// every hop of a reassigned or delegated assignment stays on record.
+type AssignmentEvent struct {
+	ID             string         `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
+	OrganizationID string         `json:"organization_id" gorm:"not null;index"`
+	AssignmentID   string         `json:"assignment_id" gorm:"not null;index"`
+	InspectionID   *string        `json:"inspection_id"` // Set for started and completed events
+	EventType      string         `json:"event_type" gorm:"size:50;not null;index"`
+	ActorID        string         `json:"actor_id"`     // Who did it; empty for system changes
+	FromUserID     *string        `json:"from_user_id"` // Inspector the work moved away from
+	ToUserID       *string        `json:"to_user_id"`   // Inspector the work moved to
+	ReasonCode     string         `json:"reason_code" gorm:"size:50"`
+	Reason         string         `json:"reason" gorm:"type:text"`
+	Metadata       datatypes.JSON `json:"metadata" gorm:"type:jsonb"`
+	OccurredAt     time.Time      `json:"occurred_at" gorm:"not null;index"`
+	CreatedAt      time.Time      `json:"created_at"`
+
+	// Relationships
+	Actor *GlobalUser `json:"actor,omitempty" gorm:"foreignKey:ActorID"`
+}
+
+// TableName specifies the table name for AssignmentEvent model
+func (AssignmentEvent) TableName() string {
+	return "assignment_events"
+}
+
+// AssignmentDelegation is an inspector's request to hand their assignment to a peer. It
+// takes effect once a supervisor approves it.
+type AssignmentDelegation struct {
+	ID              string     `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
+	OrganizationID  string     `json:"organization_id" gorm:"not null;index"`
+	AssignmentID    string     `json:"assignment_id" gorm:"not null;index"`
+	FromInspectorID string     `json:"from_inspector_id" gorm:"not null"`
+	ToInspectorID   string     `json:"to_inspector_id" gorm:"not null"`
+	Reason          string     `json:"reason" gorm:"type:text"`
+	Status          string     `json:"status" gorm:"size:20;not null;index"` // pending, approved, declined, cancelled
+	ReviewedBy      *string    `json:"reviewed_by"`
+	ReviewedAt      *time.Time `json:"reviewed_at"`
+	ReviewNote      string     `json:"review_note" gorm:"type:text"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+
+	// Relationships
+	Assignment    *InspectionAssignment `json:"assignment,omitempty" gorm:"foreignKey:AssignmentID"`
+	FromInspector *GlobalUser           `json:"from_inspector,omitempty" gorm:"foreignKey:FromInspectorID"`
+	ToInspector   *GlobalUser           `json:"to_inspector,omitempty" gorm:"foreignKey:ToInspectorID"`
+}
+
+// TableName specifies the table name for AssignmentDelegation model
+func (AssignmentDelegation) TableName() string {
+	return "assignment_delegations"
+}
+
+// DelegationRequest asks to hand an assignment to another inspector
+type DelegationRequest struct {
+	ToInspectorID string `json:"to_inspector_id" binding:"required"`
+	Reason        string `json:"reason" binding:"required"`
+}
+
+// ReviewDelegationRequest carries a supervisor's note when approving or declining a delegation
+type ReviewDelegationRequest struct {
+	Note string `json:"note"`
+}
+
+// RejectionSummary counts rejections by one dimension of a rejection report
+type RejectionSummary struct {
+	Key        string `json:"key"`
+	Rejections int    `json:"rejections"`
+}
+
+// RejectionReport says why inspectors refused work over a period
+type RejectionReport struct {
+	From        time.Time          `json:"from"`
+	To          time.Time          `json:"to"`
+	Total       int                `json:"total"`
+	ByReason    []RejectionSummary `json:"by_reason"`
+	ByInspector []RejectionSummary `json:"by_inspector"`
+	Rejections  []AssignmentEvent  `json:"rejections"`
+}
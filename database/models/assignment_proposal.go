@@ -0,0 +1,94 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Assignment proposal statuses
+const (
+	AssignmentProposalProposed  = "proposed"
+	AssignmentProposalAccepted  = "accepted"
+	AssignmentProposalDiscarded = "discarded"
+)
+
+// AssignmentProposal is the solver's suggested split of a batch of sites across
+// inspectors. Nothing is assigned until a supervisor accepts it, optionally moving sites
+// to other inspectors first.
+type AssignmentProposal struct {
+	ID             string     `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
+	OrganizationID string     `json:"organization_id" gorm:"not null;index"`
+	ProjectID      *string    `json:"project_id" gorm:"index"`
+	TemplateID     string     `json:"template_id" gorm:"not null"`
+	Status         string     `json:"status" gorm:"size:20;not null"` // proposed, accepted, discarded
+	ScheduledFor   time.Time  `json:"scheduled_for"`                  // The day the inspections are planned for
+	DueDate        *time.Time `json:"due_date"`
+
+	// Request holds the assignment details used when the proposal is accepted
+	Request datatypes.JSON `json:"request" gorm:"type:jsonb"`
+	// Items is one AssignmentProposalItem per site, in the order the solver placed them
+	Items      datatypes.JSON `json:"items" gorm:"type:jsonb"`
+	Assigned   int            `json:"assigned"`
+	Unassigned int            `json:"unassigned"`
+
+	BatchID    string     `json:"batch_id"` // Batch of the assignments created on accept
+	CreatedBy  string     `json:"created_by"`
+	AcceptedBy *string    `json:"accepted_by"`
+	AcceptedAt *time.Time `json:"accepted_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// TableName specifies the table name for AssignmentProposal model
+func (AssignmentProposal) TableName() string {
+	return "assignment_proposals"
+}
+
+// AssignmentProposalItem explains where one site went and why. InspectorID is empty when
+// no eligible inspector had room for the site.
+type AssignmentProposalItem struct {
+	SiteID        string                         `json:"site_id"`
+	SiteName      string                         `json:"site_name"`
+	InspectorID   string                         `json:"inspector_id"`
+	InspectorName string                         `json:"inspector_name"`
+	Score         float64                        `json:"score"`
+	DistanceKm    *float64                       `json:"distance_km"`
+	Reasons       []string                       `json:"reasons"`
+	Rejected      []AssignmentCandidateRejection `json:"rejected"`
+	Overridden    bool                           `json:"overridden"` // Moved by the supervisor on accept
+}
+
+// AssignmentCandidateRejection is an inspector who was not picked for a site, and why
+type AssignmentCandidateRejection struct {
+	InspectorID   string   `json:"inspector_id"`
+	InspectorName string   `json:"inspector_name"`
+	Reasons       []string `json:"reasons"`
+}
+
+// AutoAssignRequest asks the solver to distribute SiteIDs across inspectors. InspectorIDs
+// narrows the candidates; by default every active inspector in the organization is
+// considered.
+type AutoAssignRequest struct {
+	Name           string                 `json:"name" binding:"required"`
+	Description    string                 `json:"description"`
+	ProjectID      *string                `json:"project_id"`
+	TemplateID     string                 `json:"template_id" binding:"required"`
+	SiteIDs        []string               `json:"site_ids" binding:"required,min=1"`
+	InspectorIDs   []string               `json:"inspector_ids"`
+	Priority       string                 `json:"priority"`
+	ScheduledFor   *time.Time             `json:"scheduled_for"` // Defaults to today
+	DueDate        *time.Time             `json:"due_date"`
+	EstimatedHours int                    `json:"estimated_hours"`
+	Instructions   string                 `json:"instructions"`
+	Metadata       map[string]interface{} `json:"metadata"`
+}
+
+// AcceptAssignmentProposalRequest commits a proposal. Overrides maps site IDs to the
+// inspector they should go to instead; an empty inspector ID leaves the site out.
+type AcceptAssignmentProposalRequest struct {
+	Overrides          map[string]string `json:"overrides"`
+	RequiresAcceptance bool              `json:"requires_acceptance"`
+	AllowReassignment  bool              `json:"allow_reassignment"`
+	NotifyOnOverdue    bool              `json:"notify_on_overdue"`
+}
@@ -0,0 +1,147 @@
+package models
+
+import (
+	"time"
+)
+
+// Time-off request statuses
+const (
+	TimeOffPending   = "pending"
+	TimeOffApproved  = "approved"
+	TimeOffRejected  = "rejected"
+	TimeOffCancelled = "cancelled"
+)
+
+// Time-off types
+const (
+	TimeOffVacation = "vacation"
+	TimeOffSick     = "sick"
+	TimeOffTraining = "training"
+	TimeOffOther    = "other"
+)
+
+// InspectorWorkingHours is one weekday an inspector works. An inspector without any rows
+// has no weekly pattern and counts as working every day.
+type InspectorWorkingHours struct {
+	ID             string    `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
+	OrganizationID string    `json:"organization_id" gorm:"not null;uniqueIndex:idx_inspector_working_hours_day"`
+	InspectorID    string    `json:"inspector_id" gorm:"not null;uniqueIndex:idx_inspector_working_hours_day"`
+	Weekday        int       `json:"weekday" gorm:"not null;uniqueIndex:idx_inspector_working_hours_day"` // 0 is Sunday
+	StartTime      string    `json:"start_time" gorm:"size:5;not null"`                                   // HH:MM
+	EndTime        string    `json:"end_time" gorm:"size:5;not null"`                                     // HH:MM
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for InspectorWorkingHours model
+func (InspectorWorkingHours) TableName() string {
+	return "inspector_working_hours"
+}
+
+// InspectorTimeOff is a request for days off, from StartDate to EndDate inclusive. Only
+// approved requests make the inspector unavailable.
+type InspectorTimeOff struct {
+	ID             string     `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
+	OrganizationID string     `json:"organization_id" gorm:"not null;index"`
+	InspectorID    string     `json:"inspector_id" gorm:"not null;index"`
+	StartDate      time.Time  `json:"start_date" gorm:"type:date;not null"`
+	EndDate        time.Time  `json:"end_date" gorm:"type:date;not null"`
+	Type           string     `json:"type" gorm:"size:20;not null"`   // vacation, sick, training, other
+	Status         string     `json:"status" gorm:"size:20;not null"` // pending, approved, rejected, cancelled
+	Reason         string     `json:"reason" gorm:"type:text"`
+	RequestedBy    string     `json:"requested_by"`
+	ReviewedBy     *string    `json:"reviewed_by"`
+	ReviewedAt     *time.Time `json:"reviewed_at"`
+	ReviewNote     string     `json:"review_note" gorm:"type:text"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	// Relationships
+	Inspector GlobalUser `json:"inspector" gorm:"foreignKey:InspectorID"`
+}
+
+// TableName specifies the table name for InspectorTimeOff model
+func (InspectorTimeOff) TableName() string {
+	return "inspector_time_off"
+}
+
+// OrganizationHoliday is a day nobody in the organization works. Recurring holidays fall
+// on the same month and day every year.
+type OrganizationHoliday struct {
+	ID             string    `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
+	OrganizationID string    `json:"organization_id" gorm:"not null;index"`
+	Date           time.Time `json:"date" gorm:"type:date;not null"`
+	Name           string    `json:"name" gorm:"size:255;not null"`
+	Recurring      bool      `json:"recurring"`
+	CreatedBy      string    `json:"created_by"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for OrganizationHoliday model
+func (OrganizationHoliday) TableName() string {
+	return "organization_holidays"
+}
+
+// OrganizationBusinessHours is one weekday the organization is open. Business-time due
+// dates and SLA deadlines only count these hours. An organization without any rows is open
+// around the clock, holidays aside.
+type OrganizationBusinessHours struct {
+	ID             string    `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
+	OrganizationID string    `json:"organization_id" gorm:"not null;uniqueIndex:idx_organization_business_hours_day"`
+	Weekday        int       `json:"weekday" gorm:"not null;uniqueIndex:idx_organization_business_hours_day"` // 0 is Sunday
+	StartTime      string    `json:"start_time" gorm:"size:5;not null"`                                       // HH:MM, local time
+	EndTime        string    `json:"end_time" gorm:"size:5;not null"`                                         // HH:MM, local time
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for OrganizationBusinessHours model
+func (OrganizationBusinessHours) TableName() string {
+	return "organization_business_hours"
+}
+
+// WorkingHoursEntry sets the hours for one weekday
+type WorkingHoursEntry struct {
+	Weekday   int    `json:"weekday"`
+	StartTime string `json:"start_time" binding:"required"`
+	EndTime   string `json:"end_time" binding:"required"`
+}
+
+// TimeOffRequest asks for time off. Dates are calendar days (YYYY-MM-DD), inclusive.
+type TimeOffRequest struct {
+	InspectorID string `json:"inspector_id"` // Defaults to the caller
+	StartDate   string `json:"start_date" binding:"required"`
+	EndDate     string `json:"end_date" binding:"required"`
+	Type        string `json:"type"`
+	Reason      string `json:"reason"`
+}
+
+// ReviewTimeOffRequest carries a supervisor's note when approving or rejecting time off
+type ReviewTimeOffRequest struct {
+	Note string `json:"note"`
+}
+
+// CreateHolidayRequest adds a day to the organization's holiday calendar
+type CreateHolidayRequest struct {
+	Date      string `json:"date" binding:"required"` // YYYY-MM-DD
+	Name      string `json:"name" binding:"required"`
+	Recurring bool   `json:"recurring"`
+}
+
+// DueDateResult is a business-time due date with the time zone it was worked out in
+type DueDateResult struct {
+	Start    time.Time `json:"start"`
+	Hours    float64   `json:"hours"`
+	DueDate  time.Time `json:"due_date"`
+	Timezone string    `json:"timezone"`
+}
+
+// AvailabilityDay is one day of an inspector's calendar. Reason says why an unavailable
+// day is unavailable; the hours are set for working days with a weekly pattern.
+type AvailabilityDay struct {
+	Date      string `json:"date"` // YYYY-MM-DD
+	Available bool   `json:"available"`
+	Reason    string `json:"reason,omitempty"`
+	StartTime string `json:"start_time,omitempty"`
+	EndTime   string `json:"end_time,omitempty"`
+}
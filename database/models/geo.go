@@ -0,0 +1,56 @@
+package models
+
+// Site compliance statuses used to colour sites on maps
+const (
+	SiteComplianceCompliant      = "compliant"       // Inspected and nothing open is past due
+	SiteComplianceDueSoon        = "due_soon"        // An open inspection falls due within the warning window
+	SiteComplianceOverdue        = "overdue"         // An open inspection is past its due date
+	SiteComplianceNeverInspected = "never_inspected" // No completed inspection on record
+)
+
+// GeoPoint is a WGS84 coordinate in decimal degrees
+type GeoPoint struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// GeoBounds is a map viewport. MinLongitude is greater than MaxLongitude when the
+// viewport crosses the antimeridian.
+type GeoBounds struct {
+	MinLatitude  float64 `json:"min_latitude"`
+	MinLongitude float64 `json:"min_longitude"`
+	MaxLatitude  float64 `json:"max_latitude"`
+	MaxLongitude float64 `json:"max_longitude"`
+}
+
+// SiteWithDistance is a site returned by a proximity query
+type SiteWithDistance struct {
+	Site
+	DistanceKm float64 `json:"distance_km"`
+}
+
+// NearbyDueSite groups an inspector's open inspections at a site near them
+type NearbyDueSite struct {
+	Site        SiteWithDistance `json:"site"`
+	Inspections []Inspection     `json:"inspections"`
+}
+
+// GeoJSONFeatureCollection is a GeoJSON (RFC 7946) feature collection
+type GeoJSONFeatureCollection struct {
+	Type     string           `json:"type"` // Always "FeatureCollection"
+	Features []GeoJSONFeature `json:"features"`
+}
+
+// GeoJSONFeature is a single GeoJSON feature
+type GeoJSONFeature struct {
+	Type       string                 `json:"type"` // Always "Feature"
+	ID         string                 `json:"id,omitempty"`
+	Geometry   GeoJSONGeometry        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// GeoJSONGeometry is a GeoJSON point; coordinates are [longitude, latitude]
+type GeoJSONGeometry struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"`
+}
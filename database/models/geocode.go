@@ -0,0 +1,78 @@
+package models
+
+import "time"
+
+// Where a site's coordinates came from
+const (
+	CoordinatesSourceManual   = "manual"   // Entered by a person or imported; never replaced by geocoding
+	CoordinatesSourceGeocoded = "geocoded" // Looked up from the address; refreshed when the address changes
+)
+
+// Site geocode job statuses
+const (
+	SiteGeocodeStatusPending   = "pending"
+	SiteGeocodeStatusRunning   = "running"
+	SiteGeocodeStatusCompleted = "completed"
+)
+
+// GeocodeResult is a geocoder's best match for an address
+type GeocodeResult struct {
+	Latitude    float64 `json:"latitude"`
+	Longitude   float64 `json:"longitude"`
+	DisplayName string  `json:"display_name,omitempty"` // The provider's formatted address, when it returns one
+	Provider    string  `json:"provider"`
+}
+
+// GeocodeCacheEntry remembers a provider's answer for a normalized address, including
+// addresses it could not find, so repeated lookups don't hit the provider again.
+// Addresses are not tenant data, so the cache is shared by all organizations.
+type GeocodeCacheEntry struct {
+	ID          uint      `json:"id" gorm:"primarykey"`
+	Provider    string    `json:"provider" gorm:"size:50;not null;uniqueIndex:idx_geocode_cache_key"`
+	AddressKey  string    `json:"address_key" gorm:"size:500;not null;uniqueIndex:idx_geocode_cache_key"`
+	Found       bool      `json:"found"`
+	Latitude    float64   `json:"latitude"`
+	Longitude   float64   `json:"longitude"`
+	DisplayName string    `json:"display_name" gorm:"size:500"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for GeocodeCacheEntry model
+func (GeocodeCacheEntry) TableName() string {
+	return "geocode_cache"
+}
+
+// SiteGeocodeJob tracks a batch re-geocode of an organization's sites
+type SiteGeocodeJob struct {
+	ID             string     `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
+	OrganizationID string     `json:"organization_id" gorm:"not null;index"`
+	Status         string     `json:"status" gorm:"size:20;not null;default:'pending'"` // pending, running, completed
+	Force          bool       `json:"force"`                                            // Refresh geocoded coordinates too, not just missing ones
+	TotalSites     int        `json:"total_sites"`
+	ProcessedSites int        `json:"processed_sites"`
+	GeocodedCount  int        `json:"geocoded_count"`
+	NotFoundCount  int        `json:"not_found_count"`
+	FailedCount    int        `json:"failed_count"`
+	CreatedBy      string     `json:"created_by"`
+	StartedAt      *time.Time `json:"started_at"`
+	FinishedAt     *time.Time `json:"finished_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// TableName specifies the table name for SiteGeocodeJob model
+func (SiteGeocodeJob) TableName() string {
+	return "site_geocode_jobs"
+}
+
+// Progress returns the share of sites processed, from 0 to 100
+func (j *SiteGeocodeJob) Progress() int {
+	if j.TotalSites == 0 {
+		if j.Status == SiteGeocodeStatusCompleted {
+			return 100
+		}
+		return 0
+	}
+	return j.ProcessedSites * 100 / j.TotalSites
+}
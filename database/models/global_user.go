@@ -160,6 +160,22 @@ type UserSession struct {
 	CurrentOrganization *Organization `json:"current_organization" gorm:"foreignKey:CurrentOrganizationID"`
 }
 
+// UserIdentity links an external identity provider account to a global user
+type UserIdentity struct {
+	ID            string     `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
+	UserID        string     `json:"user_id" gorm:"not null;index"`
+	Provider      string     `json:"provider" gorm:"size:50;not null;uniqueIndex:unique_provider_subject"` // google, microsoft, or the configured OIDC provider
+	Subject       string     `json:"subject" gorm:"size:255;not null;uniqueIndex:unique_provider_subject"`
+	Email         string     `json:"email" gorm:"size:255"`
+	EmailVerified bool       `json:"email_verified" gorm:"default:false"`
+	LastLoginAt   *time.Time `json:"last_login_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+
+	// Relationships
+	User GlobalUser `json:"-" gorm:"foreignKey:UserID"`
+}
+
 // UserOrganizationContext represents a user's accessible organizations
 type UserOrganizationContext struct {
 	UserID               string                   `json:"user_id"`
@@ -195,4 +211,8 @@ func (OrganizationInvitation) TableName() string {
 
 func (UserSession) TableName() string {
 	return "user_sessions"
+}
+
+func (UserIdentity) TableName() string {
+	return "user_identities"
 }
\ No newline at end of file
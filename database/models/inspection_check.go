@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+)
+
+// Inspection check event types
+const (
+	CheckEventCheckIn  = "check_in"  // Recorded when an inspection is started
+	CheckEventCheckOut = "check_out" // Recorded when an inspection is completed
+)
+
+// Geofence policies, set per organization in Organization.Settings["geofence_policy"]
+const (
+	GeofencePolicyOff   = "off"   // Record coordinates but never flag
+	GeofencePolicyWarn  = "warn"  // Flag check-ins and check-outs outside the geofence
+	GeofencePolicyBlock = "block" // Refuse to start an inspection outside the geofence
+)
+
+// Check event flag reasons
+const (
+	CheckFlagOutsideGeofence = "outside_geofence"
+	CheckFlagLowAccuracy     = "low_accuracy"
+	CheckFlagNoLocation      = "no_location"
+)
+
+// DeviceLocation is a GPS fix reported by the inspector's device
+type DeviceLocation struct {
+	Latitude       float64 `json:"latitude"`
+	Longitude      float64 `json:"longitude"`
+	AccuracyMeters float64 `json:"accuracy_meters"` // Horizontal accuracy radius reported by the device
+}
+
+// InspectionCheckEvent records where an inspector was when they started or completed
+// an inspection, and how far that was from the site
+type InspectionCheckEvent struct {
+	ID                   string    `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
+	OrganizationID       string    `json:"organization_id" gorm:"not null;index"`
+	InspectionID         string    `json:"inspection_id" gorm:"type:uuid;not null;index"`
+	SiteID               string    `json:"site_id" gorm:"type:uuid;not null;index"`
+	UserID               string    `json:"user_id" gorm:"not null"`
+	EventType            string    `json:"event_type" gorm:"size:20;not null"` // check_in, check_out
+	Latitude             *float64  `json:"latitude"`
+	Longitude            *float64  `json:"longitude"`
+	AccuracyMeters       *float64  `json:"accuracy_meters"`
+	DistanceMeters       *float64  `json:"distance_meters"` // Nil when the device or the site has no coordinates
+	GeofenceRadiusMeters int       `json:"geofence_radius_meters"`
+	WithinGeofence       *bool     `json:"within_geofence"` // Nil when the distance could not be computed
+	Flagged              bool      `json:"flagged" gorm:"default:false;index"`
+	FlagReasons          string    `json:"flag_reasons" gorm:"size:255"` // Comma separated, e.g. "outside_geofence,low_accuracy"
+	TimeOnSiteSeconds    *int      `json:"time_on_site_seconds"`         // Check-out only: time since the matching check-in
+	RecordedAt           time.Time `json:"recorded_at" gorm:"not null;index"`
+	CreatedAt            time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for InspectionCheckEvent model
+func (InspectionCheckEvent) TableName() string {
+	return "inspection_check_events"
+}
+
+// OffSiteInspection is a row of the off-site report: an inspection with its flagged check events
+type OffSiteInspection struct {
+	Inspection Inspection             `json:"inspection"`
+	Events     []InspectionCheckEvent `json:"events"`
+}
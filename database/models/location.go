@@ -0,0 +1,110 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Location node types, from the broadest to the most specific
+const (
+	LocationTypeRegion   = "region"
+	LocationTypeCampus   = "campus"
+	LocationTypeBuilding = "building"
+	LocationTypeFloor    = "floor"
+	LocationTypeRoom     = "room"
+	LocationTypeArea     = "area"
+)
+
+// ValidLocationTypes lists the accepted values for LocationNode.Type
+var ValidLocationTypes = []string{
+	LocationTypeRegion,
+	LocationTypeCampus,
+	LocationTypeBuilding,
+	LocationTypeFloor,
+	LocationTypeRoom,
+	LocationTypeArea,
+}
+
+// LocationNode is a node in the location hierarchy of a site
+// (regions, campuses, buildings, floors, rooms).
+//
+// Path is the materialized path of node IDs from the root down to and including
+// the node itself, each followed by "/", so a subtree is every node whose path
+// starts with the path of its root.
+type LocationNode struct {
+	ID             string         `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
+	OrganizationID string         `json:"organization_id" gorm:"not null;index"`
+	SiteID         string         `json:"site_id" gorm:"type:uuid;not null;index"`
+	ParentID       *string        `json:"parent_id" gorm:"type:uuid;index"`
+	Name           string         `json:"name" gorm:"size:255;not null"`
+	Type           string         `json:"type" gorm:"size:50;not null"` // region, campus, building, floor, room, area
+	Code           string         `json:"code" gorm:"size:100"`
+	Path           string         `json:"path" gorm:"size:2000;not null;index"`
+	Depth          int            `json:"depth" gorm:"not null;default:0"`
+	SortOrder      int            `json:"sort_order" gorm:"default:0"`
+	Metadata       datatypes.JSON `json:"metadata" gorm:"type:jsonb"`
+	CreatedBy      string         `json:"created_by"`
+	UpdatedBy      string         `json:"updated_by"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+
+	// Children is filled in by tree queries
+	Children []LocationNode `json:"children,omitempty" gorm:"-"`
+}
+
+// TableName specifies the table name for LocationNode model
+func (LocationNode) TableName() string {
+	return "location_nodes"
+}
+
+// AncestorIDs returns the IDs of the node's ancestors, root first
+func (n *LocationNode) AncestorIDs() []string {
+	ids := strings.Split(strings.TrimSuffix(n.Path, "/"), "/")
+	if len(ids) <= 1 {
+		return []string{}
+	}
+	return ids[:len(ids)-1]
+}
+
+// LocationNodeMove records a node being moved to a new parent. Inspections keep
+// pointing at the moved nodes, so their history travels with the subtree.
+type LocationNodeMove struct {
+	ID             string    `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
+	OrganizationID string    `json:"organization_id" gorm:"not null;index"`
+	NodeID         string    `json:"node_id" gorm:"type:uuid;not null;index"`
+	SiteID         string    `json:"site_id" gorm:"type:uuid;not null"`
+	FromParentID   *string   `json:"from_parent_id" gorm:"type:uuid"`
+	ToParentID     *string   `json:"to_parent_id" gorm:"type:uuid"`
+	FromPath       string    `json:"from_path" gorm:"size:2000"`
+	ToPath         string    `json:"to_path" gorm:"size:2000"`
+	MovedBy        string    `json:"moved_by"`
+	MovedAt        time.Time `json:"moved_at"`
+}
+
+// TableName specifies the table name for LocationNodeMove model
+func (LocationNodeMove) TableName() string {
+	return "location_node_moves"
+}
+
+// LocationBreadcrumb is one step of the path from a site down to a location
+type LocationBreadcrumb struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"` // "site" for the first entry, otherwise the node type
+}
+
+// LocationStats holds inspection counts for a location, rolled up over its subtree
+type LocationStats struct {
+	NodeID               string          `json:"node_id"`
+	Name                 string          `json:"name"`
+	Type                 string          `json:"type"`
+	TotalInspections     int             `json:"total_inspections"`
+	CompletedInspections int             `json:"completed_inspections"`
+	PendingInspections   int             `json:"pending_inspections"`
+	OverdueInspections   int             `json:"overdue_inspections"`
+	Children             []LocationStats `json:"children,omitempty"`
+}
@@ -17,6 +17,12 @@ type Organization struct {
 	Settings     datatypes.JSON `json:"settings" gorm:"type:jsonb;default:'{}'"`
 	Plan         string         `json:"plan" gorm:"size:50;default:'free'"`
 	IsActive     bool           `json:"is_active" gorm:"default:true"`
+	Timezone     string         `json:"timezone" gorm:"size:64;default:'UTC'"` // IANA zone that business hours, holidays and due dates are read in
+
+	// Domain-based auto-join for users signing in with a verified email
+	AutoJoinDomain *string `json:"auto_join_domain" gorm:"size:255;index"` // e.g. "acme.com"
+	AutoJoinRole   string  `json:"auto_join_role" gorm:"size:100;default:'inspector'"`
+
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `json:"deleted_at" gorm:"index"`
@@ -65,10 +71,13 @@ type Inspection struct {
 	OrganizationID string         `json:"organization_id" gorm:"not null;index"`
 	TemplateID     uuid.UUID      `json:"template_id" gorm:"type:uuid;not null"`
 	TemplateVersion int           `json:"template_version" gorm:"not null;default:1"`
-	InspectorID    string         `json:"inspector_id" gorm:"not null"` // References global_users.id (UUID)
+	InspectorID    string         `json:"inspector_id"` // References global_users.id (UUID); empty while unassigned
 	AssignedBy     *string        `json:"assigned_by"`
 	AssignmentID   *string        `json:"assignment_id" gorm:"index"` // Reference to InspectionAssignment for workflow tracking
+	TeamID         *string        `json:"team_id" gorm:"index"` // Team whose queue it was put in
 	SiteID         string         `json:"site_id" gorm:"type:uuid;not null;index"` // Required reference to Site
+	LocationNodeID *string        `json:"location_node_id" gorm:"type:uuid;index"` // Optional location within the site
+	AssetID        *string        `json:"asset_id" gorm:"type:uuid;index"` // Optional asset being inspected
 	Status         string         `json:"status" gorm:"size:50;default:'assigned'"`
 	Priority       string         `json:"priority" gorm:"size:50;default:'medium'"`
 	ScheduledFor   *time.Time     `json:"scheduled_for"`
@@ -80,6 +89,12 @@ type Inspection struct {
 	UpdatedAt      time.Time      `json:"updated_at"`
 	DeletedAt      gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 
+	// GPS check-in/out summary (see InspectionCheckEvent)
+	CheckedInAt       *time.Time `json:"checked_in_at"`
+	CheckedOutAt      *time.Time `json:"checked_out_at"`
+	TimeOnSiteSeconds *int       `json:"time_on_site_seconds"`
+	OffSite           bool       `json:"off_site" gorm:"default:false;index"` // A check-in or check-out was flagged
+
 	// Relationships
 	Organization   Organization     `json:"organization" gorm:"foreignKey:OrganizationID"`
 	Template       Template         `json:"template" gorm:"foreignKey:TemplateID"`
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Open pool item statuses
+const (
+	OpenPoolItemOpen      = "open"      // Waiting for an inspector to claim it
+	OpenPoolItemClaimed   = "claimed"   // Claimed, waiting for a supervisor to confirm
+	OpenPoolItemAssigned  = "assigned"  // Handed to the inspector who claimed it
+	OpenPoolItemWithdrawn = "withdrawn" // Taken out of the pool by a supervisor
+)
+
+// OpenPoolItem is work published for inspectors to claim: either an existing inspection
+// nobody is assigned to, or a batch of sites that becomes an assignment once claimed
+type OpenPoolItem struct {
+	ID                   string         `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
+	OrganizationID       string         `json:"organization_id" gorm:"not null;index"`
+	ProjectID            *string        `json:"project_id" gorm:"index"`
+	TemplateID           string         `json:"template_id" gorm:"not null"`
+	InspectionID         *string        `json:"inspection_id" gorm:"index"` // Set for a published inspection
+	SiteIDs              datatypes.JSON `json:"site_ids" gorm:"type:jsonb;not null"`
+	Title                string         `json:"title" gorm:"size:255;not null"`
+	Description          string         `json:"description" gorm:"type:text"`
+	Priority             string         `json:"priority" gorm:"size:50"`
+	ScheduledFor         *time.Time     `json:"scheduled_for"`
+	DueDate              *time.Time     `json:"due_date" gorm:"index"`
+	EstimatedHours       int            `json:"estimated_hours"`
+	RequiresConfirmation bool           `json:"requires_confirmation"`
+	Status               string         `json:"status" gorm:"size:20;not null;index"` // open, claimed, assigned, withdrawn
+	PublishedBy          string         `json:"published_by" gorm:"not null"`
+	ClaimedBy            *string        `json:"claimed_by"`
+	ClaimedAt            *time.Time     `json:"claimed_at"`
+	ConfirmedBy          *string        `json:"confirmed_by"`
+	ConfirmedAt          *time.Time     `json:"confirmed_at"`
+	AssignmentID         *string        `json:"assignment_id"` // The assignment a claimed site batch became
+	EscalatedAt          *time.Time     `json:"escalated_at"`  // When the unclaimed item was escalated
+	CreatedAt            time.Time      `json:"created_at"`
+	UpdatedAt            time.Time      `json:"updated_at"`
+}
+
+// TableName specifies the table name for OpenPoolItem model
+func (OpenPoolItem) TableName() string {
+	return "open_pool_items"
+}
+
+// PublishToPoolRequest publishes work to the open pool. Each inspection in InspectionIDs
+// becomes its own item; SiteIDs become one item covering the whole batch.
+type PublishToPoolRequest struct {
+	ProjectID            *string    `json:"project_id"`
+	TemplateID           string     `json:"template_id"` // Required for a site batch
+	SiteIDs              []string   `json:"site_ids"`
+	InspectionIDs        []string   `json:"inspection_ids"`
+	Title                string     `json:"title"`
+	Description          string     `json:"description"`
+	Priority             string     `json:"priority"`
+	ScheduledFor         *time.Time `json:"scheduled_for"`
+	DueDate              *time.Time `json:"due_date"`
+	EstimatedHours       int        `json:"estimated_hours"`
+	RequiresConfirmation bool       `json:"requires_confirmation"`
+}
+
+// OpenPoolSite is a site of a pool item, with its distance from the viewing inspector
+type OpenPoolSite struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	City       string   `json:"city"`
+	State      string   `json:"state"`
+	DistanceKm *float64 `json:"distance_km,omitempty"`
+}
+
+// OpenPoolListing is a pool item as an inspector sees it: its sites, how far the nearest
+// one is, and whether they may claim it and if not, why
+type OpenPoolListing struct {
+	OpenPoolItem
+	Sites      []OpenPoolSite `json:"sites"`
+	DistanceKm *float64       `json:"distance_km,omitempty"`
+	Eligible   bool           `json:"eligible"`
+	Reasons    []string       `json:"reasons,omitempty"`
+}
@@ -0,0 +1,137 @@
+package models
+
+import "time"
+
+// Milestone statuses, worked out from the milestone's work and the project's pace
+const (
+	MilestoneAchieved = "achieved"
+	MilestoneOnTrack  = "on_track"
+	MilestoneAtRisk   = "at_risk" // Projected to finish after its target date, or can't be projected
+	MilestoneMissed   = "missed"  // Target date passed with work left
+)
+
+// Timeline task types
+const (
+	TimelineTaskAssignment = "assignment"
+	TimelineTaskMilestone  = "milestone"
+)
+
+// ProjectMilestone is a target date in a project, reached once the assignments and
+// workflow steps tied to it are done
+type ProjectMilestone struct {
+	ID             string    `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
+	OrganizationID string    `json:"organization_id" gorm:"not null;index"`
+	ProjectID      string    `json:"project_id" gorm:"not null;index"`
+	Name           string    `json:"name" gorm:"size:255;not null"`
+	Description    string    `json:"description" gorm:"type:text"`
+	TargetDate     time.Time `json:"target_date" gorm:"type:date;not null"`
+	CreatedBy      string    `json:"created_by"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+
+	// Computed
+	Status          string     `json:"status,omitempty" gorm:"-"`
+	PercentComplete float64    `json:"percent_complete" gorm:"-"`
+	ProjectedDate   *time.Time `json:"projected_date,omitempty" gorm:"-"` // When its remaining work finishes at the project's current pace
+	AchievedAt      *time.Time `json:"achieved_at,omitempty" gorm:"-"`
+
+	// Relationships
+	Items []ProjectMilestoneItem `json:"items,omitempty" gorm:"foreignKey:MilestoneID"`
+}
+
+// TableName specifies the table name for ProjectMilestone model
+func (ProjectMilestone) TableName() string {
+	return "project_milestones"
+}
+
+// ProjectMilestoneItem ties an assignment or a workflow step to a milestone. A step is done
+// once all of its executions are.
+type ProjectMilestoneItem struct {
+	ID             string  `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
+	OrganizationID string  `json:"organization_id" gorm:"not null;index"`
+	MilestoneID    string  `json:"milestone_id" gorm:"not null;index"`
+	AssignmentID   *string `json:"assignment_id,omitempty" gorm:"index"`
+	WorkflowStepID *string `json:"workflow_step_id,omitempty" gorm:"index"`
+}
+
+// TableName specifies the table name for ProjectMilestoneItem model
+func (ProjectMilestoneItem) TableName() string {
+	return "project_milestone_items"
+}
+
+// ProjectTaskDependency records that an assignment can't start before another assignment
+// of the same project is done
+type ProjectTaskDependency struct {
+	ID             string    `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
+	OrganizationID string    `json:"organization_id" gorm:"not null;index"`
+	ProjectID      string    `json:"project_id" gorm:"not null;index"`
+	AssignmentID   string    `json:"assignment_id" gorm:"not null;index"`
+	DependsOnID    string    `json:"depends_on_id" gorm:"not null"`
+	CreatedBy      string    `json:"created_by"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for ProjectTaskDependency model
+func (ProjectTaskDependency) TableName() string {
+	return "project_task_dependencies"
+}
+
+// MilestoneRequest creates or updates a milestone. The assignments and workflow steps
+// replace those already tied to it.
+type MilestoneRequest struct {
+	Name            string    `json:"name" binding:"required"`
+	Description     string    `json:"description"`
+	TargetDate      time.Time `json:"target_date"`
+	AssignmentIDs   []string  `json:"assignment_ids"`
+	WorkflowStepIDs []string  `json:"workflow_step_ids"`
+}
+
+// TaskDependenciesRequest replaces the assignments an assignment depends on
+type TaskDependenciesRequest struct {
+	DependsOn []string `json:"depends_on"`
+}
+
+// TimelineTask is a bar, or for milestones a point, on a project's Gantt chart. Slack is how
+// long the task can slip without delaying the project; tasks without any are critical.
+type TimelineTask struct {
+	ID              string    `json:"id"`
+	Type            string    `json:"type"`
+	Name            string    `json:"name"`
+	Start           time.Time `json:"start"`
+	End             time.Time `json:"end"`
+	Dependencies    []string  `json:"dependencies"`
+	PercentComplete float64   `json:"percent_complete"`
+	Status          string    `json:"status"`
+	AssigneeID      string    `json:"assignee_id,omitempty"`
+	IsCritical      bool      `json:"is_critical"`
+	SlackHours      float64   `json:"slack_hours"`
+}
+
+// ProjectProgress summarizes how far a project has come and when it should finish at its
+// current pace. Velocity is completed inspections per day over the recent window.
+type ProjectProgress struct {
+	ProjectID            string     `json:"project_id"`
+	TotalAssignments     int        `json:"total_assignments"`
+	CompletedAssignments int        `json:"completed_assignments"`
+	TotalInspections     int        `json:"total_inspections"`
+	CompletedInspections int        `json:"completed_inspections"`
+	PercentComplete      float64    `json:"percent_complete"`
+	VelocityPerDay       float64    `json:"velocity_per_day"`
+	ProjectedCompletion  *time.Time `json:"projected_completion"` // Nil while work remains and nothing was completed recently
+	DueDate              *time.Time `json:"due_date"`
+	OnSchedule           bool       `json:"on_schedule"`
+	MilestonesAtRisk     int        `json:"milestones_at_risk"`
+}
+
+// ProjectTimeline is a project's Gantt data: its tasks and milestones, the critical path
+// through them and its projected completion
+type ProjectTimeline struct {
+	ProjectID    string             `json:"project_id"`
+	Name         string             `json:"name"`
+	StartDate    *time.Time         `json:"start_date"`
+	DueDate      *time.Time         `json:"due_date"`
+	Tasks        []TimelineTask     `json:"tasks"`
+	Milestones   []ProjectMilestone `json:"milestones"`
+	CriticalPath []string           `json:"critical_path"` // Critical assignment IDs by earliest start
+	Progress     ProjectProgress    `json:"progress"`
+}
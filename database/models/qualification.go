@@ -0,0 +1,224 @@
+package models
+
+import "time"
+
+// Certification statuses, relative to a day
+const (
+	CertificationValid       = "valid"
+	CertificationExpiring    = "expiring" // Within its type's reminder window
+	CertificationExpired     = "expired"
+	CertificationRevoked     = "revoked"
+	CertificationNotYetValid = "not_yet_valid" // Issued on a later day
+)
+
+// CertificationType is a kind of certification an organization tracks, such as a boiler
+// inspector licence. Templates list the types an inspector needs to perform them.
+type CertificationType struct {
+	ID             string    `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
+	OrganizationID string    `json:"organization_id" gorm:"not null;index"`
+	Name           string    `json:"name" gorm:"size:255;not null"`
+	Issuer         string    `json:"issuer" gorm:"size:255"` // Body that issues it
+	Description    string    `json:"description" gorm:"type:text"`
+	ValidityMonths int       `json:"validity_months"` // Default validity for certificates recorded without an expiry date; 0 for none
+	ReminderDays   int       `json:"reminder_days"`   // Holders and supervisors are warned this many days before expiry
+	IsActive       bool      `json:"is_active" gorm:"default:true"`
+	CreatedBy      string    `json:"created_by"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for CertificationType model
+func (CertificationType) TableName() string {
+	return "certification_types"
+}
+
+// InspectorCertification is a certificate an inspector holds. Certificates are never
+// deleted, only revoked, so they can prove what an inspector held on any past day.
+type InspectorCertification struct {
+	ID                  string     `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
+	OrganizationID      string     `json:"organization_id" gorm:"not null;index"`
+	UserID              string     `json:"user_id" gorm:"not null;index"`
+	CertificationTypeID string     `json:"certification_type_id" gorm:"not null;index"`
+	CertificateNumber   string     `json:"certificate_number" gorm:"size:100"`
+	IssuedOn            time.Time  `json:"issued_on" gorm:"type:date;not null"`
+	ExpiresOn           *time.Time `json:"expires_on" gorm:"type:date;index"` // Valid through this day; nil for certificates that don't expire
+	RevokedOn           *time.Time `json:"revoked_on" gorm:"type:date"`       // No longer valid from this day
+	RevokedBy           *string    `json:"revoked_by"`
+	RevokeReason        string     `json:"revoke_reason" gorm:"type:text"`
+	Notes               string     `json:"notes" gorm:"type:text"`
+
+	// ReminderSentFor is the expiry date the holder was last warned about
+	ReminderSentFor *time.Time `json:"reminder_sent_for,omitempty" gorm:"type:date"`
+
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Computed
+	Status string `json:"status" gorm:"-"`
+
+	// Relationships
+	CertificationType *CertificationType      `json:"certification_type,omitempty" gorm:"foreignKey:CertificationTypeID"`
+	User              *GlobalUser             `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Evidence          []CertificationEvidence `json:"evidence,omitempty" gorm:"foreignKey:CertificationID"`
+}
+
+// TableName specifies the table name for InspectorCertification model
+func (InspectorCertification) TableName() string {
+	return "inspector_certifications"
+}
+
+// ValidOn reports whether the certificate was in force on the given day
+func (c *InspectorCertification) ValidOn(day time.Time) bool {
+	return c.StatusOn(day, 0) == CertificationValid
+}
+
+// StatusOn returns the certificate's status on the given day; reminderDays is the window
+// before expiry in which a valid certificate counts as expiring
+func (c *InspectorCertification) StatusOn(now time.Time, reminderDays int) string {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch {
+	case c.RevokedOn != nil && !today.Before(certificationDate(*c.RevokedOn)):
+		return CertificationRevoked
+	case today.Before(certificationDate(c.IssuedOn)):
+		return CertificationNotYetValid
+	case c.ExpiresOn == nil:
+		return CertificationValid
+	case certificationDate(*c.ExpiresOn).Before(today):
+		return CertificationExpired
+	case reminderDays > 0 && !certificationDate(*c.ExpiresOn).After(today.AddDate(0, 0, reminderDays)):
+		return CertificationExpiring
+	default:
+		return CertificationValid
+	}
+}
+
+func certificationDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// CertificationEvidence is a file backing a certificate, such as a scan of it. Evidence is
+// kept with the certificate for as long as the certificate is.
+type CertificationEvidence struct {
+	ID              string    `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
+	OrganizationID  string    `json:"organization_id" gorm:"not null;index"`
+	CertificationID string    `json:"certification_id" gorm:"not null;index"`
+	FileName        string    `json:"file_name" gorm:"size:255;not null"`
+	StoragePath     string    `json:"-" gorm:"size:500;not null"`
+	FileSize        int64     `json:"file_size"`
+	MimeType        string    `json:"mime_type" gorm:"size:100"`
+	UploadedBy      string    `json:"uploaded_by"`
+	UploadedAt      time.Time `json:"uploaded_at"`
+}
+
+// TableName specifies the table name for CertificationEvidence model
+func (CertificationEvidence) TableName() string {
+	return "certification_evidence"
+}
+
+// TemplateQualification is a certification type required to perform inspections from a
+// template. Requirements belong to the template's first version and apply to every version.
+type TemplateQualification struct {
+	ID                  string    `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
+	OrganizationID      string    `json:"organization_id" gorm:"not null;index"`
+	TemplateID          string    `json:"template_id" gorm:"not null;index"`
+	CertificationTypeID string    `json:"certification_type_id" gorm:"not null"`
+	CreatedBy           string    `json:"created_by"`
+	CreatedAt           time.Time `json:"created_at"`
+
+	// Relationships
+	CertificationType *CertificationType `json:"certification_type,omitempty" gorm:"foreignKey:CertificationTypeID"`
+}
+
+// TableName specifies the table name for TemplateQualification model
+func (TemplateQualification) TableName() string {
+	return "template_qualifications"
+}
+
+// InspectionQualification records, when an inspection is completed, the certificate its
+// inspector held for each qualification the template required. A nil CertificationID
+// records that no valid certificate was found.
+type InspectionQualification struct {
+	ID                  string     `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
+	OrganizationID      string     `json:"organization_id" gorm:"not null;index"`
+	InspectionID        string     `json:"inspection_id" gorm:"not null;index"`
+	InspectorID         string     `json:"inspector_id" gorm:"not null"`
+	CertificationTypeID string     `json:"certification_type_id" gorm:"not null"`
+	CertificationID     *string    `json:"certification_id"`
+	CertificateNumber   string     `json:"certificate_number" gorm:"size:100"`
+	IssuedOn            *time.Time `json:"issued_on" gorm:"type:date"`
+	ExpiresOn           *time.Time `json:"expires_on" gorm:"type:date"`
+	InspectedOn         time.Time  `json:"inspected_on" gorm:"type:date;not null"`
+	RecordedAt          time.Time  `json:"recorded_at"`
+
+	// Relationships
+	CertificationType *CertificationType      `json:"certification_type,omitempty" gorm:"foreignKey:CertificationTypeID"`
+	Certification     *InspectorCertification `json:"certification,omitempty" gorm:"foreignKey:CertificationID"`
+}
+
+// TableName specifies the table name for InspectionQualification model
+func (InspectionQualification) TableName() string {
+	return "inspection_qualifications"
+}
+
+// CertificationTypeRequest creates or updates a certification type
+type CertificationTypeRequest struct {
+	Name           string `json:"name" binding:"required"`
+	Issuer         string `json:"issuer"`
+	Description    string `json:"description"`
+	ValidityMonths int    `json:"validity_months"`
+	ReminderDays   *int   `json:"reminder_days"`
+	IsActive       *bool  `json:"is_active"`
+}
+
+// CertificationRequest records a certificate an inspector holds. ExpiresOn defaults to
+// IssuedOn plus the type's validity.
+type CertificationRequest struct {
+	UserID              string     `json:"user_id" binding:"required"`
+	CertificationTypeID string     `json:"certification_type_id" binding:"required"`
+	CertificateNumber   string     `json:"certificate_number"`
+	IssuedOn            time.Time  `json:"issued_on" binding:"required"`
+	ExpiresOn           *time.Time `json:"expires_on"`
+	Notes               string     `json:"notes"`
+}
+
+// RevokeCertificationRequest withdraws a certificate from a day, today by default
+type RevokeCertificationRequest struct {
+	Reason    string     `json:"reason" binding:"required"`
+	RevokedOn *time.Time `json:"revoked_on"`
+}
+
+// TemplateQualificationsRequest replaces a template's required certification types
+type TemplateQualificationsRequest struct {
+	CertificationTypeIDs []string `json:"certification_type_ids"`
+}
+
+// QualificationCheck says whether an inspector is qualified for a template's work over a
+// period, and which required certifications they lack
+type QualificationCheck struct {
+	InspectorID string              `json:"inspector_id"`
+	TemplateID  string              `json:"template_id"`
+	From        time.Time           `json:"from"`
+	To          time.Time           `json:"to"`
+	Qualified   bool                `json:"qualified"`
+	Required    []CertificationType `json:"required"`
+	Missing     []CertificationType `json:"missing"`
+}
+
+// QualificationProof shows the certificates an inspection's inspector held on the day of
+// the inspection. Recorded is false when the proof is worked out from certificates on
+// record because the inspection wasn't completed with the record kept.
+type QualificationProof struct {
+	InspectionID   string                    `json:"inspection_id"`
+	InspectorID    string                    `json:"inspector_id"`
+	InspectedOn    time.Time                 `json:"inspected_on"`
+	Recorded       bool                      `json:"recorded"`
+	Complete       bool                      `json:"complete"` // Every requirement was met
+	Qualifications []InspectionQualification `json:"qualifications"`
+}
+
+// CertificationExpiryCheck summarizes one run of the certification expiry check
+type CertificationExpiryCheck struct {
+	CertificationsChecked int `json:"certifications_checked"`
+	Reminded              int `json:"reminded"`
+}
@@ -6,23 +6,27 @@ import (
 )
 
 type CreateInspectionRequest struct {
-	TemplateID   uuid.UUID  `json:"template_id" binding:"required"`
-	InspectorID  string     `json:"inspector_id" binding:"required"`
-	AssignedBy   *string    `json:"assigned_by"`
-	SiteID       string     `json:"site_id" binding:"required"`
-	Priority     string     `json:"priority"`
-	ScheduledFor *time.Time `json:"scheduled_for"`
-	DueDate      *time.Time `json:"due_date"`
-	Notes        string     `json:"notes"`
-	Status       string     `json:"status"`
+	TemplateID     uuid.UUID  `json:"template_id" binding:"required"`
+	InspectorID    string     `json:"inspector_id" binding:"required"`
+	AssignedBy     *string    `json:"assigned_by"`
+	SiteID         string     `json:"site_id" binding:"required"`
+	LocationNodeID *string    `json:"location_node_id"`
+	AssetID        *string    `json:"asset_id"`
+	Priority       string     `json:"priority"`
+	ScheduledFor   *time.Time `json:"scheduled_for"`
+	DueDate        *time.Time `json:"due_date"`
+	Notes          string     `json:"notes"`
+	Status         string     `json:"status"`
 }
 
 type UpdateInspectionRequest struct {
-	SiteID   string     `json:"site_id"`
-	Status   string     `json:"status"`
-	Priority string     `json:"priority"`
-	DueDate  *time.Time `json:"due_date"`
-	Notes    string     `json:"notes"`
+	SiteID         string     `json:"site_id"`
+	LocationNodeID *string    `json:"location_node_id"`
+	AssetID        *string    `json:"asset_id"`
+	Status         string     `json:"status"`
+	Priority       string     `json:"priority"`
+	DueDate        *time.Time `json:"due_date"`
+	Notes          string     `json:"notes"`
 }
 
 type SubmitInspectionRequest struct {
@@ -89,6 +93,7 @@ type UpdateOrganizationRequest struct {
 	Settings map[string]interface{} `json:"settings"`
 	Plan     string                 `json:"plan"`
 	IsActive *bool                  `json:"is_active"`
+	Timezone string                 `json:"timezone"`
 }
 
 type InviteUserRequest struct {
@@ -128,8 +133,13 @@ type AssignInspectionRequest struct {
 }
 
 type UpdateInspectionStatusRequest struct {
-	Status string `json:"status" binding:"required"`
-	Notes  string `json:"notes"`
+	Status   string          `json:"status" binding:"required"`
+	Notes    string          `json:"notes"`
+	Location *DeviceLocation `json:"location"` // Checked against the site geofence when starting or completing
+}
+
+type InspectionCheckRequest struct {
+	Location *DeviceLocation `json:"location"`
 }
 
 type CreateNotificationRequest struct {
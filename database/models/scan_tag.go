@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Scan tag entity types
+const (
+	ScanTagEntitySite  = "site"
+	ScanTagEntityAsset = "asset"
+)
+
+// Scan tag statuses
+const (
+	ScanTagStatusUnbound = "unbound" // Printed but not yet attached to a site or asset
+	ScanTagStatusBound   = "bound"
+	ScanTagStatusRevoked = "revoked"
+)
+
+// ScanTag is a printed QR code that resolves to a site or asset. The code itself
+// only carries the organization and tag IDs plus a signature; what the tag points
+// at is looked up here, so blank tags can be printed first and bound on site.
+type ScanTag struct {
+	ID             string         `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
+	OrganizationID string         `json:"organization_id" gorm:"not null;index"`
+	EntityType     string         `json:"entity_type" gorm:"size:20;index:idx_scan_tags_entity"` // site, asset, or empty while unbound
+	EntityID       *string        `json:"entity_id" gorm:"type:uuid;index:idx_scan_tags_entity"`
+	Label          string         `json:"label" gorm:"size:255"`
+	Status         string         `json:"status" gorm:"size:20;not null;default:'unbound'"` // unbound, bound, revoked
+	BoundBy        *string        `json:"bound_by"`
+	BoundAt        *time.Time     `json:"bound_at"`
+	LastScannedAt  *time.Time     `json:"last_scanned_at"`
+	ScanCount      int            `json:"scan_count" gorm:"default:0"`
+	CreatedBy      string         `json:"created_by"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+}
+
+// TableName specifies the table name for ScanTag model
+func (ScanTag) TableName() string {
+	return "scan_tags"
+}
+
+// ScanResolution is what an inspector gets back after scanning a tag
+type ScanResolution struct {
+	Tag             ScanTag      `json:"tag"`
+	Status          string       `json:"status"` // bound, unbound
+	Site            *Site        `json:"site,omitempty"`
+	Asset           *Asset       `json:"asset,omitempty"`
+	Templates       []Template   `json:"templates"`
+	OpenInspections []Inspection `json:"open_inspections"`
+	CanBind         bool         `json:"can_bind"` // Unbound tags can be bound by supervisors and admins
+}
@@ -9,14 +9,19 @@ import (
 type Site struct {
 	ID             string         `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
 	OrganizationID string         `json:"organization_id" gorm:"not null;index"`
+	ExternalRef    *string        `json:"external_ref" gorm:"size:100;index"` // Customer's own identifier; bulk imports upsert on it
 	Name           string         `json:"name" gorm:"size:255;not null"`
 	Address        string         `json:"address" gorm:"size:500;not null"`
 	City           string         `json:"city" gorm:"size:100"`
 	State          string         `json:"state" gorm:"size:100"`
 	ZipCode        string         `json:"zip_code" gorm:"size:20"`
 	Country        string         `json:"country" gorm:"size:100;default:'USA'"`
+	Timezone       string         `json:"timezone" gorm:"size:64"` // IANA zone; empty uses the organization's
 	Latitude       *float64       `json:"latitude"`
 	Longitude      *float64       `json:"longitude"`
+	CoordinatesSource string      `json:"coordinates_source" gorm:"size:20"` // manual, geocoded; empty when there are no coordinates
+	GeocodedAt     *time.Time     `json:"geocoded_at"`
+	GeofenceRadius *int           `json:"geofence_radius_meters" gorm:"column:geofence_radius_meters"` // Check-in radius; nil uses the organization default
 	Type           string         `json:"type" gorm:"size:50"` // office, warehouse, construction, facility, etc.
 	Status         string         `json:"status" gorm:"size:50;default:'active'"` // active, inactive, maintenance
 	ContactName    string         `json:"contact_name" gorm:"size:255"`
@@ -47,6 +52,9 @@ type SiteStats struct {
 	NextInspectionDate   time.Time `json:"next_inspection_date"`
 	AverageScore         float64   `json:"average_score"`
 	CriticalIssues       int       `json:"critical_issues"`
+
+	// Locations breaks the counts down by top-level location, each rolled up over its subtree
+	Locations []LocationStats `json:"locations,omitempty"`
 }
 
 // TableName specifies the table name for Site model
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Site custom field types
+const (
+	CustomFieldText        = "text"
+	CustomFieldNumber      = "number"
+	CustomFieldBoolean     = "boolean"
+	CustomFieldDate        = "date" // YYYY-MM-DD
+	CustomFieldSelect      = "select"
+	CustomFieldMultiSelect = "multi_select"
+	CustomFieldEmail       = "email"
+	CustomFieldURL         = "url"
+)
+
+// SiteCustomField is one field of an organization's schema for Site.Metadata. Values are
+// stored in the site's metadata under Key.
+type SiteCustomField struct {
+	ID             string         `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
+	OrganizationID string         `json:"organization_id" gorm:"not null;index"`
+	Key            string         `json:"key" gorm:"size:64;not null"` // Unique per organization; lowercase letters, digits and underscores
+	Label          string         `json:"label" gorm:"size:255;not null"`
+	Type           string         `json:"type" gorm:"size:20;not null"` // text, number, boolean, date, select, multi_select, email, url
+	Description    string         `json:"description" gorm:"type:text"`
+	Required       bool           `json:"required"`
+	Unique         bool           `json:"unique"`                    // No two sites in the organization may share a value
+	Options        datatypes.JSON `json:"options" gorm:"type:jsonb"` // []string, for select and multi_select
+	Position       int            `json:"position"`
+	CreatedBy      string         `json:"created_by"`
+	UpdatedBy      string         `json:"updated_by"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+}
+
+// TableName specifies the table name for SiteCustomField model
+func (SiteCustomField) TableName() string {
+	return "site_custom_fields"
+}
+
+// OptionList returns the field's select options
+func (f *SiteCustomField) OptionList() []string {
+	var options []string
+	if len(f.Options) > 0 {
+		_ = json.Unmarshal(f.Options, &options)
+	}
+	return options
+}
+
+// UpdateSiteCustomFieldRequest changes a custom field. Changing the type, options or flags
+// re-checks every site: values that can be converted are migrated, the rest are flagged.
+type UpdateSiteCustomFieldRequest struct {
+	Label         *string           `json:"label"`
+	Type          *string           `json:"type"`
+	Description   *string           `json:"description"`
+	Required      *bool             `json:"required"`
+	Unique        *bool             `json:"unique"`
+	Options       []string          `json:"options"`
+	Position      *int              `json:"position"`
+	OptionRenames map[string]string `json:"option_renames"` // Old option to new, applied to stored values
+}
+
+// SiteFieldViolation flags a site whose metadata no longer conforms to its organization's
+// schema, usually after the schema changed. It is cleared when the site is fixed.
+type SiteFieldViolation struct {
+	ID             uint      `json:"id" gorm:"primarykey"`
+	OrganizationID string    `json:"organization_id" gorm:"not null;index"`
+	SiteID         string    `json:"site_id" gorm:"not null;index"`
+	FieldKey       string    `json:"field_key" gorm:"size:64;not null"`
+	Message        string    `json:"message" gorm:"size:500"`
+	DetectedAt     time.Time `json:"detected_at"`
+
+	Site *Site `json:"site,omitempty" gorm:"foreignKey:SiteID"`
+}
+
+// TableName specifies the table name for SiteFieldViolation model
+func (SiteFieldViolation) TableName() string {
+	return "site_field_violations"
+}
+
+// SiteCustomFieldMigration summarizes how existing sites fared after a schema change
+type SiteCustomFieldMigration struct {
+	FieldKey     string `json:"field_key"`
+	SitesChecked int    `json:"sites_checked"`
+	Migrated     int    `json:"migrated"` // Sites whose value was converted to fit the new definition
+	Flagged      int    `json:"flagged"`  // Sites that still don't conform
+}
@@ -0,0 +1,149 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Site document categories
+const (
+	SiteDocumentPermit      = "permit"
+	SiteDocumentInsurance   = "insurance"
+	SiteDocumentCertificate = "certificate"
+	SiteDocumentFloorPlan   = "floor_plan"
+	SiteDocumentOther       = "other"
+)
+
+// Site document expiry statuses, relative to today
+const (
+	SiteDocumentNoExpiry = "no_expiry"
+	SiteDocumentValid    = "valid"
+	SiteDocumentExpiring = "expiring" // Within the document's reminder window
+	SiteDocumentExpired  = "expired"
+)
+
+// SiteDocument is a file kept on record for a site, such as an operating permit or an
+// insurance certificate. Each upload adds a SiteDocumentVersion; the document carries the
+// dates of the current version.
+type SiteDocument struct {
+	ID             string         `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
+	OrganizationID string         `json:"organization_id" gorm:"not null;index"`
+	SiteID         string         `json:"site_id" gorm:"not null;index"`
+	Category       string         `json:"category" gorm:"size:50;not null"` // permit, insurance, certificate, floor_plan, other
+	Title          string         `json:"title" gorm:"size:255;not null"`
+	Description    string         `json:"description" gorm:"type:text"`
+	IssuedOn       *time.Time     `json:"issued_on" gorm:"type:date"`
+	ExpiresOn      *time.Time     `json:"expires_on" gorm:"type:date;index"` // Valid through this day; nil for documents that don't expire
+	ReminderDays   int            `json:"reminder_days"`                     // Site managers are notified this many days before expiry
+	AllowedRoles   datatypes.JSON `json:"allowed_roles" gorm:"type:jsonb"`   // []string of roles that may view; empty for every member
+	CurrentVersion int            `json:"current_version"`
+
+	// ReminderSentFor is the expiry date managers were last reminded about, so a renewed
+	// document gets a fresh reminder
+	ReminderSentFor *time.Time `json:"reminder_sent_for,omitempty" gorm:"type:date"`
+
+	CreatedBy string    `json:"created_by"`
+	UpdatedBy string    `json:"updated_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Computed
+	ExpiryStatus string `json:"expiry_status" gorm:"-"`
+
+	// Relationships
+	Site     *Site                 `json:"site,omitempty" gorm:"foreignKey:SiteID"`
+	Versions []SiteDocumentVersion `json:"versions,omitempty" gorm:"foreignKey:DocumentID"`
+}
+
+// TableName specifies the table name for SiteDocument model
+func (SiteDocument) TableName() string {
+	return "site_documents"
+}
+
+// RoleList returns the roles allowed to view the document
+func (d *SiteDocument) RoleList() []string {
+	var roles []string
+	if len(d.AllowedRoles) > 0 {
+		_ = json.Unmarshal(d.AllowedRoles, &roles)
+	}
+	return roles
+}
+
+// StatusOn returns the document's expiry status on the given day
+func (d *SiteDocument) StatusOn(now time.Time) string {
+	if d.ExpiresOn == nil {
+		return SiteDocumentNoExpiry
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	expiresOn := time.Date(d.ExpiresOn.Year(), d.ExpiresOn.Month(), d.ExpiresOn.Day(), 0, 0, 0, 0, time.UTC)
+	switch {
+	case expiresOn.Before(today):
+		return SiteDocumentExpired
+	case !expiresOn.After(today.AddDate(0, 0, d.ReminderDays)):
+		return SiteDocumentExpiring
+	default:
+		return SiteDocumentValid
+	}
+}
+
+// SiteDocumentVersion is one uploaded file of a SiteDocument. Versions are never changed;
+// a correction is uploaded as a new version.
+type SiteDocumentVersion struct {
+	ID             string     `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
+	OrganizationID string     `json:"organization_id" gorm:"not null;index"`
+	DocumentID     string     `json:"document_id" gorm:"not null;index"`
+	Version        int        `json:"version" gorm:"not null"`
+	FileName       string     `json:"file_name" gorm:"size:255;not null"`
+	StoragePath    string     `json:"-" gorm:"size:500;not null"`
+	FileSize       int64      `json:"file_size"`
+	MimeType       string     `json:"mime_type" gorm:"size:100"`
+	IssuedOn       *time.Time `json:"issued_on" gorm:"type:date"`
+	ExpiresOn      *time.Time `json:"expires_on" gorm:"type:date"`
+	Notes          string     `json:"notes" gorm:"type:text"`
+	UploadedBy     string     `json:"uploaded_by"`
+	UploadedAt     time.Time  `json:"uploaded_at"`
+}
+
+// TableName specifies the table name for SiteDocumentVersion model
+func (SiteDocumentVersion) TableName() string {
+	return "site_document_versions"
+}
+
+// SiteDocumentUpload describes a file being added as a new document or a new version
+type SiteDocumentUpload struct {
+	Category     string     `json:"category"`
+	Title        string     `json:"title"`
+	Description  string     `json:"description"`
+	IssuedOn     *time.Time `json:"issued_on"`
+	ExpiresOn    *time.Time `json:"expires_on"`
+	ReminderDays *int       `json:"reminder_days"`
+	AllowedRoles []string   `json:"allowed_roles"`
+	Notes        string     `json:"notes"`
+}
+
+// UpdateSiteDocumentRequest changes a document's details without uploading a file
+type UpdateSiteDocumentRequest struct {
+	Category     *string  `json:"category"`
+	Title        *string  `json:"title"`
+	Description  *string  `json:"description"`
+	ReminderDays *int     `json:"reminder_days"`
+	AllowedRoles []string `json:"allowed_roles"`
+}
+
+// SiteDocumentCompliance lists a site's documents that are expired or about to expire
+type SiteDocumentCompliance struct {
+	SiteID   string         `json:"site_id"`
+	SiteName string         `json:"site_name"`
+	Expired  []SiteDocument `json:"expired"`
+	Expiring []SiteDocument `json:"expiring"`
+}
+
+// SiteDocumentExpiryCheck summarizes one run of the expiry check
+type SiteDocumentExpiryCheck struct {
+	DocumentsChecked int `json:"documents_checked"`
+	Reminded         int `json:"reminded"`
+	AlertsRaised     int `json:"alerts_raised"`
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Site import job statuses
+const (
+	SiteImportStatusPending   = "pending"
+	SiteImportStatusRunning   = "running"
+	SiteImportStatusCompleted = "completed"
+	SiteImportStatusFailed    = "failed"
+)
+
+// SiteImportJob tracks a bulk site import running in the background
+type SiteImportJob struct {
+	ID             string         `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
+	OrganizationID string         `json:"organization_id" gorm:"not null;index"`
+	Status         string         `json:"status" gorm:"size:20;not null;default:'pending'"` // pending, running, completed, failed
+	Format         string         `json:"format" gorm:"size:10"`                            // csv, xlsx
+	FileName       string         `json:"file_name" gorm:"size:255"`
+	TotalRows      int            `json:"total_rows"`
+	ProcessedRows  int            `json:"processed_rows"`
+	CreatedCount   int            `json:"created_count"`
+	UpdatedCount   int            `json:"updated_count"`
+	Errors         datatypes.JSON `json:"errors" gorm:"type:jsonb"` // []SiteImportRowError
+	ErrorMessage   string         `json:"error_message" gorm:"type:text"`
+	CreatedBy      string         `json:"created_by"`
+	StartedAt      *time.Time     `json:"started_at"`
+	FinishedAt     *time.Time     `json:"finished_at"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+}
+
+// TableName specifies the table name for SiteImportJob model
+func (SiteImportJob) TableName() string {
+	return "site_import_jobs"
+}
+
+// Progress returns the share of rows processed, from 0 to 100
+func (j *SiteImportJob) Progress() int {
+	if j.TotalRows == 0 {
+		if j.Status == SiteImportStatusCompleted {
+			return 100
+		}
+		return 0
+	}
+	return j.ProcessedRows * 100 / j.TotalRows
+}
+
+// SiteImportRowError is a validation problem with one row of an import file.
+// Row numbers count the header as row 1, matching what spreadsheet users see.
+type SiteImportRowError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// SiteImportDuplicate is a row that looks like another row or an existing site
+type SiteImportDuplicate struct {
+	Row            int    `json:"row"`
+	MatchedOn      string `json:"matched_on"`                 // name, address
+	ExistingSiteID string `json:"existing_site_id,omitempty"` // Set when the match is an existing site
+	OtherRow       int    `json:"other_row,omitempty"`        // Set when the match is another row in the file
+}
+
+// SiteImportPreview is the result of a dry run
+type SiteImportPreview struct {
+	TotalRows  int                   `json:"total_rows"`
+	ValidRows  int                   `json:"valid_rows"`
+	Creates    int                   `json:"creates"`
+	Updates    int                   `json:"updates"`
+	Errors     []SiteImportRowError  `json:"errors"`
+	Duplicates []SiteImportDuplicate `json:"duplicates"`
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Site merge statuses
+const (
+	SiteMergeStatusMerged = "merged"
+	SiteMergeStatusUndone = "undone"
+)
+
+// SiteDuplicateCandidate is a pair of sites that look like the same place. Site is the
+// older of the two and the suggested survivor of a merge.
+type SiteDuplicateCandidate struct {
+	Site           Site     `json:"site"`
+	Duplicate      Site     `json:"duplicate"`
+	Score          float64  `json:"score"` // 0 to 1, combining the scores below
+	NameScore      float64  `json:"name_score"`
+	AddressScore   float64  `json:"address_score"`
+	DistanceMeters *float64 `json:"distance_meters"` // Nil when either site has no coordinates
+}
+
+// SiteMerge records one site being merged into another. Everything that pointed at the
+// merged site is re-pointed at the survivor and the merged site is soft-deleted; the
+// record keeps what was moved so the merge can be undone until UndoDeadline.
+type SiteMerge struct {
+	ID             string `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
+	OrganizationID string `json:"organization_id" gorm:"not null;index"`
+	SurvivorID     string `json:"survivor_id" gorm:"type:uuid;not null;index"`
+	MergedID       string `json:"merged_id" gorm:"type:uuid;not null;index"`
+	Status         string `json:"status" gorm:"size:20;not null"` // merged, undone
+
+	// Counts is the number of rows re-pointed per table
+	Counts datatypes.JSON `json:"counts" gorm:"type:jsonb"`
+	// MovedRows is the IDs re-pointed per table, and PreviousSiteIDs and PreviousPoolSiteIDs
+	// each assignment's and open pool item's site_ids before the merge, for undo
+	MovedRows           datatypes.JSON `json:"-" gorm:"type:jsonb"`
+	PreviousSiteIDs     datatypes.JSON `json:"-" gorm:"type:jsonb"`
+	PreviousPoolSiteIDs datatypes.JSON `json:"-" gorm:"type:jsonb"`
+
+	MergedBy     string     `json:"merged_by"`
+	MergedAt     time.Time  `json:"merged_at"`
+	UndoDeadline time.Time  `json:"undo_deadline"`
+	UndoneBy     *string    `json:"undone_by"`
+	UndoneAt     *time.Time `json:"undone_at"`
+}
+
+// TableName specifies the table name for SiteMerge model
+func (SiteMerge) TableName() string {
+	return "site_merges"
+}
+
+// CanUndo reports whether the merge can still be undone at the given time
+func (m *SiteMerge) CanUndo(now time.Time) bool {
+	return m.Status == SiteMergeStatusMerged && now.Before(m.UndoDeadline)
+}
+
+// MergeSitesRequest asks for MergedID to be folded into SurvivorID
+type MergeSitesRequest struct {
+	SurvivorID string `json:"survivor_id" binding:"required"`
+	MergedID   string `json:"merged_id" binding:"required"`
+}
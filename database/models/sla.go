@@ -0,0 +1,161 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// SLA clock stages
+const (
+	SLAStageAccept   = "accept"   // Assignment accepted by the inspector
+	SLAStageStart    = "start"    // Work started
+	SLAStageComplete = "complete" // Work completed
+	SLAStageReview   = "review"   // Completed inspection approved or rejected
+)
+
+// SLA clock statuses
+const (
+	SLAClockRunning   = "running"
+	SLAClockPaused    = "paused" // Waiting on an external party; the deadline moves by the time spent paused
+	SLAClockMet       = "met"    // The stage was reached, on time or late
+	SLAClockCancelled = "cancelled"
+)
+
+// SLA targets
+const (
+	SLATargetAssignment = "assignment"
+	SLATargetInspection = "inspection"
+)
+
+// SLA escalation recipients
+const (
+	SLANotifyAssignee       = "assignee"
+	SLANotifySupervisor     = "supervisor"
+	SLANotifyProjectManager = "project_manager"
+)
+
+// SLAPolicy sets an organization's response targets for work of a priority and inspection
+// type (template category). An empty priority or inspection type matches any; the most
+// specific active policy applies. A target of 0 minutes means the stage isn't tracked.
+type SLAPolicy struct {
+	ID                    string         `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
+	OrganizationID        string         `json:"organization_id" gorm:"not null;uniqueIndex:idx_sla_policies_scope"`
+	Name                  string         `json:"name" gorm:"size:255;not null"`
+	Priority              string         `json:"priority" gorm:"size:50;uniqueIndex:idx_sla_policies_scope"`
+	InspectionType        string         `json:"inspection_type" gorm:"size:100;uniqueIndex:idx_sla_policies_scope"`
+	TimeToAcceptMinutes   int            `json:"time_to_accept_minutes"`
+	TimeToStartMinutes    int            `json:"time_to_start_minutes"`
+	TimeToCompleteMinutes int            `json:"time_to_complete_minutes"`
+	TimeToReviewMinutes   int            `json:"time_to_review_minutes"`
+	Escalation            datatypes.JSON `json:"escalation" gorm:"type:jsonb;not null"` // []SLAEscalationStep
+	IsActive              bool           `json:"is_active" gorm:"default:true"`
+	CreatedBy             string         `json:"created_by"`
+	CreatedAt             time.Time      `json:"created_at"`
+	UpdatedAt             time.Time      `json:"updated_at"`
+}
+
+// TableName specifies the table name for SLAPolicy model
+func (SLAPolicy) TableName() string {
+	return "sla_policies"
+}
+
+// SLAEscalationStep is one rung of a policy's escalation ladder. The step fires
+// OffsetMinutes after a clock's deadline; a negative offset is a reminder before it.
+type SLAEscalationStep struct {
+	Notify        string `json:"notify"` // assignee, supervisor, project_manager
+	OffsetMinutes int    `json:"offset_minutes"`
+}
+
+// SLAClock times one stage of an assignment or inspection against its policy. Recipients
+// are kept up to date as the work is reassigned, so escalations reach whoever holds it.
+type SLAClock struct {
+	ID               string     `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
+	OrganizationID   string     `json:"organization_id" gorm:"not null;index"`
+	PolicyID         string     `json:"policy_id" gorm:"not null;index"`
+	TargetType       string     `json:"target_type" gorm:"size:20;not null;uniqueIndex:idx_sla_clocks_target"` // assignment, inspection
+	TargetID         string     `json:"target_id" gorm:"not null;uniqueIndex:idx_sla_clocks_target"`
+	Stage            string     `json:"stage" gorm:"size:20;not null;uniqueIndex:idx_sla_clocks_target"` // accept, start, complete, review
+	Status           string     `json:"status" gorm:"size:20;not null;index"`                            // running, paused, met, cancelled
+	Priority         string     `json:"priority" gorm:"size:50"`
+	InspectionType   string     `json:"inspection_type" gorm:"size:100"`
+	StartedAt        time.Time  `json:"started_at" gorm:"not null"`
+	DeadlineAt       time.Time  `json:"deadline_at" gorm:"not null"`
+	PausedAt         *time.Time `json:"paused_at"`
+	PauseReason      string     `json:"pause_reason" gorm:"type:text"`
+	PausedSeconds    int64      `json:"paused_seconds" gorm:"default:0"`
+	EscalationLevel  int        `json:"escalation_level" gorm:"default:0"` // Escalation steps already fired
+	BreachedAt       *time.Time `json:"breached_at"`
+	StoppedAt        *time.Time `json:"stopped_at"`
+	AssigneeID       string     `json:"assignee_id"`
+	SupervisorID     string     `json:"supervisor_id"`
+	ProjectManagerID string     `json:"project_manager_id"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// TableName specifies the table name for SLAClock model
+func (SLAClock) TableName() string {
+	return "sla_clocks"
+}
+
+// SLABreach records a clock passing its deadline, for reporting. MetAt is set once the
+// stage is finally reached. A clock restarted after its stage was undone can breach again.
+type SLABreach struct {
+	ID             string     `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
+	OrganizationID string     `json:"organization_id" gorm:"not null;index"`
+	ClockID        string     `json:"clock_id" gorm:"not null;index"`
+	PolicyID       string     `json:"policy_id" gorm:"not null;index"`
+	TargetType     string     `json:"target_type" gorm:"size:20;not null"`
+	TargetID       string     `json:"target_id" gorm:"not null"`
+	Stage          string     `json:"stage" gorm:"size:20;not null"`
+	Priority       string     `json:"priority" gorm:"size:50"`
+	InspectionType string     `json:"inspection_type" gorm:"size:100"`
+	AssigneeID     string     `json:"assignee_id" gorm:"index"`
+	DeadlineAt     time.Time  `json:"deadline_at" gorm:"not null"`
+	BreachedAt     time.Time  `json:"breached_at" gorm:"not null;index"`
+	MetAt          *time.Time `json:"met_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// TableName specifies the table name for SLABreach model
+func (SLABreach) TableName() string {
+	return "sla_breaches"
+}
+
+// SLAPolicyRequest creates or replaces an SLA policy
+type SLAPolicyRequest struct {
+	Name                  string              `json:"name" binding:"required"`
+	Priority              string              `json:"priority"`
+	InspectionType        string              `json:"inspection_type"`
+	TimeToAcceptMinutes   int                 `json:"time_to_accept_minutes"`
+	TimeToStartMinutes    int                 `json:"time_to_start_minutes"`
+	TimeToCompleteMinutes int                 `json:"time_to_complete_minutes"`
+	TimeToReviewMinutes   int                 `json:"time_to_review_minutes"`
+	Escalation            []SLAEscalationStep `json:"escalation"` // Defaults to assignee, supervisor, project manager
+	IsActive              *bool               `json:"is_active"`  // Defaults to true
+}
+
+// PauseSLARequest says why an assignment's or inspection's clocks are paused
+type PauseSLARequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// SLABreachSummary counts breaches by one dimension of a breach report
+type SLABreachSummary struct {
+	Key      string `json:"key"`
+	Breaches int    `json:"breaches"`
+	Open     int    `json:"open"` // Stage not reached yet
+}
+
+// SLABreachReport lists an organization's breaches over a period with totals by stage,
+// priority and inspection type
+type SLABreachReport struct {
+	From             time.Time          `json:"from"`
+	To               time.Time          `json:"to"`
+	Total            int                `json:"total"`
+	ByStage          []SLABreachSummary `json:"by_stage"`
+	ByPriority       []SLABreachSummary `json:"by_priority"`
+	ByInspectionType []SLABreachSummary `json:"by_inspection_type"`
+	Breaches         []SLABreach        `json:"breaches"`
+}
@@ -0,0 +1,89 @@
+package models
+
+import "time"
+
+// Team is a group of an organization's members, such as a regional crew. Its lead sees and
+// dispatches the team's work without organization-wide permissions, and work queued for
+// the team can be taken by any member.
+type Team struct {
+	ID             string    `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
+	OrganizationID string    `json:"organization_id" gorm:"not null;index"`
+	Name           string    `json:"name" gorm:"size:255;not null"`
+	Description    string    `json:"description" gorm:"type:text"`
+	LeadID         *string   `json:"lead_id"` // Always a member of the team
+	IsActive       bool      `json:"is_active" gorm:"default:true"`
+	CreatedBy      string    `json:"created_by"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+
+	// Relationships
+	Lead    *GlobalUser  `json:"lead,omitempty" gorm:"foreignKey:LeadID"`
+	Members []TeamMember `json:"members,omitempty" gorm:"foreignKey:TeamID"`
+}
+
+// TableName specifies the table name for Team model
+func (Team) TableName() string {
+	return "teams"
+}
+
+// TeamMember is a member of a team. A user can belong to several teams.
+type TeamMember struct {
+	ID             string    `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
+	OrganizationID string    `json:"organization_id" gorm:"not null;index"`
+	TeamID         string    `json:"team_id" gorm:"not null;index"`
+	UserID         string    `json:"user_id" gorm:"not null;index"`
+	AddedBy        string    `json:"added_by"`
+	JoinedAt       time.Time `json:"joined_at"`
+
+	// Relationships
+	User *GlobalUser `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// TableName specifies the table name for TeamMember model
+func (TeamMember) TableName() string {
+	return "team_members"
+}
+
+// TeamRequest creates or updates a team
+type TeamRequest struct {
+	Name        string  `json:"name" binding:"required"`
+	Description string  `json:"description"`
+	LeadID      *string `json:"lead_id"` // Empty string clears the lead
+	IsActive    *bool   `json:"is_active"`
+}
+
+// TeamMembersRequest replaces a team's members. The lead stays a member.
+type TeamMembersRequest struct {
+	UserIDs []string `json:"user_ids"`
+}
+
+// QueueToTeamRequest moves work into a team's queue, taking it away from whoever held it.
+// Inspections that belong to an assignment are queued with their assignment.
+type QueueToTeamRequest struct {
+	AssignmentIDs []string `json:"assignment_ids"`
+	InspectionIDs []string `json:"inspection_ids"`
+	Reason        string   `json:"reason"`
+}
+
+// TakeFromQueueRequest takes one assignment or inspection out of a team's queue. Members
+// take work for themselves; the lead and supervisors can hand it to any member.
+type TakeFromQueueRequest struct {
+	AssignmentID string `json:"assignment_id"`
+	InspectionID string `json:"inspection_id"`
+	InspectorID  string `json:"inspector_id"` // Defaults to the caller
+}
+
+// TeamQueue is the work waiting in a team's queue, oldest first
+type TeamQueue struct {
+	TeamID      string                 `json:"team_id"`
+	Assignments []InspectionAssignment `json:"assignments"`
+	Inspections []Inspection           `json:"inspections"`
+}
+
+// TeamWorkload is a team's members' workloads and what is waiting in its queue
+type TeamWorkload struct {
+	TeamID            string              `json:"team_id"`
+	Members           []InspectorWorkload `json:"members"`
+	QueuedAssignments int64               `json:"queued_assignments"`
+	QueuedInspections int64               `json:"queued_inspections"`
+}
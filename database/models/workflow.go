@@ -120,14 +120,15 @@ type InspectionAssignment struct {
 	// Assignment Details
 	Name              string         `json:"name" gorm:"size:255;not null"`
 	Description       string         `json:"description" gorm:"type:text"`
-	AssignmentType    string         `json:"assignment_type" gorm:"size:100;default:'manual'"` // manual, auto, bulk, project
+	AssignmentType    string         `json:"assignment_type" gorm:"size:100;default:'manual'"` // manual, auto, bulk, project, self
 	Status            string         `json:"status" gorm:"size:50;default:'pending'"` // pending, active, completed, cancelled
 	Priority          string         `json:"priority" gorm:"size:50;default:'medium'"` // low, medium, high, critical
 
 	// Assignment Source
 	AssignedBy        string         `json:"assigned_by" gorm:"not null"` // Who created the assignment
-	AssignedTo        string         `json:"assigned_to" gorm:"not null"` // Inspector assigned to
+	AssignedTo        string         `json:"assigned_to"` // Inspector assigned to; empty while waiting in a team queue
 	DelegatedFrom     *string        `json:"delegated_from"` // If reassigned/delegated
+	TeamID            *string        `json:"team_id" gorm:"index"` // Team whose queue it was put in
 
 	// Timeline
 	AssignedAt        time.Time      `json:"assigned_at" gorm:"default:current_timestamp()"`
@@ -250,11 +251,21 @@ type InspectorWorkload struct {
 	AverageInspectionTime int        `json:"average_inspection_time" gorm:"default:240"` // minutes
 	QualityScore          float64    `json:"quality_score" gorm:"default:0"` // Average quality score
 
+	// Running totals behind the performance metrics
+	DueCompletions        int        `json:"due_completions" gorm:"default:0"` // Completed inspections that had a due date
+	OnTimeCompletions     int        `json:"on_time_completions" gorm:"default:0"`
+	TimedInspections      int        `json:"timed_inspections" gorm:"default:0"` // Completed inspections with a start time
+	InspectionMinutes     float64    `json:"inspection_minutes" gorm:"default:0"`
+	ScoredReviews         int        `json:"scored_reviews" gorm:"default:0"`
+	QualityScoreTotal     float64    `json:"quality_score_total" gorm:"default:0"`
+
 	// Preferences
 	PreferredSiteTypes    datatypes.JSON `json:"preferred_site_types" gorm:"type:jsonb;default:'[]'"`
 	PreferredRegions      datatypes.JSON `json:"preferred_regions" gorm:"type:jsonb;default:'[]'"`
 	Specializations       datatypes.JSON `json:"specializations" gorm:"type:jsonb;default:'[]'"`
 	MaxTravelDistance     int            `json:"max_travel_distance" gorm:"default:50"` // kilometers
+	BaseLatitude          *float64       `json:"base_latitude"` // Where travel distance is measured from
+	BaseLongitude         *float64       `json:"base_longitude"`
 
 	LastUpdated           time.Time      `json:"last_updated" gorm:"default:current_timestamp()"`
 	CreatedAt             time.Time      `json:"created_at"`
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+)
+
+// InspectorWorkloadHistory is one day's snapshot of an inspector's workload, taken by the
+// nightly reconciliation so capacity trends can be charted over time
+type InspectorWorkloadHistory struct {
+	ID                    string    `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
+	OrganizationID        string    `json:"organization_id" gorm:"not null;uniqueIndex:idx_inspector_workload_history_day"`
+	InspectorID           string    `json:"inspector_id" gorm:"not null;uniqueIndex:idx_inspector_workload_history_day"`
+	Date                  time.Time `json:"date" gorm:"type:date;not null;uniqueIndex:idx_inspector_workload_history_day"`
+	DailyLoad             int       `json:"daily_load"`
+	WeeklyLoad            int       `json:"weekly_load"`
+	MaxDailyInspections   int       `json:"max_daily_inspections"`
+	MaxWeeklyInspections  int       `json:"max_weekly_inspections"`
+	Utilization           float64   `json:"utilization"` // Weekly load as a percentage of weekly capacity
+	ActiveProjects        int       `json:"active_projects"`
+	PendingAssignments    int       `json:"pending_assignments"`
+	OverdueInspections    int       `json:"overdue_inspections"`
+	CompletionRate        float64   `json:"completion_rate"`
+	AverageInspectionTime int       `json:"average_inspection_time"` // minutes
+	QualityScore          float64   `json:"quality_score"`
+	CreatedAt             time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for InspectorWorkloadHistory model
+func (InspectorWorkloadHistory) TableName() string {
+	return "inspector_workload_history"
+}
+
+// WorkloadReconcileResult summarizes a reconciliation run
+type WorkloadReconcileResult struct {
+	Inspectors int `json:"inspectors"` // Workloads recalculated
+	Corrected  int `json:"corrected"`  // Workloads whose running totals had drifted
+	Snapshots  int `json:"snapshots"`  // History rows written
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"resource-mgmt/pkg/database"
 	"resource-mgmt/pkg/tenant"
 
 	"github.com/google/uuid"
@@ -48,7 +49,7 @@ func (r *BaseRepositoryImpl[T]) Create(ctx context.Context, entity *T) error {
 	// Set created_by if the entity has this field
 	r.setCreatedBy(entity, tenantCtx.UserID)
 
-	return r.db.WithContext(ctx).Create(entity).Error
+	return database.Conn(ctx, r.db).Create(entity).Error
 }
 
 // GetByID retrieves an entity by ID within tenant scope
@@ -63,7 +64,7 @@ func (r *BaseRepositoryImpl[T]) GetByID(ctx context.Context, id uint) (*T, error
 	}
 
 	var entity T
-	err = r.buildTenantQuery(organizationID).First(&entity, id).Error
+	err = r.buildTenantQuery(ctx, organizationID).First(&entity, id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, fmt.Errorf("record not found in organization scope")
@@ -88,7 +89,7 @@ func (r *BaseRepositoryImpl[T]) GetAll(ctx context.Context, filters map[string]i
 	var entities []T
 	var total int64
 
-	query := r.buildTenantQuery(organizationID)
+	query := r.buildTenantQuery(ctx, organizationID)
 
 	// Apply additional filters
 	for key, value := range filters {
@@ -126,7 +127,7 @@ func (r *BaseRepositoryImpl[T]) Update(ctx context.Context, id uint, updates map
 	// Prevent organization_id updates
 	delete(updates, "organization_id")
 
-	result := r.buildTenantQuery(organizationID).Where("id = ?", id).Updates(updates)
+	result := r.buildTenantQuery(ctx, organizationID).Where("id = ?", id).Updates(updates)
 	if result.Error != nil {
 		return result.Error
 	}
@@ -149,7 +150,7 @@ func (r *BaseRepositoryImpl[T]) Delete(ctx context.Context, id uint) error {
 		return fmt.Errorf("tenant context required: %w", err)
 	}
 
-	result := r.buildTenantQuery(organizationID).Where("id = ?", id).Delete(new(T))
+	result := r.buildTenantQuery(ctx, organizationID).Where("id = ?", id).Delete(new(T))
 	if result.Error != nil {
 		return result.Error
 	}
@@ -172,7 +173,7 @@ func (r *BaseRepositoryImpl[T]) Count(ctx context.Context, filters map[string]in
 		return 0, fmt.Errorf("tenant context required: %w", err)
 	}
 
-	query := r.buildTenantQuery(organizationID)
+	query := r.buildTenantQuery(ctx, organizationID)
 
 	// Apply additional filters
 	for key, value := range filters {
@@ -198,7 +199,7 @@ func (r *BaseRepositoryImpl[T]) Exists(ctx context.Context, id uint) (bool, erro
 	}
 
 	var count int64
-	err = r.buildTenantQuery(organizationID).Where("id = ?", id).Model(new(T)).Count(&count).Error
+	err = r.buildTenantQuery(ctx, organizationID).Where("id = ?", id).Model(new(T)).Count(&count).Error
 	if err != nil {
 		return false, err
 	}
@@ -218,7 +219,7 @@ func (r *BaseRepositoryImpl[T]) GetByUUID(ctx context.Context, id uuid.UUID) (*T
 	}
 
 	var entity T
-	err = r.buildTenantQuery(organizationID).Where("id = ?", id).First(&entity).Error
+	err = r.buildTenantQuery(ctx, organizationID).Where("id = ?", id).First(&entity).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, fmt.Errorf("record not found in organization scope")
@@ -243,7 +244,7 @@ func (r *BaseRepositoryImpl[T]) UpdateByUUID(ctx context.Context, id uuid.UUID,
 	// Prevent organization_id updates
 	delete(updates, "organization_id")
 
-	result := r.buildTenantQuery(organizationID).Where("id = ?", id).Updates(updates)
+	result := r.buildTenantQuery(ctx, organizationID).Where("id = ?", id).Updates(updates)
 	if result.Error != nil {
 		return result.Error
 	}
@@ -266,7 +267,7 @@ func (r *BaseRepositoryImpl[T]) DeleteByUUID(ctx context.Context, id uuid.UUID)
 		return fmt.Errorf("tenant context required: %w", err)
 	}
 
-	result := r.buildTenantQuery(organizationID).Where("id = ?", id).Delete(new(T))
+	result := r.buildTenantQuery(ctx, organizationID).Where("id = ?", id).Delete(new(T))
 	if result.Error != nil {
 		return result.Error
 	}
@@ -290,7 +291,7 @@ func (r *BaseRepositoryImpl[T]) ExistsByUUID(ctx context.Context, id uuid.UUID)
 	}
 
 	var count int64
-	err = r.buildTenantQuery(organizationID).Where("id = ?", id).Model(new(T)).Count(&count).Error
+	err = r.buildTenantQuery(ctx, organizationID).Where("id = ?", id).Model(new(T)).Count(&count).Error
 	if err != nil {
 		return false, err
 	}
@@ -299,8 +300,8 @@ func (r *BaseRepositoryImpl[T]) ExistsByUUID(ctx context.Context, id uuid.UUID)
 }
 
 // buildTenantQuery builds a query with organization_id filter
-func (r *BaseRepositoryImpl[T]) buildTenantQuery(organizationID string) *gorm.DB {
-	return r.db.Where("organization_id = ?", organizationID)
+func (r *BaseRepositoryImpl[T]) buildTenantQuery(ctx context.Context, organizationID string) *gorm.DB {
+	return database.Conn(ctx, r.db).Where("organization_id = ?", organizationID)
 }
 
 // setOrganizationID sets the organization_id field using reflection
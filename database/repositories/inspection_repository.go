@@ -5,11 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"resource-mgmt/models"
+	"resource-mgmt/pkg/database"
 	"resource-mgmt/pkg/tenant"
-	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // InspectionRepositoryImpl implements InspectionRepository with tenant isolation
@@ -24,6 +25,11 @@ func NewInspectionRepository(db *gorm.DB) InspectionRepository {
 	}
 }
 
+// conn returns the request's tenant transaction when one is active
+func (r *InspectionRepositoryImpl) conn(ctx context.Context) *gorm.DB {
+	return database.Conn(ctx, r.db)
+}
+
 // Create creates a new inspection within tenant scope
 func (r *InspectionRepositoryImpl) Create(ctx context.Context, entity *models.Inspection) error {
 	if r.db == nil {
@@ -36,7 +42,7 @@ func (r *InspectionRepositoryImpl) Create(ctx context.Context, entity *models.In
 	}
 
 	entity.OrganizationID = organizationID
-	return r.db.Create(entity).Error
+	return r.conn(ctx).Create(entity).Error
 }
 
 // GetByID retrieves an inspection by ID within tenant scope
@@ -51,7 +57,7 @@ func (r *InspectionRepositoryImpl) GetByID(ctx context.Context, id uint) (*model
 	}
 
 	var inspection models.Inspection
-	err = r.db.Where("organization_id = ? AND id = ?", organizationID, id).
+	err = r.conn(ctx).Where("organization_id = ? AND id = ?", organizationID, id).
 		Preload("Template").
 		Preload("Inspector").
 		Preload("Site").
@@ -81,7 +87,7 @@ func (r *InspectionRepositoryImpl) GetByUUID(ctx context.Context, id uuid.UUID)
 	}
 
 	var inspection models.Inspection
-	err = r.db.Where("organization_id = ? AND id = ?", organizationID, id).
+	err = r.conn(ctx).Where("organization_id = ? AND id = ?", organizationID, id).
 		Preload("Template").
 		Preload("Inspector").
 		Preload("Site").
@@ -113,10 +119,14 @@ func (r *InspectionRepositoryImpl) GetAll(ctx context.Context, filters map[strin
 	var inspections []models.Inspection
 	var total int64
 
-	query := r.db.Where("organization_id = ?", organizationID)
+	query := r.conn(ctx).Where("organization_id = ?", organizationID)
 
-	// Apply filters
+	// Apply filters; expressions such as a user's team visibility are applied as they are
 	for key, value := range filters {
+		if expr, ok := value.(clause.Expr); ok {
+			query = query.Where(expr)
+			continue
+		}
 		query = query.Where(fmt.Sprintf("%s = ?", key), value)
 	}
 
@@ -156,7 +166,7 @@ func (r *InspectionRepositoryImpl) Update(ctx context.Context, id uint, updates
 		return fmt.Errorf("tenant context required: %w", err)
 	}
 
-	result := r.db.Model(&models.Inspection{}).Where("organization_id = ? AND id = ?", organizationID, id).Updates(updates)
+	result := r.conn(ctx).Model(&models.Inspection{}).Where("organization_id = ? AND id = ?", organizationID, id).Updates(updates)
 	if result.Error != nil {
 		return result.Error
 	}
@@ -179,7 +189,7 @@ func (r *InspectionRepositoryImpl) UpdateByUUID(ctx context.Context, id uuid.UUI
 		return fmt.Errorf("tenant context required: %w", err)
 	}
 
-	result := r.db.Model(&models.Inspection{}).Where("organization_id = ? AND id = ?", organizationID, id).Updates(updates)
+	result := r.conn(ctx).Model(&models.Inspection{}).Where("organization_id = ? AND id = ?", organizationID, id).Updates(updates)
 	if result.Error != nil {
 		return result.Error
 	}
@@ -202,7 +212,7 @@ func (r *InspectionRepositoryImpl) Delete(ctx context.Context, id uint) error {
 		return fmt.Errorf("tenant context required: %w", err)
 	}
 
-	result := r.db.Where("organization_id = ? AND id = ?", organizationID, id).Delete(&models.Inspection{})
+	result := r.conn(ctx).Where("organization_id = ? AND id = ?", organizationID, id).Delete(&models.Inspection{})
 	if result.Error != nil {
 		return result.Error
 	}
@@ -225,7 +235,7 @@ func (r *InspectionRepositoryImpl) DeleteByUUID(ctx context.Context, id uuid.UUI
 		return fmt.Errorf("tenant context required: %w", err)
 	}
 
-	result := r.db.Where("organization_id = ? AND id = ?", organizationID, id).Delete(&models.Inspection{})
+	result := r.conn(ctx).Where("organization_id = ? AND id = ?", organizationID, id).Delete(&models.Inspection{})
 	if result.Error != nil {
 		return result.Error
 	}
@@ -249,10 +259,14 @@ func (r *InspectionRepositoryImpl) Count(ctx context.Context, filters map[string
 	}
 
 	var count int64
-	query := r.db.Model(&models.Inspection{}).Where("organization_id = ?", organizationID)
+	query := r.conn(ctx).Model(&models.Inspection{}).Where("organization_id = ?", organizationID)
 
-	// Apply filters
+	// Apply filters; expressions such as a user's team visibility are applied as they are
 	for key, value := range filters {
+		if expr, ok := value.(clause.Expr); ok {
+			query = query.Where(expr)
+			continue
+		}
 		query = query.Where(fmt.Sprintf("%s = ?", key), value)
 	}
 
@@ -272,7 +286,7 @@ func (r *InspectionRepositoryImpl) Exists(ctx context.Context, id uint) (bool, e
 	}
 
 	var count int64
-	err = r.db.Model(&models.Inspection{}).Where("organization_id = ? AND id = ?", organizationID, id).Count(&count).Error
+	err = r.conn(ctx).Model(&models.Inspection{}).Where("organization_id = ? AND id = ?", organizationID, id).Count(&count).Error
 	return count > 0, err
 }
 
@@ -288,7 +302,7 @@ func (r *InspectionRepositoryImpl) ExistsByUUID(ctx context.Context, id uuid.UUI
 	}
 
 	var count int64
-	err = r.db.Model(&models.Inspection{}).Where("organization_id = ? AND id = ?", organizationID, id).Count(&count).Error
+	err = r.conn(ctx).Model(&models.Inspection{}).Where("organization_id = ? AND id = ?", organizationID, id).Count(&count).Error
 	return count > 0, err
 }
 
@@ -306,7 +320,7 @@ func (r *InspectionRepositoryImpl) GetByInspector(ctx context.Context, inspector
 	var inspections []models.Inspection
 	var total int64
 
-	query := r.db.Where("organization_id = ? AND inspector_id = ?", organizationID, inspectorID)
+	query := r.conn(ctx).Where("organization_id = ? AND inspector_id = ?", organizationID, inspectorID)
 
 	// Count total
 	err = query.Model(&models.Inspection{}).Count(&total).Error
@@ -347,7 +361,7 @@ func (r *InspectionRepositoryImpl) GetByStatus(ctx context.Context, status strin
 	var inspections []models.Inspection
 	var total int64
 
-	query := r.db.Where("organization_id = ? AND status = ?", organizationID, status)
+	query := r.conn(ctx).Where("organization_id = ? AND status = ?", organizationID, status)
 
 	// Count total
 	err = query.Model(&models.Inspection{}).Count(&total).Error
@@ -388,7 +402,7 @@ func (r *InspectionRepositoryImpl) GetByTemplate(ctx context.Context, templateID
 	var inspections []models.Inspection
 	var total int64
 
-	query := r.db.Where("organization_id = ? AND template_id = ?", organizationID, templateID)
+	query := r.conn(ctx).Where("organization_id = ? AND template_id = ?", organizationID, templateID)
 
 	// Count total
 	err = query.Model(&models.Inspection{}).Count(&total).Error
@@ -415,7 +429,18 @@ func (r *InspectionRepositoryImpl) GetByTemplate(ctx context.Context, templateID
 	return inspections, total, nil
 }
 
-// GetOverdue retrieves overdue inspections within tenant scope
+// An inspection's time zone is its site's, falling back to its organization's. "Due today"
+// compares calendar days in that zone, so it follows the site's clock rather than the
+// server's. Overdue needs no zone: due dates are instants.
+const (
+	inspectionTimezoneJoins = "LEFT JOIN sites ON sites.id = inspections.site_id LEFT JOIN organizations ON organizations.id = inspections.organization_id"
+	inspectionTimezoneSQL   = "COALESCE(NULLIF(sites.timezone, ''), NULLIF(organizations.timezone, ''), 'UTC')"
+	inspectionLocalDueDay   = "(inspections.due_date AT TIME ZONE " + inspectionTimezoneSQL + ")::date"
+	inspectionLocalToday    = "(NOW() AT TIME ZONE " + inspectionTimezoneSQL + ")::date"
+)
+
+// GetOverdue retrieves overdue inspections within tenant scope: open inspections whose due
+// date has passed
 func (r *InspectionRepositoryImpl) GetOverdue(ctx context.Context, limit, offset int) ([]models.Inspection, int64, error) {
 	if r.db == nil {
 		return nil, 0, errors.New("database connection not available")
@@ -429,7 +454,9 @@ func (r *InspectionRepositoryImpl) GetOverdue(ctx context.Context, limit, offset
 	var inspections []models.Inspection
 	var total int64
 
-	query := r.db.Where("organization_id = ? AND due_date < ? AND status != ?", organizationID, time.Now(), "completed")
+	query := r.conn(ctx).
+		Where("inspections.organization_id = ? AND inspections.due_date < NOW() AND inspections.status NOT IN ?", organizationID,
+			[]string{"completed", "approved", "rejected", "cancelled"})
 
 	// Count total
 	err = query.Model(&models.Inspection{}).Count(&total).Error
@@ -442,7 +469,7 @@ func (r *InspectionRepositoryImpl) GetOverdue(ctx context.Context, limit, offset
 		Preload("Template").
 		Preload("Inspector").
 		Preload("Site").
-		Order("due_date ASC").
+		Order("inspections.due_date ASC").
 		Limit(limit).
 		Offset(offset).
 		Find(&inspections).Error
@@ -454,7 +481,7 @@ func (r *InspectionRepositoryImpl) GetOverdue(ctx context.Context, limit, offset
 	return inspections, total, nil
 }
 
-// GetDueToday retrieves inspections due today within tenant scope
+// GetDueToday retrieves inspections due today within tenant scope, today being the site's
 func (r *InspectionRepositoryImpl) GetDueToday(ctx context.Context) ([]models.Inspection, error) {
 	if r.db == nil {
 		return nil, errors.New("database connection not available")
@@ -466,15 +493,14 @@ func (r *InspectionRepositoryImpl) GetDueToday(ctx context.Context) ([]models.In
 	}
 
 	var inspections []models.Inspection
-	today := time.Now().Truncate(24 * time.Hour)
-	tomorrow := today.Add(24 * time.Hour)
-
-	err = r.db.Where("organization_id = ? AND due_date >= ? AND due_date < ? AND status != ?",
-		organizationID, today, tomorrow, "completed").
+	err = r.conn(ctx).Joins(inspectionTimezoneJoins).
+		Where("inspections.organization_id = ? AND inspections.status NOT IN ?", organizationID,
+			[]string{"completed", "approved", "rejected", "cancelled"}).
+		Where(inspectionLocalDueDay + " = " + inspectionLocalToday).
 		Preload("Template").
 		Preload("Inspector").
 		Preload("Site").
-		Order("due_date ASC").
+		Order("inspections.due_date ASC").
 		Find(&inspections).Error
 
 	return inspections, err
@@ -491,7 +517,7 @@ func (r *InspectionRepositoryImpl) UpdateStatus(ctx context.Context, inspectionI
 		return fmt.Errorf("tenant context required: %w", err)
 	}
 
-	result := r.db.Model(&models.Inspection{}).
+	result := r.conn(ctx).Model(&models.Inspection{}).
 		Where("organization_id = ? AND id = ?", organizationID, inspectionID).
 		Update("status", status)
 
@@ -519,7 +545,7 @@ func (r *InspectionRepositoryImpl) Submit(ctx context.Context, inspectionID uuid
 
 	// Verify inspection exists in tenant scope using UUID
 	var inspection models.Inspection
-	err = r.db.WithContext(ctx).Where("id = ?", inspectionID).First(&inspection).Error
+	err = r.conn(ctx).Where("id = ?", inspectionID).First(&inspection).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return fmt.Errorf("inspection not found in organization scope")
@@ -529,7 +555,7 @@ func (r *InspectionRepositoryImpl) Submit(ctx context.Context, inspectionID uuid
 
 	// Store form data in the inspection_data table
 	// First, delete existing inspection data for this inspection
-	err = r.db.Where("inspection_id = ?", inspection.ID).Delete(&models.InspectionData{}).Error
+	err = r.conn(ctx).Where("inspection_id = ?", inspection.ID).Delete(&models.InspectionData{}).Error
 	if err != nil {
 		return fmt.Errorf("failed to clear existing inspection data: %w", err)
 	}
@@ -548,7 +574,7 @@ func (r *InspectionRepositoryImpl) Submit(ctx context.Context, inspectionID uuid
 			FieldValue:   fieldValueStr,
 		}
 
-		err = r.db.Create(&inspectionData).Error
+		err = r.conn(ctx).Create(&inspectionData).Error
 		if err != nil {
 			return fmt.Errorf("failed to save inspection data for field %s: %w", fieldName, err)
 		}
@@ -588,7 +614,7 @@ func (r *InspectionRepositoryImpl) GetInspectionStats(ctx context.Context) (map[
 		WHERE organization_id = ?
 	`
 
-	err = r.db.Raw(query, organizationID).Scan(&stats).Error
+	err = r.conn(ctx).Raw(query, organizationID).Scan(&stats).Error
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"resource-mgmt/services"
 	"strconv"
@@ -40,8 +41,21 @@ func (h *AnalyticsHandler) GetDashboardStats(c *gin.Context) {
 	if inspectorID := c.Query("inspector_id"); inspectorID != "" {
 		filters["inspector_id"] = inspectorID
 	}
+	if teamID := c.Query("team_id"); teamID != "" {
+		filters["team_id"] = teamID
+	}
+	if siteID := c.Query("site_id"); siteID != "" {
+		filters["site_id"] = siteID
+	}
+	if locationNodeID := c.Query("location_node_id"); locationNodeID != "" {
+		filters["location_node_id"] = locationNodeID
+	}
 
 	stats, err := h.analyticsService.GetDashboardStats(organizationID, filters)
+	if errors.Is(err, services.ErrLocationNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get dashboard stats: " + err.Error()})
 		return
@@ -85,9 +99,22 @@ func (h *AnalyticsHandler) ExportReport(c *gin.Context) {
 	if priority := c.Query("priority"); priority != "" {
 		filters["priority"] = priority
 	}
+	if teamID := c.Query("team_id"); teamID != "" {
+		filters["team_id"] = teamID
+	}
+	if siteID := c.Query("site_id"); siteID != "" {
+		filters["site_id"] = siteID
+	}
+	if locationNodeID := c.Query("location_node_id"); locationNodeID != "" {
+		filters["location_node_id"] = locationNodeID
+	}
 
 	// Generate report
 	data, filename, err := h.analyticsService.ExportInspectionReport(organizationID, format, filters)
+	if errors.Is(err, services.ErrLocationNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate report: " + err.Error()})
 		return
@@ -131,9 +158,22 @@ func (h *AnalyticsHandler) GetInspectionMetrics(c *gin.Context) {
 	if inspectorID := c.Query("inspector_id"); inspectorID != "" {
 		filters["inspector_id"] = inspectorID
 	}
+	if teamID := c.Query("team_id"); teamID != "" {
+		filters["team_id"] = teamID
+	}
+	if siteID := c.Query("site_id"); siteID != "" {
+		filters["site_id"] = siteID
+	}
+	if locationNodeID := c.Query("location_node_id"); locationNodeID != "" {
+		filters["location_node_id"] = locationNodeID
+	}
 
 	// Get only the inspection stats part
 	stats, err := h.analyticsService.GetDashboardStats(organizationID, filters)
+	if errors.Is(err, services.ErrLocationNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get inspection metrics: " + err.Error()})
 		return
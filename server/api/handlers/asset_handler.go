@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"resource-mgmt/models"
+	"resource-mgmt/services"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AssetHandler struct {
+	assetService *services.AssetService
+	auditService *services.AuditService
+}
+
+func NewAssetHandler(assetService *services.AssetService) *AssetHandler {
+	return &AssetHandler{
+		assetService: assetService,
+		auditService: services.NewAuditService(),
+	}
+}
+
+// assetErrorStatus maps asset service errors to HTTP status codes
+func assetErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrAssetNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidAsset),
+		errors.Is(err, services.ErrLocationNotFound),
+		errors.Is(err, services.ErrLocationNotInSite):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrAssetRetired),
+		errors.Is(err, services.ErrAssetHasInspections):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GetAssets handles GET /api/v1/assets
+func (h *AssetHandler) GetAssets(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
+	}
+
+	filters := make(map[string]interface{})
+	for _, key := range []string{"site_id", "type", "status", "location_node_id"} {
+		if value := c.Query(key); value != "" {
+			filters[key] = value
+		}
+	}
+	if c.Query("include_retired") == "true" {
+		filters["include_retired"] = true
+	}
+
+	assets, total, err := h.assetService.GetAssets(c.Request.Context(), c.GetString("organization_id"), filters, c.Query("search"), page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch assets"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"assets": assets,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		},
+	})
+}
+
+// GetAsset handles GET /api/v1/assets/:id
+func (h *AssetHandler) GetAsset(c *gin.Context) {
+	asset, err := h.assetService.GetAsset(c.Request.Context(), c.GetString("organization_id"), c.Param("id"))
+	if err != nil {
+		c.JSON(assetErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"asset": asset})
+}
+
+// CreateAsset handles POST /api/v1/assets
+func (h *AssetHandler) CreateAsset(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req models.Asset
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	req.ID = ""
+	req.OrganizationID = c.GetString("organization_id")
+	req.CreatedBy = userID
+	req.UpdatedBy = userID
+	req.RetiredAt = nil
+	req.RetiredBy = nil
+
+	asset, err := h.assetService.CreateAsset(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(assetErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.AssetCreated, "asset", asset.ID, nil, asset)
+
+	c.JSON(http.StatusCreated, gin.H{"asset": asset})
+}
+
+// UpdateAsset handles PUT /api/v1/assets/:id
+func (h *AssetHandler) UpdateAsset(c *gin.Context) {
+	organizationID := c.GetString("organization_id")
+	assetID := c.Param("id")
+
+	var updates map[string]interface{}
+	if err := c.ShouldBindJSON(&updates); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+	updates["updated_by"] = c.GetString("user_id")
+
+	before, err := h.assetService.GetAsset(c.Request.Context(), organizationID, assetID)
+	if err != nil {
+		c.JSON(assetErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	asset, err := h.assetService.UpdateAsset(c.Request.Context(), organizationID, assetID, updates)
+	if err != nil {
+		c.JSON(assetErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.AssetUpdated, "asset", assetID, before, asset)
+
+	c.JSON(http.StatusOK, gin.H{"asset": asset})
+}
+
+// RetireAsset handles POST /api/v1/assets/:id/retire
+func (h *AssetHandler) RetireAsset(c *gin.Context) {
+	organizationID := c.GetString("organization_id")
+	assetID := c.Param("id")
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	before, err := h.assetService.GetAsset(c.Request.Context(), organizationID, assetID)
+	if err != nil {
+		c.JSON(assetErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	asset, err := h.assetService.RetireAsset(c.Request.Context(), organizationID, assetID, c.GetString("user_id"), req.Reason)
+	if err != nil {
+		c.JSON(assetErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.AssetRetired, "asset", assetID, before, asset)
+
+	c.JSON(http.StatusOK, gin.H{"asset": asset})
+}
+
+// DeleteAsset handles DELETE /api/v1/assets/:id
+func (h *AssetHandler) DeleteAsset(c *gin.Context) {
+	organizationID := c.GetString("organization_id")
+	assetID := c.Param("id")
+
+	before, err := h.assetService.GetAsset(c.Request.Context(), organizationID, assetID)
+	if err != nil {
+		c.JSON(assetErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.assetService.DeleteAsset(c.Request.Context(), organizationID, assetID); err != nil {
+		c.JSON(assetErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.AssetDeleted, "asset", assetID, before, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Asset deleted successfully"})
+}
+
+// GetAssetInspections handles GET /api/v1/assets/:id/inspections
+func (h *AssetHandler) GetAssetInspections(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+
+	inspections, total, err := h.assetService.GetAssetInspections(c.Request.Context(), c.GetString("organization_id"), c.Param("id"), page, limit)
+	if err != nil {
+		c.JSON(assetErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"inspections": inspections,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		},
+	})
+}
+
+// GetAssetNextDue handles GET /api/v1/assets/:id/next-due
+func (h *AssetHandler) GetAssetNextDue(c *gin.Context) {
+	due, err := h.assetService.GetAssetNextDue(c.Request.Context(), c.GetString("organization_id"), c.Param("id"))
+	if err != nil {
+		c.JSON(assetErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"next_due": due})
+}
+
+// GetAssetsDue handles GET /api/v1/assets/due?within_days=30&site_id=
+func (h *AssetHandler) GetAssetsDue(c *gin.Context) {
+	withinDays, err := strconv.Atoi(c.DefaultQuery("within_days", "30"))
+	if err != nil || withinDays < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "within_days must be a non-negative number"})
+		return
+	}
+
+	due, err := h.assetService.GetAssetsDue(c.Request.Context(), c.GetString("organization_id"), c.Query("site_id"), time.Now().AddDate(0, 0, withinDays))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch assets due for inspection"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"assets": due})
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"resource-mgmt/models"
+	"resource-mgmt/services"
+	"resource-mgmt/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AssignmentHistoryHandler struct {
+	historyService *services.AssignmentHistoryService
+	auditService   *services.AuditService
+}
+
+func NewAssignmentHistoryHandler(historyService *services.AssignmentHistoryService) *AssignmentHistoryHandler {
+	return &AssignmentHistoryHandler{
+		historyService: historyService,
+		auditService:   services.NewAuditService(),
+	}
+}
+
+// assignmentHistoryErrorStatus maps assignment history and delegation errors to HTTP status codes
+func assignmentHistoryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidDelegation), errors.Is(err, services.ErrInvalidRejectionReason):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrAssignmentHistoryForbidden), errors.Is(err, services.ErrDelegationNotAllowed):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrAssignmentNotFound), errors.Is(err, services.ErrDelegationNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrDelegationConflict), errors.Is(err, services.ErrInspectorUnavailable), errors.Is(err, services.ErrInspectorNotQualified),
+		errors.Is(err, services.ErrAssignmentChanged):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GetAssignmentTimeline handles GET /api/v1/assignments/:id/timeline
+// Inspectors may only see the history of assignments they hold or held
+func (h *AssignmentHistoryHandler) GetAssignmentTimeline(c *gin.Context) {
+	events, err := h.historyService.GetTimeline(c.Request.Context(), c.GetString("organization_id"), c.Param("id"),
+		c.GetString("user_id"), c.GetString("user_role"))
+	if err != nil {
+		c.JSON(assignmentHistoryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"events": events})
+}
+
+// RequestDelegation handles POST /api/v1/assignments/:id/delegations
+// The assigned inspector asks to hand the assignment to a peer; a supervisor approves it
+func (h *AssignmentHistoryHandler) RequestDelegation(c *gin.Context) {
+	var req models.DelegationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	delegation, err := h.historyService.RequestDelegation(c.Request.Context(), c.GetString("organization_id"), c.Param("id"), c.GetString("user_id"), &req)
+	if err != nil {
+		c.JSON(assignmentHistoryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.DelegationRequested, "assignment_delegation", delegation.ID, nil, delegation)
+
+	c.JSON(http.StatusCreated, gin.H{"delegation": delegation})
+}
+
+// GetDelegations handles GET /api/v1/assignments/delegations?status=&assignment_id=&inspector_id=
+// Inspectors only see requests they made or that name them
+func (h *AssignmentHistoryHandler) GetDelegations(c *gin.Context) {
+	filters := map[string]interface{}{
+		"status":        c.Query("status"),
+		"assignment_id": c.Query("assignment_id"),
+		"inspector_id":  c.Query("inspector_id"),
+	}
+	if !utils.HasHigherOrEqualPrivilege(c.GetString("user_role"), "supervisor") {
+		filters["inspector_id"] = c.GetString("user_id")
+	}
+
+	delegations, err := h.historyService.GetDelegations(c.Request.Context(), c.GetString("organization_id"), filters)
+	if err != nil {
+		c.JSON(assignmentHistoryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"delegations": delegations})
+}
+
+// ApproveDelegation handles POST /api/v1/assignments/delegations/:delegation_id/approve
+// The assignment moves to the requested inspector, who has to accept it
+func (h *AssignmentHistoryHandler) ApproveDelegation(c *gin.Context) {
+	h.reviewDelegation(c, true)
+}
+
+// DeclineDelegation handles POST /api/v1/assignments/delegations/:delegation_id/decline
+func (h *AssignmentHistoryHandler) DeclineDelegation(c *gin.Context) {
+	h.reviewDelegation(c, false)
+}
+
+func (h *AssignmentHistoryHandler) reviewDelegation(c *gin.Context, approve bool) {
+	var req models.ReviewDelegationRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+			return
+		}
+	}
+
+	delegation, err := h.historyService.ReviewDelegation(c.Request.Context(), c.GetString("organization_id"), c.Param("delegation_id"),
+		c.GetString("user_id"), approve, req.Note)
+	if err != nil {
+		c.JSON(assignmentHistoryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	action := services.DelegationDeclined
+	if approve {
+		action = services.DelegationApproved
+	}
+	recordAudit(c, h.auditService, action, "assignment_delegation", delegation.ID,
+		gin.H{"status": models.DelegationPending}, gin.H{"status": delegation.Status, "note": delegation.ReviewNote})
+
+	c.JSON(http.StatusOK, gin.H{"delegation": delegation})
+}
+
+// CancelDelegation handles POST /api/v1/assignments/delegations/:delegation_id/cancel
+func (h *AssignmentHistoryHandler) CancelDelegation(c *gin.Context) {
+	delegation, err := h.historyService.CancelDelegation(c.Request.Context(), c.GetString("organization_id"), c.Param("delegation_id"),
+		c.GetString("user_id"), c.GetString("user_role"))
+	if err != nil {
+		c.JSON(assignmentHistoryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.DelegationCancelled, "assignment_delegation", delegation.ID,
+		gin.H{"status": models.DelegationPending}, gin.H{"status": delegation.Status})
+
+	c.JSON(http.StatusOK, gin.H{"delegation": delegation})
+}
+
+// GetRejectionReport handles GET /api/v1/assignments/rejections?from=&to=
+// The range defaults to the last 30 days; to is inclusive
+func (h *AssignmentHistoryHandler) GetRejectionReport(c *gin.Context) {
+	to := time.Now()
+	from := to.AddDate(0, 0, -30)
+	for key, target := range map[string]*time.Time{"from": &from, "to": &to} {
+		if value := c.Query(key); value != "" {
+			parsed, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": key + " must be a YYYY-MM-DD date"})
+				return
+			}
+			if key == "to" {
+				parsed = parsed.AddDate(0, 0, 1)
+			}
+			*target = parsed
+		}
+	}
+	if !from.Before(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return
+	}
+
+	report, err := h.historyService.GetRejectionReport(c.Request.Context(), c.GetString("organization_id"), from, to)
+	if err != nil {
+		c.JSON(assignmentHistoryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"resource-mgmt/models"
+	"resource-mgmt/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AssignmentSolverHandler struct {
+	solverService *services.AssignmentSolverService
+	auditService  *services.AuditService
+}
+
+func NewAssignmentSolverHandler(solverService *services.AssignmentSolverService) *AssignmentSolverHandler {
+	return &AssignmentSolverHandler{
+		solverService: solverService,
+		auditService:  services.NewAuditService(),
+	}
+}
+
+// assignmentSolverErrorStatus maps assignment solver errors to HTTP status codes
+func assignmentSolverErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidAutoAssign):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrAutoAssignDisabled):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrAssignmentProposalNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrAssignmentProposalClosed), errors.Is(err, services.ErrInspectorNotQualified):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// ProposeAssignments handles POST /api/v1/assignments/auto-assign
+// Nothing is assigned yet; the proposal is accepted or discarded separately
+func (h *AssignmentSolverHandler) ProposeAssignments(c *gin.Context) {
+	var req models.AutoAssignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name, template_id and site_ids are required"})
+		return
+	}
+
+	proposal, err := h.solverService.Propose(c.Request.Context(), c.GetString("organization_id"), c.GetString("user_id"), &req)
+	if err != nil {
+		c.JSON(assignmentSolverErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"proposal": proposal})
+}
+
+// GetAssignmentProposals handles GET /api/v1/assignments/proposals?status=&project_id=
+func (h *AssignmentSolverHandler) GetAssignmentProposals(c *gin.Context) {
+	filters := make(map[string]interface{})
+	for _, key := range []string{"status", "project_id"} {
+		if value := c.Query(key); value != "" {
+			filters[key] = value
+		}
+	}
+
+	proposals, err := h.solverService.GetProposals(c.Request.Context(), c.GetString("organization_id"), filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch assignment proposals"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"proposals": proposals})
+}
+
+// GetAssignmentProposal handles GET /api/v1/assignments/proposals/:proposal_id
+func (h *AssignmentSolverHandler) GetAssignmentProposal(c *gin.Context) {
+	proposal, err := h.solverService.GetProposal(c.Request.Context(), c.GetString("organization_id"), c.Param("proposal_id"))
+	if err != nil {
+		c.JSON(assignmentSolverErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"proposal": proposal})
+}
+
+// AcceptAssignmentProposal handles POST /api/v1/assignments/proposals/:proposal_id/accept
+func (h *AssignmentSolverHandler) AcceptAssignmentProposal(c *gin.Context) {
+	var req models.AcceptAssignmentProposalRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+			return
+		}
+	}
+
+	proposal, assignments, err := h.solverService.AcceptProposal(c.Request.Context(), c.GetString("organization_id"), c.GetString("user_id"), c.Param("proposal_id"), &req)
+	if err != nil {
+		c.JSON(assignmentSolverErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.AssignmentProposalAccepted, "assignment_proposal", proposal.ID, nil, gin.H{
+		"batch_id": proposal.BatchID, "assigned": proposal.Assigned, "unassigned": proposal.Unassigned, "overrides": req.Overrides,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"proposal": proposal, "assignments": assignments})
+}
+
+// DiscardAssignmentProposal handles POST /api/v1/assignments/proposals/:proposal_id/discard
+func (h *AssignmentSolverHandler) DiscardAssignmentProposal(c *gin.Context) {
+	proposal, err := h.solverService.DiscardProposal(c.Request.Context(), c.GetString("organization_id"), c.Param("proposal_id"))
+	if err != nil {
+		c.JSON(assignmentSolverErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.AssignmentProposalDiscarded, "assignment_proposal", proposal.ID, nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{"proposal": proposal})
+}
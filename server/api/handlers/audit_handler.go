@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"resource-mgmt/services"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditHandler struct {
+	auditService *services.AuditService
+}
+
+func NewAuditHandler(auditService *services.AuditService) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
+
+// GetAuditLogs lists the organization's audit trail with filtering
+// GET /api/v1/audit/logs
+func (h *AuditHandler) GetAuditLogs(c *gin.Context) {
+	filters, ok := parseAuditFilters(c)
+	if !ok {
+		return
+	}
+
+	page, limit := auditPagination(c)
+	logs, total, err := h.auditService.GetAuditLogs(c.Request.Context(), filters, limit, (page-1)*limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit logs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": logs,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		},
+	})
+}
+
+// GetUserAuditHistory lists audit entries targeting a user
+// GET /api/v1/audit/users/:id
+func (h *AuditHandler) GetUserAuditHistory(c *gin.Context) {
+	page, limit := auditPagination(c)
+	logs, total, err := h.auditService.GetUserAuditHistory(c.Request.Context(), c.Param("id"), limit, (page-1)*limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user audit history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": logs,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		},
+	})
+}
+
+// GetSecurityEvents lists security-related audit entries
+// GET /api/v1/audit/security-events
+func (h *AuditHandler) GetSecurityEvents(c *gin.Context) {
+	page, limit := auditPagination(c)
+	logs, total, err := h.auditService.GetSecurityEvents(c.Request.Context(), limit, (page-1)*limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch security events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": logs,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		},
+	})
+}
+
+// ExportAuditLogs downloads the filtered audit trail as csv or json
+// GET /api/v1/audit/export/:format
+func (h *AuditHandler) ExportAuditLogs(c *gin.Context) {
+	format := c.Param("format")
+	if format != "csv" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported format. Use 'csv' or 'json'"})
+		return
+	}
+
+	filters, ok := parseAuditFilters(c)
+	if !ok {
+		return
+	}
+
+	data, filename, err := h.auditService.ExportAuditLogs(c.Request.Context(), format, filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export audit logs: " + err.Error()})
+		return
+	}
+
+	contentType := "text/csv"
+	if format == "json" {
+		contentType = "application/json"
+	}
+
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Header("Content-Length", strconv.Itoa(len(data)))
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// VerifyAuditChain checks the organization's audit hash chain for tampering
+// GET /api/v1/audit/verify
+func (h *AuditHandler) VerifyAuditChain(c *gin.Context) {
+	orgID := c.GetString("organization_id")
+
+	result, err := h.auditService.VerifyChain(c.Request.Context(), orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+// parseAuditFilters reads audit query filters, writing a 400 response on invalid input
+func parseAuditFilters(c *gin.Context) (map[string]interface{}, bool) {
+	filters := make(map[string]interface{})
+	for _, key := range []string{"user_id", "target_user_id", "action", "resource_type", "resource_id"} {
+		if value := c.Query(key); value != "" {
+			filters[key] = value
+		}
+	}
+
+	if success := c.Query("success"); success != "" {
+		value, err := strconv.ParseBool(success)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid success filter"})
+			return nil, false
+		}
+		filters["success"] = value
+	}
+
+	for _, key := range []string{"from", "to"} {
+		value := c.Query(key)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			parsed, err = time.Parse("2006-01-02", value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + key + " date. Use YYYY-MM-DD or RFC3339"})
+				return nil, false
+			}
+			if key == "to" {
+				parsed = parsed.Add(24*time.Hour - time.Nanosecond)
+			}
+		}
+		filters[key] = parsed
+	}
+
+	return filters, true
+}
+
+func auditPagination(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 500 {
+		limit = 50
+	}
+	return page, limit
+}
+
+// recordAudit writes an audit entry for a change made by the current request.
+// The change has already succeeded, so a failed audit write is logged rather than returned.
+func recordAudit(c *gin.Context, auditService *services.AuditService, action services.AuditAction, resourceType, resourceID string, before, after interface{}) {
+	err := auditService.LogEntityChange(c.Request.Context(), c.GetString("organization_id"), c.GetString("user_id"), action, resourceType, resourceID, before, after)
+	if err != nil {
+		log.Printf("Failed to record audit entry %s for %s %s: %v", action, resourceType, resourceID, err)
+	}
+}
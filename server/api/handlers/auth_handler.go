@@ -2,175 +2,136 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
-	"os"
 	"resource-mgmt/config"
 	"resource-mgmt/middleware"
 	"resource-mgmt/models"
 	"resource-mgmt/services"
 	"resource-mgmt/utils"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
-	"golang.org/x/oauth2/microsoft"
 )
 
 type AuthHandler struct {
 	userService     *services.UserService
 	multiOrgService *services.MultiOrgAuthService
+	oauthService    *services.OAuthService
 }
 
-var googleOAuthConfig = &oauth2.Config{
-	ClientID:     os.Getenv("GOOGLE_CLIENT_ID"),
-	ClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
-	RedirectURL:  os.Getenv("GOOGLE_REDIRECT_URL"),
-	Scopes:       []string{"openid", "email", "profile"},
-	Endpoint:     google.Endpoint,
-}
-
-var microsoftOAuthConfig = &oauth2.Config{
-	ClientID:     os.Getenv("MICROSOFT_CLIENT_ID"),
-	ClientSecret: os.Getenv("MICROSOFT_CLIENT_SECRET"),
-	RedirectURL:  os.Getenv("MICROSOFT_REDIRECT_URL"),
-	Scopes:       []string{"openid", "email", "profile"},
-	Endpoint:     microsoft.AzureADEndpoint("common"),
-}
+// oauthVerifierCookie holds the PKCE verifier between the login redirect and the callback
+const oauthVerifierCookie = "oauth_pkce_verifier"
 
 // Google OAuth login handler
 func (h *AuthHandler) GoogleLogin(c *gin.Context) {
-	url := googleOAuthConfig.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
-	c.Redirect(http.StatusTemporaryRedirect, url)
+	h.beginOAuthLogin(c, "google")
 }
 
 // Google OAuth callback handler
 func (h *AuthHandler) GoogleCallback(c *gin.Context) {
-	code := c.Query("code")
-	if code == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing code in callback"})
-		return
-	}
-	token, err := googleOAuthConfig.Exchange(c, code)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Failed to exchange token"})
-		return
-	}
-	client := googleOAuthConfig.Client(c, token)
-	resp, err := client.Get("https://www.googleapis.com/oauth2/v2/userinfo")
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user info"})
-		return
-	}
-	defer resp.Body.Close()
-	var userInfo struct {
-		ID    string `json:"id"`
-		Email string `json:"email"`
-		Name  string `json:"name"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&userInfo); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode user info"})
-		return
-	}
-	// Check if user exists
-	user, err := h.userService.GetUserByEmail(c.Request.Context(), userInfo.Email)
-	if err != nil {
-		// Create new user
-		createReq := &models.CreateUserRequest{
-			Name:        userInfo.Name,
-			Email:       userInfo.Email,
-			Role:        "inspector", // default role
-			Permissions: map[string]interface{}{},
-		}
-		user, err = h.userService.CreateUser(c.Request.Context(), createReq)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
-			return
-		}
-	}
-	// Parse permissions JSON
-	var permissions map[string]interface{}
-	err = json.Unmarshal(user.Permissions, &permissions)
-	if err != nil || len(permissions) == 0 {
-		// If permissions parsing fails or permissions are empty, use default permissions for the role
-		permissions = utils.GetDefaultPermissions(user.Role)
-	}
-	tokenStr, err := middleware.GenerateToken(user.ID, user.OrganizationID, user.Email, user.Role, permissions)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
-		return
-	}
-	c.JSON(http.StatusOK, gin.H{"token": tokenStr, "user": user})
+	h.completeOAuthLogin(c, "google")
 }
 
 // Microsoft OAuth login handler
 func (h *AuthHandler) MicrosoftLogin(c *gin.Context) {
-	url := microsoftOAuthConfig.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
-	c.Redirect(http.StatusTemporaryRedirect, url)
+	h.beginOAuthLogin(c, "microsoft")
 }
 
 // Microsoft OAuth callback handler
 func (h *AuthHandler) MicrosoftCallback(c *gin.Context) {
-	code := c.Query("code")
-	if code == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing code in callback"})
+	h.completeOAuthLogin(c, "microsoft")
+}
+
+// OIDCLogin starts login with the generic OpenID Connect provider (Okta, Keycloak, ...)
+// GET /api/v1/auth/oidc/login
+func (h *AuthHandler) OIDCLogin(c *gin.Context) {
+	h.beginOAuthLogin(c, config.OIDCProviderName)
+}
+
+// OIDCCallback completes login with the generic OpenID Connect provider
+// GET /api/v1/auth/oidc/callback
+func (h *AuthHandler) OIDCCallback(c *gin.Context) {
+	h.completeOAuthLogin(c, config.OIDCProviderName)
+}
+
+// GetIdentities lists the external identities linked to the current user
+// GET /api/v1/auth/identities
+func (h *AuthHandler) GetIdentities(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
-	token, err := microsoftOAuthConfig.Exchange(c, code)
+
+	identities, err := h.oauthService.GetUserIdentities(userID)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Failed to exchange token"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	client := microsoftOAuthConfig.Client(c, token)
-	resp, err := client.Get("https://graph.microsoft.com/v1.0/me")
+
+	c.JSON(http.StatusOK, gin.H{"data": identities})
+}
+
+func (h *AuthHandler) beginOAuthLogin(c *gin.Context, provider string) {
+	authorization, err := h.oauthService.BeginAuthorization(c.Request.Context(), provider)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user info"})
+		if errors.Is(err, services.ErrUnknownOAuthProvider) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
 		return
 	}
-	defer resp.Body.Close()
-	var userInfo struct {
-		ID    string `json:"id"`
-		Email string `json:"mail"`
-		Name  string `json:"displayName"`
+
+	// Lax so the cookie survives the top-level redirect back from the provider
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oauthVerifierCookie, authorization.Verifier, 600, "/api/v1/auth", "", c.Request.TLS != nil, true)
+	c.Redirect(http.StatusTemporaryRedirect, authorization.URL)
+}
+
+func (h *AuthHandler) completeOAuthLogin(c *gin.Context, provider string) {
+	if errParam := c.Query("error"); errParam != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login was cancelled or denied by the provider"})
+		return
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&userInfo); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode user info"})
+
+	code := c.Query("code")
+	if code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing code in callback"})
 		return
 	}
-	// Check if user exists
-	user, err := h.userService.GetUserByEmail(c.Request.Context(), userInfo.Email)
+
+	verifier, _ := c.Cookie(oauthVerifierCookie)
+	c.SetCookie(oauthVerifierCookie, "", -1, "/api/v1/auth", "", c.Request.TLS != nil, true)
+
+	response, err := h.oauthService.CompleteAuthorization(c.Request.Context(), provider, c.Query("state"), code, verifier)
 	if err != nil {
-		// Create new user
-		createReq := &models.CreateUserRequest{
-			Name:        userInfo.Name,
-			Email:       userInfo.Email,
-			Role:        "inspector", // default role
-			Permissions: map[string]interface{}{},
-		}
-		user, err = h.userService.CreateUser(c.Request.Context(), createReq)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
-			return
+		switch {
+		case errors.Is(err, services.ErrUnknownOAuthProvider):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrInvalidOAuthState):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrUnverifiedIdentityEmail):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrNoActiveMemberships):
+			c.JSON(http.StatusForbidden, gin.H{"error": "No organization membership found for this account; ask an administrator for an invitation"})
+		default:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Failed to authenticate with provider"})
 		}
-	}
-	// Parse permissions JSON
-	var permissions map[string]interface{}
-	err = json.Unmarshal(user.Permissions, &permissions)
-	if err != nil || len(permissions) == 0 {
-		// If permissions parsing fails or permissions are empty, use default permissions for the role
-		permissions = utils.GetDefaultPermissions(user.Role)
-	}
-	tokenStr, err := middleware.GenerateToken(user.ID, user.OrganizationID, user.Email, user.Role, permissions)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"token": tokenStr, "user": user})
+
+	c.JSON(http.StatusOK, response)
 }
 
 func NewAuthHandler(userService *services.UserService) *AuthHandler {
+	multiOrgService := services.NewMultiOrgAuthService()
 	return &AuthHandler{
 		userService:     userService,
-		multiOrgService: services.NewMultiOrgAuthService(),
+		multiOrgService: multiOrgService,
+		oauthService:    services.NewOAuthService(multiOrgService),
 	}
 }
 
@@ -183,6 +144,15 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 	response, err := h.multiOrgService.Login(c.Request.Context(), &req)
 	if err != nil {
+		var locked *services.LoginLockedError
+		if errors.As(err, &locked) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter().Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error": "Too many failed login attempts, please try again later",
+				"code":  "LOGIN_LOCKED",
+			})
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 		return
 	}
@@ -273,6 +243,45 @@ func (h *AuthHandler) GetProfile(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// GetMyOrganizations lists the organizations the current user belongs to
+// GET /api/v1/auth/organizations
+func (h *AuthHandler) GetMyOrganizations(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	organizations, err := h.multiOrgService.GetUserOrganizations(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get organizations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":                    organizations,
+		"current_organization_id": c.GetString("organization_id"),
+	})
+}
+
+// SetPrimaryOrganization sets the organization used by default at login
+// PUT /api/v1/auth/organizations/:org_id/primary
+func (h *AuthHandler) SetPrimaryOrganization(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	organizations, err := h.multiOrgService.SetPrimaryOrganization(c.Request.Context(), userID, c.Param("org_id"))
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": organizations})
+}
+
 func (h *AuthHandler) UpdateProfile(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
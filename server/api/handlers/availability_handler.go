@@ -0,0 +1,323 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"resource-mgmt/models"
+	"resource-mgmt/services"
+	"resource-mgmt/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AvailabilityHandler struct {
+	availabilityService *services.AvailabilityService
+	auditService        *services.AuditService
+}
+
+func NewAvailabilityHandler(availabilityService *services.AvailabilityService) *AvailabilityHandler {
+	return &AvailabilityHandler{
+		availabilityService: availabilityService,
+		auditService:        services.NewAuditService(),
+	}
+}
+
+// availabilityErrorStatus maps availability service errors to HTTP status codes
+func availabilityErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidAvailability), errors.Is(err, services.ErrInvalidTimezone):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrTimeOffForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrTimeOffNotFound), errors.Is(err, services.ErrHolidayNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrTimeOffConflict), errors.Is(err, services.ErrInspectorUnavailable):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// canViewInspector reports whether the caller may see the inspector's availability:
+// their own, or anyone's for supervisors and admins
+func canViewInspector(c *gin.Context, inspectorID string) bool {
+	return inspectorID == c.GetString("user_id") || utils.HasHigherOrEqualPrivilege(c.GetString("user_role"), "supervisor")
+}
+
+// GetCalendar handles GET /api/v1/availability/inspectors/:inspector_id/calendar?from=&to=
+// The range defaults to the next 30 days
+func (h *AvailabilityHandler) GetCalendar(c *gin.Context) {
+	inspectorID := c.Param("inspector_id")
+	if !canViewInspector(c, inspectorID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied to this inspector's availability"})
+		return
+	}
+
+	from, to := time.Now(), time.Now().AddDate(0, 0, 29)
+	for key, target := range map[string]*time.Time{"from": &from, "to": &to} {
+		if value := c.Query(key); value != "" {
+			parsed, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": key + " must be a YYYY-MM-DD date"})
+				return
+			}
+			*target = parsed
+		}
+	}
+
+	days, err := h.availabilityService.GetCalendar(c.Request.Context(), c.GetString("organization_id"), inspectorID, from, to)
+	if err != nil {
+		c.JSON(availabilityErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"inspector_id": inspectorID, "days": days})
+}
+
+// GetWorkingHours handles GET /api/v1/availability/inspectors/:inspector_id/working-hours
+func (h *AvailabilityHandler) GetWorkingHours(c *gin.Context) {
+	inspectorID := c.Param("inspector_id")
+	if !canViewInspector(c, inspectorID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied to this inspector's availability"})
+		return
+	}
+
+	hours, err := h.availabilityService.GetWorkingHours(c.Request.Context(), c.GetString("organization_id"), inspectorID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch working hours"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"working_hours": hours})
+}
+
+// SetWorkingHours handles PUT /api/v1/availability/inspectors/:inspector_id/working-hours
+// The body replaces the whole week; weekdays left out are days off
+func (h *AvailabilityHandler) SetWorkingHours(c *gin.Context) {
+	var req struct {
+		WorkingHours []models.WorkingHoursEntry `json:"working_hours"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	orgID := c.GetString("organization_id")
+	inspectorID := c.Param("inspector_id")
+	before, _ := h.availabilityService.GetWorkingHours(c.Request.Context(), orgID, inspectorID)
+	hours, err := h.availabilityService.SetWorkingHours(c.Request.Context(), orgID, inspectorID, req.WorkingHours)
+	if err != nil {
+		c.JSON(availabilityErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.WorkingHoursUpdated, "inspector", inspectorID, gin.H{"working_hours": before}, gin.H{"working_hours": hours})
+
+	c.JSON(http.StatusOK, gin.H{"working_hours": hours})
+}
+
+// GetTimeOff handles GET /api/v1/availability/time-off?inspector_id=&status=&from=&to=
+// Inspectors only see their own time off
+func (h *AvailabilityHandler) GetTimeOff(c *gin.Context) {
+	filters := make(map[string]interface{})
+	for _, key := range []string{"inspector_id", "status", "from", "to"} {
+		if value := c.Query(key); value != "" {
+			filters[key] = value
+		}
+	}
+	if !utils.HasHigherOrEqualPrivilege(c.GetString("user_role"), "supervisor") {
+		filters["inspector_id"] = c.GetString("user_id")
+	}
+
+	timeOff, err := h.availabilityService.GetTimeOff(c.Request.Context(), c.GetString("organization_id"), filters)
+	if err != nil {
+		c.JSON(availabilityErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"time_off": timeOff})
+}
+
+// RequestTimeOff handles POST /api/v1/availability/time-off
+func (h *AvailabilityHandler) RequestTimeOff(c *gin.Context) {
+	var req models.TimeOffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "start_date and end_date are required"})
+		return
+	}
+
+	timeOff, err := h.availabilityService.RequestTimeOff(c.Request.Context(), c.GetString("organization_id"), c.GetString("user_id"), c.GetString("user_role"), &req)
+	if err != nil {
+		c.JSON(availabilityErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.TimeOffRequested, "time_off", timeOff.ID, nil, timeOff)
+
+	c.JSON(http.StatusCreated, gin.H{"time_off": timeOff})
+}
+
+// ApproveTimeOff handles POST /api/v1/availability/time-off/:id/approve
+// The response lists the inspector's inspections scheduled during the time off
+func (h *AvailabilityHandler) ApproveTimeOff(c *gin.Context) {
+	h.reviewTimeOff(c, true)
+}
+
+// RejectTimeOff handles POST /api/v1/availability/time-off/:id/reject
+func (h *AvailabilityHandler) RejectTimeOff(c *gin.Context) {
+	h.reviewTimeOff(c, false)
+}
+
+func (h *AvailabilityHandler) reviewTimeOff(c *gin.Context, approve bool) {
+	var req models.ReviewTimeOffRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+			return
+		}
+	}
+
+	timeOff, conflicts, err := h.availabilityService.ReviewTimeOff(c.Request.Context(), c.GetString("organization_id"), c.Param("id"), c.GetString("user_id"), approve, req.Note)
+	if err != nil {
+		c.JSON(availabilityErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	action := services.TimeOffRejected
+	if approve {
+		action = services.TimeOffApproved
+	}
+	recordAudit(c, h.auditService, action, "time_off", timeOff.ID, gin.H{"status": models.TimeOffPending}, gin.H{"status": timeOff.Status, "review_note": timeOff.ReviewNote})
+
+	c.JSON(http.StatusOK, gin.H{"time_off": timeOff, "conflicting_inspections": conflicts})
+}
+
+// CancelTimeOff handles POST /api/v1/availability/time-off/:id/cancel
+func (h *AvailabilityHandler) CancelTimeOff(c *gin.Context) {
+	timeOff, err := h.availabilityService.CancelTimeOff(c.Request.Context(), c.GetString("organization_id"), c.Param("id"), c.GetString("user_id"), c.GetString("user_role"))
+	if err != nil {
+		c.JSON(availabilityErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.TimeOffCancelled, "time_off", timeOff.ID, nil, gin.H{"status": timeOff.Status})
+
+	c.JSON(http.StatusOK, gin.H{"time_off": timeOff})
+}
+
+// GetHolidays handles GET /api/v1/availability/holidays?year=2026
+func (h *AvailabilityHandler) GetHolidays(c *gin.Context) {
+	year := 0
+	if value := c.Query("year"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1900 || parsed > 9999 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid year"})
+			return
+		}
+		year = parsed
+	}
+
+	holidays, err := h.availabilityService.GetHolidays(c.Request.Context(), c.GetString("organization_id"), year)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch holidays"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"holidays": holidays})
+}
+
+// CreateHoliday handles POST /api/v1/availability/holidays
+func (h *AvailabilityHandler) CreateHoliday(c *gin.Context) {
+	var req models.CreateHolidayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "date and name are required"})
+		return
+	}
+
+	holiday, err := h.availabilityService.CreateHoliday(c.Request.Context(), c.GetString("organization_id"), c.GetString("user_id"), &req)
+	if err != nil {
+		c.JSON(availabilityErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.HolidayCreated, "holiday", holiday.ID, nil, holiday)
+
+	c.JSON(http.StatusCreated, gin.H{"holiday": holiday})
+}
+
+// DeleteHoliday handles DELETE /api/v1/availability/holidays/:id
+func (h *AvailabilityHandler) DeleteHoliday(c *gin.Context) {
+	holiday, err := h.availabilityService.DeleteHoliday(c.Request.Context(), c.GetString("organization_id"), c.Param("id"))
+	if err != nil {
+		c.JSON(availabilityErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.HolidayDeleted, "holiday", holiday.ID, holiday, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Holiday deleted"})
+}
+
+// GetBusinessHours handles GET /api/v1/availability/business-hours
+func (h *AvailabilityHandler) GetBusinessHours(c *gin.Context) {
+	hours, err := h.availabilityService.GetBusinessHours(c.Request.Context(), c.GetString("organization_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch business hours"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"business_hours": hours})
+}
+
+// SetBusinessHours handles PUT /api/v1/availability/business-hours
+// The body replaces the whole week; weekdays left out are closed
+func (h *AvailabilityHandler) SetBusinessHours(c *gin.Context) {
+	var req struct {
+		BusinessHours []models.WorkingHoursEntry `json:"business_hours"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	orgID := c.GetString("organization_id")
+	before, _ := h.availabilityService.GetBusinessHours(c.Request.Context(), orgID)
+	hours, err := h.availabilityService.SetBusinessHours(c.Request.Context(), orgID, req.BusinessHours)
+	if err != nil {
+		c.JSON(availabilityErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.BusinessHoursUpdated, "organization", orgID, gin.H{"business_hours": before}, gin.H{"business_hours": hours})
+
+	c.JSON(http.StatusOK, gin.H{"business_hours": hours})
+}
+
+// GetDueDate handles GET /api/v1/availability/due-date?hours=&start=&site_id=
+// start is RFC 3339 and defaults to now; hours count business hours only
+func (h *AvailabilityHandler) GetDueDate(c *gin.Context) {
+	hours, err := strconv.ParseFloat(c.Query("hours"), 64)
+	if err != nil || hours < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "hours must be a non-negative number"})
+		return
+	}
+	start := time.Now()
+	if value := c.Query("start"); value != "" {
+		start, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "start must be an RFC 3339 time"})
+			return
+		}
+	}
+
+	result, err := h.availabilityService.DueDate(c.Request.Context(), c.GetString("organization_id"), c.Query("site_id"), start,
+		time.Duration(hours*float64(time.Hour)))
+	if err != nil {
+		c.JSON(availabilityErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"resource-mgmt/services"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type InspectionCheckHandler struct {
+	checkService *services.InspectionCheckService
+}
+
+func NewInspectionCheckHandler(checkService *services.InspectionCheckService) *InspectionCheckHandler {
+	return &InspectionCheckHandler{checkService: checkService}
+}
+
+// checkEventErrorStatus maps geofence errors to HTTP status codes, falling back to fallback
+func checkEventErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, services.ErrOutsideGeofence):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrDeviceLocationRequired),
+		errors.Is(err, services.ErrInvalidCoordinates):
+		return http.StatusBadRequest
+	default:
+		return fallback
+	}
+}
+
+// GetInspectionCheckEvents handles GET /api/v1/inspections/:id/check-events
+func (h *InspectionCheckHandler) GetInspectionCheckEvents(c *gin.Context) {
+	events, err := h.checkService.GetCheckEvents(c.Request.Context(), c.GetString("organization_id"), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch check events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"events": events})
+}
+
+// GetOffSiteInspections handles GET /api/v1/inspections/off-site?from=&to=&site_id=&inspector_id=
+// Dates are RFC 3339 or YYYY-MM-DD
+func (h *InspectionCheckHandler) GetOffSiteInspections(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
+	}
+
+	filters := make(map[string]interface{})
+	for _, key := range []string{"site_id", "inspector_id"} {
+		if value := c.Query(key); value != "" {
+			filters[key] = value
+		}
+	}
+	for _, key := range []string{"from", "to"} {
+		value := c.Query(key)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			if parsed, err = time.Parse("2006-01-02", value); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": key + " must be an RFC 3339 timestamp or YYYY-MM-DD date"})
+				return
+			}
+		}
+		filters[key] = parsed
+	}
+
+	rows, total, err := h.checkService.GetOffSiteInspections(c.Request.Context(), c.GetString("organization_id"), filters, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch off-site inspections"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"inspections": rows,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		},
+	})
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
 	"resource-mgmt/models"
@@ -13,15 +15,19 @@ import (
 )
 
 type InspectionHandler struct {
-	service *services.InspectionService
+	service      *services.InspectionService
+	auditService *services.AuditService
 }
 
 func NewInspectionHandler(service *services.InspectionService) *InspectionHandler {
-	return &InspectionHandler{service: service}
+	return &InspectionHandler{service: service, auditService: services.NewAuditService()}
 }
 
+// GetInspections handles GET /api/v1/inspections?inspector_id=&team_id=&status=&limit=&offset=
+// Users who can't view all inspections see their own, their led teams' and their teams' queues
 func (h *InspectionHandler) GetInspections(c *gin.Context) {
 	inspectorID := c.Query("inspector_id")
+	teamID := c.Query("team_id")
 	status := c.Query("status")
 	limit := c.DefaultQuery("limit", "20")
 	offset := c.DefaultQuery("offset", "0")
@@ -29,7 +35,12 @@ func (h *InspectionHandler) GetInspections(c *gin.Context) {
 	limitInt, _ := strconv.Atoi(limit)
 	offsetInt, _ := strconv.Atoi(offset)
 
-	inspections, total, err := h.service.GetInspections(c.Request.Context(), inspectorID, status, limitInt, offsetInt)
+	visibleTo := ""
+	if !hasPermission(c, "can_view_all_inspections") {
+		visibleTo = c.GetString("user_id")
+	}
+
+	inspections, total, err := h.service.GetInspections(c.Request.Context(), inspectorID, teamID, status, visibleTo, limitInt, offsetInt)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -43,6 +54,32 @@ func (h *InspectionHandler) GetInspections(c *gin.Context) {
 	})
 }
 
+// GetOverdueInspections handles GET /api/v1/inspections/overdue?limit=&offset=
+// Open inspections past their due date, the most overdue first
+func (h *InspectionHandler) GetOverdueInspections(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	inspections, total, err := h.service.GetOverdueInspections(c.Request.Context(), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"inspections": inspections,
+		"total":       total,
+		"limit":       limit,
+		"offset":      offset,
+	})
+}
+
 func (h *InspectionHandler) GetInspection(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 64)
@@ -65,6 +102,8 @@ type CreateInspectionAPIRequest struct {
 	InspectorID    string                 `json:"inspector_id" binding:"required"`
 	AssignedBy     *string                `json:"assigned_by"`
 	SiteID         string                 `json:"site_id" binding:"required"`
+	LocationNodeID *string                `json:"location_node_id"`
+	AssetID        *string                `json:"asset_id"`
 	Priority       string                 `json:"priority"`
 	ScheduledFor   *string                `json:"scheduled_for"`
 	DueDate        *string                `json:"due_date"`
@@ -73,6 +112,16 @@ type CreateInspectionAPIRequest struct {
 	InspectionData map[string]interface{} `json:"inspection_data"`
 }
 
+// isInspectionPlacementError reports errors caused by a location or asset that
+// doesn't fit the inspection's site
+func isInspectionPlacementError(err error) bool {
+	return errors.Is(err, services.ErrLocationNotFound) ||
+		errors.Is(err, services.ErrLocationNotInSite) ||
+		errors.Is(err, services.ErrAssetNotFound) ||
+		errors.Is(err, services.ErrAssetNotInSite) ||
+		errors.Is(err, services.ErrAssetRetired)
+}
+
 func (h *InspectionHandler) CreateInspection(c *gin.Context) {
 	var apiReq CreateInspectionAPIRequest
 	if err := c.ShouldBindJSON(&apiReq); err != nil {
@@ -146,6 +195,8 @@ func (h *InspectionHandler) CreateInspection(c *gin.Context) {
 		InspectorID: inspectorID,
 		AssignedBy:  apiReq.AssignedBy,
 		SiteID:      apiReq.SiteID,
+		LocationNodeID: apiReq.LocationNodeID,
+		AssetID:     apiReq.AssetID,
 		Priority:    apiReq.Priority,
 		ScheduledFor: scheduledFor,
 		DueDate:     dueDate,
@@ -156,12 +207,22 @@ func (h *InspectionHandler) CreateInspection(c *gin.Context) {
 	log.Printf("Service request: %+v", req)
 
 	inspection, err := h.service.CreateInspection(c.Request.Context(), req)
+	if isInspectionPlacementError(err) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrInspectorNotQualified) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		log.Printf("Error creating inspection: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	recordAudit(c, h.auditService, services.InspectionCreated, "inspection", inspection.ID.String(), nil, inspection)
+
 	// If inspection data is provided, save it
 	if len(apiReq.InspectionData) > 0 {
 		submitReq := &models.SubmitInspectionRequest{
@@ -194,12 +255,24 @@ func (h *InspectionHandler) UpdateInspection(c *gin.Context) {
 		return
 	}
 
+	before, err := h.service.GetInspectionByID(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Inspection not found"})
+		return
+	}
+
 	inspection, err := h.service.UpdateInspection(c.Request.Context(), uint(id), &req)
+	if isInspectionPlacementError(err) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	recordAudit(c, h.auditService, services.InspectionUpdated, "inspection", before.ID.String(), before, inspection)
+
 	c.JSON(http.StatusOK, inspection)
 }
 
@@ -211,12 +284,20 @@ func (h *InspectionHandler) DeleteInspection(c *gin.Context) {
 		return
 	}
 
+	before, err := h.service.GetInspectionByID(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Inspection not found"})
+		return
+	}
+
 	err = h.service.DeleteInspection(c.Request.Context(), uint(id))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	recordAudit(c, h.auditService, services.InspectionDeleted, "inspection", before.ID.String(), before, nil)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Inspection deleted successfully"})
 }
 
@@ -234,12 +315,22 @@ func (h *InspectionHandler) SubmitInspection(c *gin.Context) {
 		return
 	}
 
+	before, err := h.service.GetInspectionByUUID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Inspection not found"})
+		return
+	}
+
 	inspection, err := h.service.SubmitInspection(c.Request.Context(), id, &req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	if after, err := h.service.GetInspectionByUUID(c.Request.Context(), id); err == nil {
+		recordAudit(c, h.auditService, services.InspectionUpdated, "inspection", id.String(), before, after)
+	}
+
 	c.JSON(http.StatusOK, inspection)
 }
 
@@ -257,12 +348,20 @@ func (h *InspectionHandler) AssignInspection(c *gin.Context) {
 		return
 	}
 
+	before, err := h.service.GetInspectionByID(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Inspection not found"})
+		return
+	}
+
 	inspection, err := h.service.AssignInspection(c.Request.Context(), uint(id), &req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	recordAudit(c, h.auditService, services.InspectionUpdated, "inspection", before.ID.String(), before, inspection)
+
 	c.JSON(http.StatusOK, inspection)
 }
 
@@ -280,11 +379,70 @@ func (h *InspectionHandler) UpdateInspectionStatus(c *gin.Context) {
 		return
 	}
 
+	before, err := h.service.GetInspectionByID(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Inspection not found"})
+		return
+	}
+
 	inspection, err := h.service.UpdateInspectionStatus(c.Request.Context(), uint(id), &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(checkEventErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
+	recordAudit(c, h.auditService, services.InspectionUpdated, "inspection", before.ID.String(), before, inspection)
+
 	c.JSON(http.StatusOK, inspection)
 }
+
+// StartInspection handles POST /api/v1/inspections/:id/start
+// The optional device location is checked against the site geofence
+func (h *InspectionHandler) StartInspection(c *gin.Context) {
+	h.checkInOrOut(c, h.service.StartInspection)
+}
+
+// CompleteInspection handles POST /api/v1/inspections/:id/complete
+func (h *InspectionHandler) CompleteInspection(c *gin.Context) {
+	h.checkInOrOut(c, h.service.CompleteInspection)
+}
+
+func (h *InspectionHandler) checkInOrOut(c *gin.Context, transition func(context.Context, uint, *models.DeviceLocation) (*models.Inspection, error)) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid inspection ID"})
+		return
+	}
+
+	var req models.InspectionCheckRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	before, err := h.service.GetInspectionByID(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Inspection not found"})
+		return
+	}
+
+	inspection, err := transition(c.Request.Context(), uint(id), req.Location)
+	if err != nil {
+		c.JSON(checkEventErrorStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.InspectionUpdated, "inspection", before.ID.String(), before, inspection)
+
+	c.JSON(http.StatusOK, inspection)
+}
+
+// hasPermission reports whether the current user was granted the permission
+func hasPermission(c *gin.Context, permission string) bool {
+	permissions, _ := c.Get("user_permissions")
+	permsMap, _ := permissions.(map[string]interface{})
+	allowed, _ := permsMap[permission].(bool)
+	return allowed
+}
@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"resource-mgmt/models"
+	"resource-mgmt/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LocationHandler struct {
+	locationService *services.LocationService
+	auditService    *services.AuditService
+}
+
+func NewLocationHandler(locationService *services.LocationService) *LocationHandler {
+	return &LocationHandler{
+		locationService: locationService,
+		auditService:    services.NewAuditService(),
+	}
+}
+
+// locationErrorStatus maps location service errors to HTTP status codes
+func locationErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrLocationNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrLocationNotInSite),
+		errors.Is(err, services.ErrLocationNameRequired),
+		errors.Is(err, services.ErrInvalidLocationType):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrLocationCycle),
+		errors.Is(err, services.ErrLocationHasChildren),
+		errors.Is(err, services.ErrLocationHasActiveInspections):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GetSiteLocations handles GET /api/v1/sites/:id/locations
+func (h *LocationHandler) GetSiteLocations(c *gin.Context) {
+	tree, err := h.locationService.GetSiteTree(c.Request.Context(), c.GetString("organization_id"), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch site locations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"locations": tree})
+}
+
+// CreateSiteLocation handles POST /api/v1/sites/:id/locations
+func (h *LocationHandler) CreateSiteLocation(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req models.LocationNode
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	req.ID = ""
+	req.OrganizationID = c.GetString("organization_id")
+	req.SiteID = c.Param("id")
+	req.CreatedBy = userID
+	req.UpdatedBy = userID
+
+	node, err := h.locationService.CreateNode(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(locationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.LocationCreated, "location", node.ID, nil, node)
+
+	c.JSON(http.StatusCreated, gin.H{"location": node})
+}
+
+// GetLocation handles GET /api/v1/locations/:id
+func (h *LocationHandler) GetLocation(c *gin.Context) {
+	node, err := h.locationService.GetNode(c.Request.Context(), c.GetString("organization_id"), c.Param("id"))
+	if err != nil {
+		c.JSON(locationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"location": node})
+}
+
+// UpdateLocation handles PUT /api/v1/locations/:id
+func (h *LocationHandler) UpdateLocation(c *gin.Context) {
+	organizationID := c.GetString("organization_id")
+	nodeID := c.Param("id")
+
+	var updates map[string]interface{}
+	if err := c.ShouldBindJSON(&updates); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+	updates["updated_by"] = c.GetString("user_id")
+
+	before, err := h.locationService.GetNode(c.Request.Context(), organizationID, nodeID)
+	if err != nil {
+		c.JSON(locationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	node, err := h.locationService.UpdateNode(c.Request.Context(), organizationID, nodeID, updates)
+	if err != nil {
+		c.JSON(locationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.LocationUpdated, "location", nodeID, before, node)
+
+	c.JSON(http.StatusOK, gin.H{"location": node})
+}
+
+// DeleteLocation handles DELETE /api/v1/locations/:id
+func (h *LocationHandler) DeleteLocation(c *gin.Context) {
+	organizationID := c.GetString("organization_id")
+	nodeID := c.Param("id")
+
+	before, err := h.locationService.GetNode(c.Request.Context(), organizationID, nodeID)
+	if err != nil {
+		c.JSON(locationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.locationService.DeleteNode(c.Request.Context(), organizationID, nodeID); err != nil {
+		c.JSON(locationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.LocationDeleted, "location", nodeID, before, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Location deleted successfully"})
+}
+
+// MoveLocation handles POST /api/v1/locations/:id/move
+func (h *LocationHandler) MoveLocation(c *gin.Context) {
+	organizationID := c.GetString("organization_id")
+	nodeID := c.Param("id")
+
+	var req struct {
+		ParentID *string `json:"parent_id"` // null moves the location to the top level
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	before, err := h.locationService.GetNode(c.Request.Context(), organizationID, nodeID)
+	if err != nil {
+		c.JSON(locationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	node, err := h.locationService.MoveNode(c.Request.Context(), organizationID, nodeID, req.ParentID, c.GetString("user_id"))
+	if err != nil {
+		c.JSON(locationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.LocationMoved, "location", nodeID, before, node)
+
+	c.JSON(http.StatusOK, gin.H{"location": node})
+}
+
+// GetLocationSubtree handles GET /api/v1/locations/:id/subtree
+func (h *LocationHandler) GetLocationSubtree(c *gin.Context) {
+	node, err := h.locationService.GetSubtree(c.Request.Context(), c.GetString("organization_id"), c.Param("id"))
+	if err != nil {
+		c.JSON(locationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"location": node})
+}
+
+// GetLocationAncestors handles GET /api/v1/locations/:id/ancestors
+func (h *LocationHandler) GetLocationAncestors(c *gin.Context) {
+	ancestors, err := h.locationService.GetAncestors(c.Request.Context(), c.GetString("organization_id"), c.Param("id"))
+	if err != nil {
+		c.JSON(locationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ancestors": ancestors})
+}
+
+// GetLocationBreadcrumb handles GET /api/v1/locations/:id/breadcrumb
+func (h *LocationHandler) GetLocationBreadcrumb(c *gin.Context) {
+	breadcrumb, err := h.locationService.GetBreadcrumb(c.Request.Context(), c.GetString("organization_id"), c.Param("id"))
+	if err != nil {
+		c.JSON(locationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"breadcrumb": breadcrumb})
+}
+
+// GetLocationStats handles GET /api/v1/locations/:id/stats
+func (h *LocationHandler) GetLocationStats(c *gin.Context) {
+	stats, err := h.locationService.GetLocationStats(c.Request.Context(), c.GetString("organization_id"), c.Param("id"))
+	if err != nil {
+		c.JSON(locationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"stats": stats})
+}
+
+// GetLocationInspections handles GET /api/v1/locations/:id/inspections
+func (h *LocationHandler) GetLocationInspections(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+
+	inspections, total, err := h.locationService.GetLocationInspections(c.Request.Context(), c.GetString("organization_id"), c.Param("id"), page, limit)
+	if err != nil {
+		c.JSON(locationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"inspections": inspections,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		},
+	})
+}
+
+// GetLocationMoves handles GET /api/v1/locations/:id/moves
+func (h *LocationHandler) GetLocationMoves(c *gin.Context) {
+	moves, err := h.locationService.GetMoveHistory(c.Request.Context(), c.GetString("organization_id"), c.Param("id"))
+	if err != nil {
+		c.JSON(locationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"moves": moves})
+}
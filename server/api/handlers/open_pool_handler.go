@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"resource-mgmt/models"
+	"resource-mgmt/services"
+	"resource-mgmt/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OpenPoolHandler struct {
+	openPoolService *services.OpenPoolService
+	auditService    *services.AuditService
+}
+
+func NewOpenPoolHandler(openPoolService *services.OpenPoolService) *OpenPoolHandler {
+	return &OpenPoolHandler{
+		openPoolService: openPoolService,
+		auditService:    services.NewAuditService(),
+	}
+}
+
+// openPoolErrorStatus maps open pool service errors to HTTP status codes
+func openPoolErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidPoolItem):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrSelfAssignmentDisabled), errors.Is(err, services.ErrPoolClaimNotEligible):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrPoolItemNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrPoolItemUnavailable), errors.Is(err, services.ErrInspectorUnavailable), errors.Is(err, services.ErrInspectorNotQualified):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GetOpenPool handles GET /api/v1/pool?status=&project_id=&inspector_id=&max_distance_km=&due_before=&eligible_only=
+// Inspectors see open items with their own distance and eligibility; supervisors may
+// pick the inspector, or leave it out to browse the pool as a whole
+func (h *OpenPoolHandler) GetOpenPool(c *gin.Context) {
+	filters := services.PoolFilters{
+		Status:       c.Query("status"),
+		ProjectID:    c.Query("project_id"),
+		InspectorID:  c.Query("inspector_id"),
+		EligibleOnly: c.Query("eligible_only") == "true",
+	}
+	if !utils.HasHigherOrEqualPrivilege(c.GetString("user_role"), "supervisor") {
+		filters.Status = models.OpenPoolItemOpen
+		filters.InspectorID = c.GetString("user_id")
+	}
+
+	if value := c.Query("max_distance_km"); value != "" {
+		distance, err := strconv.ParseFloat(value, 64)
+		if err != nil || distance <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "max_distance_km must be a positive number"})
+			return
+		}
+		if filters.InspectorID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "max_distance_km needs an inspector_id to measure from"})
+			return
+		}
+		filters.MaxDistanceKm = &distance
+	}
+	if value := c.Query("due_before"); value != "" {
+		day, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "due_before must be a YYYY-MM-DD date"})
+			return
+		}
+		filters.DueBefore = &day
+	}
+
+	items, err := h.openPoolService.GetPool(c.Request.Context(), c.GetString("organization_id"), filters)
+	if err != nil {
+		c.JSON(openPoolErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// PublishToPool handles POST /api/v1/pool
+func (h *OpenPoolHandler) PublishToPool(c *gin.Context) {
+	var req models.PublishToPoolRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	items, err := h.openPoolService.Publish(c.Request.Context(), c.GetString("organization_id"), c.GetString("user_id"), &req)
+	if err != nil {
+		c.JSON(openPoolErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, item := range items {
+		recordAudit(c, h.auditService, services.PoolItemsPublished, "open_pool_item", item.ID, nil, item)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"items": items})
+}
+
+// ClaimPoolItem handles POST /api/v1/pool/:id/claim
+// The first eligible claim wins; later claims get 409
+func (h *OpenPoolHandler) ClaimPoolItem(c *gin.Context) {
+	item, err := h.openPoolService.Claim(c.Request.Context(), c.GetString("organization_id"), c.GetString("user_id"), c.Param("id"))
+	if err != nil {
+		c.JSON(openPoolErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.PoolItemClaimed, "open_pool_item", item.ID,
+		gin.H{"status": models.OpenPoolItemOpen}, gin.H{"status": item.Status, "claimed_by": item.ClaimedBy, "assignment_id": item.AssignmentID})
+
+	c.JSON(http.StatusOK, gin.H{"item": item})
+}
+
+// ConfirmPoolClaim handles POST /api/v1/pool/:id/confirm
+func (h *OpenPoolHandler) ConfirmPoolClaim(c *gin.Context) {
+	item, err := h.openPoolService.ConfirmClaim(c.Request.Context(), c.GetString("organization_id"), c.GetString("user_id"), c.Param("id"))
+	if err != nil {
+		c.JSON(openPoolErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.PoolClaimConfirmed, "open_pool_item", item.ID,
+		gin.H{"status": models.OpenPoolItemClaimed}, gin.H{"status": item.Status, "claimed_by": item.ClaimedBy, "assignment_id": item.AssignmentID})
+
+	c.JSON(http.StatusOK, gin.H{"item": item})
+}
+
+// RejectPoolClaim handles POST /api/v1/pool/:id/reject-claim
+// The item goes back to the pool
+func (h *OpenPoolHandler) RejectPoolClaim(c *gin.Context) {
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+			return
+		}
+	}
+
+	orgID := c.GetString("organization_id")
+	itemID := c.Param("id")
+	item, err := h.openPoolService.RejectClaim(c.Request.Context(), orgID, itemID, req.Reason)
+	if err != nil {
+		c.JSON(openPoolErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.PoolClaimRejected, "open_pool_item", item.ID,
+		gin.H{"status": models.OpenPoolItemClaimed}, gin.H{"status": item.Status, "reason": req.Reason})
+
+	c.JSON(http.StatusOK, gin.H{"item": item})
+}
+
+// WithdrawPoolItem handles POST /api/v1/pool/:id/withdraw
+func (h *OpenPoolHandler) WithdrawPoolItem(c *gin.Context) {
+	item, err := h.openPoolService.Withdraw(c.Request.Context(), c.GetString("organization_id"), c.Param("id"))
+	if err != nil {
+		c.JSON(openPoolErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.PoolItemWithdrawn, "open_pool_item", item.ID, nil, gin.H{"status": item.Status})
+
+	c.JSON(http.StatusOK, gin.H{"item": item})
+}
@@ -13,11 +13,13 @@ import (
 
 type OrganizationHandler struct {
 	organizationService *services.OrganizationService
+	auditService        *services.AuditService
 }
 
 func NewOrganizationHandler() *OrganizationHandler {
 	return &OrganizationHandler{
 		organizationService: services.NewOrganizationService(),
+		auditService:        services.NewAuditService(),
 	}
 }
 
@@ -67,12 +69,20 @@ func (h *OrganizationHandler) UpdateOrganization(c *gin.Context) {
 		return
 	}
 
+	before, err := h.organizationService.GetOrganizationByID(orgID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
 	org, err := h.organizationService.UpdateOrganization(orgID, &req)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	recordAudit(c, h.auditService, services.OrganizationSettingsUpdated, "organization", orgID, before, org)
+
 	c.JSON(http.StatusOK, org)
 }
 
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"resource-mgmt/models"
+	"resource-mgmt/services"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ProjectTimelineHandler struct {
+	timelineService *services.ProjectTimelineService
+	auditService    *services.AuditService
+}
+
+func NewProjectTimelineHandler(timelineService *services.ProjectTimelineService) *ProjectTimelineHandler {
+	return &ProjectTimelineHandler{
+		timelineService: timelineService,
+		auditService:    services.NewAuditService(),
+	}
+}
+
+// projectTimelineErrorStatus maps project timeline errors to HTTP status codes
+func projectTimelineErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidMilestone), errors.Is(err, services.ErrInvalidDependency):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrProjectNotFound), errors.Is(err, services.ErrMilestoneNotFound), errors.Is(err, services.ErrAssignmentNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GetProjectTimeline handles GET /api/v1/projects/:id/timeline
+// Tasks and milestones in a Gantt-friendly shape, with the critical path and projected completion
+func (h *ProjectTimelineHandler) GetProjectTimeline(c *gin.Context) {
+	timeline, err := h.timelineService.GetTimeline(c.Request.Context(), c.GetString("organization_id"), c.Param("id"))
+	if err != nil {
+		c.JSON(projectTimelineErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"timeline": timeline})
+}
+
+// GetMilestones handles GET /api/v1/projects/:id/milestones
+func (h *ProjectTimelineHandler) GetMilestones(c *gin.Context) {
+	milestones, err := h.timelineService.GetMilestones(c.Request.Context(), c.GetString("organization_id"), c.Param("id"))
+	if err != nil {
+		c.JSON(projectTimelineErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"milestones": milestones})
+}
+
+// CreateMilestone handles POST /api/v1/projects/:id/milestones
+// target_date is YYYY-MM-DD
+func (h *ProjectTimelineHandler) CreateMilestone(c *gin.Context) {
+	req, ok := bindMilestoneRequest(c)
+	if !ok {
+		return
+	}
+
+	milestone, err := h.timelineService.CreateMilestone(c.Request.Context(), c.GetString("organization_id"), c.Param("id"), c.GetString("user_id"), req)
+	if err != nil {
+		c.JSON(projectTimelineErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.MilestoneCreated, "project_milestone", milestone.ID, nil, milestone)
+
+	c.JSON(http.StatusCreated, gin.H{"milestone": milestone})
+}
+
+// UpdateMilestone handles PUT /api/v1/projects/:id/milestones/:milestone_id
+// The assignments and workflow steps given replace those tied to the milestone
+func (h *ProjectTimelineHandler) UpdateMilestone(c *gin.Context) {
+	req, ok := bindMilestoneRequest(c)
+	if !ok {
+		return
+	}
+
+	milestone, err := h.timelineService.UpdateMilestone(c.Request.Context(), c.GetString("organization_id"), c.Param("id"), c.Param("milestone_id"), req)
+	if err != nil {
+		c.JSON(projectTimelineErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.MilestoneUpdated, "project_milestone", milestone.ID, nil, milestone)
+
+	c.JSON(http.StatusOK, gin.H{"milestone": milestone})
+}
+
+// DeleteMilestone handles DELETE /api/v1/projects/:id/milestones/:milestone_id
+func (h *ProjectTimelineHandler) DeleteMilestone(c *gin.Context) {
+	if err := h.timelineService.DeleteMilestone(c.Request.Context(), c.GetString("organization_id"), c.Param("id"), c.Param("milestone_id")); err != nil {
+		c.JSON(projectTimelineErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.MilestoneDeleted, "project_milestone", c.Param("milestone_id"), nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Milestone deleted successfully"})
+}
+
+// SetTaskDependencies handles PUT /api/v1/projects/:id/assignments/:assignment_id/dependencies
+// Replaces the assignments that must be done before this one can start
+func (h *ProjectTimelineHandler) SetTaskDependencies(c *gin.Context) {
+	var req models.TaskDependenciesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	dependencies, err := h.timelineService.SetDependencies(c.Request.Context(), c.GetString("organization_id"), c.Param("id"),
+		c.Param("assignment_id"), c.GetString("user_id"), req.DependsOn)
+	if err != nil {
+		c.JSON(projectTimelineErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.TaskDependenciesUpdated, "inspection_assignment", c.Param("assignment_id"), nil, req)
+
+	c.JSON(http.StatusOK, gin.H{"dependencies": dependencies})
+}
+
+// bindMilestoneRequest reads a milestone request, writing the error response if it is invalid
+func bindMilestoneRequest(c *gin.Context) (*models.MilestoneRequest, bool) {
+	var body struct {
+		Name            string   `json:"name" binding:"required"`
+		Description     string   `json:"description"`
+		TargetDate      string   `json:"target_date" binding:"required"`
+		AssignmentIDs   []string `json:"assignment_ids"`
+		WorkflowStepIDs []string `json:"workflow_step_ids"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return nil, false
+	}
+	targetDate, err := time.Parse("2006-01-02", body.TargetDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "target_date must be a YYYY-MM-DD date"})
+		return nil, false
+	}
+
+	return &models.MilestoneRequest{
+		Name:            body.Name,
+		Description:     body.Description,
+		TargetDate:      targetDate,
+		AssignmentIDs:   body.AssignmentIDs,
+		WorkflowStepIDs: body.WorkflowStepIDs,
+	}, true
+}
@@ -0,0 +1,362 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"resource-mgmt/models"
+	"resource-mgmt/services"
+	"resource-mgmt/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// certificationEvidenceFileTypes are the content types accepted as certification evidence
+var certificationEvidenceFileTypes = []string{"application/pdf", "image/jpeg", "image/jpg", "image/png", "image/tiff", "image/webp"}
+
+// certificationEvidenceMaxSizeMB is the largest accepted evidence file
+const certificationEvidenceMaxSizeMB = 10
+
+type QualificationHandler struct {
+	qualificationService *services.QualificationService
+	auditService         *services.AuditService
+}
+
+func NewQualificationHandler(qualificationService *services.QualificationService) *QualificationHandler {
+	return &QualificationHandler{
+		qualificationService: qualificationService,
+		auditService:         services.NewAuditService(),
+	}
+}
+
+// qualificationErrorStatus maps qualification service errors to HTTP status codes
+func qualificationErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidQualification):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrCertificationTypeNotFound), errors.Is(err, services.ErrCertificationNotFound),
+		errors.Is(err, services.ErrProofInspectionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrCertificationTypeInUse), errors.Is(err, services.ErrCertificationRevoked),
+		errors.Is(err, services.ErrInspectorNotQualified):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GetCertificationTypes handles GET /api/v1/qualifications/types?include_inactive=
+func (h *QualificationHandler) GetCertificationTypes(c *gin.Context) {
+	types, err := h.qualificationService.GetCertificationTypes(c.Request.Context(), c.GetString("organization_id"), c.Query("include_inactive") == "true")
+	if err != nil {
+		c.JSON(qualificationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"types": types})
+}
+
+// CreateCertificationType handles POST /api/v1/qualifications/types
+func (h *QualificationHandler) CreateCertificationType(c *gin.Context) {
+	var req models.CertificationTypeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	certificationType, err := h.qualificationService.CreateCertificationType(c.Request.Context(), c.GetString("organization_id"), c.GetString("user_id"), &req)
+	if err != nil {
+		c.JSON(qualificationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.CertificationTypeCreated, "certification_type", certificationType.ID, nil, certificationType)
+
+	c.JSON(http.StatusCreated, gin.H{"type": certificationType})
+}
+
+// UpdateCertificationType handles PUT /api/v1/qualifications/types/:id
+func (h *QualificationHandler) UpdateCertificationType(c *gin.Context) {
+	var req models.CertificationTypeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	certificationType, err := h.qualificationService.UpdateCertificationType(c.Request.Context(), c.GetString("organization_id"), c.Param("id"), &req)
+	if err != nil {
+		c.JSON(qualificationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.CertificationTypeUpdated, "certification_type", certificationType.ID, nil, certificationType)
+
+	c.JSON(http.StatusOK, gin.H{"type": certificationType})
+}
+
+// DeleteCertificationType handles DELETE /api/v1/qualifications/types/:id
+// Types that certificates or templates use can only be deactivated
+func (h *QualificationHandler) DeleteCertificationType(c *gin.Context) {
+	if err := h.qualificationService.DeleteCertificationType(c.Request.Context(), c.GetString("organization_id"), c.Param("id")); err != nil {
+		c.JSON(qualificationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.CertificationTypeDeleted, "certification_type", c.Param("id"), nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Certification type deleted successfully"})
+}
+
+// GetCertifications handles GET /api/v1/qualifications/certifications?user_id=&certification_type_id=&status=
+// Inspectors only see their own certificates
+func (h *QualificationHandler) GetCertifications(c *gin.Context) {
+	filters := make(map[string]interface{})
+	for _, key := range []string{"user_id", "certification_type_id", "status"} {
+		if value := c.Query(key); value != "" {
+			filters[key] = value
+		}
+	}
+	if !utils.HasHigherOrEqualPrivilege(c.GetString("user_role"), "supervisor") {
+		filters["user_id"] = c.GetString("user_id")
+	}
+
+	certifications, err := h.qualificationService.GetCertifications(c.Request.Context(), c.GetString("organization_id"), filters)
+	if err != nil {
+		c.JSON(qualificationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"certifications": certifications})
+}
+
+// GetCertification handles GET /api/v1/qualifications/certifications/:id
+func (h *QualificationHandler) GetCertification(c *gin.Context) {
+	certification, err := h.qualificationService.GetCertification(c.Request.Context(), c.GetString("organization_id"), c.Param("id"),
+		c.GetString("user_id"), c.GetString("user_role"))
+	if err != nil {
+		c.JSON(qualificationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"certification": certification})
+}
+
+// CreateCertification handles POST /api/v1/qualifications/certifications
+// Dates are YYYY-MM-DD; expires_on defaults to the type's validity
+func (h *QualificationHandler) CreateCertification(c *gin.Context) {
+	var body struct {
+		UserID              string `json:"user_id" binding:"required"`
+		CertificationTypeID string `json:"certification_type_id" binding:"required"`
+		CertificateNumber   string `json:"certificate_number"`
+		IssuedOn            string `json:"issued_on" binding:"required"`
+		ExpiresOn           string `json:"expires_on"`
+		Notes               string `json:"notes"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	req := models.CertificationRequest{
+		UserID:              body.UserID,
+		CertificationTypeID: body.CertificationTypeID,
+		CertificateNumber:   body.CertificateNumber,
+		Notes:               body.Notes,
+	}
+	issuedOn, err := time.Parse("2006-01-02", body.IssuedOn)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "issued_on must be a YYYY-MM-DD date"})
+		return
+	}
+	req.IssuedOn = issuedOn
+	if body.ExpiresOn != "" {
+		expiresOn, err := time.Parse("2006-01-02", body.ExpiresOn)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expires_on must be a YYYY-MM-DD date"})
+			return
+		}
+		req.ExpiresOn = &expiresOn
+	}
+
+	certification, err := h.qualificationService.CreateCertification(c.Request.Context(), c.GetString("organization_id"), c.GetString("user_id"), &req)
+	if err != nil {
+		c.JSON(qualificationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.CertificationRecorded, "inspector_certification", certification.ID, nil, certification)
+
+	c.JSON(http.StatusCreated, gin.H{"certification": certification})
+}
+
+// RevokeCertification handles POST /api/v1/qualifications/certifications/:id/revoke
+// revoked_on (YYYY-MM-DD) defaults to today
+func (h *QualificationHandler) RevokeCertification(c *gin.Context) {
+	var body struct {
+		Reason    string `json:"reason" binding:"required"`
+		RevokedOn string `json:"revoked_on"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+	req := models.RevokeCertificationRequest{Reason: body.Reason}
+	if body.RevokedOn != "" {
+		revokedOn, err := time.Parse("2006-01-02", body.RevokedOn)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "revoked_on must be a YYYY-MM-DD date"})
+			return
+		}
+		req.RevokedOn = &revokedOn
+	}
+
+	certification, err := h.qualificationService.RevokeCertification(c.Request.Context(), c.GetString("organization_id"), c.Param("id"), c.GetString("user_id"), &req)
+	if err != nil {
+		c.JSON(qualificationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.CertificationRevoked, "inspector_certification", certification.ID,
+		nil, gin.H{"revoked_on": certification.RevokedOn, "reason": certification.RevokeReason})
+
+	c.JSON(http.StatusOK, gin.H{"certification": certification})
+}
+
+// AddCertificationEvidence handles POST /api/v1/qualifications/certifications/:id/evidence
+// Multipart form: file. The holder and supervisors may add evidence.
+func (h *QualificationHandler) AddCertificationEvidence(c *gin.Context) {
+	if err := c.Request.ParseMultipartForm(10 << 20); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse form"})
+		return
+	}
+	_, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file provided"})
+		return
+	}
+	if !isAllowedFileType(header.Header.Get("Content-Type"), certificationEvidenceFileTypes) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File type not allowed"})
+		return
+	}
+	if !services.IsValidFileSize(header.Size, certificationEvidenceMaxSizeMB) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("File size too large (max %dMB)", certificationEvidenceMaxSizeMB)})
+		return
+	}
+
+	evidence, err := h.qualificationService.AddEvidence(c.Request.Context(), c.GetString("organization_id"), c.Param("id"),
+		c.GetString("user_id"), c.GetString("user_role"), header)
+	if err != nil {
+		c.JSON(qualificationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.CertificationEvidenceAdded, "inspector_certification", evidence.CertificationID, nil, evidence)
+
+	c.JSON(http.StatusCreated, gin.H{"evidence": evidence})
+}
+
+// DownloadCertificationEvidence handles GET /api/v1/qualifications/certifications/:id/evidence/:evidence_id/download
+func (h *QualificationHandler) DownloadCertificationEvidence(c *gin.Context) {
+	evidence, localPath, url, err := h.qualificationService.EvidenceLocation(c.Request.Context(), c.GetString("organization_id"), c.Param("id"),
+		c.Param("evidence_id"), c.GetString("user_id"), c.GetString("user_role"))
+	if err != nil {
+		c.JSON(qualificationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	if url != "" {
+		c.Redirect(http.StatusFound, url)
+		return
+	}
+	c.FileAttachment(localPath, evidence.FileName)
+}
+
+// GetTemplateQualifications handles GET /api/v1/qualifications/templates/:template_id
+func (h *QualificationHandler) GetTemplateQualifications(c *gin.Context) {
+	types, err := h.qualificationService.GetTemplateQualifications(c.Request.Context(), c.GetString("organization_id"), c.Param("template_id"))
+	if err != nil {
+		c.JSON(qualificationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"required": types})
+}
+
+// SetTemplateQualifications handles PUT /api/v1/qualifications/templates/:template_id
+// Replaces the certification types every version of the template requires
+func (h *QualificationHandler) SetTemplateQualifications(c *gin.Context) {
+	var req models.TemplateQualificationsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	organizationID := c.GetString("organization_id")
+	before, err := h.qualificationService.GetTemplateQualifications(ctx, organizationID, c.Param("template_id"))
+	if err != nil {
+		c.JSON(qualificationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	types, err := h.qualificationService.SetTemplateQualifications(ctx, organizationID, c.Param("template_id"), c.GetString("user_id"), req.CertificationTypeIDs)
+	if err != nil {
+		c.JSON(qualificationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.TemplateQualificationsUpdated, "template", c.Param("template_id"), before, types)
+
+	c.JSON(http.StatusOK, gin.H{"required": types})
+}
+
+// CheckQualification handles GET /api/v1/qualifications/check?inspector_id=&template_id=&from=&to=
+// Dates are YYYY-MM-DD and default to today
+func (h *QualificationHandler) CheckQualification(c *gin.Context) {
+	inspectorID, templateID := c.Query("inspector_id"), c.Query("template_id")
+	if inspectorID == "" || templateID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "inspector_id and template_id are required"})
+		return
+	}
+
+	from := time.Now()
+	to := from
+	for key, target := range map[string]*time.Time{"from": &from, "to": &to} {
+		if value := c.Query(key); value != "" {
+			parsed, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": key + " must be a YYYY-MM-DD date"})
+				return
+			}
+			*target = parsed
+		}
+	}
+	if c.Query("to") == "" {
+		to = from
+	}
+	if to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must not be after to"})
+		return
+	}
+
+	check, err := h.qualificationService.CheckQualification(c.Request.Context(), c.GetString("organization_id"), inspectorID, templateID, from, to)
+	if err != nil {
+		c.JSON(qualificationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, check)
+}
+
+// GetQualificationProof handles GET /api/v1/qualifications/inspections/:inspection_id/proof
+// The certificates the inspector held on the day of the inspection, with their evidence
+func (h *QualificationHandler) GetQualificationProof(c *gin.Context) {
+	proof, err := h.qualificationService.GetQualificationProof(c.Request.Context(), c.GetString("organization_id"), c.Param("inspection_id"))
+	if err != nil {
+		c.JSON(qualificationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, proof)
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"resource-mgmt/models"
+	"resource-mgmt/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ScanTagHandler struct {
+	scanTagService *services.ScanTagService
+	auditService   *services.AuditService
+}
+
+func NewScanTagHandler(scanTagService *services.ScanTagService) *ScanTagHandler {
+	return &ScanTagHandler{
+		scanTagService: scanTagService,
+		auditService:   services.NewAuditService(),
+	}
+}
+
+// scanTagErrorStatus maps scan tag service errors to HTTP status codes
+func scanTagErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrScanTagNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidScanTag),
+		errors.Is(err, services.ErrInvalidScanTagEntity),
+		errors.Is(err, services.ErrInvalidScanTagRequest):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrScanTagRevoked):
+		return http.StatusGone
+	case errors.Is(err, services.ErrScanTagAlreadyBound),
+		errors.Is(err, services.ErrAssetRetired):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GetTags handles GET /api/v1/tags
+func (h *ScanTagHandler) GetTags(c *gin.Context) {
+	filters := make(map[string]interface{})
+	for _, key := range []string{"status", "entity_type", "entity_id"} {
+		if value := c.Query(key); value != "" {
+			filters[key] = value
+		}
+	}
+
+	tags, err := h.scanTagService.GetTags(c.Request.Context(), c.GetString("organization_id"), filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+// CreateTagSheet handles POST /api/v1/tags/sheets
+// Issues tags for the given sites or assets (plus optional blank tags) and returns a printable sheet
+func (h *ScanTagHandler) CreateTagSheet(c *gin.Context) {
+	var req struct {
+		EntityType string   `json:"entity_type"`
+		EntityIDs  []string `json:"entity_ids"`
+		BlankCount int      `json:"blank_count"`
+		Format     string   `json:"format"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+	if req.Format == "" {
+		req.Format = "pdf"
+	}
+	if req.Format != "pdf" && req.Format != "png" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format. Supported formats: pdf, png"})
+		return
+	}
+
+	tags, err := h.scanTagService.IssueTags(c.Request.Context(), c.GetString("organization_id"), c.GetString("user_id"), req.EntityType, req.EntityIDs, req.BlankCount)
+	if err != nil {
+		c.JSON(scanTagErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	data, contentType, filename, err := h.scanTagService.RenderTagSheet(tags, req.Format)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render tag sheet: " + err.Error()})
+		return
+	}
+
+	tagIDs := make([]string, len(tags))
+	for i := range tags {
+		tagIDs[i] = tags[i].ID
+	}
+	recordAudit(c, h.auditService, services.ScanTagsIssued, "scan_tag", "", nil, gin.H{
+		"entity_type": req.EntityType,
+		"tag_ids":     tagIDs,
+		"format":      req.Format,
+	})
+
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Length", strconv.Itoa(len(data)))
+
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// GetTagSheet handles GET /api/v1/tags/:id/sheet?format=png
+// Reprints a single existing tag
+func (h *ScanTagHandler) GetTagSheet(c *gin.Context) {
+	tag, err := h.scanTagService.GetTag(c.Request.Context(), c.GetString("organization_id"), c.Param("id"))
+	if err != nil {
+		c.JSON(scanTagErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if tag.Status == models.ScanTagStatusRevoked {
+		c.JSON(http.StatusGone, gin.H{"error": services.ErrScanTagRevoked.Error()})
+		return
+	}
+
+	format := c.DefaultQuery("format", "png")
+	if format != "pdf" && format != "png" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format. Supported formats: pdf, png"})
+		return
+	}
+
+	data, contentType, filename, err := h.scanTagService.RenderTagSheet([]models.ScanTag{*tag}, format)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render tag sheet: " + err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Length", strconv.Itoa(len(data)))
+
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// ResolveTag handles POST /api/v1/tags/resolve
+// Returns the site, applicable templates and the caller's open inspections for a scanned code
+func (h *ScanTagHandler) ResolveTag(c *gin.Context) {
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Scanned code is required"})
+		return
+	}
+
+	resolution, err := h.scanTagService.Resolve(c.Request.Context(), c.GetString("organization_id"), c.GetString("user_id"), c.GetString("user_role"), req.Code)
+	if err != nil {
+		c.JSON(scanTagErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"resolution": resolution})
+}
+
+// BindTag handles POST /api/v1/tags/:id/bind
+func (h *ScanTagHandler) BindTag(c *gin.Context) {
+	organizationID := c.GetString("organization_id")
+	tagID := c.Param("id")
+
+	var req struct {
+		EntityType string `json:"entity_type" binding:"required"`
+		EntityID   string `json:"entity_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "entity_type and entity_id are required"})
+		return
+	}
+
+	before, err := h.scanTagService.GetTag(c.Request.Context(), organizationID, tagID)
+	if err != nil {
+		c.JSON(scanTagErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	tag, err := h.scanTagService.BindTag(c.Request.Context(), organizationID, tagID, req.EntityType, req.EntityID, c.GetString("user_id"))
+	if err != nil {
+		c.JSON(scanTagErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.ScanTagBound, "scan_tag", tagID, before, tag)
+
+	c.JSON(http.StatusOK, gin.H{"tag": tag})
+}
+
+// RevokeTag handles POST /api/v1/tags/:id/revoke
+func (h *ScanTagHandler) RevokeTag(c *gin.Context) {
+	organizationID := c.GetString("organization_id")
+	tagID := c.Param("id")
+
+	before, err := h.scanTagService.GetTag(c.Request.Context(), organizationID, tagID)
+	if err != nil {
+		c.JSON(scanTagErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	tag, err := h.scanTagService.RevokeTag(c.Request.Context(), organizationID, tagID)
+	if err != nil {
+		c.JSON(scanTagErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.ScanTagRevoked, "scan_tag", tagID, before, tag)
+
+	c.JSON(http.StatusOK, gin.H{"tag": tag})
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"resource-mgmt/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SecurityHandler struct {
+	throttle     *services.LoginThrottleService
+	auditService *services.AuditService
+}
+
+func NewSecurityHandler(throttle *services.LoginThrottleService) *SecurityHandler {
+	return &SecurityHandler{
+		throttle:     throttle,
+		auditService: services.NewAuditService(),
+	}
+}
+
+// GetLockouts lists active login lockouts affecting the organization
+// GET /api/v1/security/lockouts
+func (h *SecurityHandler) GetLockouts(c *gin.Context) {
+	orgID := c.GetString("organization_id")
+
+	lockouts, err := h.throttle.ListLockouts(c.Request.Context(), orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": lockouts})
+}
+
+// UnlockLogin lifts a lockout on a member account or on a client IP
+// POST /api/v1/security/lockouts/unlock
+func (h *SecurityHandler) UnlockLogin(c *gin.Context) {
+	orgID := c.GetString("organization_id")
+	userID := c.GetString("user_id")
+
+	var req struct {
+		Email     string `json:"email"`
+		IPAddress string `json:"ip_address"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Email == "" && req.IPAddress == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "email or ip_address is required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	if req.Email != "" {
+		if err := h.throttle.UnlockMemberAccount(ctx, orgID, req.Email); err != nil {
+			if errors.Is(err, services.ErrLockoutNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "User not found in organization"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if req.IPAddress != "" {
+		if err := h.throttle.UnlockOrganizationIP(ctx, orgID, req.IPAddress); err != nil {
+			if errors.Is(err, services.ErrLockoutNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "No lockout found for this IP address"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	details := map[string]interface{}{
+		"email":      req.Email,
+		"ip_address": req.IPAddress,
+	}
+	if err := h.auditService.LogSecurityEvent(ctx, services.AccountUnlocked, userID, details, true, nil); err != nil {
+		log.Printf("Failed to audit login unlock: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Lockout removed"})
+}
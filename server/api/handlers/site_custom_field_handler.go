@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"resource-mgmt/models"
+	"resource-mgmt/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SiteCustomFieldHandler struct {
+	customFieldService *services.SiteCustomFieldService
+	auditService       *services.AuditService
+}
+
+func NewSiteCustomFieldHandler(customFieldService *services.SiteCustomFieldService) *SiteCustomFieldHandler {
+	return &SiteCustomFieldHandler{
+		customFieldService: customFieldService,
+		auditService:       services.NewAuditService(),
+	}
+}
+
+// customFieldErrorStatus maps custom field service errors to HTTP status codes
+func customFieldErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrCustomFieldNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidCustomField):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrCustomFieldExists):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GetCustomFields handles GET /api/v1/sites/custom-fields
+func (h *SiteCustomFieldHandler) GetCustomFields(c *gin.Context) {
+	fields, err := h.customFieldService.GetFields(c.Request.Context(), c.GetString("organization_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch custom fields"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"fields": fields})
+}
+
+// CreateCustomField handles POST /api/v1/sites/custom-fields
+func (h *SiteCustomFieldHandler) CreateCustomField(c *gin.Context) {
+	var req models.SiteCustomField
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	req.ID = ""
+	req.OrganizationID = c.GetString("organization_id")
+	req.CreatedBy = c.GetString("user_id")
+	req.UpdatedBy = req.CreatedBy
+
+	field, migration, err := h.customFieldService.CreateField(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(customFieldErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.SiteCustomFieldCreated, "site_custom_field", field.ID, nil, field)
+
+	c.JSON(http.StatusCreated, gin.H{"field": field, "migration": migration})
+}
+
+// UpdateCustomField handles PUT /api/v1/sites/custom-fields/:key
+// Existing sites are migrated to the new definition where possible and flagged otherwise
+func (h *SiteCustomFieldHandler) UpdateCustomField(c *gin.Context) {
+	var req models.UpdateSiteCustomFieldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	organizationID := c.GetString("organization_id")
+	before, err := h.customFieldService.GetField(ctx, organizationID, c.Param("key"))
+	if err != nil {
+		c.JSON(customFieldErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	field, migration, err := h.customFieldService.UpdateField(ctx, organizationID, c.GetString("user_id"), c.Param("key"), &req)
+	if err != nil {
+		c.JSON(customFieldErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.SiteCustomFieldUpdated, "site_custom_field", field.ID, before, field)
+
+	c.JSON(http.StatusOK, gin.H{"field": field, "migration": migration})
+}
+
+// DeleteCustomField handles DELETE /api/v1/sites/custom-fields/:key
+// Stored values are kept in site metadata but no longer validated
+func (h *SiteCustomFieldHandler) DeleteCustomField(c *gin.Context) {
+	ctx := c.Request.Context()
+	organizationID := c.GetString("organization_id")
+	before, err := h.customFieldService.GetField(ctx, organizationID, c.Param("key"))
+	if err != nil {
+		c.JSON(customFieldErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.customFieldService.DeleteField(ctx, organizationID, c.Param("key")); err != nil {
+		c.JSON(customFieldErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.SiteCustomFieldDeleted, "site_custom_field", before.ID, before, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Custom field deleted successfully"})
+}
+
+// GetCustomFieldViolations handles GET /api/v1/sites/custom-fields/violations?field_key=&site_id=
+// Lists sites that stopped conforming to the schema after it changed
+func (h *SiteCustomFieldHandler) GetCustomFieldViolations(c *gin.Context) {
+	filters := make(map[string]interface{})
+	for _, key := range []string{"field_key", "site_id"} {
+		if value := c.Query(key); value != "" {
+			filters[key] = value
+		}
+	}
+
+	violations, err := h.customFieldService.GetViolations(c.Request.Context(), c.GetString("organization_id"), filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch custom field violations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"violations": violations})
+}
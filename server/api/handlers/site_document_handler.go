@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"resource-mgmt/models"
+	"resource-mgmt/services"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// siteDocumentFileTypes are the content types accepted as site documents
+var siteDocumentFileTypes = []string{
+	"application/pdf", "image/jpeg", "image/jpg", "image/png", "image/tiff", "image/webp",
+	"application/msword",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	"application/vnd.ms-excel",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"image/vnd.dwg", "application/acad", "image/vnd.dxf",
+}
+
+// siteDocumentMaxSizeMB is the largest accepted document; floor plans run larger than attachments
+const siteDocumentMaxSizeMB = 25
+
+type SiteDocumentHandler struct {
+	documentService *services.SiteDocumentService
+	auditService    *services.AuditService
+}
+
+func NewSiteDocumentHandler(documentService *services.SiteDocumentService) *SiteDocumentHandler {
+	return &SiteDocumentHandler{
+		documentService: documentService,
+		auditService:    services.NewAuditService(),
+	}
+}
+
+// siteDocumentErrorStatus maps site document service errors to HTTP status codes
+func siteDocumentErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrSiteDocumentNotFound), errors.Is(err, services.ErrDocumentSiteNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidSiteDocument):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// parseSiteDocumentUpload reads the file and document details from a multipart form
+func parseSiteDocumentUpload(c *gin.Context) (*models.SiteDocumentUpload, *multipart.FileHeader, error) {
+	if err := c.Request.ParseMultipartForm(10 << 20); err != nil { // 10 MB in memory, the rest on disk
+		return nil, nil, errors.New("Failed to parse form")
+	}
+
+	_, header, err := c.Request.FormFile("file")
+	if err != nil {
+		return nil, nil, errors.New("No file provided")
+	}
+	if !isAllowedFileType(header.Header.Get("Content-Type"), siteDocumentFileTypes) {
+		return nil, nil, errors.New("File type not allowed")
+	}
+	if !services.IsValidFileSize(header.Size, siteDocumentMaxSizeMB) {
+		return nil, nil, fmt.Errorf("File size too large (max %dMB)", siteDocumentMaxSizeMB)
+	}
+
+	upload := &models.SiteDocumentUpload{
+		Category:    c.Request.FormValue("category"),
+		Title:       c.Request.FormValue("title"),
+		Description: c.Request.FormValue("description"),
+		Notes:       c.Request.FormValue("notes"),
+	}
+	for field, target := range map[string]**time.Time{"issued_on": &upload.IssuedOn, "expires_on": &upload.ExpiresOn} {
+		if raw := c.Request.FormValue(field); raw != "" {
+			date, err := time.Parse("2006-01-02", raw)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s must be a date (YYYY-MM-DD)", field)
+			}
+			*target = &date
+		}
+	}
+	if raw := c.Request.FormValue("reminder_days"); raw != "" {
+		days, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, nil, errors.New("reminder_days must be a number")
+		}
+		upload.ReminderDays = &days
+	}
+	if raw := c.Request.FormValue("allowed_roles"); raw != "" {
+		upload.AllowedRoles = strings.Split(raw, ",")
+	}
+
+	return upload, header, nil
+}
+
+// GetSiteDocuments handles GET /api/v1/sites/:id/documents?category=&expiry_status=
+func (h *SiteDocumentHandler) GetSiteDocuments(c *gin.Context) {
+	filters := make(map[string]interface{})
+	for _, key := range []string{"category", "expiry_status"} {
+		if value := c.Query(key); value != "" {
+			filters[key] = value
+		}
+	}
+
+	documents, err := h.documentService.GetDocuments(c.Request.Context(), c.GetString("organization_id"), c.Param("id"), c.GetString("user_role"), filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch site documents"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"documents": documents})
+}
+
+// GetSiteDocument handles GET /api/v1/sites/:id/documents/:document_id
+func (h *SiteDocumentHandler) GetSiteDocument(c *gin.Context) {
+	document, err := h.documentService.GetDocument(c.Request.Context(), c.GetString("organization_id"), c.Param("id"), c.Param("document_id"), c.GetString("user_role"))
+	if err != nil {
+		c.JSON(siteDocumentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"document": document})
+}
+
+// UploadSiteDocument handles POST /api/v1/sites/:id/documents
+// Multipart form: file, category, title, description, issued_on and expires_on
+// (YYYY-MM-DD), reminder_days, allowed_roles (comma-separated; empty for every member)
+func (h *SiteDocumentHandler) UploadSiteDocument(c *gin.Context) {
+	upload, header, err := parseSiteDocumentUpload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	document, err := h.documentService.UploadDocument(c.Request.Context(), c.GetString("organization_id"), c.Param("id"), c.GetString("user_id"), upload, header)
+	if err != nil {
+		c.JSON(siteDocumentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.SiteDocumentUploaded, "site_document", document.ID, nil, document)
+
+	c.JSON(http.StatusCreated, gin.H{"document": document})
+}
+
+// AddSiteDocumentVersion handles POST /api/v1/sites/:id/documents/:document_id/versions
+// Multipart form: file, issued_on, expires_on, notes. The new version becomes current.
+func (h *SiteDocumentHandler) AddSiteDocumentVersion(c *gin.Context) {
+	upload, header, err := parseSiteDocumentUpload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	organizationID := c.GetString("organization_id")
+	before, err := h.documentService.GetDocument(ctx, organizationID, c.Param("id"), c.Param("document_id"), "admin")
+	if err != nil {
+		c.JSON(siteDocumentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	document, err := h.documentService.AddVersion(ctx, organizationID, c.Param("id"), c.Param("document_id"), c.GetString("user_id"), upload, header)
+	if err != nil {
+		c.JSON(siteDocumentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.SiteDocumentVersioned, "site_document", document.ID,
+		gin.H{"version": before.CurrentVersion, "expires_on": before.ExpiresOn},
+		gin.H{"version": document.CurrentVersion, "expires_on": document.ExpiresOn})
+
+	c.JSON(http.StatusCreated, gin.H{"document": document})
+}
+
+// UpdateSiteDocument handles PUT /api/v1/sites/:id/documents/:document_id
+func (h *SiteDocumentHandler) UpdateSiteDocument(c *gin.Context) {
+	var req models.UpdateSiteDocumentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	organizationID := c.GetString("organization_id")
+	before, err := h.documentService.GetDocument(ctx, organizationID, c.Param("id"), c.Param("document_id"), "admin")
+	if err != nil {
+		c.JSON(siteDocumentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	document, err := h.documentService.UpdateDocument(ctx, organizationID, c.Param("id"), c.Param("document_id"), c.GetString("user_id"), &req)
+	if err != nil {
+		c.JSON(siteDocumentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.SiteDocumentUpdated, "site_document", document.ID, before, document)
+
+	c.JSON(http.StatusOK, gin.H{"document": document})
+}
+
+// DeleteSiteDocument handles DELETE /api/v1/sites/:id/documents/:document_id
+// Every version and its stored file is removed
+func (h *SiteDocumentHandler) DeleteSiteDocument(c *gin.Context) {
+	ctx := c.Request.Context()
+	organizationID := c.GetString("organization_id")
+	before, err := h.documentService.GetDocument(ctx, organizationID, c.Param("id"), c.Param("document_id"), "admin")
+	if err != nil {
+		c.JSON(siteDocumentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.documentService.DeleteDocument(ctx, organizationID, c.Param("id"), c.Param("document_id"), c.GetString("user_id")); err != nil {
+		c.JSON(siteDocumentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.SiteDocumentDeleted, "site_document", before.ID, before, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Site document deleted successfully"})
+}
+
+// DownloadSiteDocument handles GET /api/v1/sites/:id/documents/:document_id/download?version=
+// Serves the current version unless an earlier one is asked for
+func (h *SiteDocumentHandler) DownloadSiteDocument(c *gin.Context) {
+	version, err := strconv.Atoi(c.DefaultQuery("version", "0"))
+	if err != nil || version < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	document, file, err := h.documentService.GetVersion(ctx, c.GetString("organization_id"), c.Param("id"), c.Param("document_id"), c.GetString("user_role"), version)
+	if err != nil {
+		c.JSON(siteDocumentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	localPath, url, err := h.documentService.DownloadLocation(ctx, file)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to download document"})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.SiteDocumentDownloaded, "site_document", document.ID, nil, gin.H{"version": file.Version})
+
+	if url != "" {
+		c.Redirect(http.StatusFound, url)
+		return
+	}
+	c.FileAttachment(localPath, file.FileName)
+}
+
+// GetDocumentCompliance handles GET /api/v1/sites/documents/compliance?site_id=&category=
+// Lists, per site, documents that have expired or are about to
+func (h *SiteDocumentHandler) GetDocumentCompliance(c *gin.Context) {
+	filters := make(map[string]interface{})
+	for _, key := range []string{"site_id", "category"} {
+		if value := c.Query(key); value != "" {
+			filters[key] = value
+		}
+	}
+
+	compliance, err := h.documentService.GetCompliance(c.Request.Context(), c.GetString("organization_id"), c.GetString("user_role"), filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch document compliance"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sites": compliance})
+}
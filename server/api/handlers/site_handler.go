@@ -1,21 +1,26 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"resource-mgmt/models"
 	"resource-mgmt/services"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type SiteHandler struct {
-	siteService *services.SiteService
+	siteService  *services.SiteService
+	auditService *services.AuditService
 }
 
 func NewSiteHandler(siteService *services.SiteService) *SiteHandler {
 	return &SiteHandler{
-		siteService: siteService,
+		siteService:  siteService,
+		auditService: services.NewAuditService(),
 	}
 }
 
@@ -46,9 +51,19 @@ func (h *SiteHandler) GetSites(c *gin.Context) {
 	if siteType != "" {
 		filters["type"] = siteType
 	}
+	// Custom field filters, e.g. ?custom.site_code=NY-001
+	for key, values := range c.Request.URL.Query() {
+		if strings.HasPrefix(key, "custom.") && len(values) > 0 {
+			filters[key] = values[0]
+		}
+	}
 
-	sites, total, err := h.siteService.GetSites(filters, search, page, limit)
+	sites, total, err := h.siteService.GetSites(c.Request.Context(), filters, search, page, limit)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidSiteMetadata) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sites"})
 		return
 	}
@@ -68,7 +83,7 @@ func (h *SiteHandler) GetSite(c *gin.Context) {
 	siteID := c.Param("id")
 	organizationID, _ := c.Get("organization_id")
 
-	site, err := h.siteService.GetSite(siteID, organizationID.(string))
+	site, err := h.siteService.GetSite(c.Request.Context(), siteID, organizationID.(string))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Site not found"})
 		return
@@ -99,12 +114,18 @@ func (h *SiteHandler) CreateSite(c *gin.Context) {
 		return
 	}
 
-	site, err := h.siteService.CreateSite(&req)
+	site, err := h.siteService.CreateSite(c.Request.Context(), &req)
+	if errors.Is(err, services.ErrInvalidSiteMetadata) || errors.Is(err, services.ErrInvalidTimezone) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create site"})
 		return
 	}
 
+	recordAudit(c, h.auditService, services.SiteCreated, "site", site.ID, nil, site)
+
 	c.JSON(http.StatusCreated, gin.H{"site": site})
 }
 
@@ -123,12 +144,24 @@ func (h *SiteHandler) UpdateSite(c *gin.Context) {
 	// Set updater
 	updates["updated_by"] = userID.(string)
 
-	site, err := h.siteService.UpdateSite(siteID, organizationID.(string), updates)
+	before, err := h.siteService.GetSite(c.Request.Context(), siteID, organizationID.(string))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Site not found"})
+		return
+	}
+
+	site, err := h.siteService.UpdateSite(c.Request.Context(), siteID, organizationID.(string), updates)
+	if errors.Is(err, services.ErrInvalidSiteMetadata) || errors.Is(err, services.ErrInvalidTimezone) || errors.Is(err, services.ErrInvalidCoordinates) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update site"})
 		return
 	}
 
+	recordAudit(c, h.auditService, services.SiteUpdated, "site", siteID, before, site)
+
 	c.JSON(http.StatusOK, gin.H{"site": site})
 }
 
@@ -137,12 +170,20 @@ func (h *SiteHandler) DeleteSite(c *gin.Context) {
 	siteID := c.Param("id")
 	organizationID, _ := c.Get("organization_id")
 
-	err := h.siteService.DeleteSite(siteID, organizationID.(string))
+	before, err := h.siteService.GetSite(c.Request.Context(), siteID, organizationID.(string))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Site not found"})
+		return
+	}
+
+	err = h.siteService.DeleteSite(c.Request.Context(), siteID, organizationID.(string))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete site"})
 		return
 	}
 
+	recordAudit(c, h.auditService, services.SiteDeleted, "site", siteID, before, nil)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Site deleted successfully"})
 }
 
@@ -151,7 +192,7 @@ func (h *SiteHandler) GetSiteStats(c *gin.Context) {
 	siteID := c.Param("id")
 	organizationID, _ := c.Get("organization_id")
 
-	stats, err := h.siteService.GetSiteStats(siteID, organizationID.(string))
+	stats, err := h.siteService.GetSiteStats(c.Request.Context(), siteID, organizationID.(string))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch site statistics"})
 		return
@@ -169,7 +210,7 @@ func (h *SiteHandler) GetSiteInspections(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 
-	inspections, total, err := h.siteService.GetSiteInspections(siteID, organizationID.(string), page, limit)
+	inspections, total, err := h.siteService.GetSiteInspections(c.Request.Context(), siteID, organizationID.(string), page, limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch site inspections"})
 		return
@@ -189,11 +230,217 @@ func (h *SiteHandler) GetSiteInspections(c *gin.Context) {
 func (h *SiteHandler) GetActiveSites(c *gin.Context) {
 	organizationID, _ := c.Get("organization_id")
 
-	sites, err := h.siteService.GetActiveSites(organizationID.(string))
+	sites, err := h.siteService.GetActiveSites(c.Request.Context(), organizationID.(string))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch active sites"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"sites": sites})
-}
\ No newline at end of file
+}
+
+// geoErrorStatus maps geospatial query errors to HTTP status codes
+func geoErrorStatus(err error) int {
+	if errors.Is(err, services.ErrInvalidCoordinates) || errors.Is(err, services.ErrInvalidRadius) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// parseGeoPoint reads the lat and lng query parameters
+func parseGeoPoint(c *gin.Context) (models.GeoPoint, bool) {
+	lat, latErr := strconv.ParseFloat(c.Query("lat"), 64)
+	lng, lngErr := strconv.ParseFloat(c.Query("lng"), 64)
+	if latErr != nil || lngErr != nil {
+		return models.GeoPoint{}, false
+	}
+	return models.GeoPoint{Latitude: lat, Longitude: lng}, true
+}
+
+// parseGeoBounds reads a "min_lng,min_lat,max_lng,max_lat" bbox query parameter, the
+// order used by GeoJSON and most map libraries
+func parseGeoBounds(value string) (*models.GeoBounds, bool) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return nil, false
+	}
+	var coords [4]float64
+	for i, part := range parts {
+		coord, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, false
+		}
+		coords[i] = coord
+	}
+	return &models.GeoBounds{MinLongitude: coords[0], MinLatitude: coords[1], MaxLongitude: coords[2], MaxLatitude: coords[3]}, true
+}
+
+// siteGeoFilters collects the status and type query filters
+func siteGeoFilters(c *gin.Context) map[string]interface{} {
+	filters := make(map[string]interface{})
+	for _, key := range []string{"status", "type"} {
+		if value := c.Query(key); value != "" {
+			filters[key] = value
+		}
+	}
+	return filters
+}
+
+// GetNearbySites handles GET /api/v1/sites/nearby?lat=&lng=&radius_km=10
+func (h *SiteHandler) GetNearbySites(c *gin.Context) {
+	center, ok := parseGeoPoint(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "lat and lng are required"})
+		return
+	}
+	radiusKm, err := strconv.ParseFloat(c.DefaultQuery("radius_km", "10"), 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "radius_km must be a number"})
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+
+	sites, err := h.siteService.FindSitesWithinRadius(c.Request.Context(), c.GetString("organization_id"), center, radiusKm, siteGeoFilters(c), limit)
+	if err != nil {
+		c.JSON(geoErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sites": sites})
+}
+
+// GetNearestSites handles GET /api/v1/sites/nearest?lat=&lng=&k=5
+func (h *SiteHandler) GetNearestSites(c *gin.Context) {
+	center, ok := parseGeoPoint(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "lat and lng are required"})
+		return
+	}
+	k, _ := strconv.Atoi(c.DefaultQuery("k", "5"))
+
+	sites, err := h.siteService.FindNearestSites(c.Request.Context(), c.GetString("organization_id"), center, k)
+	if err != nil {
+		c.JSON(geoErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sites": sites})
+}
+
+// GetSitesInBounds handles GET /api/v1/sites/in-bounds?bbox=min_lng,min_lat,max_lng,max_lat
+func (h *SiteHandler) GetSitesInBounds(c *gin.Context) {
+	bounds, ok := parseGeoBounds(c.Query("bbox"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bbox must be min_lng,min_lat,max_lng,max_lat"})
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "500"))
+
+	sites, err := h.siteService.FindSitesInBounds(c.Request.Context(), c.GetString("organization_id"), *bounds, siteGeoFilters(c), limit)
+	if err != nil {
+		c.JSON(geoErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sites": sites})
+}
+
+// GetSitesGeoJSON handles GET /api/v1/sites/geojson?bbox=&color_by=compliance
+func (h *SiteHandler) GetSitesGeoJSON(c *gin.Context) {
+	var bounds *models.GeoBounds
+	if bbox := c.Query("bbox"); bbox != "" {
+		var ok bool
+		if bounds, ok = parseGeoBounds(bbox); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "bbox must be min_lng,min_lat,max_lng,max_lat"})
+			return
+		}
+	}
+
+	collection, err := h.siteService.GetSitesGeoJSON(c.Request.Context(), c.GetString("organization_id"), bounds, siteGeoFilters(c), c.Query("color_by") == "compliance")
+	if err != nil {
+		c.JSON(geoErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "application/geo+json")
+	c.JSON(http.StatusOK, collection)
+}
+
+// GetDueNearMe handles GET /api/v1/sites/due-near-me?lat=&lng=&radius_km=&within_days=7
+// The radius defaults to the inspector's maximum travel distance
+func (h *SiteHandler) GetDueNearMe(c *gin.Context) {
+	center, ok := parseGeoPoint(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "lat and lng are required"})
+		return
+	}
+	var radiusKm float64
+	if value := c.Query("radius_km"); value != "" {
+		var err error
+		if radiusKm, err = strconv.ParseFloat(value, 64); err != nil || radiusKm <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "radius_km must be a positive number"})
+			return
+		}
+	}
+	withinDays, err := strconv.Atoi(c.DefaultQuery("within_days", "7"))
+	if err != nil || withinDays < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "within_days must be a non-negative number"})
+		return
+	}
+
+	sites, err := h.siteService.GetDueNearby(c.Request.Context(), c.GetString("organization_id"), c.GetString("user_id"), center, radiusKm, time.Now().AddDate(0, 0, withinDays))
+	if err != nil {
+		c.JSON(geoErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sites": sites})
+}
+
+// geocodeErrorStatus maps geocode job errors to HTTP status codes
+func geocodeErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrGeocodingDisabled):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, services.ErrSiteGeocodeJobNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// StartGeocodeJob handles POST /api/v1/sites/geocode
+// Body: {"force": false}. Geocodes sites without coordinates in the background; force also
+// refreshes previously geocoded ones. Manual coordinates are never replaced.
+func (h *SiteHandler) StartGeocodeJob(c *gin.Context) {
+	var req struct {
+		Force bool `json:"force"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+			return
+		}
+	}
+
+	job, err := h.siteService.StartGeocodeJob(c.Request.Context(), c.GetString("organization_id"), c.GetString("user_id"), req.Force)
+	if err != nil {
+		c.JSON(geocodeErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.SitesGeocoded, "site_geocode_job", job.ID, nil, job)
+
+	c.JSON(http.StatusAccepted, gin.H{"job": job})
+}
+
+// GetGeocodeJob handles GET /api/v1/sites/geocode/:job_id
+func (h *SiteHandler) GetGeocodeJob(c *gin.Context) {
+	job, err := h.siteService.GetGeocodeJob(c.Request.Context(), c.GetString("organization_id"), c.Param("job_id"))
+	if err != nil {
+		c.JSON(geocodeErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"job": job, "progress": job.Progress()})
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"resource-mgmt/services"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SiteImportHandler struct {
+	importService *services.SiteImportService
+	auditService  *services.AuditService
+}
+
+func NewSiteImportHandler(importService *services.SiteImportService) *SiteImportHandler {
+	return &SiteImportHandler{
+		importService: importService,
+		auditService:  services.NewAuditService(),
+	}
+}
+
+// siteImportErrorStatus maps site import errors to HTTP status codes
+func siteImportErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidSiteImport):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrSiteImportHasErrors):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, services.ErrSiteImportJobNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// ImportSites handles POST /api/v1/sites/import
+// Multipart form: file (.csv or .xlsx), mapping (optional JSON object of site field to column
+// header), dry_run (true to only validate). A real import runs in the background; poll the
+// returned job for progress.
+func (h *SiteImportHandler) ImportSites(c *gin.Context) {
+	if err := c.Request.ParseMultipartForm(10 << 20); err != nil { // 10 MB limit
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse form"})
+		return
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file provided"})
+		return
+	}
+	defer file.Close()
+
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), ".")
+	if format != "csv" && format != "xlsx" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file type. Supported formats: csv, xlsx"})
+		return
+	}
+
+	mapping := map[string]string{}
+	if raw := c.Request.FormValue("mapping"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "mapping must be a JSON object of site field to column header"})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	organizationID := c.GetString("organization_id")
+
+	rows, err := h.importService.ParseSiteImport(ctx, organizationID, format, file, mapping)
+	if err != nil {
+		c.JSON(siteImportErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	if dryRun, _ := strconv.ParseBool(c.Request.FormValue("dry_run")); dryRun {
+		preview, err := h.importService.ValidateSiteImport(ctx, organizationID, rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate import"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"dry_run": true, "preview": preview})
+		return
+	}
+
+	job, preview, err := h.importService.StartImport(ctx, organizationID, c.GetString("user_id"), format, header.Filename, rows)
+	if err != nil {
+		response := gin.H{"error": err.Error()}
+		if preview != nil {
+			response["preview"] = preview
+		}
+		c.JSON(siteImportErrorStatus(err), response)
+		return
+	}
+
+	recordAudit(c, h.auditService, services.SitesImported, "site_import_job", job.ID, nil, gin.H{
+		"file_name": job.FileName,
+		"creates":   preview.Creates,
+		"updates":   preview.Updates,
+	})
+
+	c.JSON(http.StatusAccepted, gin.H{"job": job, "preview": preview})
+}
+
+// GetSiteImportJob handles GET /api/v1/sites/import/:job_id
+func (h *SiteImportHandler) GetSiteImportJob(c *gin.Context) {
+	job, err := h.importService.GetImportJob(c.Request.Context(), c.GetString("organization_id"), c.Param("job_id"))
+	if err != nil {
+		c.JSON(siteImportErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"job": job, "progress": job.Progress()})
+}
+
+// ExportSites handles GET /api/v1/sites/export?format=csv
+// The file uses the import columns, so it can be edited and imported back
+func (h *SiteImportHandler) ExportSites(c *gin.Context) {
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "xlsx" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format. Supported formats: csv, xlsx"})
+		return
+	}
+
+	data, contentType, filename, err := h.importService.ExportSites(c.Request.Context(), c.GetString("organization_id"), format)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export sites"})
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Length", strconv.Itoa(len(data)))
+
+	c.Data(http.StatusOK, contentType, data)
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"resource-mgmt/models"
+	"resource-mgmt/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SiteMergeHandler struct {
+	mergeService *services.SiteMergeService
+}
+
+// NewSiteMergeHandler creates the handler. Merges write their own audit entries, in the
+// same transaction as the merge.
+func NewSiteMergeHandler(mergeService *services.SiteMergeService) *SiteMergeHandler {
+	return &SiteMergeHandler{mergeService: mergeService}
+}
+
+// siteMergeErrorStatus maps site merge service errors to HTTP status codes
+func siteMergeErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidSiteMerge):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrSiteMergeSiteNotFound), errors.Is(err, services.ErrSiteMergeNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrSiteMergeNotUndoable):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GetDuplicateSites handles GET /api/v1/sites/duplicates?threshold=0.75&limit=50
+func (h *SiteMergeHandler) GetDuplicateSites(c *gin.Context) {
+	threshold, err := strconv.ParseFloat(c.DefaultQuery("threshold", strconv.FormatFloat(services.DefaultSiteDuplicateThreshold, 'f', -1, 64)), 64)
+	if err != nil || threshold < 0 || threshold > 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "threshold must be between 0 and 1"})
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit < 1 || limit > 500 {
+		limit = 50
+	}
+
+	candidates, err := h.mergeService.FindDuplicates(c.Request.Context(), c.GetString("organization_id"), threshold, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find duplicate sites"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"candidates": candidates, "threshold": threshold})
+}
+
+// MergeSites handles POST /api/v1/sites/merge
+// Folds merged_id into survivor_id; the merge can be undone for a while afterwards
+func (h *SiteMergeHandler) MergeSites(c *gin.Context) {
+	var req models.MergeSitesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "survivor_id and merged_id are required"})
+		return
+	}
+
+	merge, err := h.mergeService.MergeSites(c.Request.Context(), c.GetString("organization_id"), c.GetString("user_id"), req.SurvivorID, req.MergedID)
+	if err != nil {
+		c.JSON(siteMergeErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"merge": merge})
+}
+
+// GetSiteMerges handles GET /api/v1/sites/merges?site_id=&status=
+func (h *SiteMergeHandler) GetSiteMerges(c *gin.Context) {
+	filters := make(map[string]interface{})
+	for _, key := range []string{"site_id", "status"} {
+		if value := c.Query(key); value != "" {
+			filters[key] = value
+		}
+	}
+
+	merges, err := h.mergeService.GetMerges(c.Request.Context(), c.GetString("organization_id"), filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch site merges"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"merges": merges})
+}
+
+// UndoSiteMerge handles POST /api/v1/sites/merges/:merge_id/undo
+func (h *SiteMergeHandler) UndoSiteMerge(c *gin.Context) {
+	merge, err := h.mergeService.UndoMerge(c.Request.Context(), c.GetString("organization_id"), c.GetString("user_id"), c.Param("merge_id"))
+	if err != nil {
+		c.JSON(siteMergeErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"merge": merge})
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"resource-mgmt/models"
+	"resource-mgmt/services"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SLAHandler struct {
+	slaService   *services.SLAService
+	auditService *services.AuditService
+}
+
+func NewSLAHandler(slaService *services.SLAService) *SLAHandler {
+	return &SLAHandler{
+		slaService:   slaService,
+		auditService: services.NewAuditService(),
+	}
+}
+
+// slaErrorStatus maps SLA service errors to HTTP status codes
+func slaErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidSLAPolicy), errors.Is(err, services.ErrInvalidSLATarget):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrSLAPolicyNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrSLAPolicyExists), errors.Is(err, services.ErrSLAPolicyInUse), errors.Is(err, services.ErrSLAClockNotRunning):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GetSLAPolicies handles GET /api/v1/sla/policies
+func (h *SLAHandler) GetSLAPolicies(c *gin.Context) {
+	policies, err := h.slaService.GetPolicies(c.Request.Context(), c.GetString("organization_id"))
+	if err != nil {
+		c.JSON(slaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"policies": policies})
+}
+
+// CreateSLAPolicy handles POST /api/v1/sla/policies
+func (h *SLAHandler) CreateSLAPolicy(c *gin.Context) {
+	var req models.SLAPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	policy, err := h.slaService.CreatePolicy(c.Request.Context(), c.GetString("organization_id"), c.GetString("user_id"), &req)
+	if err != nil {
+		c.JSON(slaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.SLAPolicyCreated, "sla_policy", policy.ID, nil, policy)
+
+	c.JSON(http.StatusCreated, gin.H{"policy": policy})
+}
+
+// UpdateSLAPolicy handles PUT /api/v1/sla/policies/:id
+// Running clocks keep their deadlines; the new targets apply to clocks started afterwards
+func (h *SLAHandler) UpdateSLAPolicy(c *gin.Context) {
+	var req models.SLAPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	orgID := c.GetString("organization_id")
+	before, err := h.slaService.GetPolicy(c.Request.Context(), orgID, c.Param("id"))
+	if err != nil {
+		c.JSON(slaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	policy, err := h.slaService.UpdatePolicy(c.Request.Context(), orgID, before.ID, &req)
+	if err != nil {
+		c.JSON(slaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.SLAPolicyUpdated, "sla_policy", policy.ID, before, policy)
+
+	c.JSON(http.StatusOK, gin.H{"policy": policy})
+}
+
+// DeleteSLAPolicy handles DELETE /api/v1/sla/policies/:id
+// Policies that have timed work are kept for reporting and can only be deactivated
+func (h *SLAHandler) DeleteSLAPolicy(c *gin.Context) {
+	policy, err := h.slaService.DeletePolicy(c.Request.Context(), c.GetString("organization_id"), c.Param("id"))
+	if err != nil {
+		c.JSON(slaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.SLAPolicyDeleted, "sla_policy", policy.ID, policy, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "SLA policy deleted"})
+}
+
+// GetSLAClocks handles GET /api/v1/sla/clocks/:target_type/:target_id
+func (h *SLAHandler) GetSLAClocks(c *gin.Context) {
+	clocks, err := h.slaService.GetClocks(c.Request.Context(), c.GetString("organization_id"), c.Param("target_type"), c.Param("target_id"))
+	if err != nil {
+		c.JSON(slaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"clocks": clocks})
+}
+
+// PauseSLAClocks handles POST /api/v1/sla/clocks/:target_type/:target_id/pause
+// Used while the work waits on an external party; the time paused doesn't count
+func (h *SLAHandler) PauseSLAClocks(c *gin.Context) {
+	var req models.PauseSLARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	targetType, targetID := c.Param("target_type"), c.Param("target_id")
+	clocks, err := h.slaService.PauseClocks(c.Request.Context(), c.GetString("organization_id"), targetType, targetID, req.Reason, time.Now())
+	if err != nil {
+		c.JSON(slaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.SLAClocksPaused, targetType, targetID, nil, gin.H{"reason": req.Reason})
+
+	c.JSON(http.StatusOK, gin.H{"clocks": clocks})
+}
+
+// ResumeSLAClocks handles POST /api/v1/sla/clocks/:target_type/:target_id/resume
+func (h *SLAHandler) ResumeSLAClocks(c *gin.Context) {
+	targetType, targetID := c.Param("target_type"), c.Param("target_id")
+	clocks, err := h.slaService.ResumeClocks(c.Request.Context(), c.GetString("organization_id"), targetType, targetID, time.Now())
+	if err != nil {
+		c.JSON(slaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.SLAClocksResumed, targetType, targetID, nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{"clocks": clocks})
+}
+
+// GetSLABreaches handles GET /api/v1/sla/breaches?from=&to=&stage=
+// The range defaults to the last 30 days; to is inclusive
+func (h *SLAHandler) GetSLABreaches(c *gin.Context) {
+	to := time.Now()
+	from := to.AddDate(0, 0, -30)
+	for key, target := range map[string]*time.Time{"from": &from, "to": &to} {
+		if value := c.Query(key); value != "" {
+			parsed, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": key + " must be a YYYY-MM-DD date"})
+				return
+			}
+			if key == "to" {
+				parsed = parsed.AddDate(0, 0, 1)
+			}
+			*target = parsed
+		}
+	}
+	if !from.Before(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return
+	}
+
+	report, err := h.slaService.GetBreachReport(c.Request.Context(), c.GetString("organization_id"), from, to, c.Query("stage"))
+	if err != nil {
+		c.JSON(slaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"resource-mgmt/models"
+	"resource-mgmt/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TeamHandler struct {
+	teamService  *services.TeamService
+	auditService *services.AuditService
+}
+
+func NewTeamHandler(teamService *services.TeamService) *TeamHandler {
+	return &TeamHandler{
+		teamService:  teamService,
+		auditService: services.NewAuditService(),
+	}
+}
+
+// teamErrorStatus maps team service errors to HTTP status codes
+func teamErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidTeam):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrTeamForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrTeamNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrTeamInUse), errors.Is(err, services.ErrTeamQueueConflict), errors.Is(err, services.ErrAssignmentChanged),
+		errors.Is(err, services.ErrInspectorUnavailable), errors.Is(err, services.ErrInspectorNotQualified):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GetTeams handles GET /api/v1/teams?include_inactive=
+func (h *TeamHandler) GetTeams(c *gin.Context) {
+	teams, err := h.teamService.GetTeams(c.Request.Context(), c.GetString("organization_id"), c.Query("include_inactive") == "true")
+	if err != nil {
+		c.JSON(teamErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"teams": teams})
+}
+
+// GetTeam handles GET /api/v1/teams/:id
+func (h *TeamHandler) GetTeam(c *gin.Context) {
+	team, err := h.teamService.GetTeam(c.Request.Context(), c.GetString("organization_id"), c.Param("id"))
+	if err != nil {
+		c.JSON(teamErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"team": team})
+}
+
+// CreateTeam handles POST /api/v1/teams
+func (h *TeamHandler) CreateTeam(c *gin.Context) {
+	var req models.TeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	team, err := h.teamService.CreateTeam(c.Request.Context(), c.GetString("organization_id"), c.GetString("user_id"), &req)
+	if err != nil {
+		c.JSON(teamErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.TeamCreated, "team", team.ID, nil, team)
+
+	c.JSON(http.StatusCreated, gin.H{"team": team})
+}
+
+// UpdateTeam handles PUT /api/v1/teams/:id
+// An empty lead_id clears the lead
+func (h *TeamHandler) UpdateTeam(c *gin.Context) {
+	var req models.TeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	team, err := h.teamService.UpdateTeam(c.Request.Context(), c.GetString("organization_id"), c.Param("id"), c.GetString("user_id"), &req)
+	if err != nil {
+		c.JSON(teamErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.TeamUpdated, "team", team.ID, nil, team)
+
+	c.JSON(http.StatusOK, gin.H{"team": team})
+}
+
+// DeleteTeam handles DELETE /api/v1/teams/:id
+// Teams that work was queued for can only be deactivated
+func (h *TeamHandler) DeleteTeam(c *gin.Context) {
+	if err := h.teamService.DeleteTeam(c.Request.Context(), c.GetString("organization_id"), c.Param("id")); err != nil {
+		c.JSON(teamErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.TeamDeleted, "team", c.Param("id"), nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Team deleted successfully"})
+}
+
+// SetTeamMembers handles PUT /api/v1/teams/:id/members
+func (h *TeamHandler) SetTeamMembers(c *gin.Context) {
+	var req models.TeamMembersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	team, err := h.teamService.SetMembers(c.Request.Context(), c.GetString("organization_id"), c.Param("id"), c.GetString("user_id"), req.UserIDs)
+	if err != nil {
+		c.JSON(teamErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.TeamMembersUpdated, "team", team.ID, nil, req)
+
+	c.JSON(http.StatusOK, gin.H{"team": team})
+}
+
+// GetTeamQueue handles GET /api/v1/teams/:id/queue
+// The team's lead, members and supervisors can see it
+func (h *TeamHandler) GetTeamQueue(c *gin.Context) {
+	queue, err := h.teamService.GetQueue(c.Request.Context(), c.GetString("organization_id"), c.Param("id"),
+		c.GetString("user_id"), c.GetString("user_role"))
+	if err != nil {
+		c.JSON(teamErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"queue": queue})
+}
+
+// QueueToTeam handles POST /api/v1/teams/:id/queue
+// Only the team's lead and supervisors can queue work for a team
+func (h *TeamHandler) QueueToTeam(c *gin.Context) {
+	var req models.QueueToTeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	queue, err := h.teamService.Queue(c.Request.Context(), c.GetString("organization_id"), c.Param("id"),
+		c.GetString("user_id"), c.GetString("user_role"), &req)
+	if err != nil {
+		c.JSON(teamErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.TeamWorkQueued, "team", c.Param("id"), nil, req)
+
+	c.JSON(http.StatusOK, gin.H{"queue": queue})
+}
+
+// TakeFromTeamQueue handles POST /api/v1/teams/:id/queue/take
+// Members take work for themselves; the lead and supervisors can hand it to any member
+func (h *TeamHandler) TakeFromTeamQueue(c *gin.Context) {
+	var req models.TakeFromQueueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	assignment, inspection, err := h.teamService.Take(c.Request.Context(), c.GetString("organization_id"), c.Param("id"),
+		c.GetString("user_id"), c.GetString("user_role"), &req)
+	if err != nil {
+		c.JSON(teamErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.TeamWorkTaken, "team", c.Param("id"), nil, req)
+
+	c.JSON(http.StatusOK, gin.H{"assignment": assignment, "inspection": inspection})
+}
+
+// GetTeamWorkload handles GET /api/v1/teams/:id/workload
+// The team's lead and supervisors can see it
+func (h *TeamHandler) GetTeamWorkload(c *gin.Context) {
+	workload, err := h.teamService.GetWorkload(c.Request.Context(), c.GetString("organization_id"), c.Param("id"),
+		c.GetString("user_id"), c.GetString("user_role"))
+	if err != nil {
+		c.JSON(teamErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"workload": workload})
+}
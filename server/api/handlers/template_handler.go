@@ -12,11 +12,12 @@ import (
 )
 
 type TemplateHandler struct {
-	service *services.TemplateService
+	service      *services.TemplateService
+	auditService *services.AuditService
 }
 
 func NewTemplateHandler(service *services.TemplateService) *TemplateHandler {
-	return &TemplateHandler{service: service}
+	return &TemplateHandler{service: service, auditService: services.NewAuditService()}
 }
 
 func (h *TemplateHandler) GetTemplates(c *gin.Context) {
@@ -99,6 +100,8 @@ func (h *TemplateHandler) CreateTemplate(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, h.auditService, services.TemplateCreated, "template", template.ID.String(), nil, template)
+
 	c.JSON(http.StatusCreated, template)
 }
 
@@ -116,12 +119,20 @@ func (h *TemplateHandler) UpdateTemplate(c *gin.Context) {
 		return
 	}
 
+	before, err := h.service.GetTemplateByUUID(id, c.GetString("organization_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+
 	template, err := h.service.UpdateTemplateByUUID(id, &req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	recordAudit(c, h.auditService, services.TemplateUpdated, "template", id.String(), before, template)
+
 	c.JSON(http.StatusOK, template)
 }
 
@@ -133,12 +144,20 @@ func (h *TemplateHandler) DeleteTemplate(c *gin.Context) {
 		return
 	}
 
+	before, err := h.service.GetTemplateByUUID(id, c.GetString("organization_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+
 	err = h.service.DeleteTemplateByUUID(id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	recordAudit(c, h.auditService, services.TemplateDeleted, "template", id.String(), before, nil)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Template deleted successfully"})
 }
 
@@ -234,6 +253,8 @@ func (h *TemplateHandler) CreateTemplateVersion(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, h.auditService, services.TemplateCreated, "template", template.ID.String(), nil, template)
+
 	c.JSON(http.StatusCreated, template)
 }
 
@@ -271,6 +292,8 @@ func (h *TemplateHandler) DuplicateTemplate(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, h.auditService, services.TemplateCreated, "template", template.ID.String(), nil, template)
+
 	c.JSON(http.StatusCreated, template)
 }
 
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -13,12 +14,14 @@ import (
 type WorkflowHandler struct {
 	db              *gorm.DB
 	workflowService *services.WorkflowService
+	auditService    *services.AuditService
 }
 
 func NewWorkflowHandler(db *gorm.DB, workflowService *services.WorkflowService) *WorkflowHandler {
 	return &WorkflowHandler{
 		db:              db,
 		workflowService: workflowService,
+		auditService:    services.NewAuditService(),
 	}
 }
 
@@ -221,12 +224,20 @@ func (h *WorkflowHandler) CreateBulkAssignment(c *gin.Context) {
 		return
 	}
 
-	assignments, err := h.workflowService.CreateBulkAssignment(orgID, userID, req)
+	assignments, err := h.workflowService.CreateBulkAssignment(c.Request.Context(), orgID, userID, req)
 	if err != nil {
+		if errors.Is(err, services.ErrInspectorUnavailable) || errors.Is(err, services.ErrInspectorNotQualified) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	for i := range assignments {
+		recordAudit(c, h.auditService, services.AssignmentCreated, "assignment", assignments[i].ID, nil, &assignments[i])
+	}
+
 	c.JSON(http.StatusCreated, gin.H{"data": assignments})
 }
 
@@ -243,6 +254,7 @@ func (h *WorkflowHandler) GetInspectionAssignments(c *gin.Context) {
 	priority := c.Query("priority")
 	assignedTo := c.Query("assigned_to")
 	projectID := c.Query("project_id")
+	teamID := c.Query("team_id")
 	search := c.Query("search")
 	overdue := c.Query("overdue") == "true"
 
@@ -251,13 +263,14 @@ func (h *WorkflowHandler) GetInspectionAssignments(c *gin.Context) {
 		Priority:   priority,
 		AssignedTo: assignedTo,
 		ProjectID:  projectID,
+		TeamID:     teamID,
 		Search:     search,
 		Overdue:    overdue,
 		Page:       page,
 		Limit:      limit,
 	}
 
-	assignments, total, err := h.workflowService.GetInspectionAssignments(orgID, userID, filters)
+	assignments, total, err := h.workflowService.GetInspectionAssignments(c.Request.Context(), orgID, userID, filters)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -299,7 +312,17 @@ func (h *WorkflowHandler) AcceptAssignment(c *gin.Context) {
 	assignmentID := c.Param("assignment_id")
 	userID := c.GetString("user_id")
 
-	assignment, err := h.workflowService.AcceptAssignment(orgID, assignmentID, userID)
+	before, err := h.workflowService.GetInspectionAssignment(orgID, assignmentID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Assignment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	assignment, err := h.workflowService.AcceptAssignment(c.Request.Context(), orgID, assignmentID, userID)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Assignment not found"})
@@ -309,6 +332,8 @@ func (h *WorkflowHandler) AcceptAssignment(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, h.auditService, services.AssignmentUpdated, "assignment", assignmentID, before, assignment)
+
 	c.JSON(http.StatusOK, gin.H{"data": assignment})
 }
 
@@ -320,7 +345,8 @@ func (h *WorkflowHandler) RejectAssignment(c *gin.Context) {
 	userID := c.GetString("user_id")
 
 	var req struct {
-		Reason string `json:"reason" binding:"required"`
+		ReasonCode string `json:"reason_code"` // schedule_conflict, workload, qualification, distance, safety or other
+		Reason     string `json:"reason" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -328,7 +354,7 @@ func (h *WorkflowHandler) RejectAssignment(c *gin.Context) {
 		return
 	}
 
-	assignment, err := h.workflowService.RejectAssignment(orgID, assignmentID, userID, req.Reason)
+	before, err := h.workflowService.GetInspectionAssignment(orgID, assignmentID)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Assignment not found"})
@@ -338,6 +364,22 @@ func (h *WorkflowHandler) RejectAssignment(c *gin.Context) {
 		return
 	}
 
+	assignment, err := h.workflowService.RejectAssignment(c.Request.Context(), orgID, assignmentID, userID, req.ReasonCode, req.Reason)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Assignment not found"})
+			return
+		}
+		if errors.Is(err, services.ErrInvalidRejectionReason) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.AssignmentUpdated, "assignment", assignmentID, before, assignment)
+
 	c.JSON(http.StatusOK, gin.H{"data": assignment})
 }
 
@@ -359,16 +401,33 @@ func (h *WorkflowHandler) ReassignInspection(c *gin.Context) {
 		return
 	}
 
-	assignment, err := h.workflowService.ReassignInspection(orgID, assignmentID, userID, req.NewInspectorID, req.Reason, req.NotifyInspector)
+	before, err := h.workflowService.GetInspectionAssignment(orgID, assignmentID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Assignment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	assignment, err := h.workflowService.ReassignInspection(c.Request.Context(), orgID, assignmentID, userID, req.NewInspectorID, req.Reason, req.NotifyInspector)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Assignment not found"})
 			return
 		}
+		if errors.Is(err, services.ErrInspectorUnavailable) || errors.Is(err, services.ErrInspectorNotQualified) ||
+			errors.Is(err, services.ErrAssignmentChanged) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	recordAudit(c, h.auditService, services.AssignmentUpdated, "assignment", assignmentID, before, assignment)
+
 	c.JSON(http.StatusOK, gin.H{"data": assignment})
 }
 
@@ -405,6 +464,8 @@ func (h *WorkflowHandler) CreateInspectionReview(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, h.auditService, services.ReviewCreated, "review", review.ID, nil, review)
+
 	c.JSON(http.StatusCreated, gin.H{"data": review})
 }
 
@@ -473,7 +534,7 @@ func (h *WorkflowHandler) SubmitInspectionReview(c *gin.Context) {
 		return
 	}
 
-	review, err := h.workflowService.SubmitInspectionReview(orgID, reviewID, userID, req)
+	before, err := h.workflowService.GetInspectionReview(orgID, reviewID)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
@@ -483,6 +544,18 @@ func (h *WorkflowHandler) SubmitInspectionReview(c *gin.Context) {
 		return
 	}
 
+	review, err := h.workflowService.SubmitInspectionReview(c.Request.Context(), orgID, reviewID, userID, req)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.ReviewUpdated, "review", reviewID, before, review)
+
 	c.JSON(http.StatusOK, gin.H{"data": review})
 }
 
@@ -499,11 +572,13 @@ func (h *WorkflowHandler) GetInspectorWorkloads(c *gin.Context) {
 	available := c.Query("available")
 	overloaded := c.Query("overloaded") == "true"
 	search := c.Query("search")
+	teamID := c.Query("team_id")
 
 	filters := services.WorkloadFilters{
 		Available:  available,
 		Overloaded: overloaded,
 		Search:     search,
+		TeamID:     teamID,
 	}
 
 	workloads, err := h.workflowService.GetInspectorWorkloads(orgID, filters)
@@ -579,14 +654,14 @@ func (h *WorkflowHandler) GetWorkflowAnalytics(c *gin.Context) {
 }
 
 // GetProjectProgress retrieves project progress summary
-// GET /api/organizations/:org_id/projects/:project_id/progress
+// GET /api/v1/projects/:id/progress
 func (h *WorkflowHandler) GetProjectProgress(c *gin.Context) {
-	orgID := c.Param("org_id")
-	projectID := c.Param("project_id")
+	orgID := c.GetString("organization_id")
+	projectID := c.Param("id")
 
 	progress, err := h.workflowService.GetProjectProgress(orgID, projectID)
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
+		if errors.Is(err, services.ErrProjectNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
 			return
 		}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"resource-mgmt/services"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WorkloadHandler struct {
+	workloadMetrics *services.WorkloadMetricsService
+	auditService    *services.AuditService
+}
+
+func NewWorkloadHandler(workloadMetrics *services.WorkloadMetricsService) *WorkloadHandler {
+	return &WorkloadHandler{
+		workloadMetrics: workloadMetrics,
+		auditService:    services.NewAuditService(),
+	}
+}
+
+// GetWorkloadHistory handles GET /api/v1/workloads/inspectors/:inspector_id/history?from=&to=
+// The range defaults to the last 30 days
+func (h *WorkloadHandler) GetWorkloadHistory(c *gin.Context) {
+	inspectorID := c.Param("inspector_id")
+	if !canViewInspector(c, inspectorID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied to this inspector's workload"})
+		return
+	}
+
+	from, to := time.Now().AddDate(0, 0, -29), time.Now()
+	for key, target := range map[string]*time.Time{"from": &from, "to": &to} {
+		if value := c.Query(key); value != "" {
+			parsed, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": key + " must be a YYYY-MM-DD date"})
+				return
+			}
+			*target = parsed
+		}
+	}
+
+	history, err := h.workloadMetrics.GetHistory(c.Request.Context(), c.GetString("organization_id"), inspectorID, from, to)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidWorkloadRange) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch workload history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"inspector_id": inspectorID, "history": history})
+}
+
+// ReconcileWorkloads handles POST /api/v1/workloads/reconcile
+// Recounts the organization's workloads now instead of waiting for the nightly run
+func (h *WorkloadHandler) ReconcileWorkloads(c *gin.Context) {
+	orgID := c.GetString("organization_id")
+	result, err := h.workloadMetrics.ReconcileOrganization(c.Request.Context(), orgID, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reconcile workloads", "result": result})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.WorkloadsReconciled, "organization", orgID, nil, result)
+
+	c.JSON(http.StatusOK, gin.H{"result": result})
+}
@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"resource-mgmt/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditContextMiddleware records the caller's IP address and user agent in the
+// request context so audit entries written by services can include them
+func AuditContextMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := services.WithRequestMetadata(c.Request.Context(), c.ClientIP(), c.Request.UserAgent())
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
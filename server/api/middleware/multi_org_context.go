@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"resource-mgmt/services"
 	"strings"
@@ -111,7 +112,15 @@ func RequireOrganizationContext() gin.HandlerFunc {
 // OrganizationSwitchHandler handles organization switching
 func OrganizationSwitchHandler(authService *services.MultiOrgAuthService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userID, _ := c.Get("userID")
+		// Secure routes set user_id; MultiOrgContextMiddleware sets userID
+		userID := c.GetString("user_id")
+		if userID == "" {
+			userID = c.GetString("userID")
+		}
+		if userID == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
 
 		var req struct {
 			OrganizationID string `json:"organization_id" binding:"required"`
@@ -123,7 +132,12 @@ func OrganizationSwitchHandler(authService *services.MultiOrgAuthService) gin.Ha
 		}
 
 		// Switch organization and get new token
-		response, err := authService.SwitchOrganization(c.Request.Context(), userID.(string), req.OrganizationID)
+		currentToken := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		response, err := authService.SwitchOrganization(c.Request.Context(), userID, req.OrganizationID, currentToken)
+		if errors.Is(err, services.ErrSessionNotFound) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 			return
@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"resource-mgmt/config"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Rate limit bucket names used by route groups
+const (
+	RateLimitBucketAuth    = "auth"
+	RateLimitBucketUploads = "uploads"
+	RateLimitBucketExports = "exports"
+)
+
+// RateLimit allows Requests per Window for a single client, with bursts up to Requests
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// ParseRateLimit parses "<requests>/<window>", e.g. "20/1m" or "100/1h"
+func ParseRateLimit(value string) (RateLimit, error) {
+	parts := strings.SplitN(value, "/", 2)
+	if len(parts) != 2 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q, expected <requests>/<window>", value)
+	}
+	requests, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || requests <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid request count in rate limit %q", value)
+	}
+	window, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+	if err != nil || window <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid window in rate limit %q", value)
+	}
+	return RateLimit{Requests: requests, Window: window}, nil
+}
+
+// DefaultRateLimits returns the buckets configured through RATE_LIMIT_* variables.
+// Invalid values panic at startup rather than silently disabling a limit.
+func DefaultRateLimits() map[string]RateLimit {
+	limits := make(map[string]RateLimit)
+	for bucket, value := range map[string]string{
+		RateLimitBucketAuth:    config.RateLimitAuth,
+		RateLimitBucketUploads: config.RateLimitUploads,
+		RateLimitBucketExports: config.RateLimitExports,
+	} {
+		limit, err := ParseRateLimit(value)
+		if err != nil {
+			panic("Invalid rate limit configuration for " + bucket + ": " + err.Error())
+		}
+		limits[bucket] = limit
+	}
+	return limits
+}
+
+type tokenBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// RateLimiter is an in-memory token bucket limiter keyed by bucket name and client
+type RateLimiter struct {
+	mu      sync.Mutex
+	limits  map[string]RateLimit
+	buckets map[string]*tokenBucket
+	calls   int
+	now     func() time.Time
+}
+
+func NewRateLimiter(limits map[string]RateLimit) *RateLimiter {
+	return &RateLimiter{
+		limits:  limits,
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token from the client's bucket. When none is left it returns false
+// and how long until the next token is available.
+func (l *RateLimiter) Allow(bucket, client string) (bool, int, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limit, ok := l.limits[bucket]
+	if !ok {
+		return true, 0, 0
+	}
+
+	now := l.now()
+	rate := float64(limit.Requests) / limit.Window.Seconds() // tokens per second
+
+	key := bucket + "|" + client
+	b, exists := l.buckets[key]
+	if !exists {
+		b = &tokenBucket{tokens: float64(limit.Requests), lastSeen: now}
+		l.buckets[key] = b
+	} else {
+		b.tokens = math.Min(float64(limit.Requests), b.tokens+now.Sub(b.lastSeen).Seconds()*rate)
+		b.lastSeen = now
+	}
+
+	l.calls++
+	if l.calls%1000 == 0 {
+		l.evictIdle(now)
+	}
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+		return false, 0, wait
+	}
+	b.tokens--
+	return true, int(b.tokens), 0
+}
+
+// evictIdle drops buckets that have refilled completely; they hold no state
+func (l *RateLimiter) evictIdle(now time.Time) {
+	for key, b := range l.buckets {
+		bucket := key[:strings.Index(key, "|")]
+		if limit, ok := l.limits[bucket]; !ok || now.Sub(b.lastSeen) > limit.Window {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// RateLimitMiddleware limits requests per client in the named bucket. Authenticated
+// requests are keyed by user, anonymous ones by client IP.
+func RateLimitMiddleware(limiter *RateLimiter, bucket string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		client := c.GetString("user_id")
+		if client == "" {
+			client = c.ClientIP()
+		}
+
+		allowed, remaining, retryAfter := limiter.Allow(bucket, client)
+		if limit, ok := limiter.limits[bucket]; ok {
+			c.Header("X-RateLimit-Limit", strconv.Itoa(limit.Requests))
+			c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		}
+
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error": "Too many requests, please try again later",
+				"code":  "RATE_LIMITED",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -146,6 +146,8 @@ func isPublicEndpoint(path string) bool {
 		"/api/v1/auth/google/callback",
 		"/api/v1/auth/microsoft/login",
 		"/api/v1/auth/microsoft/callback",
+		"/api/v1/auth/oidc/login",
+		"/api/v1/auth/oidc/callback",
 		"/api/v1/organizations/check-domain",
 	}
 
@@ -1,8 +1,11 @@
 package middleware
 
 import (
+	"bytes"
 	"context"
+	"log"
 	"net/http"
+	"resource-mgmt/pkg/database"
 	"resource-mgmt/pkg/tenant"
 
 	"github.com/gin-gonic/gin"
@@ -155,6 +158,129 @@ func RequireSupervisorOrAdminContext() gin.HandlerFunc {
 	}
 }
 
+// TenantTransactionMiddleware runs the request inside a transaction restricted to the
+// caller's organization by row-level security. Repositories pick the transaction up from
+// the request context. It commits on success and rolls back on error responses or panics.
+// The response is held back until the commit, so a client is never told that writes
+// succeeded when they were rolled back.
+func TenantTransactionMiddleware(tenantDB *database.TenantDB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		organizationID, err := tenant.GetOrganizationID(ctx)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Organization context not found",
+				"code":  "MISSING_TENANT_CONTEXT",
+			})
+			c.Abort()
+			return
+		}
+
+		tx := tenantDB.DB.WithContext(ctx).Begin()
+		if tx.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+			c.Abort()
+			return
+		}
+
+		committed := false
+		defer func() {
+			if !committed {
+				tx.Rollback()
+			}
+		}()
+
+		if err := database.SetOrganizationContext(tx, organizationID).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set tenant context"})
+			c.Abort()
+			return
+		}
+
+		// Restored on panic too, so recovery middleware reaches the client
+		writer := &bufferedResponseWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = writer
+		defer func() { c.Writer = writer.ResponseWriter }()
+
+		c.Request = c.Request.WithContext(database.ContextWithTx(ctx, tx))
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		if writer.status >= http.StatusBadRequest || len(c.Errors) > 0 {
+			writer.flush()
+			return
+		}
+
+		if err := tx.Commit().Error; err != nil {
+			log.Printf("Failed to commit tenant transaction for %s: %v", c.Request.URL.Path, err)
+			for _, header := range []string{"Content-Type", "Content-Length", "Content-Disposition"} {
+				writer.Header().Del(header)
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save changes"})
+			return
+		}
+		committed = true
+		writer.flush()
+	}
+}
+
+// bufferedResponseWriter holds a response in memory until the tenant transaction is settled
+type bufferedResponseWriter struct {
+	gin.ResponseWriter
+	status  int
+	body    bytes.Buffer
+	written bool
+}
+
+func (w *bufferedResponseWriter) WriteHeader(code int) {
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *bufferedResponseWriter) WriteHeaderNow() {
+	w.written = true
+}
+
+func (w *bufferedResponseWriter) Write(data []byte) (int, error) {
+	w.written = true
+	return w.body.Write(data)
+}
+
+func (w *bufferedResponseWriter) WriteString(s string) (int, error) {
+	w.written = true
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedResponseWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedResponseWriter) Size() int {
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *bufferedResponseWriter) Written() bool {
+	return w.written
+}
+
+// Flush is a no-op: nothing may reach the client before the commit
+func (w *bufferedResponseWriter) Flush() {}
+
+// flush sends the held response to the client
+func (w *bufferedResponseWriter) flush() {
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.body.Len() == 0 {
+		w.ResponseWriter.WriteHeaderNow()
+		return
+	}
+	if _, err := w.ResponseWriter.Write(w.body.Bytes()); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
 // GetTenantContext extracts tenant context from Gin context
 func GetTenantContext(c *gin.Context) (*tenant.Context, error) {
 	return tenant.FromContext(c.Request.Context())
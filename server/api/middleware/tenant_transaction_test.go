@@ -0,0 +1,213 @@
+package middleware_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"resource-mgmt/handlers"
+	"resource-mgmt/middleware"
+	"resource-mgmt/pkg/database"
+	"resource-mgmt/pkg/tenant"
+	"resource-mgmt/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TenantTransactionTestSuite checks that routes behind TenantTransactionMiddleware are held
+// to the caller's organization by row-level security, and that responses wait for the commit
+type TenantTransactionTestSuite struct {
+	suite.Suite
+	db     *gorm.DB
+	router *gin.Engine
+	testDB string
+	orgA   string
+	orgB   string
+	siteA  string
+	siteB  string
+}
+
+func testPostgresDSN(dbName string) string {
+	if dsn := os.Getenv("TEST_DATABASE_DSN"); dsn != "" {
+		return fmt.Sprintf("%s dbname=%s", dsn, dbName)
+	}
+	return fmt.Sprintf("host=localhost user=postgres password=password dbname=%s port=5432 sslmode=disable", dbName)
+}
+
+// SetupSuite creates a throwaway database with a sites table under the RLS migration
+func (suite *TenantTransactionTestSuite) SetupSuite() {
+	suite.testDB = "resource_mgmt_tenant_tx_test"
+
+	mainDB, err := gorm.Open(postgres.Open(testPostgresDSN("postgres")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		suite.T().Skip("PostgreSQL not available for testing")
+		return
+	}
+	rawDB, _ := mainDB.DB()
+	if err := rawDB.Ping(); err != nil {
+		suite.T().Skip("PostgreSQL not available for testing")
+		return
+	}
+	rawDB.Exec(fmt.Sprintf("DROP DATABASE IF EXISTS %s", suite.testDB))
+	if _, err := rawDB.Exec(fmt.Sprintf("CREATE DATABASE %s", suite.testDB)); err != nil {
+		suite.T().Skip("Cannot create test database")
+		return
+	}
+	rawDB.Close()
+
+	suite.db, err = gorm.Open(postgres.New(postgres.Config{
+		DSN:                  testPostgresDSN(suite.testDB),
+		PreferSimpleProtocol: true, // the migration contains multiple statements
+	}), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	suite.Require().NoError(err)
+
+	schema := `
+		CREATE TABLE organizations (id UUID PRIMARY KEY, name TEXT);
+		CREATE TABLE global_users (id UUID PRIMARY KEY, email TEXT);
+		CREATE TABLE organization_members (id UUID PRIMARY KEY DEFAULT gen_random_uuid(), user_id UUID, organization_id UUID);
+		CREATE TABLE sites (id UUID PRIMARY KEY DEFAULT gen_random_uuid(), organization_id UUID NOT NULL, name TEXT, address TEXT, deleted_at TIMESTAMPTZ);
+		CREATE TABLE visits (id INT, CONSTRAINT visits_unique UNIQUE (id) DEFERRABLE INITIALLY DEFERRED);
+	`
+	suite.Require().NoError(suite.db.Exec(schema).Error)
+	suite.Require().NoError(suite.db.Exec(readMigrationUp(suite.T(), "033_enable_row_level_security.sql")).Error)
+	suite.Require().NoError(suite.db.Exec("GRANT INSERT ON visits TO " + database.TenantRole).Error)
+
+	suite.orgA, suite.orgB = uuid.NewString(), uuid.NewString()
+	suite.siteA, suite.siteB = uuid.NewString(), uuid.NewString()
+	for org, site := range map[string]string{suite.orgA: suite.siteA, suite.orgB: suite.siteB} {
+		suite.Require().NoError(suite.db.Exec("INSERT INTO organizations (id, name) VALUES (?, ?)", org, "org "+org).Error)
+		suite.Require().NoError(suite.db.Exec("INSERT INTO sites (id, organization_id, name, address) VALUES (?, ?, ?, '1 High St')", site, org, "site of "+org).Error)
+	}
+
+	gin.SetMode(gin.TestMode)
+	suite.router = gin.New()
+	tenantScoped := suite.router.Group("/api/v1")
+	tenantScoped.Use(fakeAuth(), middleware.TenantTransactionMiddleware(database.NewTenantDB(suite.db)))
+	tenantScoped.GET("/sites/:id", handlers.NewSiteHandler(services.NewSiteService(suite.db)).GetSite)
+	tenantScoped.GET("/raw/sites/:id", func(c *gin.Context) {
+		var names []string
+		if err := database.Conn(c.Request.Context(), suite.db).Raw("SELECT name FROM sites WHERE id = ?", c.Param("id")).Scan(&names).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"names": names})
+	})
+	tenantScoped.POST("/visits", func(c *gin.Context) {
+		// The deferred constraint only fails at commit
+		db := database.Conn(c.Request.Context(), suite.db)
+		if err := db.Exec("INSERT INTO visits (id) VALUES (1), (1)").Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"message": "Visit recorded"})
+	})
+}
+
+// TearDownSuite drops the test database
+func (suite *TenantTransactionTestSuite) TearDownSuite() {
+	if suite.db != nil {
+		sqlDB, _ := suite.db.DB()
+		sqlDB.Close()
+	}
+
+	mainDB, err := gorm.Open(postgres.Open(testPostgresDSN("postgres")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err == nil {
+		rawDB, _ := mainDB.DB()
+		rawDB.Exec(fmt.Sprintf("DROP DATABASE IF EXISTS %s", suite.testDB))
+		rawDB.Close()
+	}
+}
+
+// fakeAuth stands in for SecureAuthMiddleware, taking the caller's organization from a header
+func fakeAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		orgID := c.GetHeader("X-Test-Organization")
+		c.Set("organization_id", orgID)
+		c.Set("user_id", "user-1")
+		c.Set("user_role", "admin")
+		c.Request = c.Request.WithContext(tenant.WithTenantContext(c.Request.Context(), tenant.NewContext(orgID, "user-1", "admin")))
+		c.Next()
+	}
+}
+
+// readMigrationUp returns the goose Up section of a migration file
+func readMigrationUp(t *testing.T, name string) string {
+	_, file, _, _ := runtime.Caller(0)
+	if resolved, err := filepath.EvalSymlinks(file); err == nil {
+		file = resolved
+	}
+	path := filepath.Join(filepath.Dir(file), "..", "..", "..", "database", "migrations", name)
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read migration %s: %v", path, err)
+	}
+
+	up := strings.SplitN(string(content), "-- +goose Down", 2)[0]
+	up = strings.ReplaceAll(up, "-- +goose StatementBegin", "")
+	return strings.ReplaceAll(up, "-- +goose StatementEnd", "")
+}
+
+func (suite *TenantTransactionTestSuite) request(method, path, orgID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("X-Test-Organization", orgID)
+	recorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func (suite *TenantTransactionTestSuite) TestCrossOrganizationSiteReadIsRejected() {
+	own := suite.request(http.MethodGet, "/api/v1/sites/"+suite.siteA, suite.orgA)
+	suite.Equal(http.StatusOK, own.Code)
+	suite.Contains(own.Body.String(), "site of "+suite.orgA)
+
+	other := suite.request(http.MethodGet, "/api/v1/sites/"+suite.siteB, suite.orgA)
+	suite.Equal(http.StatusNotFound, other.Code)
+	suite.NotContains(other.Body.String(), "site of "+suite.orgB)
+}
+
+func (suite *TenantTransactionTestSuite) TestUnfilteredQueriesOnlySeeOwnOrganization() {
+	tests := []struct {
+		name   string
+		siteID string
+		want   string
+	}{
+		{"own site", suite.siteA, "site of " + suite.orgA},
+		{"other organization's site", suite.siteB, `"names":[]`},
+	}
+	for _, tt := range tests {
+		suite.Run(tt.name, func() {
+			recorder := suite.request(http.MethodGet, "/api/v1/raw/sites/"+tt.siteID, suite.orgA)
+			suite.Equal(http.StatusOK, recorder.Code)
+			suite.Contains(recorder.Body.String(), tt.want)
+		})
+	}
+}
+
+func (suite *TenantTransactionTestSuite) TestFailedCommitReturnsServerError() {
+	recorder := suite.request(http.MethodPost, "/api/v1/visits", suite.orgA)
+	suite.Equal(http.StatusInternalServerError, recorder.Code)
+	suite.NotContains(recorder.Body.String(), "Visit recorded")
+
+	var count int64
+	suite.Require().NoError(suite.db.Raw("SELECT COUNT(*) FROM visits").Scan(&count).Error)
+	suite.Zero(count)
+}
+
+func TestTenantTransactionTestSuite(t *testing.T) {
+	suite.Run(t, new(TenantTransactionTestSuite))
+}
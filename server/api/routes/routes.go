@@ -64,6 +64,8 @@ func SetupRoutes(r *gin.Engine) {
 			auth.GET("/google/callback", authHandler.GoogleCallback)
 			auth.GET("/microsoft/login", authHandler.MicrosoftLogin)
 			auth.GET("/microsoft/callback", authHandler.MicrosoftCallback)
+			auth.GET("/oidc/login", authHandler.OIDCLogin)
+			auth.GET("/oidc/callback", authHandler.OIDCCallback)
 		}
 
 		// Organization registration (public)
@@ -82,6 +84,7 @@ func SetupRoutes(r *gin.Engine) {
 			authProtected.PUT("/profile", authHandler.UpdateProfile)
 			authProtected.POST("/change-password", authHandler.ChangePassword)
 			authProtected.POST("/refresh", authHandler.RefreshToken)
+			authProtected.GET("/identities", authHandler.GetIdentities)
 		}
 
 		// Inspection routes (protected)
@@ -99,16 +102,7 @@ func SetupRoutes(r *gin.Engine) {
 				}
 				c.JSON(200, statsData)
 			})
-			inspections.GET("/overdue", func(c *gin.Context) {
-				limit := 10
-				offset := 0
-				overdueInspections, _, err := inspectionService.GetOverdueInspections(c.Request.Context(), limit, offset)
-				if err != nil {
-					c.JSON(500, gin.H{"error": err.Error()})
-					return
-				}
-				c.JSON(200, gin.H{"inspections": overdueInspections, "total": len(overdueInspections)})
-			})
+			inspections.GET("/overdue", inspectionHandler.GetOverdueInspections)
 			inspections.GET("/:id", inspectionHandler.GetInspection)
 			inspections.PUT("/:id", middleware.RequirePermission("can_edit_inspections"), inspectionHandler.UpdateInspection)
 			inspections.DELETE("/:id", middleware.RequirePermission("can_delete_inspections"), inspectionHandler.DeleteInspection)
@@ -23,6 +23,12 @@ func SetupSecureRoutes(r *gin.Engine) {
 	attachmentService := services.NewAttachmentService()
 	analyticsService := services.NewAnalyticsService()
 	siteService := services.NewSiteService(config.DB)
+	locationService := services.NewLocationService(config.DB)
+	assetService := services.NewAssetService(config.DB)
+	scanTagService := services.NewScanTagService(config.DB)
+	inspectionCheckService := services.NewInspectionCheckService(config.DB)
+	siteImportService := services.NewSiteImportService(config.DB)
+	siteCustomFieldService := services.NewSiteCustomFieldService(config.DB)
 	orgValidator := services.NewOrganizationValidator()
 	notificationService := services.NewNotificationService()
 	workflowService := services.NewWorkflowService(config.DB, notificationService)
@@ -43,10 +49,36 @@ func SetupSecureRoutes(r *gin.Engine) {
 	attachmentHandler := handlers.NewAttachmentHandler(attachmentService, storageService, "./uploads")
 	analyticsHandler := handlers.NewAnalyticsHandler(analyticsService)
 	siteHandler := handlers.NewSiteHandler(siteService)
+	locationHandler := handlers.NewLocationHandler(locationService)
+	assetHandler := handlers.NewAssetHandler(assetService)
+	scanTagHandler := handlers.NewScanTagHandler(scanTagService)
+	inspectionCheckHandler := handlers.NewInspectionCheckHandler(inspectionCheckService)
+	siteImportHandler := handlers.NewSiteImportHandler(siteImportService)
+	siteCustomFieldHandler := handlers.NewSiteCustomFieldHandler(siteCustomFieldService)
+	siteDocumentHandler := handlers.NewSiteDocumentHandler(services.NewSiteDocumentService(config.DB, storageService))
+	siteMergeHandler := handlers.NewSiteMergeHandler(services.NewSiteMergeService(config.DB))
 	workflowHandler := handlers.NewWorkflowHandler(config.DB, workflowService)
+	assignmentSolverHandler := handlers.NewAssignmentSolverHandler(services.NewAssignmentSolverService(config.DB, workflowService))
+	availabilityHandler := handlers.NewAvailabilityHandler(services.NewAvailabilityService(config.DB, notificationService))
+	workloadHandler := handlers.NewWorkloadHandler(services.NewWorkloadMetricsService(config.DB))
+	openPoolHandler := handlers.NewOpenPoolHandler(services.NewOpenPoolService(config.DB, workflowService))
+	qualificationHandler := handlers.NewQualificationHandler(services.NewQualificationService(config.DB, storageService))
+	assignmentHistoryHandler := handlers.NewAssignmentHistoryHandler(services.NewAssignmentHistoryService(config.DB, workflowService, notificationService))
+	slaHandler := handlers.NewSLAHandler(services.NewSLAService(config.DB, notificationService))
+	teamHandler := handlers.NewTeamHandler(services.NewTeamService(config.DB, workflowService))
+	projectTimelineHandler := handlers.NewProjectTimelineHandler(services.NewProjectTimelineService(config.DB))
+	auditHandler := handlers.NewAuditHandler(services.NewAuditService())
+	securityHandler := handlers.NewSecurityHandler(services.DefaultLoginThrottle())
+
+	// Request rate limits per route group
+	rateLimiter := middleware.NewRateLimiter(middleware.DefaultRateLimits())
+	authRateLimit := middleware.RateLimitMiddleware(rateLimiter, middleware.RateLimitBucketAuth)
+	uploadRateLimit := middleware.RateLimitMiddleware(rateLimiter, middleware.RateLimitBucketUploads)
+	exportRateLimit := middleware.RateLimitMiddleware(rateLimiter, middleware.RateLimitBucketExports)
 
 	// API v1 routes
 	api := r.Group("/api/v1")
+	api.Use(middleware.AuditContextMiddleware())
 	{
 		// Health check (public)
 		api.GET("/health", func(c *gin.Context) {
@@ -59,6 +91,7 @@ func SetupSecureRoutes(r *gin.Engine) {
 
 		// Public authentication routes
 		auth := api.Group("/auth")
+		auth.Use(authRateLimit)
 		{
 			auth.POST("/login", authHandler.Login)
 			auth.POST("/register", authHandler.Register)
@@ -66,6 +99,8 @@ func SetupSecureRoutes(r *gin.Engine) {
 			auth.GET("/google/callback", authHandler.GoogleCallback)
 			auth.GET("/microsoft/login", authHandler.MicrosoftLogin)
 			auth.GET("/microsoft/callback", authHandler.MicrosoftCallback)
+			auth.GET("/oidc/login", authHandler.OIDCLogin)
+			auth.GET("/oidc/callback", authHandler.OIDCCallback)
 		}
 
 		// Organization registration (public)
@@ -86,11 +121,20 @@ func SetupSecureRoutes(r *gin.Engine) {
 				authProtected.PUT("/profile", authHandler.UpdateProfile)
 				authProtected.POST("/change-password", authHandler.ChangePassword)
 				authProtected.POST("/refresh", authHandler.RefreshToken)
+				authProtected.GET("/identities", authHandler.GetIdentities)
+				authProtected.GET("/organizations", authHandler.GetMyOrganizations)
+				authProtected.PUT("/organizations/:org_id/primary", authHandler.SetPrimaryOrganization)
+				authProtected.POST("/switch-organization", middleware.OrganizationSwitchHandler(multiOrgAuthService))
 				authProtected.POST("/logout", middleware.InvalidateSessionMiddleware())
 			}
 
+			// Organization data runs in a tenant transaction, so row-level security covers
+			// whatever the handlers below read or write
+			tenantScoped := protected.Group("")
+			tenantScoped.Use(middleware.TenantTransactionMiddleware(repoManager.TenantDB()))
+
 			// Inspection routes
-			inspections := protected.Group("/inspections")
+			inspections := tenantScoped.Group("/inspections")
 			{
 				inspections.GET("", inspectionHandler.GetInspections)
 				inspections.POST("", middleware.RequireSecurePermission("can_create_inspections"), inspectionHandler.CreateInspection)
@@ -102,16 +146,8 @@ func SetupSecureRoutes(r *gin.Engine) {
 					}
 					c.JSON(200, statsData)
 				})
-				inspections.GET("/overdue", middleware.RequireSecurePermission("can_view_reports"), func(c *gin.Context) {
-					limit := 10
-					offset := 0
-					overdueInspections, _, err := inspectionService.GetOverdueInspections(c.Request.Context(), limit, offset)
-					if err != nil {
-						c.JSON(500, gin.H{"error": err.Error()})
-						return
-					}
-					c.JSON(200, gin.H{"inspections": overdueInspections, "total": len(overdueInspections)})
-				})
+				inspections.GET("/overdue", middleware.RequireSecurePermission("can_view_reports"), inspectionHandler.GetOverdueInspections)
+				inspections.GET("/off-site", middleware.RequireSecurePermission("can_view_reports"), inspectionCheckHandler.GetOffSiteInspections)
 
 				// Individual inspection routes with resource validation
 				inspections.GET("/:id", validateInspectionAccess(orgValidator), inspectionHandler.GetInspection)
@@ -120,14 +156,17 @@ func SetupSecureRoutes(r *gin.Engine) {
 				inspections.POST("/:id/submit", validateInspectionAccess(orgValidator), middleware.RequireSecurePermission("can_edit_inspections"), inspectionHandler.SubmitInspection)
 				inspections.POST("/:id/assign", middleware.RequireSecureRole("admin", "supervisor"), inspectionHandler.AssignInspection)
 				inspections.PUT("/:id/status", validateInspectionAccess(orgValidator), middleware.RequireSecurePermission("can_edit_inspections"), inspectionHandler.UpdateInspectionStatus)
+				inspections.POST("/:id/start", validateInspectionAccess(orgValidator), middleware.RequireSecurePermission("can_edit_inspections"), inspectionHandler.StartInspection)
+				inspections.POST("/:id/complete", validateInspectionAccess(orgValidator), middleware.RequireSecurePermission("can_edit_inspections"), inspectionHandler.CompleteInspection)
+				inspections.GET("/:id/check-events", validateInspectionAccess(orgValidator), inspectionCheckHandler.GetInspectionCheckEvents)
 
 				// Attachment routes for inspections
-				inspections.POST("/:id/attachments", validateInspectionAccess(orgValidator), middleware.RequireSecurePermission("can_edit_inspections"), attachmentHandler.UploadFile)
+				inspections.POST("/:id/attachments", uploadRateLimit, validateInspectionAccess(orgValidator), middleware.RequireSecurePermission("can_edit_inspections"), attachmentHandler.UploadFile)
 				inspections.GET("/:id/attachments", validateInspectionAccess(orgValidator), attachmentHandler.GetAttachments)
 			}
 
 			// Attachment routes
-			attachments := protected.Group("/attachments")
+			attachments := tenantScoped.Group("/attachments")
 			{
 				attachments.GET("/:id", attachmentHandler.GetAttachment)
 				attachments.GET("/:id/download", attachmentHandler.DownloadFile)
@@ -135,7 +174,7 @@ func SetupSecureRoutes(r *gin.Engine) {
 			}
 
 			// Template routes
-			templates := protected.Group("/templates")
+			templates := tenantScoped.Group("/templates")
 			{
 				templates.GET("", templateHandler.GetTemplates)
 				templates.POST("", middleware.RequireSecurePermission("can_manage_templates"), templateHandler.CreateTemplate)
@@ -152,7 +191,7 @@ func SetupSecureRoutes(r *gin.Engine) {
 			}
 
 			// User management routes (admin/supervisor only)
-			users := protected.Group("/users")
+			users := tenantScoped.Group("/users")
 			{
 				users.GET("", middleware.RequireSecurePermission("can_manage_users"), userHandler.GetUsers)
 				users.POST("", middleware.RequireSecurePermission("can_manage_users"), userHandler.CreateUser)
@@ -166,7 +205,7 @@ func SetupSecureRoutes(r *gin.Engine) {
 			}
 
 			// Organization management
-			orgProtected := protected.Group("/organizations")
+			orgProtected := tenantScoped.Group("/organizations")
 			{
 				orgProtected.GET("/:id", middleware.RequireSecureRole("admin"), organizationHandler.GetOrganization)
 				orgProtected.PUT("/:id", middleware.RequireSecurePermission("can_manage_organization"), organizationHandler.UpdateOrganization)
@@ -176,7 +215,7 @@ func SetupSecureRoutes(r *gin.Engine) {
 			}
 
 			// Dashboard/Stats routes
-			stats := protected.Group("/stats")
+			stats := tenantScoped.Group("/stats")
 			{
 				stats.GET("/inspections", middleware.RequireSecurePermission("can_view_reports"), func(c *gin.Context) {
 					statsData, err := inspectionService.GetInspectionStats(c.Request.Context())
@@ -188,41 +227,232 @@ func SetupSecureRoutes(r *gin.Engine) {
 				})
 			}
 
+			// Audit trail routes (admin only)
+			audit := tenantScoped.Group("/audit")
+			audit.Use(middleware.RequireSecureRole("admin"))
+			{
+				audit.GET("/logs", auditHandler.GetAuditLogs)
+				audit.GET("/users/:id", auditHandler.GetUserAuditHistory)
+				audit.GET("/security-events", auditHandler.GetSecurityEvents)
+				audit.GET("/export/:format", exportRateLimit, auditHandler.ExportAuditLogs)
+				audit.GET("/verify", auditHandler.VerifyAuditChain)
+			}
+
+			// Login security routes (admin only)
+			security := tenantScoped.Group("/security")
+			security.Use(middleware.RequireSecureRole("admin"))
+			{
+				security.GET("/lockouts", securityHandler.GetLockouts)
+				security.POST("/lockouts/unlock", securityHandler.UnlockLogin)
+			}
+
 			// Analytics routes
-			analytics := protected.Group("/analytics")
+			analytics := tenantScoped.Group("/analytics")
 			{
 				analytics.GET("/dashboard", middleware.RequireSecurePermission("can_view_reports"), analyticsHandler.GetDashboardStats)
 				analytics.GET("/metrics", middleware.RequireSecurePermission("can_view_reports"), analyticsHandler.GetInspectionMetrics)
-				analytics.GET("/export/:format", middleware.RequireSecurePermission("can_export_reports"), analyticsHandler.ExportReport)
+				analytics.GET("/export/:format", exportRateLimit, middleware.RequireSecurePermission("can_export_reports"), analyticsHandler.ExportReport)
 			}
 
 			// Site routes
-			sites := protected.Group("/sites")
+			sites := tenantScoped.Group("/sites")
 			{
 				sites.GET("", siteHandler.GetSites)
 				sites.POST("", middleware.RequireSecurePermission("can_manage_sites"), siteHandler.CreateSite)
 				sites.GET("/active", siteHandler.GetActiveSites) // For dropdowns
+				sites.GET("/nearby", siteHandler.GetNearbySites)
+				sites.GET("/nearest", siteHandler.GetNearestSites)
+				sites.GET("/in-bounds", siteHandler.GetSitesInBounds)
+				sites.GET("/geojson", siteHandler.GetSitesGeoJSON)
+				sites.GET("/due-near-me", siteHandler.GetDueNearMe)
+				sites.POST("/import", middleware.RequireSecurePermission("can_manage_sites"), uploadRateLimit, siteImportHandler.ImportSites)
+				sites.GET("/import/:job_id", middleware.RequireSecurePermission("can_manage_sites"), siteImportHandler.GetSiteImportJob)
+				sites.GET("/export", middleware.RequireSecurePermission("can_manage_sites"), exportRateLimit, siteImportHandler.ExportSites)
+				sites.POST("/geocode", middleware.RequireSecurePermission("can_manage_sites"), siteHandler.StartGeocodeJob)
+				sites.GET("/geocode/:job_id", middleware.RequireSecurePermission("can_manage_sites"), siteHandler.GetGeocodeJob)
+				sites.GET("/custom-fields", siteCustomFieldHandler.GetCustomFields)
+				sites.POST("/custom-fields", middleware.RequireSecureRole("admin"), siteCustomFieldHandler.CreateCustomField)
+				sites.GET("/custom-fields/violations", middleware.RequireSecurePermission("can_manage_sites"), siteCustomFieldHandler.GetCustomFieldViolations)
+				sites.PUT("/custom-fields/:key", middleware.RequireSecureRole("admin"), siteCustomFieldHandler.UpdateCustomField)
+				sites.DELETE("/custom-fields/:key", middleware.RequireSecureRole("admin"), siteCustomFieldHandler.DeleteCustomField)
+				sites.GET("/duplicates", middleware.RequireSecurePermission("can_manage_sites"), siteMergeHandler.GetDuplicateSites)
+				sites.POST("/merge", middleware.RequireSecurePermission("can_manage_sites"), siteMergeHandler.MergeSites)
+				sites.GET("/merges", middleware.RequireSecurePermission("can_manage_sites"), siteMergeHandler.GetSiteMerges)
+				sites.POST("/merges/:merge_id/undo", middleware.RequireSecurePermission("can_manage_sites"), siteMergeHandler.UndoSiteMerge)
+				sites.GET("/documents/compliance", middleware.RequireSecurePermission("can_manage_sites"), siteDocumentHandler.GetDocumentCompliance)
 				sites.GET("/:id", validateSiteAccess(orgValidator), siteHandler.GetSite)
 				sites.PUT("/:id", validateSiteAccess(orgValidator), middleware.RequireSecurePermission("can_manage_sites"), siteHandler.UpdateSite)
 				sites.DELETE("/:id", validateSiteAccess(orgValidator), middleware.RequireSecurePermission("can_manage_sites"), siteHandler.DeleteSite)
 				sites.GET("/:id/stats", validateSiteAccess(orgValidator), siteHandler.GetSiteStats)
 				sites.GET("/:id/inspections", validateSiteAccess(orgValidator), siteHandler.GetSiteInspections)
+				sites.GET("/:id/locations", validateSiteAccess(orgValidator), locationHandler.GetSiteLocations)
+				sites.POST("/:id/locations", validateSiteAccess(orgValidator), middleware.RequireSecurePermission("can_manage_sites"), locationHandler.CreateSiteLocation)
+				sites.GET("/:id/documents", validateSiteAccess(orgValidator), siteDocumentHandler.GetSiteDocuments)
+				sites.POST("/:id/documents", validateSiteAccess(orgValidator), middleware.RequireSecurePermission("can_manage_sites"), uploadRateLimit, siteDocumentHandler.UploadSiteDocument)
+				sites.GET("/:id/documents/:document_id", validateSiteAccess(orgValidator), siteDocumentHandler.GetSiteDocument)
+				sites.PUT("/:id/documents/:document_id", validateSiteAccess(orgValidator), middleware.RequireSecurePermission("can_manage_sites"), siteDocumentHandler.UpdateSiteDocument)
+				sites.DELETE("/:id/documents/:document_id", validateSiteAccess(orgValidator), middleware.RequireSecurePermission("can_manage_sites"), siteDocumentHandler.DeleteSiteDocument)
+				sites.POST("/:id/documents/:document_id/versions", validateSiteAccess(orgValidator), middleware.RequireSecurePermission("can_manage_sites"), uploadRateLimit, siteDocumentHandler.AddSiteDocumentVersion)
+				sites.GET("/:id/documents/:document_id/download", validateSiteAccess(orgValidator), siteDocumentHandler.DownloadSiteDocument)
+			}
+
+			// Location hierarchy routes (regions, campuses, buildings, floors, rooms within a site)
+			locations := tenantScoped.Group("/locations")
+			{
+				locations.GET("/:id", locationHandler.GetLocation)
+				locations.PUT("/:id", middleware.RequireSecurePermission("can_manage_sites"), locationHandler.UpdateLocation)
+				locations.DELETE("/:id", middleware.RequireSecurePermission("can_manage_sites"), locationHandler.DeleteLocation)
+				locations.POST("/:id/move", middleware.RequireSecurePermission("can_manage_sites"), locationHandler.MoveLocation)
+				locations.GET("/:id/subtree", locationHandler.GetLocationSubtree)
+				locations.GET("/:id/ancestors", locationHandler.GetLocationAncestors)
+				locations.GET("/:id/breadcrumb", locationHandler.GetLocationBreadcrumb)
+				locations.GET("/:id/stats", locationHandler.GetLocationStats)
+				locations.GET("/:id/inspections", locationHandler.GetLocationInspections)
+				locations.GET("/:id/moves", locationHandler.GetLocationMoves)
+			}
+
+			// Asset registry routes (equipment inspected at sites)
+			assets := tenantScoped.Group("/assets")
+			{
+				assets.GET("", assetHandler.GetAssets)
+				assets.POST("", middleware.RequireSecurePermission("can_manage_sites"), assetHandler.CreateAsset)
+				assets.GET("/due", assetHandler.GetAssetsDue)
+				assets.GET("/:id", assetHandler.GetAsset)
+				assets.PUT("/:id", middleware.RequireSecurePermission("can_manage_sites"), assetHandler.UpdateAsset)
+				assets.DELETE("/:id", middleware.RequireSecurePermission("can_manage_sites"), assetHandler.DeleteAsset)
+				assets.POST("/:id/retire", middleware.RequireSecurePermission("can_manage_sites"), assetHandler.RetireAsset)
+				assets.GET("/:id/inspections", assetHandler.GetAssetInspections)
+				assets.GET("/:id/next-due", assetHandler.GetAssetNextDue)
+			}
+
+			// QR scan tag routes (printed tags that resolve to a site or asset)
+			tags := tenantScoped.Group("/tags")
+			{
+				tags.POST("/resolve", scanTagHandler.ResolveTag)
+				tags.GET("", middleware.RequireSecureRole("admin", "supervisor"), scanTagHandler.GetTags)
+				tags.POST("/sheets", exportRateLimit, middleware.RequireSecureRole("admin", "supervisor"), scanTagHandler.CreateTagSheet)
+				tags.GET("/:id/sheet", exportRateLimit, middleware.RequireSecureRole("admin", "supervisor"), scanTagHandler.GetTagSheet)
+				tags.POST("/:id/bind", middleware.RequireSecureRole("admin", "supervisor"), scanTagHandler.BindTag)
+				tags.POST("/:id/revoke", middleware.RequireSecureRole("admin", "supervisor"), scanTagHandler.RevokeTag)
 			}
 
 			// Assignment workflow routes (simplified - no org_id prefix)
-			assignments := protected.Group("/assignments")
+			assignments := tenantScoped.Group("/assignments")
 			{
-				assignments.GET("", middleware.RequireSecureRole("admin", "supervisor"), workflowHandler.GetInspectionAssignments)
+				assignments.GET("", workflowHandler.GetInspectionAssignments)
 				assignments.POST("", middleware.RequireSecureRole("admin", "supervisor"), workflowHandler.CreateBulkAssignment)
+				assignments.POST("/auto-assign", middleware.RequireSecureRole("admin", "supervisor"), assignmentSolverHandler.ProposeAssignments)
+				assignments.GET("/proposals", middleware.RequireSecureRole("admin", "supervisor"), assignmentSolverHandler.GetAssignmentProposals)
+				assignments.GET("/proposals/:proposal_id", middleware.RequireSecureRole("admin", "supervisor"), assignmentSolverHandler.GetAssignmentProposal)
+				assignments.POST("/proposals/:proposal_id/accept", middleware.RequireSecureRole("admin", "supervisor"), assignmentSolverHandler.AcceptAssignmentProposal)
+				assignments.POST("/proposals/:proposal_id/discard", middleware.RequireSecureRole("admin", "supervisor"), assignmentSolverHandler.DiscardAssignmentProposal)
+				assignments.GET("/delegations", assignmentHistoryHandler.GetDelegations)
+				assignments.POST("/delegations/:delegation_id/approve", middleware.RequireSecureRole("admin", "supervisor"), assignmentHistoryHandler.ApproveDelegation)
+				assignments.POST("/delegations/:delegation_id/decline", middleware.RequireSecureRole("admin", "supervisor"), assignmentHistoryHandler.DeclineDelegation)
+				assignments.POST("/delegations/:delegation_id/cancel", assignmentHistoryHandler.CancelDelegation)
+				assignments.GET("/rejections", middleware.RequireSecurePermission("can_view_reports"), assignmentHistoryHandler.GetRejectionReport)
 				assignments.GET("/:id", middleware.RequireSecureRole("admin", "supervisor"), workflowHandler.GetInspectionAssignment)
+				assignments.GET("/:id/timeline", assignmentHistoryHandler.GetAssignmentTimeline)
+				assignments.POST("/:id/delegations", assignmentHistoryHandler.RequestDelegation)
 			}
 
 			// Project workflow routes (simplified - no org_id prefix)
-			projects := protected.Group("/projects")
+			projects := tenantScoped.Group("/projects")
 			{
 				projects.GET("", middleware.RequireSecureRole("admin", "supervisor"), workflowHandler.GetInspectionProjects)
 				projects.POST("", middleware.RequireSecureRole("admin", "supervisor"), workflowHandler.CreateInspectionProject)
 				projects.GET("/:id", middleware.RequireSecureRole("admin", "supervisor"), workflowHandler.GetInspectionProject)
+				projects.GET("/:id/progress", middleware.RequireSecureRole("admin", "supervisor"), workflowHandler.GetProjectProgress)
+				projects.GET("/:id/timeline", middleware.RequireSecureRole("admin", "supervisor"), projectTimelineHandler.GetProjectTimeline)
+				projects.GET("/:id/milestones", middleware.RequireSecureRole("admin", "supervisor"), projectTimelineHandler.GetMilestones)
+				projects.POST("/:id/milestones", middleware.RequireSecureRole("admin", "supervisor"), projectTimelineHandler.CreateMilestone)
+				projects.PUT("/:id/milestones/:milestone_id", middleware.RequireSecureRole("admin", "supervisor"), projectTimelineHandler.UpdateMilestone)
+				projects.DELETE("/:id/milestones/:milestone_id", middleware.RequireSecureRole("admin", "supervisor"), projectTimelineHandler.DeleteMilestone)
+				projects.PUT("/:id/assignments/:assignment_id/dependencies", middleware.RequireSecureRole("admin", "supervisor"), projectTimelineHandler.SetTaskDependencies)
+			}
+
+			// Inspector availability: working hours, time off and holidays, plus the organization's business hours and due dates
+			availability := tenantScoped.Group("/availability")
+			{
+				availability.GET("/inspectors/:inspector_id/calendar", availabilityHandler.GetCalendar)
+				availability.GET("/inspectors/:inspector_id/working-hours", availabilityHandler.GetWorkingHours)
+				availability.PUT("/inspectors/:inspector_id/working-hours", middleware.RequireSecureRole("admin", "supervisor"), availabilityHandler.SetWorkingHours)
+				availability.GET("/time-off", availabilityHandler.GetTimeOff)
+				availability.POST("/time-off", availabilityHandler.RequestTimeOff)
+				availability.POST("/time-off/:id/approve", middleware.RequireSecureRole("admin", "supervisor"), availabilityHandler.ApproveTimeOff)
+				availability.POST("/time-off/:id/reject", middleware.RequireSecureRole("admin", "supervisor"), availabilityHandler.RejectTimeOff)
+				availability.POST("/time-off/:id/cancel", availabilityHandler.CancelTimeOff)
+				availability.GET("/holidays", availabilityHandler.GetHolidays)
+				availability.POST("/holidays", middleware.RequireSecureRole("admin"), availabilityHandler.CreateHoliday)
+				availability.DELETE("/holidays/:id", middleware.RequireSecureRole("admin"), availabilityHandler.DeleteHoliday)
+				availability.GET("/business-hours", availabilityHandler.GetBusinessHours)
+				availability.PUT("/business-hours", middleware.RequireSecureRole("admin"), availabilityHandler.SetBusinessHours)
+				availability.GET("/due-date", availabilityHandler.GetDueDate)
+			}
+
+			// Inspector workload history and reconciliation
+			workloads := tenantScoped.Group("/workloads")
+			{
+				workloads.GET("/inspectors/:inspector_id/history", workloadHandler.GetWorkloadHistory)
+				workloads.POST("/reconcile", middleware.RequireSecureRole("admin"), workloadHandler.ReconcileWorkloads)
+			}
+
+			// Open pool: unassigned work inspectors can claim for themselves
+			pool := tenantScoped.Group("/pool")
+			{
+				pool.GET("", openPoolHandler.GetOpenPool)
+				pool.POST("", middleware.RequireSecureRole("admin", "supervisor"), openPoolHandler.PublishToPool)
+				pool.POST("/:id/claim", openPoolHandler.ClaimPoolItem)
+				pool.POST("/:id/confirm", middleware.RequireSecureRole("admin", "supervisor"), openPoolHandler.ConfirmPoolClaim)
+				pool.POST("/:id/reject-claim", middleware.RequireSecureRole("admin", "supervisor"), openPoolHandler.RejectPoolClaim)
+				pool.POST("/:id/withdraw", middleware.RequireSecureRole("admin", "supervisor"), openPoolHandler.WithdrawPoolItem)
+			}
+
+			// SLA policies, the clocks they keep on assignments and inspections, and breaches
+			sla := tenantScoped.Group("/sla")
+			{
+				sla.GET("/policies", slaHandler.GetSLAPolicies)
+				sla.POST("/policies", middleware.RequireSecureRole("admin"), slaHandler.CreateSLAPolicy)
+				sla.PUT("/policies/:id", middleware.RequireSecureRole("admin"), slaHandler.UpdateSLAPolicy)
+				sla.DELETE("/policies/:id", middleware.RequireSecureRole("admin"), slaHandler.DeleteSLAPolicy)
+				sla.GET("/clocks/:target_type/:target_id", slaHandler.GetSLAClocks)
+				sla.POST("/clocks/:target_type/:target_id/pause", middleware.RequireSecureRole("admin", "supervisor"), slaHandler.PauseSLAClocks)
+				sla.POST("/clocks/:target_type/:target_id/resume", middleware.RequireSecureRole("admin", "supervisor"), slaHandler.ResumeSLAClocks)
+				sla.GET("/breaches", middleware.RequireSecurePermission("can_view_reports"), slaHandler.GetSLABreaches)
+			}
+
+			// Certification types, inspector certificates and the qualifications templates require
+			qualifications := tenantScoped.Group("/qualifications")
+			{
+				qualifications.GET("/types", qualificationHandler.GetCertificationTypes)
+				qualifications.POST("/types", middleware.RequireSecureRole("admin"), qualificationHandler.CreateCertificationType)
+				qualifications.PUT("/types/:id", middleware.RequireSecureRole("admin"), qualificationHandler.UpdateCertificationType)
+				qualifications.DELETE("/types/:id", middleware.RequireSecureRole("admin"), qualificationHandler.DeleteCertificationType)
+				qualifications.GET("/certifications", qualificationHandler.GetCertifications)
+				qualifications.POST("/certifications", middleware.RequireSecureRole("admin", "supervisor"), qualificationHandler.CreateCertification)
+				qualifications.GET("/certifications/:id", qualificationHandler.GetCertification)
+				qualifications.POST("/certifications/:id/revoke", middleware.RequireSecureRole("admin", "supervisor"), qualificationHandler.RevokeCertification)
+				qualifications.POST("/certifications/:id/evidence", uploadRateLimit, qualificationHandler.AddCertificationEvidence)
+				qualifications.GET("/certifications/:id/evidence/:evidence_id/download", qualificationHandler.DownloadCertificationEvidence)
+				qualifications.GET("/templates/:template_id", qualificationHandler.GetTemplateQualifications)
+				qualifications.PUT("/templates/:template_id", middleware.RequireSecureRole("admin", "supervisor"), qualificationHandler.SetTemplateQualifications)
+				qualifications.GET("/check", middleware.RequireSecureRole("admin", "supervisor"), qualificationHandler.CheckQualification)
+				qualifications.GET("/inspections/:inspection_id/proof", middleware.RequireSecurePermission("can_view_reports"), qualificationHandler.GetQualificationProof)
+			}
+
+			// Teams, their members and the work queued for them
+			teams := tenantScoped.Group("/teams")
+			{
+				teams.GET("", teamHandler.GetTeams)
+				teams.POST("", middleware.RequireSecureRole("admin", "supervisor"), teamHandler.CreateTeam)
+				teams.GET("/:id", teamHandler.GetTeam)
+				teams.PUT("/:id", middleware.RequireSecureRole("admin", "supervisor"), teamHandler.UpdateTeam)
+				teams.DELETE("/:id", middleware.RequireSecureRole("admin", "supervisor"), teamHandler.DeleteTeam)
+				teams.PUT("/:id/members", middleware.RequireSecureRole("admin", "supervisor"), teamHandler.SetTeamMembers)
+				teams.GET("/:id/queue", teamHandler.GetTeamQueue)
+				teams.POST("/:id/queue", teamHandler.QueueToTeam)
+				teams.POST("/:id/queue/take", teamHandler.TakeFromTeamQueue)
+				teams.GET("/:id/workload", teamHandler.GetTeamWorkload)
 			}
 		}
 	}
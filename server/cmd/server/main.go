@@ -1,11 +1,13 @@
 package main
 
 import (
+	"context"
 	"log"
 	"resource-mgmt/shared/config"
 	"resource-mgmt/shared/utils"
 	"resource-mgmt/server/api/routes"
 	"resource-mgmt/server/core/services"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -29,6 +31,51 @@ func main() {
 		log.Printf("Warning: Failed to seed default templates: %v", err)
 	}
 
+	// Site imports still running belonged to a process that stopped; their work was rolled back
+	if failed, err := services.NewSiteImportService(config.DB).FailInterruptedImports(context.Background()); err != nil {
+		log.Printf("Warning: Failed to clean up interrupted site imports: %v", err)
+	} else if failed > 0 {
+		log.Printf("Marked %d interrupted site imports as failed", failed)
+	}
+
+	// Remind site managers before permits and certificates expire
+	if interval, err := time.ParseDuration(config.SiteDocumentCheckInterval); err != nil {
+		log.Printf("Warning: Invalid SITE_DOCUMENT_CHECK_INTERVAL %q, document expiry check disabled", config.SiteDocumentCheckInterval)
+	} else if interval > 0 {
+		services.NewSiteDocumentService(config.DB, nil).StartExpiryChecker(context.Background(), interval)
+	}
+
+	// Warn inspectors and supervisors before certifications lapse
+	if interval, err := time.ParseDuration(config.CertificationCheckInterval); err != nil {
+		log.Printf("Warning: Invalid CERTIFICATION_CHECK_INTERVAL %q, certification expiry check disabled", config.CertificationCheckInterval)
+	} else if interval > 0 {
+		services.NewQualificationService(config.DB, nil).StartExpiryChecker(context.Background(), interval)
+	}
+
+	// Escalate open pool items nobody has claimed as their due date nears
+	if interval, err := time.ParseDuration(config.OpenPoolCheckInterval); err != nil {
+		log.Printf("Warning: Invalid OPEN_POOL_CHECK_INTERVAL %q, open pool escalation disabled", config.OpenPoolCheckInterval)
+	} else if interval > 0 {
+		services.NewOpenPoolService(config.DB, services.NewWorkflowService(config.DB, services.NewNotificationService())).StartEscalationChecker(context.Background(), interval)
+	}
+
+	// Escalate SLA clocks nearing or past their deadlines and record breaches
+	if interval, err := time.ParseDuration(config.SLACheckInterval); err != nil {
+		log.Printf("Warning: Invalid SLA_CHECK_INTERVAL %q, SLA escalation disabled", config.SLACheckInterval)
+	} else if interval > 0 {
+		services.NewSLAService(config.DB, services.NewNotificationService()).StartSLAChecker(context.Background(), interval)
+	}
+
+	// Recount inspector workloads and record their history every night
+	if config.WorkloadReconcileTime != "off" {
+		if at, err := time.Parse("15:04", config.WorkloadReconcileTime); err != nil {
+			log.Printf("Warning: Invalid WORKLOAD_RECONCILE_TIME %q, workload reconciliation disabled", config.WorkloadReconcileTime)
+		} else {
+			offset := time.Duration(at.Hour())*time.Hour + time.Duration(at.Minute())*time.Minute
+			services.NewWorkloadMetricsService(config.DB).StartNightlyReconciliation(context.Background(), offset)
+		}
+	}
+
 	r := gin.Default()
 
 	// Setup CORS middleware for Vue.js development
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"resource-mgmt/config"
@@ -93,6 +94,13 @@ func (s *AnalyticsService) GetDashboardStats(organizationID string, filters map[
 	if inspectorID, ok := filters["inspector_id"].(string); ok && inspectorID != "" {
 		baseQuery = baseQuery.Where("inspector_id = ?", inspectorID)
 	}
+	if teamID, ok := filters["team_id"].(string); ok && teamID != "" {
+		baseQuery = baseQuery.Where(TeamWorkCondition("inspector_id", teamID))
+	}
+	baseQuery, err := s.applyLocationFilters(baseQuery, organizationID, filters)
+	if err != nil {
+		return nil, err
+	}
 
 	// Get inspection overview stats
 	inspectionStats, err := s.getInspectionOverviewStats(baseQuery)
@@ -139,6 +147,22 @@ func (s *AnalyticsService) GetDashboardStats(organizationID string, filters map[
 	return stats, nil
 }
 
+// applyLocationFilters narrows an inspection query to a site and/or a location subtree,
+// so figures for a building include its floors and rooms
+func (s *AnalyticsService) applyLocationFilters(query *gorm.DB, organizationID string, filters map[string]interface{}) (*gorm.DB, error) {
+	if siteID, ok := filters["site_id"].(string); ok && siteID != "" {
+		query = query.Where("site_id = ?", siteID)
+	}
+	if nodeID, ok := filters["location_node_id"].(string); ok && nodeID != "" {
+		subtree, err := NewLocationService(s.db).SubtreeIDsQuery(context.Background(), organizationID, nodeID)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("location_node_id IN (?)", subtree)
+	}
+	return query, nil
+}
+
 func (s *AnalyticsService) getInspectionOverviewStats(baseQuery *gorm.DB) (*InspectionOverviewStats, error) {
 	stats := &InspectionOverviewStats{}
 
@@ -466,9 +490,16 @@ func (s *AnalyticsService) ExportInspectionReport(organizationID string, format
 	if priority, ok := filters["priority"].(string); ok && priority != "" {
 		query = query.Where("priority = ?", priority)
 	}
+	if teamID, ok := filters["team_id"].(string); ok && teamID != "" {
+		query = query.Where(TeamWorkCondition("inspector_id", teamID))
+	}
+	query, err := s.applyLocationFilters(query, organizationID, filters)
+	if err != nil {
+		return nil, "", err
+	}
 
 	var inspections []models.Inspection
-	err := query.
+	err = query.
 		Preload("Template").
 		Preload("Site").
 		Order("created_at DESC").
@@ -0,0 +1,471 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"resource-mgmt/models"
+	"resource-mgmt/pkg/database"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrAssetNotFound is returned when an asset doesn't exist in the organization
+	ErrAssetNotFound = errors.New("asset not found")
+	// ErrAssetNotInSite is returned when an asset belongs to a different site than the inspection
+	ErrAssetNotInSite = errors.New("asset does not belong to this site")
+	// ErrAssetRetired is returned when a retired asset would be inspected or changed
+	ErrAssetRetired = errors.New("asset is retired")
+	// ErrAssetHasInspections is returned when deleting an asset with inspection history; retire it instead
+	ErrAssetHasInspections = errors.New("asset has inspection history, retire it instead of deleting")
+	// ErrInvalidAsset is returned when required asset fields are missing or invalid
+	ErrInvalidAsset = errors.New("invalid asset")
+)
+
+// AssetService manages equipment inspected at sites
+type AssetService struct {
+	db *gorm.DB
+}
+
+func NewAssetService(db *gorm.DB) *AssetService {
+	return &AssetService{db: db}
+}
+
+// =====================================================
+// ASSETS
+// =====================================================
+
+// GetAssets retrieves assets with filtering, search, and pagination.
+// Retired assets are only included when filtered by status or include_retired is set.
+func (s *AssetService) GetAssets(ctx context.Context, organizationID string, filters map[string]interface{}, search string, page, limit int) ([]models.Asset, int64, error) {
+	var assets []models.Asset
+	var total int64
+
+	query := database.Conn(ctx, s.db).Model(&models.Asset{}).Where("organization_id = ?", organizationID)
+
+	for _, key := range []string{"site_id", "type", "status", "location_node_id"} {
+		if value, ok := filters[key].(string); ok && value != "" {
+			query = query.Where(fmt.Sprintf("%s = ?", key), value)
+		}
+	}
+	if _, filtered := filters["status"]; !filtered {
+		if includeRetired, _ := filters["include_retired"].(bool); !includeRetired {
+			query = query.Where("status <> ?", models.AssetStatusRetired)
+		}
+	}
+
+	if search != "" {
+		searchPattern := "%" + strings.ToLower(search) + "%"
+		query = query.Where(
+			"LOWER(name) LIKE ? OR LOWER(serial_number) LIKE ? OR LOWER(asset_tag) LIKE ? OR LOWER(manufacturer) LIKE ?",
+			searchPattern, searchPattern, searchPattern, searchPattern,
+		)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count assets: %v", err)
+	}
+
+	offset := (page - 1) * limit
+	if err := query.
+		Order("name ASC").
+		Offset(offset).
+		Limit(limit).
+		Find(&assets).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get assets: %v", err)
+	}
+
+	return assets, total, nil
+}
+
+// GetAsset retrieves a single asset, including retired ones
+func (s *AssetService) GetAsset(ctx context.Context, organizationID, assetID string) (*models.Asset, error) {
+	var asset models.Asset
+	if err := database.Conn(ctx, s.db).
+		Where("id = ? AND organization_id = ?", assetID, organizationID).
+		First(&asset).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAssetNotFound
+		}
+		return nil, fmt.Errorf("failed to get asset: %v", err)
+	}
+	return &asset, nil
+}
+
+// CreateAsset registers a new asset at a site
+func (s *AssetService) CreateAsset(ctx context.Context, asset *models.Asset) (*models.Asset, error) {
+	asset.Name = strings.TrimSpace(asset.Name)
+	asset.Type = strings.TrimSpace(asset.Type)
+	if asset.Name == "" || asset.Type == "" || asset.SiteID == "" {
+		return nil, fmt.Errorf("%w: name, type and site_id are required", ErrInvalidAsset)
+	}
+	if asset.InspectionIntervalDays < 0 {
+		return nil, fmt.Errorf("%w: inspection_interval_days cannot be negative", ErrInvalidAsset)
+	}
+	if asset.Status == "" {
+		asset.Status = models.AssetStatusActive
+	}
+	if asset.Status == models.AssetStatusRetired {
+		return nil, fmt.Errorf("%w: new assets cannot be retired", ErrInvalidAsset)
+	}
+	if !isValidAssetStatus(asset.Status) {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidAsset, asset.Status)
+	}
+
+	if err := s.validatePlacement(ctx, asset.OrganizationID, asset.SiteID, asset.LocationNodeID); err != nil {
+		return nil, err
+	}
+
+	if err := database.Conn(ctx, s.db).Create(asset).Error; err != nil {
+		return nil, fmt.Errorf("failed to create asset: %v", err)
+	}
+
+	return s.GetAsset(ctx, asset.OrganizationID, asset.ID)
+}
+
+// UpdateAsset updates an asset. Retirement goes through RetireAsset so it is recorded.
+func (s *AssetService) UpdateAsset(ctx context.Context, organizationID, assetID string, updates map[string]interface{}) (*models.Asset, error) {
+	asset, err := s.GetAsset(ctx, organizationID, assetID)
+	if err != nil {
+		return nil, err
+	}
+	if asset.IsRetired() {
+		return nil, ErrAssetRetired
+	}
+
+	allowed := map[string]bool{
+		"name": true, "asset_tag": true, "type": true, "serial_number": true, "manufacturer": true,
+		"model_number": true, "install_date": true, "status": true, "inspection_interval_days": true,
+		"custom_fields": true, "notes": true, "site_id": true, "location_node_id": true, "updated_by": true,
+	}
+	filtered := make(map[string]interface{})
+	for key, value := range updates {
+		if allowed[key] {
+			filtered[key] = value
+		}
+	}
+
+	if status, ok := filtered["status"]; ok {
+		value, isString := status.(string)
+		if !isString || !isValidAssetStatus(value) {
+			return nil, fmt.Errorf("%w: unknown status %v", ErrInvalidAsset, status)
+		}
+		if value == models.AssetStatusRetired {
+			return nil, fmt.Errorf("%w: use the retire action to retire an asset", ErrInvalidAsset)
+		}
+	}
+	for _, key := range []string{"name", "type"} {
+		if value, ok := filtered[key]; ok {
+			if str, isString := value.(string); !isString || strings.TrimSpace(str) == "" {
+				return nil, fmt.Errorf("%w: %s cannot be empty", ErrInvalidAsset, key)
+			}
+		}
+	}
+	if interval, ok := filtered["inspection_interval_days"].(float64); ok && interval < 0 {
+		return nil, fmt.Errorf("%w: inspection_interval_days cannot be negative", ErrInvalidAsset)
+	}
+	if customFields, ok := filtered["custom_fields"]; ok {
+		encoded, err := json.Marshal(customFields)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid custom_fields", ErrInvalidAsset)
+		}
+		filtered["custom_fields"] = datatypes.JSON(encoded)
+	}
+
+	// Moving the asset to another site or location must keep it consistent
+	siteID := asset.SiteID
+	if value, ok := filtered["site_id"]; ok {
+		if str, isString := value.(string); isString && str != "" {
+			siteID = str
+		} else {
+			delete(filtered, "site_id")
+		}
+	}
+	locationNodeID := asset.LocationNodeID
+	if value, ok := filtered["location_node_id"]; ok {
+		locationNodeID = nil
+		if str, isString := value.(string); isString && str != "" {
+			locationNodeID = &str
+		}
+		filtered["location_node_id"] = locationNodeID
+	} else if siteID != asset.SiteID {
+		locationNodeID = nil
+		filtered["location_node_id"] = nil
+	}
+	if err := s.validatePlacement(ctx, organizationID, siteID, locationNodeID); err != nil {
+		return nil, err
+	}
+
+	if len(filtered) > 0 {
+		if err := database.Conn(ctx, s.db).Model(asset).Updates(filtered).Error; err != nil {
+			return nil, fmt.Errorf("failed to update asset: %v", err)
+		}
+	}
+
+	return s.GetAsset(ctx, organizationID, assetID)
+}
+
+// RetireAsset takes an asset out of service for good. It stays queryable with its
+// inspection history but can no longer be inspected.
+func (s *AssetService) RetireAsset(ctx context.Context, organizationID, assetID, retiredBy, reason string) (*models.Asset, error) {
+	asset, err := s.GetAsset(ctx, organizationID, assetID)
+	if err != nil {
+		return nil, err
+	}
+	if asset.IsRetired() {
+		return nil, ErrAssetRetired
+	}
+
+	now := time.Now()
+	if err := database.Conn(ctx, s.db).Model(asset).Updates(map[string]interface{}{
+		"status":         models.AssetStatusRetired,
+		"retired_at":     now,
+		"retired_by":     retiredBy,
+		"retired_reason": reason,
+		"updated_by":     retiredBy,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to retire asset: %v", err)
+	}
+
+	return s.GetAsset(ctx, organizationID, assetID)
+}
+
+// DeleteAsset soft deletes an asset that was never inspected, e.g. one created by mistake
+func (s *AssetService) DeleteAsset(ctx context.Context, organizationID, assetID string) error {
+	asset, err := s.GetAsset(ctx, organizationID, assetID)
+	if err != nil {
+		return err
+	}
+
+	db := database.Conn(ctx, s.db)
+
+	var inspectionCount int64
+	if err := db.Model(&models.Inspection{}).
+		Where("organization_id = ? AND asset_id = ?", organizationID, asset.ID).
+		Count(&inspectionCount).Error; err != nil {
+		return fmt.Errorf("failed to check asset inspections: %v", err)
+	}
+	if inspectionCount > 0 {
+		return ErrAssetHasInspections
+	}
+
+	if err := db.Delete(asset).Error; err != nil {
+		return fmt.Errorf("failed to delete asset: %v", err)
+	}
+	return nil
+}
+
+// ValidateInspectionAsset checks that an asset can be inspected as part of an inspection at siteID
+func (s *AssetService) ValidateInspectionAsset(ctx context.Context, organizationID, siteID, assetID string) error {
+	asset, err := s.GetAsset(ctx, organizationID, assetID)
+	if err != nil {
+		return err
+	}
+	if asset.SiteID != siteID {
+		return ErrAssetNotInSite
+	}
+	if asset.IsRetired() {
+		return ErrAssetRetired
+	}
+	return nil
+}
+
+// =====================================================
+// INSPECTION HISTORY
+// =====================================================
+
+// GetAssetInspections retrieves the inspection history of an asset, newest first
+func (s *AssetService) GetAssetInspections(ctx context.Context, organizationID, assetID string, page, limit int) ([]models.Inspection, int64, error) {
+	if _, err := s.GetAsset(ctx, organizationID, assetID); err != nil {
+		return nil, 0, err
+	}
+
+	var inspections []models.Inspection
+	var total int64
+
+	query := database.Conn(ctx, s.db).Model(&models.Inspection{}).
+		Where("organization_id = ? AND asset_id = ?", organizationID, assetID)
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count asset inspections: %v", err)
+	}
+
+	offset := (page - 1) * limit
+	if err := query.
+		Preload("Template").
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&inspections).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get asset inspections: %v", err)
+	}
+
+	return inspections, total, nil
+}
+
+// GetAssetNextDue returns when a single asset is next due for inspection
+func (s *AssetService) GetAssetNextDue(ctx context.Context, organizationID, assetID string) (*models.AssetDueItem, error) {
+	asset, err := s.GetAsset(ctx, organizationID, assetID)
+	if err != nil {
+		return nil, err
+	}
+	if asset.IsRetired() {
+		return nil, ErrAssetRetired
+	}
+	if asset.InspectionIntervalDays <= 0 {
+		return nil, fmt.Errorf("%w: asset has no inspection interval", ErrInvalidAsset)
+	}
+
+	items, err := s.dueItems(ctx, organizationID, []models.Asset{*asset}, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &items[0], nil
+}
+
+// GetAssetsDue lists active assets with a recurring inspection due on or before dueBefore,
+// soonest first. An asset is due InspectionIntervalDays after its last completed inspection,
+// or after its install date (or registration) when it was never inspected.
+func (s *AssetService) GetAssetsDue(ctx context.Context, organizationID, siteID string, dueBefore time.Time) ([]models.AssetDueItem, error) {
+	query := database.Conn(ctx, s.db).
+		Where("organization_id = ? AND status = ? AND inspection_interval_days > 0", organizationID, models.AssetStatusActive)
+	if siteID != "" {
+		query = query.Where("site_id = ?", siteID)
+	}
+
+	var assets []models.Asset
+	if err := query.Find(&assets).Error; err != nil {
+		return nil, fmt.Errorf("failed to get assets: %v", err)
+	}
+
+	items, err := s.dueItems(ctx, organizationID, assets, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	due := make([]models.AssetDueItem, 0, len(items))
+	for _, item := range items {
+		if !item.NextDueAt.After(dueBefore) {
+			due = append(due, item)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextDueAt.Before(due[j].NextDueAt) })
+	return due, nil
+}
+
+// dueItems computes the next due date of each asset from its inspection history. Due dates
+// landing on an organization holiday move to the next day that isn't one.
+func (s *AssetService) dueItems(ctx context.Context, organizationID string, assets []models.Asset, now time.Time) ([]models.AssetDueItem, error) {
+	if len(assets) == 0 {
+		return []models.AssetDueItem{}, nil
+	}
+
+	assetIDs := make([]string, 0, len(assets))
+	for _, asset := range assets {
+		assetIDs = append(assetIDs, asset.ID)
+	}
+
+	var history []struct {
+		ID          string
+		AssetID     string
+		Status      string
+		CompletedAt *time.Time
+	}
+	if err := database.Conn(ctx, s.db).Model(&models.Inspection{}).
+		Select("id, asset_id, status, completed_at").
+		Where("organization_id = ? AND asset_id IN ?", organizationID, assetIDs).
+		Where("status NOT IN ?", []string{"rejected", "cancelled"}).
+		Scan(&history).Error; err != nil {
+		return nil, fmt.Errorf("failed to get asset inspection history: %v", err)
+	}
+
+	holidays, err := loadOrganizationHolidays(ctx, s.db, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	lastInspected := make(map[string]time.Time)
+	openInspection := make(map[string]string)
+	for _, row := range history {
+		if isCompletedStatus(row.Status) {
+			if row.CompletedAt != nil && row.CompletedAt.After(lastInspected[row.AssetID]) {
+				lastInspected[row.AssetID] = *row.CompletedAt
+			}
+			continue
+		}
+		openInspection[row.AssetID] = row.ID
+	}
+
+	items := make([]models.AssetDueItem, 0, len(assets))
+	for _, asset := range assets {
+		item := models.AssetDueItem{
+			AssetID:   asset.ID,
+			AssetName: asset.Name,
+			AssetType: asset.Type,
+			SiteID:    asset.SiteID,
+		}
+
+		base := asset.CreatedAt
+		if asset.InstallDate != nil {
+			base = *asset.InstallDate
+		}
+		if last, ok := lastInspected[asset.ID]; ok {
+			last := last
+			item.LastInspectedAt = &last
+			base = last
+		}
+		item.NextDueAt = skipHolidays(holidays, base.AddDate(0, 0, asset.InspectionIntervalDays))
+		item.Overdue = item.NextDueAt.Before(now)
+
+		if openID, ok := openInspection[asset.ID]; ok {
+			item.OpenInspectionID = &openID
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// =====================================================
+// HELPERS
+// =====================================================
+
+// validatePlacement checks the site and optional location an asset is placed at
+func (s *AssetService) validatePlacement(ctx context.Context, organizationID, siteID string, locationNodeID *string) error {
+	var siteCount int64
+	if err := database.Conn(ctx, s.db).Model(&models.Site{}).
+		Where("id = ? AND organization_id = ?", siteID, organizationID).
+		Count(&siteCount).Error; err != nil {
+		return fmt.Errorf("failed to verify site: %v", err)
+	}
+	if siteCount == 0 {
+		return fmt.Errorf("%w: site not found", ErrInvalidAsset)
+	}
+
+	if locationNodeID != nil && *locationNodeID != "" {
+		return NewLocationService(s.db).ValidateInspectionLocation(ctx, organizationID, siteID, *locationNodeID)
+	}
+	return nil
+}
+
+func isValidAssetStatus(status string) bool {
+	switch status {
+	case models.AssetStatusActive, models.AssetStatusOutOfService, models.AssetStatusRetired:
+		return true
+	}
+	return false
+}
+
+func isCompletedStatus(status string) bool {
+	for _, completed := range completedInspectionStatuses {
+		if status == completed {
+			return true
+		}
+	}
+	return false
+}
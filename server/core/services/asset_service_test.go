@@ -0,0 +1,217 @@
+package services
+
+import (
+	"context"
+	"resource-mgmt/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// assetTestFixture is a depot with an extinguisher inspected every 30 days, installed six
+// months ago and never inspected, and a yearly ladder; plus an office of the same organization
+type assetTestFixture struct {
+	db                   *gorm.DB
+	service              *AssetService
+	site, otherSite      *models.Site
+	extinguisher, ladder *models.Asset
+}
+
+func newAssetTestFixture(t *testing.T) *assetTestFixture {
+	db := setupServiceTestDB(t, &models.Site{}, &models.LocationNode{}, &models.Asset{}, &models.Inspection{}, &models.OrganizationHoliday{})
+	ctx := context.Background()
+	f := &assetTestFixture{db: db, service: NewAssetService(db)}
+
+	f.site = createTestSite(t, db, "org-a", "Depot", "1 Yard Rd")
+	f.otherSite = createTestSite(t, db, "org-a", "Office", "2 High St")
+
+	installed := time.Now().AddDate(0, -6, 0)
+	var err error
+	f.extinguisher, err = f.service.CreateAsset(ctx, &models.Asset{
+		OrganizationID:         "org-a",
+		SiteID:                 f.site.ID,
+		Name:                   "Extinguisher FE-12",
+		Type:                   "fire_extinguisher",
+		SerialNumber:           "SN-0012",
+		InstallDate:            &installed,
+		InspectionIntervalDays: 30,
+	})
+	require.NoError(t, err)
+	f.ladder, err = f.service.CreateAsset(ctx, &models.Asset{OrganizationID: "org-a", SiteID: f.site.ID, Name: "Ladder L-3", Type: "ladder", InspectionIntervalDays: 365})
+	require.NoError(t, err)
+	return f
+}
+
+// inspectExtinguisher completes an inspection of the extinguisher ten days ago and opens
+// another, returning when the first was completed and the open one
+func (f *assetTestFixture) inspectExtinguisher(t *testing.T) (time.Time, *models.Inspection) {
+	completedAt := time.Now().AddDate(0, 0, -10)
+	require.NoError(t, f.db.Create(&models.Inspection{
+		OrganizationID: "org-a", TemplateID: uuid.New(), InspectorID: "inspector-1",
+		SiteID: f.site.ID, AssetID: &f.extinguisher.ID, Status: "completed", CompletedAt: &completedAt,
+	}).Error)
+	open := &models.Inspection{
+		OrganizationID: "org-a", TemplateID: uuid.New(), InspectorID: "inspector-1",
+		SiteID: f.site.ID, AssetID: &f.extinguisher.ID, Status: "assigned",
+	}
+	require.NoError(t, f.db.Create(open).Error)
+	return completedAt, open
+}
+
+func TestAssetService_CreateAssetValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		asset models.Asset
+	}{
+		{"missing name", models.Asset{Type: "harness"}},
+		{"missing type", models.Asset{Name: "Harness"}},
+		{"negative interval", models.Asset{Name: "Harness", Type: "harness", InspectionIntervalDays: -1}},
+		{"retired", models.Asset{Name: "Harness", Type: "harness", Status: models.AssetStatusRetired}},
+		{"unknown status", models.Asset{Name: "Harness", Type: "harness", Status: "lost"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newAssetTestFixture(t)
+			asset := tt.asset
+			asset.OrganizationID, asset.SiteID = "org-a", f.site.ID
+
+			_, err := f.service.CreateAsset(context.Background(), &asset)
+			assert.ErrorIs(t, err, ErrInvalidAsset)
+		})
+	}
+}
+
+func TestAssetService_NeverInspectedAssetIsOverdue(t *testing.T) {
+	f := newAssetTestFixture(t)
+	assert.Equal(t, models.AssetStatusActive, f.extinguisher.Status)
+
+	due, err := f.service.GetAssetsDue(context.Background(), "org-a", "", time.Now().AddDate(0, 0, 7))
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, f.extinguisher.ID, due[0].AssetID)
+	assert.True(t, due[0].Overdue)
+	assert.Nil(t, due[0].LastInspectedAt)
+}
+
+func TestAssetService_CompletedInspectionPushesNextDue(t *testing.T) {
+	f := newAssetTestFixture(t)
+	ctx := context.Background()
+	completedAt, open := f.inspectExtinguisher(t)
+
+	next, err := f.service.GetAssetNextDue(ctx, "org-a", f.extinguisher.ID)
+	require.NoError(t, err)
+	require.NotNil(t, next.LastInspectedAt)
+	assert.WithinDuration(t, completedAt.AddDate(0, 0, 30), next.NextDueAt, time.Second)
+	assert.False(t, next.Overdue)
+	require.NotNil(t, next.OpenInspectionID)
+	assert.Equal(t, open.ID.String(), *next.OpenInspectionID)
+
+	due, err := f.service.GetAssetsDue(ctx, "org-a", f.site.ID, time.Now().AddDate(0, 0, 7))
+	require.NoError(t, err)
+	assert.Empty(t, due)
+}
+
+func TestAssetService_NextDueSkipsHolidays(t *testing.T) {
+	f := newAssetTestFixture(t)
+	ctx := context.Background()
+	completedAt, _ := f.inspectExtinguisher(t)
+
+	holiday := civilDate(completedAt.AddDate(0, 0, 30))
+	require.NoError(t, f.db.Create(&models.OrganizationHoliday{OrganizationID: "org-a", Date: holiday, Name: "Founders day"}).Error)
+
+	next, err := f.service.GetAssetNextDue(ctx, "org-a", f.extinguisher.ID)
+	require.NoError(t, err)
+	assert.WithinDuration(t, completedAt.AddDate(0, 0, 31), next.NextDueAt, time.Second)
+}
+
+func TestAssetService_ValidateInspectionAsset(t *testing.T) {
+	tests := []struct {
+		name    string
+		site    func(f *assetTestFixture) string
+		retired bool
+		wantErr error
+	}{
+		{"asset at the site", func(f *assetTestFixture) string { return f.site.ID }, false, nil},
+		{"asset at another site", func(f *assetTestFixture) string { return f.otherSite.ID }, false, ErrAssetNotInSite},
+		{"retired asset", func(f *assetTestFixture) string { return f.site.ID }, true, ErrAssetRetired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newAssetTestFixture(t)
+			ctx := context.Background()
+			if tt.retired {
+				_, err := f.service.RetireAsset(ctx, "org-a", f.extinguisher.ID, "admin-1", "Failed pressure test")
+				require.NoError(t, err)
+			}
+
+			err := f.service.ValidateInspectionAsset(ctx, "org-a", tt.site(f), f.extinguisher.ID)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAssetService_DeleteAsset(t *testing.T) {
+	tests := []struct {
+		name    string
+		asset   func(f *assetTestFixture) string
+		wantErr error
+	}{
+		{"asset with inspections", func(f *assetTestFixture) string { return f.extinguisher.ID }, ErrAssetHasInspections},
+		{"never inspected asset", func(f *assetTestFixture) string { return f.ladder.ID }, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newAssetTestFixture(t)
+			ctx := context.Background()
+			f.inspectExtinguisher(t)
+			assetID := tt.asset(f)
+
+			err := f.service.DeleteAsset(ctx, "org-a", assetID)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				_, err = f.service.GetAsset(ctx, "org-a", assetID)
+				assert.NoError(t, err, "the asset is kept")
+				return
+			}
+			require.NoError(t, err)
+			_, err = f.service.GetAsset(ctx, "org-a", assetID)
+			assert.ErrorIs(t, err, ErrAssetNotFound)
+		})
+	}
+}
+
+func TestAssetService_RetiredAssetKeepsHistoryAndIsHidden(t *testing.T) {
+	f := newAssetTestFixture(t)
+	ctx := context.Background()
+	f.inspectExtinguisher(t)
+
+	retired, err := f.service.RetireAsset(ctx, "org-a", f.extinguisher.ID, "admin-1", "Failed pressure test")
+	require.NoError(t, err)
+	assert.True(t, retired.IsRetired())
+	require.NotNil(t, retired.RetiredAt)
+
+	_, err = f.service.UpdateAsset(ctx, "org-a", f.extinguisher.ID, map[string]interface{}{"name": "Renamed"})
+	assert.ErrorIs(t, err, ErrAssetRetired)
+
+	history, total, err := f.service.GetAssetInspections(ctx, "org-a", f.extinguisher.ID, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Len(t, history, 2)
+
+	assets, total, err := f.service.GetAssets(ctx, "org-a", map[string]interface{}{}, "", 1, 20)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total, "retired assets are hidden by default")
+	assert.Equal(t, f.ladder.ID, assets[0].ID)
+
+	_, total, err = f.service.GetAssets(ctx, "org-a", map[string]interface{}{"include_retired": true}, "sn-0012", 1, 20)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+}
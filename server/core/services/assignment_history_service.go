@@ -0,0 +1,430 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"resource-mgmt/models"
+	"resource-mgmt/pkg/database"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrAssignmentNotFound is returned when an assignment doesn't exist in the organization
+	ErrAssignmentNotFound = errors.New("assignment not found")
+	// ErrAssignmentHistoryForbidden is returned when someone who never held an assignment,
+	// and isn't a supervisor, asks for its history
+	ErrAssignmentHistoryForbidden = errors.New("not allowed to view this assignment's history")
+	// ErrInvalidRejectionReason is returned for rejection reason codes that aren't known
+	ErrInvalidRejectionReason = errors.New("invalid rejection reason")
+	// ErrInvalidDelegation is returned for delegations to someone who can't take the work
+	ErrInvalidDelegation = errors.New("invalid delegation")
+	// ErrDelegationNotAllowed is returned when the assignment doesn't allow reassignment or
+	// the caller isn't its inspector, and when inspectors review delegations
+	ErrDelegationNotAllowed = errors.New("delegation not allowed")
+	// ErrDelegationNotFound is returned when a delegation request doesn't exist in the organization
+	ErrDelegationNotFound = errors.New("delegation request not found")
+	// ErrDelegationConflict is returned when the assignment already has an open request, or
+	// the request was already reviewed or cancelled
+	ErrDelegationConflict = errors.New("delegation conflict")
+)
+
+// rejectionReasonCodes are the accepted reasons for rejecting an assignment
+var rejectionReasonCodes = []string{
+	models.RejectionScheduleConflict, models.RejectionWorkload, models.RejectionQualification,
+	models.RejectionDistance, models.RejectionSafety, models.RejectionOther,
+}
+
+// delegableAssignmentStatuses are the assignment statuses an inspector may still hand on
+var delegableAssignmentStatuses = []string{"pending", "active"}
+
+type AssignmentHistoryService struct {
+	db                  *gorm.DB
+	workflowService     *WorkflowService
+	notificationService *NotificationService
+}
+
+func NewAssignmentHistoryService(db *gorm.DB, workflowService *WorkflowService, notificationService *NotificationService) *AssignmentHistoryService {
+	return &AssignmentHistoryService{db: db, workflowService: workflowService, notificationService: notificationService}
+}
+
+// =====================================================
+// EVENTS
+// =====================================================
+
+// recordAssignmentEvent adds an entry to an assignment's history, timestamped now unless
+// the event says when it happened. The insert runs in a savepoint, so callers that only
+// log a failure don't lose the request transaction with it.
+func recordAssignmentEvent(ctx context.Context, db *gorm.DB, event *models.AssignmentEvent) error {
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+	err := database.Conn(ctx, db).Transaction(func(tx *gorm.DB) error {
+		return tx.Create(event).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record assignment event: %v", err)
+	}
+	return nil
+}
+
+// recordInspectionEvents adds started and completed events to the assignment an inspection
+// belongs to when the inspection starts or is completed
+func recordInspectionEvents(ctx context.Context, db *gorm.DB, before, after *models.Inspection) error {
+	if after == nil || after.AssignmentID == nil {
+		return nil
+	}
+
+	var eventType string
+	occurredAt := time.Now()
+	switch {
+	case after.Status == "in_progress" && (before == nil || before.Status != "in_progress"):
+		eventType = models.AssignmentEventStarted
+		if after.StartedAt != nil {
+			occurredAt = *after.StartedAt
+		}
+	case after.Status == "completed" && (before == nil || before.Status != "completed"):
+		eventType = models.AssignmentEventCompleted
+		if after.CompletedAt != nil {
+			occurredAt = *after.CompletedAt
+		}
+	default:
+		return nil
+	}
+
+	inspectionID := after.ID.String()
+	return recordAssignmentEvent(ctx, db, &models.AssignmentEvent{
+		OrganizationID: after.OrganizationID,
+		AssignmentID:   *after.AssignmentID,
+		InspectionID:   &inspectionID,
+		EventType:      eventType,
+		ActorID:        after.InspectorID,
+		OccurredAt:     occurredAt,
+	})
+}
+
+// normalizeRejectionReason checks a rejection reason code; empty means other
+func normalizeRejectionReason(code string) (string, error) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if code == "" {
+		return models.RejectionOther, nil
+	}
+	if !containsString(rejectionReasonCodes, code) {
+		return "", fmt.Errorf("%w: reason_code must be one of %s", ErrInvalidRejectionReason, strings.Join(rejectionReasonCodes, ", "))
+	}
+	return code, nil
+}
+
+// GetTimeline returns the assignment's history, oldest first. Supervisors see any
+// assignment's; inspectors see those they hold or held.
+func (s *AssignmentHistoryService) GetTimeline(ctx context.Context, organizationID, assignmentID, userID, userRole string) ([]models.AssignmentEvent, error) {
+	assignment, err := s.getAssignment(ctx, organizationID, assignmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	db := database.Conn(ctx, s.db)
+	if !isSupervisorRole(userRole) && assignment.AssignedTo != userID {
+		var involved int64
+		if err := db.Model(&models.AssignmentEvent{}).
+			Where("assignment_id = ? AND (actor_id = ? OR from_user_id = ? OR to_user_id = ?)", assignment.ID, userID, userID, userID).
+			Count(&involved).Error; err != nil {
+			return nil, fmt.Errorf("failed to check assignment history: %v", err)
+		}
+		if involved == 0 {
+			return nil, ErrAssignmentHistoryForbidden
+		}
+	}
+
+	var events []models.AssignmentEvent
+	if err := db.Where("organization_id = ? AND assignment_id = ?", organizationID, assignment.ID).
+		Preload("Actor").
+		Order("occurred_at ASC, created_at ASC").
+		Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("failed to get assignment history: %v", err)
+	}
+	return events, nil
+}
+
+// GetRejectionReport summarizes the assignments rejected from from up to to, by reason
+// code and by inspector, most frequent first
+func (s *AssignmentHistoryService) GetRejectionReport(ctx context.Context, organizationID string, from, to time.Time) (*models.RejectionReport, error) {
+	var rejections []models.AssignmentEvent
+	if err := database.Conn(ctx, s.db).
+		Where("organization_id = ? AND event_type = ? AND occurred_at >= ? AND occurred_at < ?", organizationID, models.AssignmentEventRejected, from, to).
+		Order("occurred_at DESC").
+		Find(&rejections).Error; err != nil {
+		return nil, fmt.Errorf("failed to get rejections: %v", err)
+	}
+
+	return &models.RejectionReport{
+		From:        from,
+		To:          to,
+		Total:       len(rejections),
+		ByReason:    summarizeRejections(rejections, func(e models.AssignmentEvent) string { return e.ReasonCode }),
+		ByInspector: summarizeRejections(rejections, func(e models.AssignmentEvent) string { return e.ActorID }),
+		Rejections:  rejections,
+	}, nil
+}
+
+func summarizeRejections(rejections []models.AssignmentEvent, key func(models.AssignmentEvent) string) []models.RejectionSummary {
+	counts := make(map[string]int)
+	for _, rejection := range rejections {
+		k := key(rejection)
+		if k == "" {
+			k = models.RejectionOther
+		}
+		counts[k]++
+	}
+	summaries := make([]models.RejectionSummary, 0, len(counts))
+	for k, n := range counts {
+		summaries = append(summaries, models.RejectionSummary{Key: k, Rejections: n})
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Rejections != summaries[j].Rejections {
+			return summaries[i].Rejections > summaries[j].Rejections
+		}
+		return summaries[i].Key < summaries[j].Key
+	})
+	return summaries
+}
+
+// =====================================================
+// DELEGATION
+// =====================================================
+
+// RequestDelegation asks to hand the caller's assignment to a peer. The assignment has to
+// allow reassignment and still be pending or active; supervisors are asked to approve.
+func (s *AssignmentHistoryService) RequestDelegation(ctx context.Context, organizationID, assignmentID, userID string, req *models.DelegationRequest) (*models.AssignmentDelegation, error) {
+	assignment, err := s.getAssignment(ctx, organizationID, assignmentID)
+	if err != nil {
+		return nil, err
+	}
+	if assignment.AssignedTo != userID {
+		return nil, fmt.Errorf("%w: only the assigned inspector can delegate this assignment", ErrDelegationNotAllowed)
+	}
+	if !assignment.AllowReassignment {
+		return nil, fmt.Errorf("%w: this assignment can't be reassigned", ErrDelegationNotAllowed)
+	}
+	if !containsString(delegableAssignmentStatuses, assignment.Status) {
+		return nil, fmt.Errorf("%w: the assignment is %s", ErrDelegationNotAllowed, assignment.Status)
+	}
+	toInspectorID := strings.TrimSpace(req.ToInspectorID)
+	if toInspectorID == userID {
+		return nil, fmt.Errorf("%w: the assignment is already yours", ErrInvalidDelegation)
+	}
+	if err := s.workflowService.availabilityService.requireInspector(ctx, organizationID, toInspectorID); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDelegation, err)
+	}
+
+	delegation := &models.AssignmentDelegation{
+		OrganizationID:  organizationID,
+		AssignmentID:    assignment.ID,
+		FromInspectorID: userID,
+		ToInspectorID:   toInspectorID,
+		Reason:          strings.TrimSpace(req.Reason),
+		Status:          models.DelegationPending,
+	}
+	err = database.Conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		var open int64
+		if err := tx.Model(&models.AssignmentDelegation{}).
+			Where("assignment_id = ? AND status = ?", assignment.ID, models.DelegationPending).
+			Count(&open).Error; err != nil {
+			return err
+		}
+		if open > 0 {
+			return fmt.Errorf("%w: the assignment already has a delegation waiting for approval", ErrDelegationConflict)
+		}
+		if err := tx.Create(delegation).Error; err != nil {
+			return err
+		}
+		return recordAssignmentEvent(ctx, tx, &models.AssignmentEvent{
+			OrganizationID: organizationID,
+			AssignmentID:   assignment.ID,
+			EventType:      models.AssignmentEventDelegationRequested,
+			ActorID:        userID,
+			FromUserID:     &userID,
+			ToUserID:       &toInspectorID,
+			Reason:         delegation.Reason,
+		})
+	})
+	if err != nil {
+		if errors.Is(err, ErrDelegationConflict) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to save delegation request: %v", err)
+	}
+
+	s.notify(organizationID, assignment.AssignedBy, "Delegation Requested",
+		fmt.Sprintf("An inspector asked to hand '%s' to a colleague: %s", assignment.Name, delegation.Reason))
+	return delegation, nil
+}
+
+// GetDelegations lists delegation requests, newest first. Supported filters: status,
+// assignment_id and inspector_id (either side of the request).
+func (s *AssignmentHistoryService) GetDelegations(ctx context.Context, organizationID string, filters map[string]interface{}) ([]models.AssignmentDelegation, error) {
+	query := database.Conn(ctx, s.db).Where("organization_id = ?", organizationID)
+	if status, ok := filters["status"].(string); ok && status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if assignmentID, ok := filters["assignment_id"].(string); ok && assignmentID != "" {
+		query = query.Where("assignment_id = ?", assignmentID)
+	}
+	if inspectorID, ok := filters["inspector_id"].(string); ok && inspectorID != "" {
+		query = query.Where("from_inspector_id = ? OR to_inspector_id = ?", inspectorID, inspectorID)
+	}
+
+	var delegations []models.AssignmentDelegation
+	if err := query.Preload("Assignment").Preload("FromInspector").Preload("ToInspector").
+		Order("created_at DESC").Limit(500).Find(&delegations).Error; err != nil {
+		return nil, fmt.Errorf("failed to get delegations: %v", err)
+	}
+	return delegations, nil
+}
+
+// ReviewDelegation approves or declines a pending request. Approving moves the assignment
+// to the requested inspector, who has to accept it. Nobody approves their own request.
+func (s *AssignmentHistoryService) ReviewDelegation(ctx context.Context, organizationID, delegationID, reviewerID string, approve bool, note string) (*models.AssignmentDelegation, error) {
+	delegation, err := s.getDelegation(ctx, organizationID, delegationID)
+	if err != nil {
+		return nil, err
+	}
+	if delegation.FromInspectorID == reviewerID {
+		return nil, fmt.Errorf("%w: you can't review your own delegation", ErrDelegationNotAllowed)
+	}
+
+	assignment, err := s.getAssignment(ctx, organizationID, delegation.AssignmentID)
+	if err != nil {
+		return nil, err
+	}
+	if approve && assignment.AssignedTo != delegation.FromInspectorID {
+		return nil, fmt.Errorf("%w: the assignment has moved since the request", ErrDelegationConflict)
+	}
+
+	status, eventType := models.DelegationDeclined, models.AssignmentEventDelegationDeclined
+	if approve {
+		status = models.DelegationApproved
+	}
+	now := time.Now()
+	note = strings.TrimSpace(note)
+	if err := s.closeDelegation(ctx, delegation, status, map[string]interface{}{"reviewed_by": reviewerID, "reviewed_at": now, "review_note": note}); err != nil {
+		return nil, err
+	}
+	delegation.ReviewedBy = &reviewerID
+	delegation.ReviewedAt = &now
+	delegation.ReviewNote = note
+
+	if approve {
+		if _, err := s.workflowService.moveAssignment(ctx, assignment, reviewerID, delegation.ToInspectorID, models.AssignmentEventDelegated, delegation.Reason, true); err != nil {
+			// Put the request back so it can be reviewed again
+			if reopenErr := database.Conn(ctx, s.db).Model(&models.AssignmentDelegation{}).Where("id = ?", delegation.ID).
+				Updates(map[string]interface{}{"status": models.DelegationPending, "reviewed_by": nil, "reviewed_at": nil, "review_note": ""}).Error; reopenErr != nil {
+				log.Printf("Failed to reopen delegation %s: %v", delegation.ID, reopenErr)
+			}
+			return nil, err
+		}
+	} else {
+		s.recordEvent(ctx, &models.AssignmentEvent{
+			OrganizationID: organizationID,
+			AssignmentID:   delegation.AssignmentID,
+			EventType:      eventType,
+			ActorID:        reviewerID,
+			FromUserID:     &delegation.FromInspectorID,
+			ToUserID:       &delegation.ToInspectorID,
+			Reason:         note,
+		})
+	}
+
+	s.notify(organizationID, delegation.FromInspectorID, "Delegation "+strings.ToUpper(status[:1])+status[1:],
+		fmt.Sprintf("Your request to hand '%s' to a colleague was %s", assignment.Name, status))
+	return delegation, nil
+}
+
+// CancelDelegation withdraws a pending request. The requester and supervisors may cancel.
+func (s *AssignmentHistoryService) CancelDelegation(ctx context.Context, organizationID, delegationID, userID, userRole string) (*models.AssignmentDelegation, error) {
+	delegation, err := s.getDelegation(ctx, organizationID, delegationID)
+	if err != nil {
+		return nil, err
+	}
+	if delegation.FromInspectorID != userID && !isSupervisorRole(userRole) {
+		return nil, fmt.Errorf("%w: only the requester or a supervisor can cancel this request", ErrDelegationNotAllowed)
+	}
+	if err := s.closeDelegation(ctx, delegation, models.DelegationCancelled, nil); err != nil {
+		return nil, err
+	}
+
+	s.recordEvent(ctx, &models.AssignmentEvent{
+		OrganizationID: organizationID,
+		AssignmentID:   delegation.AssignmentID,
+		EventType:      models.AssignmentEventDelegationCancelled,
+		ActorID:        userID,
+		FromUserID:     &delegation.FromInspectorID,
+		ToUserID:       &delegation.ToInspectorID,
+	})
+	return delegation, nil
+}
+
+// closeDelegation moves a pending request to its final status, once
+func (s *AssignmentHistoryService) closeDelegation(ctx context.Context, delegation *models.AssignmentDelegation, status string, updates map[string]interface{}) error {
+	if updates == nil {
+		updates = make(map[string]interface{})
+	}
+	updates["status"] = status
+	result := database.Conn(ctx, s.db).Model(&models.AssignmentDelegation{}).
+		Where("id = ? AND status = ?", delegation.ID, models.DelegationPending).
+		Updates(updates)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update delegation: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: the request is no longer pending", ErrDelegationConflict)
+	}
+	delegation.Status = status
+	return nil
+}
+
+func (s *AssignmentHistoryService) getAssignment(ctx context.Context, organizationID, assignmentID string) (*models.InspectionAssignment, error) {
+	var assignment models.InspectionAssignment
+	if err := database.Conn(ctx, s.db).Where("id = ? AND organization_id = ?", assignmentID, organizationID).First(&assignment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAssignmentNotFound
+		}
+		return nil, fmt.Errorf("failed to get assignment: %v", err)
+	}
+	return &assignment, nil
+}
+
+func (s *AssignmentHistoryService) getDelegation(ctx context.Context, organizationID, delegationID string) (*models.AssignmentDelegation, error) {
+	var delegation models.AssignmentDelegation
+	if err := database.Conn(ctx, s.db).Where("id = ? AND organization_id = ?", delegationID, organizationID).First(&delegation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDelegationNotFound
+		}
+		return nil, fmt.Errorf("failed to get delegation: %v", err)
+	}
+	return &delegation, nil
+}
+
+func (s *AssignmentHistoryService) recordEvent(ctx context.Context, event *models.AssignmentEvent) {
+	if err := recordAssignmentEvent(ctx, s.db, event); err != nil {
+		log.Printf("Failed to record %s event for assignment %s: %v", event.EventType, event.AssignmentID, err)
+	}
+}
+
+func (s *AssignmentHistoryService) notify(organizationID, userID, title, message string) {
+	if s.notificationService == nil || userID == "" {
+		return
+	}
+	s.notificationService.CreateNotification(&models.CreateNotificationRequest{
+		OrganizationID: organizationID,
+		UserID:         userID,
+		Title:          title,
+		Message:        message,
+		Type:           "assignment",
+	})
+}
@@ -0,0 +1,295 @@
+package services
+
+import (
+	"context"
+	"resource-mgmt/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// assignmentHistoryTestFixture is a supervisor and three inspectors of one organization, a
+// safety template and a depot to assign
+type assignmentHistoryTestFixture struct {
+	db       *gorm.DB
+	service  *AssignmentHistoryService
+	workflow *WorkflowService
+	template *models.Template
+	site     *models.Site
+}
+
+func newAssignmentHistoryTestFixture(t *testing.T) *assignmentHistoryTestFixture {
+	db := setupWorkflowTestDB(t, &models.AssignmentDelegation{})
+	notifications := NewNotificationService()
+	workflow := NewWorkflowService(db, notifications)
+	createTestMembers(t, db, "org-a", "supervisor", "super-1")
+	createTestMembers(t, db, "org-a", "inspector", "insp-1", "insp-2", "insp-3")
+	return &assignmentHistoryTestFixture{
+		db:       db,
+		service:  NewAssignmentHistoryService(db, workflow, notifications),
+		workflow: workflow,
+		template: createTestTemplate(t, db, "org-a", "Safety"),
+		site:     createTestSite(t, db, "org-a", "Depot", "1 Dock Rd"),
+	}
+}
+
+// assign gives the depot to the inspector as a reassignable assignment due in a week
+func (f *assignmentHistoryTestFixture) assign(t *testing.T, inspectorID string) models.InspectionAssignment {
+	assignments, err := f.workflow.CreateBulkAssignment(context.Background(), "org-a", "super-1", map[string]interface{}{
+		"name": "Weekly", "template_id": f.template.ID.String(), "site_ids": []string{f.site.ID}, "due_date": time.Now().AddDate(0, 0, 7),
+		"inspector_assignments": []map[string]interface{}{{"inspector_id": inspectorID, "site_ids": []string{f.site.ID}, "allow_reassignment": true}},
+	})
+	require.NoError(t, err)
+	require.Len(t, assignments, 1)
+	return assignments[0]
+}
+
+// acceptedAssignment is an assignment insp-1 has accepted and may delegate
+func (f *assignmentHistoryTestFixture) acceptedAssignment(t *testing.T) models.InspectionAssignment {
+	assignment := f.assign(t, "insp-1")
+	_, err := f.workflow.AcceptAssignment(context.Background(), "org-a", assignment.ID, "insp-1")
+	require.NoError(t, err)
+	return assignment
+}
+
+func (f *assignmentHistoryTestFixture) requestDelegation(t *testing.T, assignmentID, toInspectorID string) *models.AssignmentDelegation {
+	delegation, err := f.service.RequestDelegation(context.Background(), "org-a", assignmentID, "insp-1", &models.DelegationRequest{ToInspectorID: toInspectorID, Reason: "Sick"})
+	require.NoError(t, err)
+	require.Equal(t, models.DelegationPending, delegation.Status)
+	return delegation
+}
+
+func (f *assignmentHistoryTestFixture) assignee(t *testing.T, assignmentID string) models.InspectionAssignment {
+	var assignment models.InspectionAssignment
+	require.NoError(t, f.db.First(&assignment, "id = ?", assignmentID).Error)
+	return assignment
+}
+
+func TestAssignmentHistoryService_RejectAssignmentReasons(t *testing.T) {
+	tests := []struct {
+		name       string
+		reason     string
+		wantErr    error
+		wantStatus string
+	}{
+		{"unknown reason", "bored", ErrInvalidRejectionReason, "pending"},
+		{"known reason in any case", "Schedule_Conflict", nil, "rejected"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newAssignmentHistoryTestFixture(t)
+			assignment := f.assign(t, "insp-1")
+
+			_, err := f.workflow.RejectAssignment(context.Background(), "org-a", assignment.ID, "insp-1", tt.reason, "Not today")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantStatus, f.assignee(t, assignment.ID).Status)
+		})
+	}
+}
+
+func TestAssignmentHistoryService_RejectionReport(t *testing.T) {
+	f := newAssignmentHistoryTestFixture(t)
+	rejected := f.assign(t, "insp-1")
+	_, err := f.workflow.RejectAssignment(context.Background(), "org-a", rejected.ID, "insp-1", "Schedule_Conflict", "On another job that day")
+	require.NoError(t, err)
+	f.acceptedAssignment(t)
+
+	report, err := f.service.GetRejectionReport(context.Background(), "org-a", time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Total)
+	assert.Equal(t, []models.RejectionSummary{{Key: models.RejectionScheduleConflict, Rejections: 1}}, report.ByReason)
+	assert.Equal(t, []models.RejectionSummary{{Key: "insp-1", Rejections: 1}}, report.ByInspector)
+}
+
+func TestAssignmentHistoryService_RequestDelegationValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		prepare   func(t *testing.T, f *assignmentHistoryTestFixture, assignment models.InspectionAssignment)
+		requester string
+		to        string
+		wantErr   error
+	}{
+		{"not the assignee", nil, "insp-2", "insp-3", ErrDelegationNotAllowed},
+		{"to themselves", nil, "insp-1", "insp-1", ErrInvalidDelegation},
+		{"to someone outside the organization", nil, "insp-1", "stranger", ErrInvalidDelegation},
+		{"reassignment not allowed", func(t *testing.T, f *assignmentHistoryTestFixture, assignment models.InspectionAssignment) {
+			require.NoError(t, f.db.Model(&models.InspectionAssignment{}).Where("id = ?", assignment.ID).Update("allow_reassignment", false).Error)
+		}, "insp-1", "insp-2", ErrDelegationNotAllowed},
+		{"assignment already completed", func(t *testing.T, f *assignmentHistoryTestFixture, assignment models.InspectionAssignment) {
+			require.NoError(t, f.db.Model(&models.InspectionAssignment{}).Where("id = ?", assignment.ID).Update("status", "completed").Error)
+		}, "insp-1", "insp-2", ErrDelegationNotAllowed},
+		{"unknown assignment", func(t *testing.T, f *assignmentHistoryTestFixture, assignment models.InspectionAssignment) {
+			require.NoError(t, f.db.Delete(&models.InspectionAssignment{}, "id = ?", assignment.ID).Error)
+		}, "insp-1", "insp-2", ErrAssignmentNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newAssignmentHistoryTestFixture(t)
+			assignment := f.acceptedAssignment(t)
+			if tt.prepare != nil {
+				tt.prepare(t, f, assignment)
+			}
+
+			_, err := f.service.RequestDelegation(context.Background(), "org-a", assignment.ID, tt.requester, &models.DelegationRequest{ToInspectorID: tt.to, Reason: "Swap"})
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Zero(t, countRows(t, f.db.Model(&models.AssignmentDelegation{}).Where("assignment_id = ?", assignment.ID)), "nothing is requested")
+		})
+	}
+}
+
+func TestAssignmentHistoryService_DelegationConflicts(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name         string
+		act          func(t *testing.T, f *assignmentHistoryTestFixture, assignment models.InspectionAssignment, pending *models.AssignmentDelegation) error
+		wantErr      error
+		wantAssignee string
+	}{
+		{"second request while one is pending", func(t *testing.T, f *assignmentHistoryTestFixture, assignment models.InspectionAssignment, _ *models.AssignmentDelegation) error {
+			_, err := f.service.RequestDelegation(ctx, "org-a", assignment.ID, "insp-1", &models.DelegationRequest{ToInspectorID: "insp-3", Reason: "Sick"})
+			return err
+		}, ErrDelegationConflict, "insp-1"},
+		{"requester reviews their own request", func(t *testing.T, f *assignmentHistoryTestFixture, _ models.InspectionAssignment, pending *models.AssignmentDelegation) error {
+			_, err := f.service.ReviewDelegation(ctx, "org-a", pending.ID, "insp-1", true, "")
+			return err
+		}, ErrDelegationNotAllowed, "insp-1"},
+		{"cancelling a declined request", func(t *testing.T, f *assignmentHistoryTestFixture, _ models.InspectionAssignment, pending *models.AssignmentDelegation) error {
+			_, err := f.service.ReviewDelegation(ctx, "org-a", pending.ID, "super-1", false, "insp-2 is fully booked")
+			require.NoError(t, err)
+			_, err = f.service.CancelDelegation(ctx, "org-a", pending.ID, "insp-1", "inspector")
+			return err
+		}, ErrDelegationConflict, "insp-1"},
+		{"another inspector cancels the request", func(t *testing.T, f *assignmentHistoryTestFixture, _ models.InspectionAssignment, pending *models.AssignmentDelegation) error {
+			_, err := f.service.CancelDelegation(ctx, "org-a", pending.ID, "insp-3", "inspector")
+			return err
+		}, ErrDelegationNotAllowed, "insp-1"},
+		{"approving a request twice", func(t *testing.T, f *assignmentHistoryTestFixture, _ models.InspectionAssignment, pending *models.AssignmentDelegation) error {
+			_, err := f.service.ReviewDelegation(ctx, "org-a", pending.ID, "super-1", true, "")
+			require.NoError(t, err)
+			_, err = f.service.ReviewDelegation(ctx, "org-a", pending.ID, "super-1", true, "")
+			return err
+		}, ErrDelegationConflict, "insp-2"},
+		{"approving after the assignment moved", func(t *testing.T, f *assignmentHistoryTestFixture, assignment models.InspectionAssignment, pending *models.AssignmentDelegation) error {
+			_, err := f.workflow.ReassignInspection(context.Background(), "org-a", assignment.ID, "super-1", "insp-3", "Closer to site", true)
+			require.NoError(t, err)
+			_, err = f.service.ReviewDelegation(ctx, "org-a", pending.ID, "super-1", true, "")
+			return err
+		}, ErrDelegationConflict, "insp-3"},
+		{"reviewing an unknown request", func(t *testing.T, f *assignmentHistoryTestFixture, _ models.InspectionAssignment, _ *models.AssignmentDelegation) error {
+			_, err := f.service.ReviewDelegation(ctx, "org-a", uuid.NewString(), "super-1", true, "")
+			return err
+		}, ErrDelegationNotFound, "insp-1"},
+		{"reviewing from another organization", func(t *testing.T, f *assignmentHistoryTestFixture, _ models.InspectionAssignment, pending *models.AssignmentDelegation) error {
+			_, err := f.service.ReviewDelegation(ctx, "org-b", pending.ID, "super-1", true, "")
+			return err
+		}, ErrDelegationNotFound, "insp-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newAssignmentHistoryTestFixture(t)
+			assignment := f.acceptedAssignment(t)
+			pending := f.requestDelegation(t, assignment.ID, "insp-2")
+
+			err := tt.act(t, f, assignment, pending)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantAssignee, f.assignee(t, assignment.ID).AssignedTo)
+		})
+	}
+}
+
+func TestAssignmentHistoryService_ApprovedDelegationMovesWork(t *testing.T) {
+	f := newAssignmentHistoryTestFixture(t)
+	assignment := f.acceptedAssignment(t)
+	delegation := f.requestDelegation(t, assignment.ID, "insp-2")
+
+	approved, err := f.service.ReviewDelegation(context.Background(), "org-a", delegation.ID, "super-1", true, "")
+	require.NoError(t, err)
+	assert.Equal(t, models.DelegationApproved, approved.Status)
+
+	// The new inspector has to accept the work again
+	moved := f.assignee(t, assignment.ID)
+	assert.Equal(t, "insp-2", moved.AssignedTo)
+	assert.Equal(t, "pending", moved.Status)
+	require.NotNil(t, moved.DelegatedFrom)
+	assert.Equal(t, "insp-1", *moved.DelegatedFrom)
+}
+
+func TestAssignmentHistoryService_TimelineRecordsEveryHop(t *testing.T) {
+	f := newAssignmentHistoryTestFixture(t)
+	ctx := context.Background()
+	assignment := f.acceptedAssignment(t)
+
+	declined := f.requestDelegation(t, assignment.ID, "insp-3")
+	_, err := f.service.ReviewDelegation(ctx, "org-a", declined.ID, "super-1", false, "insp-3 is fully booked")
+	require.NoError(t, err)
+	delegation := f.requestDelegation(t, assignment.ID, "insp-2")
+	_, err = f.service.ReviewDelegation(ctx, "org-a", delegation.ID, "super-1", true, "")
+	require.NoError(t, err)
+	_, err = f.workflow.ReassignInspection(context.Background(), "org-a", assignment.ID, "super-1", "insp-3", "Closer to site", false)
+	require.NoError(t, err)
+
+	// Starting and completing one of its inspections closes the chain
+	inspection := &models.Inspection{OrganizationID: "org-a", TemplateID: f.template.ID, InspectorID: "insp-3", SiteID: f.site.ID, AssignmentID: &assignment.ID, Status: "assigned"}
+	require.NoError(t, f.db.Create(inspection).Error)
+	started := *inspection
+	started.Status = "in_progress"
+	require.NoError(t, recordInspectionEvents(ctx, f.db, inspection, &started))
+	completed := started
+	completed.Status = "completed"
+	require.NoError(t, recordInspectionEvents(ctx, f.db, &started, &completed))
+	require.NoError(t, recordInspectionEvents(ctx, f.db, &completed, &completed))
+
+	timeline, err := f.service.GetTimeline(ctx, "org-a", assignment.ID, "insp-1", "inspector")
+	require.NoError(t, err)
+	var types []string
+	for _, event := range timeline {
+		types = append(types, event.EventType)
+	}
+	assert.Equal(t, []string{
+		models.AssignmentEventCreated, models.AssignmentEventAccepted,
+		models.AssignmentEventDelegationRequested, models.AssignmentEventDelegationDeclined,
+		models.AssignmentEventDelegationRequested, models.AssignmentEventDelegated,
+		models.AssignmentEventReassigned, models.AssignmentEventStarted, models.AssignmentEventCompleted,
+	}, types)
+	assert.Equal(t, "super-1", timeline[5].ActorID)
+	assert.Equal(t, "insp-2", *timeline[5].ToUserID)
+	assert.Equal(t, "Closer to site", timeline[6].Reason)
+	require.NotNil(t, timeline[6].Actor)
+}
+
+func TestAssignmentHistoryService_GetTimelineAccess(t *testing.T) {
+	tests := []struct {
+		name         string
+		organization string
+		userID       string
+		role         string
+		wantErr      error
+	}{
+		{"assignee", "org-a", "insp-1", "inspector", nil},
+		{"supervisor", "org-a", "super-1", "supervisor", nil},
+		{"inspector who never held it", "org-a", "insp-2", "inspector", ErrAssignmentHistoryForbidden},
+		{"other organization", "org-b", "super-1", "supervisor", ErrAssignmentNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newAssignmentHistoryTestFixture(t)
+			assignment := f.assign(t, "insp-1")
+
+			timeline, err := f.service.GetTimeline(context.Background(), tt.organization, assignment.ID, tt.userID, tt.role)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotEmpty(t, timeline)
+		})
+	}
+}
@@ -0,0 +1,761 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"resource-mgmt/models"
+	"resource-mgmt/pkg/database"
+	"resource-mgmt/utils"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidAutoAssign is returned for auto-assign requests and overrides that can't be
+	// satisfied as given
+	ErrInvalidAutoAssign = errors.New("invalid auto-assign request")
+	// ErrAutoAssignDisabled is returned for projects that haven't turned on AutoAssignInspectors
+	ErrAutoAssignDisabled = errors.New("automatic inspector assignment is not enabled for this project")
+	// ErrAssignmentProposalNotFound is returned when a proposal doesn't exist in the caller's organization
+	ErrAssignmentProposalNotFound = errors.New("assignment proposal not found")
+	// ErrAssignmentProposalClosed is returned when a proposal was already accepted or discarded
+	ErrAssignmentProposalClosed = errors.New("assignment proposal was already accepted or discarded")
+)
+
+const (
+	// maxAutoAssignSites bounds the batch a single proposal can place
+	maxAutoAssignSites = 1000
+
+	// Default caps for inspectors without a workload record, matching the column defaults
+	defaultMaxDailyInspections   = 8
+	defaultMaxWeeklyInspections  = 40
+	defaultMaxConcurrentProjects = 5
+
+	// Score weights. They add up to 1, so a candidate matching on everything scores 1.
+	autoAssignRegionWeight     = 0.3
+	autoAssignSiteTypeWeight   = 0.2
+	autoAssignDistanceWeight   = 0.25
+	autoAssignCapacityWeight   = 0.15
+	autoAssignCompletionWeight = 0.1
+)
+
+// openAssignmentStatuses are the assignment statuses that still occupy an inspector
+var openAssignmentStatuses = []string{"pending", "active"}
+
+// autoAssignCandidate is an inspector the solver may place sites with, and what it knows
+// about them for the day being planned
+type autoAssignCandidate struct {
+	id       string
+	name     string
+	workload models.InspectorWorkload
+
+	regions         []string // Lower-cased PreferredRegions
+	siteTypes       []string // Lower-cased PreferredSiteTypes
+	specializations []string // Lower-cased Specializations
+
+	dailyLeft  int
+	weeklyLeft int
+	assigned   int
+
+	// blocked lists what rules the inspector out for the whole batch
+	blocked []string
+}
+
+type AssignmentSolverService struct {
+	db              *gorm.DB
+	workflowService *WorkflowService
+}
+
+// NewAssignmentSolverService creates the solver. Accepted proposals are committed through
+// the workflow service, the same way hand-picked bulk assignments are.
+func NewAssignmentSolverService(db *gorm.DB, workflowService *WorkflowService) *AssignmentSolverService {
+	return &AssignmentSolverService{db: db, workflowService: workflowService}
+}
+
+// =====================================================
+// PROPOSALS
+// =====================================================
+
+// Propose distributes req.SiteIDs across the eligible inspectors and stores the result as
+// a proposal. Sites go to the best scoring inspector with capacity left, most constrained
+// sites first; every placement and every passed-over inspector comes with its reasons.
+func (s *AssignmentSolverService) Propose(ctx context.Context, organizationID, userID string, req *models.AutoAssignRequest) (*models.AssignmentProposal, error) {
+	siteIDs := uniqueStrings(req.SiteIDs)
+	if len(siteIDs) == 0 {
+		return nil, fmt.Errorf("%w: at least one site is required", ErrInvalidAutoAssign)
+	}
+	if len(siteIDs) > maxAutoAssignSites {
+		return nil, fmt.Errorf("%w: at most %d sites can be assigned at once", ErrInvalidAutoAssign, maxAutoAssignSites)
+	}
+
+	db := database.Conn(ctx, s.db)
+
+	var template models.Template
+	if err := db.Where("id = ? AND organization_id = ?", req.TemplateID, organizationID).First(&template).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: template not found", ErrInvalidAutoAssign)
+		}
+		return nil, fmt.Errorf("failed to get template: %v", err)
+	}
+
+	projectID := ""
+	if req.ProjectID != nil && *req.ProjectID != "" {
+		var project models.InspectionProject
+		if err := db.Where("id = ? AND organization_id = ?", *req.ProjectID, organizationID).First(&project).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("%w: project not found", ErrInvalidAutoAssign)
+			}
+			return nil, fmt.Errorf("failed to get project: %v", err)
+		}
+		if !project.AutoAssignInspectors {
+			return nil, ErrAutoAssignDisabled
+		}
+		projectID = project.ID
+	}
+
+	var sites []models.Site
+	if err := db.Where("id IN ? AND organization_id = ?", siteIDs, organizationID).Order("name ASC").Find(&sites).Error; err != nil {
+		return nil, fmt.Errorf("failed to get sites: %v", err)
+	}
+	if len(sites) != len(siteIDs) {
+		return nil, fmt.Errorf("%w: some sites were not found", ErrInvalidAutoAssign)
+	}
+
+	scheduled := time.Now()
+	if req.ScheduledFor != nil {
+		scheduled = *req.ScheduledFor
+	}
+	day := time.Date(scheduled.Year(), scheduled.Month(), scheduled.Day(), 0, 0, 0, 0, scheduled.Location())
+
+	candidates, err := s.loadCandidates(ctx, organizationID, req.InspectorIDs, template.ID.String(), template.Category, projectID, day)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("%w: there are no active inspectors to assign", ErrInvalidAutoAssign)
+	}
+
+	items := solveAssignments(sites, candidates, day)
+
+	assigned := 0
+	for _, item := range items {
+		if item.InspectorID != "" {
+			assigned++
+		}
+	}
+
+	requestJSON, _ := json.Marshal(req)
+	itemsJSON, _ := json.Marshal(items)
+	proposal := &models.AssignmentProposal{
+		OrganizationID: organizationID,
+		ProjectID:      req.ProjectID,
+		TemplateID:     req.TemplateID,
+		Status:         models.AssignmentProposalProposed,
+		ScheduledFor:   day,
+		DueDate:        req.DueDate,
+		Request:        datatypes.JSON(requestJSON),
+		Items:          datatypes.JSON(itemsJSON),
+		Assigned:       assigned,
+		Unassigned:     len(items) - assigned,
+		CreatedBy:      userID,
+	}
+	if projectID == "" {
+		proposal.ProjectID = nil
+	}
+	if err := db.Create(proposal).Error; err != nil {
+		return nil, fmt.Errorf("failed to save assignment proposal: %v", err)
+	}
+	return proposal, nil
+}
+
+// GetProposal returns one proposal of the organization
+func (s *AssignmentSolverService) GetProposal(ctx context.Context, organizationID, proposalID string) (*models.AssignmentProposal, error) {
+	var proposal models.AssignmentProposal
+	if err := database.Conn(ctx, s.db).Where("id = ? AND organization_id = ?", proposalID, organizationID).First(&proposal).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAssignmentProposalNotFound
+		}
+		return nil, fmt.Errorf("failed to get assignment proposal: %v", err)
+	}
+	return &proposal, nil
+}
+
+// GetProposals lists the organization's proposals, newest first. Supported filters:
+// status, project_id.
+func (s *AssignmentSolverService) GetProposals(ctx context.Context, organizationID string, filters map[string]interface{}) ([]models.AssignmentProposal, error) {
+	query := database.Conn(ctx, s.db).Where("organization_id = ?", organizationID)
+	if status, ok := filters["status"].(string); ok && status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if projectID, ok := filters["project_id"].(string); ok && projectID != "" {
+		query = query.Where("project_id = ?", projectID)
+	}
+
+	var proposals []models.AssignmentProposal
+	if err := query.Order("created_at DESC").Limit(100).Find(&proposals).Error; err != nil {
+		return nil, fmt.Errorf("failed to get assignment proposals: %v", err)
+	}
+	return proposals, nil
+}
+
+// AcceptProposal applies the supervisor's overrides and creates the assignments and
+// inspections the proposal describes. Overridden sites skip the solver's checks: the
+// supervisor has the final say, as with hand-picked bulk assignments.
+func (s *AssignmentSolverService) AcceptProposal(ctx context.Context, organizationID, userID, proposalID string, req *models.AcceptAssignmentProposalRequest) (*models.AssignmentProposal, []models.InspectionAssignment, error) {
+	proposal, err := s.GetProposal(ctx, organizationID, proposalID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if proposal.Status != models.AssignmentProposalProposed {
+		return nil, nil, ErrAssignmentProposalClosed
+	}
+
+	var items []models.AssignmentProposalItem
+	if err := json.Unmarshal(proposal.Items, &items); err != nil {
+		return nil, nil, fmt.Errorf("failed to read assignment proposal: %v", err)
+	}
+	var original models.AutoAssignRequest
+	if err := json.Unmarshal(proposal.Request, &original); err != nil {
+		return nil, nil, fmt.Errorf("failed to read assignment proposal: %v", err)
+	}
+
+	if err := s.applyOverrides(ctx, organizationID, items, req.Overrides); err != nil {
+		return nil, nil, err
+	}
+
+	// Group sites by inspector, keeping the order inspectors were first given a site
+	var inspectorOrder []string
+	sitesByInspector := make(map[string][]string)
+	for _, item := range items {
+		if item.InspectorID == "" {
+			continue
+		}
+		if _, ok := sitesByInspector[item.InspectorID]; !ok {
+			inspectorOrder = append(inspectorOrder, item.InspectorID)
+		}
+		sitesByInspector[item.InspectorID] = append(sitesByInspector[item.InspectorID], item.SiteID)
+	}
+	if len(inspectorOrder) == 0 {
+		return nil, nil, fmt.Errorf("%w: the proposal assigns no sites", ErrInvalidAutoAssign)
+	}
+
+	// Claim the proposal so two supervisors can't both commit it
+	db := database.Conn(ctx, s.db)
+	claim := db.Model(&models.AssignmentProposal{}).
+		Where("id = ? AND status = ?", proposal.ID, models.AssignmentProposalProposed).
+		Update("status", models.AssignmentProposalAccepted)
+	if claim.Error != nil {
+		return nil, nil, fmt.Errorf("failed to accept assignment proposal: %v", claim.Error)
+	}
+	if claim.RowsAffected == 0 {
+		return nil, nil, ErrAssignmentProposalClosed
+	}
+
+	inspectorAssignments := make([]map[string]interface{}, 0, len(inspectorOrder))
+	var siteIDs []string
+	for _, inspectorID := range inspectorOrder {
+		inspectorAssignments = append(inspectorAssignments, map[string]interface{}{
+			"inspector_id": inspectorID,
+			"site_ids":     sitesByInspector[inspectorID],
+		})
+		siteIDs = append(siteIDs, sitesByInspector[inspectorID]...)
+	}
+	scheduled := proposal.ScheduledFor
+	assignments, err := s.workflowService.CreateBulkAssignment(ctx, organizationID, userID, map[string]interface{}{
+		"name":                  original.Name,
+		"description":           original.Description,
+		"project_id":            proposal.ProjectID,
+		"priority":              original.Priority,
+		"template_id":           proposal.TemplateID,
+		"site_ids":              siteIDs,
+		"inspector_assignments": inspectorAssignments,
+		"start_date":            &scheduled,
+		"due_date":              proposal.DueDate,
+		"estimated_hours":       original.EstimatedHours,
+		"instructions":          original.Instructions,
+		"requires_acceptance":   req.RequiresAcceptance,
+		"allow_reassignment":    req.AllowReassignment,
+		"notify_on_overdue":     req.NotifyOnOverdue,
+		"metadata":              original.Metadata,
+	})
+	if err != nil {
+		db.Model(&models.AssignmentProposal{}).Where("id = ?", proposal.ID).Update("status", models.AssignmentProposalProposed)
+		return nil, nil, err
+	}
+
+	batchID := ""
+	if len(assignments) > 0 {
+		batchID = assignments[0].BatchID
+		if err := db.Model(&models.InspectionAssignment{}).
+			Where("organization_id = ? AND batch_id = ?", organizationID, batchID).
+			Update("assignment_type", "auto").Error; err != nil {
+			return nil, nil, fmt.Errorf("failed to mark assignments as automatic: %v", err)
+		}
+		for i := range assignments {
+			assignments[i].AssignmentType = "auto"
+		}
+	}
+
+	assigned := 0
+	for _, item := range items {
+		if item.InspectorID != "" {
+			assigned++
+		}
+	}
+	itemsJSON, _ := json.Marshal(items)
+	now := time.Now()
+	updates := map[string]interface{}{
+		"items":       datatypes.JSON(itemsJSON),
+		"assigned":    assigned,
+		"unassigned":  len(items) - assigned,
+		"batch_id":    batchID,
+		"accepted_by": userID,
+		"accepted_at": now,
+	}
+	if err := db.Model(&models.AssignmentProposal{}).Where("id = ?", proposal.ID).Updates(updates).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to update assignment proposal: %v", err)
+	}
+
+	proposal.Status = models.AssignmentProposalAccepted
+	proposal.Items = datatypes.JSON(itemsJSON)
+	proposal.Assigned = assigned
+	proposal.Unassigned = len(items) - assigned
+	proposal.BatchID = batchID
+	proposal.AcceptedBy = &userID
+	proposal.AcceptedAt = &now
+	return proposal, assignments, nil
+}
+
+// DiscardProposal closes a proposal without assigning anything
+func (s *AssignmentSolverService) DiscardProposal(ctx context.Context, organizationID, proposalID string) (*models.AssignmentProposal, error) {
+	proposal, err := s.GetProposal(ctx, organizationID, proposalID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := database.Conn(ctx, s.db).Model(&models.AssignmentProposal{}).
+		Where("id = ? AND status = ?", proposal.ID, models.AssignmentProposalProposed).
+		Update("status", models.AssignmentProposalDiscarded)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to discard assignment proposal: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrAssignmentProposalClosed
+	}
+
+	proposal.Status = models.AssignmentProposalDiscarded
+	return proposal, nil
+}
+
+// applyOverrides moves sites to the inspectors the supervisor picked. An empty inspector
+// ID takes the site out of the batch.
+func (s *AssignmentSolverService) applyOverrides(ctx context.Context, organizationID string, items []models.AssignmentProposalItem, overrides map[string]string) error {
+	if len(overrides) == 0 {
+		return nil
+	}
+
+	index := make(map[string]int, len(items))
+	for i, item := range items {
+		index[item.SiteID] = i
+	}
+
+	names := make(map[string]string)
+	for siteID, inspectorID := range overrides {
+		i, ok := index[siteID]
+		if !ok {
+			return fmt.Errorf("%w: site %s is not part of the proposal", ErrInvalidAutoAssign, siteID)
+		}
+		if inspectorID == items[i].InspectorID {
+			continue
+		}
+
+		if inspectorID != "" {
+			if _, checked := names[inspectorID]; !checked {
+				var member models.OrganizationMember
+				err := database.Conn(ctx, s.db).
+					Where("user_id = ? AND organization_id = ? AND status = ?", inspectorID, organizationID, "active").
+					First(&member).Error
+				if err != nil || !utils.HasHigherOrEqualPrivilege(member.Role, "inspector") {
+					return fmt.Errorf("%w: %s is not an active inspector", ErrInvalidAutoAssign, inspectorID)
+				}
+				var user models.GlobalUser
+				database.Conn(ctx, s.db).Select("id", "name").Where("id = ?", inspectorID).First(&user)
+				names[inspectorID] = user.Name
+			}
+		}
+
+		items[i].InspectorID = inspectorID
+		items[i].InspectorName = names[inspectorID]
+		items[i].Score = 0
+		items[i].DistanceKm = nil
+		items[i].Overridden = true
+		if inspectorID == "" {
+			items[i].Reasons = []string{"left out by supervisor"}
+		} else {
+			items[i].Reasons = []string{"assigned by supervisor"}
+		}
+	}
+	return nil
+}
+
+// =====================================================
+// CANDIDATES
+// =====================================================
+
+// loadCandidates gathers the inspectors a batch may go to, with their workload settings
+// and how much of the planned day and week they still have free. Inspectors that are
+// ruled out for the whole batch are kept, with the reasons, so the proposal can say why.
+func (s *AssignmentSolverService) loadCandidates(ctx context.Context, organizationID string, inspectorIDs []string, templateID, category, projectID string, day time.Time) ([]*autoAssignCandidate, error) {
+	db := database.Conn(ctx, s.db)
+
+	query := db.Where("organization_id = ? AND status = ?", organizationID, "active")
+	inspectorIDs = uniqueStrings(inspectorIDs)
+	if len(inspectorIDs) > 0 {
+		query = query.Where("user_id IN ?", inspectorIDs)
+	} else {
+		query = query.Where("role = ?", "inspector")
+	}
+	var members []models.OrganizationMember
+	if err := query.Order("user_id ASC").Find(&members).Error; err != nil {
+		return nil, fmt.Errorf("failed to get inspectors: %v", err)
+	}
+
+	var ids []string
+	for _, member := range members {
+		if !utils.HasHigherOrEqualPrivilege(member.Role, "inspector") {
+			return nil, fmt.Errorf("%w: %s does not have inspector privileges", ErrInvalidAutoAssign, member.UserID)
+		}
+		ids = append(ids, member.UserID)
+	}
+	if len(inspectorIDs) > 0 && len(ids) != len(inspectorIDs) {
+		return nil, fmt.Errorf("%w: some inspectors are not active members of the organization", ErrInvalidAutoAssign)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var users []models.GlobalUser
+	if err := db.Select("id", "name").Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to get inspector names: %v", err)
+	}
+	names := make(map[string]string, len(users))
+	for _, user := range users {
+		names[user.ID] = user.Name
+	}
+
+	var workloads []models.InspectorWorkload
+	if err := db.Where("organization_id = ? AND inspector_id IN ?", organizationID, ids).Find(&workloads).Error; err != nil {
+		return nil, fmt.Errorf("failed to get inspector workloads: %v", err)
+	}
+	workloadByInspector := make(map[string]models.InspectorWorkload, len(workloads))
+	for _, workload := range workloads {
+		workloadByInspector[workload.InspectorID] = workload
+	}
+
+	weekStart := day.AddDate(0, 0, -int(day.Weekday()))
+	dailyLoad, err := s.scheduledLoad(ctx, organizationID, ids, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	weeklyLoad, err := s.scheduledLoad(ctx, organizationID, ids, weekStart, weekStart.AddDate(0, 0, 7))
+	if err != nil {
+		return nil, err
+	}
+	projects, err := s.activeProjects(ctx, organizationID, ids)
+	if err != nil {
+		return nil, err
+	}
+	availability, err := loadAvailabilityCalendar(ctx, s.db, organizationID, ids, day, day)
+	if err != nil {
+		return nil, err
+	}
+
+	_, unqualified, err := qualificationGaps(ctx, s.db, organizationID, templateID, ids, day, day)
+	if err != nil {
+		return nil, err
+	}
+
+	category = strings.ToLower(strings.TrimSpace(category))
+	candidates := make([]*autoAssignCandidate, 0, len(ids))
+	for _, id := range ids {
+		workload, ok := workloadByInspector[id]
+		if !ok {
+			workload = models.InspectorWorkload{OrganizationID: organizationID, InspectorID: id, IsAvailable: true}
+		}
+		if workload.MaxDailyInspections <= 0 {
+			workload.MaxDailyInspections = defaultMaxDailyInspections
+		}
+		if workload.MaxWeeklyInspections <= 0 {
+			workload.MaxWeeklyInspections = defaultMaxWeeklyInspections
+		}
+		if workload.MaxConcurrentProjects <= 0 {
+			workload.MaxConcurrentProjects = defaultMaxConcurrentProjects
+		}
+		if workload.MaxTravelDistance <= 0 {
+			workload.MaxTravelDistance = defaultTravelDistanceKm
+		}
+
+		candidate := &autoAssignCandidate{
+			id:              id,
+			name:            names[id],
+			workload:        workload,
+			regions:         lowerJSONStrings(workload.PreferredRegions),
+			siteTypes:       lowerJSONStrings(workload.PreferredSiteTypes),
+			specializations: lowerJSONStrings(workload.Specializations),
+			dailyLeft:       max(workload.MaxDailyInspections-dailyLoad[id], 0),
+			weeklyLeft:      max(workload.MaxWeeklyInspections-weeklyLoad[id], 0),
+		}
+
+		if reason := availability.unavailableReason(id, day); reason != "" {
+			candidate.blocked = append(candidate.blocked, reason)
+		}
+		if missing := unqualified[id]; len(missing) > 0 {
+			candidate.blocked = append(candidate.blocked, "missing certification: "+certificationTypeNames(missing))
+		}
+		if category != "" && !containsString(candidate.specializations, category) {
+			candidate.blocked = append(candidate.blocked, fmt.Sprintf("no %s specialization", category))
+		}
+		if projectID != "" && !containsString(projects[id], projectID) && len(projects[id]) >= workload.MaxConcurrentProjects {
+			candidate.blocked = append(candidate.blocked, fmt.Sprintf("already on %d of %d concurrent projects", len(projects[id]), workload.MaxConcurrentProjects))
+		}
+
+		candidates = append(candidates, candidate)
+	}
+	return candidates, nil
+}
+
+// scheduledLoad counts each inspector's open inspections scheduled in [from, to)
+func (s *AssignmentSolverService) scheduledLoad(ctx context.Context, organizationID string, inspectorIDs []string, from, to time.Time) (map[string]int, error) {
+	var rows []struct {
+		InspectorID string
+		Count       int
+	}
+	if err := database.Conn(ctx, s.db).Model(&models.Inspection{}).
+		Select("inspector_id, COUNT(*) AS count").
+		Where("organization_id = ? AND inspector_id IN ? AND status NOT IN ?", organizationID, inspectorIDs, closedInspectionStatuses).
+		Where("scheduled_for >= ? AND scheduled_for < ?", from, to).
+		Group("inspector_id").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to count scheduled inspections: %v", err)
+	}
+
+	load := make(map[string]int, len(rows))
+	for _, row := range rows {
+		load[row.InspectorID] = row.Count
+	}
+	return load, nil
+}
+
+// activeProjects returns the projects each inspector has open assignments in
+func (s *AssignmentSolverService) activeProjects(ctx context.Context, organizationID string, inspectorIDs []string) (map[string][]string, error) {
+	var rows []struct {
+		AssignedTo string
+		ProjectID  string
+	}
+	if err := database.Conn(ctx, s.db).Model(&models.InspectionAssignment{}).
+		Distinct("assigned_to", "project_id").
+		Where("organization_id = ? AND assigned_to IN ? AND status IN ? AND project_id IS NOT NULL", organizationID, inspectorIDs, openAssignmentStatuses).
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to get active projects: %v", err)
+	}
+
+	projects := make(map[string][]string)
+	for _, row := range rows {
+		projects[row.AssignedTo] = append(projects[row.AssignedTo], row.ProjectID)
+	}
+	return projects, nil
+}
+
+// =====================================================
+// SOLVER
+// =====================================================
+
+// autoAssignOption is a candidate that may take a site, before capacity is considered
+type autoAssignOption struct {
+	candidate  *autoAssignCandidate
+	distanceKm *float64
+}
+
+// solveAssignments places each site with the best scoring candidate that has room. Sites
+// with the fewest eligible inspectors are placed first so they aren't crowded out by
+// sites anyone could take.
+func solveAssignments(sites []models.Site, candidates []*autoAssignCandidate, day time.Time) []models.AssignmentProposalItem {
+	options := make(map[string][]autoAssignOption, len(sites))
+	rejections := make(map[string]map[string][]string, len(sites))
+	for _, site := range sites {
+		rejections[site.ID] = make(map[string][]string)
+		for _, candidate := range candidates {
+			reasons := append([]string(nil), candidate.blocked...)
+			distanceKm := candidate.distanceTo(site)
+			if distanceKm != nil && *distanceKm > float64(candidate.workload.MaxTravelDistance) {
+				reasons = append(reasons, fmt.Sprintf("%.1f km away, travels at most %d km", *distanceKm, candidate.workload.MaxTravelDistance))
+			}
+			if len(reasons) > 0 {
+				rejections[site.ID][candidate.id] = reasons
+				continue
+			}
+			options[site.ID] = append(options[site.ID], autoAssignOption{candidate: candidate, distanceKm: distanceKm})
+		}
+	}
+
+	// Among equally constrained sites, the strongest matches go first
+	bestFit := make(map[string]float64, len(sites))
+	for _, site := range sites {
+		for _, option := range options[site.ID] {
+			score, _ := option.candidate.score(site, option.distanceKm)
+			bestFit[site.ID] = math.Max(bestFit[site.ID], score)
+		}
+	}
+	order := append([]models.Site(nil), sites...)
+	sort.SliceStable(order, func(i, j int) bool {
+		if len(options[order[i].ID]) != len(options[order[j].ID]) {
+			return len(options[order[i].ID]) < len(options[order[j].ID])
+		}
+		return bestFit[order[i].ID] > bestFit[order[j].ID]
+	})
+
+	dayLabel := day.Format("2006-01-02")
+	items := make([]models.AssignmentProposalItem, 0, len(order))
+	for _, site := range order {
+		item := models.AssignmentProposalItem{SiteID: site.ID, SiteName: site.Name}
+
+		var best *autoAssignOption
+		bestScore := -1.0
+		var bestReasons []string
+		scores := make(map[string]float64)
+		for i := range options[site.ID] {
+			option := &options[site.ID][i]
+			candidate := option.candidate
+			if candidate.dailyLeft <= 0 {
+				rejections[site.ID][candidate.id] = []string{"no daily capacity left on " + dayLabel}
+				continue
+			}
+			if candidate.weeklyLeft <= 0 {
+				rejections[site.ID][candidate.id] = []string{"no weekly capacity left in the week of " + dayLabel}
+				continue
+			}
+
+			score, reasons := candidate.score(site, option.distanceKm)
+			scores[candidate.id] = score
+			if best == nil || score > bestScore+1e-9 ||
+				(math.Abs(score-bestScore) <= 1e-9 && candidate.assigned < best.candidate.assigned) {
+				best, bestScore, bestReasons = option, score, reasons
+			}
+		}
+
+		if best != nil {
+			item.InspectorID = best.candidate.id
+			item.InspectorName = best.candidate.name
+			item.Score = math.Round(bestScore*1000) / 1000
+			item.DistanceKm = best.distanceKm
+			item.Reasons = bestReasons
+			best.candidate.dailyLeft--
+			best.candidate.weeklyLeft--
+			best.candidate.assigned++
+
+			for id, score := range scores {
+				if id != best.candidate.id {
+					rejections[site.ID][id] = []string{fmt.Sprintf("scored %.2f against %.2f", score, bestScore)}
+				}
+			}
+		} else {
+			item.Reasons = []string{"no eligible inspector had capacity"}
+		}
+
+		item.Rejected = []models.AssignmentCandidateRejection{}
+		for _, candidate := range candidates {
+			if reasons, ok := rejections[site.ID][candidate.id]; ok {
+				item.Rejected = append(item.Rejected, models.AssignmentCandidateRejection{
+					InspectorID: candidate.id, InspectorName: candidate.name, Reasons: reasons,
+				})
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// distanceTo is the distance in km from the candidate's base to the site, or nil when
+// either has no coordinates
+func (c *autoAssignCandidate) distanceTo(site models.Site) *float64 {
+	if c.workload.BaseLatitude == nil || c.workload.BaseLongitude == nil || site.Latitude == nil || site.Longitude == nil {
+		return nil
+	}
+	distance := HaversineKm(
+		models.GeoPoint{Latitude: *c.workload.BaseLatitude, Longitude: *c.workload.BaseLongitude},
+		models.GeoPoint{Latitude: *site.Latitude, Longitude: *site.Longitude},
+	)
+	distance = math.Round(distance*10) / 10
+	return &distance
+}
+
+// score rates how well the site suits the candidate, between 0 and 1, and says why
+func (c *autoAssignCandidate) score(site models.Site, distanceKm *float64) (float64, []string) {
+	var score float64
+	var reasons []string
+
+	for _, region := range []string{site.State, site.City, site.Country, site.ZipCode} {
+		if region != "" && containsString(c.regions, strings.ToLower(region)) {
+			score += autoAssignRegionWeight
+			reasons = append(reasons, "in preferred region "+region)
+			break
+		}
+	}
+
+	if site.Type != "" && containsString(c.siteTypes, strings.ToLower(site.Type)) {
+		score += autoAssignSiteTypeWeight
+		reasons = append(reasons, "prefers "+site.Type+" sites")
+	}
+
+	if distanceKm != nil {
+		score += autoAssignDistanceWeight * (1 - *distanceKm/float64(c.workload.MaxTravelDistance))
+		reasons = append(reasons, fmt.Sprintf("%.1f km from base, within %d km", *distanceKm, c.workload.MaxTravelDistance))
+	} else {
+		score += autoAssignDistanceWeight / 2
+		reasons = append(reasons, "distance unknown")
+	}
+
+	score += autoAssignCapacityWeight * float64(c.dailyLeft) / float64(c.workload.MaxDailyInspections)
+	reasons = append(reasons, fmt.Sprintf("%d of %d daily and %d of %d weekly inspections free",
+		c.dailyLeft, c.workload.MaxDailyInspections, c.weeklyLeft, c.workload.MaxWeeklyInspections))
+
+	if c.workload.CompletionRate > 0 {
+		score += autoAssignCompletionWeight * math.Min(c.workload.CompletionRate, 100) / 100
+		reasons = append(reasons, fmt.Sprintf("%.0f%% on-time completion", c.workload.CompletionRate))
+	}
+
+	return score, reasons
+}
+
+// lowerJSONStrings decodes a JSON array of strings, trimmed and lower-cased
+func lowerJSONStrings(raw datatypes.JSON) []string {
+	var values []string
+	if len(raw) == 0 || json.Unmarshal(raw, &values) != nil {
+		return nil
+	}
+	for i, value := range values {
+		values[i] = strings.ToLower(strings.TrimSpace(value))
+	}
+	return values
+}
+
+// uniqueStrings drops empty and repeated values, keeping the first occurrence's order
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var result []string
+	for _, value := range values {
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		result = append(result, value)
+	}
+	return result
+}
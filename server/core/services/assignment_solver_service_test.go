@@ -0,0 +1,276 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"resource-mgmt/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// assignmentSolverTestFixture is a fire safety template, a project that hasn't opted in to
+// automatic assignment, and four Illinois sites for 20 October 2026. insp-north is based in
+// Chicago with one of two daily slots taken, insp-south is in Springfield, insp-away is on
+// vacation and insp-plumbing has the wrong specialization.
+type assignmentSolverTestFixture struct {
+	db                                *gorm.DB
+	service                           *AssignmentSolverService
+	template                          *models.Template
+	project                           *models.InspectionProject
+	day                               time.Time
+	loop, westSide, capitol, lakeview *models.Site
+}
+
+func newAssignmentSolverTestFixture(t *testing.T) *assignmentSolverTestFixture {
+	db := setupWorkflowTestDB(t, &models.InspectionProject{}, &models.AssignmentProposal{})
+	f := &assignmentSolverTestFixture{
+		db:      db,
+		service: NewAssignmentSolverService(db, NewWorkflowService(db, NewNotificationService())),
+		day:     time.Date(2026, 10, 20, 0, 0, 0, 0, time.Local),
+	}
+
+	f.template = &models.Template{ID: uuid.New(), OrganizationID: "org-a", Name: "Fire safety", Category: "Fire", FieldsSchema: datatypes.JSON(`{}`)}
+	require.NoError(t, db.Create(f.template).Error)
+	f.project = &models.InspectionProject{ID: uuid.NewString(), OrganizationID: "org-a", Name: "Fire audit", ProjectCode: "FA-1"}
+	require.NoError(t, db.Create(f.project).Error)
+
+	createTestMembers(t, db, "org-a", "inspector", "insp-north", "insp-south", "insp-away", "insp-plumbing")
+	createTestMembers(t, db, "org-a", "supervisor", "super-1")
+	createTestMembers(t, db, "org-a", "viewer", "viewer-1")
+
+	coords := func(lat, lng float64) (*float64, *float64) { return &lat, &lng }
+	workload := func(inspectorID, regions string, maxDaily int, lat, lng *float64) {
+		require.NoError(t, db.Create(&models.InspectorWorkload{
+			OrganizationID: "org-a", InspectorID: inspectorID, MaxDailyInspections: maxDaily, MaxWeeklyInspections: 40, MaxConcurrentProjects: 5,
+			IsAvailable: true, Specializations: datatypes.JSON(`["fire"]`), PreferredRegions: datatypes.JSON(regions),
+			PreferredSiteTypes: datatypes.JSON(`["warehouse"]`), MaxTravelDistance: 30,
+			BaseLatitude: lat, BaseLongitude: lng,
+		}).Error)
+	}
+	lat, lng := coords(41.88, -87.63) // Chicago
+	workload("insp-north", `["Illinois"]`, 2, lat, lng)
+	lat, lng = coords(39.78, -89.65) // Springfield
+	workload("insp-south", `["Springfield"]`, 8, lat, lng)
+	workload("insp-away", `["Illinois"]`, 8, nil, nil)
+	require.NoError(t, db.Create(&models.InspectorTimeOff{OrganizationID: "org-a", InspectorID: "insp-away", Type: models.TimeOffVacation, Status: models.TimeOffApproved,
+		StartDate: time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2026, 10, 21, 0, 0, 0, 0, time.UTC)}).Error)
+	require.NoError(t, db.Create(&models.InspectorWorkload{OrganizationID: "org-a", InspectorID: "insp-plumbing", Specializations: datatypes.JSON(`["plumbing"]`)}).Error)
+
+	require.NoError(t, db.Create(&models.Inspection{OrganizationID: "org-a", TemplateID: f.template.ID, InspectorID: "insp-north", SiteID: uuid.NewString(),
+		Status: "assigned", ScheduledFor: &f.day}).Error)
+
+	site := func(name, city, siteType string, lat, lng *float64) *models.Site {
+		s := &models.Site{ID: uuid.NewString(), OrganizationID: "org-a", Name: name, Address: "1 Main St", City: city, State: "Illinois",
+			Type: siteType, Latitude: lat, Longitude: lng, Status: "active"}
+		require.NoError(t, db.Create(s).Error)
+		return s
+	}
+	lat, lng = coords(41.90, -87.65)
+	f.loop = site("Loop Warehouse", "Chicago", "warehouse", lat, lng)
+	lat, lng = coords(41.85, -87.70)
+	f.westSide = site("West Side Office", "Chicago", "office", lat, lng)
+	lat, lng = coords(39.80, -89.64)
+	f.capitol = site("Capitol Depot", "Springfield", "warehouse", lat, lng)
+	lat, lng = coords(41.95, -87.60)
+	f.lakeview = site("Lakeview Store", "Chicago", "retail", lat, lng)
+	return f
+}
+
+// propose opts the project in and proposes all four sites
+func (f *assignmentSolverTestFixture) propose(t *testing.T) *models.AssignmentProposal {
+	require.NoError(t, f.db.Model(f.project).Update("auto_assign_inspectors", true).Error)
+	proposal, err := f.service.Propose(context.Background(), "org-a", "super-1", &models.AutoAssignRequest{Name: "Q4 fire checks", TemplateID: f.template.ID.String(),
+		ProjectID: &f.project.ID, SiteIDs: []string{f.loop.ID, f.westSide.ID, f.capitol.ID, f.lakeview.ID}, ScheduledFor: &f.day})
+	require.NoError(t, err)
+	return proposal
+}
+
+func proposalItemsBySite(t *testing.T, proposal *models.AssignmentProposal) map[string]models.AssignmentProposalItem {
+	var items []models.AssignmentProposalItem
+	require.NoError(t, json.Unmarshal(proposal.Items, &items))
+	bySite := make(map[string]models.AssignmentProposalItem)
+	for _, item := range items {
+		bySite[item.SiteID] = item
+	}
+	return bySite
+}
+
+func TestAssignmentSolverService_ProposeValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		request func(f *assignmentSolverTestFixture) *models.AutoAssignRequest
+		wantErr error
+	}{
+		{"no sites", func(f *assignmentSolverTestFixture) *models.AutoAssignRequest {
+			return &models.AutoAssignRequest{Name: "Q4", TemplateID: f.template.ID.String(), ScheduledFor: &f.day}
+		}, ErrInvalidAutoAssign},
+		{"unknown template", func(f *assignmentSolverTestFixture) *models.AutoAssignRequest {
+			return &models.AutoAssignRequest{Name: "Q4", TemplateID: uuid.NewString(), SiteIDs: []string{f.loop.ID}, ScheduledFor: &f.day}
+		}, ErrInvalidAutoAssign},
+		{"unknown site", func(f *assignmentSolverTestFixture) *models.AutoAssignRequest {
+			return &models.AutoAssignRequest{Name: "Q4", TemplateID: f.template.ID.String(), SiteIDs: []string{uuid.NewString()}, ScheduledFor: &f.day}
+		}, ErrInvalidAutoAssign},
+		{"unknown project", func(f *assignmentSolverTestFixture) *models.AutoAssignRequest {
+			projectID := uuid.NewString()
+			return &models.AutoAssignRequest{Name: "Q4", TemplateID: f.template.ID.String(), ProjectID: &projectID, SiteIDs: []string{f.loop.ID}, ScheduledFor: &f.day}
+		}, ErrInvalidAutoAssign},
+		{"project not opted in", func(f *assignmentSolverTestFixture) *models.AutoAssignRequest {
+			return &models.AutoAssignRequest{Name: "Q4", TemplateID: f.template.ID.String(), ProjectID: &f.project.ID, SiteIDs: []string{f.loop.ID}, ScheduledFor: &f.day}
+		}, ErrAutoAssignDisabled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newAssignmentSolverTestFixture(t)
+			_, err := f.service.Propose(context.Background(), "org-a", "super-1", tt.request(f))
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestAssignmentSolverService_ProposeRespectsRangeAndCapacity(t *testing.T) {
+	f := newAssignmentSolverTestFixture(t)
+
+	proposal := f.propose(t)
+	assert.Equal(t, models.AssignmentProposalProposed, proposal.Status)
+	bySite := proposalItemsBySite(t, proposal)
+	require.Len(t, bySite, 4)
+
+	// Springfield is out of insp-north's range, so only insp-south can take it
+	assert.Equal(t, "insp-south", bySite[f.capitol.ID].InspectorID)
+	assert.Contains(t, bySite[f.capitol.ID].Reasons, "in preferred region Springfield")
+	// insp-north has one free slot, which goes to the best match; the rest stays unassigned
+	assert.Equal(t, "insp-north", bySite[f.loop.ID].InspectorID)
+	assert.Contains(t, bySite[f.loop.ID].Reasons, "prefers warehouse sites")
+	assert.Equal(t, 2, proposal.Assigned)
+	assert.Equal(t, 2, proposal.Unassigned)
+	assert.Empty(t, bySite[f.lakeview.ID].InspectorID)
+	assert.Equal(t, []string{"no eligible inspector had capacity"}, bySite[f.lakeview.ID].Reasons)
+	assert.Empty(t, bySite[f.westSide.ID].InspectorID)
+}
+
+func TestAssignmentSolverService_ProposeExplainsRejections(t *testing.T) {
+	f := newAssignmentSolverTestFixture(t)
+	rejected := make(map[string][]string)
+	for _, rejection := range proposalItemsBySite(t, f.propose(t))[f.loop.ID].Rejected {
+		rejected[rejection.InspectorID] = rejection.Reasons
+	}
+
+	tests := []struct {
+		inspectorID string
+		want        string
+	}{
+		{"insp-away", "on approved vacation from 2026-10-19 to 2026-10-21"},
+		{"insp-plumbing", "no fire specialization"},
+		{"insp-south", "km away, travels at most 30 km"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.inspectorID, func(t *testing.T) {
+			require.NotEmpty(t, rejected[tt.inspectorID])
+			assert.Contains(t, strings.Join(rejected[tt.inspectorID], "; "), tt.want)
+		})
+	}
+}
+
+func TestAssignmentSolverService_AcceptProposalAppliesOverrides(t *testing.T) {
+	f := newAssignmentSolverTestFixture(t)
+	proposal := f.propose(t)
+
+	// The supervisor hands one leftover site to insp-plumbing and leaves the other out
+	accepted, assignments, err := f.service.AcceptProposal(context.Background(), "org-a", "super-1", proposal.ID, &models.AcceptAssignmentProposalRequest{
+		Overrides: map[string]string{f.lakeview.ID: "insp-plumbing"}, RequiresAcceptance: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.AssignmentProposalAccepted, accepted.Status)
+	assert.Equal(t, 3, accepted.Assigned)
+	require.Len(t, assignments, 3)
+	for _, assignment := range assignments {
+		assert.Equal(t, "auto", assignment.AssignmentType)
+		assert.Equal(t, f.project.ID, *assignment.ProjectID)
+	}
+
+	inspections := countRows(t, f.db.Model(&models.Inspection{}).Where("assignment_id IS NOT NULL"))
+	assert.Equal(t, int64(3), inspections)
+	var overridden models.Inspection
+	require.NoError(t, f.db.Where("site_id = ?", f.lakeview.ID).First(&overridden).Error)
+	assert.Equal(t, "insp-plumbing", overridden.InspectorID)
+}
+
+func TestAssignmentSolverService_AcceptProposalRejections(t *testing.T) {
+	tests := []struct {
+		name         string
+		organization string
+		prepare      func(t *testing.T, f *assignmentSolverTestFixture, proposal *models.AssignmentProposal)
+		overrides    func(f *assignmentSolverTestFixture) map[string]string
+		wantErr      error
+	}{
+		{"site not in the proposal", "org-a", nil,
+			func(f *assignmentSolverTestFixture) map[string]string {
+				return map[string]string{"missing-site": "insp-plumbing"}
+			}, ErrInvalidAutoAssign},
+		{"override to a non-inspector", "org-a", nil,
+			func(f *assignmentSolverTestFixture) map[string]string {
+				return map[string]string{f.lakeview.ID: "viewer-1"}
+			}, ErrInvalidAutoAssign},
+		{"override to a stranger", "org-a", nil,
+			func(f *assignmentSolverTestFixture) map[string]string {
+				return map[string]string{f.lakeview.ID: "someone-else"}
+			}, ErrInvalidAutoAssign},
+		{"every site taken out", "org-a", nil,
+			func(f *assignmentSolverTestFixture) map[string]string {
+				return map[string]string{f.loop.ID: "", f.capitol.ID: ""}
+			}, ErrInvalidAutoAssign},
+		{"already accepted", "org-a",
+			func(t *testing.T, f *assignmentSolverTestFixture, proposal *models.AssignmentProposal) {
+				_, _, err := f.service.AcceptProposal(context.Background(), "org-a", "super-1", proposal.ID, &models.AcceptAssignmentProposalRequest{})
+				require.NoError(t, err)
+			},
+			func(f *assignmentSolverTestFixture) map[string]string { return nil }, ErrAssignmentProposalClosed},
+		{"discarded", "org-a",
+			func(t *testing.T, f *assignmentSolverTestFixture, proposal *models.AssignmentProposal) {
+				_, err := f.service.DiscardProposal(context.Background(), "org-a", proposal.ID)
+				require.NoError(t, err)
+			},
+			func(f *assignmentSolverTestFixture) map[string]string { return nil }, ErrAssignmentProposalClosed},
+		{"other organization", "org-b", nil,
+			func(f *assignmentSolverTestFixture) map[string]string { return nil }, ErrAssignmentProposalNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newAssignmentSolverTestFixture(t)
+			proposal := f.propose(t)
+			if tt.prepare != nil {
+				tt.prepare(t, f, proposal)
+			}
+			before := countRows(t, f.db.Model(&models.InspectionAssignment{}))
+
+			_, _, err := f.service.AcceptProposal(context.Background(), tt.organization, "super-1", proposal.ID, &models.AcceptAssignmentProposalRequest{Overrides: tt.overrides(f)})
+			assert.ErrorIs(t, err, tt.wantErr)
+			after := countRows(t, f.db.Model(&models.InspectionAssignment{}))
+			assert.Equal(t, before, after, "no assignments are created")
+		})
+	}
+}
+
+func TestAssignmentSolverService_NextProposalSeesAcceptedLoad(t *testing.T) {
+	f := newAssignmentSolverTestFixture(t)
+	ctx := context.Background()
+	_, _, err := f.service.AcceptProposal(ctx, "org-a", "super-1", f.propose(t).ID, &models.AcceptAssignmentProposalRequest{})
+	require.NoError(t, err)
+
+	// insp-north's last slot that day is now taken
+	proposal, err := f.service.Propose(ctx, "org-a", "super-1", &models.AutoAssignRequest{Name: "Follow-up", TemplateID: f.template.ID.String(),
+		SiteIDs: []string{f.loop.ID}, ScheduledFor: &f.day})
+	require.NoError(t, err)
+	assert.Empty(t, proposalItemsBySite(t, proposal)[f.loop.ID].InspectorID)
+
+	discarded, err := f.service.DiscardProposal(ctx, "org-a", proposal.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.AssignmentProposalDiscarded, discarded.Status)
+}
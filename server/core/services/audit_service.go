@@ -1,48 +1,243 @@
 package services
 
 import (
+	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"reflect"
 	"resource-mgmt/config"
+	"resource-mgmt/pkg/database"
+	"resource-mgmt/pkg/tenant"
+	"resource-mgmt/pkg/utils"
+	"strconv"
+	"strings"
 	"time"
 
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // AuditAction represents the type of action performed
 type AuditAction string
 
 const (
-	UserCreated        AuditAction = "user_created"
-	UserUpdated        AuditAction = "user_updated"
-	UserDeleted        AuditAction = "user_deleted"
-	UserRoleChanged    AuditAction = "user_role_changed"
-	UserStatusChanged  AuditAction = "user_status_changed"
-	UserPasswordReset  AuditAction = "user_password_reset"
-	LoginAttempt       AuditAction = "login_attempt"
-	LoginSuccess       AuditAction = "login_success"
-	LoginFailure       AuditAction = "login_failure"
-	PermissionDenied   AuditAction = "permission_denied"
+	UserCreated       AuditAction = "user_created"
+	UserUpdated       AuditAction = "user_updated"
+	UserDeleted       AuditAction = "user_deleted"
+	UserRoleChanged   AuditAction = "user_role_changed"
+	UserStatusChanged AuditAction = "user_status_changed"
+	UserPasswordReset AuditAction = "user_password_reset"
+	LoginAttempt      AuditAction = "login_attempt"
+	LoginSuccess      AuditAction = "login_success"
+	LoginFailure      AuditAction = "login_failure"
+	PermissionDenied  AuditAction = "permission_denied"
+	AccountLocked     AuditAction = "account_locked"
+	AccountUnlocked   AuditAction = "account_unlocked"
+
+	SuspiciousLoginActivity AuditAction = "suspicious_login_activity"
+
+	InspectionCreated AuditAction = "inspection_created"
+	InspectionUpdated AuditAction = "inspection_updated"
+	InspectionDeleted AuditAction = "inspection_deleted"
+
+	TemplateCreated AuditAction = "template_created"
+	TemplateUpdated AuditAction = "template_updated"
+	TemplateDeleted AuditAction = "template_deleted"
+
+	SiteCreated     AuditAction = "site_created"
+	SiteUpdated     AuditAction = "site_updated"
+	SiteDeleted     AuditAction = "site_deleted"
+	SitesImported   AuditAction = "sites_imported"
+	SitesGeocoded   AuditAction = "sites_geocoded"
+	SitesMerged     AuditAction = "sites_merged"
+	SiteMergeUndone AuditAction = "site_merge_undone"
+
+	SiteCustomFieldCreated AuditAction = "site_custom_field_created"
+	SiteCustomFieldUpdated AuditAction = "site_custom_field_updated"
+	SiteCustomFieldDeleted AuditAction = "site_custom_field_deleted"
+
+	SiteDocumentUploaded   AuditAction = "site_document_uploaded"
+	SiteDocumentVersioned  AuditAction = "site_document_versioned"
+	SiteDocumentUpdated    AuditAction = "site_document_updated"
+	SiteDocumentDeleted    AuditAction = "site_document_deleted"
+	SiteDocumentDownloaded AuditAction = "site_document_downloaded"
+
+	LocationCreated AuditAction = "location_created"
+	LocationUpdated AuditAction = "location_updated"
+	LocationDeleted AuditAction = "location_deleted"
+	LocationMoved   AuditAction = "location_moved"
+
+	AssetCreated AuditAction = "asset_created"
+	AssetUpdated AuditAction = "asset_updated"
+	AssetRetired AuditAction = "asset_retired"
+	AssetDeleted AuditAction = "asset_deleted"
+
+	ScanTagsIssued AuditAction = "scan_tags_issued"
+	ScanTagBound   AuditAction = "scan_tag_bound"
+	ScanTagRevoked AuditAction = "scan_tag_revoked"
+
+	AssignmentCreated AuditAction = "assignment_created"
+	AssignmentUpdated AuditAction = "assignment_updated"
+	AssignmentDeleted AuditAction = "assignment_deleted"
+
+	AssignmentProposalAccepted  AuditAction = "assignment_proposal_accepted"
+	AssignmentProposalDiscarded AuditAction = "assignment_proposal_discarded"
+
+	DelegationRequested AuditAction = "delegation_requested"
+	DelegationApproved  AuditAction = "delegation_approved"
+	DelegationDeclined  AuditAction = "delegation_declined"
+	DelegationCancelled AuditAction = "delegation_cancelled"
+
+	WorkingHoursUpdated  AuditAction = "working_hours_updated"
+	TimeOffRequested     AuditAction = "time_off_requested"
+	TimeOffApproved      AuditAction = "time_off_approved"
+	TimeOffRejected      AuditAction = "time_off_rejected"
+	TimeOffCancelled     AuditAction = "time_off_cancelled"
+	HolidayCreated       AuditAction = "holiday_created"
+	HolidayDeleted       AuditAction = "holiday_deleted"
+	BusinessHoursUpdated AuditAction = "business_hours_updated"
+
+	CertificationTypeCreated      AuditAction = "certification_type_created"
+	CertificationTypeUpdated      AuditAction = "certification_type_updated"
+	CertificationTypeDeleted      AuditAction = "certification_type_deleted"
+	CertificationRecorded         AuditAction = "certification_recorded"
+	CertificationRevoked          AuditAction = "certification_revoked"
+	CertificationEvidenceAdded    AuditAction = "certification_evidence_added"
+	TemplateQualificationsUpdated AuditAction = "template_qualifications_updated"
+
+	WorkloadsReconciled AuditAction = "workloads_reconciled"
+
+	PoolItemsPublished AuditAction = "pool_items_published"
+	PoolItemClaimed    AuditAction = "pool_item_claimed"
+	PoolClaimConfirmed AuditAction = "pool_claim_confirmed"
+	PoolClaimRejected  AuditAction = "pool_claim_rejected"
+	PoolItemWithdrawn  AuditAction = "pool_item_withdrawn"
+
+	SLAPolicyCreated AuditAction = "sla_policy_created"
+	SLAPolicyUpdated AuditAction = "sla_policy_updated"
+	SLAPolicyDeleted AuditAction = "sla_policy_deleted"
+	SLAClocksPaused  AuditAction = "sla_clocks_paused"
+	SLAClocksResumed AuditAction = "sla_clocks_resumed"
+
+	TeamCreated        AuditAction = "team_created"
+	TeamUpdated        AuditAction = "team_updated"
+	TeamDeleted        AuditAction = "team_deleted"
+	TeamMembersUpdated AuditAction = "team_members_updated"
+	TeamWorkQueued     AuditAction = "team_work_queued"
+	TeamWorkTaken      AuditAction = "team_work_taken"
+
+	MilestoneCreated        AuditAction = "milestone_created"
+	MilestoneUpdated        AuditAction = "milestone_updated"
+	MilestoneDeleted        AuditAction = "milestone_deleted"
+	TaskDependenciesUpdated AuditAction = "task_dependencies_updated"
+
+	ReviewCreated AuditAction = "review_created"
+	ReviewUpdated AuditAction = "review_updated"
+	ReviewDeleted AuditAction = "review_deleted"
+
+	OrganizationSettingsUpdated AuditAction = "organization_settings_updated"
 )
 
-// AuditLog represents an audit trail entry
+// auditAppendAttempts bounds retries when a concurrent writer takes the next sequence number
+const auditAppendAttempts = 5
+
+// auditExportLimit caps the number of entries written to a single export
+const auditExportLimit = 50000
+
+// auditIgnoredFields are left out of change diffs; they change on every write
+var auditIgnoredFields = map[string]bool{
+	"updated_at": true,
+}
+
+// AuditLog represents an audit trail entry.
+//
+// Entries of an organization form a hash chain: every entry stores the hash of the
+// previous one, and its own hash covers that link plus all of its content. Hashes are
+// keyed with a server-held secret, so rewriting the chain needs more than database
+// access. Editing or deleting an entry breaks the chain from that point, and the
+// organization's AuditChainHead catches entries deleted from the end; VerifyChain
+// reports both.
 type AuditLog struct {
 	ID             string         `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
-	OrganizationID string         `json:"organization_id" gorm:"not null;index"`
+	OrganizationID string         `json:"organization_id" gorm:"not null;index;uniqueIndex:idx_audit_logs_org_sequence,priority:1"`
+	Sequence       int64          `json:"sequence" gorm:"not null;uniqueIndex:idx_audit_logs_org_sequence,priority:2"`
 	UserID         string         `json:"user_id" gorm:"not null;index"`
 	TargetUserID   *string        `json:"target_user_id" gorm:"index"` // For user management actions
 	Action         AuditAction    `json:"action" gorm:"not null;index"`
 	ResourceType   string         `json:"resource_type" gorm:"not null"` // user, inspection, etc.
 	ResourceID     *string        `json:"resource_id" gorm:"index"`
 	Details        datatypes.JSON `json:"details" gorm:"type:jsonb"`
+	Changes        datatypes.JSON `json:"changes" gorm:"type:jsonb"` // field -> {before, after}
 	IPAddress      string         `json:"ip_address" gorm:"size:45"`
 	UserAgent      string         `json:"user_agent" gorm:"size:500"`
-	Success        bool           `json:"success" gorm:"default:true"`
+	Success        bool           `json:"success" gorm:"not null"` // written explicitly; a column default would turn failures into successes
 	ErrorMessage   *string        `json:"error_message" gorm:"type:text"`
+	PreviousHash   string         `json:"previous_hash" gorm:"size:64"`
+	Hash           string         `json:"hash" gorm:"size:64;not null"`
 	CreatedAt      time.Time      `json:"created_at" gorm:"index"`
 }
 
+// TableName specifies the table name for AuditLog
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
+
+// AuditChainHead records the sequence and hash of an organization's newest audit entry,
+// outside audit_logs, so that deleting entries from the end of the chain is detectable.
+// The signature keys the row itself, so it can't be rolled back to an earlier entry.
+type AuditChainHead struct {
+	ID             string    `json:"id" gorm:"primarykey;type:uuid;default:gen_random_uuid()"`
+	OrganizationID string    `json:"organization_id" gorm:"not null;uniqueIndex"`
+	Sequence       int64     `json:"sequence" gorm:"not null"`
+	Hash           string    `json:"hash" gorm:"size:64;not null"`
+	Signature      string    `json:"signature" gorm:"size:64;not null"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for AuditChainHead
+func (AuditChainHead) TableName() string {
+	return "audit_chain_heads"
+}
+
+// AuditFieldChange holds the value of a field before and after a change
+type AuditFieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditChainVerification is the result of checking an organization's audit hash chain
+type AuditChainVerification struct {
+	OrganizationID string    `json:"organization_id"`
+	Valid          bool      `json:"valid"`
+	EntriesChecked int64     `json:"entries_checked"`
+	LastSequence   int64     `json:"last_sequence"`
+	LastHash       string    `json:"last_hash"`
+	BrokenAt       *int64    `json:"broken_at_sequence,omitempty"`
+	BrokenEntryID  string    `json:"broken_entry_id,omitempty"`
+	Reason         string    `json:"reason,omitempty"`
+	VerifiedAt     time.Time `json:"verified_at"`
+}
+
+type auditContextKey string
+
+const (
+	clientIPContextKey  auditContextKey = "client_ip"
+	userAgentContextKey auditContextKey = "user_agent"
+)
+
+// WithRequestMetadata attaches the caller's IP address and user agent to ctx so
+// audit entries written further down the call chain can record them
+func WithRequestMetadata(ctx context.Context, clientIP, userAgent string) context.Context {
+	ctx = context.WithValue(ctx, clientIPContextKey, clientIP)
+	return context.WithValue(ctx, userAgentContextKey, userAgent)
+}
+
 type AuditService struct {
 	db *gorm.DB
 }
@@ -53,60 +248,301 @@ func NewAuditService() *AuditService {
 	}
 }
 
+// ClientIPFromContext returns the caller IP recorded by WithRequestMetadata
+func ClientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPContextKey).(string)
+	return ip
+}
+
 // LogUserAction logs user management actions with comprehensive details
 func (s *AuditService) LogUserAction(ctx context.Context, action AuditAction, actorUserID string, targetUserID *string, details map[string]interface{}, success bool, errorMsg *string) error {
-	// Get organization ID from context
-	orgID, ok := ctx.Value("organization_id").(string)
-	if !ok {
-		orgID = "unknown"
-	}
-
-	// Get IP and User Agent from context if available
-	ipAddress, _ := ctx.Value("client_ip").(string)
-	userAgent, _ := ctx.Value("user_agent").(string)
-
 	detailsJSON, _ := json.Marshal(details)
 
 	auditLog := &AuditLog{
-		OrganizationID: orgID,
+		OrganizationID: auditOrganizationID(ctx),
 		UserID:         actorUserID,
 		TargetUserID:   targetUserID,
 		Action:         action,
 		ResourceType:   "user",
 		ResourceID:     targetUserID,
 		Details:        datatypes.JSON(detailsJSON),
-		IPAddress:      ipAddress,
-		UserAgent:      userAgent,
 		Success:        success,
 		ErrorMessage:   errorMsg,
-		CreatedAt:      time.Now(),
 	}
 
-	return s.db.Create(auditLog).Error
+	return s.appendEntry(ctx, auditLog)
 }
 
 // LogSecurityEvent logs security-related events
 func (s *AuditService) LogSecurityEvent(ctx context.Context, action AuditAction, userID string, details map[string]interface{}, success bool, errorMsg *string) error {
-	orgID, _ := ctx.Value("organization_id").(string)
-	ipAddress, _ := ctx.Value("client_ip").(string)
-	userAgent, _ := ctx.Value("user_agent").(string)
-
 	detailsJSON, _ := json.Marshal(details)
 
 	auditLog := &AuditLog{
-		OrganizationID: orgID,
+		OrganizationID: auditOrganizationID(ctx),
 		UserID:         userID,
 		Action:         action,
 		ResourceType:   "security",
 		Details:        datatypes.JSON(detailsJSON),
-		IPAddress:      ipAddress,
-		UserAgent:      userAgent,
 		Success:        success,
 		ErrorMessage:   errorMsg,
-		CreatedAt:      time.Now(),
 	}
 
-	return s.db.Create(auditLog).Error
+	return s.appendEntry(ctx, auditLog)
+}
+
+// LogEntityChange records a create, update or delete of a domain entity together with
+// the field-level differences between its before and after snapshots. Pass nil as
+// before for creations and nil as after for deletions.
+func (s *AuditService) LogEntityChange(ctx context.Context, organizationID, userID string, action AuditAction, resourceType, resourceID string, before, after interface{}) error {
+	changes, err := json.Marshal(diffAuditSnapshots(before, after))
+	if err != nil {
+		return fmt.Errorf("failed to encode audit changes: %v", err)
+	}
+
+	auditLog := &AuditLog{
+		OrganizationID: organizationID,
+		UserID:         userID,
+		Action:         action,
+		ResourceType:   resourceType,
+		Changes:        datatypes.JSON(changes),
+		Success:        true,
+	}
+	if resourceID != "" {
+		auditLog.ResourceID = &resourceID
+	}
+
+	return s.appendEntry(ctx, auditLog)
+}
+
+// appendEntry links the entry to the tail of its organization's chain and stores it.
+// When the request runs in a tenant transaction the entry is written in it, so the
+// audit record commits or rolls back together with the change it describes.
+func (s *AuditService) appendEntry(ctx context.Context, entry *AuditLog) error {
+	entry.IPAddress, _ = ctx.Value(clientIPContextKey).(string)
+	entry.UserAgent, _ = ctx.Value(userAgentContextKey).(string)
+	if len(entry.UserAgent) > 500 {
+		entry.UserAgent = entry.UserAgent[:500]
+	}
+	entry.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+
+	var err error
+	for attempt := 0; attempt < auditAppendAttempts; attempt++ {
+		err = database.Conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+			var last AuditLog
+			if err := tx.Select("sequence", "hash").
+				Where("organization_id = ?", entry.OrganizationID).
+				Order("sequence DESC").
+				Limit(1).
+				Find(&last).Error; err != nil {
+				return err
+			}
+
+			entry.Sequence = last.Sequence + 1
+			entry.PreviousHash = last.Hash
+			hash, err := entry.computeHash()
+			if err != nil {
+				return err
+			}
+			entry.Hash = hash
+
+			if err := tx.Create(entry).Error; err != nil {
+				return err
+			}
+			return advanceChainHead(tx, entry)
+		})
+		if err == nil || !isDuplicateKeyError(err) {
+			break
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write audit log: %v", err)
+	}
+	return nil
+}
+
+// advanceChainHead moves the organization's chain head to entry
+func advanceChainHead(tx *gorm.DB, entry *AuditLog) error {
+	signature, err := chainHeadSignature(entry.OrganizationID, entry.Sequence, entry.Hash)
+	if err != nil {
+		return err
+	}
+	head := AuditChainHead{
+		OrganizationID: entry.OrganizationID,
+		Sequence:       entry.Sequence,
+		Hash:           entry.Hash,
+		Signature:      signature,
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "organization_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"sequence", "hash", "signature", "updated_at"}),
+	}).Create(&head).Error
+}
+
+// auditChainKey returns the secret that keys audit hashes
+func auditChainKey() ([]byte, error) {
+	if config.AuditChainSecret != "" {
+		return []byte(config.AuditChainSecret), nil
+	}
+	secret, err := utils.GetJWTSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit chain secret: %w", err)
+	}
+	return secret, nil
+}
+
+// auditMAC returns the hex HMAC-SHA256 of the length-prefixed fields
+func auditMAC(fields ...string) (string, error) {
+	key, err := auditChainKey()
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	for _, field := range fields {
+		// Length prefixes keep field boundaries unambiguous
+		fmt.Fprintf(mac, "%d:%s;", len(field), field)
+	}
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// chainHeadSignature keys an AuditChainHead row. The "head" prefix keeps it from
+// colliding with an entry hash.
+func chainHeadSignature(organizationID string, sequence int64, hash string) (string, error) {
+	return auditMAC("head", organizationID, strconv.FormatInt(sequence, 10), hash)
+}
+
+// computeHash returns the keyed hash of the entry's chain link and content.
+// JSON columns are canonicalized so jsonb re-formatting doesn't change the hash.
+func (l *AuditLog) computeHash() (string, error) {
+	fields := []string{
+		l.PreviousHash,
+		strconv.FormatInt(l.Sequence, 10),
+		l.OrganizationID,
+		l.UserID,
+		stringValue(l.TargetUserID),
+		string(l.Action),
+		l.ResourceType,
+		stringValue(l.ResourceID),
+		canonicalJSON(l.Details),
+		canonicalJSON(l.Changes),
+		l.IPAddress,
+		l.UserAgent,
+		strconv.FormatBool(l.Success),
+		stringValue(l.ErrorMessage),
+		l.CreatedAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano),
+	}
+	return auditMAC(fields...)
+}
+
+// VerifyChain walks an organization's audit entries in sequence order and checks that
+// sequence numbers are contiguous, every entry links to its predecessor's hash, every
+// stored hash matches the entry's content and the chain ends at the recorded head.
+func (s *AuditService) VerifyChain(ctx context.Context, organizationID string) (*AuditChainVerification, error) {
+	result := &AuditChainVerification{
+		OrganizationID: organizationID,
+		Valid:          true,
+		VerifiedAt:     time.Now(),
+	}
+
+	// The head is read first and bounds the walk, so entries appended meanwhile don't
+	// count as a mismatch
+	var head AuditChainHead
+	if err := database.Conn(ctx, s.db).
+		Where("organization_id = ?", organizationID).
+		Limit(1).
+		Find(&head).Error; err != nil {
+		return nil, fmt.Errorf("failed to verify audit chain: %v", err)
+	}
+	if head.ID != "" {
+		signature, err := chainHeadSignature(head.OrganizationID, head.Sequence, head.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify audit chain: %v", err)
+		}
+		if !hmac.Equal([]byte(signature), []byte(head.Signature)) {
+			result.Valid = false
+			result.Reason = "chain head signature does not match"
+			return result, nil
+		}
+	}
+
+	for result.LastSequence < head.Sequence {
+		var batch []AuditLog
+		err := database.Conn(ctx, s.db).
+			Where("organization_id = ? AND sequence > ? AND sequence <= ?", organizationID, result.LastSequence, head.Sequence).
+			Order("sequence ASC").
+			Limit(500).
+			Find(&batch).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify audit chain: %v", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		for i := range batch {
+			entry := &batch[i]
+			hash, err := entry.computeHash()
+			if err != nil {
+				return nil, fmt.Errorf("failed to verify audit chain: %v", err)
+			}
+
+			reason := ""
+			switch {
+			case entry.Sequence != result.LastSequence+1:
+				reason = fmt.Sprintf("expected sequence %d, found %d", result.LastSequence+1, entry.Sequence)
+			case entry.PreviousHash != result.LastHash:
+				reason = "previous hash does not match the preceding entry"
+			case hash != entry.Hash:
+				reason = "entry content does not match its hash"
+			}
+			if reason != "" {
+				sequence := entry.Sequence
+				result.Valid = false
+				result.BrokenAt = &sequence
+				result.BrokenEntryID = entry.ID
+				result.Reason = reason
+				return result, nil
+			}
+
+			result.EntriesChecked++
+			result.LastSequence = entry.Sequence
+			result.LastHash = entry.Hash
+		}
+	}
+
+	if head.ID == "" {
+		// Without a head there must be no entries at all
+		var first AuditLog
+		if err := database.Conn(ctx, s.db).
+			Where("organization_id = ?", organizationID).
+			Order("sequence ASC").
+			Limit(1).
+			Find(&first).Error; err != nil {
+			return nil, fmt.Errorf("failed to verify audit chain: %v", err)
+		}
+		if first.ID != "" {
+			sequence := first.Sequence
+			result.Valid = false
+			result.BrokenAt = &sequence
+			result.BrokenEntryID = first.ID
+			result.Reason = "chain head is missing"
+		}
+		return result, nil
+	}
+
+	switch {
+	case result.LastSequence != head.Sequence:
+		sequence := result.LastSequence + 1
+		result.Valid = false
+		result.BrokenAt = &sequence
+		result.Reason = fmt.Sprintf("entries %d to %d are missing from the end of the chain", sequence, head.Sequence)
+	case result.LastHash != head.Hash:
+		sequence := result.LastSequence
+		result.Valid = false
+		result.BrokenAt = &sequence
+		result.Reason = "last entry does not match the chain head"
+	}
+
+	return result, nil
 }
 
 // GetAuditLogs retrieves audit logs with filtering
@@ -114,13 +550,29 @@ func (s *AuditService) GetAuditLogs(ctx context.Context, filters map[string]inte
 	var logs []AuditLog
 	var total int64
 
-	query := s.db.Model(&AuditLog{})
+	query := s.filteredQuery(ctx, filters)
 
-	// Apply organization filter from context
-	if orgID, ok := ctx.Value("organization_id").(string); ok {
-		query = query.Where("organization_id = ?", orgID)
+	// Count total
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
 	}
 
+	// Get records with pagination
+	err := query.Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&logs).Error
+
+	return logs, total, err
+}
+
+// filteredQuery builds the audit log query shared by listing and export
+func (s *AuditService) filteredQuery(ctx context.Context, filters map[string]interface{}) *gorm.DB {
+	query := database.Conn(ctx, s.db).Model(&AuditLog{})
+
+	// Apply organization filter from context
+	query = query.Where("organization_id = ?", auditOrganizationID(ctx))
+
 	// Apply filters
 	if userID, ok := filters["user_id"].(string); ok && userID != "" {
 		query = query.Where("user_id = ?", userID)
@@ -134,22 +586,20 @@ func (s *AuditService) GetAuditLogs(ctx context.Context, filters map[string]inte
 	if resourceType, ok := filters["resource_type"].(string); ok && resourceType != "" {
 		query = query.Where("resource_type = ?", resourceType)
 	}
+	if resourceID, ok := filters["resource_id"].(string); ok && resourceID != "" {
+		query = query.Where("resource_id = ?", resourceID)
+	}
 	if success, ok := filters["success"].(bool); ok {
 		query = query.Where("success = ?", success)
 	}
-
-	// Count total
-	if err := query.Count(&total).Error; err != nil {
-		return nil, 0, err
+	if from, ok := filters["from"].(time.Time); ok && !from.IsZero() {
+		query = query.Where("created_at >= ?", from)
+	}
+	if to, ok := filters["to"].(time.Time); ok && !to.IsZero() {
+		query = query.Where("created_at <= ?", to)
 	}
 
-	// Get records with pagination
-	err := query.Order("created_at DESC").
-		Limit(limit).
-		Offset(offset).
-		Find(&logs).Error
-
-	return logs, total, err
+	return query
 }
 
 // GetUserAuditHistory gets audit history for a specific user
@@ -174,4 +624,153 @@ func (s *AuditService) GetFailedActions(ctx context.Context, limit, offset int)
 		"success": false,
 	}
 	return s.GetAuditLogs(ctx, filters, limit, offset)
-}
\ No newline at end of file
+}
+
+// ExportAuditLogs renders the filtered audit trail in sequence order as csv or json.
+// Hashes are included so the export can be checked against the chain independently.
+func (s *AuditService) ExportAuditLogs(ctx context.Context, format string, filters map[string]interface{}) ([]byte, string, error) {
+	var logs []AuditLog
+	if err := s.filteredQuery(ctx, filters).Order("sequence ASC").Limit(auditExportLimit).Find(&logs).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to fetch audit logs: %v", err)
+	}
+
+	timestamp := time.Now().Format("20060102_150405")
+	switch format {
+	case "csv":
+		data, err := auditLogsToCSV(logs)
+		if err != nil {
+			return nil, "", err
+		}
+		return data, fmt.Sprintf("audit_trail_%s.csv", timestamp), nil
+	case "json":
+		data, err := json.MarshalIndent(logs, "", "  ")
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to encode audit logs: %v", err)
+		}
+		return data, fmt.Sprintf("audit_trail_%s.json", timestamp), nil
+	default:
+		return nil, "", fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+func auditLogsToCSV(logs []AuditLog) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+
+	header := []string{"Sequence", "ID", "Created At", "User ID", "Action", "Resource Type", "Resource ID", "Target User ID", "Success", "Error", "IP Address", "User Agent", "Details", "Changes", "Previous Hash", "Hash"}
+	if err := writer.Write(header); err != nil {
+		return nil, fmt.Errorf("failed to write audit export: %v", err)
+	}
+
+	for _, entry := range logs {
+		record := []string{
+			strconv.FormatInt(entry.Sequence, 10),
+			entry.ID,
+			entry.CreatedAt.UTC().Format(time.RFC3339Nano),
+			entry.UserID,
+			string(entry.Action),
+			entry.ResourceType,
+			stringValue(entry.ResourceID),
+			stringValue(entry.TargetUserID),
+			strconv.FormatBool(entry.Success),
+			stringValue(entry.ErrorMessage),
+			entry.IPAddress,
+			entry.UserAgent,
+			canonicalJSON(entry.Details),
+			canonicalJSON(entry.Changes),
+			entry.PreviousHash,
+			entry.Hash,
+		}
+		if err := writer.Write(record); err != nil {
+			return nil, fmt.Errorf("failed to write audit export: %v", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, fmt.Errorf("failed to write audit export: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// diffAuditSnapshots compares the JSON representation of two snapshots field by field
+func diffAuditSnapshots(before, after interface{}) map[string]AuditFieldChange {
+	beforeFields := auditSnapshot(before)
+	afterFields := auditSnapshot(after)
+
+	changes := make(map[string]AuditFieldChange)
+	for field, value := range beforeFields {
+		if auditIgnoredFields[field] {
+			continue
+		}
+		if newValue, ok := afterFields[field]; !ok || !reflect.DeepEqual(value, newValue) {
+			changes[field] = AuditFieldChange{Before: value, After: afterFields[field]}
+		}
+	}
+	for field, value := range afterFields {
+		if auditIgnoredFields[field] {
+			continue
+		}
+		if _, ok := beforeFields[field]; !ok {
+			changes[field] = AuditFieldChange{After: value}
+		}
+	}
+	return changes
+}
+
+// auditSnapshot flattens v into its top-level JSON fields
+func auditSnapshot(v interface{}) map[string]interface{} {
+	if v == nil {
+		return nil
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		var value interface{}
+		_ = json.Unmarshal(data, &value)
+		return map[string]interface{}{"value": value}
+	}
+	return fields
+}
+
+// canonicalJSON re-encodes raw JSON with sorted keys and no insignificant whitespace
+func canonicalJSON(raw datatypes.JSON) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return string(raw)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return string(raw)
+	}
+	return string(data)
+}
+
+// auditOrganizationID returns the organization of the request, or "" for events
+// outside any organization such as failed logins
+func auditOrganizationID(ctx context.Context) string {
+	orgID, _ := tenant.GetOrganizationID(ctx)
+	return orgID
+}
+
+func isDuplicateKeyError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "duplicate key") || strings.Contains(msg, "unique constraint")
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"resource-mgmt/models"
+	"resource-mgmt/pkg/tenant"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// auditTestContext is an org-a admin request from a known address
+func auditTestContext() context.Context {
+	ctx := tenant.WithTenantContext(context.Background(), tenant.NewContext("org-a", "admin-1", "admin"))
+	return WithRequestMetadata(ctx, "203.0.113.7", "audit-test")
+}
+
+// seedAuditChain records a site's create, update and delete for org-a and a create for
+// org-b, and returns org-a's entries in order
+func seedAuditChain(t *testing.T, db *gorm.DB, service *AuditService) []AuditLog {
+	ctx := auditTestContext()
+	before := &models.Site{ID: "site-1", OrganizationID: "org-a", Name: "Plant North", Status: "active"}
+	after := &models.Site{ID: "site-1", OrganizationID: "org-a", Name: "Plant North", Status: "inactive"}
+
+	require.NoError(t, service.LogEntityChange(ctx, "org-a", "admin-1", SiteCreated, "site", "site-1", nil, before))
+	require.NoError(t, service.LogEntityChange(ctx, "org-a", "admin-1", SiteUpdated, "site", "site-1", before, after))
+	require.NoError(t, service.LogEntityChange(ctx, "org-a", "admin-1", SiteDeleted, "site", "site-1", after, nil))
+	require.NoError(t, service.LogEntityChange(ctx, "org-b", "admin-2", SiteCreated, "site", "site-2", nil, before))
+
+	var entries []AuditLog
+	require.NoError(t, db.Where("organization_id = ?", "org-a").Order("sequence ASC").Find(&entries).Error)
+	require.Len(t, entries, 3)
+	return entries
+}
+
+func TestAuditService_EntriesChainPerOrganization(t *testing.T) {
+	db := setupAuditTestDB(t)
+	service := NewAuditService()
+	entries := seedAuditChain(t, db, service)
+
+	assert.Empty(t, entries[0].PreviousHash)
+	assert.Equal(t, entries[0].Hash, entries[1].PreviousHash)
+	assert.Equal(t, entries[1].Hash, entries[2].PreviousHash)
+	assert.Equal(t, "203.0.113.7", entries[1].IPAddress)
+
+	result, err := service.VerifyChain(auditTestContext(), "org-a")
+	require.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, int64(3), result.EntriesChecked)
+	assert.Equal(t, entries[2].Hash, result.LastHash)
+}
+
+func TestAuditService_RecordsChangedFieldsOnly(t *testing.T) {
+	db := setupAuditTestDB(t)
+	entries := seedAuditChain(t, db, NewAuditService())
+
+	var changes map[string]AuditFieldChange
+	require.NoError(t, json.Unmarshal(entries[1].Changes, &changes))
+	assert.Equal(t, AuditFieldChange{Before: "active", After: "inactive"}, changes["status"])
+	assert.NotContains(t, changes, "name")
+}
+
+func TestAuditService_VerifyChainDetectsTampering(t *testing.T) {
+	tests := []struct {
+		name         string
+		tamper       func(db *gorm.DB, entries []AuditLog) error
+		organization string
+		wantValid    bool
+		wantBrokenAt int64
+		wantReason   string
+	}{
+		{
+			name: "edited entry",
+			tamper: func(db *gorm.DB, entries []AuditLog) error {
+				return db.Model(&AuditLog{}).Where("id = ?", entries[1].ID).Update("user_id", "someone-else").Error
+			},
+			organization: "org-a",
+			wantBrokenAt: 2,
+		},
+		{
+			name: "deleted entry",
+			tamper: func(db *gorm.DB, entries []AuditLog) error {
+				return db.Where("id = ?", entries[1].ID).Delete(&AuditLog{}).Error
+			},
+			organization: "org-a",
+			wantReason:   "expected sequence 2",
+		},
+		{
+			name: "deleted last entry",
+			tamper: func(db *gorm.DB, entries []AuditLog) error {
+				return db.Where("id = ?", entries[2].ID).Delete(&AuditLog{}).Error
+			},
+			organization: "org-a",
+			wantBrokenAt: 3,
+			wantReason:   "missing from the end of the chain",
+		},
+		{
+			name: "head rolled back with the last entry",
+			tamper: func(db *gorm.DB, entries []AuditLog) error {
+				if err := db.Where("id = ?", entries[2].ID).Delete(&AuditLog{}).Error; err != nil {
+					return err
+				}
+				return db.Model(&AuditChainHead{}).Where("organization_id = ?", "org-a").
+					Updates(map[string]interface{}{"sequence": entries[1].Sequence, "hash": entries[1].Hash}).Error
+			},
+			organization: "org-a",
+			wantReason:   "chain head signature does not match",
+		},
+		{
+			name: "other organization's chain",
+			tamper: func(db *gorm.DB, entries []AuditLog) error {
+				return db.Where("id = ?", entries[1].ID).Delete(&AuditLog{}).Error
+			},
+			organization: "org-b",
+			wantValid:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupAuditTestDB(t)
+			service := NewAuditService()
+			entries := seedAuditChain(t, db, service)
+			require.NoError(t, tt.tamper(db, entries))
+
+			result, err := service.VerifyChain(auditTestContext(), tt.organization)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantValid, result.Valid)
+			if tt.wantBrokenAt != 0 {
+				require.NotNil(t, result.BrokenAt)
+				assert.Equal(t, tt.wantBrokenAt, *result.BrokenAt)
+			}
+			if tt.wantReason != "" {
+				assert.Contains(t, result.Reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+// seedAuditEvents records a template creation and a failed login for org-a
+func seedAuditEvents(t *testing.T, service *AuditService) context.Context {
+	ctx := tenant.WithTenantContext(context.Background(), tenant.NewContext("org-a", "admin-1", "admin"))
+	require.NoError(t, service.LogEntityChange(ctx, "org-a", "admin-1", TemplateCreated, "template", "tpl-1", nil, map[string]interface{}{"name": "Fire safety, v1"}))
+	require.NoError(t, service.LogSecurityEvent(ctx, LoginFailure, "user-9", map[string]interface{}{"reason": "bad password"}, false, nil))
+	return ctx
+}
+
+func TestAuditService_GetAuditLogsFilters(t *testing.T) {
+	setupAuditTestDB(t)
+	service := NewAuditService()
+	ctx := seedAuditEvents(t, service)
+
+	logs, total, err := service.GetAuditLogs(ctx, map[string]interface{}{"resource_type": "template"}, 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, TemplateCreated, logs[0].Action)
+
+	failed, _, err := service.GetFailedActions(ctx, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, failed, 1)
+	assert.False(t, failed[0].Success)
+}
+
+func TestAuditService_ExportAuditLogs(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		wantErr bool
+		check   func(t *testing.T, data []byte)
+	}{
+		{
+			name:   "csv",
+			format: "csv",
+			check: func(t *testing.T, data []byte) {
+				records, err := csv.NewReader(strings.NewReader(string(data))).ReadAll()
+				require.NoError(t, err)
+				require.Len(t, records, 3)
+				assert.Equal(t, "1", records[1][0])
+				assert.Contains(t, records[1][13], "Fire safety, v1")
+			},
+		},
+		{
+			name:   "json",
+			format: "json",
+			check: func(t *testing.T, data []byte) {
+				var logs []AuditLog
+				require.NoError(t, json.Unmarshal(data, &logs))
+				assert.Len(t, logs, 2)
+			},
+		},
+		{name: "unsupported format", format: "xml", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupAuditTestDB(t)
+			service := NewAuditService()
+			ctx := seedAuditEvents(t, service)
+
+			data, filename, err := service.ExportAuditLogs(ctx, tt.format, map[string]interface{}{})
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, strings.HasSuffix(filename, "."+tt.format))
+			tt.check(t, data)
+		})
+	}
+}
@@ -0,0 +1,759 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"resource-mgmt/models"
+	"resource-mgmt/pkg/database"
+	"resource-mgmt/utils"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInspectorUnavailable is returned when work is scheduled on a day the inspector
+	// doesn't work: time off, a holiday, outside their working week or availability window
+	ErrInspectorUnavailable = errors.New("inspector is unavailable")
+	// ErrInvalidAvailability is returned for malformed working hours, dates and holidays
+	ErrInvalidAvailability = errors.New("invalid availability")
+	// ErrTimeOffNotFound is returned when a time-off request doesn't exist in the organization
+	ErrTimeOffNotFound = errors.New("time off request not found")
+	// ErrTimeOffConflict is returned for requests overlapping other pending or approved
+	// time off, and for reviewing or cancelling a request that is already closed
+	ErrTimeOffConflict = errors.New("time off conflict")
+	// ErrTimeOffForbidden is returned when a user acts on someone else's time off without
+	// being a supervisor, or reviews their own request
+	ErrTimeOffForbidden = errors.New("not allowed to manage this time off")
+	// ErrHolidayNotFound is returned when a holiday doesn't exist in the organization
+	ErrHolidayNotFound = errors.New("holiday not found")
+)
+
+const (
+	// maxAvailabilityRangeDays bounds calendar queries and single time-off requests
+	maxAvailabilityRangeDays = 366
+	// civilDateLayout is how calendar days are written in requests and responses
+	civilDateLayout = "2006-01-02"
+)
+
+// timeOffTypes are the accepted kinds of time off
+var timeOffTypes = []string{models.TimeOffVacation, models.TimeOffSick, models.TimeOffTraining, models.TimeOffOther}
+
+// clockPattern matches HH:MM on a 24 hour clock
+var clockPattern = regexp.MustCompile(`^([01][0-9]|2[0-3]):[0-5][0-9]$`)
+
+type AvailabilityService struct {
+	db                  *gorm.DB
+	notificationService *NotificationService
+}
+
+func NewAvailabilityService(db *gorm.DB, notificationService *NotificationService) *AvailabilityService {
+	return &AvailabilityService{db: db, notificationService: notificationService}
+}
+
+// =====================================================
+// WORKING HOURS
+// =====================================================
+
+// GetWorkingHours returns the inspector's weekly pattern, Sunday first
+func (s *AvailabilityService) GetWorkingHours(ctx context.Context, organizationID, inspectorID string) ([]models.InspectorWorkingHours, error) {
+	var hours []models.InspectorWorkingHours
+	if err := database.Conn(ctx, s.db).
+		Where("organization_id = ? AND inspector_id = ?", organizationID, inspectorID).
+		Order("weekday ASC").
+		Find(&hours).Error; err != nil {
+		return nil, fmt.Errorf("failed to get working hours: %v", err)
+	}
+	return hours, nil
+}
+
+// SetWorkingHours replaces the inspector's weekly pattern. Weekdays left out are days off;
+// an empty list removes the pattern, so every day counts as a working day again.
+func (s *AvailabilityService) SetWorkingHours(ctx context.Context, organizationID, inspectorID string, entries []models.WorkingHoursEntry) ([]models.InspectorWorkingHours, error) {
+	if err := s.requireInspector(ctx, organizationID, inspectorID); err != nil {
+		return nil, err
+	}
+
+	if err := validateWeeklyHours(entries); err != nil {
+		return nil, err
+	}
+
+	hours := make([]models.InspectorWorkingHours, 0, len(entries))
+	for _, entry := range entries {
+		hours = append(hours, models.InspectorWorkingHours{
+			OrganizationID: organizationID,
+			InspectorID:    inspectorID,
+			Weekday:        entry.Weekday,
+			StartTime:      entry.StartTime,
+			EndTime:        entry.EndTime,
+		})
+	}
+	sort.Slice(hours, func(i, j int) bool { return hours[i].Weekday < hours[j].Weekday })
+
+	err := database.Conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("organization_id = ? AND inspector_id = ?", organizationID, inspectorID).
+			Delete(&models.InspectorWorkingHours{}).Error; err != nil {
+			return err
+		}
+		if len(hours) == 0 {
+			return nil
+		}
+		return tx.Create(&hours).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save working hours: %v", err)
+	}
+	return hours, nil
+}
+
+// validateWeeklyHours checks a weekly pattern lists each weekday at most once, with HH:MM
+// hours that start before they end
+func validateWeeklyHours(entries []models.WorkingHoursEntry) error {
+	seen := make(map[int]bool)
+	for _, entry := range entries {
+		if entry.Weekday < 0 || entry.Weekday > 6 {
+			return fmt.Errorf("%w: weekday must be between 0 (Sunday) and 6 (Saturday)", ErrInvalidAvailability)
+		}
+		if seen[entry.Weekday] {
+			return fmt.Errorf("%w: %s is listed twice", ErrInvalidAvailability, time.Weekday(entry.Weekday))
+		}
+		seen[entry.Weekday] = true
+		if !clockPattern.MatchString(entry.StartTime) || !clockPattern.MatchString(entry.EndTime) {
+			return fmt.Errorf("%w: times must be HH:MM", ErrInvalidAvailability)
+		}
+		if entry.StartTime >= entry.EndTime {
+			return fmt.Errorf("%w: %s starts after it ends", ErrInvalidAvailability, time.Weekday(entry.Weekday))
+		}
+	}
+	return nil
+}
+
+// =====================================================
+// BUSINESS CALENDAR
+// =====================================================
+
+// GetBusinessHours returns the organization's weekly business hours, Sunday first
+func (s *AvailabilityService) GetBusinessHours(ctx context.Context, organizationID string) ([]models.OrganizationBusinessHours, error) {
+	var hours []models.OrganizationBusinessHours
+	if err := database.Conn(ctx, s.db).
+		Where("organization_id = ?", organizationID).
+		Order("weekday ASC").
+		Find(&hours).Error; err != nil {
+		return nil, fmt.Errorf("failed to get business hours: %v", err)
+	}
+	return hours, nil
+}
+
+// SetBusinessHours replaces the organization's weekly business hours. Weekdays left out are
+// closed; an empty list makes every day but holidays count in full again. Running SLA clocks
+// keep their deadlines.
+func (s *AvailabilityService) SetBusinessHours(ctx context.Context, organizationID string, entries []models.WorkingHoursEntry) ([]models.OrganizationBusinessHours, error) {
+	if err := validateWeeklyHours(entries); err != nil {
+		return nil, err
+	}
+
+	hours := make([]models.OrganizationBusinessHours, 0, len(entries))
+	for _, entry := range entries {
+		hours = append(hours, models.OrganizationBusinessHours{
+			OrganizationID: organizationID,
+			Weekday:        entry.Weekday,
+			StartTime:      entry.StartTime,
+			EndTime:        entry.EndTime,
+		})
+	}
+	sort.Slice(hours, func(i, j int) bool { return hours[i].Weekday < hours[j].Weekday })
+
+	err := database.Conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("organization_id = ?", organizationID).Delete(&models.OrganizationBusinessHours{}).Error; err != nil {
+			return err
+		}
+		if len(hours) == 0 {
+			return nil
+		}
+		return tx.Create(&hours).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save business hours: %v", err)
+	}
+	return hours, nil
+}
+
+// DueDate returns when work of the given duration, started at start, is due: the duration
+// counts business hours only, skipping closed weekdays and holidays, in the site's time zone
+// or the organization's when siteID is empty
+func (s *AvailabilityService) DueDate(ctx context.Context, organizationID, siteID string, start time.Time, duration time.Duration) (*models.DueDateResult, error) {
+	if duration < 0 {
+		return nil, fmt.Errorf("%w: duration can't be negative", ErrInvalidAvailability)
+	}
+	calendar, err := loadBusinessCalendar(ctx, s.db, organizationID, siteID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.DueDateResult{
+		Start:    start.In(calendar.location),
+		Hours:    duration.Hours(),
+		DueDate:  calendar.AddWorkingTime(start, duration).In(calendar.location),
+		Timezone: calendar.location.String(),
+	}, nil
+}
+
+// =====================================================
+// TIME OFF
+// =====================================================
+
+// GetTimeOff lists time-off requests, latest start first. Supported filters: inspector_id,
+// status, from and to (YYYY-MM-DD, requests overlapping the range).
+func (s *AvailabilityService) GetTimeOff(ctx context.Context, organizationID string, filters map[string]interface{}) ([]models.InspectorTimeOff, error) {
+	query := database.Conn(ctx, s.db).Where("organization_id = ?", organizationID)
+	if inspectorID, ok := filters["inspector_id"].(string); ok && inspectorID != "" {
+		query = query.Where("inspector_id = ?", inspectorID)
+	}
+	if status, ok := filters["status"].(string); ok && status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if from, ok := filters["from"].(string); ok && from != "" {
+		day, err := parseCivilDate(from)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("end_date >= ?", day)
+	}
+	if to, ok := filters["to"].(string); ok && to != "" {
+		day, err := parseCivilDate(to)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("start_date <= ?", day)
+	}
+
+	var timeOff []models.InspectorTimeOff
+	if err := query.Preload("Inspector").Order("start_date DESC").Limit(500).Find(&timeOff).Error; err != nil {
+		return nil, fmt.Errorf("failed to get time off: %v", err)
+	}
+	return timeOff, nil
+}
+
+// RequestTimeOff files a pending request. Inspectors request time off for themselves;
+// supervisors and admins may file on anyone's behalf. Supervisors are notified.
+func (s *AvailabilityService) RequestTimeOff(ctx context.Context, organizationID, userID, userRole string, req *models.TimeOffRequest) (*models.InspectorTimeOff, error) {
+	inspectorID := req.InspectorID
+	if inspectorID == "" {
+		inspectorID = userID
+	}
+	if inspectorID != userID && !isSupervisorRole(userRole) {
+		return nil, ErrTimeOffForbidden
+	}
+	if err := s.requireInspector(ctx, organizationID, inspectorID); err != nil {
+		return nil, err
+	}
+
+	start, err := parseCivilDate(req.StartDate)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseCivilDate(req.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("%w: end_date is before start_date", ErrInvalidAvailability)
+	}
+	if end.Sub(start) >= maxAvailabilityRangeDays*24*time.Hour {
+		return nil, fmt.Errorf("%w: time off can span at most %d days", ErrInvalidAvailability, maxAvailabilityRangeDays)
+	}
+	timeOffType := strings.ToLower(strings.TrimSpace(req.Type))
+	if timeOffType == "" {
+		timeOffType = models.TimeOffVacation
+	}
+	if !containsString(timeOffTypes, timeOffType) {
+		return nil, fmt.Errorf("%w: type must be one of %s", ErrInvalidAvailability, strings.Join(timeOffTypes, ", "))
+	}
+
+	var overlapping int64
+	if err := database.Conn(ctx, s.db).Model(&models.InspectorTimeOff{}).
+		Where("organization_id = ? AND inspector_id = ? AND status IN ?", organizationID, inspectorID, []string{models.TimeOffPending, models.TimeOffApproved}).
+		Where("start_date <= ? AND end_date >= ?", end, start).
+		Count(&overlapping).Error; err != nil {
+		return nil, fmt.Errorf("failed to check time off: %v", err)
+	}
+	if overlapping > 0 {
+		return nil, fmt.Errorf("%w: overlaps time off that is already requested or approved", ErrTimeOffConflict)
+	}
+
+	timeOff := &models.InspectorTimeOff{
+		OrganizationID: organizationID,
+		InspectorID:    inspectorID,
+		StartDate:      start,
+		EndDate:        end,
+		Type:           timeOffType,
+		Status:         models.TimeOffPending,
+		Reason:         strings.TrimSpace(req.Reason),
+		RequestedBy:    userID,
+	}
+	if err := database.Conn(ctx, s.db).Create(timeOff).Error; err != nil {
+		return nil, fmt.Errorf("failed to save time off request: %v", err)
+	}
+
+	var supervisors []string
+	if err := database.Conn(ctx, s.db).Model(&models.OrganizationMember{}).
+		Where("organization_id = ? AND role IN ? AND status = ? AND user_id <> ?", organizationID, siteManagerRoles, "active", userID).
+		Pluck("user_id", &supervisors).Error; err != nil {
+		return nil, fmt.Errorf("failed to get supervisors: %v", err)
+	}
+	for _, supervisorID := range supervisors {
+		s.notify(organizationID, supervisorID, "Time Off Requested",
+			fmt.Sprintf("%s time off requested from %s to %s", timeOffType, timeOff.StartDate.Format(civilDateLayout), timeOff.EndDate.Format(civilDateLayout)))
+	}
+	return timeOff, nil
+}
+
+// ReviewTimeOff approves or rejects a pending request. Nobody reviews their own time off.
+// On approval it also returns the inspector's open inspections scheduled in the period,
+// which need reassigning.
+func (s *AvailabilityService) ReviewTimeOff(ctx context.Context, organizationID, timeOffID, reviewerID string, approve bool, note string) (*models.InspectorTimeOff, []models.Inspection, error) {
+	timeOff, err := s.getTimeOff(ctx, organizationID, timeOffID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if timeOff.InspectorID == reviewerID {
+		return nil, nil, ErrTimeOffForbidden
+	}
+
+	status := models.TimeOffRejected
+	if approve {
+		status = models.TimeOffApproved
+	}
+	now := time.Now()
+	var conflicts []models.Inspection
+	err = database.Conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.InspectorTimeOff{}).
+			Where("id = ? AND status = ?", timeOff.ID, models.TimeOffPending).
+			Updates(map[string]interface{}{"status": status, "reviewed_by": reviewerID, "reviewed_at": now, "review_note": strings.TrimSpace(note)})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: the request is no longer pending", ErrTimeOffConflict)
+		}
+		if !approve {
+			return nil
+		}
+		if err := syncScheduledTimeOff(tx, organizationID, timeOff.InspectorID); err != nil {
+			return err
+		}
+		return tx.Where("organization_id = ? AND inspector_id = ? AND status NOT IN ?", organizationID, timeOff.InspectorID, closedInspectionStatuses).
+			Where("scheduled_for >= ? AND scheduled_for < ?", timeOff.StartDate, timeOff.EndDate.AddDate(0, 0, 1)).
+			Order("scheduled_for ASC").
+			Find(&conflicts).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrTimeOffConflict) {
+			return nil, nil, err
+		}
+		return nil, nil, fmt.Errorf("failed to review time off: %v", err)
+	}
+
+	timeOff.Status = status
+	timeOff.ReviewedBy = &reviewerID
+	timeOff.ReviewedAt = &now
+	timeOff.ReviewNote = strings.TrimSpace(note)
+	s.notify(organizationID, timeOff.InspectorID, "Time Off "+strings.ToUpper(status[:1])+status[1:],
+		fmt.Sprintf("Your time off from %s to %s was %s", timeOff.StartDate.Format(civilDateLayout), timeOff.EndDate.Format(civilDateLayout), status))
+	return timeOff, conflicts, nil
+}
+
+// CancelTimeOff withdraws a pending or approved request. The inspector, whoever filed it
+// and supervisors may cancel.
+func (s *AvailabilityService) CancelTimeOff(ctx context.Context, organizationID, timeOffID, userID, userRole string) (*models.InspectorTimeOff, error) {
+	timeOff, err := s.getTimeOff(ctx, organizationID, timeOffID)
+	if err != nil {
+		return nil, err
+	}
+	if timeOff.InspectorID != userID && timeOff.RequestedBy != userID && !isSupervisorRole(userRole) {
+		return nil, ErrTimeOffForbidden
+	}
+
+	err = database.Conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.InspectorTimeOff{}).
+			Where("id = ? AND status IN ?", timeOff.ID, []string{models.TimeOffPending, models.TimeOffApproved}).
+			Update("status", models.TimeOffCancelled)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: the request was already rejected or cancelled", ErrTimeOffConflict)
+		}
+		if timeOff.Status == models.TimeOffApproved {
+			return syncScheduledTimeOff(tx, organizationID, timeOff.InspectorID)
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrTimeOffConflict) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to cancel time off: %v", err)
+	}
+
+	timeOff.Status = models.TimeOffCancelled
+	return timeOff, nil
+}
+
+func (s *AvailabilityService) getTimeOff(ctx context.Context, organizationID, timeOffID string) (*models.InspectorTimeOff, error) {
+	var timeOff models.InspectorTimeOff
+	if err := database.Conn(ctx, s.db).Where("id = ? AND organization_id = ?", timeOffID, organizationID).First(&timeOff).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTimeOffNotFound
+		}
+		return nil, fmt.Errorf("failed to get time off: %v", err)
+	}
+	return &timeOff, nil
+}
+
+// syncScheduledTimeOff rewrites the workload's ScheduledTimeOff from the approved requests,
+// so the workload keeps showing the inspector's upcoming time off
+func syncScheduledTimeOff(tx *gorm.DB, organizationID, inspectorID string) error {
+	var approved []models.InspectorTimeOff
+	if err := tx.Where("organization_id = ? AND inspector_id = ? AND status = ? AND end_date >= ?",
+		organizationID, inspectorID, models.TimeOffApproved, civilDate(time.Now())).
+		Order("start_date ASC").
+		Find(&approved).Error; err != nil {
+		return err
+	}
+
+	periods := make([]map[string]string, 0, len(approved))
+	for _, timeOff := range approved {
+		periods = append(periods, map[string]string{
+			"id":    timeOff.ID,
+			"start": timeOff.StartDate.Format(civilDateLayout),
+			"end":   timeOff.EndDate.Format(civilDateLayout),
+			"type":  timeOff.Type,
+		})
+	}
+	periodsJSON, _ := json.Marshal(periods)
+	return tx.Model(&models.InspectorWorkload{}).
+		Where("organization_id = ? AND inspector_id = ?", organizationID, inspectorID).
+		Update("scheduled_time_off", datatypes.JSON(periodsJSON)).Error
+}
+
+// =====================================================
+// HOLIDAYS
+// =====================================================
+
+// GetHolidays lists the organization's holidays by date. With a year, only the holidays
+// falling in that year are returned, recurring ones moved to it.
+func (s *AvailabilityService) GetHolidays(ctx context.Context, organizationID string, year int) ([]models.OrganizationHoliday, error) {
+	holidays, err := loadOrganizationHolidays(ctx, s.db, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	if year == 0 {
+		return holidays, nil
+	}
+
+	inYear := make([]models.OrganizationHoliday, 0, len(holidays))
+	for _, holiday := range holidays {
+		if holiday.Recurring {
+			holiday.Date = time.Date(year, holiday.Date.Month(), holiday.Date.Day(), 0, 0, 0, 0, time.UTC)
+		}
+		if holiday.Date.Year() == year {
+			inYear = append(inYear, holiday)
+		}
+	}
+	sort.SliceStable(inYear, func(i, j int) bool { return inYear[i].Date.Before(inYear[j].Date) })
+	return inYear, nil
+}
+
+// CreateHoliday adds a day to the organization's holiday calendar
+func (s *AvailabilityService) CreateHoliday(ctx context.Context, organizationID, userID string, req *models.CreateHolidayRequest) (*models.OrganizationHoliday, error) {
+	date, err := parseCivilDate(req.Date)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: holiday name is required", ErrInvalidAvailability)
+	}
+
+	holiday := &models.OrganizationHoliday{
+		OrganizationID: organizationID,
+		Date:           date,
+		Name:           name,
+		Recurring:      req.Recurring,
+		CreatedBy:      userID,
+	}
+	if err := database.Conn(ctx, s.db).Create(holiday).Error; err != nil {
+		return nil, fmt.Errorf("failed to save holiday: %v", err)
+	}
+	return holiday, nil
+}
+
+// DeleteHoliday removes a day from the organization's holiday calendar
+func (s *AvailabilityService) DeleteHoliday(ctx context.Context, organizationID, holidayID string) (*models.OrganizationHoliday, error) {
+	var holiday models.OrganizationHoliday
+	db := database.Conn(ctx, s.db)
+	if err := db.Where("id = ? AND organization_id = ?", holidayID, organizationID).First(&holiday).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrHolidayNotFound
+		}
+		return nil, fmt.Errorf("failed to get holiday: %v", err)
+	}
+	if err := db.Delete(&holiday).Error; err != nil {
+		return nil, fmt.Errorf("failed to delete holiday: %v", err)
+	}
+	return &holiday, nil
+}
+
+func loadOrganizationHolidays(ctx context.Context, db *gorm.DB, organizationID string) ([]models.OrganizationHoliday, error) {
+	var holidays []models.OrganizationHoliday
+	if err := database.Conn(ctx, db).Where("organization_id = ?", organizationID).Order("date ASC").Find(&holidays).Error; err != nil {
+		return nil, fmt.Errorf("failed to get holidays: %v", err)
+	}
+	return holidays, nil
+}
+
+// holidayOn returns the holiday falling on the day, if any
+func holidayOn(holidays []models.OrganizationHoliday, day time.Time) (models.OrganizationHoliday, bool) {
+	for _, holiday := range holidays {
+		if holiday.Date.Month() != day.Month() || holiday.Date.Day() != day.Day() {
+			continue
+		}
+		if holiday.Recurring || holiday.Date.Year() == day.Year() {
+			return holiday, true
+		}
+	}
+	return models.OrganizationHoliday{}, false
+}
+
+// skipHolidays moves t forward, keeping its time of day, until it no longer falls on a
+// holiday
+func skipHolidays(holidays []models.OrganizationHoliday, t time.Time) time.Time {
+	for i := 0; i < maxAvailabilityRangeDays; i++ {
+		if _, ok := holidayOn(holidays, t); !ok {
+			return t
+		}
+		t = t.AddDate(0, 0, 1)
+	}
+	return t
+}
+
+// =====================================================
+// AVAILABILITY
+// =====================================================
+
+// GetCalendar returns the inspector's availability for each day from from to to inclusive
+func (s *AvailabilityService) GetCalendar(ctx context.Context, organizationID, inspectorID string, from, to time.Time) ([]models.AvailabilityDay, error) {
+	from, to = civilDate(from), civilDate(to)
+	if to.Before(from) {
+		return nil, fmt.Errorf("%w: to is before from", ErrInvalidAvailability)
+	}
+	if to.Sub(from) >= maxAvailabilityRangeDays*24*time.Hour {
+		return nil, fmt.Errorf("%w: the calendar covers at most %d days", ErrInvalidAvailability, maxAvailabilityRangeDays)
+	}
+	if err := s.requireInspector(ctx, organizationID, inspectorID); err != nil {
+		return nil, err
+	}
+
+	calendar, err := loadAvailabilityCalendar(ctx, s.db, organizationID, []string{inspectorID}, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var days []models.AvailabilityDay
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		entry := models.AvailabilityDay{Date: day.Format(civilDateLayout)}
+		if reason := calendar.unavailableReason(inspectorID, day); reason != "" {
+			entry.Reason = reason
+		} else {
+			entry.Available = true
+			if hours, ok := calendar.hours[inspectorID][int(day.Weekday())]; ok {
+				entry.StartTime, entry.EndTime = hours.StartTime, hours.EndTime
+			}
+		}
+		days = append(days, entry)
+	}
+	return days, nil
+}
+
+// EnsureAvailable returns ErrInspectorUnavailable, with the reason, when the inspector
+// doesn't work on the day
+func (s *AvailabilityService) EnsureAvailable(ctx context.Context, organizationID, inspectorID string, day time.Time) error {
+	day = civilDate(day)
+	calendar, err := loadAvailabilityCalendar(ctx, s.db, organizationID, []string{inspectorID}, day, day)
+	if err != nil {
+		return err
+	}
+	if reason := calendar.unavailableReason(inspectorID, day); reason != "" {
+		return fmt.Errorf("%w: %s on %s: %s", ErrInspectorUnavailable, inspectorID, day.Format(civilDateLayout), reason)
+	}
+	return nil
+}
+
+// LatestAvailableDay finds the last day on or before day, and not before notBefore, that
+// the inspector works. Due dates are pulled back to it so they never land on a day off.
+func (s *AvailabilityService) LatestAvailableDay(ctx context.Context, organizationID, inspectorID string, day, notBefore time.Time) (time.Time, bool, error) {
+	day, notBefore = civilDate(day), civilDate(notBefore)
+	if day.Before(notBefore) {
+		return time.Time{}, false, nil
+	}
+	if day.Sub(notBefore) >= maxAvailabilityRangeDays*24*time.Hour {
+		notBefore = day.AddDate(0, 0, -maxAvailabilityRangeDays+1)
+	}
+
+	calendar, err := loadAvailabilityCalendar(ctx, s.db, organizationID, []string{inspectorID}, notBefore, day)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	for d := day; !d.Before(notBefore); d = d.AddDate(0, 0, -1) {
+		if calendar.unavailableReason(inspectorID, d) == "" {
+			return d, true, nil
+		}
+	}
+	return time.Time{}, false, nil
+}
+
+// requireInspector checks the user is an active member with inspector privileges
+func (s *AvailabilityService) requireInspector(ctx context.Context, organizationID, inspectorID string) error {
+	var member models.OrganizationMember
+	err := database.Conn(ctx, s.db).
+		Where("user_id = ? AND organization_id = ? AND status = ?", inspectorID, organizationID, "active").
+		First(&member).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: %s is not an active member of the organization", ErrInvalidAvailability, inspectorID)
+		}
+		return fmt.Errorf("failed to get member: %v", err)
+	}
+	if !utils.HasHigherOrEqualPrivilege(member.Role, "inspector") {
+		return fmt.Errorf("%w: %s does not have inspector privileges", ErrInvalidAvailability, inspectorID)
+	}
+	return nil
+}
+
+func (s *AvailabilityService) notify(organizationID, userID, title, message string) {
+	if s.notificationService == nil {
+		return
+	}
+	s.notificationService.CreateNotification(&models.CreateNotificationRequest{
+		OrganizationID: organizationID,
+		UserID:         userID,
+		Title:          title,
+		Message:        message,
+		Type:           "availability",
+	})
+}
+
+// availabilityCalendar holds what decides whether inspectors work on the days of a range
+type availabilityCalendar struct {
+	holidays  []models.OrganizationHoliday
+	workloads map[string]models.InspectorWorkload
+	hours     map[string]map[int]models.InspectorWorkingHours
+	timeOff   map[string][]models.InspectorTimeOff
+}
+
+// loadAvailabilityCalendar loads the holidays, workloads, working hours and approved time
+// off of the inspectors for the days from from to to
+func loadAvailabilityCalendar(ctx context.Context, db *gorm.DB, organizationID string, inspectorIDs []string, from, to time.Time) (*availabilityCalendar, error) {
+	from, to = civilDate(from), civilDate(to)
+	calendar := &availabilityCalendar{
+		workloads: make(map[string]models.InspectorWorkload),
+		hours:     make(map[string]map[int]models.InspectorWorkingHours),
+		timeOff:   make(map[string][]models.InspectorTimeOff),
+	}
+
+	holidays, err := loadOrganizationHolidays(ctx, db, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	calendar.holidays = holidays
+	if len(inspectorIDs) == 0 {
+		return calendar, nil
+	}
+
+	conn := database.Conn(ctx, db)
+	var workloads []models.InspectorWorkload
+	if err := conn.Where("organization_id = ? AND inspector_id IN ?", organizationID, inspectorIDs).Find(&workloads).Error; err != nil {
+		return nil, fmt.Errorf("failed to get inspector workloads: %v", err)
+	}
+	for _, workload := range workloads {
+		calendar.workloads[workload.InspectorID] = workload
+	}
+
+	var hours []models.InspectorWorkingHours
+	if err := conn.Where("organization_id = ? AND inspector_id IN ?", organizationID, inspectorIDs).Find(&hours).Error; err != nil {
+		return nil, fmt.Errorf("failed to get working hours: %v", err)
+	}
+	for _, h := range hours {
+		if calendar.hours[h.InspectorID] == nil {
+			calendar.hours[h.InspectorID] = make(map[int]models.InspectorWorkingHours)
+		}
+		calendar.hours[h.InspectorID][h.Weekday] = h
+	}
+
+	var timeOff []models.InspectorTimeOff
+	if err := conn.Where("organization_id = ? AND inspector_id IN ? AND status = ?", organizationID, inspectorIDs, models.TimeOffApproved).
+		Where("start_date <= ? AND end_date >= ?", to, from).
+		Find(&timeOff).Error; err != nil {
+		return nil, fmt.Errorf("failed to get time off: %v", err)
+	}
+	for _, t := range timeOff {
+		calendar.timeOff[t.InspectorID] = append(calendar.timeOff[t.InspectorID], t)
+	}
+	return calendar, nil
+}
+
+// unavailableReason says why the inspector doesn't work on the day, or "" when they do
+func (c *availabilityCalendar) unavailableReason(inspectorID string, day time.Time) string {
+	day = civilDate(day)
+	if workload, ok := c.workloads[inspectorID]; ok {
+		if !workload.IsAvailable {
+			return "marked as unavailable"
+		}
+		if workload.AvailableFrom != nil && day.Before(civilDate(*workload.AvailableFrom)) {
+			return "not available until " + workload.AvailableFrom.Format(civilDateLayout)
+		}
+		if workload.AvailableUntil != nil && day.After(civilDate(*workload.AvailableUntil)) {
+			return "not available after " + workload.AvailableUntil.Format(civilDateLayout)
+		}
+	}
+	if holiday, ok := holidayOn(c.holidays, day); ok {
+		return "organization holiday: " + holiday.Name
+	}
+	for _, t := range c.timeOff[inspectorID] {
+		if !day.Before(civilDate(t.StartDate)) && !day.After(civilDate(t.EndDate)) {
+			return fmt.Sprintf("on approved %s from %s to %s", t.Type, t.StartDate.Format(civilDateLayout), t.EndDate.Format(civilDateLayout))
+		}
+	}
+	if hours := c.hours[inspectorID]; len(hours) > 0 {
+		if _, ok := hours[int(day.Weekday())]; !ok {
+			return "does not work on " + day.Weekday().String() + "s"
+		}
+	}
+	return ""
+}
+
+// civilDate is the calendar day of t, as midnight UTC. Dates are stored and compared in
+// this form so the database's time zone doesn't shift them.
+func civilDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// parseCivilDate reads a YYYY-MM-DD date
+func parseCivilDate(value string) (time.Time, error) {
+	day, err := time.Parse(civilDateLayout, strings.TrimSpace(value))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %q is not a YYYY-MM-DD date", ErrInvalidAvailability, value)
+	}
+	return day, nil
+}
+
+// isSupervisorRole reports whether the role may manage other members' availability
+func isSupervisorRole(role string) bool {
+	return utils.HasHigherOrEqualPrivilege(role, "supervisor")
+}
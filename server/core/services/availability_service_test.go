@@ -0,0 +1,324 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"resource-mgmt/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// availabilityTestFixture is insp-1 working 08:00-16:00 Monday to Friday, planned around a
+// Monday a couple of weeks out whose Friday is a company holiday, and a depot to assign
+type availabilityTestFixture struct {
+	db       *gorm.DB
+	service  *AvailabilityService
+	workflow *WorkflowService
+	monday   time.Time
+	template *models.Template
+	site     *models.Site
+}
+
+func newAvailabilityTestFixture(t *testing.T) *availabilityTestFixture {
+	db := setupWorkflowTestDB(t)
+	ctx := context.Background()
+	notifications := NewNotificationService()
+	f := &availabilityTestFixture{db: db, service: NewAvailabilityService(db, notifications), workflow: NewWorkflowService(db, notifications)}
+	createTestMembers(t, db, "org-a", "supervisor", "super-1")
+	createTestMembers(t, db, "org-a", "inspector", "insp-1", "insp-2")
+	require.NoError(t, db.Create(&models.InspectorWorkload{OrganizationID: "org-a", InspectorID: "insp-1", IsAvailable: true}).Error)
+
+	f.monday = civilDate(time.Now()).AddDate(0, 0, 14)
+	for f.monday.Weekday() != time.Monday {
+		f.monday = f.monday.AddDate(0, 0, 1)
+	}
+
+	var week []models.WorkingHoursEntry
+	for weekday := 1; weekday <= 5; weekday++ {
+		week = append(week, models.WorkingHoursEntry{Weekday: weekday, StartTime: "08:00", EndTime: "16:00"})
+	}
+	hours, err := f.service.SetWorkingHours(ctx, "org-a", "insp-1", week)
+	require.NoError(t, err)
+	require.Len(t, hours, 5)
+	_, err = f.service.CreateHoliday(ctx, "org-a", "super-1", &models.CreateHolidayRequest{Date: f.date(4), Name: "Founders day"})
+	require.NoError(t, err)
+
+	f.template = createTestTemplate(t, db, "org-a", "Safety")
+	f.site = createTestSite(t, db, "org-a", "Depot", "1 Dock Rd")
+	return f
+}
+
+func (f *availabilityTestFixture) day(offset int) time.Time { return f.monday.AddDate(0, 0, offset) }
+
+func (f *availabilityTestFixture) date(offset int) string {
+	return f.day(offset).Format(civilDateLayout)
+}
+
+// requestTimeOff has insp-1 ask for Tuesday and Wednesday off
+func (f *availabilityTestFixture) requestTimeOff(t *testing.T) *models.InspectorTimeOff {
+	timeOff, err := f.service.RequestTimeOff(context.Background(), "org-a", "insp-1", "inspector", &models.TimeOffRequest{StartDate: f.date(1), EndDate: f.date(2), Reason: "Family visit"})
+	require.NoError(t, err)
+	return timeOff
+}
+
+// approveTimeOff requests Tuesday and Wednesday off and has the supervisor approve it
+func (f *availabilityTestFixture) approveTimeOff(t *testing.T) *models.InspectorTimeOff {
+	timeOff, _, err := f.service.ReviewTimeOff(context.Background(), "org-a", f.requestTimeOff(t).ID, "super-1", true, "Enjoy")
+	require.NoError(t, err)
+	return timeOff
+}
+
+// bulkAssign assigns the depot to insp-1 between start and due
+func (f *availabilityTestFixture) bulkAssign(start, due time.Time) ([]models.InspectionAssignment, error) {
+	return f.workflow.CreateBulkAssignment(context.Background(), "org-a", "super-1", map[string]interface{}{
+		"name": "Weekly", "template_id": f.template.ID.String(), "site_ids": []string{f.site.ID}, "start_date": start, "due_date": due,
+		"inspector_assignments": []map[string]interface{}{{"inspector_id": "insp-1", "site_ids": []string{f.site.ID}}},
+	})
+}
+
+func TestAvailabilityService_SetWorkingHoursValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry models.WorkingHoursEntry
+		twice bool
+	}{
+		{"time not HH:MM", models.WorkingHoursEntry{Weekday: 1, StartTime: "9am", EndTime: "17:00"}, false},
+		{"starts after it ends", models.WorkingHoursEntry{Weekday: 1, StartTime: "17:00", EndTime: "09:00"}, false},
+		{"weekday out of range", models.WorkingHoursEntry{Weekday: 7, StartTime: "09:00", EndTime: "17:00"}, false},
+		{"weekday listed twice", models.WorkingHoursEntry{Weekday: 1, StartTime: "09:00", EndTime: "17:00"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newAvailabilityTestFixture(t)
+			entries := []models.WorkingHoursEntry{tt.entry}
+			if tt.twice {
+				entries = append(entries, tt.entry)
+			}
+
+			_, err := f.service.SetWorkingHours(context.Background(), "org-a", "insp-1", entries)
+			assert.ErrorIs(t, err, ErrInvalidAvailability)
+		})
+	}
+}
+
+func TestAvailabilityService_RecurringHolidaysRepeatEveryYear(t *testing.T) {
+	f := newAvailabilityTestFixture(t)
+	ctx := context.Background()
+
+	_, err := f.service.CreateHoliday(ctx, "org-a", "super-1", &models.CreateHolidayRequest{Date: "2020-12-25", Name: "Christmas", Recurring: true})
+	require.NoError(t, err)
+	holidays, err := f.service.GetHolidays(ctx, "org-a", 2031)
+	require.NoError(t, err)
+	require.Len(t, holidays, 1)
+	assert.Equal(t, "2031-12-25", holidays[0].Date.Format(civilDateLayout))
+}
+
+func TestAvailabilityService_RequestTimeOffValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		userID  string
+		request func(f *availabilityTestFixture) *models.TimeOffRequest
+		wantErr error
+	}{
+		{"for someone else", "insp-2", func(f *availabilityTestFixture) *models.TimeOffRequest {
+			return &models.TimeOffRequest{InspectorID: "insp-1", StartDate: f.date(1), EndDate: f.date(2)}
+		}, ErrTimeOffForbidden},
+		{"ends before it starts", "insp-1", func(f *availabilityTestFixture) *models.TimeOffRequest {
+			return &models.TimeOffRequest{StartDate: f.date(2), EndDate: f.date(1)}
+		}, ErrInvalidAvailability},
+		{"overlaps a pending request", "insp-1", func(f *availabilityTestFixture) *models.TimeOffRequest {
+			return &models.TimeOffRequest{StartDate: f.date(2), EndDate: f.date(3)}
+		}, ErrTimeOffConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newAvailabilityTestFixture(t)
+			f.requestTimeOff(t)
+
+			_, err := f.service.RequestTimeOff(context.Background(), "org-a", tt.userID, "inspector", tt.request(f))
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestAvailabilityService_ApprovingTimeOffReportsConflicts(t *testing.T) {
+	f := newAvailabilityTestFixture(t)
+	ctx := context.Background()
+	timeOff := f.requestTimeOff(t)
+	assert.Equal(t, models.TimeOffPending, timeOff.Status)
+
+	// Pending time off doesn't block scheduling yet
+	require.NoError(t, f.service.EnsureAvailable(ctx, "org-a", "insp-1", f.day(1)))
+
+	scheduled := f.day(2).Add(10 * time.Hour)
+	require.NoError(t, f.db.Create(&models.Inspection{OrganizationID: "org-a", TemplateID: uuid.New(), InspectorID: "insp-1", SiteID: uuid.NewString(),
+		Status: "assigned", ScheduledFor: &scheduled}).Error)
+
+	timeOff, conflicts, err := f.service.ReviewTimeOff(ctx, "org-a", timeOff.ID, "super-1", true, "Enjoy")
+	require.NoError(t, err)
+	assert.Equal(t, models.TimeOffApproved, timeOff.Status)
+	assert.Len(t, conflicts, 1, "the inspection scheduled during the time off needs reassigning")
+	assert.ErrorIs(t, f.service.EnsureAvailable(ctx, "org-a", "insp-1", f.day(1)), ErrInspectorUnavailable)
+
+	var workload models.InspectorWorkload
+	require.NoError(t, f.db.Where("inspector_id = ?", "insp-1").First(&workload).Error)
+	var periods []map[string]string
+	require.NoError(t, json.Unmarshal(workload.ScheduledTimeOff, &periods))
+	require.Len(t, periods, 1)
+	assert.Equal(t, f.date(1), periods[0]["start"])
+}
+
+func TestAvailabilityService_ReviewTimeOffRejections(t *testing.T) {
+	tests := []struct {
+		name       string
+		reviewerID string
+		reviewed   bool
+		timeOffID  func(timeOff *models.InspectorTimeOff) string
+		wantErr    error
+	}{
+		{"own request", "insp-1", false, func(timeOff *models.InspectorTimeOff) string { return timeOff.ID }, ErrTimeOffForbidden},
+		{"already reviewed", "super-1", true, func(timeOff *models.InspectorTimeOff) string { return timeOff.ID }, ErrTimeOffConflict},
+		{"unknown request", "super-1", false, func(*models.InspectorTimeOff) string { return uuid.NewString() }, ErrTimeOffNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newAvailabilityTestFixture(t)
+			var timeOff *models.InspectorTimeOff
+			if tt.reviewed {
+				timeOff = f.approveTimeOff(t)
+			} else {
+				timeOff = f.requestTimeOff(t)
+			}
+
+			_, _, err := f.service.ReviewTimeOff(context.Background(), "org-a", tt.timeOffID(timeOff), tt.reviewerID, false, "")
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestAvailabilityService_CalendarExplainsDaysOff(t *testing.T) {
+	f := newAvailabilityTestFixture(t)
+	f.approveTimeOff(t)
+
+	calendar, err := f.service.GetCalendar(context.Background(), "org-a", "insp-1", f.day(-1), f.day(4))
+	require.NoError(t, err)
+	require.Len(t, calendar, 6)
+
+	tests := []struct {
+		name       string
+		offset     int
+		wantReason string
+	}{
+		{"weekend", -1, "does not work on Sundays"},
+		{"working day", 0, ""},
+		{"time off", 1, "on approved vacation from " + f.date(1) + " to " + f.date(2)},
+		{"after the time off", 3, ""},
+		{"holiday", 4, "organization holiday: Founders day"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := calendar[tt.offset+1]
+			assert.Equal(t, tt.wantReason, entry.Reason)
+			assert.Equal(t, tt.wantReason == "", entry.Available)
+			if entry.Available {
+				assert.Equal(t, "08:00", entry.StartTime)
+			}
+		})
+	}
+}
+
+func TestAvailabilityService_AssignmentsAvoidTimeOff(t *testing.T) {
+	f := newAvailabilityTestFixture(t)
+	f.approveTimeOff(t)
+
+	// Assigning work that starts during the time off fails with the reason
+	_, err := f.bulkAssign(f.day(1), f.day(3))
+	assert.ErrorIs(t, err, ErrInspectorUnavailable)
+	assert.Contains(t, err.Error(), "on approved vacation")
+
+	// A due date on Saturday moves back past the Friday holiday to Thursday
+	assignments, err := f.bulkAssign(f.day(0), f.day(5).Add(17*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, assignments, 1)
+	require.NotNil(t, assignments[0].DueDate)
+	assert.Equal(t, f.day(3).Add(17*time.Hour), assignments[0].DueDate.UTC())
+}
+
+func TestAvailabilityService_AssignmentsWithoutStartCheckToday(t *testing.T) {
+	f := newAvailabilityTestFixture(t)
+	today := civilDate(time.Now())
+	require.NoError(t, f.db.Create(&models.InspectorTimeOff{OrganizationID: "org-a", InspectorID: "insp-1", StartDate: today.AddDate(0, 0, -1), EndDate: today.AddDate(0, 0, 1),
+		Type: "sick", Status: "approved", RequestedBy: "insp-1"}).Error)
+
+	_, err := f.workflow.CreateBulkAssignment(context.Background(), "org-a", "super-1", map[string]interface{}{
+		"name": "Weekly", "template_id": f.template.ID.String(), "site_ids": []string{f.site.ID}, "due_date": f.day(3),
+		"inspector_assignments": []map[string]interface{}{{"inspector_id": "insp-1", "site_ids": []string{f.site.ID}}},
+	})
+	assert.ErrorIs(t, err, ErrInspectorUnavailable)
+}
+
+func TestAvailabilityService_ReassignmentAvoidsTimeOff(t *testing.T) {
+	tests := []struct {
+		name    string
+		start   int
+		wantErr error
+	}{
+		{"starts on a working day", 0, nil},
+		{"starts during the time off", 2, ErrInspectorUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newAvailabilityTestFixture(t)
+			assignments, err := f.bulkAssign(f.day(0), f.day(3))
+			require.NoError(t, err)
+			f.approveTimeOff(t)
+			require.NoError(t, f.db.Model(&models.InspectionAssignment{}).Where("id = ?", assignments[0].ID).Update("start_date", f.day(tt.start)).Error)
+
+			_, err = f.workflow.ReassignInspection(context.Background(), "org-a", assignments[0].ID, "super-1", "insp-1", "cover", false)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAvailabilityService_CancelTimeOff(t *testing.T) {
+	tests := []struct {
+		name     string
+		userID   string
+		userRole string
+		wantErr  error
+	}{
+		{"other inspector", "insp-2", "inspector", ErrTimeOffForbidden},
+		{"own time off", "insp-1", "inspector", nil},
+		{"supervisor", "super-1", "supervisor", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newAvailabilityTestFixture(t)
+			ctx := context.Background()
+			timeOff := f.approveTimeOff(t)
+
+			_, err := f.service.CancelTimeOff(ctx, "org-a", timeOff.ID, tt.userID, tt.userRole)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.ErrorIs(t, f.service.EnsureAvailable(ctx, "org-a", "insp-1", f.day(1)), ErrInspectorUnavailable)
+				return
+			}
+			require.NoError(t, err)
+
+			// Cancelling approved time off frees the days again
+			require.NoError(t, f.service.EnsureAvailable(ctx, "org-a", "insp-1", f.day(1)))
+			var workload models.InspectorWorkload
+			require.NoError(t, f.db.Where("inspector_id = ?", "insp-1").First(&workload).Error)
+			assert.JSONEq(t, `[]`, string(workload.ScheduledTimeOff))
+		})
+	}
+}
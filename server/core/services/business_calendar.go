@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"resource-mgmt/models"
+	"resource-mgmt/pkg/database"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidTimezone is returned for time zones that aren't IANA names, like "Europe/Paris"
+var ErrInvalidTimezone = errors.New("invalid time zone")
+
+// maxBusinessCalendarDays bounds how far a due-date calculation walks the calendar
+const maxBusinessCalendarDays = 10 * maxAvailabilityRangeDays
+
+// validateTimezone checks the time zone can be loaded. Empty is allowed; it means the
+// default of whatever the zone is set on.
+func validateTimezone(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return nil
+	}
+	if _, err := time.LoadLocation(name); err != nil || name == "Local" {
+		return fmt.Errorf("%w: %q is not an IANA time zone", ErrInvalidTimezone, name)
+	}
+	return nil
+}
+
+// loadTimezone returns the named zone, or UTC when it is empty or unknown
+func loadTimezone(name string) *time.Location {
+	if name == "" || name == "Local" {
+		return time.UTC
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// organizationLocation returns the organization's time zone
+func organizationLocation(ctx context.Context, db *gorm.DB, organizationID string) *time.Location {
+	var org models.Organization
+	if err := database.Conn(ctx, db).Select("id", "timezone").Where("id = ?", organizationID).First(&org).Error; err != nil {
+		return time.UTC
+	}
+	return loadTimezone(org.Timezone)
+}
+
+// businessCalendar is when an organization, or one of its sites, is open for work: its
+// weekly business hours and holidays, read in the site's or organization's time zone
+type businessCalendar struct {
+	location *time.Location
+	hours    map[int]models.OrganizationBusinessHours
+	holidays []models.OrganizationHoliday
+}
+
+// loadBusinessCalendar loads the organization's business calendar in the site's time zone,
+// falling back to the organization's. siteID may be empty.
+func loadBusinessCalendar(ctx context.Context, db *gorm.DB, organizationID, siteID string) (*businessCalendar, error) {
+	conn := database.Conn(ctx, db)
+	calendar := &businessCalendar{hours: make(map[int]models.OrganizationBusinessHours)}
+
+	if siteID != "" {
+		var site models.Site
+		if err := conn.Select("id", "timezone").Where("id = ? AND organization_id = ?", siteID, organizationID).First(&site).Error; err == nil && site.Timezone != "" {
+			calendar.location = loadTimezone(site.Timezone)
+		}
+	}
+	if calendar.location == nil {
+		calendar.location = organizationLocation(ctx, db, organizationID)
+	}
+
+	var hours []models.OrganizationBusinessHours
+	if err := conn.Where("organization_id = ?", organizationID).Find(&hours).Error; err != nil {
+		return nil, fmt.Errorf("failed to get business hours: %v", err)
+	}
+	for _, h := range hours {
+		calendar.hours[h.Weekday] = h
+	}
+
+	holidays, err := loadOrganizationHolidays(ctx, db, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	calendar.holidays = holidays
+	return calendar, nil
+}
+
+// openOn returns when the business opens and closes on the local day of t. Without business
+// hours every day that isn't a holiday is open from midnight to midnight.
+func (c *businessCalendar) openOn(t time.Time) (time.Time, time.Time, bool) {
+	local := t.In(c.location)
+	year, month, day := local.Date()
+	if _, ok := holidayOn(c.holidays, time.Date(year, month, day, 0, 0, 0, 0, time.UTC)); ok {
+		return time.Time{}, time.Time{}, false
+	}
+	if len(c.hours) == 0 {
+		return time.Date(year, month, day, 0, 0, 0, 0, c.location), time.Date(year, month, day+1, 0, 0, 0, 0, c.location), true
+	}
+	hours, ok := c.hours[int(local.Weekday())]
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	return c.clockTime(year, month, day, hours.StartTime), c.clockTime(year, month, day, hours.EndTime), true
+}
+
+// clockTime is the HH:MM time on the local day
+func (c *businessCalendar) clockTime(year int, month time.Month, day int, clock string) time.Time {
+	var hour, minute int
+	fmt.Sscanf(clock, "%d:%d", &hour, &minute)
+	return time.Date(year, month, day, hour, minute, 0, 0, c.location)
+}
+
+// nextDay is local midnight of the day after t's local day
+func (c *businessCalendar) nextDay(t time.Time) time.Time {
+	year, month, day := t.In(c.location).Date()
+	return time.Date(year, month, day+1, 0, 0, 0, 0, c.location)
+}
+
+// AddWorkingTime returns when d of business time has passed from start. Time outside
+// business hours and on holidays doesn't count.
+func (c *businessCalendar) AddWorkingTime(start time.Time, d time.Duration) time.Time {
+	if d <= 0 {
+		return start
+	}
+	remaining := d
+	t := start
+	for i := 0; i < maxBusinessCalendarDays; i++ {
+		if opens, closes, ok := c.openOn(t); ok {
+			if opens.Before(t) {
+				opens = t
+			}
+			if available := closes.Sub(opens); available > 0 {
+				if remaining <= available {
+					return opens.Add(remaining)
+				}
+				remaining -= available
+			}
+		}
+		t = c.nextDay(t)
+	}
+	// A calendar that is never open; fall back to wall-clock time
+	return start.Add(d)
+}
+
+// WorkingTimeBetween returns how much business time passes from a to b
+func (c *businessCalendar) WorkingTimeBetween(a, b time.Time) time.Duration {
+	var total time.Duration
+	for t, i := a, 0; t.Before(b) && i < maxBusinessCalendarDays; t, i = c.nextDay(t), i+1 {
+		opens, closes, ok := c.openOn(t)
+		if !ok {
+			continue
+		}
+		if opens.Before(t) {
+			opens = t
+		}
+		if b.Before(closes) {
+			closes = b
+		}
+		if opens.Before(closes) {
+			total += closes.Sub(opens)
+		}
+	}
+	return total
+}
@@ -0,0 +1,254 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"resource-mgmt/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// businessCalendarTestFixture is org-a in Chicago, open 9 to 5 on weekdays with a holiday on
+// the Monday after daylight saving time ends and a Paris office, and org-b with no business
+// hours or timezone
+type businessCalendarTestFixture struct {
+	db      *gorm.DB
+	service *AvailabilityService
+	paris   *models.Site
+	chicago *time.Location
+	friday  time.Time
+}
+
+func newBusinessCalendarTestFixture(t *testing.T) *businessCalendarTestFixture {
+	db := setupServiceTestDB(t, &models.Organization{}, &models.Site{}, &models.OrganizationBusinessHours{}, &models.OrganizationHoliday{},
+		&models.SLAPolicy{}, &models.SLAClock{}, &models.SLABreach{})
+	ctx := context.Background()
+	f := &businessCalendarTestFixture{db: db, service: NewAvailabilityService(db, nil)}
+
+	require.NoError(t, db.Create(&models.Organization{ID: "org-a", Name: "Client A", Domain: "client-a", Slug: "client-a", Timezone: "America/Chicago"}).Error)
+	require.NoError(t, db.Create(&models.Organization{ID: "org-b", Name: "Client B", Domain: "client-b", Slug: "client-b"}).Error)
+	f.paris = &models.Site{OrganizationID: "org-a", Name: "Paris office", Address: "1 Rue de Rivoli", Timezone: "Europe/Paris"}
+	require.NoError(t, db.Create(f.paris).Error)
+
+	var week []models.WorkingHoursEntry
+	for weekday := 1; weekday <= 5; weekday++ {
+		week = append(week, models.WorkingHoursEntry{Weekday: weekday, StartTime: "09:00", EndTime: "17:00"})
+	}
+	hours, err := f.service.SetBusinessHours(ctx, "org-a", week)
+	require.NoError(t, err)
+	require.Len(t, hours, 5)
+	_, err = f.service.CreateHoliday(ctx, "org-a", "admin-1", &models.CreateHolidayRequest{Date: "2026-11-02", Name: "Company day"})
+	require.NoError(t, err)
+
+	f.chicago, err = time.LoadLocation("America/Chicago")
+	require.NoError(t, err)
+	f.friday = time.Date(2026, 10, 30, 16, 0, 0, 0, f.chicago)
+	return f
+}
+
+// tuesday is a time on the first working day after the Friday, in Chicago
+func (f *businessCalendarTestFixture) tuesday(hour, minute int) time.Time {
+	return time.Date(2026, 11, 3, hour, minute, 0, 0, f.chicago)
+}
+
+// trackSLA starts a four-hour completion clock for an assignment at the Friday
+func (f *businessCalendarTestFixture) trackSLA(t *testing.T) *SLAService {
+	ctx := context.Background()
+	sla := NewSLAService(f.db, nil)
+	_, err := sla.CreatePolicy(ctx, "org-a", "admin-1", &models.SLAPolicyRequest{Name: "Standard", TimeToCompleteMinutes: 4 * 60})
+	require.NoError(t, err)
+	work := &slaWork{organizationID: "org-a", targetType: models.SLATargetAssignment, targetID: "assignment-1", assigneeID: "insp-1",
+		stages: []slaStage{{name: models.SLAStageComplete, ready: true}}}
+	require.NoError(t, sla.track(ctx, work, f.friday))
+	return sla
+}
+
+func TestValidateTimezone(t *testing.T) {
+	tests := []struct {
+		name     string
+		timezone string
+		wantErr  error
+	}{
+		{"unset", "", nil},
+		{"IANA name", "America/Chicago", nil},
+		{"unknown zone", "Mars/Olympus_Mons", ErrInvalidTimezone},
+		{"server local time", "Local", ErrInvalidTimezone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTimezone(tt.timezone)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestBusinessCalendar_SetBusinessHoursValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry models.WorkingHoursEntry
+	}{
+		{"closes before it opens", models.WorkingHoursEntry{Weekday: 1, StartTime: "17:00", EndTime: "09:00"}},
+		{"time not HH:MM", models.WorkingHoursEntry{Weekday: 1, StartTime: "9am", EndTime: "17:00"}},
+		{"weekday out of range", models.WorkingHoursEntry{Weekday: -1, StartTime: "09:00", EndTime: "17:00"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newBusinessCalendarTestFixture(t)
+			_, err := f.service.SetBusinessHours(context.Background(), "org-a", []models.WorkingHoursEntry{tt.entry})
+			assert.ErrorIs(t, err, ErrInvalidAvailability)
+		})
+	}
+}
+
+func TestBusinessCalendar_DueDate(t *testing.T) {
+	tests := []struct {
+		name         string
+		organization string
+		site         func(f *businessCalendarTestFixture) string
+		start        func(f *businessCalendarTestFixture) time.Time
+		estimate     time.Duration
+		wantTimezone string
+		want         func(f *businessCalendarTestFixture) time.Time
+	}{
+		{"weekend and holiday are skipped", "org-a",
+			func(f *businessCalendarTestFixture) string { return "" },
+			func(f *businessCalendarTestFixture) time.Time { return f.friday },
+			3 * time.Hour, "America/Chicago",
+			func(f *businessCalendarTestFixture) time.Time { return f.tuesday(11, 0) }},
+		{"site keeps its own clock", "org-a",
+			func(f *businessCalendarTestFixture) string { return f.paris.ID },
+			func(f *businessCalendarTestFixture) time.Time {
+				return time.Date(2026, 10, 30, 16, 0, 0, 0, loadTimezone("Europe/Paris"))
+			},
+			2 * time.Hour, "Europe/Paris",
+			func(f *businessCalendarTestFixture) time.Time {
+				return time.Date(2026, 11, 3, 10, 0, 0, 0, loadTimezone("Europe/Paris"))
+			}},
+		{"every day counts without business hours", "org-b",
+			func(f *businessCalendarTestFixture) string { return "" },
+			func(f *businessCalendarTestFixture) time.Time { return time.Date(2026, 10, 31, 22, 0, 0, 0, time.UTC) },
+			30 * time.Hour, "UTC",
+			func(f *businessCalendarTestFixture) time.Time { return time.Date(2026, 11, 2, 4, 0, 0, 0, time.UTC) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newBusinessCalendarTestFixture(t)
+			due, err := f.service.DueDate(context.Background(), tt.organization, tt.site(f), tt.start(f), tt.estimate)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantTimezone, due.Timezone)
+			assert.True(t, tt.want(f).Equal(due.DueDate), "due %s", due.DueDate)
+		})
+	}
+}
+
+func TestBusinessCalendar_WorkingTime(t *testing.T) {
+	f := newBusinessCalendarTestFixture(t)
+	calendar, err := loadBusinessCalendar(context.Background(), f.db, "org-a", "")
+	require.NoError(t, err)
+
+	assert.Equal(t, 3*time.Hour, calendar.WorkingTimeBetween(f.friday, f.tuesday(11, 0)), "an hour on Friday and two on Tuesday")
+	assert.True(t, f.tuesday(11, 0).Equal(calendar.AddWorkingTime(f.friday.Add(18*time.Hour), 2*time.Hour)), "work starting on Saturday waits for Tuesday")
+}
+
+func TestBusinessCalendar_SLADeadlineCountsBusinessHours(t *testing.T) {
+	f := newBusinessCalendarTestFixture(t)
+	sla := f.trackSLA(t)
+
+	clocks, err := sla.GetClocks(context.Background(), "org-a", models.SLATargetAssignment, "assignment-1")
+	require.NoError(t, err)
+	require.Len(t, clocks, 1)
+	assert.True(t, f.tuesday(12, 0).Equal(clocks[0].DeadlineAt), "deadline %s", clocks[0].DeadlineAt)
+}
+
+func TestBusinessCalendar_SLAPausesCountBusinessHours(t *testing.T) {
+	tests := []struct {
+		name         string
+		pausedAt     func(f *businessCalendarTestFixture) time.Time
+		resumedAt    func(f *businessCalendarTestFixture) time.Time
+		wantDeadline func(f *businessCalendarTestFixture) time.Time
+	}{
+		{"only the time after opening counts",
+			func(f *businessCalendarTestFixture) time.Time { return f.tuesday(8, 0) },
+			func(f *businessCalendarTestFixture) time.Time { return f.tuesday(9, 30) },
+			func(f *businessCalendarTestFixture) time.Time { return f.tuesday(12, 30) }},
+		{"paused over the closed days",
+			func(f *businessCalendarTestFixture) time.Time { return f.friday.Add(30 * time.Minute) },
+			func(f *businessCalendarTestFixture) time.Time { return f.tuesday(8, 0) },
+			func(f *businessCalendarTestFixture) time.Time { return f.tuesday(12, 30) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newBusinessCalendarTestFixture(t)
+			ctx := context.Background()
+			sla := f.trackSLA(t)
+
+			_, err := sla.PauseClocks(ctx, "org-a", models.SLATargetAssignment, "assignment-1", "Waiting for the client", tt.pausedAt(f))
+			require.NoError(t, err)
+			resumed, err := sla.ResumeClocks(ctx, "org-a", models.SLATargetAssignment, "assignment-1", tt.resumedAt(f))
+			require.NoError(t, err)
+			assert.True(t, tt.wantDeadline(f).Equal(resumed[0].DeadlineAt), "deadline %s", resumed[0].DeadlineAt)
+		})
+	}
+}
+
+func TestWorkflowService_ComputedDueDateSkipsInspectorDaysOff(t *testing.T) {
+	// Plan around a Monday a couple of weeks out; work estimated at three days of round the
+	// clock business time is due on Thursday at 08:00
+	monday := civilDate(time.Now()).AddDate(0, 0, 14)
+	for monday.Weekday() != time.Monday {
+		monday = monday.AddDate(0, 0, 1)
+	}
+	day := func(offset int) time.Time { return monday.AddDate(0, 0, offset) }
+
+	tests := []struct {
+		name     string
+		workdays []int
+		timeOff  bool
+		want     time.Time
+	}{
+		{"due on a working day", []int{1, 2, 3, 4, 5}, false, day(3).Add(8 * time.Hour)},
+		{"due during time off", []int{1, 2, 3, 4, 5}, true, day(1).Add(8 * time.Hour)},
+		{"due on a non-working day", []int{1, 2, 3}, false, day(2).Add(8 * time.Hour)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupWorkflowTestDB(t, &models.Organization{})
+			ctx := context.Background()
+			workflow := NewWorkflowService(db, NewNotificationService())
+
+			require.NoError(t, db.Create(&models.Organization{ID: "org-a", Name: "Client A", Domain: "client-a", Slug: "client-a", Timezone: "UTC"}).Error)
+			createTestMembers(t, db, "org-a", "inspector", "insp-1")
+			var week []models.WorkingHoursEntry
+			for _, weekday := range tt.workdays {
+				week = append(week, models.WorkingHoursEntry{Weekday: weekday, StartTime: "08:00", EndTime: "16:00"})
+			}
+			_, err := NewAvailabilityService(db, nil).SetWorkingHours(ctx, "org-a", "insp-1", week)
+			require.NoError(t, err)
+			if tt.timeOff {
+				require.NoError(t, db.Create(&models.InspectorTimeOff{OrganizationID: "org-a", InspectorID: "insp-1", StartDate: day(2), EndDate: day(3),
+					Type: "vacation", Status: models.TimeOffApproved, RequestedBy: "insp-1"}).Error)
+			}
+
+			template := createTestTemplate(t, db, "org-a", "Safety")
+			site := createTestSite(t, db, "org-a", "Depot", "1 Dock Rd")
+
+			assignments, err := workflow.CreateBulkAssignment(context.Background(), "org-a", "super-1", map[string]interface{}{
+				"name": "Weekly", "template_id": template.ID.String(), "site_ids": []string{site.ID},
+				"start_date": day(0).Add(8 * time.Hour), "estimated_hours": 72,
+				"inspector_assignments": []map[string]interface{}{{"inspector_id": "insp-1", "site_ids": []string{site.ID}}},
+			})
+			require.NoError(t, err)
+			require.Len(t, assignments, 1)
+			require.NotNil(t, assignments[0].DueDate)
+			assert.Equal(t, tt.want, assignments[0].DueDate.UTC())
+		})
+	}
+}
@@ -0,0 +1,302 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"resource-mgmt/config"
+	"resource-mgmt/models"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrAddressNotFound is returned when a geocoder has no match for an address
+var ErrAddressNotFound = errors.New("address not found")
+
+// Geocoder turns an address into coordinates
+type Geocoder interface {
+	// Geocode returns the best match for address, or ErrAddressNotFound
+	Geocode(ctx context.Context, address string) (*models.GeocodeResult, error)
+	// Name identifies the provider, so cached answers from one aren't served for another
+	Name() string
+}
+
+// negativeGeocodeTTL is how long an address the provider couldn't find is remembered
+const negativeGeocodeTTL = 7 * 24 * time.Hour
+
+// normalizeGeocodeAddress makes trivially different spellings of an address share a cache entry
+func normalizeGeocodeAddress(address string) string {
+	address = strings.ToLower(strings.ReplaceAll(address, ",", " "))
+	return strings.Join(strings.Fields(address), " ")
+}
+
+// =====================================================
+// NOMINATIM
+// =====================================================
+
+// NominatimGeocoder queries a Nominatim-compatible search API. Requests are serialized
+// and spaced by minInterval to stay within the provider's usage policy.
+type NominatimGeocoder struct {
+	baseURL     string
+	userAgent   string
+	email       string
+	minInterval time.Duration
+	httpClient  *http.Client
+
+	mu          sync.Mutex
+	lastRequest time.Time
+}
+
+func NewNominatimGeocoder(baseURL, userAgent, email string, minInterval time.Duration) *NominatimGeocoder {
+	return &NominatimGeocoder{
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		userAgent:   userAgent,
+		email:       email,
+		minInterval: minInterval,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (g *NominatimGeocoder) Name() string {
+	return "nominatim"
+}
+
+func (g *NominatimGeocoder) Geocode(ctx context.Context, address string) (*models.GeocodeResult, error) {
+	if err := g.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	query := url.Values{"q": {address}, "format": {"jsonv2"}, "limit": {"1"}}
+	if g.email != "" {
+		query.Set("email", g.email)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.baseURL+"/search?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build geocode request: %v", err)
+	}
+	req.Header.Set("User-Agent", g.userAgent)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("geocode request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("geocode request failed with status %d", resp.StatusCode)
+	}
+
+	var matches []struct {
+		Lat         string `json:"lat"`
+		Lon         string `json:"lon"`
+		DisplayName string `json:"display_name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&matches); err != nil {
+		return nil, fmt.Errorf("failed to decode geocode response: %v", err)
+	}
+	if len(matches) == 0 {
+		return nil, ErrAddressNotFound
+	}
+
+	lat, latErr := strconv.ParseFloat(matches[0].Lat, 64)
+	lng, lngErr := strconv.ParseFloat(matches[0].Lon, 64)
+	if latErr != nil || lngErr != nil {
+		return nil, fmt.Errorf("geocode response has invalid coordinates %q, %q", matches[0].Lat, matches[0].Lon)
+	}
+	return &models.GeocodeResult{Latitude: lat, Longitude: lng, DisplayName: matches[0].DisplayName, Provider: g.Name()}, nil
+}
+
+// wait blocks until minInterval has passed since the previous request
+func (g *NominatimGeocoder) wait(ctx context.Context) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if delay := time.Until(g.lastRequest.Add(g.minInterval)); delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	g.lastRequest = time.Now()
+	return nil
+}
+
+// =====================================================
+// STATIC
+// =====================================================
+
+// StaticGeocoder answers from a fixed table of addresses, for tests and installs
+// without internet access
+type StaticGeocoder struct {
+	entries map[string]models.GeoPoint
+}
+
+// NewStaticGeocoder builds a geocoder from address to coordinate pairs
+func NewStaticGeocoder(entries map[string]models.GeoPoint) *StaticGeocoder {
+	normalized := make(map[string]models.GeoPoint, len(entries))
+	for address, point := range entries {
+		normalized[normalizeGeocodeAddress(address)] = point
+	}
+	return &StaticGeocoder{entries: normalized}
+}
+
+// LoadStaticGeocoder reads a lookup file: either a JSON object of address to
+// {"latitude", "longitude"}, or a CSV with address, latitude and longitude columns
+func LoadStaticGeocoder(path string) (*StaticGeocoder, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open geocoder file: %v", err)
+	}
+	defer file.Close()
+
+	entries := make(map[string]models.GeoPoint)
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		records, err := csv.NewReader(file).ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read geocoder file: %v", err)
+		}
+		for i, record := range records {
+			if len(record) < 3 {
+				return nil, fmt.Errorf("geocoder file line %d: expected address, latitude, longitude", i+1)
+			}
+			lat, latErr := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+			lng, lngErr := strconv.ParseFloat(strings.TrimSpace(record[2]), 64)
+			if latErr != nil || lngErr != nil {
+				if i == 0 {
+					continue // Header row
+				}
+				return nil, fmt.Errorf("geocoder file line %d: invalid coordinates", i+1)
+			}
+			entries[record[0]] = models.GeoPoint{Latitude: lat, Longitude: lng}
+		}
+	} else if err := json.NewDecoder(file).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("failed to read geocoder file: %v", err)
+	}
+
+	for address, point := range entries {
+		if err := validateGeoPoint(point); err != nil {
+			return nil, fmt.Errorf("geocoder file entry %q: %v", address, err)
+		}
+	}
+	return NewStaticGeocoder(entries), nil
+}
+
+func (g *StaticGeocoder) Name() string {
+	return "static"
+}
+
+func (g *StaticGeocoder) Geocode(ctx context.Context, address string) (*models.GeocodeResult, error) {
+	point, ok := g.entries[normalizeGeocodeAddress(address)]
+	if !ok {
+		return nil, ErrAddressNotFound
+	}
+	return &models.GeocodeResult{Latitude: point.Latitude, Longitude: point.Longitude, Provider: g.Name()}, nil
+}
+
+// =====================================================
+// CACHE
+// =====================================================
+
+// CachingGeocoder remembers another geocoder's answers in the geocode_cache table. Misses
+// are remembered for negativeGeocodeTTL; provider errors are not cached.
+type CachingGeocoder struct {
+	db   *gorm.DB
+	next Geocoder
+}
+
+func NewCachingGeocoder(db *gorm.DB, next Geocoder) *CachingGeocoder {
+	return &CachingGeocoder{db: db, next: next}
+}
+
+func (g *CachingGeocoder) Name() string {
+	return g.next.Name()
+}
+
+func (g *CachingGeocoder) Geocode(ctx context.Context, address string) (*models.GeocodeResult, error) {
+	key := normalizeGeocodeAddress(address)
+	// The cache isn't tenant data, so it bypasses any tenant transaction on ctx; a failed
+	// write must not abort the caller's transaction
+	db := g.db.WithContext(ctx)
+
+	var entry models.GeocodeCacheEntry
+	err := db.Where("provider = ? AND address_key = ?", g.Name(), key).First(&entry).Error
+	switch {
+	case err == nil && entry.Found:
+		return &models.GeocodeResult{Latitude: entry.Latitude, Longitude: entry.Longitude, DisplayName: entry.DisplayName, Provider: entry.Provider}, nil
+	case err == nil && time.Since(entry.UpdatedAt) < negativeGeocodeTTL:
+		return nil, ErrAddressNotFound
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		log.Printf("Geocode cache lookup failed: %v", err)
+	}
+
+	result, err := g.next.Geocode(ctx, address)
+	if err != nil && !errors.Is(err, ErrAddressNotFound) {
+		return nil, err
+	}
+
+	entry = models.GeocodeCacheEntry{Provider: g.Name(), AddressKey: key, Found: result != nil}
+	if result != nil {
+		entry.Latitude = result.Latitude
+		entry.Longitude = result.Longitude
+		entry.DisplayName = result.DisplayName
+	}
+	if cacheErr := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "provider"}, {Name: "address_key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"found", "latitude", "longitude", "display_name", "updated_at"}),
+	}).Create(&entry).Error; cacheErr != nil {
+		log.Printf("Failed to cache geocode result: %v", cacheErr)
+	}
+
+	return result, err
+}
+
+var (
+	defaultGeocoder     Geocoder
+	defaultGeocoderOnce sync.Once
+)
+
+// DefaultGeocoder returns the process-wide geocoder chosen by config.GeocoderProvider,
+// or nil when geocoding is off
+func DefaultGeocoder() Geocoder {
+	defaultGeocoderOnce.Do(func() {
+		var geocoder Geocoder
+		switch config.GeocoderProvider {
+		case "nominatim":
+			interval, err := time.ParseDuration(config.GeocoderMinInterval)
+			if err != nil {
+				interval = time.Second
+			}
+			geocoder = NewNominatimGeocoder(config.GeocoderURL, config.GeocoderUserAgent, config.GeocoderEmail, interval)
+		case "static":
+			static, err := LoadStaticGeocoder(config.GeocoderStaticFile)
+			if err != nil {
+				log.Printf("Geocoding disabled: %v", err)
+				return
+			}
+			geocoder = static
+		default:
+			return
+		}
+
+		if config.DB != nil {
+			geocoder = NewCachingGeocoder(config.DB, geocoder)
+		}
+		defaultGeocoder = geocoder
+	})
+	return defaultGeocoder
+}
@@ -0,0 +1,277 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"resource-mgmt/models"
+	"resource-mgmt/pkg/database"
+	"resource-mgmt/pkg/tenant"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrOutsideGeofence is returned when an organization blocks starting inspections away from the site
+	ErrOutsideGeofence = errors.New("device location is outside the site geofence")
+	// ErrDeviceLocationRequired is returned when an organization requires coordinates to start an inspection
+	ErrDeviceLocationRequired = errors.New("device location is required to start this inspection")
+)
+
+const (
+	// defaultGeofenceRadiusMeters applies when neither the site nor the organization sets a radius
+	defaultGeofenceRadiusMeters = 200
+	// defaultMaxAccuracyMeters flags fixes less precise than this
+	defaultMaxAccuracyMeters = 100
+)
+
+// GeofenceSettings are read from Organization.Settings
+type GeofenceSettings struct {
+	Policy            string  `json:"geofence_policy"`              // off, warn (default), block
+	RadiusMeters      int     `json:"geofence_radius_meters"`       // Default radius for sites without their own
+	MaxAccuracyMeters float64 `json:"geofence_max_accuracy_meters"` // Fixes less accurate than this are flagged
+	RequireLocation   bool    `json:"geofence_require_location"`    // Flag (or block) check-ins without coordinates
+}
+
+// InspectionCheckService records GPS check-ins and check-outs and verifies them against site geofences
+type InspectionCheckService struct {
+	db *gorm.DB
+}
+
+func NewInspectionCheckService(db *gorm.DB) *InspectionCheckService {
+	return &InspectionCheckService{db: db}
+}
+
+// =====================================================
+// CHECK-IN AND CHECK-OUT
+// =====================================================
+
+// CheckIn records the inspector's location when an inspection starts. Under the block
+// policy an out-of-geofence or (when required) missing location is refused.
+func (s *InspectionCheckService) CheckIn(ctx context.Context, inspection *models.Inspection, location *models.DeviceLocation) (*models.InspectionCheckEvent, error) {
+	return s.record(ctx, inspection, models.CheckEventCheckIn, location)
+}
+
+// CheckOut records the inspector's location when an inspection completes, along with the
+// time on site since check-in. Check-outs are only ever flagged, never refused, so finished
+// work is not lost.
+func (s *InspectionCheckService) CheckOut(ctx context.Context, inspection *models.Inspection, location *models.DeviceLocation) (*models.InspectionCheckEvent, error) {
+	return s.record(ctx, inspection, models.CheckEventCheckOut, location)
+}
+
+func (s *InspectionCheckService) record(ctx context.Context, inspection *models.Inspection, eventType string, location *models.DeviceLocation) (*models.InspectionCheckEvent, error) {
+	db := database.Conn(ctx, s.db)
+
+	settings, err := s.GetGeofenceSettings(ctx, inspection.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := tenant.GetUserID(ctx)
+	if err != nil || userID == "" {
+		userID = inspection.InspectorID
+	}
+
+	var site models.Site
+	if err := db.Select("id, latitude, longitude, geofence_radius_meters").
+		Where("id = ? AND organization_id = ?", inspection.SiteID, inspection.OrganizationID).
+		First(&site).Error; err != nil {
+		return nil, fmt.Errorf("failed to get inspection site: %v", err)
+	}
+
+	event := &models.InspectionCheckEvent{
+		OrganizationID:       inspection.OrganizationID,
+		InspectionID:         inspection.ID.String(),
+		SiteID:               inspection.SiteID,
+		UserID:               userID,
+		EventType:            eventType,
+		GeofenceRadiusMeters: settings.RadiusMeters,
+		RecordedAt:           time.Now(),
+	}
+	if site.GeofenceRadius != nil && *site.GeofenceRadius > 0 {
+		event.GeofenceRadiusMeters = *site.GeofenceRadius
+	}
+
+	enforce := settings.Policy != models.GeofencePolicyOff
+	blocking := settings.Policy == models.GeofencePolicyBlock && eventType == models.CheckEventCheckIn
+	var reasons []string
+
+	if location == nil {
+		if enforce && settings.RequireLocation {
+			if blocking {
+				return nil, ErrDeviceLocationRequired
+			}
+			reasons = append(reasons, models.CheckFlagNoLocation)
+		}
+	} else {
+		point := models.GeoPoint{Latitude: location.Latitude, Longitude: location.Longitude}
+		if err := validateGeoPoint(point); err != nil {
+			return nil, err
+		}
+		accuracy := math.Max(0, location.AccuracyMeters)
+		event.Latitude = &point.Latitude
+		event.Longitude = &point.Longitude
+		event.AccuracyMeters = &accuracy
+
+		// Sites without coordinates can't be verified; the event is kept unflagged
+		if site.Latitude != nil && site.Longitude != nil {
+			distance := math.Round(HaversineKm(point, models.GeoPoint{Latitude: *site.Latitude, Longitude: *site.Longitude}) * 1000)
+			// Give the device the benefit of its reported accuracy, but no more than one radius,
+			// so a wildly imprecise fix can't vouch for itself
+			allowance := math.Min(accuracy, float64(event.GeofenceRadiusMeters))
+			within := distance <= float64(event.GeofenceRadiusMeters)+allowance
+			event.DistanceMeters = &distance
+			event.WithinGeofence = &within
+
+			if !within && enforce {
+				if blocking {
+					return nil, fmt.Errorf("%w: %.0f m from the site, allowed %d m", ErrOutsideGeofence, distance, event.GeofenceRadiusMeters)
+				}
+				reasons = append(reasons, models.CheckFlagOutsideGeofence)
+			}
+		}
+		if enforce && accuracy > settings.MaxAccuracyMeters {
+			reasons = append(reasons, models.CheckFlagLowAccuracy)
+		}
+	}
+
+	event.Flagged = len(reasons) > 0
+	event.FlagReasons = strings.Join(reasons, ",")
+
+	updates := map[string]interface{}{}
+	switch eventType {
+	case models.CheckEventCheckIn:
+		updates["checked_in_at"] = event.RecordedAt
+	case models.CheckEventCheckOut:
+		updates["checked_out_at"] = event.RecordedAt
+		var checkIn models.InspectionCheckEvent
+		err := db.Where("inspection_id = ? AND event_type = ?", event.InspectionID, models.CheckEventCheckIn).
+			Order("recorded_at DESC").
+			First(&checkIn).Error
+		if err == nil {
+			seconds := int(event.RecordedAt.Sub(checkIn.RecordedAt).Seconds())
+			event.TimeOnSiteSeconds = &seconds
+			updates["time_on_site_seconds"] = seconds
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("failed to get check-in: %v", err)
+		}
+	}
+	if event.Flagged {
+		updates["off_site"] = true
+	}
+
+	if err := db.Create(event).Error; err != nil {
+		return nil, fmt.Errorf("failed to record check event: %v", err)
+	}
+	if err := db.Model(&models.Inspection{}).Where("id = ?", inspection.ID).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to update inspection check summary: %v", err)
+	}
+	return event, nil
+}
+
+// GetGeofenceSettings reads the organization's geofence policy, filling in defaults
+func (s *InspectionCheckService) GetGeofenceSettings(ctx context.Context, organizationID string) (*GeofenceSettings, error) {
+	var organization models.Organization
+	if err := database.Conn(ctx, s.db).Select("id, settings").Where("id = ?", organizationID).First(&organization).Error; err != nil {
+		return nil, fmt.Errorf("failed to get organization settings: %v", err)
+	}
+
+	settings := &GeofenceSettings{}
+	if len(organization.Settings) > 0 {
+		if err := json.Unmarshal(organization.Settings, settings); err != nil {
+			return nil, fmt.Errorf("failed to parse organization settings: %v", err)
+		}
+	}
+
+	switch settings.Policy {
+	case models.GeofencePolicyOff, models.GeofencePolicyWarn, models.GeofencePolicyBlock:
+	default:
+		settings.Policy = models.GeofencePolicyWarn
+	}
+	if settings.RadiusMeters <= 0 {
+		settings.RadiusMeters = defaultGeofenceRadiusMeters
+	}
+	if settings.MaxAccuracyMeters <= 0 {
+		settings.MaxAccuracyMeters = defaultMaxAccuracyMeters
+	}
+	return settings, nil
+}
+
+// =====================================================
+// REPORTING
+// =====================================================
+
+// GetCheckEvents lists an inspection's check-ins and check-outs in order
+func (s *InspectionCheckService) GetCheckEvents(ctx context.Context, organizationID, inspectionID string) ([]models.InspectionCheckEvent, error) {
+	var events []models.InspectionCheckEvent
+	if err := database.Conn(ctx, s.db).
+		Where("organization_id = ? AND inspection_id = ?", organizationID, inspectionID).
+		Order("recorded_at ASC").
+		Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("failed to get check events: %v", err)
+	}
+	return events, nil
+}
+
+// GetOffSiteInspections lists inspections with a flagged check-in or check-out, most recent first.
+// Filters: site_id, inspector_id, and from/to (time.Time) on the check-in time.
+func (s *InspectionCheckService) GetOffSiteInspections(ctx context.Context, organizationID string, filters map[string]interface{}, page, limit int) ([]models.OffSiteInspection, int64, error) {
+	db := database.Conn(ctx, s.db)
+	query := db.Model(&models.Inspection{}).Where("organization_id = ? AND off_site = ?", organizationID, true)
+	for _, key := range []string{"site_id", "inspector_id"} {
+		if value, ok := filters[key].(string); ok && value != "" {
+			query = query.Where(fmt.Sprintf("%s = ?", key), value)
+		}
+	}
+	if from, ok := filters["from"].(time.Time); ok {
+		query = query.Where("checked_in_at >= ?", from)
+	}
+	if to, ok := filters["to"].(time.Time); ok {
+		query = query.Where("checked_in_at < ?", to)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count off-site inspections: %v", err)
+	}
+
+	var inspections []models.Inspection
+	if err := query.
+		Preload("Site").
+		Preload("Template").
+		Order("checked_in_at DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&inspections).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get off-site inspections: %v", err)
+	}
+	if len(inspections) == 0 {
+		return []models.OffSiteInspection{}, total, nil
+	}
+
+	inspectionIDs := make([]string, len(inspections))
+	for i, inspection := range inspections {
+		inspectionIDs[i] = inspection.ID.String()
+	}
+	var events []models.InspectionCheckEvent
+	if err := db.Where("inspection_id IN ? AND flagged = ?", inspectionIDs, true).
+		Order("recorded_at ASC").
+		Find(&events).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get flagged check events: %v", err)
+	}
+
+	byInspection := make(map[string][]models.InspectionCheckEvent)
+	for _, event := range events {
+		byInspection[event.InspectionID] = append(byInspection[event.InspectionID], event)
+	}
+
+	rows := make([]models.OffSiteInspection, len(inspections))
+	for i, inspection := range inspections {
+		rows[i] = models.OffSiteInspection{Inspection: inspection, Events: byInspection[inspectionIDs[i]]}
+	}
+	return rows, total, nil
+}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"context"
+	"resource-mgmt/models"
+	"resource-mgmt/pkg/repository"
+	"resource-mgmt/pkg/tenant"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Device fixes around the Trafalgar test site, which has a 150 m geofence
+var (
+	onSiteFix  = &models.DeviceLocation{Latitude: 51.5085, Longitude: -0.1285, AccuracyMeters: 12}    // ~60 m away
+	edgeFix    = &models.DeviceLocation{Latitude: 51.5097, Longitude: -0.1281, AccuracyMeters: 40}    // ~190 m away, inside once accuracy is allowed for
+	offSiteFix = &models.DeviceLocation{Latitude: 51.5308, Longitude: -0.1238, AccuracyMeters: 8}     // ~2.6 km away
+	vagueFix   = &models.DeviceLocation{Latitude: 51.5081, Longitude: -0.1282, AccuracyMeters: 450.0} // on site but imprecise
+)
+
+// checkTestFixture is an organization with the given geofence settings and a site with a
+// 150 m geofence
+type checkTestFixture struct {
+	db      *gorm.DB
+	service *InspectionCheckService
+	site    *models.Site
+}
+
+func newCheckTestFixture(t *testing.T, settings string) *checkTestFixture {
+	db := setupServiceTestDB(t, &models.Organization{}, &models.Site{}, &models.Inspection{}, &models.InspectionCheckEvent{})
+	f := &checkTestFixture{db: db, service: NewInspectionCheckService(db)}
+
+	require.NoError(t, db.Create(&models.Organization{ID: "org-a", Name: "Acme", Domain: "acme.test", Settings: datatypes.JSON(settings)}).Error)
+	lat, lng, radius := 51.5080, -0.1281, 150
+	f.site = &models.Site{ID: uuid.NewString(), OrganizationID: "org-a", Name: "Trafalgar", Address: "WC2N", Status: "active", Latitude: &lat, Longitude: &lng, GeofenceRadius: &radius}
+	require.NoError(t, db.Create(f.site).Error)
+	return f
+}
+
+func (f *checkTestFixture) newInspection(t *testing.T) *models.Inspection {
+	inspection := &models.Inspection{ID: uuid.New(), OrganizationID: "org-a", TemplateID: uuid.New(), InspectorID: "inspector-1", SiteID: f.site.ID, Status: "draft"}
+	require.NoError(t, f.db.Create(inspection).Error)
+	return inspection
+}
+
+func TestInspectionCheckService_OnSiteVisitRecordsTimeOnSite(t *testing.T) {
+	f := newCheckTestFixture(t, `{"geofence_policy":"warn"}`)
+	ctx := context.Background()
+	inspection := f.newInspection(t)
+
+	checkIn, err := f.service.CheckIn(ctx, inspection, onSiteFix)
+	require.NoError(t, err)
+	require.NotNil(t, checkIn.DistanceMeters)
+	assert.InDelta(t, 60, *checkIn.DistanceMeters, 15)
+	assert.Equal(t, 150, checkIn.GeofenceRadiusMeters)
+	assert.True(t, *checkIn.WithinGeofence)
+	assert.False(t, checkIn.Flagged)
+
+	require.NoError(t, f.db.Model(checkIn).Update("recorded_at", time.Now().Add(-45*time.Minute)).Error)
+	checkOut, err := f.service.CheckOut(ctx, inspection, edgeFix)
+	require.NoError(t, err)
+	assert.True(t, *checkOut.WithinGeofence)
+	require.NotNil(t, checkOut.TimeOnSiteSeconds)
+	assert.InDelta(t, 45*60, *checkOut.TimeOnSiteSeconds, 5)
+
+	var stored models.Inspection
+	require.NoError(t, f.db.First(&stored, "id = ?", inspection.ID).Error)
+	assert.False(t, stored.OffSite)
+	require.NotNil(t, stored.TimeOnSiteSeconds)
+}
+
+func TestInspectionCheckService_WarnPolicyFlagsChecks(t *testing.T) {
+	tests := []struct {
+		name       string
+		location   *models.DeviceLocation
+		wantWithin bool
+		wantFlags  string
+	}{
+		{"on site", onSiteFix, true, ""},
+		{"at the edge allowing for accuracy", edgeFix, true, ""},
+		{"off site", offSiteFix, false, models.CheckFlagOutsideGeofence},
+		{"low accuracy", vagueFix, true, models.CheckFlagLowAccuracy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newCheckTestFixture(t, `{"geofence_policy":"warn"}`)
+			event, err := f.service.CheckIn(context.Background(), f.newInspection(t), tt.location)
+			require.NoError(t, err)
+			require.NotNil(t, event.WithinGeofence)
+			assert.Equal(t, tt.wantWithin, *event.WithinGeofence)
+			assert.Equal(t, tt.wantFlags != "", event.Flagged)
+			assert.Equal(t, tt.wantFlags, event.FlagReasons)
+		})
+	}
+}
+
+func TestInspectionCheckService_BlockPolicyRefusesCheckIn(t *testing.T) {
+	tests := []struct {
+		name     string
+		location *models.DeviceLocation
+		wantErr  error
+	}{
+		{"off site", offSiteFix, ErrOutsideGeofence},
+		{"no location", nil, ErrDeviceLocationRequired},
+		{"invalid coordinates", &models.DeviceLocation{Latitude: 123, Longitude: 0}, ErrInvalidCoordinates},
+		{"on site", onSiteFix, nil},
+		{"at the edge allowing for accuracy", edgeFix, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newCheckTestFixture(t, `{"geofence_policy":"block","geofence_require_location":true}`)
+			ctx := context.Background()
+			inspection := f.newInspection(t)
+
+			_, err := f.service.CheckIn(ctx, inspection, tt.location)
+			events, listErr := f.service.GetCheckEvents(ctx, "org-a", inspection.ID.String())
+			require.NoError(t, listErr)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Empty(t, events, "a refused check-in is not recorded")
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, events, 1)
+		})
+	}
+}
+
+func TestInspectionCheckService_BlockPolicyNeverRefusesCheckOut(t *testing.T) {
+	f := newCheckTestFixture(t, `{"geofence_policy":"block","geofence_require_location":true}`)
+	ctx := context.Background()
+	inspection := f.newInspection(t)
+
+	event, err := f.service.CheckOut(ctx, inspection, offSiteFix)
+	require.NoError(t, err)
+	assert.True(t, event.Flagged)
+
+	events, err := f.service.GetCheckEvents(ctx, "org-a", inspection.ID.String())
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+}
+
+func TestInspectionCheckService_GetOffSiteInspections(t *testing.T) {
+	f := newCheckTestFixture(t, `{"geofence_policy":"warn"}`)
+	ctx := context.Background()
+
+	earlier := f.newInspection(t)
+	_, err := f.service.CheckIn(ctx, earlier, offSiteFix)
+	require.NoError(t, err)
+	require.NoError(t, f.db.Model(&models.Inspection{}).Where("id = ?", earlier.ID).Update("checked_in_at", time.Now().Add(-2*time.Hour)).Error)
+	flagged := f.newInspection(t)
+	_, err = f.service.CheckIn(ctx, flagged, offSiteFix)
+	require.NoError(t, err)
+	_, err = f.service.CheckIn(ctx, f.newInspection(t), onSiteFix)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		filters map[string]interface{}
+		want    []uuid.UUID
+	}{
+		{"all", map[string]interface{}{}, []uuid.UUID{flagged.ID, earlier.ID}},
+		{"from the last hour", map[string]interface{}{"from": time.Now().Add(-time.Hour)}, []uuid.UUID{flagged.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, total, err := f.service.GetOffSiteInspections(ctx, "org-a", tt.filters, 1, 20)
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(tt.want)), total)
+			var ids []uuid.UUID
+			for _, item := range report {
+				ids = append(ids, item.Inspection.ID)
+				assert.Len(t, item.Events, 1)
+			}
+			assert.ElementsMatch(t, tt.want, ids)
+		})
+	}
+}
+
+func TestInspectionService_FailedStatusChangeLeavesNoCheckEvent(t *testing.T) {
+	tests := []struct {
+		name       string
+		checkEvent string
+	}{
+		{"check-in", models.CheckEventCheckIn},
+		{"check-out", models.CheckEventCheckOut},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupServiceTestDB(t, &models.Organization{}, &models.Site{}, &models.Inspection{}, &models.InspectionCheckEvent{})
+			ctx := tenant.WithTenantContext(context.Background(), tenant.NewContext("org-a", "inspector-1", "inspector"))
+			service := NewInspectionService(repository.NewRepositoryManager(db))
+
+			require.NoError(t, db.Create(&models.Organization{ID: "org-a", Name: "Acme", Domain: "acme.test", Settings: datatypes.JSON(`{"geofence_policy":"warn"}`)}).Error)
+			lat, lng := 51.5080, -0.1281
+			site := &models.Site{ID: uuid.NewString(), OrganizationID: "org-a", Name: "Trafalgar", Address: "WC2N", Status: "active", Latitude: &lat, Longitude: &lng}
+			require.NoError(t, db.Create(site).Error)
+			inspection := &models.Inspection{ID: uuid.New(), OrganizationID: "org-a", TemplateID: uuid.New(), InspectorID: "inspector-1", SiteID: site.ID, Status: "draft"}
+			require.NoError(t, db.Create(inspection).Error)
+
+			// No inspection has this legacy numeric ID, so the status update fails after the check
+			offSite := &models.DeviceLocation{Latitude: 51.5308, Longitude: -0.1238, AccuracyMeters: 8}
+			err := service.checkAndUpdate(ctx, 42, inspection, tt.checkEvent, offSite, map[string]interface{}{"status": "in_progress"})
+			require.Error(t, err)
+
+			events := countRows(t, db.Model(&models.InspectionCheckEvent{}).Where("inspection_id = ?", inspection.ID.String()))
+			assert.Zero(t, events)
+
+			var stored models.Inspection
+			require.NoError(t, db.First(&stored, "id = ?", inspection.ID).Error)
+			assert.False(t, stored.OffSite)
+			assert.Nil(t, stored.CheckedInAt)
+			assert.Nil(t, stored.CheckedOutAt)
+		})
+	}
+}
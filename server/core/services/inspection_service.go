@@ -3,12 +3,16 @@ package services
 import (
 	"context"
 	"errors"
+	"log"
+	"resource-mgmt/config"
 	"resource-mgmt/models"
+	"resource-mgmt/pkg/database"
 	"resource-mgmt/pkg/repository"
 	"resource-mgmt/pkg/tenant"
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type InspectionService struct {
@@ -21,15 +25,31 @@ func NewInspectionService(repoManager *repository.RepositoryManager) *Inspection
 	}
 }
 
-func (s *InspectionService) GetInspections(ctx context.Context, inspectorID, status string, limit, offset int) ([]models.Inspection, int64, error) {
+// GetInspections lists inspections by inspector, team and status. When visibleTo is set, only
+// the inspections that user sees through their teams are listed.
+func (s *InspectionService) GetInspections(ctx context.Context, inspectorID, teamID, status, visibleTo string, limit, offset int) ([]models.Inspection, int64, error) {
 	filters := make(map[string]interface{})
 
 	if inspectorID != "" {
 		filters["inspector_id"] = inspectorID
 	}
+	if teamID != "" {
+		filters["team"] = TeamWorkCondition("inspector_id", teamID)
+	}
 	if status != "" {
 		filters["status"] = status
 	}
+	if visibleTo != "" {
+		organizationID, err := tenant.GetOrganizationID(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+		visible, err := VisibleInspections(ctx, config.DB, organizationID, visibleTo)
+		if err != nil {
+			return nil, 0, err
+		}
+		filters["visibility"] = visible
+	}
 
 	return s.inspectionRepo.GetAll(ctx, filters, limit, offset)
 }
@@ -38,6 +58,10 @@ func (s *InspectionService) GetInspectionByID(ctx context.Context, id uint) (*mo
 	return s.inspectionRepo.GetByID(ctx, id)
 }
 
+func (s *InspectionService) GetInspectionByUUID(ctx context.Context, id uuid.UUID) (*models.Inspection, error) {
+	return s.inspectionRepo.GetByUUID(ctx, id)
+}
+
 func (s *InspectionService) GetInspectionWithTemplateVersion(ctx context.Context, id uint) (*models.Inspection, *models.Template, error) {
 	// Get the inspection
 	inspection, err := s.inspectionRepo.GetByID(ctx, id)
@@ -75,6 +99,28 @@ func (s *InspectionService) CreateInspection(ctx context.Context, req *models.Cr
 		return nil, errors.New("template not found or inaccessible")
 	}
 
+	if req.LocationNodeID != nil && *req.LocationNodeID != "" {
+		if err := NewLocationService(config.DB).ValidateInspectionLocation(ctx, organizationID, req.SiteID, *req.LocationNodeID); err != nil {
+			return nil, err
+		}
+	} else {
+		req.LocationNodeID = nil
+	}
+
+	if req.AssetID != nil && *req.AssetID != "" {
+		if err := NewAssetService(config.DB).ValidateInspectionAsset(ctx, organizationID, req.SiteID, *req.AssetID); err != nil {
+			return nil, err
+		}
+	} else {
+		req.AssetID = nil
+	}
+
+	// The inspector has to hold the template's certifications while the work is open
+	from, to := workPeriod(req.ScheduledFor, req.DueDate)
+	if err := requireQualified(ctx, config.DB, organizationID, req.InspectorID, req.TemplateID.String(), from, to); err != nil {
+		return nil, err
+	}
+
 	inspection := &models.Inspection{
 		OrganizationID:  organizationID, // Always use tenant context
 		TemplateID:      req.TemplateID,
@@ -82,6 +128,8 @@ func (s *InspectionService) CreateInspection(ctx context.Context, req *models.Cr
 		InspectorID:     req.InspectorID,
 		AssignedBy:      req.AssignedBy,
 		SiteID:          req.SiteID,
+		LocationNodeID:  req.LocationNodeID,
+		AssetID:         req.AssetID,
 		Status:          "draft",
 		Priority:        req.Priority,
 		ScheduledFor:    req.ScheduledFor,
@@ -101,6 +149,7 @@ func (s *InspectionService) CreateInspection(ctx context.Context, req *models.Cr
 	if err != nil {
 		return nil, err
 	}
+	recordInspectionChange(ctx, nil, inspection)
 
 	// Return the created inspection with relationships loaded
 	return s.inspectionRepo.GetByUUID(ctx, inspection.ID)
@@ -108,7 +157,7 @@ func (s *InspectionService) CreateInspection(ctx context.Context, req *models.Cr
 
 func (s *InspectionService) UpdateInspection(ctx context.Context, id uint, req *models.UpdateInspectionRequest) (*models.Inspection, error) {
 	// First ensure inspection exists in tenant scope
-	_, err := s.inspectionRepo.GetByID(ctx, id)
+	existing, err := s.inspectionRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -117,6 +166,41 @@ func (s *InspectionService) UpdateInspection(ctx context.Context, id uint, req *
 
 	if req.SiteID != "" {
 		updates["site_id"] = req.SiteID
+		// A location or asset from the previous site no longer applies
+		if req.SiteID != existing.SiteID && req.LocationNodeID == nil {
+			updates["location_node_id"] = nil
+		}
+		if req.SiteID != existing.SiteID && req.AssetID == nil {
+			updates["asset_id"] = nil
+		}
+	}
+	if req.LocationNodeID != nil {
+		if *req.LocationNodeID == "" {
+			updates["location_node_id"] = nil
+		} else {
+			siteID := existing.SiteID
+			if req.SiteID != "" {
+				siteID = req.SiteID
+			}
+			if err := NewLocationService(config.DB).ValidateInspectionLocation(ctx, existing.OrganizationID, siteID, *req.LocationNodeID); err != nil {
+				return nil, err
+			}
+			updates["location_node_id"] = *req.LocationNodeID
+		}
+	}
+	if req.AssetID != nil {
+		if *req.AssetID == "" {
+			updates["asset_id"] = nil
+		} else {
+			siteID := existing.SiteID
+			if req.SiteID != "" {
+				siteID = req.SiteID
+			}
+			if err := NewAssetService(config.DB).ValidateInspectionAsset(ctx, existing.OrganizationID, siteID, *req.AssetID); err != nil {
+				return nil, err
+			}
+			updates["asset_id"] = *req.AssetID
+		}
 	}
 	if req.Status != "" {
 		updates["status"] = req.Status
@@ -141,11 +225,20 @@ func (s *InspectionService) UpdateInspection(ctx context.Context, id uint, req *
 	}
 
 	// Return the updated inspection
-	return s.inspectionRepo.GetByID(ctx, id)
+	return s.reloadAfterChange(ctx, id, existing)
 }
 
 func (s *InspectionService) DeleteInspection(ctx context.Context, id uint) error {
-	return s.inspectionRepo.Delete(ctx, id)
+	existing, err := s.inspectionRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.inspectionRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	recordInspectionChange(ctx, existing, nil)
+	return nil
 }
 
 func (s *InspectionService) SubmitInspection(ctx context.Context, id uuid.UUID, req *models.SubmitInspectionRequest) (*models.Inspection, error) {
@@ -164,7 +257,9 @@ func (s *InspectionService) GetInspectionStats(ctx context.Context) (map[string]
 	return s.inspectionRepo.GetInspectionStats(ctx)
 }
 
-func (s *InspectionService) StartInspection(ctx context.Context, id uint) (*models.Inspection, error) {
+// StartInspection moves a draft inspection to in progress and checks the inspector in.
+// location may be nil when the device has no GPS fix.
+func (s *InspectionService) StartInspection(ctx context.Context, id uint, location *models.DeviceLocation) (*models.Inspection, error) {
 	inspection, err := s.inspectionRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
@@ -180,15 +275,15 @@ func (s *InspectionService) StartInspection(ctx context.Context, id uint) (*mode
 		"started_at": &now,
 	}
 
-	err = s.inspectionRepo.Update(ctx, id, updates)
-	if err != nil {
+	if err := s.checkAndUpdate(ctx, id, inspection, models.CheckEventCheckIn, location, updates); err != nil {
 		return nil, err
 	}
 
-	return s.GetInspectionByID(ctx, id)
+	return s.reloadAfterChange(ctx, id, inspection)
 }
 
-func (s *InspectionService) CompleteInspection(ctx context.Context, id uint) (*models.Inspection, error) {
+// CompleteInspection marks an inspection completed and checks the inspector out
+func (s *InspectionService) CompleteInspection(ctx context.Context, id uint, location *models.DeviceLocation) (*models.Inspection, error) {
 	inspection, err := s.inspectionRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
@@ -208,12 +303,11 @@ func (s *InspectionService) CompleteInspection(ctx context.Context, id uint) (*m
 		updates["started_at"] = &now
 	}
 
-	err = s.inspectionRepo.Update(ctx, id, updates)
-	if err != nil {
+	if err := s.checkAndUpdate(ctx, id, inspection, models.CheckEventCheckOut, location, updates); err != nil {
 		return nil, err
 	}
 
-	return s.GetInspectionByID(ctx, id)
+	return s.reloadAfterChange(ctx, id, inspection)
 }
 
 func (s *InspectionService) GetOverdueInspections(ctx context.Context, limit, offset int) ([]models.Inspection, int64, error) {
@@ -226,7 +320,7 @@ func (s *InspectionService) GetInspectionsByTemplate(ctx context.Context, templa
 
 func (s *InspectionService) AssignInspection(ctx context.Context, id uint, req *models.AssignInspectionRequest) (*models.Inspection, error) {
 	// First ensure inspection exists in tenant scope
-	_, err := s.inspectionRepo.GetByID(ctx, id)
+	inspection, err := s.inspectionRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -258,7 +352,7 @@ func (s *InspectionService) AssignInspection(ctx context.Context, id uint, req *
 		return nil, err
 	}
 
-	return s.GetInspectionByID(ctx, id)
+	return s.reloadAfterChange(ctx, id, inspection)
 }
 
 func (s *InspectionService) UpdateInspectionStatus(ctx context.Context, id uint, req *models.UpdateInspectionStatusRequest) (*models.Inspection, error) {
@@ -272,6 +366,15 @@ func (s *InspectionService) UpdateInspectionStatus(ctx context.Context, id uint,
 		return nil, errors.New("invalid status transition")
 	}
 
+	// Starting and completing check the inspector in and out against the site geofence
+	checkEvent := ""
+	switch req.Status {
+	case "in_progress":
+		checkEvent = models.CheckEventCheckIn
+	case "completed":
+		checkEvent = models.CheckEventCheckOut
+	}
+
 	updates := map[string]interface{}{
 		"status": req.Status,
 	}
@@ -297,12 +400,71 @@ func (s *InspectionService) UpdateInspectionStatus(ctx context.Context, id uint,
 		}
 	}
 
-	err = s.inspectionRepo.Update(ctx, id, updates)
+	if err := s.checkAndUpdate(ctx, id, inspection, checkEvent, req.Location, updates); err != nil {
+		return nil, err
+	}
+
+	return s.reloadAfterChange(ctx, id, inspection)
+}
+
+// checkAndUpdate records the check-in or check-out, if any, and applies updates in one
+// transaction, so a failed status change leaves no check event or off-site flag behind
+func (s *InspectionService) checkAndUpdate(ctx context.Context, id uint, inspection *models.Inspection, checkEvent string, location *models.DeviceLocation, updates map[string]interface{}) error {
+	return database.Conn(ctx, config.DB).Transaction(func(tx *gorm.DB) error {
+		txCtx := database.ContextWithTx(ctx, tx)
+		checks := NewInspectionCheckService(config.DB)
+
+		var err error
+		switch checkEvent {
+		case models.CheckEventCheckIn:
+			_, err = checks.CheckIn(txCtx, inspection, location)
+		case models.CheckEventCheckOut:
+			_, err = checks.CheckOut(txCtx, inspection, location)
+		}
+		if err != nil {
+			return err
+		}
+
+		return s.inspectionRepo.Update(txCtx, id, updates)
+	})
+}
+
+// reloadAfterChange returns the inspection as saved and brings the workloads of its
+// previous and current inspector and its SLA clocks up to date with the change
+func (s *InspectionService) reloadAfterChange(ctx context.Context, id uint, before *models.Inspection) (*models.Inspection, error) {
+	after, err := s.inspectionRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
+	recordInspectionChange(ctx, before, after)
+	return after, nil
+}
 
-	return s.GetInspectionByID(ctx, id)
+// recordInspectionChange updates inspector workloads, SLA clocks, assignment history and
+// qualification records after an inspection changed. The change is already saved, so a failure is logged and
+// left to the nightly reconciliation. Each update runs in a savepoint, so a failing one
+// doesn't abort the request transaction the change is part of.
+func recordInspectionChange(ctx context.Context, before, after *models.Inspection) {
+	if err := NewWorkloadMetricsService(config.DB).InspectionChanged(ctx, before, after); err != nil {
+		log.Printf("Failed to update inspector workload metrics: %v", err)
+	}
+
+	changed := after
+	if changed == nil {
+		changed = before
+	}
+	if changed != nil {
+		err := database.Conn(ctx, config.DB).Transaction(func(tx *gorm.DB) error {
+			return NewSLAService(tx, NewNotificationService()).InspectionChanged(database.ContextWithTx(ctx, tx), changed)
+		})
+		if err != nil {
+			log.Printf("Failed to update SLA clocks for inspection %s: %v", changed.ID, err)
+		}
+	}
+	if err := recordInspectionEvents(ctx, config.DB, before, after); err != nil {
+		log.Printf("Failed to record assignment events for inspection %s: %v", changed.ID, err)
+	}
+	recordInspectionQualifications(ctx, config.DB, before, after)
 }
 
 func (s *InspectionService) isValidStatusTransition(currentStatus, newStatus string) bool {
@@ -0,0 +1,590 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"resource-mgmt/models"
+	"resource-mgmt/pkg/database"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrLocationNotFound is returned when a location node doesn't exist in the organization
+	ErrLocationNotFound = errors.New("location not found")
+	// ErrLocationNotInSite is returned when a location belongs to a different site than expected
+	ErrLocationNotInSite = errors.New("location does not belong to this site")
+	// ErrLocationNameRequired is returned when a location has no name
+	ErrLocationNameRequired = errors.New("location name is required")
+	// ErrInvalidLocationType is returned for node types outside models.ValidLocationTypes
+	ErrInvalidLocationType = errors.New("invalid location type")
+	// ErrLocationCycle is returned when a node would be moved under itself or one of its descendants
+	ErrLocationCycle = errors.New("cannot move a location under itself or one of its descendants")
+	// ErrLocationHasChildren is returned when deleting a node that still has child locations
+	ErrLocationHasChildren = errors.New("cannot delete a location that has child locations")
+	// ErrLocationHasActiveInspections is returned when deleting a node with open inspections
+	ErrLocationHasActiveInspections = errors.New("cannot delete a location with active inspections")
+)
+
+// completedInspectionStatuses and pendingInspectionStatuses match the buckets used by site statistics.
+// closedInspectionStatuses are the statuses in which an inspection no longer needs doing.
+var (
+	completedInspectionStatuses = []string{"completed", "approved"}
+	pendingInspectionStatuses   = []string{"draft", "in_progress", "requires_review"}
+	closedInspectionStatuses    = []string{"completed", "approved", "rejected", "cancelled"}
+)
+
+// LocationService manages the location hierarchy under sites
+type LocationService struct {
+	db *gorm.DB
+}
+
+func NewLocationService(db *gorm.DB) *LocationService {
+	return &LocationService{db: db}
+}
+
+// =====================================================
+// NODES
+// =====================================================
+
+// GetNode retrieves a single location node
+func (s *LocationService) GetNode(ctx context.Context, organizationID, nodeID string) (*models.LocationNode, error) {
+	return s.getNode(database.Conn(ctx, s.db), organizationID, nodeID)
+}
+
+func (s *LocationService) getNode(tx *gorm.DB, organizationID, nodeID string) (*models.LocationNode, error) {
+	var node models.LocationNode
+	if err := tx.Where("id = ? AND organization_id = ?", nodeID, organizationID).First(&node).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLocationNotFound
+		}
+		return nil, fmt.Errorf("failed to get location: %v", err)
+	}
+	return &node, nil
+}
+
+// CreateNode adds a node to a site, under node.ParentID or at the top level
+func (s *LocationService) CreateNode(ctx context.Context, node *models.LocationNode) (*models.LocationNode, error) {
+	node.Name = strings.TrimSpace(node.Name)
+	if node.Name == "" {
+		return nil, ErrLocationNameRequired
+	}
+	if !isValidLocationType(node.Type) {
+		return nil, ErrInvalidLocationType
+	}
+
+	db := database.Conn(ctx, s.db)
+
+	var siteCount int64
+	if err := db.Model(&models.Site{}).
+		Where("id = ? AND organization_id = ?", node.SiteID, node.OrganizationID).
+		Count(&siteCount).Error; err != nil {
+		return nil, fmt.Errorf("failed to verify site: %v", err)
+	}
+	if siteCount == 0 {
+		return nil, errors.New("site not found")
+	}
+
+	// The ID is part of the path, so it is assigned up front
+	if node.ID == "" {
+		node.ID = uuid.NewString()
+	}
+	node.Path = node.ID + "/"
+	node.Depth = 0
+
+	if node.ParentID != nil && *node.ParentID != "" {
+		parent, err := s.getNode(db, node.OrganizationID, *node.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		if parent.SiteID != node.SiteID {
+			return nil, ErrLocationNotInSite
+		}
+		node.Path = parent.Path + node.ID + "/"
+		node.Depth = parent.Depth + 1
+	} else {
+		node.ParentID = nil
+	}
+
+	if err := db.Create(node).Error; err != nil {
+		return nil, fmt.Errorf("failed to create location: %v", err)
+	}
+
+	return s.getNode(db, node.OrganizationID, node.ID)
+}
+
+// UpdateNode updates the descriptive fields of a node. The parent is changed through MoveNode.
+func (s *LocationService) UpdateNode(ctx context.Context, organizationID, nodeID string, updates map[string]interface{}) (*models.LocationNode, error) {
+	db := database.Conn(ctx, s.db)
+
+	node, err := s.getNode(db, organizationID, nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := map[string]bool{"name": true, "type": true, "code": true, "sort_order": true, "metadata": true, "updated_by": true}
+	filtered := make(map[string]interface{})
+	for key, value := range updates {
+		if allowed[key] {
+			filtered[key] = value
+		}
+	}
+
+	if name, ok := filtered["name"].(string); ok && strings.TrimSpace(name) == "" {
+		return nil, ErrLocationNameRequired
+	}
+	if metadata, ok := filtered["metadata"]; ok {
+		encoded, err := json.Marshal(metadata)
+		if err != nil {
+			return nil, fmt.Errorf("invalid metadata: %v", err)
+		}
+		filtered["metadata"] = datatypes.JSON(encoded)
+	}
+	if nodeType, ok := filtered["type"]; ok {
+		if t, isString := nodeType.(string); !isString || !isValidLocationType(t) {
+			return nil, ErrInvalidLocationType
+		}
+	}
+
+	if len(filtered) > 0 {
+		if err := db.Model(node).Updates(filtered).Error; err != nil {
+			return nil, fmt.Errorf("failed to update location: %v", err)
+		}
+	}
+
+	return s.getNode(db, organizationID, nodeID)
+}
+
+// DeleteNode soft deletes a leaf node without open inspections. Past inspections keep
+// their reference to it.
+func (s *LocationService) DeleteNode(ctx context.Context, organizationID, nodeID string) error {
+	db := database.Conn(ctx, s.db)
+
+	node, err := s.getNode(db, organizationID, nodeID)
+	if err != nil {
+		return err
+	}
+
+	var childCount int64
+	if err := db.Model(&models.LocationNode{}).
+		Where("organization_id = ? AND parent_id = ?", organizationID, node.ID).
+		Count(&childCount).Error; err != nil {
+		return fmt.Errorf("failed to check child locations: %v", err)
+	}
+	if childCount > 0 {
+		return ErrLocationHasChildren
+	}
+
+	var activeCount int64
+	if err := db.Model(&models.Inspection{}).
+		Where("organization_id = ? AND location_node_id = ? AND status NOT IN ?", organizationID, node.ID, closedInspectionStatuses).
+		Count(&activeCount).Error; err != nil {
+		return fmt.Errorf("failed to check location inspections: %v", err)
+	}
+	if activeCount > 0 {
+		return ErrLocationHasActiveInspections
+	}
+
+	if err := db.Delete(node).Error; err != nil {
+		return fmt.Errorf("failed to delete location: %v", err)
+	}
+	return nil
+}
+
+// MoveNode moves a node and its whole subtree under a new parent within the same site,
+// or to the top level when newParentID is nil. Node IDs don't change, so inspections
+// recorded against the subtree stay attached to it.
+func (s *LocationService) MoveNode(ctx context.Context, organizationID, nodeID string, newParentID *string, movedBy string) (*models.LocationNode, error) {
+	if newParentID != nil && *newParentID == "" {
+		newParentID = nil
+	}
+
+	err := database.Conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		node, err := s.getNode(tx, organizationID, nodeID)
+		if err != nil {
+			return err
+		}
+
+		newPath := node.ID + "/"
+		newDepth := 0
+		if newParentID != nil {
+			parent, err := s.getNode(tx, organizationID, *newParentID)
+			if err != nil {
+				return err
+			}
+			if parent.SiteID != node.SiteID {
+				return ErrLocationNotInSite
+			}
+			if strings.HasPrefix(parent.Path, node.Path) {
+				return ErrLocationCycle
+			}
+			newPath = parent.Path + node.ID + "/"
+			newDepth = parent.Depth + 1
+		}
+
+		if newPath == node.Path {
+			return nil
+		}
+
+		// Rewrite the path prefix and depth of the node and all of its descendants
+		if err := tx.Model(&models.LocationNode{}).
+			Where("organization_id = ? AND path LIKE ?", organizationID, node.Path+"%").
+			Updates(map[string]interface{}{
+				"path":       gorm.Expr("? || SUBSTR(path, ?)", newPath, len(node.Path)+1),
+				"depth":      gorm.Expr("depth + ?", newDepth-node.Depth),
+				"updated_at": time.Now(),
+			}).Error; err != nil {
+			return fmt.Errorf("failed to move location subtree: %v", err)
+		}
+
+		if err := tx.Model(&models.LocationNode{}).
+			Where("id = ?", node.ID).
+			Updates(map[string]interface{}{"parent_id": newParentID, "updated_by": movedBy}).Error; err != nil {
+			return fmt.Errorf("failed to update location parent: %v", err)
+		}
+
+		move := &models.LocationNodeMove{
+			OrganizationID: organizationID,
+			NodeID:         node.ID,
+			SiteID:         node.SiteID,
+			FromParentID:   node.ParentID,
+			ToParentID:     newParentID,
+			FromPath:       node.Path,
+			ToPath:         newPath,
+			MovedBy:        movedBy,
+			MovedAt:        time.Now(),
+		}
+		if err := tx.Create(move).Error; err != nil {
+			return fmt.Errorf("failed to record location move: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetNode(ctx, organizationID, nodeID)
+}
+
+// GetMoveHistory lists the moves of a node and of its current ancestors, newest first
+func (s *LocationService) GetMoveHistory(ctx context.Context, organizationID, nodeID string) ([]models.LocationNodeMove, error) {
+	db := database.Conn(ctx, s.db)
+
+	node, err := s.getNode(db, organizationID, nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	var moves []models.LocationNodeMove
+	if err := db.Where("organization_id = ? AND node_id IN ?", organizationID, append(node.AncestorIDs(), node.ID)).
+		Order("moved_at DESC").
+		Find(&moves).Error; err != nil {
+		return nil, fmt.Errorf("failed to get location moves: %v", err)
+	}
+	return moves, nil
+}
+
+// =====================================================
+// TREE QUERIES
+// =====================================================
+
+// GetSiteTree returns the top-level locations of a site with their descendants nested
+func (s *LocationService) GetSiteTree(ctx context.Context, organizationID, siteID string) ([]models.LocationNode, error) {
+	var nodes []models.LocationNode
+	if err := database.Conn(ctx, s.db).
+		Where("organization_id = ? AND site_id = ?", organizationID, siteID).
+		Order("depth ASC, sort_order ASC, name ASC").
+		Find(&nodes).Error; err != nil {
+		return nil, fmt.Errorf("failed to get site locations: %v", err)
+	}
+	return buildLocationTree(nodes, nil), nil
+}
+
+// GetSubtree returns a node with all of its descendants nested
+func (s *LocationService) GetSubtree(ctx context.Context, organizationID, nodeID string) (*models.LocationNode, error) {
+	db := database.Conn(ctx, s.db)
+
+	root, err := s.getNode(db, organizationID, nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []models.LocationNode
+	if err := db.Where("organization_id = ? AND path LIKE ? AND id <> ?", organizationID, root.Path+"%", root.ID).
+		Order("depth ASC, sort_order ASC, name ASC").
+		Find(&nodes).Error; err != nil {
+		return nil, fmt.Errorf("failed to get location subtree: %v", err)
+	}
+
+	root.Children = buildLocationTree(nodes, &root.ID)
+	return root, nil
+}
+
+// GetAncestors returns the ancestors of a node, root first
+func (s *LocationService) GetAncestors(ctx context.Context, organizationID, nodeID string) ([]models.LocationNode, error) {
+	db := database.Conn(ctx, s.db)
+
+	node, err := s.getNode(db, organizationID, nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	ancestorIDs := node.AncestorIDs()
+	if len(ancestorIDs) == 0 {
+		return []models.LocationNode{}, nil
+	}
+
+	var ancestors []models.LocationNode
+	if err := db.Unscoped().
+		Where("organization_id = ? AND id IN ?", organizationID, ancestorIDs).
+		Order("depth ASC").
+		Find(&ancestors).Error; err != nil {
+		return nil, fmt.Errorf("failed to get location ancestors: %v", err)
+	}
+	return ancestors, nil
+}
+
+// GetBreadcrumb returns the path from the site down to the node, e.g.
+// Site > Region > Campus > Building > Floor > Room
+func (s *LocationService) GetBreadcrumb(ctx context.Context, organizationID, nodeID string) ([]models.LocationBreadcrumb, error) {
+	node, err := s.GetNode(ctx, organizationID, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	ancestors, err := s.GetAncestors(ctx, organizationID, nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	var site models.Site
+	if err := database.Conn(ctx, s.db).Unscoped().
+		Where("id = ? AND organization_id = ?", node.SiteID, organizationID).
+		First(&site).Error; err != nil {
+		return nil, fmt.Errorf("failed to get site: %v", err)
+	}
+
+	breadcrumb := []models.LocationBreadcrumb{{ID: site.ID, Name: site.Name, Type: "site"}}
+	for _, ancestor := range append(ancestors, *node) {
+		breadcrumb = append(breadcrumb, models.LocationBreadcrumb{ID: ancestor.ID, Name: ancestor.Name, Type: ancestor.Type})
+	}
+	return breadcrumb, nil
+}
+
+// SubtreeIDsQuery returns a subquery selecting the IDs of a node and all of its descendants
+func (s *LocationService) SubtreeIDsQuery(ctx context.Context, organizationID, nodeID string) (*gorm.DB, error) {
+	db := database.Conn(ctx, s.db)
+
+	node, err := s.getNode(db, organizationID, nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.Unscoped().Model(&models.LocationNode{}).
+		Select("id").
+		Where("organization_id = ? AND path LIKE ?", organizationID, node.Path+"%"), nil
+}
+
+// ValidateInspectionLocation checks that a location belongs to the inspection's site
+func (s *LocationService) ValidateInspectionLocation(ctx context.Context, organizationID, siteID, nodeID string) error {
+	node, err := s.GetNode(ctx, organizationID, nodeID)
+	if err != nil {
+		return err
+	}
+	if node.SiteID != siteID {
+		return ErrLocationNotInSite
+	}
+	return nil
+}
+
+// GetLocationInspections retrieves inspections recorded anywhere in a node's subtree
+func (s *LocationService) GetLocationInspections(ctx context.Context, organizationID, nodeID string, page, limit int) ([]models.Inspection, int64, error) {
+	subtree, err := s.SubtreeIDsQuery(ctx, organizationID, nodeID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var inspections []models.Inspection
+	var total int64
+
+	query := database.Conn(ctx, s.db).Model(&models.Inspection{}).
+		Where("organization_id = ? AND location_node_id IN (?)", organizationID, subtree)
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count location inspections: %v", err)
+	}
+
+	offset := (page - 1) * limit
+	if err := query.
+		Preload("Template").
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&inspections).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get location inspections: %v", err)
+	}
+
+	return inspections, total, nil
+}
+
+// =====================================================
+// ROLL-UP STATISTICS
+// =====================================================
+
+// GetLocationStats returns inspection counts for a node, rolled up over its subtree,
+// with the same breakdown for each descendant
+func (s *LocationService) GetLocationStats(ctx context.Context, organizationID, nodeID string) (*models.LocationStats, error) {
+	root, err := s.GetSubtree(ctx, organizationID, nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := s.countInspectionsByNode(ctx, organizationID, "location_node_id IN (?)", collectLocationIDs(*root))
+	if err != nil {
+		return nil, err
+	}
+
+	stats := rollUpLocationStats(*root, counts)
+	return &stats, nil
+}
+
+// GetSiteLocationStats returns rolled-up inspection counts for each top-level location of a site
+func (s *LocationService) GetSiteLocationStats(ctx context.Context, organizationID, siteID string) ([]models.LocationStats, error) {
+	tree, err := s.GetSiteTree(ctx, organizationID, siteID)
+	if err != nil {
+		return nil, err
+	}
+	if len(tree) == 0 {
+		return []models.LocationStats{}, nil
+	}
+
+	counts, err := s.countInspectionsByNode(ctx, organizationID, "site_id = ? AND location_node_id IS NOT NULL", siteID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]models.LocationStats, 0, len(tree))
+	for _, node := range tree {
+		stats = append(stats, rollUpLocationStats(node, counts))
+	}
+	return stats, nil
+}
+
+// countInspectionsByNode counts inspections per location node, without rolling up
+func (s *LocationService) countInspectionsByNode(ctx context.Context, organizationID, condition string, args ...interface{}) (map[string]models.LocationStats, error) {
+	var rows []struct {
+		LocationNodeID string
+		Total          int
+		Completed      int
+		Pending        int
+		Overdue        int
+	}
+
+	if err := database.Conn(ctx, s.db).Model(&models.Inspection{}).
+		Select(`location_node_id,
+			COUNT(*) AS total,
+			SUM(CASE WHEN status IN ? THEN 1 ELSE 0 END) AS completed,
+			SUM(CASE WHEN status IN ? THEN 1 ELSE 0 END) AS pending,
+			SUM(CASE WHEN due_date < ? AND status NOT IN ? THEN 1 ELSE 0 END) AS overdue`,
+			completedInspectionStatuses, pendingInspectionStatuses, time.Now(), completedInspectionStatuses).
+		Where("organization_id = ?", organizationID).
+		Where(condition, args...).
+		Group("location_node_id").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to count location inspections: %v", err)
+	}
+
+	counts := make(map[string]models.LocationStats, len(rows))
+	for _, row := range rows {
+		counts[row.LocationNodeID] = models.LocationStats{
+			TotalInspections:     row.Total,
+			CompletedInspections: row.Completed,
+			PendingInspections:   row.Pending,
+			OverdueInspections:   row.Overdue,
+		}
+	}
+	return counts, nil
+}
+
+// =====================================================
+// HELPERS
+// =====================================================
+
+func isValidLocationType(nodeType string) bool {
+	for _, valid := range models.ValidLocationTypes {
+		if nodeType == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// buildLocationTree nests nodes under their parents and returns the children of rootID
+// (the top-level nodes when rootID is nil). Nodes must be ordered by depth.
+func buildLocationTree(nodes []models.LocationNode, rootID *string) []models.LocationNode {
+	childrenOf := make(map[string][]models.LocationNode)
+	for _, node := range nodes {
+		parent := ""
+		if node.ParentID != nil {
+			parent = *node.ParentID
+		}
+		childrenOf[parent] = append(childrenOf[parent], node)
+	}
+
+	var attach func(parentID string) []models.LocationNode
+	attach = func(parentID string) []models.LocationNode {
+		children := childrenOf[parentID]
+		for i := range children {
+			children[i].Children = attach(children[i].ID)
+		}
+		sort.SliceStable(children, func(a, b int) bool {
+			if children[a].SortOrder != children[b].SortOrder {
+				return children[a].SortOrder < children[b].SortOrder
+			}
+			return children[a].Name < children[b].Name
+		})
+		return children
+	}
+
+	root := ""
+	if rootID != nil {
+		root = *rootID
+	}
+	tree := attach(root)
+	if tree == nil {
+		return []models.LocationNode{}
+	}
+	return tree
+}
+
+func collectLocationIDs(node models.LocationNode) []string {
+	ids := []string{node.ID}
+	for _, child := range node.Children {
+		ids = append(ids, collectLocationIDs(child)...)
+	}
+	return ids
+}
+
+// rollUpLocationStats adds each node's own counts to those of its descendants
+func rollUpLocationStats(node models.LocationNode, counts map[string]models.LocationStats) models.LocationStats {
+	stats := counts[node.ID]
+	stats.NodeID = node.ID
+	stats.Name = node.Name
+	stats.Type = node.Type
+
+	for _, child := range node.Children {
+		childStats := rollUpLocationStats(child, counts)
+		stats.TotalInspections += childStats.TotalInspections
+		stats.CompletedInspections += childStats.CompletedInspections
+		stats.PendingInspections += childStats.PendingInspections
+		stats.OverdueInspections += childStats.OverdueInspections
+		stats.Children = append(stats.Children, childStats)
+	}
+	return stats
+}
@@ -0,0 +1,281 @@
+package services
+
+import (
+	"context"
+	"resource-mgmt/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func createTestLocation(t *testing.T, service *LocationService, siteID, name, nodeType string, parent *models.LocationNode) *models.LocationNode {
+	node := &models.LocationNode{OrganizationID: "org-a", SiteID: siteID, Name: name, Type: nodeType}
+	if parent != nil {
+		node.ParentID = &parent.ID
+	}
+	created, err := service.CreateNode(context.Background(), node)
+	require.NoError(t, err)
+	return created
+}
+
+func createTestInspection(t *testing.T, db *gorm.DB, siteID string, node *models.LocationNode, status string, dueDate *time.Time) {
+	inspection := &models.Inspection{
+		OrganizationID: "org-a",
+		TemplateID:     uuid.New(),
+		InspectorID:    "inspector-1",
+		SiteID:         siteID,
+		LocationNodeID: &node.ID,
+		Status:         status,
+		DueDate:        dueDate,
+	}
+	require.NoError(t, db.Create(inspection).Error)
+}
+
+// locationTestTree is a site with a region > campus > buildings A and B tree, where
+// building A holds floor 2 and room 201, and a second site of the same organization
+type locationTestTree struct {
+	db                   *gorm.DB
+	service              *LocationService
+	site, otherSite      *models.Site
+	region, campus       *models.LocationNode
+	buildingA, buildingB *models.LocationNode
+	floor, room          *models.LocationNode
+}
+
+func newLocationTestTree(t *testing.T) *locationTestTree {
+	db := setupServiceTestDB(t, &models.Site{}, &models.LocationNode{}, &models.LocationNodeMove{}, &models.Inspection{})
+	tree := &locationTestTree{db: db, service: NewLocationService(db)}
+
+	tree.site = createTestSite(t, db, "org-a", "Northern Portfolio", "1 Main St")
+	tree.otherSite = createTestSite(t, db, "org-a", "Southern Portfolio", "2 Main St")
+
+	siteID := tree.site.ID
+	tree.region = createTestLocation(t, tree.service, siteID, "North Region", models.LocationTypeRegion, nil)
+	tree.campus = createTestLocation(t, tree.service, siteID, "Riverside Campus", models.LocationTypeCampus, tree.region)
+	tree.buildingA = createTestLocation(t, tree.service, siteID, "Building A", models.LocationTypeBuilding, tree.campus)
+	tree.buildingB = createTestLocation(t, tree.service, siteID, "Building B", models.LocationTypeBuilding, tree.campus)
+	tree.floor = createTestLocation(t, tree.service, siteID, "Floor 2", models.LocationTypeFloor, tree.buildingA)
+	tree.room = createTestLocation(t, tree.service, siteID, "Room 201", models.LocationTypeRoom, tree.floor)
+	return tree
+}
+
+// addInspections puts a completed inspection in the room, an overdue one on the floor and
+// a draft in building B
+func (tree *locationTestTree) addInspections(t *testing.T) {
+	past := time.Now().Add(-48 * time.Hour)
+	createTestInspection(t, tree.db, tree.site.ID, tree.room, "completed", nil)
+	createTestInspection(t, tree.db, tree.site.ID, tree.floor, "in_progress", &past)
+	createTestInspection(t, tree.db, tree.site.ID, tree.buildingB, "draft", nil)
+}
+
+func TestLocationService_CreateNodeMaterializesPath(t *testing.T) {
+	tree := newLocationTestTree(t)
+
+	assert.Equal(t, tree.region.ID+"/"+tree.campus.ID+"/"+tree.buildingA.ID+"/"+tree.floor.ID+"/"+tree.room.ID+"/", tree.room.Path)
+	assert.Equal(t, 4, tree.room.Depth)
+}
+
+func TestLocationService_CreateNodeValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		node    func(tree *locationTestTree) *models.LocationNode
+		wantErr error
+	}{
+		{
+			name: "parent in another site",
+			node: func(tree *locationTestTree) *models.LocationNode {
+				return &models.LocationNode{OrganizationID: "org-a", SiteID: tree.otherSite.ID, Name: "Stray", Type: models.LocationTypeRoom, ParentID: &tree.floor.ID}
+			},
+			wantErr: ErrLocationNotInSite,
+		},
+		{
+			name: "unknown type",
+			node: func(tree *locationTestTree) *models.LocationNode {
+				return &models.LocationNode{OrganizationID: "org-a", SiteID: tree.site.ID, Name: "Cellar", Type: "basement"}
+			},
+			wantErr: ErrInvalidLocationType,
+		},
+		{
+			name: "missing name",
+			node: func(tree *locationTestTree) *models.LocationNode {
+				return &models.LocationNode{OrganizationID: "org-a", SiteID: tree.site.ID, Type: models.LocationTypeRoom}
+			},
+			wantErr: ErrLocationNameRequired,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := newLocationTestTree(t)
+			_, err := tree.service.CreateNode(context.Background(), tt.node(tree))
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestLocationService_TreeQueries(t *testing.T) {
+	tree := newLocationTestTree(t)
+	ctx := context.Background()
+
+	nodes, err := tree.service.GetSiteTree(ctx, "org-a", tree.site.ID)
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+	require.Len(t, nodes[0].Children, 1)
+	require.Len(t, nodes[0].Children[0].Children, 2)
+	assert.Equal(t, "Building A", nodes[0].Children[0].Children[0].Name)
+	assert.Equal(t, "Room 201", nodes[0].Children[0].Children[0].Children[0].Children[0].Name)
+
+	ancestors, err := tree.service.GetAncestors(ctx, "org-a", tree.room.ID)
+	require.NoError(t, err)
+	require.Len(t, ancestors, 4)
+	assert.Equal(t, tree.region.ID, ancestors[0].ID)
+	assert.Equal(t, tree.floor.ID, ancestors[3].ID)
+
+	breadcrumb, err := tree.service.GetBreadcrumb(ctx, "org-a", tree.room.ID)
+	require.NoError(t, err)
+	require.Len(t, breadcrumb, 6)
+	assert.Equal(t, models.LocationBreadcrumb{ID: tree.site.ID, Name: "Northern Portfolio", Type: "site"}, breadcrumb[0])
+	assert.Equal(t, "Room 201", breadcrumb[5].Name)
+}
+
+func TestLocationService_StatsRollUpTheTree(t *testing.T) {
+	tree := newLocationTestTree(t)
+	tree.addInspections(t)
+
+	stats, err := tree.service.GetLocationStats(context.Background(), "org-a", tree.campus.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.TotalInspections)
+	assert.Equal(t, 1, stats.CompletedInspections)
+	assert.Equal(t, 2, stats.PendingInspections)
+	assert.Equal(t, 1, stats.OverdueInspections)
+	require.Len(t, stats.Children, 2)
+	assert.Equal(t, 2, stats.Children[0].TotalInspections, "Building A includes its floor and room")
+}
+
+func TestLocationService_MoveNodeRejectsCycles(t *testing.T) {
+	tests := []struct {
+		name   string
+		node   func(tree *locationTestTree) string
+		parent func(tree *locationTestTree) string
+	}{
+		{"under itself", func(tree *locationTestTree) string { return tree.floor.ID }, func(tree *locationTestTree) string { return tree.floor.ID }},
+		{"under its child", func(tree *locationTestTree) string { return tree.floor.ID }, func(tree *locationTestTree) string { return tree.room.ID }},
+		{"under a deeper descendant", func(tree *locationTestTree) string { return tree.buildingA.ID }, func(tree *locationTestTree) string { return tree.room.ID }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := newLocationTestTree(t)
+			parentID := tt.parent(tree)
+			_, err := tree.service.MoveNode(context.Background(), "org-a", tt.node(tree), &parentID, "admin-1")
+			assert.ErrorIs(t, err, ErrLocationCycle)
+
+			unmoved, err := tree.service.GetNode(context.Background(), "org-a", tree.room.ID)
+			require.NoError(t, err)
+			assert.Equal(t, tree.room.Path, unmoved.Path)
+		})
+	}
+}
+
+func TestLocationService_MoveNodeCarriesSubtreeAndHistory(t *testing.T) {
+	tree := newLocationTestTree(t)
+	tree.addInspections(t)
+	ctx := context.Background()
+
+	moved, err := tree.service.MoveNode(ctx, "org-a", tree.floor.ID, &tree.buildingB.ID, "admin-1")
+	require.NoError(t, err)
+	assert.Equal(t, tree.buildingB.ID, *moved.ParentID)
+
+	movedRoom, err := tree.service.GetNode(ctx, "org-a", tree.room.ID)
+	require.NoError(t, err)
+	assert.Equal(t, tree.region.ID+"/"+tree.campus.ID+"/"+tree.buildingB.ID+"/"+tree.floor.ID+"/"+tree.room.ID+"/", movedRoom.Path)
+	assert.Equal(t, 4, movedRoom.Depth)
+
+	siteStats, err := tree.service.GetSiteLocationStats(ctx, "org-a", tree.site.ID)
+	require.NoError(t, err)
+	require.Len(t, siteStats, 1)
+	assert.Equal(t, 3, siteStats[0].TotalInspections)
+	campusStats := siteStats[0].Children[0]
+	assert.Equal(t, 0, campusStats.Children[0].TotalInspections, "Building A no longer holds the floor")
+	assert.Equal(t, 3, campusStats.Children[1].TotalInspections)
+
+	inspections, total, err := tree.service.GetLocationInspections(ctx, "org-a", tree.buildingB.ID, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	assert.Len(t, inspections, 3)
+
+	moves, err := tree.service.GetMoveHistory(ctx, "org-a", tree.room.ID)
+	require.NoError(t, err)
+	require.Len(t, moves, 1)
+	assert.Equal(t, tree.floor.ID, moves[0].NodeID)
+	assert.Equal(t, tree.buildingA.ID, *moves[0].FromParentID)
+}
+
+func TestLocationService_DeleteNodeWithChildren(t *testing.T) {
+	tests := []struct {
+		name    string
+		node    func(tree *locationTestTree) string
+		wantErr error
+	}{
+		{"node with children", func(tree *locationTestTree) string { return tree.floor.ID }, ErrLocationHasChildren},
+		{"leaf", func(tree *locationTestTree) string { return tree.buildingB.ID }, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := newLocationTestTree(t)
+			err := tree.service.DeleteNode(context.Background(), "org-a", tt.node(tree))
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestLocationService_OtherOrganizationsCannotSeeTree(t *testing.T) {
+	tree := newLocationTestTree(t)
+	ctx := context.Background()
+
+	_, err := tree.service.GetNode(ctx, "org-b", tree.room.ID)
+	assert.ErrorIs(t, err, ErrLocationNotFound)
+	_, err = tree.service.GetAncestors(ctx, "org-b", tree.room.ID)
+	assert.ErrorIs(t, err, ErrLocationNotFound)
+	_, err = tree.service.MoveNode(ctx, "org-b", tree.floor.ID, &tree.buildingB.ID, "admin-b")
+	assert.ErrorIs(t, err, ErrLocationNotFound)
+}
+
+func TestLocationService_DeleteNodeWithOpenInspections(t *testing.T) {
+	tests := []struct {
+		status  string
+		wantErr error
+	}{
+		{"assigned", ErrLocationHasActiveInspections},
+		{"draft", ErrLocationHasActiveInspections},
+		{"in_progress", ErrLocationHasActiveInspections},
+		{"requires_review", ErrLocationHasActiveInspections},
+		{"completed", nil},
+		{"approved", nil},
+		{"rejected", nil},
+		{"cancelled", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			db := setupServiceTestDB(t, &models.Site{}, &models.LocationNode{}, &models.LocationNodeMove{}, &models.Inspection{})
+			service := NewLocationService(db)
+			site := createTestSite(t, db, "org-a", "Depot", "1 Main St")
+
+			room := createTestLocation(t, service, site.ID, "Plant room", "room", nil)
+			createTestInspection(t, db, site.ID, room, tt.status, nil)
+
+			err := service.DeleteNode(context.Background(), "org-a", room.ID)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
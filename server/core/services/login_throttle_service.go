@@ -0,0 +1,573 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"resource-mgmt/config"
+	"resource-mgmt/models"
+	"resource-mgmt/pkg/database"
+	"resource-mgmt/pkg/tenant"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrLoginLocked is returned when an account or client IP is temporarily locked out
+	ErrLoginLocked = errors.New("too many failed login attempts")
+	// ErrLockoutNotFound is returned when an organization admin unlocks an account that
+	// isn't a member, or an IP with no lockout visible to the organization
+	ErrLockoutNotFound = errors.New("lockout not found")
+)
+
+// LoginLockedError carries when a lockout ends
+type LoginLockedError struct {
+	Until time.Time
+}
+
+func (e *LoginLockedError) Error() string {
+	return fmt.Sprintf("%s, try again after %s", ErrLoginLocked.Error(), e.Until.Format(time.RFC3339))
+}
+
+func (e *LoginLockedError) Is(target error) bool {
+	return target == ErrLoginLocked
+}
+
+// RetryAfter returns how long the caller has to wait
+func (e *LoginLockedError) RetryAfter() time.Duration {
+	return time.Until(e.Until)
+}
+
+// LoginThrottleConfig controls failure thresholds and lockout durations
+type LoginThrottleConfig struct {
+	MaxAccountFailures      int           // failures per account within FailureWindow before a lockout
+	MaxIPFailures           int           // failures per client IP within FailureWindow before a lockout
+	FailureWindow           time.Duration // failures older than this are forgotten
+	BaseLockout             time.Duration // first lockout; doubles with every repeated lockout
+	MaxLockout              time.Duration // lockout cap, also how long repeated lockouts are remembered
+	SuspiciousAccountsPerIP int           // distinct accounts failing from one IP that trigger an alert
+}
+
+// DefaultLoginThrottleConfig returns the production thresholds
+func DefaultLoginThrottleConfig() LoginThrottleConfig {
+	return LoginThrottleConfig{
+		MaxAccountFailures:      5,
+		MaxIPFailures:           20,
+		FailureWindow:           15 * time.Minute,
+		BaseLockout:             time.Minute,
+		MaxLockout:              24 * time.Hour,
+		SuspiciousAccountsPerIP: 5,
+	}
+}
+
+// LockoutRecord is the failure state of one throttled key (an account or a client IP)
+type LockoutRecord struct {
+	Key           string         `json:"key" gorm:"primarykey;size:320"` // account:<email> or ip:<address>
+	Failures      int            `json:"failures" gorm:"not null;default:0"`
+	Lockouts      int            `json:"lockouts" gorm:"not null;default:0"`
+	Accounts      datatypes.JSON `json:"accounts" gorm:"type:jsonb"` // IP keys: distinct accounts that failed in the window
+	WindowStart   time.Time      `json:"window_start"`
+	LastFailureAt time.Time      `json:"last_failure_at"`
+	LockedUntil   *time.Time     `json:"locked_until" gorm:"index"`
+	AlertedAt     *time.Time     `json:"alerted_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+// TableName specifies the table name for LockoutRecord
+func (LockoutRecord) TableName() string {
+	return "login_lockouts"
+}
+
+// IsLocked reports whether the record is locked at now
+func (r *LockoutRecord) IsLocked(now time.Time) bool {
+	return r.LockedUntil != nil && r.LockedUntil.After(now)
+}
+
+func (r *LockoutRecord) accountList() []string {
+	var accounts []string
+	if len(r.Accounts) > 0 {
+		_ = json.Unmarshal(r.Accounts, &accounts)
+	}
+	return accounts
+}
+
+// LockoutStore persists lockout records
+type LockoutStore interface {
+	// Get returns the record for key, or nil when there is none
+	Get(ctx context.Context, key string) (*LockoutRecord, error)
+	// Update applies change to the record for key, starting from an empty record when
+	// there is none, and saves the result. Concurrent updates of one key are serialized.
+	Update(ctx context.Context, key string, change func(record *LockoutRecord)) (*LockoutRecord, error)
+	Delete(ctx context.Context, key string) error
+	// ListLocked returns records locked at now
+	ListLocked(ctx context.Context, now time.Time) ([]LockoutRecord, error)
+}
+
+// =====================================================
+// LOCKOUT STORES
+// =====================================================
+
+// MemoryLockoutStore keeps lockout state in process memory. State is per instance
+// and lost on restart; use DBLockoutStore when running several instances.
+type MemoryLockoutStore struct {
+	mu      sync.Mutex
+	records map[string]LockoutRecord
+}
+
+func NewMemoryLockoutStore() *MemoryLockoutStore {
+	return &MemoryLockoutStore{records: make(map[string]LockoutRecord)}
+}
+
+func (s *MemoryLockoutStore) Get(ctx context.Context, key string) (*LockoutRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[key]
+	if !ok {
+		return nil, nil
+	}
+	return &record, nil
+}
+
+func (s *MemoryLockoutStore) Update(ctx context.Context, key string, change func(record *LockoutRecord)) (*LockoutRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[key]
+	if !ok {
+		record = LockoutRecord{Key: key}
+	}
+	change(&record)
+	record.UpdatedAt = time.Now()
+	s.records[key] = record
+	return &record, nil
+}
+
+func (s *MemoryLockoutStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+	return nil
+}
+
+func (s *MemoryLockoutStore) ListLocked(ctx context.Context, now time.Time) ([]LockoutRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var locked []LockoutRecord
+	for _, record := range s.records {
+		if record.IsLocked(now) {
+			locked = append(locked, record)
+		}
+	}
+	return locked, nil
+}
+
+// DBLockoutStore keeps lockout state in the login_lockouts table, shared by all instances
+type DBLockoutStore struct {
+	db *gorm.DB
+}
+
+func NewDBLockoutStore(db *gorm.DB) *DBLockoutStore {
+	return &DBLockoutStore{db: db}
+}
+
+func (s *DBLockoutStore) Get(ctx context.Context, key string) (*LockoutRecord, error) {
+	var record LockoutRecord
+	err := s.db.WithContext(ctx).Where("key = ?", key).First(&record).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &record, nil
+}
+
+// Update locks the record's row for the duration of the change, so instances sharing
+// the table can't lose each other's failures
+func (s *DBLockoutStore) Update(ctx context.Context, key string, change func(record *LockoutRecord)) (*LockoutRecord, error) {
+	var record LockoutRecord
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The row must exist to be locked; concurrent first failures then queue on it
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&LockoutRecord{Key: key}).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("key = ?", key).First(&record).Error; err != nil {
+			return err
+		}
+		change(&record)
+		return tx.Save(&record).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (s *DBLockoutStore) Delete(ctx context.Context, key string) error {
+	return s.db.WithContext(ctx).Where("key = ?", key).Delete(&LockoutRecord{}).Error
+}
+
+func (s *DBLockoutStore) ListLocked(ctx context.Context, now time.Time) ([]LockoutRecord, error) {
+	var records []LockoutRecord
+	err := s.db.WithContext(ctx).Where("locked_until > ?", now).Order("locked_until DESC").Find(&records).Error
+	return records, err
+}
+
+// =====================================================
+// LOGIN THROTTLE
+// =====================================================
+
+// LoginThrottleService counts failed logins per account and per client IP and locks
+// them out with exponentially growing durations
+type LoginThrottleService struct {
+	db                  *gorm.DB
+	store               LockoutStore
+	config              LoginThrottleConfig
+	auditService        *AuditService
+	notificationService *NotificationService
+
+	now func() time.Time
+}
+
+func NewLoginThrottleService(store LockoutStore, throttleConfig LoginThrottleConfig) *LoginThrottleService {
+	return &LoginThrottleService{
+		db:                  config.DB,
+		store:               store,
+		config:              throttleConfig,
+		auditService:        NewAuditService(),
+		notificationService: NewNotificationService(),
+		now:                 time.Now,
+	}
+}
+
+var (
+	defaultLoginThrottle     *LoginThrottleService
+	defaultLoginThrottleOnce sync.Once
+)
+
+// DefaultLoginThrottle returns the process-wide throttle, so every service and handler
+// instance sees the same failure counts. The store is chosen by config.LoginLockoutStore.
+func DefaultLoginThrottle() *LoginThrottleService {
+	defaultLoginThrottleOnce.Do(func() {
+		var store LockoutStore = NewMemoryLockoutStore()
+		if config.LoginLockoutStore == "db" && config.DB != nil {
+			store = NewDBLockoutStore(config.DB)
+		}
+		defaultLoginThrottle = NewLoginThrottleService(store, DefaultLoginThrottleConfig())
+	})
+	return defaultLoginThrottle
+}
+
+func accountLockoutKey(email string) string {
+	return "account:" + normalizeLoginEmail(email)
+}
+
+func ipLockoutKey(ip string) string {
+	return "ip:" + ip
+}
+
+func normalizeLoginEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Check returns a *LoginLockedError when the account or the client IP is locked out
+func (s *LoginThrottleService) Check(ctx context.Context, email, ip string) error {
+	now := s.now()
+	for _, key := range s.keys(email, ip) {
+		record, err := s.store.Get(ctx, key)
+		if err != nil {
+			return fmt.Errorf("failed to read lockout state: %v", err)
+		}
+		if record != nil && record.IsLocked(now) {
+			return &LoginLockedError{Until: *record.LockedUntil}
+		}
+	}
+	return nil
+}
+
+// RecordFailure counts a failed login for the account and the client IP, locking
+// either once its threshold is reached and alerting on many accounts from one IP
+func (s *LoginThrottleService) RecordFailure(ctx context.Context, email, ip string) error {
+	now := s.now()
+	email = normalizeLoginEmail(email)
+
+	account, err := s.registerFailure(ctx, accountLockoutKey(email), s.config.MaxAccountFailures, now, "")
+	if err != nil {
+		return err
+	}
+	if account.IsLocked(now) && account.Failures == 0 {
+		s.logLockout(ctx, email, ip, account, []string{email})
+	}
+
+	if ip == "" {
+		return nil
+	}
+
+	ipRecord, err := s.registerFailure(ctx, ipLockoutKey(ip), s.config.MaxIPFailures, now, email)
+	if err != nil {
+		return err
+	}
+	if ipRecord.IsLocked(now) && ipRecord.Failures == 0 {
+		s.logLockout(ctx, "", ip, ipRecord, ipRecord.accountList())
+	}
+
+	if s.config.SuspiciousAccountsPerIP <= 0 || len(ipRecord.accountList()) < s.config.SuspiciousAccountsPerIP || ipRecord.AlertedAt != nil {
+		return nil
+	}
+
+	// Claim the alert under the store's lock, so only one failure sends it
+	alert := false
+	ipRecord, err = s.store.Update(ctx, ipLockoutKey(ip), func(record *LockoutRecord) {
+		if record.AlertedAt == nil {
+			record.AlertedAt = &now
+			alert = true
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save lockout state: %v", err)
+	}
+	if alert {
+		s.alertSuspiciousIP(ctx, ip, ipRecord.accountList())
+	}
+
+	return nil
+}
+
+// registerFailure increments the failure count of key and applies a lockout at the threshold.
+// A fresh lockout resets Failures to zero, which callers use to detect it.
+func (s *LoginThrottleService) registerFailure(ctx context.Context, key string, threshold int, now time.Time, account string) (*LockoutRecord, error) {
+	record, err := s.store.Update(ctx, key, func(record *LockoutRecord) {
+		s.applyFailure(record, threshold, now, account)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save lockout state: %v", err)
+	}
+	return record, nil
+}
+
+// applyFailure counts one failure on record. A new record has a zero WindowStart and
+// so starts a fresh window.
+func (s *LoginThrottleService) applyFailure(record *LockoutRecord, threshold int, now time.Time, account string) {
+	// Forget escalation after a long quiet period, and failures outside the window
+	if !record.LastFailureAt.IsZero() && now.Sub(record.LastFailureAt) > s.config.MaxLockout {
+		record.Lockouts = 0
+	}
+	if now.Sub(record.WindowStart) > s.config.FailureWindow {
+		record.Failures = 0
+		record.WindowStart = now
+		record.Accounts = nil
+		record.AlertedAt = nil
+	}
+
+	record.Failures++
+	record.LastFailureAt = now
+
+	if account != "" {
+		accounts := record.accountList()
+		if !containsString(accounts, account) {
+			accounts = append(accounts, account)
+			encoded, _ := json.Marshal(accounts)
+			record.Accounts = datatypes.JSON(encoded)
+		}
+	}
+
+	if threshold > 0 && record.Failures >= threshold {
+		until := now.Add(s.lockoutDuration(record.Lockouts))
+		record.LockedUntil = &until
+		record.Lockouts++
+		record.Failures = 0
+	}
+}
+
+// lockoutDuration doubles BaseLockout for every previous lockout, capped at MaxLockout
+func (s *LoginThrottleService) lockoutDuration(previousLockouts int) time.Duration {
+	duration := s.config.BaseLockout
+	for i := 0; i < previousLockouts && duration < s.config.MaxLockout; i++ {
+		duration *= 2
+	}
+	if duration > s.config.MaxLockout {
+		duration = s.config.MaxLockout
+	}
+	return duration
+}
+
+// RecordSuccess clears the account's failure count. The IP record is kept: a client
+// that owns one valid account must not be able to reset its counter with it.
+func (s *LoginThrottleService) RecordSuccess(ctx context.Context, email string) error {
+	return s.store.Delete(ctx, accountLockoutKey(email))
+}
+
+// UnlockAccount lifts a lockout on an account and forgets its failures
+func (s *LoginThrottleService) UnlockAccount(ctx context.Context, email string) error {
+	return s.store.Delete(ctx, accountLockoutKey(email))
+}
+
+// UnlockIP lifts a lockout on a client IP and forgets its failures
+func (s *LoginThrottleService) UnlockIP(ctx context.Context, ip string) error {
+	return s.store.Delete(ctx, ipLockoutKey(ip))
+}
+
+// UnlockMemberAccount lifts a lockout on the account of a member of the organization;
+// admins can only unlock their own members
+func (s *LoginThrottleService) UnlockMemberAccount(ctx context.Context, orgID, email string) error {
+	var count int64
+	err := database.Conn(ctx, s.db).Model(&models.OrganizationMember{}).
+		Joins("JOIN global_users ON global_users.id = organization_members.user_id").
+		Where("organization_members.organization_id = ? AND LOWER(global_users.email) = ?", orgID, normalizeLoginEmail(email)).
+		Count(&count).Error
+	if err != nil {
+		return fmt.Errorf("failed to check membership: %v", err)
+	}
+	if count == 0 {
+		return ErrLockoutNotFound
+	}
+	return s.UnlockAccount(ctx, email)
+}
+
+// UnlockOrganizationIP lifts a lockout on a client IP that is visible to the
+// organization, see ListLockouts
+func (s *LoginThrottleService) UnlockOrganizationIP(ctx context.Context, orgID, ip string) error {
+	lockouts, err := s.ListLockouts(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	for _, lockout := range lockouts {
+		if lockout.Key == ipLockoutKey(ip) {
+			return s.UnlockIP(ctx, ip)
+		}
+	}
+	return ErrLockoutNotFound
+}
+
+// ListLockouts returns active lockouts relevant to an organization: its members'
+// accounts and IPs from which any of its members failed to log in
+func (s *LoginThrottleService) ListLockouts(ctx context.Context, orgID string) ([]LockoutRecord, error) {
+	records, err := s.store.ListLocked(ctx, s.now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list lockouts: %v", err)
+	}
+	if len(records) == 0 {
+		return records, nil
+	}
+
+	var emails []string
+	err = database.Conn(ctx, s.db).Model(&models.GlobalUser{}).
+		Joins("JOIN organization_members ON organization_members.user_id = global_users.id").
+		Where("organization_members.organization_id = ?", orgID).
+		Pluck("LOWER(global_users.email)", &emails).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load organization members: %v", err)
+	}
+
+	var visible []LockoutRecord
+	for _, record := range records {
+		if strings.HasPrefix(record.Key, "account:") {
+			if containsString(emails, strings.TrimPrefix(record.Key, "account:")) {
+				visible = append(visible, record)
+			}
+			continue
+		}
+		for _, account := range record.accountList() {
+			if containsString(emails, account) {
+				visible = append(visible, record)
+				break
+			}
+		}
+	}
+	return visible, nil
+}
+
+func (s *LoginThrottleService) keys(email, ip string) []string {
+	keys := []string{accountLockoutKey(email)}
+	if ip != "" {
+		keys = append(keys, ipLockoutKey(ip))
+	}
+	return keys
+}
+
+// logLockout audits a fresh lockout under the organizations of the accounts involved
+func (s *LoginThrottleService) logLockout(ctx context.Context, email, ip string, record *LockoutRecord, accounts []string) {
+	details := map[string]interface{}{
+		"key":          record.Key,
+		"locked_until": record.LockedUntil,
+		"lockouts":     record.Lockouts,
+		"ip_address":   ip,
+	}
+	if email != "" {
+		details["email"] = email
+	}
+	s.logAccountsEvent(ctx, AccountLocked, details, accounts)
+}
+
+// alertSuspiciousIP records the pattern in the audit trail and notifies the admins of
+// every organization that has one of the targeted accounts as a member
+func (s *LoginThrottleService) alertSuspiciousIP(ctx context.Context, ip string, accounts []string) {
+	details := map[string]interface{}{
+		"ip_address": ip,
+		"accounts":   accounts,
+	}
+	for _, orgID := range s.logAccountsEvent(ctx, SuspiciousLoginActivity, details, accounts) {
+		if err := s.notificationService.NotifySuspiciousLoginActivity(orgID, ip, len(accounts)); err != nil {
+			log.Printf("Failed to notify admins of organization %s: %v", orgID, err)
+		}
+	}
+}
+
+// logAccountsEvent records a security event in the audit chain of every organization the
+// accounts belong to, returning those organizations. Only events that involve no known
+// account go to the global chain.
+func (s *LoginThrottleService) logAccountsEvent(ctx context.Context, action AuditAction, details map[string]interface{}, accounts []string) []string {
+	orgIDs, err := s.accountOrganizationIDs(accounts)
+	if err != nil {
+		log.Printf("Failed to resolve organizations for %s: %v", action, err)
+	}
+
+	if len(orgIDs) == 0 {
+		if err := s.auditService.LogSecurityEvent(ctx, action, "", details, false, nil); err != nil {
+			log.Printf("Failed to audit %s: %v", action, err)
+		}
+		return nil
+	}
+
+	for _, orgID := range orgIDs {
+		orgCtx := tenant.WithTenantContext(ctx, tenant.NewContext(orgID, "system", "system"))
+		if err := s.auditService.LogSecurityEvent(orgCtx, action, "", details, false, nil); err != nil {
+			log.Printf("Failed to audit %s for organization %s: %v", action, orgID, err)
+		}
+	}
+	return orgIDs
+}
+
+// accountOrganizationIDs returns the organizations that accounts with the given emails belong to
+func (s *LoginThrottleService) accountOrganizationIDs(accounts []string) ([]string, error) {
+	if s.db == nil || len(accounts) == 0 {
+		return nil, nil
+	}
+
+	var orgIDs []string
+	err := s.db.Model(&models.OrganizationMember{}).
+		Joins("JOIN global_users ON global_users.id = organization_members.user_id").
+		Where("LOWER(global_users.email) IN ?", accounts).
+		Distinct().
+		Pluck("organization_members.organization_id", &orgIDs).Error
+	return orgIDs, err
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"resource-mgmt/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+func testThrottleConfig() LoginThrottleConfig {
+	return LoginThrottleConfig{
+		MaxAccountFailures:      3,
+		MaxIPFailures:           10,
+		FailureWindow:           15 * time.Minute,
+		BaseLockout:             time.Minute,
+		MaxLockout:              time.Hour,
+		SuspiciousAccountsPerIP: 4,
+	}
+}
+
+func TestLoginThrottle_ExponentialAccountLockout(t *testing.T) {
+	ctx := context.Background()
+
+	for name, newStore := range map[string]func(db *gorm.DB) LockoutStore{
+		"memory": func(db *gorm.DB) LockoutStore { return NewMemoryLockoutStore() },
+		"db":     func(db *gorm.DB) LockoutStore { return NewDBLockoutStore(db) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			db := setupAuditTestDB(t, &LockoutRecord{})
+			store := newStore(db)
+			throttle := NewLoginThrottleService(store, testThrottleConfig())
+			now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+			throttle.now = func() time.Time { return now }
+
+			for i := 0; i < 3; i++ {
+				require.NoError(t, throttle.Check(ctx, "Inspector@Example.com", "198.51.100.1"))
+				require.NoError(t, throttle.RecordFailure(ctx, "Inspector@Example.com", "198.51.100.1"))
+			}
+
+			// Locked for the base duration, regardless of email casing or client IP
+			err := throttle.Check(ctx, "inspector@example.com", "198.51.100.2")
+			require.ErrorIs(t, err, ErrLoginLocked)
+			var locked *LoginLockedError
+			require.True(t, errors.As(err, &locked))
+			assert.Equal(t, now.Add(time.Minute), locked.Until)
+
+			// The second lockout doubles
+			now = now.Add(2 * time.Minute)
+			require.NoError(t, throttle.Check(ctx, "inspector@example.com", "198.51.100.1"))
+			for i := 0; i < 3; i++ {
+				require.NoError(t, throttle.RecordFailure(ctx, "inspector@example.com", "198.51.100.1"))
+			}
+			require.True(t, errors.As(throttle.Check(ctx, "inspector@example.com", ""), &locked))
+			assert.Equal(t, now.Add(2*time.Minute), locked.Until)
+
+			require.NoError(t, throttle.UnlockAccount(ctx, "inspector@example.com"))
+			assert.NoError(t, throttle.Check(ctx, "inspector@example.com", ""))
+		})
+	}
+}
+
+func TestLoginThrottle_AlertsOnManyAccountsFromOneIP(t *testing.T) {
+	db := setupAuditTestDB(t, &models.GlobalUser{}, &models.Organization{}, &models.OrganizationMember{}, &models.Notification{})
+	ctx := WithRequestMetadata(context.Background(), "203.0.113.50", "credential-stuffer")
+
+	require.NoError(t, db.Create(&models.Organization{ID: "org-a", Name: "Client A", Domain: "client-a", Slug: "client-a", IsActive: true}).Error)
+	require.NoError(t, db.Create(&models.GlobalUser{ID: "admin-a", Email: "admin@client-a.com", Name: "Admin"}).Error)
+	require.NoError(t, db.Create(&models.GlobalUser{ID: "insp-a", Email: "inspector@client-a.com", Name: "Inspector"}).Error)
+	require.NoError(t, db.Create(&models.OrganizationMember{UserID: "admin-a", OrganizationID: "org-a", Role: "admin", Status: "active"}).Error)
+	require.NoError(t, db.Create(&models.OrganizationMember{UserID: "insp-a", OrganizationID: "org-a", Role: "inspector", Status: "active"}).Error)
+
+	throttle := NewLoginThrottleService(NewMemoryLockoutStore(), testThrottleConfig())
+	for _, email := range []string{"inspector@client-a.com", "a@other.com", "b@other.com", "c@other.com", "d@other.com"} {
+		require.NoError(t, throttle.RecordFailure(ctx, email, "203.0.113.50"))
+	}
+
+	var notifications []models.Notification
+	require.NoError(t, db.Where("organization_id = ? AND user_id = ?", "org-a", "admin-a").Find(&notifications).Error)
+	require.Len(t, notifications, 1, "alert is raised once per window")
+	assert.Equal(t, "security", notifications[0].Type)
+	assert.Contains(t, notifications[0].Message, "203.0.113.50")
+
+	var events int64
+	require.NoError(t, db.Model(&AuditLog{}).Where("organization_id = ? AND action = ?", "org-a", SuspiciousLoginActivity).Count(&events).Error)
+	assert.Equal(t, int64(1), events)
+
+	lockouts, err := throttle.ListLockouts(ctx, "org-a")
+	require.NoError(t, err)
+	assert.Empty(t, lockouts, "the IP is not locked yet")
+}
+
+func TestLoginThrottle_UnlockMemberAccountOnlyUnlocksMembers(t *testing.T) {
+	ctx := context.Background()
+	db := setupAuditTestDB(t, &LockoutRecord{}, &models.GlobalUser{}, &models.OrganizationMember{})
+	createTestMembers(t, db, "org-a", "inspector", "inspector")
+
+	throttle := NewLoginThrottleService(NewDBLockoutStore(db), testThrottleConfig())
+	for _, email := range []string{"inspector@org-a.test", "outsider@other.com"} {
+		for i := 0; i < 3; i++ {
+			require.NoError(t, throttle.RecordFailure(ctx, email, ""))
+		}
+	}
+
+	assert.ErrorIs(t, throttle.UnlockMemberAccount(ctx, "org-a", "outsider@other.com"), ErrLockoutNotFound)
+	assert.ErrorIs(t, throttle.Check(ctx, "outsider@other.com", ""), ErrLoginLocked)
+
+	require.NoError(t, throttle.UnlockMemberAccount(ctx, "org-a", " Inspector@Org-A.test"))
+	assert.NoError(t, throttle.Check(ctx, "inspector@org-a.test", ""))
+}
+
+func TestMultiOrgAuthService_LoginLocksAfterRepeatedFailures(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret-that-is-at-least-32-characters-long")
+	db := setupAuditTestDB(t, &models.GlobalUser{}, &models.Organization{}, &models.OrganizationMember{}, &models.UserSession{})
+	ctx := WithRequestMetadata(context.Background(), "198.51.100.7", "test")
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct-horse"), bcrypt.MinCost)
+	require.NoError(t, err)
+	require.NoError(t, db.Create(&models.Organization{ID: "org-a", Name: "Client A", Domain: "client-a", Slug: "client-a", IsActive: true}).Error)
+	require.NoError(t, db.Create(&models.GlobalUser{ID: "user-1", Email: "inspector@client-a.com", Name: "Inspector", Password: string(hash)}).Error)
+	require.NoError(t, db.Create(&models.OrganizationMember{UserID: "user-1", OrganizationID: "org-a", Role: "inspector", Status: "active", IsPrimary: true}).Error)
+
+	service := NewMultiOrgAuthService()
+	service.throttle = NewLoginThrottleService(NewMemoryLockoutStore(), testThrottleConfig())
+
+	for i := 0; i < 3; i++ {
+		_, err := service.Login(ctx, &MultiOrgLoginRequest{Email: "inspector@client-a.com", Password: fmt.Sprintf("guess-%d", i)})
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, ErrLoginLocked)
+	}
+
+	// Even the right password is refused while locked
+	_, err = service.Login(ctx, &MultiOrgLoginRequest{Email: "inspector@client-a.com", Password: "correct-horse"})
+	assert.ErrorIs(t, err, ErrLoginLocked)
+
+	var failures int64
+	require.NoError(t, db.Model(&AuditLog{}).Where("organization_id = ? AND action = ?", "org-a", LoginFailure).Count(&failures).Error)
+	assert.Equal(t, int64(4), failures)
+
+	require.NoError(t, service.throttle.UnlockAccount(ctx, "inspector@client-a.com"))
+	response, err := service.Login(ctx, &MultiOrgLoginRequest{Email: "inspector@client-a.com", Password: "correct-horse"})
+	require.NoError(t, err)
+	assert.Equal(t, "org-a", response.CurrentOrganization.ID)
+}
+
+func TestMultiOrgAuthService_LoginFailuresAuditedUnderAccountOrganization(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret-that-is-at-least-32-characters-long")
+	db := setupAuditTestDB(t, &models.GlobalUser{}, &models.Organization{}, &models.OrganizationMember{}, &models.UserSession{}, &models.Notification{})
+
+	require.NoError(t, db.Create(&models.Organization{ID: "org-a", Name: "Client A", Domain: "client-a", Slug: "client-a", IsActive: true}).Error)
+	require.NoError(t, db.Create(&models.Organization{ID: "org-b", Name: "Client B", Domain: "client-b", Slug: "client-b", IsActive: true}).Error)
+	require.NoError(t, db.Create(&models.GlobalUser{ID: "user-a", Email: "inspector@client-a.com", Name: "Inspector"}).Error)
+	require.NoError(t, db.Create(&models.GlobalUser{ID: "user-b", Email: "former@client-b.com", Name: "Former"}).Error)
+	require.NoError(t, db.Create(&models.OrganizationMember{UserID: "user-a", OrganizationID: "org-a", Role: "inspector", Status: "active", IsPrimary: true}).Error)
+	require.NoError(t, db.Create(&models.OrganizationMember{UserID: "user-b", OrganizationID: "org-b", Role: "inspector", Status: "suspended"}).Error)
+
+	tests := []struct {
+		name  string
+		email string
+		chain string
+	}{
+		{"active member", "inspector@client-a.com", "org-a"},
+		{"suspended member", "former@client-b.com", "org-b"},
+		{"unknown account", "nobody@example.com", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, db.Where("1 = 1").Delete(&AuditLog{}).Error)
+			service := NewMultiOrgAuthService()
+			service.throttle = NewLoginThrottleService(NewMemoryLockoutStore(), testThrottleConfig())
+			ctx := WithRequestMetadata(context.Background(), "198.51.100.7", "test")
+
+			for i := 0; i < 3; i++ {
+				_, err := service.Login(ctx, &MultiOrgLoginRequest{Email: tt.email, Password: "wrong"})
+				require.Error(t, err)
+			}
+
+			var chains []string
+			require.NoError(t, db.Model(&AuditLog{}).Where("action IN ?", []AuditAction{LoginFailure, AccountLocked}).
+				Distinct().Pluck("organization_id", &chains).Error)
+			assert.Equal(t, []string{tt.chain}, chains)
+
+			var lockouts int64
+			require.NoError(t, db.Model(&AuditLog{}).Where("organization_id = ? AND action = ?", tt.chain, AccountLocked).Count(&lockouts).Error)
+			assert.Equal(t, int64(1), lockouts)
+		})
+	}
+}
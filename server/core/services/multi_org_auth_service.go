@@ -6,8 +6,10 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"log"
 	"resource-mgmt/config"
 	"resource-mgmt/models"
+	"resource-mgmt/pkg/tenant"
 	"resource-mgmt/pkg/utils"
 	"strings"
 	"time"
@@ -17,13 +19,24 @@ import (
 	"gorm.io/gorm"
 )
 
+var (
+	// ErrNoActiveMemberships is returned when a user signs in without belonging to any active organization
+	ErrNoActiveMemberships = errors.New("user has no active organization memberships")
+	// ErrSessionNotFound is returned when the token being switched from has no live session
+	ErrSessionNotFound = errors.New("session not found or revoked")
+)
+
 type MultiOrgAuthService struct {
-	db *gorm.DB
+	db           *gorm.DB
+	throttle     *LoginThrottleService
+	auditService *AuditService
 }
 
 func NewMultiOrgAuthService() *MultiOrgAuthService {
 	return &MultiOrgAuthService{
-		db: config.DB,
+		db:           config.DB,
+		throttle:     DefaultLoginThrottle(),
+		auditService: NewAuditService(),
 	}
 }
 
@@ -55,11 +68,25 @@ type MultiOrgLoginResponse struct {
 
 // Login authenticates user and returns JWT with multi-org context
 func (s *MultiOrgAuthService) Login(ctx context.Context, req *MultiOrgLoginRequest) (*MultiOrgLoginResponse, error) {
+	clientIP := ClientIPFromContext(ctx)
+
+	// Locked accounts and IPs are refused before the password is even checked
+	if err := s.throttle.Check(ctx, req.Email, clientIP); err != nil {
+		var lockedUser *models.GlobalUser
+		var user models.GlobalUser
+		if s.db.Where("email = ? AND deleted_at IS NULL", req.Email).First(&user).Error == nil {
+			lockedUser = &user
+		}
+		s.logLoginEvent(ctx, lockedUser, req.Email, LoginFailure, "locked_out")
+		return nil, err
+	}
+
 	// Find user by email
 	var user models.GlobalUser
 	err := s.db.Where("email = ? AND deleted_at IS NULL", req.Email).First(&user).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
+			s.recordLoginFailure(ctx, nil, req.Email, clientIP, "unknown_email")
 			return nil, errors.New("invalid email or password")
 		}
 		return nil, err
@@ -67,12 +94,73 @@ func (s *MultiOrgAuthService) Login(ctx context.Context, req *MultiOrgLoginReque
 
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		s.recordLoginFailure(ctx, &user, req.Email, clientIP, "invalid_password")
 		return nil, errors.New("invalid email or password")
 	}
 
+	response, err := s.issueSession(&user, req.OrganizationSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.throttle.RecordSuccess(ctx, req.Email); err != nil {
+		log.Printf("Failed to reset login failures for %s: %v", req.Email, err)
+	}
+	s.logLoginEvent(ctx, &user, req.Email, LoginSuccess, "")
+
+	return response, nil
+}
+
+// recordLoginFailure feeds a failed attempt to the throttle and the audit trail
+func (s *MultiOrgAuthService) recordLoginFailure(ctx context.Context, user *models.GlobalUser, email, clientIP, reason string) {
+	if err := s.throttle.RecordFailure(ctx, email, clientIP); err != nil {
+		log.Printf("Failed to record login failure for %s: %v", email, err)
+	}
+	s.logLoginEvent(ctx, user, email, LoginFailure, reason)
+}
+
+// logLoginEvent writes a login security event. Events for known users go to the
+// audit chain of their primary organization so its admins can see them, falling back to
+// any organization they belong to; only unknown accounts go to the global chain.
+func (s *MultiOrgAuthService) logLoginEvent(ctx context.Context, user *models.GlobalUser, email string, action AuditAction, reason string) {
+	userID := ""
+	details := map[string]interface{}{"email": normalizeLoginEmail(email)}
+	if reason != "" {
+		details["reason"] = reason
+	}
+
+	if user != nil {
+		userID = user.ID
+		var membership models.OrganizationMember
+		err := s.db.Where("user_id = ?", user.ID).
+			Order("CASE WHEN status = 'active' THEN 0 ELSE 1 END").
+			Order("is_primary DESC").
+			First(&membership).Error
+		if err == nil {
+			ctx = tenant.WithTenantContext(ctx, tenant.NewContext(membership.OrganizationID, user.ID, membership.Role))
+		}
+	}
+
+	var errorMsg *string
+	if action == LoginFailure {
+		errorMsg = &reason
+	}
+	if err := s.auditService.LogSecurityEvent(ctx, action, userID, details, action != LoginFailure, errorMsg); err != nil {
+		log.Printf("Failed to audit %s for %s: %v", action, email, err)
+	}
+}
+
+// LoginWithIdentity issues a multi-org session for a user already authenticated
+// by an external identity provider
+func (s *MultiOrgAuthService) LoginWithIdentity(ctx context.Context, user *models.GlobalUser, organizationSlug string) (*MultiOrgLoginResponse, error) {
+	return s.issueSession(user, organizationSlug)
+}
+
+// issueSession resolves the user's organizations, generates tokens and records the session
+func (s *MultiOrgAuthService) issueSession(user *models.GlobalUser, organizationSlug string) (*MultiOrgLoginResponse, error) {
 	// Get user's organizations
 	var memberships []models.OrganizationMember
-	err = s.db.Preload("Organization").
+	err := s.db.Preload("Organization").
 		Where("user_id = ? AND status = ?", user.ID, "active").
 		Find(&memberships).Error
 	if err != nil {
@@ -80,7 +168,7 @@ func (s *MultiOrgAuthService) Login(ctx context.Context, req *MultiOrgLoginReque
 	}
 
 	if len(memberships) == 0 {
-		return nil, errors.New("user has no active organization memberships")
+		return nil, ErrNoActiveMemberships
 	}
 
 	// Build organization info
@@ -102,7 +190,7 @@ func (s *MultiOrgAuthService) Login(ctx context.Context, req *MultiOrgLoginReque
 		organizations = append(organizations, orgInfo)
 
 		// Set current organization
-		if req.OrganizationSlug != "" && m.Organization.Slug == req.OrganizationSlug {
+		if organizationSlug != "" && m.Organization.Slug == organizationSlug {
 			currentOrg = &m.Organization
 			currentOrgID = m.OrganizationID
 		} else if currentOrg == nil && m.IsPrimary {
@@ -118,7 +206,7 @@ func (s *MultiOrgAuthService) Login(ctx context.Context, req *MultiOrgLoginReque
 	}
 
 	// Update last login
-	s.db.Model(&user).Update("last_login_at", time.Now())
+	s.db.Model(user).Update("last_login_at", time.Now())
 
 	// Update last accessed for current org
 	if currentOrgID != "" {
@@ -128,12 +216,12 @@ func (s *MultiOrgAuthService) Login(ctx context.Context, req *MultiOrgLoginReque
 	}
 
 	// Generate tokens
-	token, err := s.generateToken(&user, currentOrgID, organizations)
+	token, err := s.generateToken(user, currentOrgID, organizations)
 	if err != nil {
 		return nil, err
 	}
 
-	refreshToken, err := s.generateRefreshToken(&user)
+	refreshToken, err := s.generateRefreshToken(user)
 	if err != nil {
 		return nil, err
 	}
@@ -153,7 +241,7 @@ func (s *MultiOrgAuthService) Login(ctx context.Context, req *MultiOrgLoginReque
 	return &MultiOrgLoginResponse{
 		Token:               token,
 		RefreshToken:        refreshToken,
-		User:                &user,
+		User:                user,
 		CurrentOrganization: currentOrg,
 		Organizations:       organizations,
 	}, nil
@@ -257,8 +345,9 @@ func (s *MultiOrgAuthService) Register(ctx context.Context, req *models.Register
 	}, nil
 }
 
-// SwitchOrganization changes the user's current organization context
-func (s *MultiOrgAuthService) SwitchOrganization(ctx context.Context, userID, newOrgID string) (*MultiOrgLoginResponse, error) {
+// SwitchOrganization changes the user's current organization context.
+// The session identified by currentToken is rotated to the new tokens and organization.
+func (s *MultiOrgAuthService) SwitchOrganization(ctx context.Context, userID, newOrgID, currentToken string) (*MultiOrgLoginResponse, error) {
 	// Verify user has access to the organization
 	var membership models.OrganizationMember
 	err := s.db.Preload("Organization").Preload("User").
@@ -312,6 +401,10 @@ func (s *MultiOrgAuthService) SwitchOrganization(ctx context.Context, userID, ne
 		return nil, err
 	}
 
+	if err := s.rotateSession(userID, currentToken, newOrgID, token, refreshToken); err != nil {
+		return nil, err
+	}
+
 	return &MultiOrgLoginResponse{
 		Token:               token,
 		RefreshToken:        refreshToken,
@@ -349,7 +442,59 @@ func (s *MultiOrgAuthService) GetUserOrganizations(ctx context.Context, userID s
 	return organizations, nil
 }
 
+// SetPrimaryOrganization marks one of the user's organizations as their primary (default login) organization
+func (s *MultiOrgAuthService) SetPrimaryOrganization(ctx context.Context, userID, orgID string) ([]models.OrganizationMemberInfo, error) {
+	var membership models.OrganizationMember
+	err := s.db.Where("user_id = ? AND organization_id = ? AND status = ?", userID, orgID, "active").
+		First(&membership).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user does not have access to this organization")
+		}
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.OrganizationMember{}).
+			Where("user_id = ? AND organization_id <> ?", userID, orgID).
+			Update("is_primary", false).Error; err != nil {
+			return err
+		}
+		return tx.Model(&membership).Update("is_primary", true).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set primary organization: %v", err)
+	}
+
+	return s.GetUserOrganizations(ctx, userID)
+}
+
 // Helper functions
+
+// rotateSession moves the session for currentToken to the newly issued tokens and organization
+func (s *MultiOrgAuthService) rotateSession(userID, currentToken, orgID, token, refreshToken string) error {
+	now := time.Now()
+	result := s.db.Model(&models.UserSession{}).
+		Where("user_id = ? AND token = ? AND expires_at > ?", userID, currentToken, now).
+		Updates(map[string]interface{}{
+			"current_organization_id": orgID,
+			"token":                   token,
+			"refresh_token":           refreshToken,
+			"expires_at":              now.Add(24 * time.Hour),
+			"last_activity_at":        now,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to rotate session: %v", result.Error)
+	}
+
+	// A revoked or expired session can't be revived through a still-valid token
+	if result.RowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+
+	return nil
+}
+
 func (s *MultiOrgAuthService) generateToken(user *models.GlobalUser, currentOrgID string, orgs []models.OrganizationMemberInfo) (string, error) {
 	expirationTime := time.Now().Add(24 * time.Hour)
 	claims := &MultiOrgClaims{
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"resource-mgmt/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiOrgAuthService_SwitchOrganizationRotatesSession(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret-that-is-at-least-32-characters-long")
+	db := setupServiceTestDB(t, &models.GlobalUser{}, &models.Organization{}, &models.OrganizationMember{}, &models.UserSession{})
+	ctx := context.Background()
+
+	user := &models.GlobalUser{ID: "user-1", Email: "consultant@example.com", Name: "Consultant"}
+	require.NoError(t, db.Create(user).Error)
+	for _, org := range []*models.Organization{
+		{ID: "org-a", Name: "Client A", Domain: "client-a", Slug: "client-a", IsActive: true},
+		{ID: "org-b", Name: "Client B", Domain: "client-b", Slug: "client-b", IsActive: true},
+		{ID: "org-c", Name: "Client C", Domain: "client-c", Slug: "client-c", IsActive: true},
+	} {
+		require.NoError(t, db.Create(org).Error)
+	}
+	require.NoError(t, db.Create(&models.OrganizationMember{UserID: user.ID, OrganizationID: "org-a", Role: "inspector", Status: "active", IsPrimary: true}).Error)
+	require.NoError(t, db.Create(&models.OrganizationMember{UserID: user.ID, OrganizationID: "org-b", Role: "supervisor", Status: "active"}).Error)
+
+	service := NewMultiOrgAuthService()
+	login, err := service.LoginWithIdentity(ctx, user, "")
+	require.NoError(t, err)
+	assert.Equal(t, "org-a", login.CurrentOrganization.ID)
+
+	switched, err := service.SwitchOrganization(ctx, user.ID, "org-b", login.Token)
+	require.NoError(t, err)
+	assert.Equal(t, "org-b", switched.CurrentOrganization.ID)
+	assert.NotEqual(t, login.Token, switched.Token)
+
+	var sessions []models.UserSession
+	require.NoError(t, db.Where("user_id = ?", user.ID).Find(&sessions).Error)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, switched.Token, sessions[0].Token)
+	assert.Equal(t, "org-b", *sessions[0].CurrentOrganizationID)
+
+	var member models.OrganizationMember
+	require.NoError(t, db.Where("user_id = ? AND organization_id = ?", user.ID, "org-b").First(&member).Error)
+	assert.NotNil(t, member.LastAccessedAt)
+
+	_, err = service.SwitchOrganization(ctx, user.ID, "org-c", switched.Token)
+	assert.Error(t, err)
+
+	organizations, err := service.SetPrimaryOrganization(ctx, user.ID, "org-b")
+	require.NoError(t, err)
+	for _, org := range organizations {
+		assert.Equal(t, org.OrganizationID == "org-b", org.IsPrimary)
+	}
+}
+
+func TestMultiOrgAuthService_SwitchOrganizationRequiresLiveSession(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret-that-is-at-least-32-characters-long")
+	db := setupServiceTestDB(t, &models.GlobalUser{}, &models.Organization{}, &models.OrganizationMember{}, &models.UserSession{})
+	ctx := context.Background()
+
+	user := &models.GlobalUser{ID: "user-1", Email: "consultant@example.com", Name: "Consultant"}
+	require.NoError(t, db.Create(user).Error)
+	for _, org := range []*models.Organization{
+		{ID: "org-a", Name: "Client A", Domain: "client-a", Slug: "client-a", IsActive: true},
+		{ID: "org-b", Name: "Client B", Domain: "client-b", Slug: "client-b", IsActive: true},
+	} {
+		require.NoError(t, db.Create(org).Error)
+	}
+	require.NoError(t, db.Create(&models.OrganizationMember{UserID: user.ID, OrganizationID: "org-a", Role: "inspector", Status: "active", IsPrimary: true}).Error)
+	require.NoError(t, db.Create(&models.OrganizationMember{UserID: user.ID, OrganizationID: "org-b", Role: "supervisor", Status: "active"}).Error)
+
+	tests := []struct {
+		name   string
+		revoke func(token string)
+	}{
+		{"revoked session", func(token string) {
+			require.NoError(t, db.Where("token = ?", token).Delete(&models.UserSession{}).Error)
+		}},
+		{"expired session", func(token string) {
+			require.NoError(t, db.Model(&models.UserSession{}).Where("token = ?", token).Update("expires_at", time.Now().Add(-time.Minute)).Error)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, db.Where("user_id = ?", user.ID).Delete(&models.UserSession{}).Error)
+			service := NewMultiOrgAuthService()
+			login, err := service.LoginWithIdentity(ctx, user, "")
+			require.NoError(t, err)
+			tt.revoke(login.Token)
+
+			_, err = service.SwitchOrganization(ctx, user.ID, "org-b", login.Token)
+			assert.ErrorIs(t, err, ErrSessionNotFound)
+
+			var live int64
+			require.NoError(t, db.Model(&models.UserSession{}).Where("user_id = ? AND expires_at > ?", user.ID, time.Now()).Count(&live).Error)
+			assert.Zero(t, live)
+		})
+	}
+}
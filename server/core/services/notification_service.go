@@ -1,10 +1,12 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"resource-mgmt/config"
 	"resource-mgmt/models"
+	"resource-mgmt/pkg/database"
 	"time"
 
 	"gorm.io/gorm"
@@ -21,6 +23,13 @@ func NewNotificationService() *NotificationService {
 }
 
 func (s *NotificationService) CreateNotification(req *models.CreateNotificationRequest) (*models.Notification, error) {
+	return s.CreateNotificationWithContext(context.Background(), req)
+}
+
+// CreateNotificationWithContext creates a notification in the request transaction, if
+// any, so it is only sent when the change it is about commits. Savepoints keep a failure
+// here from aborting that transaction.
+func (s *NotificationService) CreateNotificationWithContext(ctx context.Context, req *models.CreateNotificationRequest) (*models.Notification, error) {
 	notification := &models.Notification{
 		OrganizationID: req.OrganizationID,
 		UserID:         req.UserID,
@@ -32,17 +41,22 @@ func (s *NotificationService) CreateNotification(req *models.CreateNotificationR
 		CreatedAt:      time.Now(),
 	}
 
-	err := s.db.Create(notification).Error
+	db := database.Conn(ctx, s.db)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return tx.Create(notification).Error
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	// Load relationships
-	err = s.db.
-		Preload("Organization").
-		Preload("User").
-		Preload("Inspection").
-		First(notification, notification.ID).Error
+	err = db.Transaction(func(tx *gorm.DB) error {
+		return tx.
+			Preload("Organization").
+			Preload("User").
+			Preload("Inspection").
+			First(notification, notification.ID).Error
+	})
 
 	if err != nil {
 		return nil, err
@@ -279,4 +293,32 @@ func (s *NotificationService) NotifyInspectionOverdue(inspection *models.Inspect
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// NotifySuspiciousLoginActivity alerts the organization's admins that many accounts,
+// some of them their members, failed to log in from one IP address
+func (s *NotificationService) NotifySuspiciousLoginActivity(organizationID, ipAddress string, accountCount int) error {
+	var adminIDs []string
+	err := s.db.Model(&models.OrganizationMember{}).
+		Where("organization_id = ? AND role = ? AND status = ?", organizationID, "admin", "active").
+		Pluck("user_id", &adminIDs).Error
+	if err != nil {
+		return err
+	}
+
+	for _, adminID := range adminIDs {
+		req := &models.CreateNotificationRequest{
+			OrganizationID: organizationID,
+			UserID:         adminID,
+			Title:          "Suspicious Login Activity",
+			Message:        fmt.Sprintf("Failed logins for %d different accounts from IP address %s, including members of your organization", accountCount, ipAddress),
+			Type:           "security",
+		}
+
+		if _, err := s.CreateNotification(req); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,586 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"resource-mgmt/config"
+	"resource-mgmt/models"
+	"resource-mgmt/pkg/utils"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"golang.org/x/oauth2/microsoft"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrUnknownOAuthProvider is returned when the provider is not configured
+	ErrUnknownOAuthProvider = errors.New("unknown or unconfigured identity provider")
+	// ErrInvalidOAuthState is returned when the callback state is missing, forged or expired
+	ErrInvalidOAuthState = errors.New("invalid or expired OAuth state")
+	// ErrUnverifiedIdentityEmail is returned when an unverified email would be linked to an existing account
+	ErrUnverifiedIdentityEmail = errors.New("identity provider did not verify this email; sign in with your password to link the account")
+)
+
+// oauthStateTTL bounds how long a login attempt may take at the provider
+const oauthStateTTL = 10 * time.Minute
+
+// OAuthUserInfo is the normalized identity returned by an external provider
+type OAuthUserInfo struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// OAuthProvider describes an external identity provider
+type OAuthProvider struct {
+	Name        string
+	Config      *oauth2.Config
+	UserInfoURL string
+	IssuerURL   string // Set for generic OIDC providers; endpoints are resolved via discovery
+
+	// UnverifiedEmails marks providers that don't assert email ownership; their emails are
+	// never treated as verified, whatever the claims say
+	UnverifiedEmails bool
+
+	mu sync.Mutex
+}
+
+// OAuthAuthorization is the provider redirect plus the PKCE verifier the caller
+// must keep (e.g. in an HttpOnly cookie) until the callback
+type OAuthAuthorization struct {
+	URL      string
+	State    string
+	Verifier string
+}
+
+// oauthState is the signed payload carried through the provider round trip
+type oauthState struct {
+	Provider  string `json:"p"`
+	Nonce     string `json:"n"`
+	Challenge string `json:"c"` // S256 PKCE challenge, binds the state to the verifier cookie
+	ExpiresAt int64  `json:"e"`
+}
+
+// oidcDiscoveryDocument holds the fields used from /.well-known/openid-configuration
+type oidcDiscoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+type OAuthService struct {
+	db          *gorm.DB
+	authService *MultiOrgAuthService
+	httpClient  *http.Client
+	providers   map[string]*OAuthProvider
+}
+
+// NewOAuthService creates the OAuth service with the providers configured in the environment
+func NewOAuthService(authService *MultiOrgAuthService) *OAuthService {
+	s := &OAuthService{
+		db:          config.DB,
+		authService: authService,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+		providers:   make(map[string]*OAuthProvider),
+	}
+
+	cfg := config.GetOAuthConfig()
+	scopes := []string{"openid", "email", "profile"}
+
+	if cfg.GoogleClientID != "" {
+		s.RegisterProvider(&OAuthProvider{
+			Name: "google",
+			Config: &oauth2.Config{
+				ClientID:     cfg.GoogleClientID,
+				ClientSecret: cfg.GoogleClientSecret,
+				RedirectURL:  cfg.GoogleRedirectURL,
+				Scopes:       scopes,
+				Endpoint:     google.Endpoint,
+			},
+			UserInfoURL: "https://openidconnect.googleapis.com/v1/userinfo",
+		})
+	}
+
+	if cfg.MicrosoftClientID != "" {
+		// Microsoft does not assert email ownership, so these emails are treated as unverified
+		s.RegisterProvider(&OAuthProvider{
+			Name: "microsoft",
+			Config: &oauth2.Config{
+				ClientID:     cfg.MicrosoftClientID,
+				ClientSecret: cfg.MicrosoftClientSecret,
+				RedirectURL:  cfg.MicrosoftRedirectURL,
+				Scopes:       scopes,
+				Endpoint:     microsoft.AzureADEndpoint("common"),
+			},
+			UserInfoURL:      "https://graph.microsoft.com/oidc/userinfo",
+			UnverifiedEmails: true,
+		})
+	}
+
+	if cfg.OIDCIssuerURL != "" && cfg.OIDCClientID != "" {
+		s.RegisterProvider(&OAuthProvider{
+			Name: strings.ToLower(cfg.OIDCProviderName),
+			Config: &oauth2.Config{
+				ClientID:     cfg.OIDCClientID,
+				ClientSecret: cfg.OIDCClientSecret,
+				RedirectURL:  cfg.OIDCRedirectURL,
+				Scopes:       scopes,
+			},
+			IssuerURL: strings.TrimSuffix(cfg.OIDCIssuerURL, "/"),
+		})
+	}
+
+	return s
+}
+
+// RegisterProvider adds or replaces an identity provider
+func (s *OAuthService) RegisterProvider(provider *OAuthProvider) {
+	s.providers[provider.Name] = provider
+}
+
+// GetProvider returns a configured provider, resolving OIDC discovery on first use
+func (s *OAuthService) GetProvider(ctx context.Context, name string) (*OAuthProvider, error) {
+	provider, ok := s.providers[strings.ToLower(name)]
+	if !ok {
+		return nil, ErrUnknownOAuthProvider
+	}
+
+	if provider.IssuerURL != "" {
+		if err := s.discover(ctx, provider); err != nil {
+			return nil, err
+		}
+	}
+
+	return provider, nil
+}
+
+// BeginAuthorization builds the provider redirect with a signed state and a PKCE challenge
+func (s *OAuthService) BeginAuthorization(ctx context.Context, providerName string) (*OAuthAuthorization, error) {
+	provider, err := s.GetProvider(ctx, providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	verifier := oauth2.GenerateVerifier()
+	state, err := signOAuthState(&oauthState{
+		Provider:  provider.Name,
+		Nonce:     nonce,
+		Challenge: oauth2.S256ChallengeFromVerifier(verifier),
+		ExpiresAt: time.Now().Add(oauthStateTTL).Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	url := provider.Config.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.S256ChallengeOption(verifier))
+
+	return &OAuthAuthorization{
+		URL:      url,
+		State:    state,
+		Verifier: verifier,
+	}, nil
+}
+
+// CompleteAuthorization verifies the callback, resolves the local user and issues a multi-org session
+func (s *OAuthService) CompleteAuthorization(ctx context.Context, providerName, state, code, verifier string) (*MultiOrgLoginResponse, error) {
+	provider, err := s.GetProvider(ctx, providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	if code == "" || verifier == "" {
+		return nil, ErrInvalidOAuthState
+	}
+
+	if err := verifyOAuthState(state, provider.Name, verifier); err != nil {
+		return nil, err
+	}
+
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, s.httpClient)
+	token, err := provider.Config.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange authorization code: %v", err)
+	}
+
+	info, err := s.fetchUserInfo(ctx, provider, token)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.resolveUser(provider.Name, info)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.authService.LoginWithIdentity(ctx, user, "")
+}
+
+// GetUserIdentities returns the external identities linked to a user
+func (s *OAuthService) GetUserIdentities(userID string) ([]models.UserIdentity, error) {
+	var identities []models.UserIdentity
+	err := s.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&identities).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get identities: %v", err)
+	}
+	return identities, nil
+}
+
+// =====================================================
+// IDENTITY RESOLUTION
+// =====================================================
+
+// resolveUser finds the user for an external identity, linking or creating accounts as allowed,
+// and joins them to the organizations that auto-join a verified email's domain. Nothing is
+// created for a sign-in that would end up without an organization.
+func (s *OAuthService) resolveUser(providerName string, info *OAuthUserInfo) (*models.GlobalUser, error) {
+	now := time.Now()
+
+	// Existing link always wins, regardless of the email currently on the provider account
+	var identity models.UserIdentity
+	err := s.db.Where("provider = ? AND subject = ?", providerName, info.Subject).First(&identity).Error
+	if err == nil {
+		var user models.GlobalUser
+		if err := s.db.Where("id = ? AND deleted_at IS NULL", identity.UserID).First(&user).Error; err != nil {
+			return nil, fmt.Errorf("linked user not found: %v", err)
+		}
+		if err := s.db.Model(&identity).Updates(map[string]interface{}{
+			"email":          info.Email,
+			"email_verified": info.EmailVerified,
+			"last_login_at":  now,
+		}).Error; err != nil {
+			return nil, fmt.Errorf("failed to update identity: %v", err)
+		}
+		if info.EmailVerified {
+			orgs, err := s.autoJoinOrganizations(info.Email)
+			if err != nil {
+				return nil, err
+			}
+			if err := joinOrganizations(s.db, &user, orgs); err != nil {
+				return nil, err
+			}
+		}
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to look up identity: %v", err)
+	}
+
+	if info.Email == "" {
+		return nil, errors.New("identity provider did not return an email address")
+	}
+	email := strings.ToLower(info.Email)
+
+	var user models.GlobalUser
+	existing := true
+	err = s.db.Where("email = ? AND deleted_at IS NULL", email).First(&user).Error
+	switch {
+	case err == nil:
+		// Only a provider-verified email may be attached to an existing account
+		if !info.EmailVerified {
+			return nil, ErrUnverifiedIdentityEmail
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		existing = false
+		user = models.GlobalUser{
+			Email:         email,
+			Name:          info.Name,
+			EmailVerified: info.EmailVerified,
+		}
+		if user.Name == "" {
+			user.Name = email
+		}
+		if info.EmailVerified {
+			user.EmailVerifiedAt = &now
+		}
+	default:
+		return nil, fmt.Errorf("failed to look up user: %v", err)
+	}
+
+	// Decide on membership before creating anything
+	var orgs []models.Organization
+	if info.EmailVerified {
+		if orgs, err = s.autoJoinOrganizations(email); err != nil {
+			return nil, err
+		}
+	}
+	if len(orgs) == 0 {
+		var memberships int64
+		if existing {
+			if err := s.db.Model(&models.OrganizationMember{}).Where("user_id = ? AND status = ?", user.ID, "active").
+				Count(&memberships).Error; err != nil {
+				return nil, fmt.Errorf("failed to check memberships: %v", err)
+			}
+		}
+		if memberships == 0 {
+			return nil, ErrNoActiveMemberships
+		}
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if !existing {
+			if err := tx.Create(&user).Error; err != nil {
+				return fmt.Errorf("failed to create user: %v", err)
+			}
+		}
+		identity = models.UserIdentity{
+			UserID:        user.ID,
+			Provider:      providerName,
+			Subject:       info.Subject,
+			Email:         email,
+			EmailVerified: info.EmailVerified,
+			LastLoginAt:   &now,
+		}
+		if err := tx.Create(&identity).Error; err != nil {
+			return fmt.Errorf("failed to link identity: %v", err)
+		}
+		return joinOrganizations(tx, &user, orgs)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// autoJoinOrganizations returns the active organizations that auto-join the email's domain
+func (s *OAuthService) autoJoinOrganizations(email string) ([]models.Organization, error) {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return nil, nil
+	}
+	domain := strings.ToLower(email[at+1:])
+
+	var orgs []models.Organization
+	err := s.db.Where("LOWER(auto_join_domain) = ? AND is_active = ?", domain, true).Find(&orgs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to find auto-join organizations: %v", err)
+	}
+	return orgs, nil
+}
+
+// joinOrganizations adds the user to the organizations they don't belong to yet
+func joinOrganizations(db *gorm.DB, user *models.GlobalUser, orgs []models.Organization) error {
+	for _, org := range orgs {
+		var count int64
+		if err := db.Model(&models.OrganizationMember{}).
+			Where("user_id = ? AND organization_id = ?", user.ID, org.ID).
+			Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to check membership: %v", err)
+		}
+		if count > 0 {
+			continue
+		}
+
+		var memberships int64
+		if err := db.Model(&models.OrganizationMember{}).Where("user_id = ?", user.ID).Count(&memberships).Error; err != nil {
+			return fmt.Errorf("failed to count memberships: %v", err)
+		}
+
+		role := org.AutoJoinRole
+		if role == "" {
+			role = "inspector"
+		}
+		permissionsJSON, err := json.Marshal(utils.GetDefaultPermissions(role))
+		if err != nil {
+			return fmt.Errorf("failed to marshal permissions: %v", err)
+		}
+
+		membership := &models.OrganizationMember{
+			UserID:         user.ID,
+			OrganizationID: org.ID,
+			Role:           role,
+			Permissions:    datatypes.JSON(permissionsJSON),
+			IsPrimary:      memberships == 0,
+			Status:         "active",
+		}
+		if err := db.Create(membership).Error; err != nil {
+			return fmt.Errorf("failed to join organization: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// =====================================================
+// PROVIDER PROTOCOL HELPERS
+// =====================================================
+
+// discover resolves OIDC endpoints from the issuer's discovery document
+func (s *OAuthService) discover(ctx context.Context, provider *OAuthProvider) error {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	if provider.Config.Endpoint.AuthURL != "" {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, provider.IssuerURL+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch OIDC discovery document: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("OIDC discovery returned status %d", resp.StatusCode)
+	}
+
+	var doc oidcDiscoveryDocument
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return fmt.Errorf("failed to decode OIDC discovery document: %v", err)
+	}
+
+	if strings.TrimSuffix(doc.Issuer, "/") != provider.IssuerURL {
+		return fmt.Errorf("OIDC issuer mismatch: expected %s, got %s", provider.IssuerURL, doc.Issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.UserInfoEndpoint == "" {
+		return errors.New("OIDC discovery document is missing required endpoints")
+	}
+
+	provider.Config.Endpoint = oauth2.Endpoint{
+		AuthURL:  doc.AuthorizationEndpoint,
+		TokenURL: doc.TokenEndpoint,
+	}
+	provider.UserInfoURL = doc.UserInfoEndpoint
+
+	return nil
+}
+
+// fetchUserInfo calls the provider's userinfo endpoint and normalizes the claims
+func (s *OAuthService) fetchUserInfo(ctx context.Context, provider *OAuthProvider, token *oauth2.Token) (*OAuthUserInfo, error) {
+	client := provider.Config.Client(ctx, token)
+	resp, err := client.Get(provider.UserInfoURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user info: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("user info request returned status %d", resp.StatusCode)
+	}
+
+	var claims struct {
+		Subject           string      `json:"sub"`
+		Email             string      `json:"email"`
+		EmailVerified     interface{} `json:"email_verified"` // Some providers send "true" as a string
+		Name              string      `json:"name"`
+		PreferredUsername string      `json:"preferred_username"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&claims); err != nil {
+		return nil, fmt.Errorf("failed to decode user info: %v", err)
+	}
+
+	if claims.Subject == "" {
+		return nil, errors.New("identity provider did not return a subject")
+	}
+
+	info := &OAuthUserInfo{
+		Subject: claims.Subject,
+		Email:   claims.Email,
+		Name:    claims.Name,
+	}
+	switch v := claims.EmailVerified.(type) {
+	case bool:
+		info.EmailVerified = v
+	case string:
+		info.EmailVerified = strings.EqualFold(v, "true")
+	}
+	if provider.UnverifiedEmails {
+		info.EmailVerified = false
+	}
+	if info.Name == "" {
+		info.Name = claims.PreferredUsername
+	}
+
+	return info, nil
+}
+
+func signOAuthState(state *oauthState) (string, error) {
+	payload, err := json.Marshal(state)
+	if err != nil {
+		return "", err
+	}
+
+	secret, err := utils.GetJWTSecret()
+	if err != nil {
+		return "", fmt.Errorf("failed to get JWT secret: %w", err)
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("oauth-state." + encoded))
+
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+func verifyOAuthState(raw, providerName, verifier string) error {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 2 {
+		return ErrInvalidOAuthState
+	}
+
+	secret, err := utils.GetJWTSecret()
+	if err != nil {
+		return fmt.Errorf("failed to get JWT secret: %w", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ErrInvalidOAuthState
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("oauth-state." + parts[0]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return ErrInvalidOAuthState
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return ErrInvalidOAuthState
+	}
+	var state oauthState
+	if err := json.Unmarshal(payload, &state); err != nil {
+		return ErrInvalidOAuthState
+	}
+
+	if state.Provider != providerName || time.Now().Unix() > state.ExpiresAt {
+		return ErrInvalidOAuthState
+	}
+	challenge := oauth2.S256ChallengeFromVerifier(verifier)
+	if !hmac.Equal([]byte(state.Challenge), []byte(challenge)) {
+		return ErrInvalidOAuthState
+	}
+
+	return nil
+}
+
+func randomToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
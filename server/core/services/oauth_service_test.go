@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"resource-mgmt/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+// fakeOIDCProvider is a local OpenID Connect stand-in that enforces PKCE
+type fakeOIDCProvider struct {
+	server    *httptest.Server
+	challenge string
+	claims    map[string]interface{}
+}
+
+func newFakeOIDCProvider(t *testing.T) *fakeOIDCProvider {
+	p := &fakeOIDCProvider{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.server.URL,
+			"authorization_endpoint": p.server.URL + "/authorize",
+			"token_endpoint":         p.server.URL + "/token",
+			"userinfo_endpoint":      p.server.URL + "/userinfo",
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if oauth2.S256ChallengeFromVerifier(r.Form.Get("code_verifier")) != p.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(p.claims)
+	})
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func setupOAuthTestService(t *testing.T) (*OAuthService, *fakeOIDCProvider, *gorm.DB) {
+	t.Setenv("JWT_SECRET", "test-secret-that-is-at-least-32-characters-long")
+
+	db := setupServiceTestDB(t, &models.GlobalUser{}, &models.Organization{}, &models.OrganizationMember{}, &models.UserSession{}, &models.UserIdentity{})
+
+	provider := newFakeOIDCProvider(t)
+	service := NewOAuthService(NewMultiOrgAuthService())
+	service.RegisterProvider(&OAuthProvider{
+		Name: "keycloak",
+		Config: &oauth2.Config{
+			ClientID:     "client",
+			ClientSecret: "secret",
+			RedirectURL:  "http://localhost/api/v1/auth/oidc/callback",
+			Scopes:       []string{"openid", "email", "profile"},
+		},
+		IssuerURL: provider.server.URL,
+	})
+
+	return service, provider, db
+}
+
+// authorize runs the redirect leg and returns the state and verifier the callback receives
+func authorize(t *testing.T, service *OAuthService, provider *fakeOIDCProvider) (string, string) {
+	authorization, err := service.BeginAuthorization(context.Background(), "keycloak")
+	require.NoError(t, err)
+
+	redirect, err := url.Parse(authorization.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "S256", redirect.Query().Get("code_challenge_method"))
+	assert.Equal(t, authorization.State, redirect.Query().Get("state"))
+	provider.challenge = redirect.Query().Get("code_challenge")
+
+	return authorization.State, authorization.Verifier
+}
+
+func TestOAuthService_RejectsForgedOrUnboundState(t *testing.T) {
+	service, provider, _ := setupOAuthTestService(t)
+	ctx := context.Background()
+
+	state, verifier := authorize(t, service, provider)
+
+	_, err := service.CompleteAuthorization(ctx, "keycloak", "state-token", "code", verifier)
+	assert.ErrorIs(t, err, ErrInvalidOAuthState)
+
+	_, err = service.CompleteAuthorization(ctx, "keycloak", state, "code", oauth2.GenerateVerifier())
+	assert.ErrorIs(t, err, ErrInvalidOAuthState)
+
+	_, err = service.CompleteAuthorization(ctx, "keycloak", state+"x", "code", verifier)
+	assert.ErrorIs(t, err, ErrInvalidOAuthState)
+
+	_, err = service.CompleteAuthorization(ctx, "okta", state, "code", verifier)
+	assert.ErrorIs(t, err, ErrUnknownOAuthProvider)
+}
+
+func TestOAuthService_VerifiedEmailAutoJoinsOrganization(t *testing.T) {
+	service, provider, db := setupOAuthTestService(t)
+
+	domain := "acme.com"
+	org := &models.Organization{ID: "org-acme", Name: "Acme", Domain: "acme", Slug: "acme", IsActive: true, AutoJoinDomain: &domain, AutoJoinRole: "inspector"}
+	require.NoError(t, db.Create(org).Error)
+
+	provider.claims = map[string]interface{}{
+		"sub":            "kc-123",
+		"email":          "Jane@Acme.com",
+		"email_verified": true,
+		"name":           "Jane Doe",
+	}
+
+	state, verifier := authorize(t, service, provider)
+	response, err := service.CompleteAuthorization(context.Background(), "keycloak", state, "code", verifier)
+	require.NoError(t, err)
+
+	assert.NotEmpty(t, response.Token)
+	assert.NotEmpty(t, response.RefreshToken)
+	require.NotNil(t, response.CurrentOrganization)
+	assert.Equal(t, org.ID, response.CurrentOrganization.ID)
+	assert.Equal(t, "jane@acme.com", response.User.Email)
+
+	var identity models.UserIdentity
+	require.NoError(t, db.Where("provider = ? AND subject = ?", "keycloak", "kc-123").First(&identity).Error)
+	assert.Equal(t, response.User.ID, identity.UserID)
+
+	var member models.OrganizationMember
+	require.NoError(t, db.Where("user_id = ? AND organization_id = ?", response.User.ID, org.ID).First(&member).Error)
+	assert.Equal(t, "inspector", member.Role)
+	assert.True(t, member.IsPrimary)
+
+	var sessions int64
+	db.Model(&models.UserSession{}).Where("user_id = ?", response.User.ID).Count(&sessions)
+	assert.Equal(t, int64(1), sessions)
+}
+
+func TestOAuthService_UnverifiedEmailDoesNotLinkExistingUser(t *testing.T) {
+	service, provider, db := setupOAuthTestService(t)
+
+	existing := &models.GlobalUser{ID: "user-existing", Email: "admin@acme.com", Name: "Admin"}
+	require.NoError(t, db.Create(existing).Error)
+
+	provider.claims = map[string]interface{}{
+		"sub":            "attacker",
+		"email":          "admin@acme.com",
+		"email_verified": "false",
+	}
+
+	state, verifier := authorize(t, service, provider)
+	_, err := service.CompleteAuthorization(context.Background(), "keycloak", state, "code", verifier)
+	assert.ErrorIs(t, err, ErrUnverifiedIdentityEmail)
+
+	var identities int64
+	db.Model(&models.UserIdentity{}).Count(&identities)
+	assert.Equal(t, int64(0), identities)
+}
+
+func TestOAuthService_RejectedSignInCreatesNoAccount(t *testing.T) {
+	tests := []struct {
+		name             string
+		claims           map[string]interface{}
+		unverifiedEmails bool
+	}{
+		{name: "unverified email", claims: map[string]interface{}{"sub": "kc-1", "email": "jane@acme.com", "email_verified": false}},
+		{name: "no auto-join organization", claims: map[string]interface{}{"sub": "kc-2", "email": "jane@elsewhere.com", "email_verified": true}},
+		{name: "provider that doesn't verify emails", claims: map[string]interface{}{"sub": "kc-3", "email": "jane@acme.com", "email_verified": true}, unverifiedEmails: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, provider, db := setupOAuthTestService(t)
+			domain := "acme.com"
+			require.NoError(t, db.Create(&models.Organization{ID: "org-acme", Name: "Acme", Domain: "acme", Slug: "acme", IsActive: true, AutoJoinDomain: &domain}).Error)
+			service.providers["keycloak"].UnverifiedEmails = tt.unverifiedEmails
+			provider.claims = tt.claims
+
+			state, verifier := authorize(t, service, provider)
+			_, err := service.CompleteAuthorization(context.Background(), "keycloak", state, "code", verifier)
+			assert.ErrorIs(t, err, ErrNoActiveMemberships)
+
+			var users, identities int64
+			db.Model(&models.GlobalUser{}).Count(&users)
+			db.Model(&models.UserIdentity{}).Count(&identities)
+			assert.Zero(t, users)
+			assert.Zero(t, identities)
+		})
+	}
+}
@@ -0,0 +1,798 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"resource-mgmt/config"
+	"resource-mgmt/models"
+	"resource-mgmt/pkg/database"
+	"resource-mgmt/utils"
+	"strings"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Open pool errors
+var (
+	// ErrInvalidPoolItem is returned when published work is missing or malformed
+	ErrInvalidPoolItem = errors.New("invalid open pool item")
+	// ErrSelfAssignmentDisabled is returned when publishing work of a project that doesn't
+	// allow self-assignment
+	ErrSelfAssignmentDisabled = errors.New("project does not allow self-assignment")
+	// ErrPoolItemNotFound is returned when the item doesn't exist in the organization
+	ErrPoolItemNotFound = errors.New("open pool item not found")
+	// ErrPoolItemUnavailable is returned when the item was already claimed or withdrawn
+	ErrPoolItemUnavailable = errors.New("open pool item is no longer available")
+	// ErrPoolClaimNotEligible is returned when the inspector may not claim the item
+	ErrPoolClaimNotEligible = errors.New("not eligible to claim this item")
+)
+
+// maxPoolBatchSites caps how many sites one published batch may cover
+const maxPoolBatchSites = 100
+
+// openPoolAlertType is the WorkflowAlert type raised for unclaimed items nearing their due date
+const openPoolAlertType = "escalation"
+
+// PoolFilters narrow the open pool listing. InspectorID is whose distance and eligibility
+// the listing shows.
+type PoolFilters struct {
+	Status        string
+	ProjectID     string
+	InspectorID   string
+	MaxDistanceKm *float64   // Only items with a site within this distance of the inspector's base
+	DueBefore     *time.Time // Only items due before this time
+	EligibleOnly  bool
+}
+
+// OpenPoolService lets supervisors publish unassigned work and eligible inspectors claim it
+type OpenPoolService struct {
+	db                  *gorm.DB
+	workflowService     *WorkflowService
+	solver              *AssignmentSolverService
+	notificationService *NotificationService
+}
+
+// NewOpenPoolService creates the pool. Claimed site batches become assignments through
+// the workflow service, and eligibility uses the same inspector checks as the assignment
+// solver.
+func NewOpenPoolService(db *gorm.DB, workflowService *WorkflowService) *OpenPoolService {
+	return &OpenPoolService{
+		db:                  db,
+		workflowService:     workflowService,
+		solver:              NewAssignmentSolverService(db, workflowService),
+		notificationService: workflowService.notificationService,
+	}
+}
+
+// defaultOpenPoolEscalationWindow reads OPEN_POOL_ESCALATION_WINDOW, falling back to 48 hours
+func defaultOpenPoolEscalationWindow() time.Duration {
+	window, err := time.ParseDuration(config.OpenPoolEscalationWindow)
+	if err != nil || window <= 0 {
+		return 48 * time.Hour
+	}
+	return window
+}
+
+// =====================================================
+// PUBLISHING
+// =====================================================
+
+// Publish puts work in the open pool: each unassigned inspection in req.InspectionIDs as
+// its own item, and req.SiteIDs as one batch
+func (s *OpenPoolService) Publish(ctx context.Context, organizationID, userID string, req *models.PublishToPoolRequest) ([]models.OpenPoolItem, error) {
+	siteIDs := uniqueStrings(req.SiteIDs)
+	inspectionIDs := uniqueStrings(req.InspectionIDs)
+	if len(siteIDs) == 0 && len(inspectionIDs) == 0 {
+		return nil, fmt.Errorf("%w: site_ids or inspection_ids is required", ErrInvalidPoolItem)
+	}
+	if len(siteIDs) > maxPoolBatchSites {
+		return nil, fmt.Errorf("%w: a batch can cover at most %d sites", ErrInvalidPoolItem, maxPoolBatchSites)
+	}
+	if req.ScheduledFor != nil && req.DueDate != nil && req.DueDate.Before(*req.ScheduledFor) {
+		return nil, fmt.Errorf("%w: due_date is before scheduled_for", ErrInvalidPoolItem)
+	}
+
+	db := database.Conn(ctx, s.db)
+
+	if req.ProjectID != nil && *req.ProjectID != "" {
+		var project models.InspectionProject
+		if err := db.Where("id = ? AND organization_id = ?", *req.ProjectID, organizationID).First(&project).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("%w: project not found", ErrInvalidPoolItem)
+			}
+			return nil, fmt.Errorf("failed to get project: %v", err)
+		}
+		if !project.AllowSelfAssignment {
+			return nil, ErrSelfAssignmentDisabled
+		}
+	} else {
+		req.ProjectID = nil
+	}
+
+	priority := req.Priority
+	if priority == "" {
+		priority = "medium"
+	}
+	newItem := func(templateID string, siteIDs []string) models.OpenPoolItem {
+		siteIDsJSON, _ := json.Marshal(siteIDs)
+		return models.OpenPoolItem{
+			OrganizationID:       organizationID,
+			ProjectID:            req.ProjectID,
+			TemplateID:           templateID,
+			SiteIDs:              datatypes.JSON(siteIDsJSON),
+			Title:                strings.TrimSpace(req.Title),
+			Description:          req.Description,
+			Priority:             priority,
+			ScheduledFor:         req.ScheduledFor,
+			DueDate:              req.DueDate,
+			EstimatedHours:       req.EstimatedHours,
+			RequiresConfirmation: req.RequiresConfirmation,
+			Status:               models.OpenPoolItemOpen,
+			PublishedBy:          userID,
+		}
+	}
+
+	var items []models.OpenPoolItem
+
+	if len(siteIDs) > 0 {
+		if strings.TrimSpace(req.Title) == "" {
+			return nil, fmt.Errorf("%w: a site batch needs a title", ErrInvalidPoolItem)
+		}
+		var template models.Template
+		if err := db.Where("id = ? AND organization_id = ?", req.TemplateID, organizationID).First(&template).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("%w: template not found", ErrInvalidPoolItem)
+			}
+			return nil, fmt.Errorf("failed to get template: %v", err)
+		}
+		var found int64
+		if err := db.Model(&models.Site{}).Where("id IN ? AND organization_id = ?", siteIDs, organizationID).Count(&found).Error; err != nil {
+			return nil, fmt.Errorf("failed to get sites: %v", err)
+		}
+		if int(found) != len(siteIDs) {
+			return nil, fmt.Errorf("%w: some sites were not found", ErrInvalidPoolItem)
+		}
+		items = append(items, newItem(template.ID.String(), siteIDs))
+	}
+
+	if len(inspectionIDs) > 0 {
+		var inspections []models.Inspection
+		if err := db.Where("id IN ? AND organization_id = ?", inspectionIDs, organizationID).Find(&inspections).Error; err != nil {
+			return nil, fmt.Errorf("failed to get inspections: %v", err)
+		}
+		if len(inspections) != len(inspectionIDs) {
+			return nil, fmt.Errorf("%w: some inspections were not found", ErrInvalidPoolItem)
+		}
+
+		var pooled int64
+		if err := db.Model(&models.OpenPoolItem{}).
+			Where("organization_id = ? AND inspection_id IN ? AND status IN ?", organizationID, inspectionIDs, []string{models.OpenPoolItemOpen, models.OpenPoolItemClaimed}).
+			Count(&pooled).Error; err != nil {
+			return nil, fmt.Errorf("failed to check the open pool: %v", err)
+		}
+		if pooled > 0 {
+			return nil, fmt.Errorf("%w: some inspections are already in the open pool", ErrInvalidPoolItem)
+		}
+
+		for _, inspection := range inspections {
+			if inspection.InspectorID != "" {
+				return nil, fmt.Errorf("%w: inspection %s is already assigned", ErrInvalidPoolItem, inspection.ID)
+			}
+			if containsString(closedInspectionStatuses, inspection.Status) {
+				return nil, fmt.Errorf("%w: inspection %s is %s", ErrInvalidPoolItem, inspection.ID, inspection.Status)
+			}
+
+			item := newItem(inspection.TemplateID.String(), []string{inspection.SiteID})
+			inspectionID := inspection.ID.String()
+			item.InspectionID = &inspectionID
+			if item.Title == "" {
+				item.Title = "Inspection " + inspectionID
+			}
+			if inspection.ScheduledFor != nil {
+				item.ScheduledFor = inspection.ScheduledFor
+			}
+			if inspection.DueDate != nil {
+				item.DueDate = inspection.DueDate
+			}
+			if req.Priority == "" && inspection.Priority != "" {
+				item.Priority = inspection.Priority
+			}
+			items = append(items, item)
+		}
+	}
+
+	if err := db.Create(&items).Error; err != nil {
+		return nil, fmt.Errorf("failed to publish to the open pool: %v", err)
+	}
+	return items, nil
+}
+
+// getItem returns one pool item of the organization
+func (s *OpenPoolService) getItem(ctx context.Context, organizationID, itemID string) (*models.OpenPoolItem, error) {
+	var item models.OpenPoolItem
+	if err := database.Conn(ctx, s.db).Where("id = ? AND organization_id = ?", itemID, organizationID).First(&item).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPoolItemNotFound
+		}
+		return nil, fmt.Errorf("failed to get open pool item: %v", err)
+	}
+	return &item, nil
+}
+
+// =====================================================
+// BROWSING AND ELIGIBILITY
+// =====================================================
+
+// GetPool lists pool items, open ones unless filtered otherwise, soonest due first. With
+// filters.InspectorID set, each item says how far it is from that inspector and whether
+// they may claim it.
+func (s *OpenPoolService) GetPool(ctx context.Context, organizationID string, filters PoolFilters) ([]models.OpenPoolListing, error) {
+	db := database.Conn(ctx, s.db)
+
+	status := filters.Status
+	if status == "" {
+		status = models.OpenPoolItemOpen
+	}
+	query := db.Where("organization_id = ? AND status = ?", organizationID, status)
+	if filters.ProjectID != "" {
+		query = query.Where("project_id = ?", filters.ProjectID)
+	}
+	if filters.DueBefore != nil {
+		query = query.Where("due_date < ?", *filters.DueBefore)
+	}
+	var items []models.OpenPoolItem
+	if err := query.Order("due_date IS NULL, due_date, created_at").Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("failed to get open pool: %v", err)
+	}
+
+	sites, err := s.loadSites(ctx, organizationID, items)
+	if err != nil {
+		return nil, err
+	}
+
+	checker := &poolEligibility{service: s, organizationID: organizationID, inspectorID: filters.InspectorID}
+	if filters.InspectorID != "" {
+		if err := checker.loadMember(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	listings := []models.OpenPoolListing{}
+	for _, item := range items {
+		listing := models.OpenPoolListing{OpenPoolItem: item}
+		itemSites := sites[item.ID]
+
+		var candidate *autoAssignCandidate
+		if filters.InspectorID != "" {
+			candidate, listing.Reasons, err = checker.check(ctx, &item, itemSites)
+			if err != nil {
+				return nil, err
+			}
+			listing.Eligible = len(listing.Reasons) == 0
+		}
+
+		for _, site := range itemSites {
+			entry := models.OpenPoolSite{ID: site.ID, Name: site.Name, City: site.City, State: site.State}
+			if candidate != nil {
+				entry.DistanceKm = candidate.distanceTo(site)
+				if entry.DistanceKm != nil && (listing.DistanceKm == nil || *entry.DistanceKm < *listing.DistanceKm) {
+					listing.DistanceKm = entry.DistanceKm
+				}
+			}
+			listing.Sites = append(listing.Sites, entry)
+		}
+
+		if filters.MaxDistanceKm != nil && (listing.DistanceKm == nil || *listing.DistanceKm > *filters.MaxDistanceKm) {
+			continue
+		}
+		if filters.EligibleOnly && !listing.Eligible {
+			continue
+		}
+		listings = append(listings, listing)
+	}
+	return listings, nil
+}
+
+// loadSites returns the sites of each item, in the order the item lists them
+func (s *OpenPoolService) loadSites(ctx context.Context, organizationID string, items []models.OpenPoolItem) (map[string][]models.Site, error) {
+	siteIDsByItem := make(map[string][]string, len(items))
+	var allIDs []string
+	for _, item := range items {
+		var ids []string
+		if err := json.Unmarshal(item.SiteIDs, &ids); err != nil {
+			return nil, fmt.Errorf("failed to read open pool item sites: %v", err)
+		}
+		siteIDsByItem[item.ID] = ids
+		allIDs = append(allIDs, ids...)
+	}
+
+	sitesByItem := make(map[string][]models.Site, len(items))
+	if len(allIDs) == 0 {
+		return sitesByItem, nil
+	}
+	var sites []models.Site
+	if err := database.Conn(ctx, s.db).Where("id IN ? AND organization_id = ?", uniqueStrings(allIDs), organizationID).Find(&sites).Error; err != nil {
+		return nil, fmt.Errorf("failed to get sites: %v", err)
+	}
+	byID := make(map[string]models.Site, len(sites))
+	for _, site := range sites {
+		byID[site.ID] = site
+	}
+	for itemID, ids := range siteIDsByItem {
+		for _, id := range ids {
+			if site, ok := byID[id]; ok {
+				sitesByItem[itemID] = append(sitesByItem[itemID], site)
+			}
+		}
+	}
+	return sitesByItem, nil
+}
+
+// poolEligibility checks one inspector against pool items, reusing what it loaded for
+// items that share a template, project and day
+type poolEligibility struct {
+	service        *OpenPoolService
+	organizationID string
+	inspectorID    string
+
+	member     *models.OrganizationMember
+	categories map[string]string
+	candidates map[string]*autoAssignCandidate
+}
+
+// loadMember looks the inspector up in the organization; a missing or inactive member
+// is simply not eligible for anything
+func (e *poolEligibility) loadMember(ctx context.Context) error {
+	var member models.OrganizationMember
+	err := database.Conn(ctx, e.service.db).
+		Where("organization_id = ? AND user_id = ? AND status = ?", e.organizationID, e.inspectorID, "active").
+		First(&member).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to get organization member: %v", err)
+	}
+	if err == nil {
+		e.member = &member
+	}
+	return nil
+}
+
+// check returns the inspector's candidate record for the item, and the reasons they may
+// not claim it; no reasons means they may
+func (e *poolEligibility) check(ctx context.Context, item *models.OpenPoolItem, sites []models.Site) (*autoAssignCandidate, []string, error) {
+	if e.member == nil {
+		return nil, []string{"not an active member of the organization"}, nil
+	}
+	if !utils.HasHigherOrEqualPrivilege(e.member.Role, "inspector") {
+		return nil, []string{"role " + e.member.Role + " cannot take inspections"}, nil
+	}
+
+	if e.categories == nil {
+		e.categories = make(map[string]string)
+		e.candidates = make(map[string]*autoAssignCandidate)
+	}
+	category, ok := e.categories[item.TemplateID]
+	if !ok {
+		var template models.Template
+		if err := database.Conn(ctx, e.service.db).Select("id", "category").
+			Where("id = ? AND organization_id = ?", item.TemplateID, e.organizationID).First(&template).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, fmt.Errorf("failed to get template: %v", err)
+		}
+		category = template.Category
+		e.categories[item.TemplateID] = category
+	}
+
+	projectID := ""
+	if item.ProjectID != nil {
+		projectID = *item.ProjectID
+	}
+	scheduled := time.Now()
+	if item.ScheduledFor != nil {
+		scheduled = *item.ScheduledFor
+	}
+	day := time.Date(scheduled.Year(), scheduled.Month(), scheduled.Day(), 0, 0, 0, 0, scheduled.Location())
+
+	key := strings.Join([]string{item.TemplateID, projectID, day.Format(civilDateLayout)}, "|")
+	candidate, ok := e.candidates[key]
+	if !ok {
+		candidates, err := e.service.solver.loadCandidates(ctx, e.organizationID, []string{e.inspectorID}, item.TemplateID, category, projectID, day)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(candidates) == 1 {
+			candidate = candidates[0]
+		}
+		e.candidates[key] = candidate
+	}
+	if candidate == nil {
+		return nil, []string{"not an active member of the organization"}, nil
+	}
+
+	reasons := append([]string{}, candidate.blocked...)
+
+	if len(sites) > candidate.dailyLeft {
+		reasons = append(reasons, fmt.Sprintf("%d of %d daily inspections free on %s, %d needed",
+			candidate.dailyLeft, candidate.workload.MaxDailyInspections, day.Format(civilDateLayout), len(sites)))
+	} else if len(sites) > candidate.weeklyLeft {
+		reasons = append(reasons, fmt.Sprintf("%d of %d weekly inspections free, %d needed",
+			candidate.weeklyLeft, candidate.workload.MaxWeeklyInspections, len(sites)))
+	}
+
+	if len(candidate.regions) > 0 {
+		inRegion := false
+		for _, site := range sites {
+			for _, region := range []string{site.State, site.City, site.Country, site.ZipCode} {
+				if region != "" && containsString(candidate.regions, strings.ToLower(region)) {
+					inRegion = true
+				}
+			}
+		}
+		if !inRegion {
+			reasons = append(reasons, "outside preferred regions")
+		}
+	}
+
+	for _, site := range sites {
+		if distance := candidate.distanceTo(site); distance != nil && *distance > float64(candidate.workload.MaxTravelDistance) {
+			reasons = append(reasons, fmt.Sprintf("%s is %.1f km away, travels at most %d km", site.Name, *distance, candidate.workload.MaxTravelDistance))
+		}
+	}
+
+	return candidate, reasons, nil
+}
+
+// =====================================================
+// CLAIMING
+// =====================================================
+
+// Claim gives an open item to the inspector if they are eligible. The first claim wins;
+// later ones get ErrPoolItemUnavailable. Items that need confirmation wait for a
+// supervisor, others are assigned straight away.
+func (s *OpenPoolService) Claim(ctx context.Context, organizationID, inspectorID, itemID string) (*models.OpenPoolItem, error) {
+	item, err := s.getItem(ctx, organizationID, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if item.Status != models.OpenPoolItemOpen {
+		return nil, ErrPoolItemUnavailable
+	}
+
+	sites, err := s.loadSites(ctx, organizationID, []models.OpenPoolItem{*item})
+	if err != nil {
+		return nil, err
+	}
+	checker := &poolEligibility{service: s, organizationID: organizationID, inspectorID: inspectorID}
+	if err := checker.loadMember(ctx); err != nil {
+		return nil, err
+	}
+	_, reasons, err := checker.check(ctx, item, sites[item.ID])
+	if err != nil {
+		return nil, err
+	}
+	if len(reasons) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrPoolClaimNotEligible, strings.Join(reasons, "; "))
+	}
+
+	// Only one claim can move the item out of open. The claim and the assignment it
+	// leads to commit together, so a failed assignment leaves the item open.
+	now := time.Now()
+	err = database.Conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		claim := tx.Model(&models.OpenPoolItem{}).
+			Where("id = ? AND status = ?", item.ID, models.OpenPoolItemOpen).
+			Updates(map[string]interface{}{"status": models.OpenPoolItemClaimed, "claimed_by": inspectorID, "claimed_at": now})
+		if claim.Error != nil {
+			return fmt.Errorf("failed to claim open pool item: %v", claim.Error)
+		}
+		if claim.RowsAffected == 0 {
+			return ErrPoolItemUnavailable
+		}
+		item.Status = models.OpenPoolItemClaimed
+		item.ClaimedBy = &inspectorID
+		item.ClaimedAt = &now
+
+		if item.RequiresConfirmation {
+			return nil
+		}
+		return s.assign(database.ContextWithTx(ctx, tx), item, inspectorID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if item.RequiresConfirmation {
+		s.notify(ctx, item, s.responsibleFor(ctx, item), "Open Pool Claim Awaiting Confirmation",
+			fmt.Sprintf("'%s' was claimed from the open pool and needs your confirmation", item.Title))
+	}
+	return item, nil
+}
+
+// ConfirmClaim assigns a claimed item to the inspector who claimed it
+func (s *OpenPoolService) ConfirmClaim(ctx context.Context, organizationID, userID, itemID string) (*models.OpenPoolItem, error) {
+	item, err := s.getItem(ctx, organizationID, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if item.Status != models.OpenPoolItemClaimed || item.ClaimedBy == nil {
+		return nil, ErrPoolItemUnavailable
+	}
+
+	// Claim the confirmation so two supervisors can't both assign the item. A failed
+	// assignment rolls the confirmation back with it.
+	now := time.Now()
+	err = database.Conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		claim := tx.Model(&models.OpenPoolItem{}).
+			Where("id = ? AND status = ?", item.ID, models.OpenPoolItemClaimed).
+			Updates(map[string]interface{}{"status": models.OpenPoolItemAssigned, "confirmed_by": userID, "confirmed_at": now})
+		if claim.Error != nil {
+			return fmt.Errorf("failed to confirm open pool claim: %v", claim.Error)
+		}
+		if claim.RowsAffected == 0 {
+			return ErrPoolItemUnavailable
+		}
+		item.ConfirmedBy = &userID
+		item.ConfirmedAt = &now
+
+		return s.assign(database.ContextWithTx(ctx, tx), item, *item.ClaimedBy)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// RejectClaim turns down a claim waiting for confirmation and puts the item back in the pool
+func (s *OpenPoolService) RejectClaim(ctx context.Context, organizationID, itemID, reason string) (*models.OpenPoolItem, error) {
+	item, err := s.getItem(ctx, organizationID, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if item.Status != models.OpenPoolItemClaimed || item.ClaimedBy == nil {
+		return nil, ErrPoolItemUnavailable
+	}
+
+	result := database.Conn(ctx, s.db).Model(&models.OpenPoolItem{}).
+		Where("id = ? AND status = ?", item.ID, models.OpenPoolItemClaimed).
+		Updates(map[string]interface{}{"status": models.OpenPoolItemOpen, "claimed_by": nil, "claimed_at": nil})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to reject open pool claim: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrPoolItemUnavailable
+	}
+
+	message := fmt.Sprintf("Your claim on '%s' was not confirmed", item.Title)
+	if reason != "" {
+		message += ". Reason: " + reason
+	}
+	s.notify(ctx, item, *item.ClaimedBy, "Open Pool Claim Rejected", message)
+
+	item.Status = models.OpenPoolItemOpen
+	item.ClaimedBy = nil
+	item.ClaimedAt = nil
+	return item, nil
+}
+
+// Withdraw takes an open or claimed item out of the pool
+func (s *OpenPoolService) Withdraw(ctx context.Context, organizationID, itemID string) (*models.OpenPoolItem, error) {
+	item, err := s.getItem(ctx, organizationID, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := database.Conn(ctx, s.db).Model(&models.OpenPoolItem{}).
+		Where("id = ? AND status IN ?", item.ID, []string{models.OpenPoolItemOpen, models.OpenPoolItemClaimed}).
+		Update("status", models.OpenPoolItemWithdrawn)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to withdraw open pool item: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrPoolItemUnavailable
+	}
+
+	if item.Status == models.OpenPoolItemClaimed && item.ClaimedBy != nil {
+		s.notify(ctx, item, *item.ClaimedBy, "Open Pool Item Withdrawn", fmt.Sprintf("'%s', which you claimed, was withdrawn from the open pool", item.Title))
+	}
+	item.Status = models.OpenPoolItemWithdrawn
+	return item, nil
+}
+
+// assign hands the item's work to the inspector: a published inspection gets them as its
+// inspector, and a site batch becomes a self-assigned assignment. The work is assigned on
+// behalf of whoever published it.
+func (s *OpenPoolService) assign(ctx context.Context, item *models.OpenPoolItem, inspectorID string) error {
+	db := database.Conn(ctx, s.db)
+	updates := map[string]interface{}{"status": models.OpenPoolItemAssigned}
+
+	if item.InspectionID != nil {
+		var before models.Inspection
+		if err := db.Where("id = ? AND organization_id = ?", *item.InspectionID, item.OrganizationID).First(&before).Error; err != nil {
+			return fmt.Errorf("failed to get inspection: %v", err)
+		}
+		if before.InspectorID != "" {
+			return ErrPoolItemUnavailable
+		}
+
+		scheduled := time.Now()
+		if before.ScheduledFor != nil && before.ScheduledFor.After(scheduled) {
+			scheduled = *before.ScheduledFor
+		}
+		if err := s.workflowService.availabilityService.EnsureAvailable(ctx, item.OrganizationID, inspectorID, scheduled); err != nil {
+			return err
+		}
+
+		result := db.Model(&models.Inspection{}).
+			Where("id = ? AND (inspector_id IS NULL OR inspector_id = '')", before.ID).
+			Updates(map[string]interface{}{"inspector_id": inspectorID, "assigned_by": item.PublishedBy, "status": "assigned"})
+		if result.Error != nil {
+			return fmt.Errorf("failed to assign inspection: %v", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrPoolItemUnavailable
+		}
+
+		after := before
+		after.InspectorID = inspectorID
+		after.Status = "assigned"
+		if err := s.workflowService.workloadMetrics.InspectionChanged(ctx, &before, &after); err != nil {
+			log.Printf("Failed to update workload metrics for inspection %s: %v", before.ID, err)
+		}
+		if err := s.workflowService.slaService.InspectionChanged(ctx, &after); err != nil {
+			log.Printf("Failed to update SLA clocks for inspection %s: %v", before.ID, err)
+		}
+		s.notify(ctx, item, inspectorID, "New Inspection Assignment", fmt.Sprintf("You have been assigned '%s' from the open pool", item.Title))
+	} else {
+		var siteIDs []string
+		if err := json.Unmarshal(item.SiteIDs, &siteIDs); err != nil {
+			return fmt.Errorf("failed to read open pool item sites: %v", err)
+		}
+		assignments, err := s.workflowService.CreateBulkAssignment(ctx, item.OrganizationID, item.PublishedBy, map[string]interface{}{
+			"name":                  item.Title,
+			"description":           item.Description,
+			"project_id":            item.ProjectID,
+			"priority":              item.Priority,
+			"template_id":           item.TemplateID,
+			"site_ids":              siteIDs,
+			"inspector_assignments": []map[string]interface{}{{"inspector_id": inspectorID, "site_ids": siteIDs}},
+			"start_date":            item.ScheduledFor,
+			"due_date":              item.DueDate,
+			"estimated_hours":       item.EstimatedHours,
+			"metadata":              map[string]interface{}{"open_pool_item_id": item.ID},
+		})
+		if err != nil {
+			return err
+		}
+		if len(assignments) > 0 {
+			assignmentID := assignments[0].ID
+			if err := db.Model(&models.InspectionAssignment{}).Where("id = ?", assignmentID).Update("assignment_type", "self").Error; err != nil {
+				return fmt.Errorf("failed to mark assignment as self-assigned: %v", err)
+			}
+			updates["assignment_id"] = assignmentID
+			item.AssignmentID = &assignmentID
+		}
+	}
+
+	if err := db.Model(&models.OpenPoolItem{}).Where("id = ?", item.ID).Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to update open pool item: %v", err)
+	}
+	item.Status = models.OpenPoolItemAssigned
+	return nil
+}
+
+// responsibleFor is who looks after an item: its project's manager, or whoever published it
+func (s *OpenPoolService) responsibleFor(ctx context.Context, item *models.OpenPoolItem) string {
+	if item.ProjectID != nil {
+		var project models.InspectionProject
+		if err := database.Conn(ctx, s.db).Select("id", "project_manager").Where("id = ?", *item.ProjectID).First(&project).Error; err == nil && project.ProjectManager != "" {
+			return project.ProjectManager
+		}
+	}
+	return item.PublishedBy
+}
+
+// notify sends an open pool notification, logging rather than failing when it can't
+func (s *OpenPoolService) notify(ctx context.Context, item *models.OpenPoolItem, userID, title, message string) {
+	if _, err := s.notificationService.CreateNotificationWithContext(ctx, &models.CreateNotificationRequest{
+		OrganizationID: item.OrganizationID,
+		UserID:         userID,
+		Title:          title,
+		Message:        message,
+		Type:           "open_pool",
+	}); err != nil {
+		log.Printf("Failed to notify %s about open pool item %s: %v", userID, item.ID, err)
+	}
+}
+
+// =====================================================
+// ESCALATION
+// =====================================================
+
+// EscalateUnclaimed escalates open items due within the escalation window to their
+// project manager, or to whoever published them, once per item. It returns how many
+// items were escalated.
+func (s *OpenPoolService) EscalateUnclaimed(ctx context.Context, now time.Time) (int, error) {
+	db := database.Conn(ctx, s.db)
+
+	var items []models.OpenPoolItem
+	if err := db.Where("status = ? AND escalated_at IS NULL AND due_date IS NOT NULL AND due_date <= ?",
+		models.OpenPoolItemOpen, now.Add(defaultOpenPoolEscalationWindow())).
+		Order("organization_id, due_date").Find(&items).Error; err != nil {
+		return 0, fmt.Errorf("failed to get unclaimed open pool items: %v", err)
+	}
+
+	escalated := 0
+	for i := range items {
+		item := &items[i]
+
+		// Claim the escalation so another server running the check skips it
+		claim := db.Model(&models.OpenPoolItem{}).
+			Where("id = ? AND status = ? AND escalated_at IS NULL", item.ID, models.OpenPoolItemOpen).
+			Update("escalated_at", now)
+		if claim.Error != nil {
+			return escalated, fmt.Errorf("failed to mark open pool escalation: %v", claim.Error)
+		}
+		if claim.RowsAffected == 0 {
+			continue
+		}
+
+		recipient := s.responsibleFor(ctx, item)
+		severity, title := "medium", "Open Pool Item Unclaimed"
+		message := fmt.Sprintf("Nobody has claimed '%s', due %s. Assign it directly or extend the due date.", item.Title, item.DueDate.Format("2006-01-02 15:04"))
+		if item.DueDate.Before(now) {
+			severity, title = "high", "Open Pool Item Overdue"
+		}
+		s.notify(ctx, item, recipient, title, message)
+
+		details, _ := json.Marshal(map[string]interface{}{
+			"project_id": item.ProjectID,
+			"due_date":   item.DueDate,
+			"site_ids":   item.SiteIDs,
+		})
+		notifyUsers, _ := json.Marshal([]string{recipient})
+		if err := db.Create(&models.WorkflowAlert{
+			OrganizationID: item.OrganizationID,
+			AlertType:      openPoolAlertType,
+			Severity:       severity,
+			Status:         "active",
+			TargetType:     "open_pool_item",
+			TargetID:       item.ID,
+			Title:          title,
+			Message:        message,
+			Details:        datatypes.JSON(details),
+			AssignedTo:     recipient,
+			NotifyUsers:    datatypes.JSON(notifyUsers),
+			TriggeredAt:    now,
+			AutoResolve:    true,
+		}).Error; err != nil {
+			log.Printf("Failed to raise alert for open pool item %s: %v", item.ID, err)
+		}
+		escalated++
+	}
+	return escalated, nil
+}
+
+// StartEscalationChecker runs EscalateUnclaimed now and then every interval until ctx is done
+func (s *OpenPoolService) StartEscalationChecker(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			escalated, err := s.EscalateUnclaimed(ctx, time.Now())
+			if err != nil {
+				log.Printf("Open pool escalation check failed: %v", err)
+			} else if escalated > 0 {
+				log.Printf("Open pool escalation check escalated %d items", escalated)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
@@ -0,0 +1,448 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"resource-mgmt/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// openPoolTestFixture is a fire safety template and a project managed by manager-1 that
+// doesn't allow self-assignment yet. insp-near and insp-downtown are fire inspectors based
+// in Chicago, insp-far is one in Springfield and insp-plumbing has the wrong
+// specialization. Two Chicago sites have coordinates, Lakeview has none.
+type openPoolTestFixture struct {
+	db                       *gorm.DB
+	service                  *OpenPoolService
+	template                 *models.Template
+	project                  *models.InspectionProject
+	loop, westSide, lakeview *models.Site
+	day, due                 time.Time
+}
+
+func newOpenPoolTestFixture(t *testing.T) *openPoolTestFixture {
+	db := setupWorkflowTestDB(t, &models.InspectionProject{}, &models.OpenPoolItem{}, &models.WorkflowAlert{})
+	f := &openPoolTestFixture{db: db, service: NewOpenPoolService(db, NewWorkflowService(db, NewNotificationService()))}
+	createTestMembers(t, db, "org-a", "supervisor", "super-1", "manager-1")
+	createTestMembers(t, db, "org-a", "inspector", "insp-near", "insp-downtown", "insp-far", "insp-plumbing")
+	coords := func(lat, lng float64) (*float64, *float64) { return &lat, &lng }
+	workload := func(inspectorID, specialization string, lat, lng *float64) {
+		require.NoError(t, db.Create(&models.InspectorWorkload{
+			OrganizationID: "org-a", InspectorID: inspectorID, MaxDailyInspections: 8, MaxWeeklyInspections: 40, MaxConcurrentProjects: 5,
+			IsAvailable: true, Specializations: datatypes.JSON(`["` + specialization + `"]`), MaxTravelDistance: 30, BaseLatitude: lat, BaseLongitude: lng,
+		}).Error)
+	}
+	lat, lng := coords(41.88, -87.63) // Chicago
+	workload("insp-near", "fire", lat, lng)
+	workload("insp-downtown", "fire", lat, lng)
+	workload("insp-plumbing", "plumbing", lat, lng)
+	lat, lng = coords(39.78, -89.65) // Springfield
+	workload("insp-far", "fire", lat, lng)
+
+	f.template = &models.Template{ID: uuid.New(), OrganizationID: "org-a", Name: "Fire safety", Category: "Fire", FieldsSchema: datatypes.JSON(`{}`)}
+	require.NoError(t, db.Create(f.template).Error)
+	f.project = &models.InspectionProject{ID: uuid.NewString(), OrganizationID: "org-a", Name: "Fire audit", ProjectCode: "FA-1", ProjectManager: "manager-1"}
+	require.NoError(t, db.Create(f.project).Error)
+
+	site := func(name string, lat, lng *float64) *models.Site {
+		s := &models.Site{ID: uuid.NewString(), OrganizationID: "org-a", Name: name, Address: "1 Main St", City: "Chicago", Latitude: lat, Longitude: lng, Status: "active"}
+		require.NoError(t, db.Create(s).Error)
+		return s
+	}
+	lat, lng = coords(41.90, -87.65)
+	f.loop = site("Loop Warehouse", lat, lng)
+	lat, lng = coords(41.85, -87.70)
+	f.westSide = site("West Side Office", lat, lng)
+	f.lakeview = site("Lakeview Store", nil, nil)
+
+	f.day = civilDate(time.Now()).AddDate(0, 0, 3)
+	f.due = f.day.Add(17 * time.Hour)
+	return f
+}
+
+func (f *openPoolTestFixture) allowSelfAssignment(t *testing.T) {
+	require.NoError(t, f.db.Model(f.project).Update("allow_self_assignment", true).Error)
+}
+
+func (f *openPoolTestFixture) batchRequest() *models.PublishToPoolRequest {
+	return &models.PublishToPoolRequest{ProjectID: &f.project.ID, TemplateID: f.template.ID.String(), SiteIDs: []string{f.loop.ID, f.westSide.ID},
+		Title: "Downtown fire checks", ScheduledFor: &f.day, DueDate: &f.due}
+}
+
+// publishBatch publishes the two downtown sites as one batch that is assigned on claim
+func (f *openPoolTestFixture) publishBatch(t *testing.T) *models.OpenPoolItem {
+	f.allowSelfAssignment(t)
+	batch, err := f.service.Publish(context.Background(), "org-a", "super-1", f.batchRequest())
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+	return &batch[0]
+}
+
+// publishInspection publishes an unassigned Lakeview inspection whose claims need confirmation
+func (f *openPoolTestFixture) publishInspection(t *testing.T) (*models.OpenPoolItem, *models.Inspection) {
+	f.allowSelfAssignment(t)
+	unassigned := &models.Inspection{OrganizationID: "org-a", TemplateID: f.template.ID, SiteID: f.lakeview.ID, Status: "draft", ScheduledFor: &f.day, DueDate: &f.due}
+	require.NoError(t, f.db.Create(unassigned).Error)
+	published, err := f.service.Publish(context.Background(), "org-a", "super-1", &models.PublishToPoolRequest{ProjectID: &f.project.ID,
+		InspectionIDs: []string{unassigned.ID.String()}, RequiresConfirmation: true})
+	require.NoError(t, err)
+	require.Len(t, published, 1)
+	return &published[0], unassigned
+}
+
+func (f *openPoolTestFixture) itemStatus(t *testing.T, itemID string) string {
+	var item models.OpenPoolItem
+	require.NoError(t, f.db.First(&item, "id = ?", itemID).Error)
+	return item.Status
+}
+
+func TestOpenPoolService_PublishValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(t *testing.T, f *openPoolTestFixture) *models.PublishToPoolRequest
+		wantErr error
+	}{
+		{"project without self-assignment", func(t *testing.T, f *openPoolTestFixture) *models.PublishToPoolRequest {
+			return f.batchRequest()
+		}, ErrSelfAssignmentDisabled},
+		{"unknown project", func(t *testing.T, f *openPoolTestFixture) *models.PublishToPoolRequest {
+			req := f.batchRequest()
+			projectID := uuid.NewString()
+			req.ProjectID = &projectID
+			return req
+		}, ErrInvalidPoolItem},
+		{"nothing to publish", func(t *testing.T, f *openPoolTestFixture) *models.PublishToPoolRequest {
+			f.allowSelfAssignment(t)
+			return &models.PublishToPoolRequest{ProjectID: &f.project.ID, TemplateID: f.template.ID.String()}
+		}, ErrInvalidPoolItem},
+		{"due before it is scheduled", func(t *testing.T, f *openPoolTestFixture) *models.PublishToPoolRequest {
+			f.allowSelfAssignment(t)
+			req := f.batchRequest()
+			early := f.day.Add(-time.Hour)
+			req.DueDate = &early
+			return req
+		}, ErrInvalidPoolItem},
+		{"inspection already in the pool", func(t *testing.T, f *openPoolTestFixture) *models.PublishToPoolRequest {
+			_, unassigned := f.publishInspection(t)
+			return &models.PublishToPoolRequest{InspectionIDs: []string{unassigned.ID.String()}}
+		}, ErrInvalidPoolItem},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newOpenPoolTestFixture(t)
+			_, err := f.service.Publish(context.Background(), "org-a", "super-1", tt.prepare(t, f))
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestOpenPoolService_PublishInspectionKeepsItsSchedule(t *testing.T) {
+	f := newOpenPoolTestFixture(t)
+
+	published, _ := f.publishInspection(t)
+	assert.Equal(t, f.day, published.ScheduledFor.UTC())
+	assert.Equal(t, models.OpenPoolItemOpen, published.Status)
+}
+
+func TestOpenPoolService_GetPoolExplainsEligibility(t *testing.T) {
+	maxDistance := 10.0
+	tests := []struct {
+		name      string
+		filters   PoolFilters
+		wantItems func(batch, inspection *models.OpenPoolItem) []string
+		check     func(t *testing.T, listings []models.OpenPoolListing)
+	}{
+		{"nearby inspector", PoolFilters{InspectorID: "insp-near"},
+			func(batch, inspection *models.OpenPoolItem) []string { return []string{batch.ID, inspection.ID} },
+			func(t *testing.T, listings []models.OpenPoolListing) {
+				for _, listing := range listings {
+					assert.True(t, listing.Eligible, listing.Reasons)
+				}
+				require.NotNil(t, listings[0].DistanceKm)
+				assert.Less(t, *listings[0].DistanceKm, 5.0)
+			}},
+		{"wrong specialization", PoolFilters{InspectorID: "insp-plumbing"},
+			func(batch, inspection *models.OpenPoolItem) []string { return []string{batch.ID, inspection.ID} },
+			func(t *testing.T, listings []models.OpenPoolListing) {
+				assert.False(t, listings[0].Eligible)
+				assert.Contains(t, listings[0].Reasons, "no fire specialization")
+			}},
+		{"eligible only, out of reach", PoolFilters{InspectorID: "insp-far", EligibleOnly: true},
+			func(batch, inspection *models.OpenPoolItem) []string { return []string{inspection.ID} }, nil},
+		{"within a distance", PoolFilters{InspectorID: "insp-near", MaxDistanceKm: &maxDistance},
+			func(batch, inspection *models.OpenPoolItem) []string { return []string{batch.ID} }, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newOpenPoolTestFixture(t)
+			batch := f.publishBatch(t)
+			inspection, _ := f.publishInspection(t)
+
+			listings, err := f.service.GetPool(context.Background(), "org-a", tt.filters)
+			require.NoError(t, err)
+			var ids []string
+			for _, listing := range listings {
+				ids = append(ids, listing.ID)
+			}
+			assert.ElementsMatch(t, tt.wantItems(batch, inspection), ids)
+			if tt.check != nil {
+				tt.check(t, listings)
+			}
+		})
+	}
+}
+
+func TestOpenPoolService_ClaimBecomesSelfAssignment(t *testing.T) {
+	f := newOpenPoolTestFixture(t)
+	batch := f.publishBatch(t)
+
+	claimed, err := f.service.Claim(context.Background(), "org-a", "insp-near", batch.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.OpenPoolItemAssigned, claimed.Status)
+	require.NotNil(t, claimed.AssignmentID)
+
+	var assignment models.InspectionAssignment
+	require.NoError(t, f.db.Where("id = ?", *claimed.AssignmentID).First(&assignment).Error)
+	assert.Equal(t, "self", assignment.AssignmentType)
+	assert.Equal(t, "insp-near", assignment.AssignedTo)
+	assert.Equal(t, "super-1", assignment.AssignedBy)
+	created := countRows(t, f.db.Model(&models.Inspection{}).Where("assignment_id = ? AND inspector_id = ?", assignment.ID, "insp-near"))
+	assert.Equal(t, int64(2), created)
+}
+
+func TestOpenPoolService_FailedAssignmentRollsBackClaim(t *testing.T) {
+	f := newOpenPoolTestFixture(t)
+	batch := f.publishBatch(t)
+	require.NoError(t, f.db.Callback().Update().Before("gorm:update").Register("test:fail_self_assignment", func(tx *gorm.DB) {
+		if tx.Statement.Table == "inspection_assignments" {
+			tx.AddError(errors.New("disk full"))
+		}
+	}))
+
+	_, err := f.service.Claim(context.Background(), "org-a", "insp-near", batch.ID)
+	require.ErrorContains(t, err, "disk full")
+
+	// The assignment, its inspections and the notice were written in the claim's transaction
+	assert.Equal(t, models.OpenPoolItemOpen, f.itemStatus(t, batch.ID))
+	assert.Zero(t, countRows(t, f.db.Model(&models.InspectionAssignment{})))
+	assert.Zero(t, countRows(t, f.db.Model(&models.Inspection{}).Where("inspector_id = ?", "insp-near")))
+	assert.Zero(t, countRows(t, f.db.Model(&models.Notification{}).Where("user_id = ?", "insp-near")))
+}
+
+func TestOpenPoolService_ClaimRejections(t *testing.T) {
+	tests := []struct {
+		name         string
+		organization string
+		inspectorID  string
+		prepare      func(t *testing.T, f *openPoolTestFixture, item *models.OpenPoolItem) string
+		wantErr      error
+		wantStatus   string
+	}{
+		{"ineligible inspector", "org-a", "insp-plumbing", func(t *testing.T, f *openPoolTestFixture, item *models.OpenPoolItem) string {
+			return item.ID
+		}, ErrPoolClaimNotEligible, models.OpenPoolItemOpen},
+		{"already claimed", "org-a", "insp-downtown", func(t *testing.T, f *openPoolTestFixture, item *models.OpenPoolItem) string {
+			_, err := f.service.Claim(context.Background(), "org-a", "insp-near", item.ID)
+			require.NoError(t, err)
+			return item.ID
+		}, ErrPoolItemUnavailable, models.OpenPoolItemAssigned},
+		{"withdrawn", "org-a", "insp-near", func(t *testing.T, f *openPoolTestFixture, item *models.OpenPoolItem) string {
+			withdrawn, err := f.service.Withdraw(context.Background(), "org-a", item.ID)
+			require.NoError(t, err)
+			assert.Equal(t, models.OpenPoolItemWithdrawn, withdrawn.Status)
+			return item.ID
+		}, ErrPoolItemUnavailable, models.OpenPoolItemWithdrawn},
+		{"other organization", "org-b", "insp-near", func(t *testing.T, f *openPoolTestFixture, item *models.OpenPoolItem) string {
+			return item.ID
+		}, ErrPoolItemNotFound, models.OpenPoolItemOpen},
+		{"unknown item", "org-a", "insp-near", func(t *testing.T, f *openPoolTestFixture, item *models.OpenPoolItem) string {
+			return uuid.NewString()
+		}, ErrPoolItemNotFound, models.OpenPoolItemOpen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newOpenPoolTestFixture(t)
+			item := f.publishBatch(t)
+			itemID := tt.prepare(t, f, item)
+			before := countRows(t, f.db.Model(&models.InspectionAssignment{}))
+
+			_, err := f.service.Claim(context.Background(), tt.organization, tt.inspectorID, itemID)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantStatus, f.itemStatus(t, item.ID))
+			assert.Equal(t, before, countRows(t, f.db.Model(&models.InspectionAssignment{})), "the losing claim assigns nothing")
+		})
+	}
+}
+
+func TestOpenPoolService_ConcurrentClaimsHaveOneWinner(t *testing.T) {
+	tests := []struct {
+		name       string
+		publish    func(t *testing.T, f *openPoolTestFixture) *models.OpenPoolItem
+		wantStatus string
+	}{
+		{"assigned on claim", func(t *testing.T, f *openPoolTestFixture) *models.OpenPoolItem { return f.publishBatch(t) }, models.OpenPoolItemAssigned},
+		{"awaiting confirmation", func(t *testing.T, f *openPoolTestFixture) *models.OpenPoolItem {
+			item, _ := f.publishInspection(t)
+			return item
+		}, models.OpenPoolItemClaimed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newOpenPoolTestFixture(t)
+			// Every goroutine has to see the same in-memory database
+			sqlDB, err := f.db.DB()
+			require.NoError(t, err)
+			sqlDB.SetMaxOpenConns(1)
+			item := tt.publish(t, f)
+
+			claimants := []string{"insp-near", "insp-downtown", "insp-near", "insp-downtown"}
+			errs := make([]error, len(claimants))
+			var wg sync.WaitGroup
+			for i, inspectorID := range claimants {
+				wg.Add(1)
+				go func(i int, inspectorID string) {
+					defer wg.Done()
+					_, errs[i] = f.service.Claim(context.Background(), "org-a", inspectorID, item.ID)
+				}(i, inspectorID)
+			}
+			wg.Wait()
+
+			winners := 0
+			for _, err := range errs {
+				if err == nil {
+					winners++
+					continue
+				}
+				assert.ErrorIs(t, err, ErrPoolItemUnavailable)
+			}
+			assert.Equal(t, 1, winners)
+			assert.Equal(t, tt.wantStatus, f.itemStatus(t, item.ID))
+			assignments := countRows(t, f.db.Model(&models.InspectionAssignment{}).Where("assignment_type = ?", "self"))
+			assert.LessOrEqual(t, assignments, int64(1), "the item is never assigned twice")
+		})
+	}
+}
+
+func TestOpenPoolService_ConfirmedClaimAssignsInspection(t *testing.T) {
+	f := newOpenPoolTestFixture(t)
+	ctx := context.Background()
+	item, unassigned := f.publishInspection(t)
+
+	claimed, err := f.service.Claim(ctx, "org-a", "insp-far", item.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.OpenPoolItemClaimed, claimed.Status)
+	managerNotices := countRows(t, f.db.Model(&models.Notification{}).Where("user_id = ?", "manager-1"))
+	assert.Equal(t, int64(1), managerNotices)
+
+	confirmed, err := f.service.ConfirmClaim(ctx, "org-a", "super-1", item.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.OpenPoolItemAssigned, confirmed.Status)
+	var inspection models.Inspection
+	require.NoError(t, f.db.Where("id = ?", unassigned.ID).First(&inspection).Error)
+	assert.Equal(t, "insp-far", inspection.InspectorID)
+	assert.Equal(t, "assigned", inspection.Status)
+}
+
+func TestOpenPoolService_ReviewClaimRaces(t *testing.T) {
+	tests := []struct {
+		name       string
+		first      func(f *openPoolTestFixture, itemID string) error
+		second     func(f *openPoolTestFixture, itemID string) error
+		wantStatus string
+	}{
+		{"confirmed after being rejected",
+			func(f *openPoolTestFixture, itemID string) error {
+				_, err := f.service.RejectClaim(context.Background(), "org-a", itemID, "Needs a senior inspector")
+				return err
+			},
+			func(f *openPoolTestFixture, itemID string) error {
+				_, err := f.service.ConfirmClaim(context.Background(), "org-a", "super-1", itemID)
+				return err
+			}, models.OpenPoolItemOpen},
+		{"confirmed twice",
+			func(f *openPoolTestFixture, itemID string) error {
+				_, err := f.service.ConfirmClaim(context.Background(), "org-a", "super-1", itemID)
+				return err
+			},
+			func(f *openPoolTestFixture, itemID string) error {
+				_, err := f.service.ConfirmClaim(context.Background(), "org-a", "manager-1", itemID)
+				return err
+			}, models.OpenPoolItemAssigned},
+		{"rejected after being confirmed",
+			func(f *openPoolTestFixture, itemID string) error {
+				_, err := f.service.ConfirmClaim(context.Background(), "org-a", "super-1", itemID)
+				return err
+			},
+			func(f *openPoolTestFixture, itemID string) error {
+				_, err := f.service.RejectClaim(context.Background(), "org-a", itemID, "Too late")
+				return err
+			}, models.OpenPoolItemAssigned},
+		{"withdrawn after being confirmed",
+			func(f *openPoolTestFixture, itemID string) error {
+				_, err := f.service.ConfirmClaim(context.Background(), "org-a", "super-1", itemID)
+				return err
+			},
+			func(f *openPoolTestFixture, itemID string) error {
+				_, err := f.service.Withdraw(context.Background(), "org-a", itemID)
+				return err
+			}, models.OpenPoolItemAssigned},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newOpenPoolTestFixture(t)
+			item, _ := f.publishInspection(t)
+			_, err := f.service.Claim(context.Background(), "org-a", "insp-near", item.ID)
+			require.NoError(t, err)
+
+			require.NoError(t, tt.first(f, item.ID))
+			assert.ErrorIs(t, tt.second(f, item.ID), ErrPoolItemUnavailable)
+			assert.Equal(t, tt.wantStatus, f.itemStatus(t, item.ID))
+		})
+	}
+}
+
+func TestOpenPoolService_RejectedClaimCanBeClaimedAgain(t *testing.T) {
+	f := newOpenPoolTestFixture(t)
+	ctx := context.Background()
+	item, _ := f.publishInspection(t)
+	_, err := f.service.Claim(ctx, "org-a", "insp-near", item.ID)
+	require.NoError(t, err)
+	_, err = f.service.RejectClaim(ctx, "org-a", item.ID, "Needs a senior inspector")
+	require.NoError(t, err)
+
+	claimed, err := f.service.Claim(ctx, "org-a", "insp-far", item.ID)
+	require.NoError(t, err)
+	require.NotNil(t, claimed.ClaimedBy)
+	assert.Equal(t, "insp-far", *claimed.ClaimedBy)
+}
+
+func TestOpenPoolService_EscalateUnclaimed(t *testing.T) {
+	f := newOpenPoolTestFixture(t)
+	ctx := context.Background()
+	item := f.publishBatch(t)
+
+	escalated, err := f.service.EscalateUnclaimed(ctx, f.day.AddDate(0, 0, -7))
+	require.NoError(t, err)
+	assert.Equal(t, 0, escalated, "not due soon yet")
+
+	// Unclaimed work nearing its due date escalates to the project manager once
+	escalated, err = f.service.EscalateUnclaimed(ctx, f.day)
+	require.NoError(t, err)
+	assert.Equal(t, 1, escalated)
+	escalated, err = f.service.EscalateUnclaimed(ctx, f.day)
+	require.NoError(t, err)
+	assert.Equal(t, 0, escalated)
+
+	var alert models.WorkflowAlert
+	require.NoError(t, f.db.Where("target_id = ?", item.ID).First(&alert).Error)
+	assert.Equal(t, "manager-1", alert.AssignedTo)
+}
@@ -196,6 +196,13 @@ func (s *OrganizationService) UpdateOrganization(id string, req *models.UpdateOr
 		updates["is_active"] = *req.IsActive
 	}
 
+	if req.Timezone != "" {
+		if err := validateTimezone(req.Timezone); err != nil {
+			return nil, err
+		}
+		updates["timezone"] = req.Timezone
+	}
+
 	if req.Settings != nil {
 		settingsJSON, err := json.Marshal(req.Settings)
 		if err == nil {
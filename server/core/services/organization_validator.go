@@ -219,7 +219,7 @@ func (v *OrganizationValidator) validateInspectionAccess(context *ValidatedOrgan
 
 	// Check if user owns the inspection
 	var inspection models.Inspection
-	err := v.db.Select("inspector_id, organization_id").
+	err := v.db.Select("inspector_id, team_id, organization_id").
 		Where("id = ? AND organization_id = ?", inspectionID, context.Organization.ID).
 		First(&inspection).Error
 
@@ -235,6 +235,15 @@ func (v *OrganizationValidator) validateInspectionAccess(context *ValidatedOrgan
 		return nil
 	}
 
+	// Allow team leads, and members while it waits in their team's queue
+	scope, err := loadTeamScope(v.db.Statement.Context, v.db, context.Organization.ID, context.User.ID)
+	if err != nil {
+		return err
+	}
+	if scope.sees(inspection.InspectorID, inspection.TeamID) {
+		return nil
+	}
+
 	return ErrInsufficientPrivileges
 }
 
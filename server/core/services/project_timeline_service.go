@@ -0,0 +1,681 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"resource-mgmt/models"
+	"resource-mgmt/pkg/database"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrProjectNotFound is returned when a project doesn't exist in the organization
+	ErrProjectNotFound = errors.New("project not found")
+	// ErrInvalidMilestone is returned when a milestone fails validation
+	ErrInvalidMilestone = errors.New("invalid milestone")
+	// ErrMilestoneNotFound is returned when a milestone doesn't exist in the project
+	ErrMilestoneNotFound = errors.New("milestone not found")
+	// ErrInvalidDependency is returned when task dependencies leave the project or form a cycle
+	ErrInvalidDependency = errors.New("invalid task dependency")
+)
+
+// velocityWindow is how far back completed inspections count towards a project's velocity
+const velocityWindow = 14 * 24 * time.Hour
+
+// doneStepExecutionStatuses are the step execution statuses that finish a workflow step
+var doneStepExecutionStatuses = []string{"completed", "skipped"}
+
+// ProjectTimelineService manages project milestones and task dependencies, and lays a
+// project's work out as a timeline
+type ProjectTimelineService struct {
+	db *gorm.DB
+}
+
+func NewProjectTimelineService(db *gorm.DB) *ProjectTimelineService {
+	return &ProjectTimelineService{db: db}
+}
+
+// =====================================================
+// MILESTONES
+// =====================================================
+
+// GetMilestones lists the project's milestones by target date, with their progress and status
+func (s *ProjectTimelineService) GetMilestones(ctx context.Context, organizationID, projectID string) ([]models.ProjectMilestone, error) {
+	timeline, err := buildProjectTimeline(ctx, s.db, organizationID, projectID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return timeline.Milestones, nil
+}
+
+// CreateMilestone adds a milestone tied to some of the project's assignments and workflow steps
+func (s *ProjectTimelineService) CreateMilestone(ctx context.Context, organizationID, projectID, userID string, req *models.MilestoneRequest) (*models.ProjectMilestone, error) {
+	if err := requireProject(ctx, s.db, organizationID, projectID); err != nil {
+		return nil, err
+	}
+	milestone := &models.ProjectMilestone{OrganizationID: organizationID, ProjectID: projectID, CreatedBy: userID}
+	items, err := s.applyMilestone(ctx, milestone, req)
+	if err != nil {
+		return nil, err
+	}
+
+	err = database.Conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(milestone).Error; err != nil {
+			return err
+		}
+		return createMilestoneItems(tx, milestone.ID, items)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save milestone: %v", err)
+	}
+	return s.getMilestone(ctx, organizationID, projectID, milestone.ID)
+}
+
+// UpdateMilestone changes a milestone and replaces the work tied to it
+func (s *ProjectTimelineService) UpdateMilestone(ctx context.Context, organizationID, projectID, milestoneID string, req *models.MilestoneRequest) (*models.ProjectMilestone, error) {
+	milestone, err := s.findMilestone(ctx, organizationID, projectID, milestoneID)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.applyMilestone(ctx, milestone, req)
+	if err != nil {
+		return nil, err
+	}
+
+	err = database.Conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.ProjectMilestone{}).Where("id = ?", milestone.ID).Updates(map[string]interface{}{
+			"name":        milestone.Name,
+			"description": milestone.Description,
+			"target_date": milestone.TargetDate,
+			"updated_at":  time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("milestone_id = ?", milestone.ID).Delete(&models.ProjectMilestoneItem{}).Error; err != nil {
+			return err
+		}
+		return createMilestoneItems(tx, milestone.ID, items)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save milestone: %v", err)
+	}
+	return s.getMilestone(ctx, organizationID, projectID, milestone.ID)
+}
+
+// DeleteMilestone removes a milestone. The work tied to it is left as it is.
+func (s *ProjectTimelineService) DeleteMilestone(ctx context.Context, organizationID, projectID, milestoneID string) error {
+	milestone, err := s.findMilestone(ctx, organizationID, projectID, milestoneID)
+	if err != nil {
+		return err
+	}
+
+	return database.Conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("milestone_id = ?", milestone.ID).Delete(&models.ProjectMilestoneItem{}).Error; err != nil {
+			return fmt.Errorf("failed to delete milestone items: %v", err)
+		}
+		if err := tx.Delete(&models.ProjectMilestone{}, "id = ?", milestone.ID).Error; err != nil {
+			return fmt.Errorf("failed to delete milestone: %v", err)
+		}
+		return nil
+	})
+}
+
+func (s *ProjectTimelineService) findMilestone(ctx context.Context, organizationID, projectID, milestoneID string) (*models.ProjectMilestone, error) {
+	var milestone models.ProjectMilestone
+	if err := database.Conn(ctx, s.db).Where("id = ? AND organization_id = ? AND project_id = ?", milestoneID, organizationID, projectID).
+		First(&milestone).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMilestoneNotFound
+		}
+		return nil, fmt.Errorf("failed to get milestone: %v", err)
+	}
+	return &milestone, nil
+}
+
+// getMilestone returns the milestone with its progress and status
+func (s *ProjectTimelineService) getMilestone(ctx context.Context, organizationID, projectID, milestoneID string) (*models.ProjectMilestone, error) {
+	milestones, err := s.GetMilestones(ctx, organizationID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range milestones {
+		if milestones[i].ID == milestoneID {
+			return &milestones[i], nil
+		}
+	}
+	return nil, ErrMilestoneNotFound
+}
+
+// applyMilestone validates the request, copies it onto the milestone and returns the items
+// to tie to it
+func (s *ProjectTimelineService) applyMilestone(ctx context.Context, milestone *models.ProjectMilestone, req *models.MilestoneRequest) ([]models.ProjectMilestoneItem, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidMilestone)
+	}
+	if req.TargetDate.IsZero() {
+		return nil, fmt.Errorf("%w: target_date is required", ErrInvalidMilestone)
+	}
+	assignmentIDs, stepIDs := uniqueStrings(req.AssignmentIDs), uniqueStrings(req.WorkflowStepIDs)
+	if len(assignmentIDs) == 0 && len(stepIDs) == 0 {
+		return nil, fmt.Errorf("%w: assignment_ids or workflow_step_ids is required", ErrInvalidMilestone)
+	}
+
+	db := database.Conn(ctx, s.db)
+	if len(assignmentIDs) > 0 {
+		var found int64
+		if err := db.Model(&models.InspectionAssignment{}).
+			Where("id IN ? AND organization_id = ? AND project_id = ?", assignmentIDs, milestone.OrganizationID, milestone.ProjectID).
+			Count(&found).Error; err != nil {
+			return nil, fmt.Errorf("failed to check assignments: %v", err)
+		}
+		if int(found) != len(assignmentIDs) {
+			return nil, fmt.Errorf("%w: some assignments aren't part of the project", ErrInvalidMilestone)
+		}
+	}
+	if len(stepIDs) > 0 {
+		var found int64
+		if err := db.Model(&models.WorkflowStep{}).Where("id IN ? AND project_id = ?", stepIDs, milestone.ProjectID).
+			Count(&found).Error; err != nil {
+			return nil, fmt.Errorf("failed to check workflow steps: %v", err)
+		}
+		if int(found) != len(stepIDs) {
+			return nil, fmt.Errorf("%w: some workflow steps aren't part of the project", ErrInvalidMilestone)
+		}
+	}
+
+	milestone.Name = name
+	milestone.Description = req.Description
+	milestone.TargetDate = civilDate(req.TargetDate)
+
+	items := make([]models.ProjectMilestoneItem, 0, len(assignmentIDs)+len(stepIDs))
+	for i := range assignmentIDs {
+		items = append(items, models.ProjectMilestoneItem{OrganizationID: milestone.OrganizationID, AssignmentID: &assignmentIDs[i]})
+	}
+	for i := range stepIDs {
+		items = append(items, models.ProjectMilestoneItem{OrganizationID: milestone.OrganizationID, WorkflowStepID: &stepIDs[i]})
+	}
+	return items, nil
+}
+
+func createMilestoneItems(tx *gorm.DB, milestoneID string, items []models.ProjectMilestoneItem) error {
+	for i := range items {
+		items[i].MilestoneID = milestoneID
+	}
+	return tx.Create(&items).Error
+}
+
+// =====================================================
+// TASK DEPENDENCIES
+// =====================================================
+
+// SetDependencies replaces the assignments an assignment of the project depends on. Every
+// one must belong to the project, and none may depend on the assignment in turn.
+func (s *ProjectTimelineService) SetDependencies(ctx context.Context, organizationID, projectID, assignmentID, userID string, dependsOn []string) ([]models.ProjectTaskDependency, error) {
+	if err := requireProject(ctx, s.db, organizationID, projectID); err != nil {
+		return nil, err
+	}
+	db := database.Conn(ctx, s.db)
+	var projectAssignments []string
+	if err := db.Model(&models.InspectionAssignment{}).Where("organization_id = ? AND project_id = ?", organizationID, projectID).
+		Pluck("id", &projectAssignments).Error; err != nil {
+		return nil, fmt.Errorf("failed to get project assignments: %v", err)
+	}
+	if !containsString(projectAssignments, assignmentID) {
+		return nil, ErrAssignmentNotFound
+	}
+	dependsOn = uniqueStrings(dependsOn)
+	for _, id := range dependsOn {
+		if id == assignmentID {
+			return nil, fmt.Errorf("%w: an assignment can't depend on itself", ErrInvalidDependency)
+		}
+		if !containsString(projectAssignments, id) {
+			return nil, fmt.Errorf("%w: %s isn't part of the project", ErrInvalidDependency, id)
+		}
+	}
+
+	// The new dependencies must not lead back to the assignment
+	var others []models.ProjectTaskDependency
+	if err := db.Where("project_id = ? AND assignment_id <> ?", projectID, assignmentID).Find(&others).Error; err != nil {
+		return nil, fmt.Errorf("failed to get task dependencies: %v", err)
+	}
+	edges := make(map[string][]string)
+	for _, dependency := range others {
+		edges[dependency.AssignmentID] = append(edges[dependency.AssignmentID], dependency.DependsOnID)
+	}
+	seen := make(map[string]bool)
+	pending := append([]string{}, dependsOn...)
+	for len(pending) > 0 {
+		id := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if id == assignmentID {
+			return nil, fmt.Errorf("%w: dependencies would form a cycle", ErrInvalidDependency)
+		}
+		if !seen[id] {
+			seen[id] = true
+			pending = append(pending, edges[id]...)
+		}
+	}
+
+	dependencies := make([]models.ProjectTaskDependency, 0, len(dependsOn))
+	for _, id := range dependsOn {
+		dependencies = append(dependencies, models.ProjectTaskDependency{
+			OrganizationID: organizationID, ProjectID: projectID, AssignmentID: assignmentID, DependsOnID: id, CreatedBy: userID,
+		})
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("assignment_id = ?", assignmentID).Delete(&models.ProjectTaskDependency{}).Error; err != nil {
+			return err
+		}
+		if len(dependencies) == 0 {
+			return nil
+		}
+		return tx.Create(&dependencies).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save task dependencies: %v", err)
+	}
+	return dependencies, nil
+}
+
+// =====================================================
+// TIMELINE
+// =====================================================
+
+// GetTimeline lays the project's assignments and milestones out as Gantt tasks, with the
+// critical path through them and the project's projected completion
+func (s *ProjectTimelineService) GetTimeline(ctx context.Context, organizationID, projectID string) (*models.ProjectTimeline, error) {
+	return buildProjectTimeline(ctx, s.db, organizationID, projectID, time.Now())
+}
+
+func requireProject(ctx context.Context, db *gorm.DB, organizationID, projectID string) error {
+	var found int64
+	if err := database.Conn(ctx, db).Model(&models.InspectionProject{}).
+		Where("id = ? AND organization_id = ?", projectID, organizationID).Count(&found).Error; err != nil {
+		return fmt.Errorf("failed to get project: %v", err)
+	}
+	if found == 0 {
+		return ErrProjectNotFound
+	}
+	return nil
+}
+
+// taskProgress is the work in an assignment, or tied to a milestone, in units of inspections
+// or step executions
+type taskProgress struct {
+	total, done int
+	finishedAt  *time.Time // Latest completion, once the work is done
+}
+
+func (p *taskProgress) add(other taskProgress) {
+	p.total += other.total
+	p.done += other.done
+	p.finishedAt = laterTime(p.finishedAt, other.finishedAt)
+}
+
+func (p taskProgress) percent() float64 {
+	if p.total == 0 {
+		return 0
+	}
+	return math.Round(float64(p.done)/float64(p.total)*1000) / 10
+}
+
+// buildProjectTimeline works out the project's timeline as of now. Assignments are laid out
+// from when they started, or were planned to, until they finished or are due; the critical
+// path is found from their planned durations and dependencies.
+func buildProjectTimeline(ctx context.Context, db *gorm.DB, organizationID, projectID string, now time.Time) (*models.ProjectTimeline, error) {
+	db = database.Conn(ctx, db)
+	var project models.InspectionProject
+	if err := db.Where("organization_id = ? AND id = ?", organizationID, projectID).First(&project).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrProjectNotFound
+		}
+		return nil, fmt.Errorf("failed to get project: %v", err)
+	}
+
+	var assignments []models.InspectionAssignment
+	if err := db.Where("organization_id = ? AND project_id = ? AND status <> ?", organizationID, projectID, "cancelled").
+		Order("created_at").Find(&assignments).Error; err != nil {
+		return nil, fmt.Errorf("failed to get project assignments: %v", err)
+	}
+	assignmentIDs := make([]string, len(assignments))
+	for i, assignment := range assignments {
+		assignmentIDs[i] = assignment.ID
+	}
+
+	var inspections []models.Inspection
+	var dependencies []models.ProjectTaskDependency
+	if len(assignmentIDs) > 0 {
+		if err := db.Select("assignment_id, status, completed_at").
+			Where("assignment_id IN ? AND status <> ?", assignmentIDs, "cancelled").Find(&inspections).Error; err != nil {
+			return nil, fmt.Errorf("failed to get project inspections: %v", err)
+		}
+		if err := db.Where("project_id = ?", projectID).Find(&dependencies).Error; err != nil {
+			return nil, fmt.Errorf("failed to get task dependencies: %v", err)
+		}
+	}
+	var milestones []models.ProjectMilestone
+	if err := db.Where("organization_id = ? AND project_id = ?", organizationID, projectID).
+		Preload("Items").Order("target_date, created_at").Find(&milestones).Error; err != nil {
+		return nil, fmt.Errorf("failed to get milestones: %v", err)
+	}
+
+	// Progress of each assignment, and the project's pace over the velocity window
+	progress := make(map[string]taskProgress, len(assignments))
+	windowStart := now.Add(-velocityWindow)
+	projectStart := project.CreatedAt
+	if project.StartDate != nil {
+		projectStart = *project.StartDate
+	}
+	if projectStart.After(windowStart) {
+		windowStart = projectStart
+	}
+	recentlyCompleted := 0
+	for _, inspection := range inspections {
+		if inspection.AssignmentID == nil {
+			continue
+		}
+		p := progress[*inspection.AssignmentID]
+		p.total++
+		if containsString(completedInspectionStatuses, inspection.Status) {
+			p.done++
+			p.finishedAt = laterTime(p.finishedAt, inspection.CompletedAt)
+			if inspection.CompletedAt != nil && inspection.CompletedAt.After(windowStart) && !inspection.CompletedAt.After(now) {
+				recentlyCompleted++
+			}
+		}
+		progress[*inspection.AssignmentID] = p
+	}
+	windowDays := math.Max(now.Sub(windowStart).Hours()/24, 1)
+	velocity := float64(recentlyCompleted) / windowDays
+
+	summary := models.ProjectProgress{ProjectID: project.ID, TotalAssignments: len(assignments), VelocityPerDay: math.Round(velocity*100) / 100}
+	var overall taskProgress
+	for _, assignment := range assignments {
+		p := progress[assignment.ID]
+		summary.TotalInspections += p.total
+		summary.CompletedInspections += p.done
+		// Assignments without inspections count as one unit of work
+		if p.total == 0 {
+			p.total = 1
+		}
+		if assignment.Status == "completed" {
+			summary.CompletedAssignments++
+			p.done = p.total
+			p.finishedAt = laterTime(p.finishedAt, assignment.CompletedAt)
+		}
+		if p.done < p.total {
+			p.finishedAt = nil
+		}
+		progress[assignment.ID] = p
+		overall.add(p)
+	}
+	summary.PercentComplete = overall.percent()
+	summary.ProjectedCompletion = projectedFinish(overall, velocity, now)
+
+	tasks, criticalPath := scheduleAssignments(assignments, dependencies, progress)
+
+	// Milestones, as points on the chart after the assignments tied to them
+	critical := make(map[string]bool, len(criticalPath))
+	for _, id := range criticalPath {
+		critical[id] = true
+	}
+	stepProgress, err := workflowStepProgress(db, milestones, assignmentIDs)
+	if err != nil {
+		return nil, err
+	}
+	today := civilDate(now)
+	for i := range milestones {
+		milestone := &milestones[i]
+		var work taskProgress
+		task := models.TimelineTask{
+			ID: milestone.ID, Type: models.TimelineTaskMilestone, Name: milestone.Name,
+			Start: milestone.TargetDate, End: milestone.TargetDate, Dependencies: []string{},
+		}
+		for _, item := range milestone.Items {
+			switch {
+			case item.AssignmentID != nil:
+				p, ok := progress[*item.AssignmentID]
+				if !ok {
+					continue // Cancelled
+				}
+				work.add(p)
+				task.Dependencies = append(task.Dependencies, *item.AssignmentID)
+				task.IsCritical = task.IsCritical || critical[*item.AssignmentID]
+			case item.WorkflowStepID != nil:
+				work.add(stepProgress[*item.WorkflowStepID])
+			}
+		}
+		if work.done < work.total {
+			work.finishedAt = nil
+		}
+
+		milestone.PercentComplete = work.percent()
+		switch {
+		case work.total > 0 && work.done == work.total:
+			milestone.Status = models.MilestoneAchieved
+			milestone.AchievedAt = work.finishedAt
+		case today.After(civilDate(milestone.TargetDate)):
+			milestone.Status = models.MilestoneMissed
+		default:
+			milestone.ProjectedDate = projectedFinish(work, velocity, now)
+			milestone.Status = models.MilestoneOnTrack
+			if milestone.ProjectedDate == nil || civilDate(*milestone.ProjectedDate).After(civilDate(milestone.TargetDate)) {
+				milestone.Status = models.MilestoneAtRisk
+			}
+		}
+		if milestone.Status == models.MilestoneAtRisk || milestone.Status == models.MilestoneMissed {
+			summary.MilestonesAtRisk++
+		}
+		task.PercentComplete = milestone.PercentComplete
+		task.Status = milestone.Status
+		tasks = append(tasks, task)
+	}
+
+	summary.DueDate = project.DueDate
+	if summary.DueDate == nil {
+		summary.DueDate = project.EndDate
+	}
+	summary.OnSchedule = summary.DueDate == nil ||
+		(summary.ProjectedCompletion != nil && !civilDate(*summary.ProjectedCompletion).After(civilDate(*summary.DueDate)))
+
+	if milestones == nil {
+		milestones = []models.ProjectMilestone{}
+	}
+	return &models.ProjectTimeline{
+		ProjectID:    project.ID,
+		Name:         project.Name,
+		StartDate:    project.StartDate,
+		DueDate:      summary.DueDate,
+		Tasks:        tasks,
+		Milestones:   milestones,
+		CriticalPath: criticalPath,
+		Progress:     summary,
+	}, nil
+}
+
+// workflowStepProgress returns the progress of the workflow steps tied to the milestones,
+// counting their executions for the project's assignments
+func workflowStepProgress(db *gorm.DB, milestones []models.ProjectMilestone, assignmentIDs []string) (map[string]taskProgress, error) {
+	var stepIDs []string
+	for _, milestone := range milestones {
+		for _, item := range milestone.Items {
+			if item.WorkflowStepID != nil {
+				stepIDs = append(stepIDs, *item.WorkflowStepID)
+			}
+		}
+	}
+	progress := make(map[string]taskProgress)
+	if len(stepIDs) == 0 {
+		return progress, nil
+	}
+	for _, id := range stepIDs {
+		progress[id] = taskProgress{}
+	}
+	if len(assignmentIDs) == 0 {
+		return progress, nil
+	}
+
+	var executions []models.StepExecution
+	if err := db.Select("workflow_step_id, status, completed_at").
+		Where("workflow_step_id IN ? AND assignment_id IN ?", uniqueStrings(stepIDs), assignmentIDs).
+		Find(&executions).Error; err != nil {
+		return nil, fmt.Errorf("failed to get step executions: %v", err)
+	}
+	for _, execution := range executions {
+		p := progress[execution.WorkflowStepID]
+		p.total++
+		if containsString(doneStepExecutionStatuses, execution.Status) {
+			p.done++
+			p.finishedAt = laterTime(p.finishedAt, execution.CompletedAt)
+		}
+		progress[execution.WorkflowStepID] = p
+	}
+	// Steps nothing has reached yet are one unit of work left
+	for id, p := range progress {
+		if p.total == 0 {
+			p.total = 1
+			progress[id] = p
+		}
+	}
+	return progress, nil
+}
+
+// scheduleAssignments turns the assignments into Gantt tasks and finds the critical path: the
+// chain of dependent assignments, by planned duration, that decides how long the project
+// takes. Critical tasks have no slack and come back ordered by earliest start.
+func scheduleAssignments(assignments []models.InspectionAssignment, dependencies []models.ProjectTaskDependency, progress map[string]taskProgress) ([]models.TimelineTask, []string) {
+	index := make(map[string]int, len(assignments))
+	for i, assignment := range assignments {
+		index[assignment.ID] = i
+	}
+	dependsOn := make([][]int, len(assignments))
+	successors := make([][]int, len(assignments))
+	for _, dependency := range dependencies {
+		from, okFrom := index[dependency.DependsOnID]
+		to, okTo := index[dependency.AssignmentID]
+		if okFrom && okTo {
+			dependsOn[to] = append(dependsOn[to], from)
+			successors[from] = append(successors[from], to)
+		}
+	}
+
+	tasks := make([]models.TimelineTask, len(assignments))
+	durations := make([]float64, len(assignments))
+	for i, assignment := range assignments {
+		durations[i] = plannedHours(&assignment)
+		start := assignment.AssignedAt
+		if assignment.StartDate != nil {
+			start = *assignment.StartDate
+		}
+		if assignment.StartedAt != nil {
+			start = *assignment.StartedAt
+		}
+		end := start.Add(time.Duration(durations[i] * float64(time.Hour)))
+		if assignment.DueDate != nil {
+			end = *assignment.DueDate
+		}
+		if assignment.CompletedAt != nil {
+			end = *assignment.CompletedAt
+		}
+		if end.Before(start) {
+			end = start.Add(time.Duration(durations[i] * float64(time.Hour)))
+		}
+		tasks[i] = models.TimelineTask{
+			ID: assignment.ID, Type: models.TimelineTaskAssignment, Name: assignment.Name, Start: start, End: end,
+			Dependencies: []string{}, PercentComplete: progress[assignment.ID].percent(), Status: assignment.Status, AssigneeID: assignment.AssignedTo,
+		}
+		for _, j := range dependsOn[i] {
+			tasks[i].Dependencies = append(tasks[i].Dependencies, assignments[j].ID)
+		}
+	}
+
+	// Forward pass in dependency order for earliest finishes, then backward for latest starts
+	order := make([]int, 0, len(assignments))
+	waiting := make([]int, len(assignments))
+	for i := range assignments {
+		waiting[i] = len(dependsOn[i])
+		if waiting[i] == 0 {
+			order = append(order, i)
+		}
+	}
+	for next := 0; next < len(order); next++ {
+		for _, j := range successors[order[next]] {
+			if waiting[j]--; waiting[j] == 0 {
+				order = append(order, j)
+			}
+		}
+	}
+	earliestStart := make([]float64, len(assignments))
+	length := 0.0
+	for _, i := range order {
+		for _, j := range dependsOn[i] {
+			earliestStart[i] = math.Max(earliestStart[i], earliestStart[j]+durations[j])
+		}
+		length = math.Max(length, earliestStart[i]+durations[i])
+	}
+	latestStart := make([]float64, len(assignments))
+	for k := len(order) - 1; k >= 0; k-- {
+		i := order[k]
+		latestFinish := length
+		for _, j := range successors[i] {
+			latestFinish = math.Min(latestFinish, latestStart[j])
+		}
+		latestStart[i] = latestFinish - durations[i]
+	}
+
+	var critical []int
+	for _, i := range order {
+		slack := latestStart[i] - earliestStart[i]
+		tasks[i].SlackHours = math.Round(slack*10) / 10
+		if slack < 1e-6 {
+			tasks[i].IsCritical = true
+			critical = append(critical, i)
+		}
+	}
+	sort.SliceStable(critical, func(a, b int) bool { return earliestStart[critical[a]] < earliestStart[critical[b]] })
+	criticalPath := make([]string, len(critical))
+	for k, i := range critical {
+		criticalPath[k] = assignments[i].ID
+	}
+	return tasks, criticalPath
+}
+
+// plannedHours is how long an assignment is planned to take: from its start date to its due
+// date when both are set, its estimate otherwise
+func plannedHours(assignment *models.InspectionAssignment) float64 {
+	if assignment.StartDate != nil && assignment.DueDate != nil && assignment.DueDate.After(*assignment.StartDate) {
+		return assignment.DueDate.Sub(*assignment.StartDate).Hours()
+	}
+	if assignment.EstimatedHours > 0 {
+		return float64(assignment.EstimatedHours)
+	}
+	return 1
+}
+
+// projectedFinish is when the remaining work finishes at the given velocity, in units per
+// day. Finished work returns when it finished; it is nil when work remains at no velocity.
+func projectedFinish(work taskProgress, velocity float64, now time.Time) *time.Time {
+	remaining := work.total - work.done
+	if remaining <= 0 {
+		return work.finishedAt
+	}
+	if velocity <= 0 {
+		return nil
+	}
+	projected := now.Add(time.Duration(float64(remaining) / velocity * float64(24*time.Hour)))
+	return &projected
+}
+
+func laterTime(a, b *time.Time) *time.Time {
+	if a == nil || (b != nil && b.After(*a)) {
+		return b
+	}
+	return a
+}
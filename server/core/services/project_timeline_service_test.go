@@ -0,0 +1,299 @@
+package services
+
+import (
+	"context"
+	"resource-mgmt/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// projectTimelineTestFixture is a fire audit that started a week ago and is due in three
+// days: a finished 8 hour survey, a 16 hour repairs check that depends on it with both of
+// its inspections open, and a 4 hour signage check done three days ago. A boiler audit
+// holds one more assignment, and the fire audit has a sign-off workflow step.
+type projectTimelineTestFixture struct {
+	db                       *gorm.DB
+	service                  *ProjectTimelineService
+	now                      time.Time
+	project, other           *models.InspectionProject
+	survey, repairs, signage *models.InspectionAssignment
+	elsewhere                *models.InspectionAssignment
+	step                     *models.WorkflowStep
+}
+
+func newProjectTimelineTestFixture(t *testing.T) *projectTimelineTestFixture {
+	db := setupServiceTestDB(t, &models.Template{}, &models.Site{}, &models.Inspection{}, &models.InspectionProject{}, &models.InspectionAssignment{},
+		&models.WorkflowStep{}, &models.StepExecution{}, &models.ProjectMilestone{}, &models.ProjectMilestoneItem{}, &models.ProjectTaskDependency{})
+	now := time.Now()
+	f := &projectTimelineTestFixture{db: db, service: NewProjectTimelineService(db), now: now}
+
+	template := createTestTemplate(t, db, "org-a", "Fire safety")
+	site := createTestSite(t, db, "org-a", "Depot", "4 Yard Rd")
+	start := now.AddDate(0, 0, -7)
+	due := now.AddDate(0, 0, 3)
+	f.project = &models.InspectionProject{ID: uuid.NewString(), OrganizationID: "org-a", Name: "Fire audit", ProjectCode: "FA-1", StartDate: &start, DueDate: &due}
+	require.NoError(t, db.Create(f.project).Error)
+	f.other = &models.InspectionProject{ID: uuid.NewString(), OrganizationID: "org-a", Name: "Boiler audit", ProjectCode: "BA-1"}
+	require.NoError(t, db.Create(f.other).Error)
+
+	assignment := func(projectID, name, status string, hours int) *models.InspectionAssignment {
+		a := &models.InspectionAssignment{OrganizationID: "org-a", ProjectID: &projectID, Name: name, Status: status, AssignedBy: "super-1", AssignedTo: "insp-1",
+			AssignedAt: start, EstimatedHours: hours, SiteIDs: datatypes.JSON(`[]`), TemplateID: template.ID.String()}
+		require.NoError(t, db.Create(a).Error)
+		return a
+	}
+	f.survey = assignment(f.project.ID, "Survey", "completed", 8)
+	f.repairs = assignment(f.project.ID, "Repairs check", "active", 16)
+	f.signage = assignment(f.project.ID, "Signage", "active", 4)
+	f.elsewhere = assignment(f.other.ID, "Boilers", "active", 4)
+
+	inspection := func(a *models.InspectionAssignment, completedAt *time.Time) {
+		status := "assigned"
+		if completedAt != nil {
+			status = "completed"
+		}
+		require.NoError(t, db.Create(&models.Inspection{OrganizationID: "org-a", TemplateID: template.ID, InspectorID: "insp-1", SiteID: site.ID,
+			AssignmentID: &a.ID, Status: status, CompletedAt: completedAt}).Error)
+	}
+	yesterday, earlier := now.AddDate(0, 0, -1), now.AddDate(0, 0, -3)
+	inspection(f.survey, &yesterday)
+	inspection(f.survey, &yesterday)
+	inspection(f.repairs, nil)
+	inspection(f.repairs, nil)
+	inspection(f.signage, &earlier)
+
+	_, err := f.service.SetDependencies(context.Background(), "org-a", f.project.ID, f.repairs.ID, "super-1", []string{f.survey.ID})
+	require.NoError(t, err)
+
+	f.step = &models.WorkflowStep{ProjectID: f.project.ID, Name: "Sign-off", StepType: "approval", StepOrder: 1}
+	require.NoError(t, db.Create(f.step).Error)
+	return f
+}
+
+func (f *projectTimelineTestFixture) milestone(t *testing.T, name string, target time.Time, req models.MilestoneRequest) *models.ProjectMilestone {
+	req.Name, req.TargetDate = name, target
+	milestone, err := f.service.CreateMilestone(context.Background(), "org-a", f.project.ID, "super-1", &req)
+	require.NoError(t, err)
+	return milestone
+}
+
+func TestProjectTimelineService_SetDependenciesValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		project   func(f *projectTimelineTestFixture) string
+		task      func(f *projectTimelineTestFixture) string
+		dependsOn func(f *projectTimelineTestFixture) []string
+		wantErr   error
+	}{
+		{"cycle",
+			func(f *projectTimelineTestFixture) string { return f.project.ID },
+			func(f *projectTimelineTestFixture) string { return f.survey.ID },
+			func(f *projectTimelineTestFixture) []string { return []string{f.repairs.ID} }, ErrInvalidDependency},
+		{"depends on itself",
+			func(f *projectTimelineTestFixture) string { return f.project.ID },
+			func(f *projectTimelineTestFixture) string { return f.signage.ID },
+			func(f *projectTimelineTestFixture) []string { return []string{f.signage.ID} }, ErrInvalidDependency},
+		{"depends on another project",
+			func(f *projectTimelineTestFixture) string { return f.project.ID },
+			func(f *projectTimelineTestFixture) string { return f.signage.ID },
+			func(f *projectTimelineTestFixture) []string { return []string{f.elsewhere.ID} }, ErrInvalidDependency},
+		{"assignment of another project",
+			func(f *projectTimelineTestFixture) string { return f.project.ID },
+			func(f *projectTimelineTestFixture) string { return f.elsewhere.ID },
+			func(f *projectTimelineTestFixture) []string { return nil }, ErrAssignmentNotFound},
+		{"unknown project",
+			func(f *projectTimelineTestFixture) string { return uuid.NewString() },
+			func(f *projectTimelineTestFixture) string { return f.signage.ID },
+			func(f *projectTimelineTestFixture) []string { return nil }, ErrProjectNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newProjectTimelineTestFixture(t)
+
+			_, err := f.service.SetDependencies(context.Background(), "org-a", tt.project(f), tt.task(f), "super-1", tt.dependsOn(f))
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, int64(1), countRows(t, f.db.Model(&models.ProjectTaskDependency{})), "only repairs on survey remains")
+		})
+	}
+}
+
+func TestProjectTimelineService_SetDependenciesReplaces(t *testing.T) {
+	f := newProjectTimelineTestFixture(t)
+
+	dependencies, err := f.service.SetDependencies(context.Background(), "org-a", f.project.ID, f.repairs.ID, "super-1", nil)
+	require.NoError(t, err)
+	assert.Empty(t, dependencies)
+	assert.Zero(t, countRows(t, f.db.Model(&models.ProjectTaskDependency{})))
+}
+
+func TestProjectTimelineService_CreateMilestoneValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		project func(f *projectTimelineTestFixture) string
+		req     func(f *projectTimelineTestFixture) models.MilestoneRequest
+		wantErr error
+	}{
+		{"missing name", func(f *projectTimelineTestFixture) string { return f.project.ID }, func(f *projectTimelineTestFixture) models.MilestoneRequest {
+			return models.MilestoneRequest{Name: " ", TargetDate: f.now, AssignmentIDs: []string{f.survey.ID}}
+		}, ErrInvalidMilestone},
+		{"missing target date", func(f *projectTimelineTestFixture) string { return f.project.ID }, func(f *projectTimelineTestFixture) models.MilestoneRequest {
+			return models.MilestoneRequest{Name: "Surveyed", AssignmentIDs: []string{f.survey.ID}}
+		}, ErrInvalidMilestone},
+		{"nothing tied to it", func(f *projectTimelineTestFixture) string { return f.project.ID }, func(f *projectTimelineTestFixture) models.MilestoneRequest {
+			return models.MilestoneRequest{Name: "Surveyed", TargetDate: f.now}
+		}, ErrInvalidMilestone},
+		{"assignment of another project", func(f *projectTimelineTestFixture) string { return f.project.ID }, func(f *projectTimelineTestFixture) models.MilestoneRequest {
+			return models.MilestoneRequest{Name: "Boilers", TargetDate: f.now, AssignmentIDs: []string{f.elsewhere.ID}}
+		}, ErrInvalidMilestone},
+		{"unknown workflow step", func(f *projectTimelineTestFixture) string { return f.project.ID }, func(f *projectTimelineTestFixture) models.MilestoneRequest {
+			return models.MilestoneRequest{Name: "Signed off", TargetDate: f.now, WorkflowStepIDs: []string{uuid.NewString()}}
+		}, ErrInvalidMilestone},
+		{"unknown project", func(f *projectTimelineTestFixture) string { return uuid.NewString() }, func(f *projectTimelineTestFixture) models.MilestoneRequest {
+			return models.MilestoneRequest{Name: "Surveyed", TargetDate: f.now, AssignmentIDs: []string{f.survey.ID}}
+		}, ErrProjectNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newProjectTimelineTestFixture(t)
+			req := tt.req(f)
+
+			_, err := f.service.CreateMilestone(context.Background(), "org-a", tt.project(f), "super-1", &req)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Zero(t, countRows(t, f.db.Model(&models.ProjectMilestone{})))
+		})
+	}
+}
+
+func TestProjectTimelineService_MilestoneStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		targetDays  int
+		req         func(f *projectTimelineTestFixture) models.MilestoneRequest
+		wantStatus  string
+		wantPercent float64
+	}{
+		{"finished work is achieved", 1, func(f *projectTimelineTestFixture) models.MilestoneRequest {
+			return models.MilestoneRequest{AssignmentIDs: []string{f.survey.ID}}
+		}, models.MilestoneAchieved, 100},
+		{"open work projected past the target is at risk", 1, func(f *projectTimelineTestFixture) models.MilestoneRequest {
+			return models.MilestoneRequest{AssignmentIDs: []string{f.repairs.ID}}
+		}, models.MilestoneAtRisk, 0},
+		{"open work projected before the target is on track", 10, func(f *projectTimelineTestFixture) models.MilestoneRequest {
+			return models.MilestoneRequest{AssignmentIDs: []string{f.repairs.ID, f.signage.ID}}
+		}, models.MilestoneOnTrack, 33.3},
+		{"open work past the target is missed", -1, func(f *projectTimelineTestFixture) models.MilestoneRequest {
+			return models.MilestoneRequest{WorkflowStepIDs: []string{f.step.ID}}
+		}, models.MilestoneMissed, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newProjectTimelineTestFixture(t)
+
+			milestone := f.milestone(t, "Milestone", f.now.AddDate(0, 0, tt.targetDays), tt.req(f))
+			assert.Equal(t, tt.wantStatus, milestone.Status)
+			assert.Equal(t, tt.wantPercent, milestone.PercentComplete)
+		})
+	}
+}
+
+func TestProjectTimelineService_UpdateMilestoneReprojects(t *testing.T) {
+	f := newProjectTimelineTestFixture(t)
+
+	// Three inspections in the week since the start leave over four days' work for repairs
+	repaired := f.milestone(t, "Repaired", f.now.AddDate(0, 0, 1), models.MilestoneRequest{AssignmentIDs: []string{f.repairs.ID}})
+	require.NotNil(t, repaired.ProjectedDate)
+	assert.WithinDuration(t, f.now.Add(time.Duration(2.0/(3.0/7.0)*24)*time.Hour), *repaired.ProjectedDate, time.Hour)
+
+	repaired, err := f.service.UpdateMilestone(context.Background(), "org-a", f.project.ID, repaired.ID, &models.MilestoneRequest{
+		Name: "Repaired", TargetDate: f.now.AddDate(0, 0, 10), AssignmentIDs: []string{f.repairs.ID, f.signage.ID}})
+	require.NoError(t, err)
+	assert.Equal(t, models.MilestoneOnTrack, repaired.Status)
+	assert.Equal(t, 33.3, repaired.PercentComplete)
+}
+
+func TestProjectTimelineService_TimelineCriticalPath(t *testing.T) {
+	f := newProjectTimelineTestFixture(t)
+	surveyed := f.milestone(t, "Surveyed", f.now.AddDate(0, 0, 1), models.MilestoneRequest{AssignmentIDs: []string{f.survey.ID}})
+
+	timeline, err := f.service.GetTimeline(context.Background(), "org-a", f.project.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{f.survey.ID, f.repairs.ID}, timeline.CriticalPath)
+	require.Len(t, timeline.Tasks, 4)
+	tasks := make(map[string]models.TimelineTask)
+	for _, task := range timeline.Tasks {
+		tasks[task.ID] = task
+	}
+	assert.Equal(t, []string{f.survey.ID}, tasks[f.repairs.ID].Dependencies)
+	assert.Equal(t, 20.0, tasks[f.signage.ID].SlackHours)
+	assert.False(t, tasks[f.signage.ID].IsCritical)
+	assert.Equal(t, 100.0, tasks[f.survey.ID].PercentComplete)
+	assert.Equal(t, models.TimelineTaskMilestone, tasks[surveyed.ID].Type)
+	assert.True(t, tasks[surveyed.ID].IsCritical)
+}
+
+func TestProjectTimelineService_TimelineProgress(t *testing.T) {
+	f := newProjectTimelineTestFixture(t)
+	f.milestone(t, "Repaired", f.now.AddDate(0, 0, 10), models.MilestoneRequest{AssignmentIDs: []string{f.repairs.ID}})
+	f.milestone(t, "Signed off", f.now.AddDate(0, 0, -1), models.MilestoneRequest{WorkflowStepIDs: []string{f.step.ID}})
+
+	timeline, err := f.service.GetTimeline(context.Background(), "org-a", f.project.ID)
+	require.NoError(t, err)
+	progress := timeline.Progress
+	assert.Equal(t, 3, progress.TotalAssignments)
+	assert.Equal(t, 1, progress.CompletedAssignments)
+	assert.Equal(t, 5, progress.TotalInspections)
+	assert.Equal(t, 3, progress.CompletedInspections)
+	assert.Equal(t, 60.0, progress.PercentComplete)
+	assert.Equal(t, 0.43, progress.VelocityPerDay)
+	require.NotNil(t, progress.ProjectedCompletion)
+	assert.False(t, progress.OnSchedule, "the open inspections take over four days at this pace")
+	assert.Equal(t, 1, progress.MilestonesAtRisk, "the missed sign-off")
+}
+
+func TestProjectTimelineService_GetTimelineOtherOrganization(t *testing.T) {
+	f := newProjectTimelineTestFixture(t)
+
+	_, err := f.service.GetTimeline(context.Background(), "org-b", f.project.ID)
+	assert.ErrorIs(t, err, ErrProjectNotFound)
+}
+
+func TestProjectTimelineService_DeleteMilestone(t *testing.T) {
+	tests := []struct {
+		name      string
+		milestone func(f *projectTimelineTestFixture, signedOff *models.ProjectMilestone) string
+		project   func(f *projectTimelineTestFixture) string
+		wantErr   error
+	}{
+		{"existing milestone",
+			func(f *projectTimelineTestFixture, signedOff *models.ProjectMilestone) string { return signedOff.ID },
+			func(f *projectTimelineTestFixture) string { return f.project.ID }, nil},
+		{"unknown milestone",
+			func(f *projectTimelineTestFixture, _ *models.ProjectMilestone) string { return uuid.NewString() },
+			func(f *projectTimelineTestFixture) string { return f.project.ID }, ErrMilestoneNotFound},
+		{"milestone of another project",
+			func(f *projectTimelineTestFixture, signedOff *models.ProjectMilestone) string { return signedOff.ID },
+			func(f *projectTimelineTestFixture) string { return f.other.ID }, ErrMilestoneNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newProjectTimelineTestFixture(t)
+			ctx := context.Background()
+			signedOff := f.milestone(t, "Signed off", f.now.AddDate(0, 0, -1), models.MilestoneRequest{WorkflowStepIDs: []string{f.step.ID}})
+
+			err := f.service.DeleteMilestone(ctx, "org-a", tt.project(f), tt.milestone(f, signedOff))
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Equal(t, int64(1), countRows(t, f.db.Model(&models.ProjectMilestone{})), "the milestone is kept")
+				return
+			}
+			require.NoError(t, err)
+			assert.ErrorIs(t, f.service.DeleteMilestone(ctx, "org-a", f.project.ID, signedOff.ID), ErrMilestoneNotFound)
+		})
+	}
+}
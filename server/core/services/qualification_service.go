@@ -0,0 +1,917 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"resource-mgmt/config"
+	"resource-mgmt/models"
+	"resource-mgmt/pkg/database"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidQualification is returned when a certification type, certificate or template
+	// requirement fails validation
+	ErrInvalidQualification = errors.New("invalid qualification")
+	// ErrCertificationTypeNotFound is returned when a certification type doesn't exist in the organization
+	ErrCertificationTypeNotFound = errors.New("certification type not found")
+	// ErrCertificationTypeInUse is returned when deleting a type that certificates or templates use
+	ErrCertificationTypeInUse = errors.New("certification type in use")
+	// ErrCertificationNotFound is returned when a certificate or its evidence doesn't exist in
+	// the organization, or belongs to someone else and the caller isn't a supervisor
+	ErrCertificationNotFound = errors.New("certification not found")
+	// ErrCertificationRevoked is returned when revoking or adding evidence to a revoked certificate
+	ErrCertificationRevoked = errors.New("certification already revoked")
+	// ErrInspectorNotQualified is returned when an inspector lacks a certification the
+	// template requires for the period of the work
+	ErrInspectorNotQualified = errors.New("inspector not qualified")
+	// ErrProofInspectionNotFound is returned when proof is asked for an inspection outside the organization
+	ErrProofInspectionNotFound = errors.New("inspection not found")
+)
+
+const (
+	// maxCertificationReminderDays bounds how far ahead of expiry holders are warned
+	maxCertificationReminderDays = 365
+	// certificationEvidenceDownloadExpiry is how long a presigned evidence link stays valid
+	certificationEvidenceDownloadExpiry = 15 * time.Minute
+)
+
+// qualificationManagerRoles are the roles warned about their inspectors' lapsing certifications
+var qualificationManagerRoles = []string{"admin", "supervisor"}
+
+type QualificationService struct {
+	db                  *gorm.DB
+	storage             *StorageService
+	notificationService *NotificationService
+}
+
+func NewQualificationService(db *gorm.DB, storage *StorageService) *QualificationService {
+	return &QualificationService{
+		db:                  db,
+		storage:             storage,
+		notificationService: NewNotificationService(),
+	}
+}
+
+// defaultCertificationReminderDays returns config.CertificationReminderDays as a number
+func defaultCertificationReminderDays() int {
+	days, err := strconv.Atoi(config.CertificationReminderDays)
+	if err != nil || days < 0 || days > maxCertificationReminderDays {
+		return 30
+	}
+	return days
+}
+
+// =====================================================
+// CERTIFICATION TYPES
+// =====================================================
+
+// GetCertificationTypes lists the organization's certification types by name
+func (s *QualificationService) GetCertificationTypes(ctx context.Context, organizationID string, includeInactive bool) ([]models.CertificationType, error) {
+	query := database.Conn(ctx, s.db).Where("organization_id = ?", organizationID)
+	if !includeInactive {
+		query = query.Where("is_active = ?", true)
+	}
+
+	var types []models.CertificationType
+	if err := query.Order("name").Find(&types).Error; err != nil {
+		return nil, fmt.Errorf("failed to get certification types: %v", err)
+	}
+	return types, nil
+}
+
+// CreateCertificationType adds a certification type. Names are unique in the organization.
+func (s *QualificationService) CreateCertificationType(ctx context.Context, organizationID, userID string, req *models.CertificationTypeRequest) (*models.CertificationType, error) {
+	certificationType := &models.CertificationType{
+		OrganizationID: organizationID,
+		ReminderDays:   defaultCertificationReminderDays(),
+		IsActive:       true,
+		CreatedBy:      userID,
+	}
+	if err := s.applyCertificationType(ctx, certificationType, req); err != nil {
+		return nil, err
+	}
+
+	if err := database.Conn(ctx, s.db).Create(certificationType).Error; err != nil {
+		return nil, fmt.Errorf("failed to create certification type: %v", err)
+	}
+	return certificationType, nil
+}
+
+// UpdateCertificationType changes a certification type. Certificates already recorded
+// keep their dates.
+func (s *QualificationService) UpdateCertificationType(ctx context.Context, organizationID, typeID string, req *models.CertificationTypeRequest) (*models.CertificationType, error) {
+	certificationType, err := s.getCertificationType(ctx, organizationID, typeID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyCertificationType(ctx, certificationType, req); err != nil {
+		return nil, err
+	}
+
+	if err := database.Conn(ctx, s.db).Save(certificationType).Error; err != nil {
+		return nil, fmt.Errorf("failed to update certification type: %v", err)
+	}
+	return certificationType, nil
+}
+
+// DeleteCertificationType removes a type nothing refers to. Types with certificates or
+// template requirements are deactivated instead.
+func (s *QualificationService) DeleteCertificationType(ctx context.Context, organizationID, typeID string) error {
+	certificationType, err := s.getCertificationType(ctx, organizationID, typeID)
+	if err != nil {
+		return err
+	}
+
+	db := database.Conn(ctx, s.db)
+	for _, model := range []interface{}{&models.InspectorCertification{}, &models.TemplateQualification{}} {
+		var uses int64
+		if err := db.Model(model).Where("certification_type_id = ?", certificationType.ID).Count(&uses).Error; err != nil {
+			return fmt.Errorf("failed to check certification type use: %v", err)
+		}
+		if uses > 0 {
+			return fmt.Errorf("%w: deactivate it instead", ErrCertificationTypeInUse)
+		}
+	}
+
+	if err := db.Delete(&models.CertificationType{}, "id = ?", certificationType.ID).Error; err != nil {
+		return fmt.Errorf("failed to delete certification type: %v", err)
+	}
+	return nil
+}
+
+// applyCertificationType validates a request onto a type
+func (s *QualificationService) applyCertificationType(ctx context.Context, certificationType *models.CertificationType, req *models.CertificationTypeRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidQualification)
+	}
+	if req.ValidityMonths < 0 {
+		return fmt.Errorf("%w: validity_months can't be negative", ErrInvalidQualification)
+	}
+	if req.ReminderDays != nil {
+		if *req.ReminderDays < 0 || *req.ReminderDays > maxCertificationReminderDays {
+			return fmt.Errorf("%w: reminder_days must be between 0 and %d", ErrInvalidQualification, maxCertificationReminderDays)
+		}
+		certificationType.ReminderDays = *req.ReminderDays
+	}
+
+	var clashes int64
+	if err := database.Conn(ctx, s.db).Model(&models.CertificationType{}).
+		Where("organization_id = ? AND LOWER(name) = ? AND id <> ?", certificationType.OrganizationID, strings.ToLower(name), certificationType.ID).
+		Count(&clashes).Error; err != nil {
+		return fmt.Errorf("failed to check certification type name: %v", err)
+	}
+	if clashes > 0 {
+		return fmt.Errorf("%w: a certification type named %q already exists", ErrInvalidQualification, name)
+	}
+
+	certificationType.Name = name
+	certificationType.Issuer = strings.TrimSpace(req.Issuer)
+	certificationType.Description = req.Description
+	certificationType.ValidityMonths = req.ValidityMonths
+	if req.IsActive != nil {
+		certificationType.IsActive = *req.IsActive
+	}
+	return nil
+}
+
+func (s *QualificationService) getCertificationType(ctx context.Context, organizationID, typeID string) (*models.CertificationType, error) {
+	var certificationType models.CertificationType
+	if err := database.Conn(ctx, s.db).Where("id = ? AND organization_id = ?", typeID, organizationID).First(&certificationType).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCertificationTypeNotFound
+		}
+		return nil, fmt.Errorf("failed to get certification type: %v", err)
+	}
+	return &certificationType, nil
+}
+
+// =====================================================
+// CERTIFICATES
+// =====================================================
+
+// GetCertifications lists certificates, newest first. Filters: user_id,
+// certification_type_id, status (valid, expiring, expired, revoked, not_yet_valid).
+func (s *QualificationService) GetCertifications(ctx context.Context, organizationID string, filters map[string]interface{}) ([]models.InspectorCertification, error) {
+	query := database.Conn(ctx, s.db).Where("organization_id = ?", organizationID)
+	if userID, ok := filters["user_id"].(string); ok && userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	if typeID, ok := filters["certification_type_id"].(string); ok && typeID != "" {
+		query = query.Where("certification_type_id = ?", typeID)
+	}
+
+	var certifications []models.InspectorCertification
+	if err := query.Preload("CertificationType").Preload("User").
+		Order("issued_on DESC, created_at DESC").Find(&certifications).Error; err != nil {
+		return nil, fmt.Errorf("failed to get certifications: %v", err)
+	}
+
+	status, _ := filters["status"].(string)
+	now := time.Now()
+	listed := make([]models.InspectorCertification, 0, len(certifications))
+	for _, certification := range certifications {
+		setCertificationStatus(&certification, now)
+		if status != "" && certification.Status != status {
+			continue
+		}
+		listed = append(listed, certification)
+	}
+	return listed, nil
+}
+
+// GetCertification returns a certificate with its evidence. Inspectors may only see their own.
+func (s *QualificationService) GetCertification(ctx context.Context, organizationID, certificationID, userID, userRole string) (*models.InspectorCertification, error) {
+	var certification models.InspectorCertification
+	err := database.Conn(ctx, s.db).Preload("CertificationType").Preload("User").
+		Preload("Evidence", func(db *gorm.DB) *gorm.DB { return db.Order("uploaded_at") }).
+		Where("id = ? AND organization_id = ?", certificationID, organizationID).
+		First(&certification).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCertificationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get certification: %v", err)
+	}
+	if certification.UserID != userID && !isSupervisorRole(userRole) {
+		return nil, ErrCertificationNotFound
+	}
+
+	setCertificationStatus(&certification, time.Now())
+	return &certification, nil
+}
+
+// CreateCertification records a certificate an organization member holds. Without an
+// expiry date it runs for the type's validity, if it has one.
+func (s *QualificationService) CreateCertification(ctx context.Context, organizationID, userID string, req *models.CertificationRequest) (*models.InspectorCertification, error) {
+	certificationType, err := s.getCertificationType(ctx, organizationID, req.CertificationTypeID)
+	if err != nil {
+		if errors.Is(err, ErrCertificationTypeNotFound) {
+			return nil, fmt.Errorf("%w: unknown certification type", ErrInvalidQualification)
+		}
+		return nil, err
+	}
+	if !certificationType.IsActive {
+		return nil, fmt.Errorf("%w: %s is no longer in use", ErrInvalidQualification, certificationType.Name)
+	}
+
+	db := database.Conn(ctx, s.db)
+	var members int64
+	if err := db.Model(&models.OrganizationMember{}).
+		Where("organization_id = ? AND user_id = ? AND status = ?", organizationID, req.UserID, "active").
+		Count(&members).Error; err != nil {
+		return nil, fmt.Errorf("failed to check member: %v", err)
+	}
+	if members == 0 {
+		return nil, fmt.Errorf("%w: %s is not an active member of the organization", ErrInvalidQualification, req.UserID)
+	}
+
+	issuedOn := civilDate(req.IssuedOn)
+	var expiresOn *time.Time
+	if req.ExpiresOn != nil {
+		day := civilDate(*req.ExpiresOn)
+		expiresOn = &day
+	} else if certificationType.ValidityMonths > 0 {
+		day := issuedOn.AddDate(0, certificationType.ValidityMonths, -1)
+		expiresOn = &day
+	}
+	if expiresOn != nil && expiresOn.Before(issuedOn) {
+		return nil, fmt.Errorf("%w: expires_on is before issued_on", ErrInvalidQualification)
+	}
+
+	certification := &models.InspectorCertification{
+		OrganizationID:      organizationID,
+		UserID:              req.UserID,
+		CertificationTypeID: certificationType.ID,
+		CertificateNumber:   strings.TrimSpace(req.CertificateNumber),
+		IssuedOn:            issuedOn,
+		ExpiresOn:           expiresOn,
+		Notes:               req.Notes,
+		CreatedBy:           userID,
+	}
+	if err := db.Create(certification).Error; err != nil {
+		return nil, fmt.Errorf("failed to save certification: %v", err)
+	}
+
+	return s.GetCertification(ctx, organizationID, certification.ID, userID, "admin")
+}
+
+// RevokeCertification withdraws a certificate from a day, today unless given. Revoked
+// certificates stay on record and still prove qualification before that day.
+func (s *QualificationService) RevokeCertification(ctx context.Context, organizationID, certificationID, userID string, req *models.RevokeCertificationRequest) (*models.InspectorCertification, error) {
+	certification, err := s.GetCertification(ctx, organizationID, certificationID, userID, "admin")
+	if err != nil {
+		return nil, err
+	}
+	revokedOn := civilDate(time.Now())
+	if req.RevokedOn != nil {
+		revokedOn = civilDate(*req.RevokedOn)
+	}
+	if revokedOn.Before(certification.IssuedOn) {
+		return nil, fmt.Errorf("%w: revoked_on is before the certificate was issued", ErrInvalidQualification)
+	}
+
+	result := database.Conn(ctx, s.db).Model(&models.InspectorCertification{}).
+		Where("id = ? AND revoked_on IS NULL", certification.ID).
+		Updates(map[string]interface{}{
+			"revoked_on":    revokedOn,
+			"revoked_by":    userID,
+			"revoke_reason": strings.TrimSpace(req.Reason),
+			"updated_at":    time.Now(),
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to revoke certification: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrCertificationRevoked
+	}
+
+	return s.GetCertification(ctx, organizationID, certification.ID, userID, "admin")
+}
+
+// AddEvidence stores a file backing a certificate. The holder and supervisors may add evidence.
+func (s *QualificationService) AddEvidence(ctx context.Context, organizationID, certificationID, userID, userRole string, file *multipart.FileHeader) (*models.CertificationEvidence, error) {
+	certification, err := s.GetCertification(ctx, organizationID, certificationID, userID, userRole)
+	if err != nil {
+		return nil, err
+	}
+	if certification.RevokedOn != nil {
+		return nil, ErrCertificationRevoked
+	}
+
+	stored, err := s.storage.UploadFile(ctx, file, fmt.Sprintf("certifications/%s/%s", certification.UserID, certification.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to store evidence: %v", err)
+	}
+
+	evidence := &models.CertificationEvidence{
+		ID:              uuid.NewString(),
+		OrganizationID:  organizationID,
+		CertificationID: certification.ID,
+		FileName:        stored.OriginalName,
+		StoragePath:     stored.Path,
+		FileSize:        stored.Size,
+		MimeType:        stored.MimeType,
+		UploadedBy:      userID,
+		UploadedAt:      stored.UploadedAt,
+	}
+	if err := database.Conn(ctx, s.db).Create(evidence).Error; err != nil {
+		if deleteErr := s.storage.DeleteFile(context.Background(), stored.Path); deleteErr != nil {
+			log.Printf("Failed to delete stored evidence %s: %v", stored.Path, deleteErr)
+		}
+		return nil, fmt.Errorf("failed to save evidence: %v", err)
+	}
+	return evidence, nil
+}
+
+// EvidenceLocation returns where an evidence file can be fetched: a path on disk for local
+// storage, or a short-lived presigned URL for R2
+func (s *QualificationService) EvidenceLocation(ctx context.Context, organizationID, certificationID, evidenceID, userID, userRole string) (*models.CertificationEvidence, string, string, error) {
+	certification, err := s.GetCertification(ctx, organizationID, certificationID, userID, userRole)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	for i := range certification.Evidence {
+		evidence := &certification.Evidence[i]
+		if evidence.ID != evidenceID {
+			continue
+		}
+		if localPath := s.storage.GetLocalFilePath(evidence.StoragePath); localPath != "" {
+			return evidence, localPath, "", nil
+		}
+		url, err := s.storage.GeneratePresignedURL(ctx, evidence.StoragePath, certificationEvidenceDownloadExpiry)
+		if err != nil {
+			return nil, "", "", fmt.Errorf("failed to create download link: %v", err)
+		}
+		return evidence, "", url, nil
+	}
+	return nil, "", "", ErrCertificationNotFound
+}
+
+// setCertificationStatus fills in the certificate's status on the given day
+func setCertificationStatus(certification *models.InspectorCertification, now time.Time) {
+	reminderDays := defaultCertificationReminderDays()
+	if certification.CertificationType != nil {
+		reminderDays = certification.CertificationType.ReminderDays
+	}
+	certification.Status = certification.StatusOn(now, reminderDays)
+}
+
+// =====================================================
+// TEMPLATE REQUIREMENTS
+// =====================================================
+
+// GetTemplateQualifications returns the certification types a template requires
+func (s *QualificationService) GetTemplateQualifications(ctx context.Context, organizationID, templateID string) ([]models.CertificationType, error) {
+	familyID, err := templateFamilyID(ctx, s.db, organizationID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	return requiredCertificationTypes(ctx, s.db, organizationID, familyID)
+}
+
+// SetTemplateQualifications replaces the certification types a template requires. The
+// requirements apply to every version of the template.
+func (s *QualificationService) SetTemplateQualifications(ctx context.Context, organizationID, templateID, userID string, typeIDs []string) ([]models.CertificationType, error) {
+	familyID, err := templateFamilyID(ctx, s.db, organizationID, templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	typeIDs = uniqueStrings(typeIDs)
+	if len(typeIDs) > 0 {
+		var found int64
+		if err := database.Conn(ctx, s.db).Model(&models.CertificationType{}).
+			Where("organization_id = ? AND id IN ? AND is_active = ?", organizationID, typeIDs, true).
+			Count(&found).Error; err != nil {
+			return nil, fmt.Errorf("failed to check certification types: %v", err)
+		}
+		if int(found) != len(typeIDs) {
+			return nil, fmt.Errorf("%w: some certification types don't exist or are no longer in use", ErrInvalidQualification)
+		}
+	}
+
+	err = database.Conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("organization_id = ? AND template_id = ?", organizationID, familyID).Delete(&models.TemplateQualification{}).Error; err != nil {
+			return err
+		}
+		for _, typeID := range typeIDs {
+			if err := tx.Create(&models.TemplateQualification{
+				OrganizationID:      organizationID,
+				TemplateID:          familyID,
+				CertificationTypeID: typeID,
+				CreatedBy:           userID,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save template qualifications: %v", err)
+	}
+
+	return requiredCertificationTypes(ctx, s.db, organizationID, familyID)
+}
+
+// templateFamilyID returns the ID of the template's first version, which holds the
+// requirements for all of its versions
+func templateFamilyID(ctx context.Context, db *gorm.DB, organizationID, templateID string) (string, error) {
+	var template models.Template
+	if err := database.Conn(ctx, db).Select("id", "parent_template_id").
+		Where("id = ? AND organization_id = ?", templateID, organizationID).First(&template).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", fmt.Errorf("%w: template not found", ErrInvalidQualification)
+		}
+		return "", fmt.Errorf("failed to get template: %v", err)
+	}
+	if template.ParentTemplateID != nil {
+		return template.ParentTemplateID.String(), nil
+	}
+	return template.ID.String(), nil
+}
+
+// requiredCertificationTypes returns the certification types required by a template family
+func requiredCertificationTypes(ctx context.Context, db *gorm.DB, organizationID, familyID string) ([]models.CertificationType, error) {
+	var types []models.CertificationType
+	if err := database.Conn(ctx, db).
+		Where("organization_id = ? AND id IN (?)", organizationID,
+			database.Conn(ctx, db).Model(&models.TemplateQualification{}).Select("certification_type_id").Where("template_id = ?", familyID)).
+		Order("name").Find(&types).Error; err != nil {
+		return nil, fmt.Errorf("failed to get template qualifications: %v", err)
+	}
+	return types, nil
+}
+
+// =====================================================
+// QUALIFICATION CHECKS
+// =====================================================
+
+// qualificationGaps returns the certification types the template requires and, for each
+// inspector missing any, those they don't hold for every day from from through to
+func qualificationGaps(ctx context.Context, db *gorm.DB, organizationID, templateID string, inspectorIDs []string, from, to time.Time) ([]models.CertificationType, map[string][]models.CertificationType, error) {
+	familyID, err := templateFamilyID(ctx, db, organizationID, templateID)
+	if errors.Is(err, ErrInvalidQualification) {
+		// Callers check the template exists; one that doesn't requires nothing
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	required, err := requiredCertificationTypes(ctx, db, organizationID, familyID)
+	if err != nil || len(required) == 0 || len(inspectorIDs) == 0 {
+		return required, nil, err
+	}
+
+	typeIDs := make([]string, len(required))
+	for i, certificationType := range required {
+		typeIDs[i] = certificationType.ID
+	}
+	var certifications []models.InspectorCertification
+	if err := database.Conn(ctx, db).
+		Where("organization_id = ? AND user_id IN ? AND certification_type_id IN ?", organizationID, inspectorIDs, typeIDs).
+		Find(&certifications).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to get certifications: %v", err)
+	}
+	held := make(map[string][]models.InspectorCertification)
+	for _, certification := range certifications {
+		key := certification.UserID + "|" + certification.CertificationTypeID
+		held[key] = append(held[key], certification)
+	}
+
+	from, to = civilDate(from), civilDate(to)
+	if to.Before(from) {
+		to = from
+	}
+	missing := make(map[string][]models.CertificationType)
+	for _, inspectorID := range inspectorIDs {
+		for _, certificationType := range required {
+			if !certificationsCover(held[inspectorID+"|"+certificationType.ID], from, to) {
+				missing[inspectorID] = append(missing[inspectorID], certificationType)
+			}
+		}
+	}
+	return required, missing, nil
+}
+
+// certificationsCover reports whether the certificates, together, are valid on every day
+// from from through to, so a renewal taking over from an expiring certificate counts
+func certificationsCover(certifications []models.InspectorCertification, from, to time.Time) bool {
+	day := from
+	for progressed := true; progressed && !day.After(to); {
+		progressed = false
+		for _, certification := range certifications {
+			if !certification.ValidOn(day) {
+				continue
+			}
+			// Valid through the earlier of expiry and the day before revocation
+			last := to
+			if certification.ExpiresOn != nil && civilDate(*certification.ExpiresOn).Before(last) {
+				last = civilDate(*certification.ExpiresOn)
+			}
+			if certification.RevokedOn != nil && civilDate(*certification.RevokedOn).AddDate(0, 0, -1).Before(last) {
+				last = civilDate(*certification.RevokedOn).AddDate(0, 0, -1)
+			}
+			day = last.AddDate(0, 0, 1)
+			progressed = true
+		}
+	}
+	return day.After(to)
+}
+
+// requireQualified checks the inspector holds every certification the template requires
+// for each day from from through to
+func requireQualified(ctx context.Context, db *gorm.DB, organizationID, inspectorID, templateID string, from, to time.Time) error {
+	_, missing, err := qualificationGaps(ctx, db, organizationID, templateID, []string{inspectorID}, from, to)
+	if err != nil {
+		return err
+	}
+	if len(missing[inspectorID]) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s lacks %s from %s through %s", ErrInspectorNotQualified, inspectorID,
+		certificationTypeNames(missing[inspectorID]), civilDate(from).Format(civilDateLayout), civilDate(to).Format(civilDateLayout))
+}
+
+// workPeriod is the first and last day of work starting on start, or today if that has
+// passed, and due on due, which may be nil
+func workPeriod(start, due *time.Time) (time.Time, time.Time) {
+	from := time.Now()
+	if start != nil && start.After(from) {
+		from = *start
+	}
+	to := from
+	if due != nil && due.After(to) {
+		to = *due
+	}
+	return from, to
+}
+
+func certificationTypeNames(types []models.CertificationType) string {
+	names := make([]string, len(types))
+	for i, certificationType := range types {
+		names[i] = certificationType.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// CheckQualification says whether the inspector may take the template's work from from
+// through to, and what they'd need
+func (s *QualificationService) CheckQualification(ctx context.Context, organizationID, inspectorID, templateID string, from, to time.Time) (*models.QualificationCheck, error) {
+	required, missing, err := qualificationGaps(ctx, s.db, organizationID, templateID, []string{inspectorID}, from, to)
+	if err != nil {
+		return nil, err
+	}
+	check := &models.QualificationCheck{
+		InspectorID: inspectorID,
+		TemplateID:  templateID,
+		From:        civilDate(from),
+		To:          civilDate(to),
+		Qualified:   len(missing[inspectorID]) == 0,
+		Required:    required,
+		Missing:     missing[inspectorID],
+	}
+	if check.Required == nil {
+		check.Required = []models.CertificationType{}
+	}
+	if check.Missing == nil {
+		check.Missing = []models.CertificationType{}
+	}
+	return check, nil
+}
+
+// =====================================================
+// INSPECTION PROOF
+// =====================================================
+
+// inspectionDay is the day an inspection was carried out: when it was completed, else
+// started, else scheduled, else today
+func inspectionDay(inspection *models.Inspection) time.Time {
+	for _, t := range []*time.Time{inspection.CompletedAt, inspection.StartedAt, inspection.ScheduledFor} {
+		if t != nil {
+			return civilDate(*t)
+		}
+	}
+	return civilDate(time.Now())
+}
+
+// inspectionQualifications matches each certification type the inspection's template
+// requires with the certificate its inspector held on the inspection day
+func inspectionQualifications(ctx context.Context, db *gorm.DB, inspection *models.Inspection) ([]models.InspectionQualification, error) {
+	familyID, err := templateFamilyID(ctx, db, inspection.OrganizationID, inspection.TemplateID.String())
+	if err != nil {
+		return nil, err
+	}
+	required, err := requiredCertificationTypes(ctx, db, inspection.OrganizationID, familyID)
+	if err != nil || len(required) == 0 {
+		return nil, err
+	}
+
+	day := inspectionDay(inspection)
+	var certifications []models.InspectorCertification
+	if err := database.Conn(ctx, db).
+		Where("organization_id = ? AND user_id = ?", inspection.OrganizationID, inspection.InspectorID).
+		Order("issued_on DESC").Find(&certifications).Error; err != nil {
+		return nil, fmt.Errorf("failed to get certifications: %v", err)
+	}
+
+	qualifications := make([]models.InspectionQualification, 0, len(required))
+	for _, certificationType := range required {
+		qualification := models.InspectionQualification{
+			OrganizationID:      inspection.OrganizationID,
+			InspectionID:        inspection.ID.String(),
+			InspectorID:         inspection.InspectorID,
+			CertificationTypeID: certificationType.ID,
+			InspectedOn:         day,
+			RecordedAt:          time.Now(),
+		}
+		for _, certification := range certifications {
+			if certification.CertificationTypeID != certificationType.ID || !certification.ValidOn(day) {
+				continue
+			}
+			issuedOn := certification.IssuedOn
+			qualification.CertificationID = &certification.ID
+			qualification.CertificateNumber = certification.CertificateNumber
+			qualification.IssuedOn = &issuedOn
+			qualification.ExpiresOn = certification.ExpiresOn
+			break
+		}
+		qualifications = append(qualifications, qualification)
+	}
+	return qualifications, nil
+}
+
+// recordInspectionQualifications keeps, when an inspection is completed, the certificates
+// its inspector held that day. Completing a reopened inspection replaces the record.
+func recordInspectionQualifications(ctx context.Context, db *gorm.DB, before, after *models.Inspection) {
+	if after == nil || after.Status != "completed" || (before != nil && before.Status == "completed") {
+		return
+	}
+
+	qualifications, err := inspectionQualifications(ctx, db, after)
+	if err == nil {
+		err = database.Conn(ctx, db).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("inspection_id = ?", after.ID.String()).Delete(&models.InspectionQualification{}).Error; err != nil {
+				return err
+			}
+			if len(qualifications) == 0 {
+				return nil
+			}
+			return tx.Create(&qualifications).Error
+		})
+	}
+	if err != nil {
+		log.Printf("Failed to record qualifications for inspection %s: %v", after.ID, err)
+	}
+}
+
+// GetQualificationProof shows the certificates the inspector held on the day of the
+// inspection, as recorded when it was completed, or worked out from the certificates on
+// record for inspections completed without one
+func (s *QualificationService) GetQualificationProof(ctx context.Context, organizationID, inspectionID string) (*models.QualificationProof, error) {
+	db := database.Conn(ctx, s.db)
+	var inspection models.Inspection
+	if err := db.Where("id = ? AND organization_id = ?", inspectionID, organizationID).First(&inspection).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrProofInspectionNotFound
+		}
+		return nil, fmt.Errorf("failed to get inspection: %v", err)
+	}
+
+	proof := &models.QualificationProof{
+		InspectionID: inspection.ID.String(),
+		InspectorID:  inspection.InspectorID,
+		InspectedOn:  inspectionDay(&inspection),
+	}
+	if err := db.Where("inspection_id = ?", proof.InspectionID).
+		Preload("CertificationType").Preload("Certification.Evidence").
+		Find(&proof.Qualifications).Error; err != nil {
+		return nil, fmt.Errorf("failed to get inspection qualifications: %v", err)
+	}
+
+	if len(proof.Qualifications) > 0 {
+		proof.Recorded = true
+		proof.InspectorID = proof.Qualifications[0].InspectorID
+		proof.InspectedOn = proof.Qualifications[0].InspectedOn
+	} else {
+		qualifications, err := inspectionQualifications(ctx, s.db, &inspection)
+		if err != nil {
+			return nil, err
+		}
+		for i := range qualifications {
+			qualification := &qualifications[i]
+			qualification.CertificationType = &models.CertificationType{}
+			if err := db.First(qualification.CertificationType, "id = ?", qualification.CertificationTypeID).Error; err != nil {
+				return nil, fmt.Errorf("failed to get certification type: %v", err)
+			}
+			if qualification.CertificationID != nil {
+				qualification.Certification = &models.InspectorCertification{}
+				if err := db.Preload("Evidence").First(qualification.Certification, "id = ?", *qualification.CertificationID).Error; err != nil {
+					return nil, fmt.Errorf("failed to get certification: %v", err)
+				}
+			}
+		}
+		proof.Qualifications = qualifications
+	}
+
+	sort.Slice(proof.Qualifications, func(i, j int) bool {
+		a, b := proof.Qualifications[i].CertificationType, proof.Qualifications[j].CertificationType
+		return a != nil && b != nil && a.Name < b.Name
+	})
+	proof.Complete = true
+	for _, qualification := range proof.Qualifications {
+		if qualification.CertificationID == nil {
+			proof.Complete = false
+		}
+	}
+	if proof.Qualifications == nil {
+		proof.Qualifications = []models.InspectionQualification{}
+	}
+	return proof, nil
+}
+
+// =====================================================
+// EXPIRY TRACKING
+// =====================================================
+
+// CheckExpiringCertifications warns holders and their organization's supervisors, in every
+// organization, about certificates that entered their reminder window or lapsed and
+// haven't been renewed. A certificate is warned about once per expiry date, even with
+// several servers running.
+func (s *QualificationService) CheckExpiringCertifications(ctx context.Context, now time.Time) (*models.CertificationExpiryCheck, error) {
+	horizon := civilDate(now).AddDate(0, 0, maxCertificationReminderDays)
+
+	var certifications []models.InspectorCertification
+	err := s.db.WithContext(ctx).Preload("CertificationType").Preload("User").
+		Where("expires_on IS NOT NULL AND expires_on <= ? AND revoked_on IS NULL", horizon).
+		Where("reminder_sent_for IS NULL OR reminder_sent_for <> expires_on").
+		Order("organization_id, expires_on").
+		Find(&certifications).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch expiring certifications: %v", err)
+	}
+
+	result := &models.CertificationExpiryCheck{CertificationsChecked: len(certifications)}
+	managers := make(map[string][]string)
+	for i := range certifications {
+		certification := &certifications[i]
+		setCertificationStatus(certification, now)
+		if certification.Status != models.CertificationExpiring && certification.Status != models.CertificationExpired {
+			continue
+		}
+
+		// A newer certificate of the same type that runs longer means it was renewed
+		var renewals int64
+		if err := s.db.WithContext(ctx).Model(&models.InspectorCertification{}).
+			Where("organization_id = ? AND user_id = ? AND certification_type_id = ? AND id <> ? AND revoked_on IS NULL",
+				certification.OrganizationID, certification.UserID, certification.CertificationTypeID, certification.ID).
+			Where("expires_on IS NULL OR expires_on > ?", *certification.ExpiresOn).
+			Count(&renewals).Error; err != nil {
+			return result, fmt.Errorf("failed to check certification renewals: %v", err)
+		}
+		if renewals > 0 {
+			continue
+		}
+
+		// Claim the reminder so another server running the check skips it
+		claim := s.db.WithContext(ctx).Model(&models.InspectorCertification{}).
+			Where("id = ? AND (reminder_sent_for IS NULL OR reminder_sent_for <> expires_on)", certification.ID).
+			UpdateColumn("reminder_sent_for", gorm.Expr("expires_on"))
+		if claim.Error != nil {
+			return result, fmt.Errorf("failed to mark certification reminder: %v", claim.Error)
+		}
+		if claim.RowsAffected == 0 {
+			continue
+		}
+
+		recipients, ok := managers[certification.OrganizationID]
+		if !ok {
+			if err := s.db.WithContext(ctx).Model(&models.OrganizationMember{}).
+				Where("organization_id = ? AND role IN ? AND status = ?", certification.OrganizationID, qualificationManagerRoles, "active").
+				Order("role, joined_at").
+				Pluck("user_id", &recipients).Error; err != nil {
+				return result, fmt.Errorf("failed to fetch supervisors: %v", err)
+			}
+			managers[certification.OrganizationID] = recipients
+		}
+
+		title, holderMessage, managerMessage := certificationReminderText(certification)
+		s.notify(certification.OrganizationID, certification.UserID, title, holderMessage)
+		for _, userID := range recipients {
+			if userID != certification.UserID {
+				s.notify(certification.OrganizationID, userID, title, managerMessage)
+			}
+		}
+		result.Reminded++
+	}
+
+	return result, nil
+}
+
+// certificationReminderText builds the notifications for a lapsing certificate, for its
+// holder and for supervisors
+func certificationReminderText(certification *models.InspectorCertification) (string, string, string) {
+	typeName, holder := certification.CertificationTypeID, certification.UserID
+	if certification.CertificationType != nil {
+		typeName = certification.CertificationType.Name
+	}
+	if certification.User != nil && certification.User.Name != "" {
+		holder = certification.User.Name
+	}
+	expiresOn := certification.ExpiresOn.Format(civilDateLayout)
+
+	if certification.Status == models.CertificationExpired {
+		return "Certification Expired",
+			fmt.Sprintf("Your %s certification expired on %s. You can't be assigned work that requires it until it is renewed.", typeName, expiresOn),
+			fmt.Sprintf("%s's %s certification expired on %s.", holder, typeName, expiresOn)
+	}
+	return "Certification Expiring",
+		fmt.Sprintf("Your %s certification expires on %s. Renew it before then to keep taking work that requires it.", typeName, expiresOn),
+		fmt.Sprintf("%s's %s certification expires on %s.", holder, typeName, expiresOn)
+}
+
+func (s *QualificationService) notify(organizationID, userID, title, message string) {
+	if _, err := s.notificationService.CreateNotification(&models.CreateNotificationRequest{
+		OrganizationID: organizationID,
+		UserID:         userID,
+		Title:          title,
+		Message:        message,
+		Type:           "certification_expiry",
+	}); err != nil {
+		log.Printf("Failed to notify %s about certification expiry: %v", userID, err)
+	}
+}
+
+// StartExpiryChecker runs CheckExpiringCertifications now and then every interval until ctx is done
+func (s *QualificationService) StartExpiryChecker(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			result, err := s.CheckExpiringCertifications(ctx, time.Now())
+			if err != nil {
+				log.Printf("Certification expiry check failed: %v", err)
+			} else if result.Reminded > 0 {
+				log.Printf("Certification expiry check sent %d reminders", result.Reminded)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
@@ -0,0 +1,365 @@
+package services
+
+import (
+	"context"
+	"resource-mgmt/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// qualificationTestFixture is a boiler template whose newer version requires a yearly
+// boiler licence, and a plant to inspect. insp-1 holds a licence that lapses in nine days;
+// insp-2 holds nothing.
+type qualificationTestFixture struct {
+	db                *gorm.DB
+	service           *QualificationService
+	workflow          *WorkflowService
+	template, version *models.Template
+	site              *models.Site
+	boiler            *models.CertificationType
+	current           *models.InspectorCertification
+	today             time.Time
+}
+
+func newQualificationTestFixture(t *testing.T) *qualificationTestFixture {
+	db := setupWorkflowTestDB(t, &models.CertificationEvidence{}, &models.InspectionQualification{})
+	ctx := context.Background()
+	storage, err := NewStorageService(StorageConfig{Provider: StorageLocal, LocalPath: t.TempDir(), BaseURL: "/uploads"})
+	require.NoError(t, err)
+	f := &qualificationTestFixture{db: db, service: NewQualificationService(db, storage), workflow: NewWorkflowService(db, NewNotificationService()), today: civilDate(time.Now())}
+	createTestMembers(t, db, "org-a", "supervisor", "super-1")
+	createTestMembers(t, db, "org-a", "inspector", "insp-1", "insp-2")
+
+	f.template = createTestTemplate(t, db, "org-a", "Boiler")
+	f.version = &models.Template{ID: uuid.New(), OrganizationID: "org-a", Name: "Boiler", FieldsSchema: datatypes.JSON(`{}`), ParentTemplateID: &f.template.ID}
+	require.NoError(t, db.Create(f.version).Error)
+	f.site = createTestSite(t, db, "org-a", "Plant", "2 Mill Ln")
+
+	reminderDays := 30
+	f.boiler, err = f.service.CreateCertificationType(ctx, "org-a", "super-1", &models.CertificationTypeRequest{Name: "Boiler licence", ValidityMonths: 12, ReminderDays: &reminderDays})
+	require.NoError(t, err)
+	f.current, err = f.service.CreateCertification(ctx, "org-a", "super-1", &models.CertificationRequest{
+		UserID: "insp-1", CertificationTypeID: f.boiler.ID, CertificateNumber: "BL-1", IssuedOn: f.today.AddDate(-1, 0, 10)})
+	require.NoError(t, err)
+	_, err = f.service.SetTemplateQualifications(ctx, "org-a", f.version.ID.String(), "super-1", []string{f.boiler.ID})
+	require.NoError(t, err)
+	return f
+}
+
+// assign gives the plant to the inspector on the newer template version
+func (f *qualificationTestFixture) assign(inspectorID string, due time.Time) ([]models.InspectionAssignment, error) {
+	return f.workflow.CreateBulkAssignment(context.Background(), "org-a", "super-1", map[string]interface{}{
+		"name": "Annual", "template_id": f.version.ID.String(), "site_ids": []string{f.site.ID}, "due_date": due,
+		"inspector_assignments": []map[string]interface{}{{"inspector_id": inspectorID, "site_ids": []string{f.site.ID}}},
+	})
+}
+
+// renew gives insp-1 a licence that takes over on the tenth day and runs for a year
+func (f *qualificationTestFixture) renew(t *testing.T) *models.InspectorCertification {
+	expiresOn := f.today.AddDate(1, 0, 0)
+	renewal, err := f.service.CreateCertification(context.Background(), "org-a", "super-1", &models.CertificationRequest{
+		UserID: "insp-1", CertificationTypeID: f.boiler.ID, CertificateNumber: "BL-2", IssuedOn: f.today.AddDate(0, 0, 10), ExpiresOn: &expiresOn})
+	require.NoError(t, err)
+	return renewal
+}
+
+func (f *qualificationTestFixture) revokeCurrent(t *testing.T) {
+	_, err := f.service.RevokeCertification(context.Background(), "org-a", f.current.ID, "super-1", &models.RevokeCertificationRequest{Reason: "Replaced"})
+	require.NoError(t, err)
+}
+
+func TestQualificationService_CreateCertificationTypeValidation(t *testing.T) {
+	negative, tooMany := -1, maxCertificationReminderDays+1
+	tests := []struct {
+		name string
+		req  models.CertificationTypeRequest
+	}{
+		{"missing name", models.CertificationTypeRequest{Name: "  "}},
+		{"name already used", models.CertificationTypeRequest{Name: "boiler LICENCE"}},
+		{"negative validity", models.CertificationTypeRequest{Name: "Gas safe", ValidityMonths: -1}},
+		{"negative reminder", models.CertificationTypeRequest{Name: "Gas safe", ReminderDays: &negative}},
+		{"reminder too far ahead", models.CertificationTypeRequest{Name: "Gas safe", ReminderDays: &tooMany}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newQualificationTestFixture(t)
+			req := tt.req
+
+			_, err := f.service.CreateCertificationType(context.Background(), "org-a", "super-1", &req)
+			assert.ErrorIs(t, err, ErrInvalidQualification)
+		})
+	}
+}
+
+func TestQualificationService_CertificateDefaultsToTypeValidity(t *testing.T) {
+	f := newQualificationTestFixture(t)
+
+	require.NotNil(t, f.current.ExpiresOn)
+	assert.Equal(t, f.today.AddDate(0, 0, 9), civilDate(*f.current.ExpiresOn))
+	assert.Equal(t, models.CertificationExpiring, f.current.Status)
+}
+
+func TestQualificationService_CreateCertificationValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		req  func(f *qualificationTestFixture) models.CertificationRequest
+	}{
+		{"not a member", func(f *qualificationTestFixture) models.CertificationRequest {
+			return models.CertificationRequest{UserID: "stranger", CertificationTypeID: f.boiler.ID, IssuedOn: f.today}
+		}},
+		{"unknown type", func(f *qualificationTestFixture) models.CertificationRequest {
+			return models.CertificationRequest{UserID: "insp-2", CertificationTypeID: uuid.NewString(), IssuedOn: f.today}
+		}},
+		{"expires before issued", func(f *qualificationTestFixture) models.CertificationRequest {
+			expiresOn := f.today.AddDate(0, 0, -1)
+			return models.CertificationRequest{UserID: "insp-2", CertificationTypeID: f.boiler.ID, IssuedOn: f.today, ExpiresOn: &expiresOn}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newQualificationTestFixture(t)
+			req := tt.req(f)
+
+			_, err := f.service.CreateCertification(context.Background(), "org-a", "super-1", &req)
+			assert.ErrorIs(t, err, ErrInvalidQualification)
+		})
+	}
+}
+
+func TestQualificationService_RequirementsApplyToTemplateFamily(t *testing.T) {
+	f := newQualificationTestFixture(t)
+	ctx := context.Background()
+
+	required, err := f.service.GetTemplateQualifications(ctx, "org-a", f.template.ID.String())
+	require.NoError(t, err)
+	require.Len(t, required, 1)
+	assert.Equal(t, f.boiler.ID, required[0].ID)
+
+	assert.ErrorIs(t, f.service.DeleteCertificationType(ctx, "org-a", f.boiler.ID), ErrCertificationTypeInUse)
+}
+
+func TestQualificationService_AssignmentsNeedCoverUntilDue(t *testing.T) {
+	tests := []struct {
+		name      string
+		renew     bool
+		inspector string
+		dueInDays int
+		wantErr   error
+	}{
+		{"no certificate", false, "insp-2", 3, ErrInspectorNotQualified},
+		{"certificate lapses before due date", false, "insp-1", 21, ErrInspectorNotQualified},
+		{"certificate covers due date", false, "insp-1", 3, nil},
+		{"renewal takes over on expiry", true, "insp-1", 21, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newQualificationTestFixture(t)
+			if tt.renew {
+				f.renew(t)
+			}
+
+			assignments, err := f.assign(tt.inspector, f.today.AddDate(0, 0, tt.dueInDays))
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Zero(t, countRows(t, f.db.Model(&models.InspectionAssignment{})), "nothing is assigned")
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, assignments, 1)
+		})
+	}
+}
+
+func TestQualificationService_CheckQualificationListsMissing(t *testing.T) {
+	f := newQualificationTestFixture(t)
+
+	check, err := f.service.CheckQualification(context.Background(), "org-a", "insp-1", f.template.ID.String(), f.today, f.today.AddDate(0, 0, 21))
+	require.NoError(t, err)
+	assert.False(t, check.Qualified)
+	require.Len(t, check.Missing, 1)
+	assert.Equal(t, "Boiler licence", check.Missing[0].Name)
+}
+
+func TestQualificationService_ReassignToUnqualifiedIsRefused(t *testing.T) {
+	f := newQualificationTestFixture(t)
+	assignments, err := f.assign("insp-1", f.today.AddDate(0, 0, 3))
+	require.NoError(t, err)
+
+	_, err = f.workflow.ReassignInspection(context.Background(), "org-a", assignments[0].ID, "super-1", "insp-2", "Closer to site", false)
+	assert.ErrorIs(t, err, ErrInspectorNotQualified)
+
+	var kept models.InspectionAssignment
+	require.NoError(t, f.db.First(&kept, "id = ?", assignments[0].ID).Error)
+	assert.Equal(t, "insp-1", kept.AssignedTo)
+}
+
+func TestQualificationService_EvidenceAccess(t *testing.T) {
+	tests := []struct {
+		name    string
+		userID  string
+		role    string
+		wantErr error
+	}{
+		{"holder", "insp-1", "inspector", nil},
+		{"supervisor", "super-1", "supervisor", nil},
+		{"another inspector", "insp-2", "inspector", ErrCertificationNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newQualificationTestFixture(t)
+			ctx := context.Background()
+			evidence, err := f.service.AddEvidence(ctx, "org-a", f.current.ID, "insp-1", "inspector", testDocumentFile(t, "licence.pdf", "scan"))
+			require.NoError(t, err)
+
+			_, localPath, _, err := f.service.EvidenceLocation(ctx, "org-a", f.current.ID, evidence.ID, tt.userID, tt.role)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.FileExists(t, localPath)
+		})
+	}
+}
+
+func TestQualificationService_GetCertificationAccess(t *testing.T) {
+	tests := []struct {
+		name         string
+		organization string
+		userID       string
+		role         string
+		wantErr      error
+	}{
+		{"holder", "org-a", "insp-1", "inspector", nil},
+		{"supervisor", "org-a", "super-1", "supervisor", nil},
+		{"another inspector", "org-a", "insp-2", "inspector", ErrCertificationNotFound},
+		{"other organization", "org-b", "super-1", "supervisor", ErrCertificationNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newQualificationTestFixture(t)
+
+			_, err := f.service.GetCertification(context.Background(), tt.organization, f.current.ID, tt.userID, tt.role)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestQualificationService_RevokeCertificationOnce(t *testing.T) {
+	f := newQualificationTestFixture(t)
+	f.revokeCurrent(t)
+
+	_, err := f.service.RevokeCertification(context.Background(), "org-a", f.current.ID, "super-1", &models.RevokeCertificationRequest{Reason: "Again"})
+	assert.ErrorIs(t, err, ErrCertificationRevoked)
+}
+
+func TestQualificationService_RecordedProofSurvivesRevocation(t *testing.T) {
+	f := newQualificationTestFixture(t)
+	ctx := context.Background()
+	_, err := f.service.AddEvidence(ctx, "org-a", f.current.ID, "insp-1", "inspector", testDocumentFile(t, "licence.pdf", "scan"))
+	require.NoError(t, err)
+
+	// Completing an inspection records the certificate held that day
+	completedAt := time.Now()
+	inspection := &models.Inspection{OrganizationID: "org-a", TemplateID: f.version.ID, InspectorID: "insp-1", SiteID: f.site.ID, Status: "in_progress"}
+	require.NoError(t, f.db.Create(inspection).Error)
+	completed := *inspection
+	completed.Status = "completed"
+	completed.CompletedAt = &completedAt
+	recordInspectionQualifications(ctx, f.db, inspection, &completed)
+
+	f.revokeCurrent(t)
+
+	proof, err := f.service.GetQualificationProof(ctx, "org-a", inspection.ID.String())
+	require.NoError(t, err)
+	assert.True(t, proof.Recorded)
+	assert.True(t, proof.Complete)
+	require.Len(t, proof.Qualifications, 1)
+	assert.Equal(t, "BL-1", proof.Qualifications[0].CertificateNumber)
+	require.NotNil(t, proof.Qualifications[0].Certification)
+	assert.Len(t, proof.Qualifications[0].Certification.Evidence, 1)
+}
+
+func TestQualificationService_UnrecordedProof(t *testing.T) {
+	tests := []struct {
+		name         string
+		organization string
+		wantErr      error
+	}{
+		{"worked out from certificates on file", "org-a", nil},
+		{"other organization", "org-b", ErrProofInspectionNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newQualificationTestFixture(t)
+			completedAt := time.Now()
+			unrecorded := &models.Inspection{OrganizationID: "org-a", TemplateID: f.template.ID, InspectorID: "insp-2", SiteID: f.site.ID, Status: "completed", CompletedAt: &completedAt}
+			require.NoError(t, f.db.Create(unrecorded).Error)
+
+			proof, err := f.service.GetQualificationProof(context.Background(), tt.organization, unrecorded.ID.String())
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.False(t, proof.Recorded)
+			assert.False(t, proof.Complete, "insp-2 holds no licence")
+		})
+	}
+}
+
+func TestQualificationService_ExpiryReminders(t *testing.T) {
+	tests := []struct {
+		name         string
+		prepare      func(t *testing.T, f *qualificationTestFixture)
+		wantReminded int
+		wantNotified []string
+	}{
+		{"lapsing certificate", nil, 1, []string{"insp-1", "super-1"}},
+		{"renewed certificate", func(t *testing.T, f *qualificationTestFixture) { f.renew(t) }, 0, []string{}},
+		{"revoked certificate", func(t *testing.T, f *qualificationTestFixture) { f.revokeCurrent(t) }, 0, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newQualificationTestFixture(t)
+			ctx := context.Background()
+			if tt.prepare != nil {
+				tt.prepare(t, f)
+			}
+
+			result, err := f.service.CheckExpiringCertifications(ctx, time.Now())
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantReminded, result.Reminded)
+			var notified []string
+			require.NoError(t, f.db.Model(&models.Notification{}).Where("type = ?", "certification_expiry").Order("user_id").Pluck("user_id", &notified).Error)
+			assert.Equal(t, tt.wantNotified, notified)
+		})
+	}
+}
+
+func TestQualificationService_ExpiryReminderSentOnce(t *testing.T) {
+	f := newQualificationTestFixture(t)
+	ctx := context.Background()
+
+	result, err := f.service.CheckExpiringCertifications(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Reminded)
+	result, err = f.service.CheckExpiringCertifications(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Reminded)
+
+	reloaded, err := f.service.GetCertification(ctx, "org-a", f.current.ID, "insp-1", "inspector")
+	require.NoError(t, err)
+	require.NotNil(t, reloaded.ReminderSentFor)
+	assert.Equal(t, civilDate(*f.current.ExpiresOn), civilDate(*reloaded.ReminderSentFor))
+}
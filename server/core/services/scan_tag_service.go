@@ -0,0 +1,419 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"resource-mgmt/config"
+	"resource-mgmt/models"
+	"resource-mgmt/pkg/database"
+	"resource-mgmt/pkg/utils"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidScanTag is returned when a scanned code is malformed or its signature doesn't match
+	ErrInvalidScanTag = errors.New("invalid scan tag")
+	// ErrScanTagNotFound is returned when a tag doesn't exist in the caller's organization
+	ErrScanTagNotFound = errors.New("scan tag not found")
+	// ErrScanTagRevoked is returned when a revoked tag is scanned or bound
+	ErrScanTagRevoked = errors.New("scan tag has been revoked")
+	// ErrScanTagAlreadyBound is returned when binding a tag that already points at an entity
+	ErrScanTagAlreadyBound = errors.New("scan tag is already bound")
+	// ErrInvalidScanTagEntity is returned for entity types other than site and asset, or unknown entities
+	ErrInvalidScanTagEntity = errors.New("invalid scan tag entity")
+	// ErrInvalidScanTagRequest is returned when a sheet request asks for no tags or too many
+	ErrInvalidScanTagRequest = errors.New("invalid scan tag request")
+)
+
+// scanTagCodeVersion prefixes every tag code so the format can evolve
+const scanTagCodeVersion = "t1"
+
+// maxScanTagsPerRequest bounds how many tags one sheet request can create
+const maxScanTagsPerRequest = 500
+
+// ScanTagService issues, signs and resolves printed QR tags for sites and assets
+type ScanTagService struct {
+	db *gorm.DB
+}
+
+func NewScanTagService(db *gorm.DB) *ScanTagService {
+	return &ScanTagService{db: db}
+}
+
+// =====================================================
+// TAG CODES
+// =====================================================
+
+// TagCode returns the signed code printed on a tag: "t1.<organization>.<tag>.<signature>"
+func (s *ScanTagService) TagCode(tag *models.ScanTag) (string, error) {
+	signature, err := signScanTag(tag.OrganizationID, tag.ID)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join([]string{scanTagCodeVersion, tag.OrganizationID, tag.ID, signature}, "."), nil
+}
+
+// TagContent returns what the QR code encodes: the code, or a link to it when
+// SCAN_TAG_BASE_URL is configured
+func (s *ScanTagService) TagContent(tag *models.ScanTag) (string, error) {
+	code, err := s.TagCode(tag)
+	if err != nil {
+		return "", err
+	}
+	if config.ScanTagBaseURL != "" {
+		return strings.TrimRight(config.ScanTagBaseURL, "/") + "/" + code, nil
+	}
+	return code, nil
+}
+
+// ParseTagCode verifies a scanned code, which may be the bare code or a link ending in it,
+// and returns the organization and tag IDs it carries
+func (s *ScanTagService) ParseTagCode(scanned string) (string, string, error) {
+	code := strings.TrimSpace(scanned)
+	if i := strings.LastIndex(code, "/"); i >= 0 {
+		code = code[i+1:]
+	}
+
+	parts := strings.Split(code, ".")
+	if len(parts) != 4 || parts[0] != scanTagCodeVersion || parts[1] == "" || parts[2] == "" {
+		return "", "", ErrInvalidScanTag
+	}
+
+	expected, err := signScanTag(parts[1], parts[2])
+	if err != nil {
+		return "", "", err
+	}
+	if !hmac.Equal([]byte(expected), []byte(parts[3])) {
+		return "", "", ErrInvalidScanTag
+	}
+	return parts[1], parts[2], nil
+}
+
+func signScanTag(organizationID, tagID string) (string, error) {
+	secret := []byte(config.ScanTagSecret)
+	if len(secret) == 0 {
+		jwtSecret, err := utils.GetJWTSecret()
+		if err != nil {
+			return "", fmt.Errorf("failed to get scan tag secret: %w", err)
+		}
+		secret = jwtSecret
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("scan-tag." + scanTagCodeVersion + "." + organizationID + "." + tagID))
+	// 128 bits keeps the QR code small enough to scan from a distance
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16]), nil
+}
+
+// =====================================================
+// ISSUING AND BINDING
+// =====================================================
+
+// IssueTags returns one tag per entity, reusing an entity's existing bound tag, plus
+// blankCount unbound tags that can be bound after they are stuck on the wall
+func (s *ScanTagService) IssueTags(ctx context.Context, organizationID, createdBy, entityType string, entityIDs []string, blankCount int) ([]models.ScanTag, error) {
+	if blankCount < 0 || len(entityIDs)+blankCount == 0 {
+		return nil, fmt.Errorf("%w: at least one entity or blank tag is required", ErrInvalidScanTagRequest)
+	}
+	if len(entityIDs)+blankCount > maxScanTagsPerRequest {
+		return nil, fmt.Errorf("%w: at most %d tags can be issued at once", ErrInvalidScanTagRequest, maxScanTagsPerRequest)
+	}
+
+	var tags []models.ScanTag
+	err := database.Conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		for _, entityID := range entityIDs {
+			label, err := s.entityLabel(tx, organizationID, entityType, entityID)
+			if err != nil {
+				return err
+			}
+
+			var existing models.ScanTag
+			err = tx.Where("organization_id = ? AND entity_type = ? AND entity_id = ? AND status = ?",
+				organizationID, entityType, entityID, models.ScanTagStatusBound).
+				First(&existing).Error
+			if err == nil {
+				tags = append(tags, existing)
+				continue
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("failed to look up existing tag: %v", err)
+			}
+
+			id := entityID
+			tag := models.ScanTag{
+				OrganizationID: organizationID,
+				EntityType:     entityType,
+				EntityID:       &id,
+				Label:          label,
+				Status:         models.ScanTagStatusBound,
+				BoundBy:        &createdBy,
+				BoundAt:        &now,
+				CreatedBy:      createdBy,
+			}
+			if err := tx.Create(&tag).Error; err != nil {
+				return fmt.Errorf("failed to create scan tag: %v", err)
+			}
+			tags = append(tags, tag)
+		}
+
+		for i := 0; i < blankCount; i++ {
+			tag := models.ScanTag{
+				OrganizationID: organizationID,
+				Label:          "Unassigned tag",
+				Status:         models.ScanTagStatusUnbound,
+				CreatedBy:      createdBy,
+			}
+			if err := tx.Create(&tag).Error; err != nil {
+				return fmt.Errorf("failed to create scan tag: %v", err)
+			}
+			tags = append(tags, tag)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// GetTags lists the organization's tags, optionally filtered by status and entity
+func (s *ScanTagService) GetTags(ctx context.Context, organizationID string, filters map[string]interface{}) ([]models.ScanTag, error) {
+	query := database.Conn(ctx, s.db).Where("organization_id = ?", organizationID)
+	for _, key := range []string{"status", "entity_type", "entity_id"} {
+		if value, ok := filters[key].(string); ok && value != "" {
+			query = query.Where(fmt.Sprintf("%s = ?", key), value)
+		}
+	}
+
+	var tags []models.ScanTag
+	if err := query.Order("created_at DESC").Find(&tags).Error; err != nil {
+		return nil, fmt.Errorf("failed to get scan tags: %v", err)
+	}
+	return tags, nil
+}
+
+// GetTag retrieves a single tag
+func (s *ScanTagService) GetTag(ctx context.Context, organizationID, tagID string) (*models.ScanTag, error) {
+	var tag models.ScanTag
+	if err := database.Conn(ctx, s.db).
+		Where("id = ? AND organization_id = ?", tagID, organizationID).
+		First(&tag).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrScanTagNotFound
+		}
+		return nil, fmt.Errorf("failed to get scan tag: %v", err)
+	}
+	return &tag, nil
+}
+
+// BindTag attaches an unbound tag to a site or asset
+func (s *ScanTagService) BindTag(ctx context.Context, organizationID, tagID, entityType, entityID, boundBy string) (*models.ScanTag, error) {
+	tag, err := s.GetTag(ctx, organizationID, tagID)
+	if err != nil {
+		return nil, err
+	}
+	switch tag.Status {
+	case models.ScanTagStatusRevoked:
+		return nil, ErrScanTagRevoked
+	case models.ScanTagStatusBound:
+		return nil, ErrScanTagAlreadyBound
+	}
+
+	db := database.Conn(ctx, s.db)
+	label, err := s.entityLabel(db, organizationID, entityType, entityID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Guard on status so two supervisors binding the same tag can't both win
+	now := time.Now()
+	result := db.Model(&models.ScanTag{}).
+		Where("id = ? AND organization_id = ? AND status = ?", tag.ID, organizationID, models.ScanTagStatusUnbound).
+		Updates(map[string]interface{}{
+			"entity_type": entityType,
+			"entity_id":   entityID,
+			"label":       label,
+			"status":      models.ScanTagStatusBound,
+			"bound_by":    boundBy,
+			"bound_at":    now,
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to bind scan tag: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrScanTagAlreadyBound
+	}
+
+	return s.GetTag(ctx, organizationID, tagID)
+}
+
+// RevokeTag disables a lost or damaged tag; scanning it afterwards fails
+func (s *ScanTagService) RevokeTag(ctx context.Context, organizationID, tagID string) (*models.ScanTag, error) {
+	tag, err := s.GetTag(ctx, organizationID, tagID)
+	if err != nil {
+		return nil, err
+	}
+	if err := database.Conn(ctx, s.db).Model(tag).Update("status", models.ScanTagStatusRevoked).Error; err != nil {
+		return nil, fmt.Errorf("failed to revoke scan tag: %v", err)
+	}
+	return s.GetTag(ctx, organizationID, tagID)
+}
+
+// =====================================================
+// RESOLVING
+// =====================================================
+
+// Resolve turns a scanned code into what the inspector needs on the spot: the site
+// (and asset), the templates that apply there and the open inspections assigned to them.
+// Codes from other organizations resolve as not found.
+func (s *ScanTagService) Resolve(ctx context.Context, organizationID, userID, userRole, scanned string) (*models.ScanResolution, error) {
+	tagOrganizationID, tagID, err := s.ParseTagCode(scanned)
+	if err != nil {
+		return nil, err
+	}
+	if tagOrganizationID != organizationID {
+		return nil, ErrScanTagNotFound
+	}
+
+	tag, err := s.GetTag(ctx, organizationID, tagID)
+	if err != nil {
+		return nil, err
+	}
+	if tag.Status == models.ScanTagStatusRevoked {
+		return nil, ErrScanTagRevoked
+	}
+
+	db := database.Conn(ctx, s.db)
+	if err := db.Model(tag).Updates(map[string]interface{}{
+		"scan_count":      gorm.Expr("scan_count + 1"),
+		"last_scanned_at": time.Now(),
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to record scan: %v", err)
+	}
+
+	resolution := &models.ScanResolution{
+		Tag:             *tag,
+		Status:          tag.Status,
+		Templates:       []models.Template{},
+		OpenInspections: []models.Inspection{},
+	}
+
+	if tag.Status == models.ScanTagStatusUnbound || tag.EntityID == nil {
+		resolution.Status = models.ScanTagStatusUnbound
+		resolution.CanBind = userRole == "admin" || userRole == "supervisor"
+		return resolution, nil
+	}
+
+	var siteID, category string
+	switch tag.EntityType {
+	case models.ScanTagEntityAsset:
+		var asset models.Asset
+		if err := db.Where("id = ? AND organization_id = ?", *tag.EntityID, organizationID).First(&asset).Error; err != nil {
+			return nil, fmt.Errorf("failed to get tagged asset: %v", err)
+		}
+		resolution.Asset = &asset
+		siteID = asset.SiteID
+		category = asset.Type
+	case models.ScanTagEntitySite:
+		siteID = *tag.EntityID
+	default:
+		return nil, ErrInvalidScanTagEntity
+	}
+
+	var site models.Site
+	if err := db.Where("id = ? AND organization_id = ?", siteID, organizationID).First(&site).Error; err != nil {
+		return nil, fmt.Errorf("failed to get tagged site: %v", err)
+	}
+	resolution.Site = &site
+	if category == "" {
+		category = site.Type
+	}
+
+	openQuery := db.Where("organization_id = ? AND site_id = ? AND inspector_id = ? AND status NOT IN ?",
+		organizationID, siteID, userID, closedInspectionStatuses)
+	if resolution.Asset != nil {
+		openQuery = openQuery.Where("asset_id = ?", resolution.Asset.ID)
+	}
+	if err := openQuery.Preload("Template").Order("due_date ASC").Find(&resolution.OpenInspections).Error; err != nil {
+		return nil, fmt.Errorf("failed to get open inspections: %v", err)
+	}
+
+	templates, err := s.applicableTemplates(db, organizationID, siteID, resolution.Asset, category)
+	if err != nil {
+		return nil, err
+	}
+	resolution.Templates = templates
+
+	return resolution, nil
+}
+
+// applicableTemplates returns the active templates whose category matches the asset or
+// site type, or that were used for inspections there before. When nothing matches,
+// every active template is offered so the inspector is never stuck.
+func (s *ScanTagService) applicableTemplates(db *gorm.DB, organizationID, siteID string, asset *models.Asset, category string) ([]models.Template, error) {
+	used := db.Model(&models.Inspection{}).Select("template_id").Where("organization_id = ? AND site_id = ?", organizationID, siteID)
+	if asset != nil {
+		used = used.Where("asset_id = ?", asset.ID)
+	} else {
+		// Site tags offer what is inspected at the site itself, not its equipment
+		used = used.Where("asset_id IS NULL")
+	}
+
+	latest := db.Where("organization_id = ? AND is_active = ? AND is_latest_version = ?", organizationID, true, true)
+
+	var templates []models.Template
+	if err := latest.Session(&gorm.Session{}).
+		Where("LOWER(category) = ? OR id IN (?) OR parent_template_id IN (?)", strings.ToLower(category), used, used).
+		Order("name ASC").
+		Find(&templates).Error; err != nil {
+		return nil, fmt.Errorf("failed to get applicable templates: %v", err)
+	}
+	if len(templates) > 0 {
+		return templates, nil
+	}
+
+	if err := latest.Session(&gorm.Session{}).Order("name ASC").Find(&templates).Error; err != nil {
+		return nil, fmt.Errorf("failed to get templates: %v", err)
+	}
+	return templates, nil
+}
+
+// entityLabel checks that a tag target exists and returns the name printed under the code
+func (s *ScanTagService) entityLabel(db *gorm.DB, organizationID, entityType, entityID string) (string, error) {
+	var name string
+	var err error
+	switch entityType {
+	case models.ScanTagEntitySite:
+		var site models.Site
+		err = db.Select("id, name").Where("id = ? AND organization_id = ?", entityID, organizationID).First(&site).Error
+		name = site.Name
+	case models.ScanTagEntityAsset:
+		var asset models.Asset
+		err = db.Select("id, name, asset_tag, status").Where("id = ? AND organization_id = ?", entityID, organizationID).First(&asset).Error
+		if err == nil && asset.IsRetired() {
+			return "", ErrAssetRetired
+		}
+		name = asset.Name
+		if asset.AssetTag != "" {
+			name += " (" + asset.AssetTag + ")"
+		}
+	default:
+		return "", ErrInvalidScanTagEntity
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", fmt.Errorf("%w: %s %s not found", ErrInvalidScanTagEntity, entityType, entityID)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get %s: %v", entityType, err)
+	}
+	return name, nil
+}
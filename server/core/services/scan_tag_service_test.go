@@ -0,0 +1,235 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"resource-mgmt/models"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// scanTagTestFixture is a warehouse with an extinguisher that has open inspections for
+// two inspectors, a template per category, and a tag for the extinguisher plus a blank one
+type scanTagTestFixture struct {
+	db                     *gorm.DB
+	service                *ScanTagService
+	site                   *models.Site
+	extinguisher           *models.Asset
+	fireCheck, walkthrough *models.Template
+	open                   *models.Inspection
+	tags                   []models.ScanTag
+	assetTag, blankTag     models.ScanTag
+}
+
+func newScanTagTestFixture(t *testing.T) *scanTagTestFixture {
+	t.Setenv("JWT_SECRET", "test-secret-that-is-at-least-32-characters-long")
+	db := setupServiceTestDB(t, &models.Site{}, &models.Asset{}, &models.Template{}, &models.Inspection{}, &models.ScanTag{})
+	f := &scanTagTestFixture{db: db, service: NewScanTagService(db)}
+
+	f.site = &models.Site{ID: uuid.NewString(), OrganizationID: "org-a", Name: "Depot", Address: "1 Yard Rd", Type: "warehouse", Status: "active"}
+	require.NoError(t, db.Create(f.site).Error)
+	f.extinguisher = &models.Asset{ID: uuid.NewString(), OrganizationID: "org-a", SiteID: f.site.ID, Name: "Extinguisher", AssetTag: "FE-12", Type: "fire_extinguisher", Status: models.AssetStatusActive}
+	require.NoError(t, db.Create(f.extinguisher).Error)
+
+	f.fireCheck = &models.Template{ID: uuid.New(), OrganizationID: "org-a", Name: "Fire extinguisher check", Category: "fire_extinguisher", FieldsSchema: datatypes.JSON("[]"), IsActive: true, IsLatestVersion: true}
+	require.NoError(t, db.Create(f.fireCheck).Error)
+	f.walkthrough = &models.Template{ID: uuid.New(), OrganizationID: "org-a", Name: "Warehouse walkthrough", Category: "warehouse", FieldsSchema: datatypes.JSON("[]"), IsActive: true, IsLatestVersion: true}
+	require.NoError(t, db.Create(f.walkthrough).Error)
+
+	f.open = &models.Inspection{OrganizationID: "org-a", TemplateID: f.fireCheck.ID, InspectorID: "inspector-1", SiteID: f.site.ID, AssetID: &f.extinguisher.ID, Status: "assigned"}
+	require.NoError(t, db.Create(f.open).Error)
+	require.NoError(t, db.Create(&models.Inspection{OrganizationID: "org-a", TemplateID: f.fireCheck.ID, InspectorID: "inspector-2", SiteID: f.site.ID, AssetID: &f.extinguisher.ID, Status: "assigned"}).Error)
+
+	var err error
+	f.tags, err = f.service.IssueTags(context.Background(), "org-a", "admin-1", models.ScanTagEntityAsset, []string{f.extinguisher.ID}, 1)
+	require.NoError(t, err)
+	require.Len(t, f.tags, 2)
+	f.assetTag, f.blankTag = f.tags[0], f.tags[1]
+	return f
+}
+
+func (f *scanTagTestFixture) code(t *testing.T, tag *models.ScanTag) string {
+	code, err := f.service.TagCode(tag)
+	require.NoError(t, err)
+	return code
+}
+
+func TestScanTagService_IssueTags(t *testing.T) {
+	f := newScanTagTestFixture(t)
+
+	assert.Equal(t, "Extinguisher (FE-12)", f.assetTag.Label)
+	assert.Equal(t, models.ScanTagStatusBound, f.assetTag.Status)
+	assert.Equal(t, models.ScanTagStatusUnbound, f.blankTag.Status)
+
+	// Printing again reuses the asset's tag so old stickers keep working
+	again, err := f.service.IssueTags(context.Background(), "org-a", "admin-1", models.ScanTagEntityAsset, []string{f.extinguisher.ID}, 0)
+	require.NoError(t, err)
+	require.Len(t, again, 1)
+	assert.Equal(t, f.assetTag.ID, again[0].ID)
+}
+
+func TestScanTagService_IssueTagsValidation(t *testing.T) {
+	tests := []struct {
+		name       string
+		entityType string
+		entityIDs  func(f *scanTagTestFixture) []string
+		blankCount int
+		wantErr    error
+	}{
+		{"nothing to issue", models.ScanTagEntityAsset, func(f *scanTagTestFixture) []string { return nil }, 0, ErrInvalidScanTagRequest},
+		{"negative blank count", models.ScanTagEntityAsset, func(f *scanTagTestFixture) []string { return nil }, -1, ErrInvalidScanTagRequest},
+		{"too many tags", models.ScanTagEntityAsset, func(f *scanTagTestFixture) []string { return nil }, maxScanTagsPerRequest + 1, ErrInvalidScanTagRequest},
+		{"unknown entity type", "room", func(f *scanTagTestFixture) []string { return []string{f.site.ID} }, 0, ErrInvalidScanTagEntity},
+		{"missing entity", models.ScanTagEntityAsset, func(f *scanTagTestFixture) []string { return []string{uuid.NewString()} }, 0, ErrInvalidScanTagEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newScanTagTestFixture(t)
+			_, err := f.service.IssueTags(context.Background(), "org-a", "admin-1", tt.entityType, tt.entityIDs(f), tt.blankCount)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestScanTagService_ParseTagCode(t *testing.T) {
+	f := newScanTagTestFixture(t)
+	code := f.code(t, &f.assetTag)
+
+	tests := []struct {
+		name    string
+		code    string
+		wantErr error
+	}{
+		{"bare code", code, nil},
+		{"scan URL", "https://app.example.com/scan/" + code, nil},
+		{"tampered organization", strings.Replace(code, "org-a", "org-b", 1), ErrInvalidScanTag},
+		{"truncated signature", code[:len(code)-4], ErrInvalidScanTag},
+		{"garbage", "not-a-tag", ErrInvalidScanTag},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orgID, tagID, err := f.service.ParseTagCode(tt.code)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "org-a", orgID)
+			assert.Equal(t, f.assetTag.ID, tagID)
+		})
+	}
+}
+
+func TestScanTagService_ResolveBoundTag(t *testing.T) {
+	f := newScanTagTestFixture(t)
+
+	// The inspector gets the site, matching templates and only their own open inspection
+	resolution, err := f.service.Resolve(context.Background(), "org-a", "inspector-1", "inspector", f.code(t, &f.assetTag))
+	require.NoError(t, err)
+	assert.Equal(t, models.ScanTagStatusBound, resolution.Status)
+	require.NotNil(t, resolution.Site)
+	assert.Equal(t, f.site.ID, resolution.Site.ID)
+	require.NotNil(t, resolution.Asset)
+	require.Len(t, resolution.Templates, 1)
+	assert.Equal(t, f.fireCheck.ID, resolution.Templates[0].ID)
+	require.Len(t, resolution.OpenInspections, 1)
+	assert.Equal(t, f.open.ID, resolution.OpenInspections[0].ID)
+}
+
+func TestScanTagService_ResolveRejections(t *testing.T) {
+	tests := []struct {
+		name         string
+		organization string
+		revoke       bool
+		wantErr      error
+	}{
+		{"other organization", "org-b", false, ErrScanTagNotFound},
+		{"revoked tag", "org-a", true, ErrScanTagRevoked},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newScanTagTestFixture(t)
+			ctx := context.Background()
+			if tt.revoke {
+				_, err := f.service.RevokeTag(ctx, "org-a", f.assetTag.ID)
+				require.NoError(t, err)
+			}
+
+			_, err := f.service.Resolve(ctx, tt.organization, "inspector-1", "inspector", f.code(t, &f.assetTag))
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestScanTagService_BindBlankTag(t *testing.T) {
+	f := newScanTagTestFixture(t)
+	ctx := context.Background()
+	blankCode := f.code(t, &f.blankTag)
+
+	resolution, err := f.service.Resolve(ctx, "org-a", "supervisor-1", "supervisor", blankCode)
+	require.NoError(t, err)
+	assert.Equal(t, models.ScanTagStatusUnbound, resolution.Status)
+	assert.True(t, resolution.CanBind)
+
+	bound, err := f.service.BindTag(ctx, "org-a", f.blankTag.ID, models.ScanTagEntitySite, f.site.ID, "supervisor-1")
+	require.NoError(t, err)
+	assert.Equal(t, "Depot", bound.Label)
+
+	// Once bound to the site, scans offer the site's templates and no asset
+	resolution, err = f.service.Resolve(ctx, "org-a", "inspector-1", "inspector", blankCode)
+	require.NoError(t, err)
+	require.Len(t, resolution.Templates, 1)
+	assert.Equal(t, f.walkthrough.ID, resolution.Templates[0].ID)
+	assert.Nil(t, resolution.Asset)
+}
+
+func TestScanTagService_BindTagRejections(t *testing.T) {
+	tests := []struct {
+		name       string
+		tag        func(f *scanTagTestFixture) string
+		entityType string
+		wantErr    error
+	}{
+		{"unknown entity type", func(f *scanTagTestFixture) string { return f.blankTag.ID }, "room", ErrInvalidScanTagEntity},
+		{"already bound", func(f *scanTagTestFixture) string { return f.assetTag.ID }, models.ScanTagEntitySite, ErrScanTagAlreadyBound},
+		{"missing tag", func(f *scanTagTestFixture) string { return uuid.NewString() }, models.ScanTagEntitySite, ErrScanTagNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newScanTagTestFixture(t)
+			_, err := f.service.BindTag(context.Background(), "org-a", tt.tag(f), tt.entityType, f.site.ID, "supervisor-1")
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestScanTagService_RenderTagSheet(t *testing.T) {
+	tests := []struct {
+		format      string
+		contentType string
+		magic       string
+	}{
+		{"png", "image/png", "\x89PNG"},
+		{"pdf", "application/pdf", "%PDF"},
+		{"svg", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			f := newScanTagTestFixture(t)
+			data, contentType, _, err := f.service.RenderTagSheet(f.tags, tt.format)
+			if tt.contentType == "" {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.contentType, contentType)
+			assert.True(t, bytes.HasPrefix(data, []byte(tt.magic)))
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"resource-mgmt/models"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/skip2/go-qrcode"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Sheet layout. PNG sizes are in pixels, PDF sizes in millimetres on A4 portrait.
+const (
+	scanSheetColumns = 3
+
+	pngTagSize     = 300
+	pngLabelHeight = 44
+	pngCellPadding = 20
+
+	pdfRowsPerPage = 4
+	pdfCellWidth   = 63.0
+	pdfCellHeight  = 68.0
+	pdfQRSize      = 48.0
+	pdfMarginLeft  = 10.5
+	pdfMarginTop   = 12.0
+)
+
+// RenderTagSheet renders printable tags as a single PNG image or a paginated A4 PDF.
+// Each tag shows the QR code, its label and a short ID for manual lookup.
+func (s *ScanTagService) RenderTagSheet(tags []models.ScanTag, format string) ([]byte, string, string, error) {
+	if len(tags) == 0 {
+		return nil, "", "", fmt.Errorf("no tags to render")
+	}
+
+	timestamp := time.Now().Format("20060102_150405")
+	switch format {
+	case "png":
+		data, err := s.renderPNGSheet(tags)
+		return data, "image/png", fmt.Sprintf("scan_tags_%s.png", timestamp), err
+	case "pdf":
+		data, err := s.renderPDFSheet(tags)
+		return data, "application/pdf", fmt.Sprintf("scan_tags_%s.pdf", timestamp), err
+	default:
+		return nil, "", "", fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+func (s *ScanTagService) qrCode(tag *models.ScanTag) (*qrcode.QRCode, error) {
+	content, err := s.TagContent(tag)
+	if err != nil {
+		return nil, err
+	}
+	code, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode QR code: %v", err)
+	}
+	return code, nil
+}
+
+func (s *ScanTagService) renderPNGSheet(tags []models.ScanTag) ([]byte, error) {
+	columns := scanSheetColumns
+	if len(tags) < columns {
+		columns = len(tags)
+	}
+	rows := (len(tags) + columns - 1) / columns
+	cellWidth := pngTagSize + 2*pngCellPadding
+	cellHeight := pngTagSize + pngLabelHeight + 2*pngCellPadding
+
+	sheet := image.NewRGBA(image.Rect(0, 0, columns*cellWidth, rows*cellHeight))
+	draw.Draw(sheet, sheet.Bounds(), image.White, image.Point{}, draw.Src)
+
+	drawer := &font.Drawer{Dst: sheet, Src: image.NewUniform(color.Black), Face: basicfont.Face7x13}
+	maxChars := pngTagSize / 7
+
+	for i := range tags {
+		code, err := s.qrCode(&tags[i])
+		if err != nil {
+			return nil, err
+		}
+
+		x := (i%columns)*cellWidth + pngCellPadding
+		y := (i/columns)*cellHeight + pngCellPadding
+		draw.Draw(sheet, image.Rect(x, y, x+pngTagSize, y+pngTagSize), code.Image(pngTagSize), image.Point{}, draw.Src)
+
+		for line, text := range []string{truncateLabel(tags[i].Label, maxChars), shortTagID(tags[i].ID)} {
+			width := drawer.MeasureString(text).Round()
+			drawer.Dot = fixed.P(x+(pngTagSize-width)/2, y+pngTagSize+16+line*18)
+			drawer.DrawString(text)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, sheet); err != nil {
+		return nil, fmt.Errorf("failed to encode PNG sheet: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *ScanTagService) renderPDFSheet(tags []models.ScanTag) ([]byte, error) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetAutoPageBreak(false, 0)
+	translate := pdf.UnicodeTranslatorFromDescriptor("")
+
+	perPage := scanSheetColumns * pdfRowsPerPage
+	for i := range tags {
+		if i%perPage == 0 {
+			pdf.AddPage()
+		}
+
+		code, err := s.qrCode(&tags[i])
+		if err != nil {
+			return nil, err
+		}
+		pngData, err := code.PNG(512)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode QR code: %v", err)
+		}
+
+		name := "tag-" + tags[i].ID
+		options := gofpdf.ImageOptions{ImageType: "PNG"}
+		pdf.RegisterImageOptionsReader(name, options, bytes.NewReader(pngData))
+
+		slot := i % perPage
+		x := pdfMarginLeft + float64(slot%scanSheetColumns)*pdfCellWidth
+		y := pdfMarginTop + float64(slot/scanSheetColumns)*pdfCellHeight
+
+		// Light cut guide around each tag
+		pdf.SetDrawColor(200, 200, 200)
+		pdf.Rect(x, y, pdfCellWidth, pdfCellHeight, "D")
+
+		pdf.ImageOptions(name, x+(pdfCellWidth-pdfQRSize)/2, y+3, pdfQRSize, pdfQRSize, false, options, 0, "")
+
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.SetXY(x+2, y+pdfQRSize+4)
+		pdf.CellFormat(pdfCellWidth-4, 5, translate(truncateLabel(tags[i].Label, 38)), "", 2, "C", false, 0, "")
+		pdf.SetFont("Courier", "", 8)
+		pdf.SetX(x + 2)
+		pdf.CellFormat(pdfCellWidth-4, 4, shortTagID(tags[i].ID), "", 0, "C", false, 0, "")
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to render PDF sheet: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// shortTagID is printed under the code so a tag can be looked up by hand if it won't scan
+func shortTagID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+func truncateLabel(label string, maxChars int) string {
+	runes := []rune(label)
+	if len(runes) <= maxChars {
+		return label
+	}
+	return string(runes[:maxChars-3]) + "..."
+}
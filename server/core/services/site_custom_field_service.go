@@ -0,0 +1,636 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"resource-mgmt/models"
+	"resource-mgmt/pkg/database"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidCustomField is returned when a custom field definition is malformed
+	ErrInvalidCustomField = errors.New("invalid custom field")
+	// ErrCustomFieldNotFound is returned when a field doesn't exist in the caller's organization
+	ErrCustomFieldNotFound = errors.New("custom field not found")
+	// ErrCustomFieldExists is returned when a field key is already defined in the organization
+	ErrCustomFieldExists = errors.New("custom field already exists")
+	// ErrInvalidSiteMetadata is returned when a site's metadata doesn't satisfy its organization's schema
+	ErrInvalidSiteMetadata = errors.New("invalid site custom fields")
+)
+
+var customFieldKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+var customFieldTypes = []string{
+	models.CustomFieldText, models.CustomFieldNumber, models.CustomFieldBoolean, models.CustomFieldDate,
+	models.CustomFieldSelect, models.CustomFieldMultiSelect, models.CustomFieldEmail, models.CustomFieldURL,
+}
+
+// customFieldColumnPrefix marks custom fields in site filters and import/export columns
+const customFieldColumnPrefix = "custom."
+
+// SiteCustomFieldService manages organization-defined schemas for Site.Metadata
+type SiteCustomFieldService struct {
+	db *gorm.DB
+}
+
+func NewSiteCustomFieldService(db *gorm.DB) *SiteCustomFieldService {
+	return &SiteCustomFieldService{db: db}
+}
+
+// =====================================================
+// SCHEMA
+// =====================================================
+
+// GetFields returns the organization's custom fields in display order
+func (s *SiteCustomFieldService) GetFields(ctx context.Context, organizationID string) ([]models.SiteCustomField, error) {
+	var fields []models.SiteCustomField
+	if err := database.Conn(ctx, s.db).
+		Where("organization_id = ?", organizationID).
+		Order("position ASC, key ASC").
+		Find(&fields).Error; err != nil {
+		return nil, fmt.Errorf("failed to get custom fields: %v", err)
+	}
+	return fields, nil
+}
+
+// GetField returns one custom field by key
+func (s *SiteCustomFieldService) GetField(ctx context.Context, organizationID, key string) (*models.SiteCustomField, error) {
+	var field models.SiteCustomField
+	if err := database.Conn(ctx, s.db).
+		Where("organization_id = ? AND key = ?", organizationID, key).
+		First(&field).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCustomFieldNotFound
+		}
+		return nil, fmt.Errorf("failed to get custom field: %v", err)
+	}
+	return &field, nil
+}
+
+// CreateField adds a field to the schema and flags existing sites that don't satisfy it,
+// such as sites missing a new required field
+func (s *SiteCustomFieldService) CreateField(ctx context.Context, field *models.SiteCustomField) (*models.SiteCustomField, *models.SiteCustomFieldMigration, error) {
+	if err := validateCustomFieldDefinition(field); err != nil {
+		return nil, nil, err
+	}
+
+	var migration *models.SiteCustomFieldMigration
+	err := database.Conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.SiteCustomField{}).
+			Where("organization_id = ? AND key = ?", field.OrganizationID, field.Key).
+			Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to check custom field key: %v", err)
+		}
+		if count > 0 {
+			return fmt.Errorf("%w: %s", ErrCustomFieldExists, field.Key)
+		}
+		if err := tx.Create(field).Error; err != nil {
+			return fmt.Errorf("failed to create custom field: %v", err)
+		}
+
+		var err error
+		migration, err = reconcileCustomField(tx, field, nil)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return field, migration, nil
+}
+
+// UpdateField changes a field definition and brings existing sites along: renamed options
+// are rewritten, values that can be converted to a new type are migrated, and sites that
+// still don't conform are flagged as violations.
+func (s *SiteCustomFieldService) UpdateField(ctx context.Context, organizationID, userID, key string, req *models.UpdateSiteCustomFieldRequest) (*models.SiteCustomField, *models.SiteCustomFieldMigration, error) {
+	field, err := s.GetField(ctx, organizationID, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if req.Label != nil {
+		field.Label = *req.Label
+	}
+	if req.Type != nil {
+		field.Type = *req.Type
+	}
+	if req.Description != nil {
+		field.Description = *req.Description
+	}
+	if req.Required != nil {
+		field.Required = *req.Required
+	}
+	if req.Unique != nil {
+		field.Unique = *req.Unique
+	}
+	if req.Position != nil {
+		field.Position = *req.Position
+	}
+	options := field.OptionList()
+	if req.Options != nil {
+		options = req.Options
+	} else if len(req.OptionRenames) > 0 {
+		// Renaming without a new option list renames the options in place
+		for i, option := range options {
+			if renamed, ok := req.OptionRenames[option]; ok {
+				options[i] = renamed
+			}
+		}
+	}
+	encoded, _ := json.Marshal(options)
+	field.Options = datatypes.JSON(encoded)
+	field.UpdatedBy = userID
+
+	if err := validateCustomFieldDefinition(field); err != nil {
+		return nil, nil, err
+	}
+
+	var migration *models.SiteCustomFieldMigration
+	err = database.Conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(field).Error; err != nil {
+			return fmt.Errorf("failed to update custom field: %v", err)
+		}
+		var err error
+		migration, err = reconcileCustomField(tx, field, req.OptionRenames)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return field, migration, nil
+}
+
+// DeleteField removes a field from the schema. Values already stored in site metadata are
+// kept but no longer validated.
+func (s *SiteCustomFieldService) DeleteField(ctx context.Context, organizationID, key string) error {
+	field, err := s.GetField(ctx, organizationID, key)
+	if err != nil {
+		return err
+	}
+
+	return database.Conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("organization_id = ? AND field_key = ?", organizationID, key).Delete(&models.SiteFieldViolation{}).Error; err != nil {
+			return fmt.Errorf("failed to clear custom field violations: %v", err)
+		}
+		if err := tx.Delete(field).Error; err != nil {
+			return fmt.Errorf("failed to delete custom field: %v", err)
+		}
+		return nil
+	})
+}
+
+// GetViolations lists sites flagged as not conforming to the schema. Filters: field_key, site_id.
+func (s *SiteCustomFieldService) GetViolations(ctx context.Context, organizationID string, filters map[string]interface{}) ([]models.SiteFieldViolation, error) {
+	query := database.Conn(ctx, s.db).Where("organization_id = ?", organizationID)
+	for _, key := range []string{"field_key", "site_id"} {
+		if value, ok := filters[key].(string); ok && value != "" {
+			query = query.Where(fmt.Sprintf("%s = ?", key), value)
+		}
+	}
+
+	var violations []models.SiteFieldViolation
+	if err := query.
+		Preload("Site", func(db *gorm.DB) *gorm.DB { return db.Select("id, name, external_ref") }).
+		Order("site_id ASC, field_key ASC").
+		Find(&violations).Error; err != nil {
+		return nil, fmt.Errorf("failed to get custom field violations: %v", err)
+	}
+	return violations, nil
+}
+
+func validateCustomFieldDefinition(field *models.SiteCustomField) error {
+	if !customFieldKeyPattern.MatchString(field.Key) {
+		return fmt.Errorf("%w: key must start with a letter and contain only lowercase letters, digits and underscores", ErrInvalidCustomField)
+	}
+	if strings.TrimSpace(field.Label) == "" {
+		return fmt.Errorf("%w: label is required", ErrInvalidCustomField)
+	}
+	if !containsString(customFieldTypes, field.Type) {
+		return fmt.Errorf("%w: type must be one of %s", ErrInvalidCustomField, strings.Join(customFieldTypes, ", "))
+	}
+
+	options := field.OptionList()
+	if field.Type == models.CustomFieldSelect || field.Type == models.CustomFieldMultiSelect {
+		if len(options) == 0 {
+			return fmt.Errorf("%w: %s fields need options", ErrInvalidCustomField, field.Type)
+		}
+		seen := make(map[string]bool, len(options))
+		for _, option := range options {
+			if option == "" || seen[option] {
+				return fmt.Errorf("%w: options must be unique and non-empty", ErrInvalidCustomField)
+			}
+			seen[option] = true
+		}
+	} else {
+		field.Options = nil
+	}
+
+	if field.Unique && (field.Type == models.CustomFieldBoolean || field.Type == models.CustomFieldMultiSelect) {
+		return fmt.Errorf("%w: %s fields can't be unique", ErrInvalidCustomField, field.Type)
+	}
+	return nil
+}
+
+// reconcileCustomField re-checks every site in the field's organization against it,
+// migrating values where a lossless conversion exists and recording violations for the rest
+func reconcileCustomField(tx *gorm.DB, field *models.SiteCustomField, optionRenames map[string]string) (*models.SiteCustomFieldMigration, error) {
+	var sites []models.Site
+	if err := tx.Select("id, metadata").Where("organization_id = ?", field.OrganizationID).Find(&sites).Error; err != nil {
+		return nil, fmt.Errorf("failed to load sites: %v", err)
+	}
+	if err := tx.Where("organization_id = ? AND field_key = ?", field.OrganizationID, field.Key).Delete(&models.SiteFieldViolation{}).Error; err != nil {
+		return nil, fmt.Errorf("failed to clear custom field violations: %v", err)
+	}
+
+	migration := &models.SiteCustomFieldMigration{FieldKey: field.Key, SitesChecked: len(sites)}
+	now := time.Now()
+	var violations []models.SiteFieldViolation
+	flag := func(siteID, message string) {
+		violations = append(violations, models.SiteFieldViolation{
+			OrganizationID: field.OrganizationID, SiteID: siteID, FieldKey: field.Key, Message: message, DetectedAt: now,
+		})
+	}
+	byValue := make(map[string][]string)
+
+	for _, site := range sites {
+		metadata := decodeSiteMetadata(site.Metadata)
+		original, present := metadata[field.Key]
+		value := renameCustomFieldOptions(original, optionRenames)
+
+		normalized, message := checkCustomFieldValue(field, value)
+		if message != "" {
+			if coerced, ok := coerceCustomFieldValue(field, value); ok {
+				normalized, message = checkCustomFieldValue(field, coerced)
+				if message == "" {
+					value = coerced
+				}
+			}
+		}
+		if message == "" {
+			value = normalized
+		}
+
+		if present && !reflect.DeepEqual(original, value) {
+			if value == nil {
+				delete(metadata, field.Key)
+			} else {
+				metadata[field.Key] = value
+			}
+			encoded, _ := json.Marshal(metadata)
+			if err := tx.Model(&models.Site{}).Where("id = ?", site.ID).Update("metadata", datatypes.JSON(encoded)).Error; err != nil {
+				return nil, fmt.Errorf("failed to migrate site %s: %v", site.ID, err)
+			}
+			migration.Migrated++
+		}
+
+		if message != "" {
+			flag(site.ID, message)
+		} else if field.Unique && value != nil {
+			text := customFieldText(value)
+			byValue[text] = append(byValue[text], site.ID)
+		}
+	}
+
+	values := make([]string, 0, len(byValue))
+	for text := range byValue {
+		values = append(values, text)
+	}
+	sort.Strings(values)
+	for _, text := range values {
+		if siteIDs := byValue[text]; len(siteIDs) > 1 {
+			for _, siteID := range siteIDs {
+				flag(siteID, fmt.Sprintf("value %q is shared by %d sites", text, len(siteIDs)))
+			}
+		}
+	}
+
+	if len(violations) > 0 {
+		if err := tx.Create(&violations).Error; err != nil {
+			return nil, fmt.Errorf("failed to record custom field violations: %v", err)
+		}
+	}
+	migration.Flagged = len(violations)
+	return migration, nil
+}
+
+// =====================================================
+// VALIDATION
+// =====================================================
+
+// ValidateMetadata checks a site's metadata against the organization's schema and returns
+// it with values normalized. siteID is the site being saved, excluded from uniqueness
+// checks; pass "" for a new site. Keys outside the schema are kept as they are.
+func (s *SiteCustomFieldService) ValidateMetadata(ctx context.Context, organizationID, siteID string, metadata datatypes.JSON) (datatypes.JSON, error) {
+	fields, err := s.GetFields(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return metadata, nil
+	}
+
+	values := map[string]interface{}{}
+	if len(metadata) > 0 && string(metadata) != "null" {
+		if err := json.Unmarshal(metadata, &values); err != nil {
+			return nil, fmt.Errorf("%w: metadata must be a JSON object", ErrInvalidSiteMetadata)
+		}
+	}
+
+	var problems []string
+	for i := range fields {
+		field := &fields[i]
+		normalized, message := checkCustomFieldValue(field, values[field.Key])
+		if message != "" {
+			problems = append(problems, field.Key+": "+message)
+			continue
+		}
+		if normalized == nil {
+			delete(values, field.Key)
+			continue
+		}
+		values[field.Key] = normalized
+
+		if field.Unique {
+			taken, err := s.valueTaken(ctx, organizationID, siteID, field.Key, customFieldText(normalized))
+			if err != nil {
+				return nil, err
+			}
+			if taken {
+				problems = append(problems, fmt.Sprintf("%s: %q is already used by another site", field.Key, customFieldText(normalized)))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSiteMetadata, strings.Join(problems, "; "))
+	}
+
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode metadata: %v", err)
+	}
+	return datatypes.JSON(encoded), nil
+}
+
+// valueTaken reports whether another site in the organization already has value for key
+func (s *SiteCustomFieldService) valueTaken(ctx context.Context, organizationID, siteID, key, value string) (bool, error) {
+	query := database.Conn(ctx, s.db).Model(&models.Site{}).
+		Where("organization_id = ? AND metadata->>? = ?", organizationID, key, value)
+	if siteID != "" {
+		query = query.Where("id <> ?", siteID)
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check custom field uniqueness: %v", err)
+	}
+	return count > 0, nil
+}
+
+// ClearViolations removes a site's violations once it has been saved with valid metadata
+func (s *SiteCustomFieldService) ClearViolations(ctx context.Context, organizationID, siteID string) error {
+	if err := database.Conn(ctx, s.db).
+		Where("organization_id = ? AND site_id = ?", organizationID, siteID).
+		Delete(&models.SiteFieldViolation{}).Error; err != nil {
+		return fmt.Errorf("failed to clear custom field violations: %v", err)
+	}
+	return nil
+}
+
+// checkCustomFieldValue validates one value as decoded from JSON, returning it normalized
+// (nil when empty) or a message describing the problem
+func checkCustomFieldValue(field *models.SiteCustomField, value interface{}) (interface{}, string) {
+	if isEmptyCustomFieldValue(value) {
+		if field.Required {
+			return nil, "is required"
+		}
+		return nil, ""
+	}
+
+	switch field.Type {
+	case models.CustomFieldText:
+		if text, ok := value.(string); ok {
+			return text, ""
+		}
+		return nil, "must be text"
+	case models.CustomFieldNumber:
+		switch number := value.(type) {
+		case float64:
+			return number, ""
+		case int:
+			return float64(number), ""
+		}
+		return nil, "must be a number"
+	case models.CustomFieldBoolean:
+		if flag, ok := value.(bool); ok {
+			return flag, ""
+		}
+		return nil, "must be true or false"
+	case models.CustomFieldDate:
+		if text, ok := value.(string); ok {
+			if _, err := time.Parse("2006-01-02", text); err == nil {
+				return text, ""
+			}
+		}
+		return nil, "must be a date (YYYY-MM-DD)"
+	case models.CustomFieldSelect:
+		if text, ok := value.(string); ok && containsString(field.OptionList(), text) {
+			return text, ""
+		}
+		return nil, "must be one of " + strings.Join(field.OptionList(), ", ")
+	case models.CustomFieldMultiSelect:
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, "must be a list of options"
+		}
+		options := field.OptionList()
+		selected := make([]interface{}, 0, len(items))
+		seen := make(map[string]bool)
+		for _, item := range items {
+			text, ok := item.(string)
+			if !ok || !containsString(options, text) {
+				return nil, "must only contain " + strings.Join(options, ", ")
+			}
+			if !seen[text] {
+				seen[text] = true
+				selected = append(selected, text)
+			}
+		}
+		return selected, ""
+	case models.CustomFieldEmail:
+		if text, ok := value.(string); ok {
+			if address, err := mail.ParseAddress(text); err == nil && address.Address == text {
+				return text, ""
+			}
+		}
+		return nil, "must be an email address"
+	case models.CustomFieldURL:
+		if text, ok := value.(string); ok {
+			if parsed, err := url.ParseRequestURI(text); err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != "" {
+				return text, ""
+			}
+		}
+		return nil, "must be an http or https URL"
+	}
+	return nil, "has an unknown type"
+}
+
+// coerceCustomFieldValue converts a value of the wrong shape, such as a number stored as
+// text or a CSV cell, into the field's type when that can be done without guessing
+func coerceCustomFieldValue(field *models.SiteCustomField, value interface{}) (interface{}, bool) {
+	text, isText := value.(string)
+	text = strings.TrimSpace(text)
+
+	switch field.Type {
+	case models.CustomFieldText, models.CustomFieldEmail, models.CustomFieldURL, models.CustomFieldSelect:
+		switch v := value.(type) {
+		case string:
+			return text, true
+		case float64, bool:
+			return customFieldText(v), true
+		case []interface{}:
+			if len(v) == 1 {
+				return v[0], true
+			}
+		}
+	case models.CustomFieldNumber:
+		if isText {
+			if number, err := strconv.ParseFloat(text, 64); err == nil {
+				return number, true
+			}
+		}
+	case models.CustomFieldBoolean:
+		if isText {
+			switch strings.ToLower(text) {
+			case "true", "yes", "y", "1":
+				return true, true
+			case "false", "no", "n", "0":
+				return false, true
+			}
+		}
+	case models.CustomFieldDate:
+		if isText {
+			if parsed, err := time.Parse(time.RFC3339, text); err == nil {
+				return parsed.Format("2006-01-02"), true
+			}
+		}
+	case models.CustomFieldMultiSelect:
+		if isText {
+			var items []interface{}
+			for _, item := range strings.Split(text, ";") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+			return items, true
+		}
+	}
+	return nil, false
+}
+
+// renameCustomFieldOptions applies option renames to a select or multi-select value
+func renameCustomFieldOptions(value interface{}, renames map[string]string) interface{} {
+	if len(renames) == 0 {
+		return value
+	}
+	switch v := value.(type) {
+	case string:
+		if renamed, ok := renames[v]; ok {
+			return renamed
+		}
+	case []interface{}:
+		renamed := make([]interface{}, len(v))
+		for i, item := range v {
+			renamed[i] = renameCustomFieldOptions(item, renames)
+		}
+		return renamed
+	}
+	return value
+}
+
+// customFieldText renders a value the way Postgres' ->> operator does, for uniqueness
+// checks and filters, and for export cells
+func customFieldText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = customFieldText(item)
+		}
+		return strings.Join(items, "; ")
+	}
+	return fmt.Sprint(value)
+}
+
+func isEmptyCustomFieldValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(v) == ""
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// decodeSiteMetadata returns a site's metadata as a map, empty when unset or not an object
+func decodeSiteMetadata(metadata datatypes.JSON) map[string]interface{} {
+	values := map[string]interface{}{}
+	if len(metadata) > 0 {
+		_ = json.Unmarshal(metadata, &values)
+	}
+	if values == nil {
+		values = map[string]interface{}{}
+	}
+	return values
+}
+
+// =====================================================
+// FILTERING
+// =====================================================
+
+// applyCustomFieldFilters narrows a site query to custom field values. filters maps field
+// keys to the value as given in a query string.
+func applyCustomFieldFilters(query *gorm.DB, fields []models.SiteCustomField, filters map[string]string) (*gorm.DB, error) {
+	byKey := make(map[string]*models.SiteCustomField, len(fields))
+	for i := range fields {
+		byKey[fields[i].Key] = &fields[i]
+	}
+
+	for key, raw := range filters {
+		field, ok := byKey[key]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown custom field %q", ErrInvalidSiteMetadata, key)
+		}
+		if field.Type == models.CustomFieldMultiSelect {
+			query = query.Where("metadata->>? LIKE ?", key, "%"+strconv.Quote(raw)+"%")
+			continue
+		}
+		value := interface{}(raw)
+		if coerced, ok := coerceCustomFieldValue(field, raw); ok {
+			value = coerced
+		}
+		query = query.Where("metadata->>? = ?", key, customFieldText(value))
+	}
+	return query, nil
+}
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"resource-mgmt/models"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// siteCustomFieldTestFixture is a legacy site saved before any schema existed, with a text
+// "3" for floors and a Gold tier
+type siteCustomFieldTestFixture struct {
+	db      *gorm.DB
+	service *SiteCustomFieldService
+	sites   *SiteService
+	legacy  *models.Site
+}
+
+func newSiteCustomFieldTestFixture(t *testing.T) *siteCustomFieldTestFixture {
+	db := setupServiceTestDB(t, &models.Site{}, &models.SiteCustomField{}, &models.SiteFieldViolation{})
+	f := &siteCustomFieldTestFixture{db: db, service: NewSiteCustomFieldService(db), sites: NewSiteService(db)}
+
+	f.legacy = &models.Site{ID: uuid.NewString(), OrganizationID: "org-a", Name: "Legacy", Address: "1 Old Rd", Status: "active", Metadata: datatypes.JSON(`{"floors":"3","tier":"Gold"}`)}
+	require.NoError(t, db.Create(f.legacy).Error)
+	return f
+}
+
+// defineSchema adds a required unique site code, a number of floors and a Gold/Silver tier
+func (f *siteCustomFieldTestFixture) defineSchema(t *testing.T) {
+	for _, field := range []*models.SiteCustomField{
+		{OrganizationID: "org-a", Key: "site_code", Label: "Site code", Type: models.CustomFieldText, Required: true, Unique: true},
+		{OrganizationID: "org-a", Key: "floors", Label: "Floors", Type: models.CustomFieldNumber},
+		{OrganizationID: "org-a", Key: "tier", Label: "Tier", Type: models.CustomFieldSelect, Options: datatypes.JSON(`["Gold","Silver"]`)},
+	} {
+		_, _, err := f.service.CreateField(context.Background(), field)
+		require.NoError(t, err)
+	}
+}
+
+// createSite creates a conforming site with the given code and tier
+func (f *siteCustomFieldTestFixture) createSite(t *testing.T, code, tier string) *models.Site {
+	site, err := f.sites.CreateSite(context.Background(), &models.Site{ID: uuid.NewString(), OrganizationID: "org-a", Name: code, Address: "2 High St",
+		Metadata: datatypes.JSON(`{"site_code":"` + code + `","floors":4,"tier":"` + tier + `","legacy_note":"kept"}`)})
+	require.NoError(t, err)
+	return site
+}
+
+func (f *siteCustomFieldTestFixture) storedMetadata(t *testing.T, siteID string) map[string]interface{} {
+	var stored models.Site
+	require.NoError(t, f.db.First(&stored, "id = ?", siteID).Error)
+	metadata := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(stored.Metadata, &metadata))
+	return metadata
+}
+
+func TestSiteCustomFieldService_CreateFieldValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		field   models.SiteCustomField
+		wantErr error
+	}{
+		{"key not snake case", models.SiteCustomField{Key: "Site Code", Label: "Code", Type: models.CustomFieldText}, ErrInvalidCustomField},
+		{"select without options", models.SiteCustomField{Key: "grade", Label: "Grade", Type: models.CustomFieldSelect}, ErrInvalidCustomField},
+		{"duplicate key", models.SiteCustomField{Key: "site_code", Label: "Again", Type: models.CustomFieldText}, ErrCustomFieldExists},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSiteCustomFieldTestFixture(t)
+			f.defineSchema(t)
+			field := tt.field
+			field.OrganizationID = "org-a"
+
+			_, _, err := f.service.CreateField(context.Background(), &field)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestSiteCustomFieldService_NewFieldsMigrateExistingSites(t *testing.T) {
+	tests := []struct {
+		name         string
+		field        models.SiteCustomField
+		wantMigrated int
+		wantFlagged  int
+	}{
+		{"required field flags sites without it", models.SiteCustomField{Key: "site_code", Label: "Site code", Type: models.CustomFieldText, Required: true}, 0, 1},
+		{"number field converts text losslessly", models.SiteCustomField{Key: "floors", Label: "Floors", Type: models.CustomFieldNumber}, 1, 0},
+		{"optional field leaves sites alone", models.SiteCustomField{Key: "manager", Label: "Manager", Type: models.CustomFieldText}, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSiteCustomFieldTestFixture(t)
+			field := tt.field
+			field.OrganizationID = "org-a"
+
+			_, migration, err := f.service.CreateField(context.Background(), &field)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantMigrated, migration.Migrated)
+			assert.Equal(t, tt.wantFlagged, migration.Flagged)
+		})
+	}
+}
+
+func TestSiteCustomFieldService_SiteMetadataValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		save    func(f *siteCustomFieldTestFixture, north *models.Site) error
+		wantErr error
+	}{
+		{"wrong type on create", func(f *siteCustomFieldTestFixture, _ *models.Site) error {
+			_, err := f.sites.CreateSite(context.Background(), &models.Site{ID: uuid.NewString(), OrganizationID: "org-a", Name: "South", Address: "3 High St", Metadata: datatypes.JSON(`{"floors":"many"}`)})
+			return err
+		}, ErrInvalidSiteMetadata},
+		{"duplicate unique value", func(f *siteCustomFieldTestFixture, _ *models.Site) error {
+			_, err := f.sites.CreateSite(context.Background(), &models.Site{ID: uuid.NewString(), OrganizationID: "org-a", Name: "South", Address: "3 High St", Metadata: datatypes.JSON(`{"site_code":"NY-001"}`)})
+			return err
+		}, ErrInvalidSiteMetadata},
+		{"unknown option on update", func(f *siteCustomFieldTestFixture, north *models.Site) error {
+			_, err := f.sites.UpdateSite(context.Background(), north.ID, "org-a", map[string]interface{}{"metadata": map[string]interface{}{"site_code": "NY-001", "tier": "Bronze"}})
+			return err
+		}, ErrInvalidSiteMetadata},
+		{"site keeps its own unique value", func(f *siteCustomFieldTestFixture, north *models.Site) error {
+			_, err := f.sites.UpdateSite(context.Background(), north.ID, "org-a", map[string]interface{}{"metadata": map[string]interface{}{"site_code": "NY-001", "floors": 5, "tier": "Silver"}})
+			return err
+		}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSiteCustomFieldTestFixture(t)
+			f.defineSchema(t)
+			north := f.createSite(t, "NY-001", "Gold")
+
+			err := tt.save(f, north)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSiteCustomFieldService_FilterSitesByCustomField(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters map[string]interface{}
+		wantErr error
+	}{
+		{"known field", map[string]interface{}{"organization_id": "org-a", "custom.tier": "Silver"}, nil},
+		{"unknown field", map[string]interface{}{"organization_id": "org-a", "custom.unknown": "x"}, ErrInvalidSiteMetadata},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSiteCustomFieldTestFixture(t)
+			f.defineSchema(t)
+			north := f.createSite(t, "NY-001", "Silver")
+
+			found, total, err := f.sites.GetSites(context.Background(), tt.filters, "", 1, 20)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, int64(1), total)
+			assert.Equal(t, north.ID, found[0].ID)
+		})
+	}
+}
+
+func TestSiteCustomFieldService_UpdateFieldMigratesStoredValues(t *testing.T) {
+	f := newSiteCustomFieldTestFixture(t)
+	ctx := context.Background()
+	f.defineSchema(t)
+
+	_, migration, err := f.service.UpdateField(ctx, "org-a", "admin-1", "tier", &models.UpdateSiteCustomFieldRequest{OptionRenames: map[string]string{"Gold": "Platinum"}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, migration.Migrated)
+	_, migration, err = f.service.UpdateField(ctx, "org-a", "admin-1", "floors", &models.UpdateSiteCustomFieldRequest{Required: boolPtr(true)})
+	require.NoError(t, err)
+	assert.Equal(t, 0, migration.Flagged, "every site already has floors")
+
+	metadata := f.storedMetadata(t, f.legacy.ID)
+	assert.Equal(t, "Platinum", metadata["tier"])
+	assert.Equal(t, 3.0, metadata["floors"])
+}
+
+func TestSiteCustomFieldService_ViolationsFlaggedAndCleared(t *testing.T) {
+	f := newSiteCustomFieldTestFixture(t)
+	ctx := context.Background()
+	f.defineSchema(t)
+
+	// Narrowing the options flags values that no longer fit
+	_, migration, err := f.service.UpdateField(ctx, "org-a", "admin-1", "tier", &models.UpdateSiteCustomFieldRequest{Options: []string{"Silver"}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, migration.Flagged)
+
+	violations, err := f.service.GetViolations(ctx, "org-a", map[string]interface{}{"site_id": f.legacy.ID})
+	require.NoError(t, err)
+	require.Len(t, violations, 2)
+	assert.Equal(t, "site_code", violations[0].FieldKey)
+	assert.Equal(t, "tier", violations[1].FieldKey)
+	require.NotNil(t, violations[0].Site)
+	assert.Equal(t, "Legacy", violations[0].Site.Name)
+
+	// Fixing the site clears its flags
+	_, err = f.sites.UpdateSite(ctx, f.legacy.ID, "org-a", map[string]interface{}{"metadata": map[string]interface{}{"site_code": "LG-001", "floors": 3, "tier": "Silver"}})
+	require.NoError(t, err)
+	violations, err = f.service.GetViolations(ctx, "org-a", map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+}
+
+func TestSiteCustomFieldService_DeleteField(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		wantErr error
+	}{
+		{"defined field", "tier", nil},
+		{"unknown field", "unknown", ErrCustomFieldNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSiteCustomFieldTestFixture(t)
+			ctx := context.Background()
+			f.defineSchema(t)
+
+			err := f.service.DeleteField(ctx, "org-a", tt.key)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			_, err = f.service.GetField(ctx, "org-a", tt.key)
+			assert.ErrorIs(t, err, ErrCustomFieldNotFound)
+		})
+	}
+}
+
+func boolPtr(value bool) *bool {
+	return &value
+}
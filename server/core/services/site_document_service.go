@@ -0,0 +1,653 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"path/filepath"
+	"resource-mgmt/config"
+	"resource-mgmt/models"
+	"resource-mgmt/pkg/database"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrSiteDocumentNotFound is returned when a document or version doesn't exist in the
+	// caller's organization, or the caller's role may not view it
+	ErrSiteDocumentNotFound = errors.New("site document not found")
+	// ErrInvalidSiteDocument is returned when a document's details fail validation
+	ErrInvalidSiteDocument = errors.New("invalid site document")
+	// ErrDocumentSiteNotFound is returned when documents are added to a site outside the organization
+	ErrDocumentSiteNotFound = errors.New("site not found")
+)
+
+const (
+	// maxSiteDocumentReminderDays bounds how far ahead of expiry a reminder can be sent
+	maxSiteDocumentReminderDays = 365
+	// siteDocumentDownloadExpiry is how long a presigned download link stays valid
+	siteDocumentDownloadExpiry = 15 * time.Minute
+	// siteDocumentAlertType is the WorkflowAlert type raised for expiring documents
+	siteDocumentAlertType = "document_expiring"
+	// siteDocumentAlertTarget is the WorkflowAlert target type for site documents
+	siteDocumentAlertTarget = "site_document"
+)
+
+// siteDocumentCategories are the accepted SiteDocument categories
+var siteDocumentCategories = []string{
+	models.SiteDocumentPermit,
+	models.SiteDocumentInsurance,
+	models.SiteDocumentCertificate,
+	models.SiteDocumentFloorPlan,
+	models.SiteDocumentOther,
+}
+
+// siteManagerRoles are the roles reminded about expiring documents; they are the roles
+// granted can_manage_sites
+var siteManagerRoles = []string{"admin", "supervisor"}
+
+type SiteDocumentService struct {
+	db                  *gorm.DB
+	storage             *StorageService
+	notificationService *NotificationService
+}
+
+func NewSiteDocumentService(db *gorm.DB, storage *StorageService) *SiteDocumentService {
+	return &SiteDocumentService{
+		db:                  db,
+		storage:             storage,
+		notificationService: NewNotificationService(),
+	}
+}
+
+// defaultSiteDocumentReminderDays returns config.SiteDocumentReminderDays as a number
+func defaultSiteDocumentReminderDays() int {
+	days, err := strconv.Atoi(config.SiteDocumentReminderDays)
+	if err != nil || days < 0 || days > maxSiteDocumentReminderDays {
+		return 30
+	}
+	return days
+}
+
+// siteDocumentDate drops the time of day, so dates compare the same in every database
+func siteDocumentDate(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	date := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return &date
+}
+
+// canViewSiteDocument reports whether a member with role may see a document. Admins see
+// every document; documents without allowed roles are open to every member.
+func canViewSiteDocument(document *models.SiteDocument, role string) bool {
+	roles := document.RoleList()
+	return role == "admin" || len(roles) == 0 || containsString(roles, role)
+}
+
+// normalizeSiteDocumentRoles trims, lowercases and de-duplicates a role list
+func normalizeSiteDocumentRoles(roles []string) datatypes.JSON {
+	var normalized []string
+	for _, role := range roles {
+		role = strings.ToLower(strings.TrimSpace(role))
+		if role != "" && !containsString(normalized, role) {
+			normalized = append(normalized, role)
+		}
+	}
+	if len(normalized) == 0 {
+		return nil
+	}
+	encoded, _ := json.Marshal(normalized)
+	return datatypes.JSON(encoded)
+}
+
+// validateSiteDocumentUpload checks an upload's details and fills in defaults
+func validateSiteDocumentUpload(upload *models.SiteDocumentUpload) error {
+	upload.IssuedOn = siteDocumentDate(upload.IssuedOn)
+	upload.ExpiresOn = siteDocumentDate(upload.ExpiresOn)
+	if upload.IssuedOn != nil && upload.ExpiresOn != nil && upload.ExpiresOn.Before(*upload.IssuedOn) {
+		return fmt.Errorf("%w: expires_on is before issued_on", ErrInvalidSiteDocument)
+	}
+	if upload.ReminderDays != nil && (*upload.ReminderDays < 0 || *upload.ReminderDays > maxSiteDocumentReminderDays) {
+		return fmt.Errorf("%w: reminder_days must be between 0 and %d", ErrInvalidSiteDocument, maxSiteDocumentReminderDays)
+	}
+	return nil
+}
+
+// =====================================================
+// DOCUMENTS
+// =====================================================
+
+// GetDocuments returns a site's documents that role may view. Filters: category,
+// expiry_status (no_expiry, valid, expiring, expired).
+func (s *SiteDocumentService) GetDocuments(ctx context.Context, organizationID, siteID, role string, filters map[string]interface{}) ([]models.SiteDocument, error) {
+	query := database.Conn(ctx, s.db).Where("organization_id = ? AND site_id = ?", organizationID, siteID)
+	if category, ok := filters["category"]; ok {
+		query = query.Where("category = ?", category)
+	}
+
+	var documents []models.SiteDocument
+	if err := query.Order("category, title").Find(&documents).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch site documents: %v", err)
+	}
+
+	status, _ := filters["expiry_status"].(string)
+	now := time.Now()
+	visible := make([]models.SiteDocument, 0, len(documents))
+	for _, document := range documents {
+		if !canViewSiteDocument(&document, role) {
+			continue
+		}
+		document.ExpiryStatus = document.StatusOn(now)
+		if status != "" && document.ExpiryStatus != status {
+			continue
+		}
+		visible = append(visible, document)
+	}
+
+	return visible, nil
+}
+
+// GetDocument returns a document with its version history, newest first
+func (s *SiteDocumentService) GetDocument(ctx context.Context, organizationID, siteID, documentID, role string) (*models.SiteDocument, error) {
+	var document models.SiteDocument
+	err := database.Conn(ctx, s.db).
+		Preload("Versions", func(db *gorm.DB) *gorm.DB { return db.Order("version DESC") }).
+		Where("id = ? AND organization_id = ? AND site_id = ?", documentID, organizationID, siteID).
+		First(&document).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSiteDocumentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch site document: %v", err)
+	}
+	if !canViewSiteDocument(&document, role) {
+		return nil, ErrSiteDocumentNotFound
+	}
+
+	document.ExpiryStatus = document.StatusOn(time.Now())
+	return &document, nil
+}
+
+// UploadDocument stores a file as version 1 of a new document on a site
+func (s *SiteDocumentService) UploadDocument(ctx context.Context, organizationID, siteID, userID string, upload *models.SiteDocumentUpload, file *multipart.FileHeader) (*models.SiteDocument, error) {
+	if err := validateSiteDocumentUpload(upload); err != nil {
+		return nil, err
+	}
+	if !containsString(siteDocumentCategories, upload.Category) {
+		return nil, fmt.Errorf("%w: category must be one of %s", ErrInvalidSiteDocument, strings.Join(siteDocumentCategories, ", "))
+	}
+	title := strings.TrimSpace(upload.Title)
+	if title == "" {
+		title = strings.TrimSuffix(file.Filename, filepath.Ext(file.Filename))
+	}
+	reminderDays := defaultSiteDocumentReminderDays()
+	if upload.ReminderDays != nil {
+		reminderDays = *upload.ReminderDays
+	}
+
+	db := database.Conn(ctx, s.db)
+	var sites int64
+	if err := db.Model(&models.Site{}).Where("id = ? AND organization_id = ?", siteID, organizationID).Count(&sites).Error; err != nil {
+		return nil, fmt.Errorf("failed to check site: %v", err)
+	}
+	if sites == 0 {
+		return nil, ErrDocumentSiteNotFound
+	}
+
+	document := &models.SiteDocument{
+		ID:             uuid.NewString(),
+		OrganizationID: organizationID,
+		SiteID:         siteID,
+		Category:       upload.Category,
+		Title:          title,
+		Description:    upload.Description,
+		IssuedOn:       upload.IssuedOn,
+		ExpiresOn:      upload.ExpiresOn,
+		ReminderDays:   reminderDays,
+		AllowedRoles:   normalizeSiteDocumentRoles(upload.AllowedRoles),
+		CurrentVersion: 1,
+		CreatedBy:      userID,
+		UpdatedBy:      userID,
+	}
+
+	stored, err := s.storage.UploadFile(ctx, file, fmt.Sprintf("sites/%s/documents/%s", siteID, document.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to store document: %v", err)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(document).Error; err != nil {
+			return err
+		}
+		return tx.Create(newSiteDocumentVersion(document, 1, stored, upload, userID)).Error
+	})
+	if err != nil {
+		s.removeStoredFile(stored.Path)
+		return nil, fmt.Errorf("failed to save site document: %v", err)
+	}
+
+	return s.GetDocument(ctx, organizationID, siteID, document.ID, "admin")
+}
+
+// AddVersion stores a file as the document's new current version. The document takes the
+// new version's dates, so a renewed permit is tracked from its new expiry date and any
+// alert about the old one is resolved.
+func (s *SiteDocumentService) AddVersion(ctx context.Context, organizationID, siteID, documentID, userID string, upload *models.SiteDocumentUpload, file *multipart.FileHeader) (*models.SiteDocument, error) {
+	if err := validateSiteDocumentUpload(upload); err != nil {
+		return nil, err
+	}
+
+	document, err := s.GetDocument(ctx, organizationID, siteID, documentID, "admin")
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := s.storage.UploadFile(ctx, file, fmt.Sprintf("sites/%s/documents/%s", siteID, document.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to store document: %v", err)
+	}
+
+	renewed := !sameSiteDocumentDate(document.ExpiresOn, upload.ExpiresOn)
+	err = database.Conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		// Incrementing in place locks the row, so concurrent uploads get distinct numbers
+		if err := tx.Model(&models.SiteDocument{}).Where("id = ?", document.ID).
+			Update("current_version", gorm.Expr("current_version + 1")).Error; err != nil {
+			return err
+		}
+
+		var version int
+		if err := tx.Model(&models.SiteDocument{}).Where("id = ?", document.ID).Pluck("current_version", &version).Error; err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{
+			"issued_on":  upload.IssuedOn,
+			"expires_on": upload.ExpiresOn,
+			"updated_by": userID,
+			"updated_at": time.Now(),
+		}
+		if renewed {
+			updates["reminder_sent_for"] = nil
+		}
+		if err := tx.Model(&models.SiteDocument{}).Where("id = ?", document.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Create(newSiteDocumentVersion(document, version, stored, upload, userID)).Error; err != nil {
+			return err
+		}
+
+		if renewed {
+			return resolveSiteDocumentAlerts(tx, document.ID, userID)
+		}
+		return nil
+	})
+	if err != nil {
+		s.removeStoredFile(stored.Path)
+		return nil, fmt.Errorf("failed to save document version: %v", err)
+	}
+
+	return s.GetDocument(ctx, organizationID, siteID, document.ID, "admin")
+}
+
+// UpdateDocument changes a document's details. Dates belong to the uploaded file and
+// change only with a new version.
+func (s *SiteDocumentService) UpdateDocument(ctx context.Context, organizationID, siteID, documentID, userID string, req *models.UpdateSiteDocumentRequest) (*models.SiteDocument, error) {
+	document, err := s.GetDocument(ctx, organizationID, siteID, documentID, "admin")
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{
+		"updated_by": userID,
+		"updated_at": time.Now(),
+	}
+	if req.Category != nil {
+		if !containsString(siteDocumentCategories, *req.Category) {
+			return nil, fmt.Errorf("%w: category must be one of %s", ErrInvalidSiteDocument, strings.Join(siteDocumentCategories, ", "))
+		}
+		updates["category"] = *req.Category
+	}
+	if req.Title != nil {
+		if strings.TrimSpace(*req.Title) == "" {
+			return nil, fmt.Errorf("%w: title is required", ErrInvalidSiteDocument)
+		}
+		updates["title"] = strings.TrimSpace(*req.Title)
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.ReminderDays != nil {
+		if *req.ReminderDays < 0 || *req.ReminderDays > maxSiteDocumentReminderDays {
+			return nil, fmt.Errorf("%w: reminder_days must be between 0 and %d", ErrInvalidSiteDocument, maxSiteDocumentReminderDays)
+		}
+		updates["reminder_days"] = *req.ReminderDays
+	}
+	if req.AllowedRoles != nil {
+		updates["allowed_roles"] = normalizeSiteDocumentRoles(req.AllowedRoles)
+	}
+
+	if err := database.Conn(ctx, s.db).Model(&models.SiteDocument{}).Where("id = ?", document.ID).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to update site document: %v", err)
+	}
+
+	return s.GetDocument(ctx, organizationID, siteID, document.ID, "admin")
+}
+
+// DeleteDocument removes a document, its versions and their stored files
+func (s *SiteDocumentService) DeleteDocument(ctx context.Context, organizationID, siteID, documentID, userID string) error {
+	document, err := s.GetDocument(ctx, organizationID, siteID, documentID, "admin")
+	if err != nil {
+		return err
+	}
+
+	err = database.Conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("document_id = ?", document.ID).Delete(&models.SiteDocumentVersion{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.SiteDocument{}, "id = ?", document.ID).Error; err != nil {
+			return err
+		}
+		return resolveSiteDocumentAlerts(tx, document.ID, userID)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete site document: %v", err)
+	}
+
+	for _, version := range document.Versions {
+		s.removeStoredFile(version.StoragePath)
+	}
+	return nil
+}
+
+// GetVersion returns one version of a document; version 0 is the current one
+func (s *SiteDocumentService) GetVersion(ctx context.Context, organizationID, siteID, documentID, role string, version int) (*models.SiteDocument, *models.SiteDocumentVersion, error) {
+	document, err := s.GetDocument(ctx, organizationID, siteID, documentID, role)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if version == 0 {
+		version = document.CurrentVersion
+	}
+	for i := range document.Versions {
+		if document.Versions[i].Version == version {
+			return document, &document.Versions[i], nil
+		}
+	}
+	return nil, nil, ErrSiteDocumentNotFound
+}
+
+// DownloadLocation returns where a version's file can be fetched: a path on disk for local
+// storage, or a short-lived presigned URL for R2
+func (s *SiteDocumentService) DownloadLocation(ctx context.Context, version *models.SiteDocumentVersion) (localPath, url string, err error) {
+	if localPath = s.storage.GetLocalFilePath(version.StoragePath); localPath != "" {
+		return localPath, "", nil
+	}
+
+	url, err = s.storage.GeneratePresignedURL(ctx, version.StoragePath, siteDocumentDownloadExpiry)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create download link: %v", err)
+	}
+	return "", url, nil
+}
+
+// newSiteDocumentVersion records a stored file as a version of document
+func newSiteDocumentVersion(document *models.SiteDocument, version int, stored *UploadResult, upload *models.SiteDocumentUpload, userID string) *models.SiteDocumentVersion {
+	return &models.SiteDocumentVersion{
+		OrganizationID: document.OrganizationID,
+		DocumentID:     document.ID,
+		Version:        version,
+		FileName:       stored.OriginalName,
+		StoragePath:    stored.Path,
+		FileSize:       stored.Size,
+		MimeType:       stored.MimeType,
+		IssuedOn:       upload.IssuedOn,
+		ExpiresOn:      upload.ExpiresOn,
+		Notes:          upload.Notes,
+		UploadedBy:     userID,
+		UploadedAt:     stored.UploadedAt,
+	}
+}
+
+// removeStoredFile deletes a file that is no longer referenced, logging failures
+func (s *SiteDocumentService) removeStoredFile(path string) {
+	if err := s.storage.DeleteFile(context.Background(), path); err != nil {
+		log.Printf("Failed to delete stored document %s: %v", path, err)
+	}
+}
+
+// sameSiteDocumentDate reports whether two optional dates are the same day
+func sameSiteDocumentDate(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return siteDocumentDate(a).Equal(*siteDocumentDate(b))
+}
+
+// resolveSiteDocumentAlerts resolves a document's active expiry alerts
+func resolveSiteDocumentAlerts(tx *gorm.DB, documentID, userID string) error {
+	now := time.Now()
+	return tx.Model(&models.WorkflowAlert{}).
+		Where("target_type = ? AND target_id = ? AND status = ?", siteDocumentAlertTarget, documentID, "active").
+		Updates(map[string]interface{}{"status": "resolved", "resolved_at": now, "resolved_by": userID}).Error
+}
+
+// =====================================================
+// EXPIRY TRACKING
+// =====================================================
+
+// GetCompliance lists, per site, the documents role may view that have expired or are
+// within their reminder window. Sites with nothing outstanding are left out.
+func (s *SiteDocumentService) GetCompliance(ctx context.Context, organizationID, role string, filters map[string]interface{}) ([]models.SiteDocumentCompliance, error) {
+	query := database.Conn(ctx, s.db).Preload("Site").
+		Where("organization_id = ? AND expires_on IS NOT NULL", organizationID)
+	if siteID, ok := filters["site_id"]; ok {
+		query = query.Where("site_id = ?", siteID)
+	}
+	if category, ok := filters["category"]; ok {
+		query = query.Where("category = ?", category)
+	}
+
+	var documents []models.SiteDocument
+	if err := query.Order("expires_on").Find(&documents).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch site documents: %v", err)
+	}
+
+	now := time.Now()
+	bySite := make(map[string]*models.SiteDocumentCompliance)
+	for _, document := range documents {
+		if !canViewSiteDocument(&document, role) {
+			continue
+		}
+		document.ExpiryStatus = document.StatusOn(now)
+		if document.ExpiryStatus != models.SiteDocumentExpired && document.ExpiryStatus != models.SiteDocumentExpiring {
+			continue
+		}
+
+		entry, ok := bySite[document.SiteID]
+		if !ok {
+			entry = &models.SiteDocumentCompliance{SiteID: document.SiteID, Expired: []models.SiteDocument{}, Expiring: []models.SiteDocument{}}
+			if document.Site != nil {
+				entry.SiteName = document.Site.Name
+			}
+			bySite[document.SiteID] = entry
+		}
+		document.Site = nil
+		if document.ExpiryStatus == models.SiteDocumentExpired {
+			entry.Expired = append(entry.Expired, document)
+		} else {
+			entry.Expiring = append(entry.Expiring, document)
+		}
+	}
+
+	compliance := make([]models.SiteDocumentCompliance, 0, len(bySite))
+	for _, entry := range bySite {
+		compliance = append(compliance, *entry)
+	}
+	sort.Slice(compliance, func(i, j int) bool {
+		if len(compliance[i].Expired) != len(compliance[j].Expired) {
+			return len(compliance[i].Expired) > len(compliance[j].Expired)
+		}
+		return compliance[i].SiteName < compliance[j].SiteName
+	})
+
+	return compliance, nil
+}
+
+// CheckExpiringDocuments reminds site managers, in every organization, about documents
+// that entered their reminder window or expired, and raises a WorkflowAlert for each.
+// A document is reminded about once per expiry date, even with several servers running.
+func (s *SiteDocumentService) CheckExpiringDocuments(ctx context.Context, now time.Time) (*models.SiteDocumentExpiryCheck, error) {
+	horizon := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, maxSiteDocumentReminderDays)
+
+	var documents []models.SiteDocument
+	err := s.db.WithContext(ctx).Preload("Site").
+		Where("expires_on IS NOT NULL AND expires_on <= ?", horizon).
+		Where("reminder_sent_for IS NULL OR reminder_sent_for <> expires_on").
+		Order("organization_id, expires_on").
+		Find(&documents).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch expiring site documents: %v", err)
+	}
+
+	result := &models.SiteDocumentExpiryCheck{DocumentsChecked: len(documents)}
+	managers := make(map[string][]string)
+	for i := range documents {
+		document := &documents[i]
+		status := document.StatusOn(now)
+		if status != models.SiteDocumentExpiring && status != models.SiteDocumentExpired {
+			continue
+		}
+
+		// Claim the reminder so another server running the check skips it
+		claim := s.db.WithContext(ctx).Model(&models.SiteDocument{}).
+			Where("id = ? AND (reminder_sent_for IS NULL OR reminder_sent_for <> expires_on)", document.ID).
+			UpdateColumn("reminder_sent_for", gorm.Expr("expires_on"))
+		if claim.Error != nil {
+			return result, fmt.Errorf("failed to mark site document reminder: %v", claim.Error)
+		}
+		if claim.RowsAffected == 0 {
+			continue
+		}
+
+		recipients, ok := managers[document.OrganizationID]
+		if !ok {
+			if err := s.db.WithContext(ctx).Model(&models.OrganizationMember{}).
+				Where("organization_id = ? AND role IN ? AND status = ?", document.OrganizationID, siteManagerRoles, "active").
+				Order("role, joined_at").
+				Pluck("user_id", &recipients).Error; err != nil {
+				return result, fmt.Errorf("failed to fetch site managers: %v", err)
+			}
+			managers[document.OrganizationID] = recipients
+		}
+
+		title, message := siteDocumentReminderText(document, status)
+		for _, userID := range recipients {
+			if _, err := s.notificationService.CreateNotification(&models.CreateNotificationRequest{
+				OrganizationID: document.OrganizationID,
+				UserID:         userID,
+				Title:          title,
+				Message:        message,
+				Type:           "document_expiry",
+			}); err != nil {
+				log.Printf("Failed to notify %s about site document %s: %v", userID, document.ID, err)
+			}
+		}
+		result.Reminded++
+
+		if err := s.raiseSiteDocumentAlert(ctx, document, status, title, message, recipients); err != nil {
+			log.Printf("Failed to raise alert for site document %s: %v", document.ID, err)
+			continue
+		}
+		result.AlertsRaised++
+	}
+
+	return result, nil
+}
+
+// raiseSiteDocumentAlert opens a WorkflowAlert about an expiring document, assigned to the
+// first site manager
+func (s *SiteDocumentService) raiseSiteDocumentAlert(ctx context.Context, document *models.SiteDocument, status, title, message string, recipients []string) error {
+	severity := "medium"
+	if status == models.SiteDocumentExpired {
+		severity = "high"
+	}
+	assignedTo := document.CreatedBy
+	if len(recipients) > 0 {
+		assignedTo = recipients[0]
+	}
+
+	details, _ := json.Marshal(map[string]interface{}{
+		"site_id":    document.SiteID,
+		"category":   document.Category,
+		"expires_on": document.ExpiresOn.Format("2006-01-02"),
+		"version":    document.CurrentVersion,
+	})
+	notifyUsers, _ := json.Marshal(recipients)
+
+	return s.db.WithContext(ctx).Create(&models.WorkflowAlert{
+		OrganizationID: document.OrganizationID,
+		AlertType:      siteDocumentAlertType,
+		Severity:       severity,
+		Status:         "active",
+		TargetType:     siteDocumentAlertTarget,
+		TargetID:       document.ID,
+		Title:          title,
+		Message:        message,
+		Details:        datatypes.JSON(details),
+		AssignedTo:     assignedTo,
+		NotifyUsers:    datatypes.JSON(notifyUsers),
+		TriggeredAt:    time.Now(),
+		AutoResolve:    true,
+	}).Error
+}
+
+// siteDocumentReminderText builds the notification for an expiring document
+func siteDocumentReminderText(document *models.SiteDocument, status string) (string, string) {
+	siteName := document.SiteID
+	if document.Site != nil {
+		siteName = document.Site.Name
+	}
+	expiresOn := document.ExpiresOn.Format("2006-01-02")
+
+	if status == models.SiteDocumentExpired {
+		return "Site Document Expired",
+			fmt.Sprintf("%s for %s expired on %s. Upload the renewed document to keep the site compliant.", document.Title, siteName, expiresOn)
+	}
+	return "Site Document Expiring",
+		fmt.Sprintf("%s for %s expires on %s. Upload the renewed document before then.", document.Title, siteName, expiresOn)
+}
+
+// StartExpiryChecker runs CheckExpiringDocuments now and then every interval until ctx is done
+func (s *SiteDocumentService) StartExpiryChecker(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			result, err := s.CheckExpiringDocuments(ctx, time.Now())
+			if err != nil {
+				log.Printf("Site document expiry check failed: %v", err)
+			} else if result.Reminded > 0 {
+				log.Printf("Site document expiry check sent %d reminders", result.Reminded)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
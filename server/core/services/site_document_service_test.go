@@ -0,0 +1,235 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"resource-mgmt/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// testDocumentFile builds the multipart file header a handler would pass in
+func testDocumentFile(t *testing.T, name, content string) *multipart.FileHeader {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", `form-data; name="file"; filename="`+name+`"`)
+	header.Set("Content-Type", "application/pdf")
+	part, err := writer.CreatePart(header)
+	require.NoError(t, err)
+	_, err = part.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	form, err := multipart.NewReader(&body, writer.Boundary()).ReadForm(1 << 20)
+	require.NoError(t, err)
+	return form.File["file"][0]
+}
+
+// siteDocumentTestFixture is a warehouse with an admin, a supervisor and an inspector, and
+// three documents: a permit inside its reminder window, an expired insurance certificate
+// only supervisors may see, and an undated floor plan
+type siteDocumentTestFixture struct {
+	db                *gorm.DB
+	service           *SiteDocumentService
+	now               time.Time
+	permit, insurance *models.SiteDocument
+}
+
+func newSiteDocumentTestFixture(t *testing.T) *siteDocumentTestFixture {
+	db := setupServiceTestDB(t, &models.Site{}, &models.SiteDocument{}, &models.SiteDocumentVersion{}, &models.WorkflowAlert{},
+		&models.GlobalUser{}, &models.Organization{}, &models.OrganizationMember{}, &models.Notification{})
+	ctx := context.Background()
+
+	storage, err := NewStorageService(StorageConfig{Provider: StorageLocal, LocalPath: t.TempDir(), BaseURL: "/uploads"})
+	require.NoError(t, err)
+	f := &siteDocumentTestFixture{db: db, service: NewSiteDocumentService(db, storage), now: time.Now()}
+
+	require.NoError(t, db.Create(&models.Organization{ID: "org-a", Name: "Client A", Domain: "client-a", Slug: "client-a", IsActive: true}).Error)
+	require.NoError(t, db.Create(&models.Site{ID: "site-1", OrganizationID: "org-a", Name: "Warehouse", Address: "1 Dock Rd", Status: "active"}).Error)
+	createTestMembers(t, db, "org-a", "admin", "admin-1")
+	createTestMembers(t, db, "org-a", "supervisor", "super-1")
+	createTestMembers(t, db, "org-a", "inspector", "insp-1")
+
+	reminderDays := 30
+	f.permit, err = f.service.UploadDocument(ctx, "org-a", "site-1", "admin-1", &models.SiteDocumentUpload{
+		Category: models.SiteDocumentPermit, Title: "Operating permit", IssuedOn: f.date(-340), ExpiresOn: f.date(20), ReminderDays: &reminderDays,
+	}, testDocumentFile(t, "permit-2025.pdf", "permit v1"))
+	require.NoError(t, err)
+	f.insurance, err = f.service.UploadDocument(ctx, "org-a", "site-1", "admin-1", &models.SiteDocumentUpload{
+		Category: models.SiteDocumentInsurance, ExpiresOn: f.date(-1), AllowedRoles: []string{" Supervisor ", "supervisor"},
+	}, testDocumentFile(t, "insurance.pdf", "insurance"))
+	require.NoError(t, err)
+	_, err = f.service.UploadDocument(ctx, "org-a", "site-1", "admin-1", &models.SiteDocumentUpload{Category: models.SiteDocumentFloorPlan, Title: "Ground floor"}, testDocumentFile(t, "ground.pdf", "plan"))
+	require.NoError(t, err)
+	return f
+}
+
+func (f *siteDocumentTestFixture) date(days int) *time.Time {
+	d := f.now.AddDate(0, 0, days)
+	return &d
+}
+
+// renewPermit uploads the permit's renewal as the supervisor
+func (f *siteDocumentTestFixture) renewPermit(t *testing.T) *models.SiteDocument {
+	permit, err := f.service.AddVersion(context.Background(), "org-a", "site-1", f.permit.ID, "super-1", &models.SiteDocumentUpload{
+		IssuedOn: f.date(0), ExpiresOn: f.date(365), Notes: "Renewed",
+	}, testDocumentFile(t, "permit-2026.pdf", "permit v2"))
+	require.NoError(t, err)
+	return permit
+}
+
+func TestSiteDocumentService_UploadDocumentValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		siteID   string
+		category string
+		wantErr  error
+	}{
+		{"unknown category", "site-1", "lease", ErrInvalidSiteDocument},
+		{"missing category", "site-1", "", ErrInvalidSiteDocument},
+		{"unknown site", "site-9", models.SiteDocumentPermit, ErrDocumentSiteNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSiteDocumentTestFixture(t)
+			_, err := f.service.UploadDocument(context.Background(), "org-a", tt.siteID, "admin-1", &models.SiteDocumentUpload{Category: tt.category}, testDocumentFile(t, "permit.pdf", "x"))
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestSiteDocumentService_UploadDocumentDefaults(t *testing.T) {
+	f := newSiteDocumentTestFixture(t)
+
+	assert.Equal(t, 1, f.permit.CurrentVersion)
+	assert.Equal(t, models.SiteDocumentExpiring, f.permit.ExpiryStatus)
+	assert.Equal(t, models.SiteDocumentExpired, f.insurance.ExpiryStatus)
+	assert.Equal(t, "insurance", f.insurance.Title, "the title defaults to the file name")
+	assert.Equal(t, []string{"supervisor"}, f.insurance.RoleList())
+}
+
+func TestSiteDocumentService_AccessFollowsAllowedRoles(t *testing.T) {
+	tests := []struct {
+		name      string
+		role      string
+		filters   map[string]interface{}
+		wantCount int
+		wantSee   bool
+	}{
+		{"inspector", "inspector", map[string]interface{}{}, 2, false},
+		{"supervisor", "supervisor", map[string]interface{}{}, 3, true},
+		{"supervisor filtering expired", "supervisor", map[string]interface{}{"expiry_status": models.SiteDocumentExpired}, 1, true},
+		{"admin", "admin", map[string]interface{}{}, 3, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSiteDocumentTestFixture(t)
+			ctx := context.Background()
+
+			documents, err := f.service.GetDocuments(ctx, "org-a", "site-1", tt.role, tt.filters)
+			require.NoError(t, err)
+			assert.Len(t, documents, tt.wantCount)
+
+			_, err = f.service.GetDocument(ctx, "org-a", "site-1", f.insurance.ID, tt.role)
+			if tt.wantSee {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrSiteDocumentNotFound)
+			}
+		})
+	}
+}
+
+func TestSiteDocumentService_ComplianceGroupsOutstandingDocuments(t *testing.T) {
+	f := newSiteDocumentTestFixture(t)
+
+	compliance, err := f.service.GetCompliance(context.Background(), "org-a", "admin", map[string]interface{}{})
+	require.NoError(t, err)
+	require.Len(t, compliance, 1)
+	assert.Equal(t, "Warehouse", compliance[0].SiteName)
+	require.Len(t, compliance[0].Expired, 1)
+	assert.Equal(t, f.insurance.ID, compliance[0].Expired[0].ID)
+	require.Len(t, compliance[0].Expiring, 1)
+	assert.Equal(t, f.permit.ID, compliance[0].Expiring[0].ID)
+}
+
+func TestSiteDocumentService_ExpiryCheckRemindsManagersOnce(t *testing.T) {
+	f := newSiteDocumentTestFixture(t)
+	ctx := context.Background()
+
+	result, err := f.service.CheckExpiringDocuments(ctx, f.now)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Reminded)
+	assert.Equal(t, 2, result.AlertsRaised)
+	result, err = f.service.CheckExpiringDocuments(ctx, f.now)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Reminded)
+
+	var notified []string
+	require.NoError(t, f.db.Model(&models.Notification{}).Where("type = ?", "document_expiry").Order("user_id").Pluck("user_id", &notified).Error)
+	assert.Equal(t, []string{"admin-1", "admin-1", "super-1", "super-1"}, notified)
+
+	var alert models.WorkflowAlert
+	require.NoError(t, f.db.Where("target_type = ? AND target_id = ?", "site_document", f.permit.ID).First(&alert).Error)
+	assert.Equal(t, "active", alert.Status)
+	assert.Equal(t, "admin-1", alert.AssignedTo)
+	assert.Equal(t, "medium", alert.Severity)
+}
+
+func TestSiteDocumentService_RenewalKeepsHistoryAndResolvesAlert(t *testing.T) {
+	f := newSiteDocumentTestFixture(t)
+	ctx := context.Background()
+	_, err := f.service.CheckExpiringDocuments(ctx, f.now)
+	require.NoError(t, err)
+
+	permit := f.renewPermit(t)
+	assert.Equal(t, 2, permit.CurrentVersion)
+	assert.Equal(t, models.SiteDocumentValid, permit.ExpiryStatus)
+	require.Len(t, permit.Versions, 2)
+	assert.Equal(t, "permit-2026.pdf", permit.Versions[0].FileName)
+	assert.Nil(t, permit.ReminderSentFor, "the reminder is re-armed")
+
+	var alert models.WorkflowAlert
+	require.NoError(t, f.db.Where("target_type = ? AND target_id = ?", "site_document", f.permit.ID).First(&alert).Error)
+	assert.Equal(t, "resolved", alert.Status)
+
+	_, previous, err := f.service.GetVersion(ctx, "org-a", "site-1", f.permit.ID, "inspector", 1)
+	require.NoError(t, err)
+	localPath, url, err := f.service.DownloadLocation(ctx, previous)
+	require.NoError(t, err)
+	assert.Empty(t, url)
+	content, err := os.ReadFile(localPath)
+	require.NoError(t, err)
+	assert.Equal(t, "permit v1", string(content))
+}
+
+func TestSiteDocumentService_DeleteDocumentRemovesFiles(t *testing.T) {
+	f := newSiteDocumentTestFixture(t)
+	ctx := context.Background()
+	f.renewPermit(t)
+
+	var paths []string
+	for _, version := range []int{1, 2} {
+		_, stored, err := f.service.GetVersion(ctx, "org-a", "site-1", f.permit.ID, "admin", version)
+		require.NoError(t, err)
+		localPath, _, err := f.service.DownloadLocation(ctx, stored)
+		require.NoError(t, err)
+		paths = append(paths, localPath)
+	}
+
+	require.NoError(t, f.service.DeleteDocument(ctx, "org-a", "site-1", f.permit.ID, "admin-1"))
+	for _, path := range paths {
+		_, err := os.Stat(path)
+		assert.True(t, os.IsNotExist(err))
+	}
+	_, err := f.service.GetDocument(ctx, "org-a", "site-1", f.permit.ID, "admin")
+	assert.ErrorIs(t, err, ErrSiteDocumentNotFound)
+}
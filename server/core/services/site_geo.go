@@ -0,0 +1,396 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"resource-mgmt/models"
+	"resource-mgmt/pkg/database"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidCoordinates is returned for latitudes or longitudes outside WGS84 ranges
+	ErrInvalidCoordinates = errors.New("invalid coordinates")
+	// ErrInvalidRadius is returned for non-positive or out of range search radii
+	ErrInvalidRadius = errors.New("invalid search radius")
+)
+
+const (
+	// earthRadiusKm is the mean Earth radius used for haversine distances
+	earthRadiusKm = 6371.0
+	// maxSearchRadiusKm is half the Earth's circumference; nothing is further away
+	maxSearchRadiusKm = math.Pi * earthRadiusKm
+	// defaultTravelDistanceKm applies when an inspector has no workload record yet
+	defaultTravelDistanceKm = 50
+	// complianceDueSoonWindow is how far ahead a due date turns a site amber on the map
+	complianceDueSoonWindow = 7 * 24 * time.Hour
+	// maxGeoResults bounds proximity and viewport queries
+	maxGeoResults = 1000
+)
+
+// siteComplianceColors follow the simplestyle-spec "marker-color" property most map clients understand
+var siteComplianceColors = map[string]string{
+	models.SiteComplianceCompliant:      "#2e7d32",
+	models.SiteComplianceDueSoon:        "#f9a825",
+	models.SiteComplianceOverdue:        "#c62828",
+	models.SiteComplianceNeverInspected: "#757575",
+}
+
+// siteGeoFilterKeys are the site columns proximity and viewport queries can filter on
+var siteGeoFilterKeys = []string{"status", "type"}
+
+// =====================================================
+// PROXIMITY QUERIES
+// =====================================================
+
+// FindSitesWithinRadius returns sites within radiusKm of center, nearest first
+func (s *SiteService) FindSitesWithinRadius(ctx context.Context, organizationID string, center models.GeoPoint, radiusKm float64, filters map[string]interface{}, limit int) ([]models.SiteWithDistance, error) {
+	if err := validateGeoPoint(center); err != nil {
+		return nil, err
+	}
+	if radiusKm <= 0 || radiusKm > maxSearchRadiusKm {
+		return nil, fmt.Errorf("%w: radius must be between 0 and %.0f km", ErrInvalidRadius, maxSearchRadiusKm)
+	}
+
+	var sites []models.Site
+	query := s.geoQuery(ctx, organizationID, filters)
+	if err := applyBoundsFilter(query, boundsAround(center, radiusKm)).Find(&sites).Error; err != nil {
+		return nil, fmt.Errorf("failed to search sites by radius: %v", err)
+	}
+
+	// The bounding box over-selects at the corners; the exact distance decides
+	results := make([]models.SiteWithDistance, 0, len(sites))
+	for _, site := range sites {
+		distance := HaversineKm(center, models.GeoPoint{Latitude: *site.Latitude, Longitude: *site.Longitude})
+		if distance <= radiusKm {
+			results = append(results, models.SiteWithDistance{Site: site, DistanceKm: roundKm(distance)})
+		}
+	}
+	sortByDistance(results)
+
+	if limit <= 0 || limit > maxGeoResults {
+		limit = maxGeoResults
+	}
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// FindNearestSites returns the k nearest active sites to center. The search radius
+// widens until enough sites are found, so dense areas stay cheap.
+func (s *SiteService) FindNearestSites(ctx context.Context, organizationID string, center models.GeoPoint, k int) ([]models.SiteWithDistance, error) {
+	if k <= 0 {
+		k = 5
+	}
+	if k > maxGeoResults {
+		k = maxGeoResults
+	}
+
+	filters := map[string]interface{}{"status": "active"}
+	for _, radiusKm := range []float64{10, 50, 250, 1000, 5000, maxSearchRadiusKm} {
+		results, err := s.FindSitesWithinRadius(ctx, organizationID, center, radiusKm, filters, 0)
+		if err != nil {
+			return nil, err
+		}
+		if len(results) >= k || radiusKm == maxSearchRadiusKm {
+			if len(results) > k {
+				results = results[:k]
+			}
+			return results, nil
+		}
+	}
+	return []models.SiteWithDistance{}, nil
+}
+
+// FindSitesInBounds returns the sites inside a map viewport
+func (s *SiteService) FindSitesInBounds(ctx context.Context, organizationID string, bounds models.GeoBounds, filters map[string]interface{}, limit int) ([]models.Site, error) {
+	if err := validateGeoBounds(bounds); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > maxGeoResults {
+		limit = maxGeoResults
+	}
+
+	var sites []models.Site
+	query := s.geoQuery(ctx, organizationID, filters)
+	if err := applyBoundsFilter(query, bounds).Order("name ASC").Limit(limit).Find(&sites).Error; err != nil {
+		return nil, fmt.Errorf("failed to search sites by bounds: %v", err)
+	}
+	return sites, nil
+}
+
+// GetDueNearby returns the inspector's open inspections due before dueBefore at sites
+// within radiusKm of where they are, nearest site first. A zero radius falls back to the
+// inspector's MaxTravelDistance.
+func (s *SiteService) GetDueNearby(ctx context.Context, organizationID, inspectorID string, center models.GeoPoint, radiusKm float64, dueBefore time.Time) ([]models.NearbyDueSite, error) {
+	if radiusKm <= 0 {
+		travelDistance, err := s.inspectorTravelDistance(ctx, organizationID, inspectorID)
+		if err != nil {
+			return nil, err
+		}
+		radiusKm = travelDistance
+	}
+
+	sites, err := s.FindSitesWithinRadius(ctx, organizationID, center, radiusKm, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(sites) == 0 {
+		return []models.NearbyDueSite{}, nil
+	}
+
+	siteIDs := make([]string, len(sites))
+	for i, site := range sites {
+		siteIDs[i] = site.ID
+	}
+
+	var inspections []models.Inspection
+	if err := database.Conn(ctx, s.db).
+		Where("organization_id = ? AND inspector_id = ? AND site_id IN ? AND status NOT IN ?",
+			organizationID, inspectorID, siteIDs, closedInspectionStatuses).
+		Where("(due_date IS NULL OR due_date <= ?)", dueBefore).
+		Preload("Template").
+		Order("due_date ASC").
+		Find(&inspections).Error; err != nil {
+		return nil, fmt.Errorf("failed to get inspections due nearby: %v", err)
+	}
+
+	bySite := make(map[string][]models.Inspection)
+	for _, inspection := range inspections {
+		bySite[inspection.SiteID] = append(bySite[inspection.SiteID], inspection)
+	}
+
+	results := []models.NearbyDueSite{}
+	for _, site := range sites {
+		if due := bySite[site.ID]; len(due) > 0 {
+			results = append(results, models.NearbyDueSite{Site: site, Inspections: due})
+		}
+	}
+	return results, nil
+}
+
+// inspectorTravelDistance returns the inspector's configured maximum travel distance
+func (s *SiteService) inspectorTravelDistance(ctx context.Context, organizationID, inspectorID string) (float64, error) {
+	var workload models.InspectorWorkload
+	err := database.Conn(ctx, s.db).
+		Select("max_travel_distance").
+		Where("organization_id = ? AND inspector_id = ?", organizationID, inspectorID).
+		First(&workload).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && workload.MaxTravelDistance <= 0) {
+		return defaultTravelDistanceKm, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get inspector travel distance: %v", err)
+	}
+	return float64(workload.MaxTravelDistance), nil
+}
+
+// =====================================================
+// GEOJSON
+// =====================================================
+
+// GetSitesGeoJSON returns the organization's located sites as a GeoJSON feature collection,
+// optionally limited to a viewport. With colorByCompliance each feature carries its
+// compliance status and a matching marker colour.
+func (s *SiteService) GetSitesGeoJSON(ctx context.Context, organizationID string, bounds *models.GeoBounds, filters map[string]interface{}, colorByCompliance bool) (*models.GeoJSONFeatureCollection, error) {
+	var sites []models.Site
+	if bounds != nil {
+		var err error
+		if sites, err = s.FindSitesInBounds(ctx, organizationID, *bounds, filters, 0); err != nil {
+			return nil, err
+		}
+	} else if err := s.geoQuery(ctx, organizationID, filters).Order("name ASC").Find(&sites).Error; err != nil {
+		return nil, fmt.Errorf("failed to get located sites: %v", err)
+	}
+
+	var compliance map[string]string
+	if colorByCompliance {
+		var err error
+		if compliance, err = s.GetSiteComplianceStatuses(ctx, organizationID); err != nil {
+			return nil, err
+		}
+	}
+
+	collection := &models.GeoJSONFeatureCollection{Type: "FeatureCollection", Features: make([]models.GeoJSONFeature, 0, len(sites))}
+	for _, site := range sites {
+		properties := map[string]interface{}{
+			"name":    site.Name,
+			"address": site.GetFullAddress(),
+			"type":    site.Type,
+			"status":  site.Status,
+		}
+		if colorByCompliance {
+			status, ok := compliance[site.ID]
+			if !ok {
+				status = models.SiteComplianceNeverInspected
+			}
+			properties["compliance_status"] = status
+			properties["marker-color"] = siteComplianceColors[status]
+		}
+
+		collection.Features = append(collection.Features, models.GeoJSONFeature{
+			Type: "Feature",
+			ID:   site.ID,
+			Geometry: models.GeoJSONGeometry{
+				Type:        "Point",
+				Coordinates: []float64{*site.Longitude, *site.Latitude},
+			},
+			Properties: properties,
+		})
+	}
+	return collection, nil
+}
+
+// GetSiteComplianceStatuses returns the compliance status of every site with inspections.
+// Sites missing from the map have never been inspected.
+func (s *SiteService) GetSiteComplianceStatuses(ctx context.Context, organizationID string) (map[string]string, error) {
+	now := time.Now()
+	var rows []struct {
+		SiteID    string
+		Completed int
+		Overdue   int
+		DueSoon   int
+	}
+	if err := database.Conn(ctx, s.db).Model(&models.Inspection{}).
+		Select(`site_id,
+			SUM(CASE WHEN status IN ? THEN 1 ELSE 0 END) AS completed,
+			SUM(CASE WHEN status NOT IN ? AND due_date < ? THEN 1 ELSE 0 END) AS overdue,
+			SUM(CASE WHEN status NOT IN ? AND due_date >= ? AND due_date < ? THEN 1 ELSE 0 END) AS due_soon`,
+			completedInspectionStatuses,
+			closedInspectionStatuses, now,
+			closedInspectionStatuses, now, now.Add(complianceDueSoonWindow)).
+		Where("organization_id = ?", organizationID).
+		Group("site_id").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to get site compliance: %v", err)
+	}
+
+	statuses := make(map[string]string, len(rows))
+	for _, row := range rows {
+		switch {
+		case row.Overdue > 0:
+			statuses[row.SiteID] = models.SiteComplianceOverdue
+		case row.DueSoon > 0:
+			statuses[row.SiteID] = models.SiteComplianceDueSoon
+		case row.Completed > 0:
+			statuses[row.SiteID] = models.SiteComplianceCompliant
+		default:
+			statuses[row.SiteID] = models.SiteComplianceNeverInspected
+		}
+	}
+	return statuses, nil
+}
+
+// =====================================================
+// HELPERS
+// =====================================================
+
+// geoQuery selects the organization's sites that have coordinates
+func (s *SiteService) geoQuery(ctx context.Context, organizationID string, filters map[string]interface{}) *gorm.DB {
+	query := database.Conn(ctx, s.db).Model(&models.Site{}).
+		Where("organization_id = ? AND latitude IS NOT NULL AND longitude IS NOT NULL", organizationID)
+	for _, key := range siteGeoFilterKeys {
+		if value, ok := filters[key].(string); ok && value != "" {
+			query = query.Where(fmt.Sprintf("%s = ?", key), value)
+		}
+	}
+	return query
+}
+
+// HaversineKm returns the great-circle distance between two points in kilometres
+func HaversineKm(from, to models.GeoPoint) float64 {
+	lat1 := from.Latitude * math.Pi / 180
+	lat2 := to.Latitude * math.Pi / 180
+	dLat := lat2 - lat1
+	dLng := (to.Longitude - from.Longitude) * math.Pi / 180
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// boundsAround returns a box that contains every point within radiusKm of center,
+// so the database can narrow candidates before exact distances are computed
+func boundsAround(center models.GeoPoint, radiusKm float64) models.GeoBounds {
+	latDelta := radiusKm / earthRadiusKm * 180 / math.Pi
+	bounds := models.GeoBounds{
+		MinLatitude:  math.Max(-90, center.Latitude-latDelta),
+		MaxLatitude:  math.Min(90, center.Latitude+latDelta),
+		MinLongitude: -180,
+		MaxLongitude: 180,
+	}
+
+	// Near the poles, or for very large radii, every longitude is in range
+	cosLat := math.Cos(center.Latitude * math.Pi / 180)
+	if bounds.MinLatitude == -90 || bounds.MaxLatitude == 90 || cosLat <= 0 {
+		return bounds
+	}
+	// The circle's widest point lies poleward of the centre, so scaling latDelta by
+	// 1/cos(lat) is too narrow; the tangent meridian is asin(sin(r/R)/cos(lat)) away
+	ratio := math.Sin(radiusKm/earthRadiusKm) / cosLat
+	if ratio >= 1 {
+		return bounds
+	}
+	lngDelta := math.Asin(ratio) * 180 / math.Pi
+
+	bounds.MinLongitude = normalizeLongitude(center.Longitude - lngDelta)
+	bounds.MaxLongitude = normalizeLongitude(center.Longitude + lngDelta)
+	return bounds
+}
+
+// applyBoundsFilter restricts a site query to a bounding box, handling boxes that
+// cross the antimeridian
+func applyBoundsFilter(query *gorm.DB, bounds models.GeoBounds) *gorm.DB {
+	query = query.Where("latitude BETWEEN ? AND ?", bounds.MinLatitude, bounds.MaxLatitude)
+	if bounds.MinLongitude <= bounds.MaxLongitude {
+		return query.Where("longitude BETWEEN ? AND ?", bounds.MinLongitude, bounds.MaxLongitude)
+	}
+	return query.Where("(longitude >= ? OR longitude <= ?)", bounds.MinLongitude, bounds.MaxLongitude)
+}
+
+func normalizeLongitude(longitude float64) float64 {
+	for longitude > 180 {
+		longitude -= 360
+	}
+	for longitude < -180 {
+		longitude += 360
+	}
+	return longitude
+}
+
+func validateGeoPoint(point models.GeoPoint) error {
+	if math.IsNaN(point.Latitude) || math.IsNaN(point.Longitude) ||
+		point.Latitude < -90 || point.Latitude > 90 || point.Longitude < -180 || point.Longitude > 180 {
+		return fmt.Errorf("%w: latitude must be within ±90 and longitude within ±180", ErrInvalidCoordinates)
+	}
+	return nil
+}
+
+func validateGeoBounds(bounds models.GeoBounds) error {
+	if err := validateGeoPoint(models.GeoPoint{Latitude: bounds.MinLatitude, Longitude: bounds.MinLongitude}); err != nil {
+		return err
+	}
+	if err := validateGeoPoint(models.GeoPoint{Latitude: bounds.MaxLatitude, Longitude: bounds.MaxLongitude}); err != nil {
+		return err
+	}
+	if bounds.MinLatitude > bounds.MaxLatitude {
+		return fmt.Errorf("%w: min_latitude is greater than max_latitude", ErrInvalidCoordinates)
+	}
+	return nil
+}
+
+func sortByDistance(sites []models.SiteWithDistance) {
+	sort.SliceStable(sites, func(i, j int) bool {
+		return sites[i].DistanceKm < sites[j].DistanceKm
+	})
+}
+
+// roundKm keeps distances to metre precision in responses
+func roundKm(distance float64) float64 {
+	return math.Round(distance*1000) / 1000
+}
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"resource-mgmt/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// siteGeoTestFixture is three active sites around central London, sites that geo queries
+// must skip, and two Pacific sites either side of the antimeridian
+type siteGeoTestFixture struct {
+	db                              *gorm.DB
+	service                         *SiteService
+	trafalgar, kingsCross, heathrow *models.Site
+	fiji, samoa                     *models.Site
+}
+
+// londonCenter is Charing Cross, a few hundred metres from Trafalgar
+var londonCenter = models.GeoPoint{Latitude: 51.5074, Longitude: -0.1278}
+
+func newSiteGeoTestFixture(t *testing.T) *siteGeoTestFixture {
+	db := setupServiceTestDB(t, &models.Site{}, &models.Inspection{}, &models.InspectorWorkload{})
+	f := &siteGeoTestFixture{db: db, service: NewSiteService(db)}
+
+	createSite := func(org, name, status string, lat, lng float64) *models.Site {
+		site := &models.Site{ID: uuid.NewString(), OrganizationID: org, Name: name, Address: name, Status: status, Latitude: &lat, Longitude: &lng}
+		require.NoError(t, db.Create(site).Error)
+		return site
+	}
+
+	f.trafalgar = createSite("org-a", "Trafalgar", "active", 51.5080, -0.1281)
+	f.kingsCross = createSite("org-a", "Kings Cross", "active", 51.5308, -0.1238) // ~2.6 km
+	f.heathrow = createSite("org-a", "Heathrow", "active", 51.4700, -0.4543)      // ~23 km
+	createSite("org-a", "Closed depot", "inactive", 51.5100, -0.1300)
+	createSite("org-b", "Other org", "active", 51.5081, -0.1280)
+	require.NoError(t, db.Create(&models.Site{ID: uuid.NewString(), OrganizationID: "org-a", Name: "No coords", Address: "x", Status: "active"}).Error)
+
+	f.fiji = createSite("org-a", "Suva", "active", -18.1416, 178.4419)
+	f.samoa = createSite("org-a", "Apia", "active", -13.8333, -171.7667)
+	return f
+}
+
+// addInspections makes Trafalgar overdue, Kings Cross due in three days and Heathrow
+// inspected a month ago
+func (f *siteGeoTestFixture) addInspections(t *testing.T) {
+	past := time.Now().AddDate(0, 0, -2)
+	soon := time.Now().AddDate(0, 0, 3)
+	completedAt := time.Now().AddDate(0, 0, -30)
+	require.NoError(t, f.db.Create(&models.Inspection{OrganizationID: "org-a", TemplateID: uuid.New(), InspectorID: "inspector-1", SiteID: f.trafalgar.ID, Status: "assigned", DueDate: &past}).Error)
+	require.NoError(t, f.db.Create(&models.Inspection{OrganizationID: "org-a", TemplateID: uuid.New(), InspectorID: "inspector-1", SiteID: f.kingsCross.ID, Status: "draft", DueDate: &soon}).Error)
+	require.NoError(t, f.db.Create(&models.Inspection{OrganizationID: "org-a", TemplateID: uuid.New(), InspectorID: "inspector-2", SiteID: f.heathrow.ID, Status: "completed", CompletedAt: &completedAt}).Error)
+}
+
+func TestHaversineKm(t *testing.T) {
+	assert.InDelta(t, 2.57, HaversineKm(models.GeoPoint{Latitude: 51.5080, Longitude: -0.1281}, models.GeoPoint{Latitude: 51.5308, Longitude: -0.1238}), 0.05)
+}
+
+func TestSiteService_FindSitesWithinRadius(t *testing.T) {
+	f := newSiteGeoTestFixture(t)
+
+	nearby, err := f.service.FindSitesWithinRadius(context.Background(), "org-a", londonCenter, 5, map[string]interface{}{"status": "active"}, 0)
+	require.NoError(t, err)
+	require.Len(t, nearby, 2)
+	assert.Equal(t, f.trafalgar.ID, nearby[0].ID)
+	assert.Equal(t, f.kingsCross.ID, nearby[1].ID)
+	assert.Less(t, nearby[0].DistanceKm, nearby[1].DistanceKm)
+}
+
+func TestSiteService_FindSitesWithinRadiusValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		center   models.GeoPoint
+		radiusKm float64
+		wantErr  error
+	}{
+		{"latitude out of range", models.GeoPoint{Latitude: 95, Longitude: 0}, 5, ErrInvalidCoordinates},
+		{"longitude out of range", models.GeoPoint{Latitude: 0, Longitude: -181}, 5, ErrInvalidCoordinates},
+		{"zero radius", londonCenter, 0, ErrInvalidRadius},
+		{"negative radius", londonCenter, -1, ErrInvalidRadius},
+		{"radius too large", londonCenter, maxSearchRadiusKm + 1, ErrInvalidRadius},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSiteGeoTestFixture(t)
+			_, err := f.service.FindSitesWithinRadius(context.Background(), "org-a", tt.center, tt.radiusKm, nil, 0)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestSiteService_FindNearestSitesWidensSearch(t *testing.T) {
+	f := newSiteGeoTestFixture(t)
+
+	nearest, err := f.service.FindNearestSites(context.Background(), "org-a", londonCenter, 3)
+	require.NoError(t, err)
+	require.Len(t, nearest, 3)
+	assert.Equal(t, f.heathrow.ID, nearest[2].ID, "inactive sites are skipped and the search widens")
+}
+
+func TestSiteService_FindSitesWithinRadiusAtHighLatitude(t *testing.T) {
+	f := newSiteGeoTestFixture(t)
+	lat, lng := 65.6, 37.0
+	arkhangelsk := &models.Site{ID: uuid.NewString(), OrganizationID: "org-a", Name: "Arkhangelsk", Address: "Arkhangelsk", Status: "active", Latitude: &lat, Longitude: &lng}
+	require.NoError(t, f.db.Create(arkhangelsk).Error)
+
+	// ~1948 km away, further east than the radius scaled by 1/cos(60°) reaches
+	center := models.GeoPoint{Latitude: 60, Longitude: 0}
+	require.InDelta(t, 1947.6, HaversineKm(center, models.GeoPoint{Latitude: lat, Longitude: lng}), 0.5)
+
+	nearby, err := f.service.FindSitesWithinRadius(context.Background(), "org-a", center, 2000, nil, 0)
+	require.NoError(t, err)
+	var ids []string
+	for _, site := range nearby {
+		ids = append(ids, site.ID)
+	}
+	assert.Contains(t, ids, arkhangelsk.ID)
+}
+
+func TestSiteService_GeoQueriesAcrossAntimeridian(t *testing.T) {
+	tests := []struct {
+		name  string
+		query func(f *siteGeoTestFixture) ([]string, error)
+	}{
+		{"radius", func(f *siteGeoTestFixture) ([]string, error) {
+			sites, err := f.service.FindSitesWithinRadius(context.Background(), "org-a", models.GeoPoint{Latitude: -16, Longitude: 179.9}, 1200, nil, 0)
+			var ids []string
+			for _, site := range sites {
+				ids = append(ids, site.ID)
+			}
+			return ids, err
+		}},
+		{"viewport", func(f *siteGeoTestFixture) ([]string, error) {
+			sites, err := f.service.FindSitesInBounds(context.Background(), "org-a",
+				models.GeoBounds{MinLatitude: -20, MinLongitude: 170, MaxLatitude: -10, MaxLongitude: -170}, nil, 0)
+			var ids []string
+			for _, site := range sites {
+				ids = append(ids, site.ID)
+			}
+			return ids, err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSiteGeoTestFixture(t)
+			ids, err := tt.query(f)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, []string{f.fiji.ID, f.samoa.ID}, ids)
+		})
+	}
+}
+
+func TestSiteService_GeoJSONColoursSitesByCompliance(t *testing.T) {
+	f := newSiteGeoTestFixture(t)
+	f.addInspections(t)
+
+	collection, err := f.service.GetSitesGeoJSON(context.Background(), "org-a", nil, map[string]interface{}{"status": "active"}, true)
+	require.NoError(t, err)
+	assert.Equal(t, "FeatureCollection", collection.Type)
+	require.Len(t, collection.Features, 5)
+	byID := make(map[string]models.GeoJSONFeature)
+	for _, feature := range collection.Features {
+		byID[feature.ID] = feature
+	}
+	assert.Equal(t, []float64{-0.1281, 51.5080}, byID[f.trafalgar.ID].Geometry.Coordinates)
+
+	tests := []struct {
+		name string
+		site *models.Site
+		want string
+	}{
+		{"overdue", f.trafalgar, models.SiteComplianceOverdue},
+		{"due soon", f.kingsCross, models.SiteComplianceDueSoon},
+		{"compliant", f.heathrow, models.SiteComplianceCompliant},
+		{"never inspected", f.fiji, models.SiteComplianceNeverInspected},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, byID[tt.site.ID].Properties["compliance_status"])
+		})
+	}
+}
+
+func TestSiteService_GetDueNearby(t *testing.T) {
+	tests := []struct {
+		name     string
+		radiusKm float64
+		want     func(f *siteGeoTestFixture) []string
+	}{
+		{"defaults to the inspector's travel distance", 0, func(f *siteGeoTestFixture) []string { return []string{f.trafalgar.ID} }},
+		{"explicit radius", 10, func(f *siteGeoTestFixture) []string { return []string{f.trafalgar.ID, f.kingsCross.ID} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSiteGeoTestFixture(t)
+			f.addInspections(t)
+			require.NoError(t, f.db.Create(&models.InspectorWorkload{ID: uuid.NewString(), OrganizationID: "org-a", InspectorID: "inspector-1", MaxTravelDistance: 2}).Error)
+
+			due, err := f.service.GetDueNearby(context.Background(), "org-a", "inspector-1", londonCenter, tt.radiusKm, time.Now().AddDate(0, 0, 7))
+			require.NoError(t, err)
+			var ids []string
+			for _, item := range due {
+				ids = append(ids, item.Site.ID)
+			}
+			assert.Equal(t, tt.want(f), ids, "nearest first")
+		})
+	}
+}
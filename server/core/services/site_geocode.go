@@ -0,0 +1,211 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"resource-mgmt/models"
+	"resource-mgmt/pkg/database"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrGeocodingDisabled is returned when a geocode job is requested but no provider is configured
+	ErrGeocodingDisabled = errors.New("geocoding is not configured")
+	// ErrSiteGeocodeJobNotFound is returned when a job doesn't exist in the caller's organization
+	ErrSiteGeocodeJobNotFound = errors.New("site geocode job not found")
+)
+
+const (
+	// siteGeocodeTimeout bounds the lookup done inline when a site is saved
+	siteGeocodeTimeout = 10 * time.Second
+	// siteGeocodeProgressInterval is how many sites pass between job progress updates
+	siteGeocodeProgressInterval = 10
+)
+
+// siteAddressColumns are the columns GetFullAddress is built from
+var siteAddressColumns = []string{"address", "city", "state", "zip_code", "country"}
+
+// geocodeSite fills in a site's coordinates from its address. Sites with manual
+// coordinates are left alone. It reports whether coordinates were set.
+func (s *SiteService) geocodeSite(ctx context.Context, site *models.Site) (bool, error) {
+	if s.geocoder == nil || site.CoordinatesSource == models.CoordinatesSourceManual || site.Address == "" {
+		return false, nil
+	}
+
+	result, err := s.geocoder.Geocode(ctx, site.GetFullAddress())
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	site.Latitude = &result.Latitude
+	site.Longitude = &result.Longitude
+	site.CoordinatesSource = models.CoordinatesSourceGeocoded
+	site.GeocodedAt = &now
+	return true, nil
+}
+
+// geocodeOnSave geocodes a site being created or updated. Geocoding is best effort: a
+// failed lookup leaves the site without coordinates for the batch job to retry.
+func (s *SiteService) geocodeOnSave(site *models.Site) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), siteGeocodeTimeout)
+	defer cancel()
+
+	geocoded, err := s.geocodeSite(ctx, site)
+	if err != nil && !errors.Is(err, ErrAddressNotFound) {
+		log.Printf("Failed to geocode site %s: %v", site.ID, err)
+	}
+	return geocoded
+}
+
+// saveGeocodedCoordinates writes a site's geocoded coordinates unless a manual override
+// was saved since the site was read, reporting whether they were written
+func saveGeocodedCoordinates(db *gorm.DB, site *models.Site) (bool, error) {
+	result := db.Model(&models.Site{}).
+		Where("id = ? AND (coordinates_source IS NULL OR coordinates_source <> ?)", site.ID, models.CoordinatesSourceManual).
+		Updates(siteCoordinateUpdates(site))
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to save site coordinates: %v", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// siteCoordinateUpdates returns the columns geocodeSite sets on a site
+func siteCoordinateUpdates(site *models.Site) map[string]interface{} {
+	return map[string]interface{}{
+		"latitude":           site.Latitude,
+		"longitude":          site.Longitude,
+		"coordinates_source": site.CoordinatesSource,
+		"geocoded_at":        site.GeocodedAt,
+	}
+}
+
+// =====================================================
+// BATCH RE-GEOCODING
+// =====================================================
+
+// StartGeocodeJob queues a background job that geocodes the organization's sites that
+// have no coordinates. With force, coordinates found by earlier geocoding are refreshed
+// too. Manual coordinates are never touched.
+func (s *SiteService) StartGeocodeJob(ctx context.Context, organizationID, userID string, force bool) (*models.SiteGeocodeJob, error) {
+	if s.geocoder == nil {
+		return nil, ErrGeocodingDisabled
+	}
+
+	db := database.Conn(ctx, s.db)
+	query := db.Model(&models.Site{}).
+		Where("organization_id = ?", organizationID).
+		Where("coordinates_source IS NULL OR coordinates_source <> ?", models.CoordinatesSourceManual)
+	if !force {
+		query = query.Where("latitude IS NULL OR longitude IS NULL")
+	}
+	var siteIDs []string
+	if err := query.Order("name ASC").Pluck("id", &siteIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to find sites to geocode: %v", err)
+	}
+
+	job := &models.SiteGeocodeJob{
+		OrganizationID: organizationID,
+		Status:         models.SiteGeocodeStatusPending,
+		Force:          force,
+		TotalSites:     len(siteIDs),
+		CreatedBy:      userID,
+	}
+	if err := db.Create(job).Error; err != nil {
+		return nil, fmt.Errorf("failed to create geocode job: %v", err)
+	}
+
+	// The job outlives the request, so it must not inherit its context or transaction
+	go s.runGeocodeJob(context.Background(), job.ID, organizationID, siteIDs)
+
+	return job, nil
+}
+
+// GetGeocodeJob returns a geocode job and its progress
+func (s *SiteService) GetGeocodeJob(ctx context.Context, organizationID, jobID string) (*models.SiteGeocodeJob, error) {
+	var job models.SiteGeocodeJob
+	if err := database.Conn(ctx, s.db).
+		Where("id = ? AND organization_id = ?", jobID, organizationID).
+		First(&job).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSiteGeocodeJobNotFound
+		}
+		return nil, fmt.Errorf("failed to get geocode job: %v", err)
+	}
+	return &job, nil
+}
+
+// runGeocodeJob geocodes each site in turn, saving as it goes so a long job that fails
+// part way keeps the coordinates it found
+func (s *SiteService) runGeocodeJob(ctx context.Context, jobID, organizationID string, siteIDs []string) {
+	jobs := s.db.WithContext(ctx).Model(&models.SiteGeocodeJob{}).Where("id = ?", jobID)
+	jobs.Session(&gorm.Session{}).Updates(map[string]interface{}{"status": models.SiteGeocodeStatusRunning, "started_at": time.Now()})
+
+	var geocoded, notFound, failed int
+	for i, siteID := range siteIDs {
+		var site models.Site
+		err := s.db.WithContext(ctx).Where("id = ? AND organization_id = ?", siteID, organizationID).First(&site).Error
+		if err == nil {
+			var ok bool
+			if ok, err = s.geocodeSite(ctx, &site); ok {
+				if ok, err = saveGeocodedCoordinates(s.db.WithContext(ctx), &site); err == nil && !ok {
+					// A manual override was saved while the address was being looked up
+					site.CoordinatesSource = models.CoordinatesSourceManual
+				}
+			}
+		}
+
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			// Deleted since the job was queued
+		case errors.Is(err, ErrAddressNotFound):
+			notFound++
+		case err != nil:
+			log.Printf("Geocode job %s: site %s: %v", jobID, siteID, err)
+			failed++
+		case site.CoordinatesSource == models.CoordinatesSourceGeocoded:
+			geocoded++
+		}
+
+		if (i+1)%siteGeocodeProgressInterval == 0 {
+			jobs.Session(&gorm.Session{}).Updates(map[string]interface{}{
+				"processed_sites": i + 1,
+				"geocoded_count":  geocoded,
+				"not_found_count": notFound,
+				"failed_count":    failed,
+			})
+		}
+	}
+
+	if err := jobs.Session(&gorm.Session{}).Updates(map[string]interface{}{
+		"status":          models.SiteGeocodeStatusCompleted,
+		"processed_sites": len(siteIDs),
+		"geocoded_count":  geocoded,
+		"not_found_count": notFound,
+		"failed_count":    failed,
+		"finished_at":     time.Now(),
+	}).Error; err != nil {
+		log.Printf("Failed to record result of geocode job %s: %v", jobID, err)
+	}
+}
+
+// siteAddressValue returns the current value of one of siteAddressColumns
+func siteAddressValue(site *models.Site, column string) string {
+	switch column {
+	case "address":
+		return site.Address
+	case "city":
+		return site.City
+	case "state":
+		return site.State
+	case "zip_code":
+		return site.ZipCode
+	case "country":
+		return site.Country
+	}
+	return ""
+}
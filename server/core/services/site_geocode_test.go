@@ -0,0 +1,271 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"resource-mgmt/models"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// countingGeocoder records how often the wrapped provider is asked
+type countingGeocoder struct {
+	Geocoder
+	calls int
+}
+
+func (g *countingGeocoder) Geocode(ctx context.Context, address string) (*models.GeocodeResult, error) {
+	g.calls++
+	return g.Geocoder.Geocode(ctx, address)
+}
+
+// geocodeTestFixture is a site service geocoding through a cache from a static file that
+// knows 10 Downing St and 221B Baker St
+type geocodeTestFixture struct {
+	db       *gorm.DB
+	service  *SiteService
+	provider *countingGeocoder
+}
+
+func newGeocodeTestFixture(t *testing.T) *geocodeTestFixture {
+	db := setupServiceTestDB(t, &models.Site{}, &models.GeocodeCacheEntry{}, &models.SiteGeocodeJob{}, &models.SiteCustomField{})
+
+	path := filepath.Join(t.TempDir(), "addresses.csv")
+	require.NoError(t, os.WriteFile(path, []byte("address,latitude,longitude\n"+
+		"\"10 Downing St, London, UK\",51.5034,-0.1276\n"+
+		"\"221B Baker St, London, UK\",51.5238,-0.1586\n"), 0o600))
+	static, err := LoadStaticGeocoder(path)
+	require.NoError(t, err)
+
+	f := &geocodeTestFixture{db: db, service: NewSiteService(db), provider: &countingGeocoder{Geocoder: static}}
+	f.service.geocoder = NewCachingGeocoder(db, f.provider)
+	return f
+}
+
+// createSite creates a London site at address, with manual coordinates when lat is given
+func (f *geocodeTestFixture) createSite(t *testing.T, name, address string, lat *float64) *models.Site {
+	site := &models.Site{ID: uuid.NewString(), OrganizationID: "org-a", Name: name, Address: address, City: "London", Country: "UK"}
+	if lat != nil {
+		lng := -3.0
+		site.Latitude, site.Longitude = lat, &lng
+	}
+	created, err := f.service.CreateSite(context.Background(), site)
+	require.NoError(t, err)
+	return created
+}
+
+func TestSiteService_CreateSiteGeocodesAddress(t *testing.T) {
+	f := newGeocodeTestFixture(t)
+
+	site := f.createSite(t, "Number 10", "10 Downing St", nil)
+	require.NotNil(t, site.Latitude)
+	assert.InDelta(t, 51.5034, *site.Latitude, 1e-6)
+	assert.Equal(t, models.CoordinatesSourceGeocoded, site.CoordinatesSource)
+	assert.NotNil(t, site.GeocodedAt)
+}
+
+func TestSiteService_CreateSiteWithUnknownAddress(t *testing.T) {
+	f := newGeocodeTestFixture(t)
+
+	site, err := f.service.CreateSite(context.Background(), &models.Site{ID: uuid.NewString(), OrganizationID: "org-a", Name: "Nowhere", Address: "1 Nowhere Rd", City: "Atlantis"})
+	require.NoError(t, err, "a failed lookup doesn't fail the save")
+	assert.Nil(t, site.Latitude)
+	assert.Empty(t, site.CoordinatesSource)
+}
+
+func TestCachingGeocoder_AnswersRepeatLookupsFromCache(t *testing.T) {
+	tests := []struct {
+		name    string
+		first   string
+		repeat  string
+		wantErr error
+	}{
+		{"hit, normalized", "10 Downing St, London, UK", "10  downing st, London, UK", nil},
+		{"miss", "1 Nowhere Rd, Atlantis", "1 Nowhere Rd, Atlantis", ErrAddressNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newGeocodeTestFixture(t)
+			ctx := context.Background()
+
+			_, err := f.service.geocoder.Geocode(ctx, tt.first)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			_, err = f.service.geocoder.Geocode(ctx, tt.repeat)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, 1, f.provider.calls)
+		})
+	}
+}
+
+func TestSiteService_UpdateSiteCoordinateSources(t *testing.T) {
+	lat := 40.0
+	tests := []struct {
+		name       string
+		manual     bool
+		updates    map[string]interface{}
+		wantSource string
+		wantLat    float64
+	}{
+		{"address change re-geocodes", false, map[string]interface{}{"address": "221B Baker St", "coordinates_source": "geocoded"},
+			models.CoordinatesSourceGeocoded, 51.5238},
+		{"address change keeps manual coordinates", true, map[string]interface{}{"address": "221B Baker St"},
+			models.CoordinatesSourceManual, 40.0},
+		{"setting coordinates makes them manual", false, map[string]interface{}{"latitude": 51.6, "longitude": -0.2},
+			models.CoordinatesSourceManual, 51.6},
+		{"clearing manual coordinates re-geocodes", true, map[string]interface{}{"latitude": nil, "longitude": nil},
+			models.CoordinatesSourceGeocoded, 51.5034},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newGeocodeTestFixture(t)
+			var manual *float64
+			if tt.manual {
+				manual = &lat
+			}
+			site := f.createSite(t, "Depot", "10 Downing St", manual)
+
+			updated, err := f.service.UpdateSite(context.Background(), site.ID, "org-a", tt.updates)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantSource, updated.CoordinatesSource)
+			require.NotNil(t, updated.Latitude)
+			assert.InDelta(t, tt.wantLat, *updated.Latitude, 1e-6)
+			assert.Equal(t, tt.wantSource == models.CoordinatesSourceGeocoded, updated.GeocodedAt != nil)
+		})
+	}
+}
+
+func TestSiteService_GeocodeJobFillsMissingCoordinates(t *testing.T) {
+	f := newGeocodeTestFixture(t)
+	ctx := context.Background()
+
+	// Sites created before geocoding was configured
+	for _, address := range []string{"221B Baker St", "1 Nowhere Rd"} {
+		require.NoError(t, f.db.Create(&models.Site{ID: uuid.NewString(), OrganizationID: "org-a", Name: address, Address: address, City: "London", Country: "UK"}).Error)
+	}
+	job := &models.SiteGeocodeJob{ID: uuid.NewString(), OrganizationID: "org-a", Status: models.SiteGeocodeStatusPending}
+	require.NoError(t, f.db.Create(job).Error)
+
+	var pending []string
+	require.NoError(t, f.db.Model(&models.Site{}).Where("organization_id = ? AND latitude IS NULL", "org-a").Pluck("id", &pending).Error)
+	require.Len(t, pending, 2)
+	f.service.runGeocodeJob(ctx, job.ID, "org-a", pending)
+
+	stored, err := f.service.GetGeocodeJob(ctx, "org-a", job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.SiteGeocodeStatusCompleted, stored.Status)
+	assert.Equal(t, 1, stored.GeocodedCount)
+	assert.Equal(t, 1, stored.NotFoundCount)
+	assert.Equal(t, 100, stored.Progress())
+}
+
+func TestSiteService_StartGeocodeJobWithoutGeocoder(t *testing.T) {
+	f := newGeocodeTestFixture(t)
+
+	_, err := NewSiteService(f.db).StartGeocodeJob(context.Background(), "org-a", "admin-1", false)
+	assert.ErrorIs(t, err, ErrGeocodingDisabled)
+}
+
+// overridingGeocoder saves a manual override on the site while its address is being
+// looked up, as a user editing the site during a slow lookup would
+type overridingGeocoder struct {
+	db     *gorm.DB
+	siteID string
+}
+
+func (g *overridingGeocoder) Name() string { return "overriding" }
+
+func (g *overridingGeocoder) Geocode(ctx context.Context, address string) (*models.GeocodeResult, error) {
+	err := g.db.Model(&models.Site{}).Where("id = ?", g.siteID).
+		Updates(map[string]interface{}{"latitude": 40.0, "longitude": -3.0, "coordinates_source": models.CoordinatesSourceManual}).Error
+	return &models.GeocodeResult{Latitude: 51.5, Longitude: -0.1, Provider: g.Name()}, err
+}
+
+func TestSiteService_GeocodingNeverOverwritesManualOverride(t *testing.T) {
+	tests := []struct {
+		name    string
+		geocode func(t *testing.T, service *SiteService, site *models.Site)
+	}{
+		{"batch job", func(t *testing.T, service *SiteService, site *models.Site) {
+			job := &models.SiteGeocodeJob{ID: uuid.NewString(), OrganizationID: "org-a", Status: models.SiteGeocodeStatusPending}
+			require.NoError(t, service.db.Create(job).Error)
+			service.runGeocodeJob(context.Background(), job.ID, "org-a", []string{site.ID})
+
+			stored, err := service.GetGeocodeJob(context.Background(), "org-a", job.ID)
+			require.NoError(t, err)
+			assert.Zero(t, stored.GeocodedCount, "the site was skipped")
+		}},
+		{"address change", func(t *testing.T, service *SiteService, site *models.Site) {
+			_, err := service.UpdateSite(context.Background(), site.ID, "org-a", map[string]interface{}{"address": "221B Baker St"})
+			require.NoError(t, err)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupServiceTestDB(t, &models.Site{}, &models.SiteGeocodeJob{}, &models.SiteCustomField{})
+			site := &models.Site{ID: uuid.NewString(), OrganizationID: "org-a", Name: "Depot", Address: "10 Downing St", City: "London", Country: "UK", Status: "active"}
+			require.NoError(t, db.Create(site).Error)
+
+			service := NewSiteService(db)
+			service.geocoder = &overridingGeocoder{db: db, siteID: site.ID}
+			tt.geocode(t, service, site)
+
+			var stored models.Site
+			require.NoError(t, db.First(&stored, "id = ?", site.ID).Error)
+			assert.Equal(t, models.CoordinatesSourceManual, stored.CoordinatesSource)
+			require.NotNil(t, stored.Latitude)
+			assert.Equal(t, 40.0, *stored.Latitude)
+			assert.Nil(t, stored.GeocodedAt)
+		})
+	}
+}
+
+func TestSiteService_UpdateSiteCoordinatesSetTogether(t *testing.T) {
+	tests := []struct {
+		name    string
+		updates map[string]interface{}
+		wantErr bool
+	}{
+		{"both set", map[string]interface{}{"latitude": 51.6, "longitude": -0.2}, false},
+		{"both cleared", map[string]interface{}{"latitude": nil, "longitude": nil}, false},
+		{"longitude cleared", map[string]interface{}{"latitude": 51.6, "longitude": nil}, true},
+		{"latitude cleared", map[string]interface{}{"latitude": nil, "longitude": -0.2}, true},
+		{"latitude only", map[string]interface{}{"latitude": 51.6}, true},
+		{"longitude only", map[string]interface{}{"longitude": nil}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupServiceTestDB(t, &models.Site{}, &models.SiteCustomField{})
+			lat, lng := 40.0, -3.0
+			site := &models.Site{ID: uuid.NewString(), OrganizationID: "org-a", Name: "Depot", Address: "10 Downing St", Status: "active",
+				Latitude: &lat, Longitude: &lng, CoordinatesSource: models.CoordinatesSourceManual}
+			require.NoError(t, db.Create(site).Error)
+
+			service := NewSiteService(db)
+			service.geocoder = nil
+			_, err := service.UpdateSite(context.Background(), site.ID, "org-a", tt.updates)
+			if !tt.wantErr {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrInvalidCoordinates)
+
+			var stored models.Site
+			require.NoError(t, db.First(&stored, "id = ?", site.ID).Error)
+			assert.Equal(t, 40.0, *stored.Latitude)
+			assert.Equal(t, -3.0, *stored.Longitude)
+		})
+	}
+}
@@ -0,0 +1,733 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/mail"
+	"resource-mgmt/models"
+	"resource-mgmt/pkg/database"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidSiteImport is returned when an import file can't be read or its columns can't be mapped
+	ErrInvalidSiteImport = errors.New("invalid site import")
+	// ErrSiteImportHasErrors is returned when a real import is attempted on a file with row errors
+	ErrSiteImportHasErrors = errors.New("site import has validation errors")
+	// ErrSiteImportJobNotFound is returned when a job doesn't exist in the caller's organization
+	ErrSiteImportJobNotFound = errors.New("site import job not found")
+)
+
+// siteImportFields are the site columns an import file can map, in export column order
+var siteImportFields = []string{
+	"external_ref", "name", "address", "city", "state", "zip_code", "country",
+	"latitude", "longitude", "type", "status",
+	"contact_name", "contact_email", "contact_phone", "notes", "geofence_radius_meters",
+}
+
+var validSiteStatuses = []string{"active", "inactive", "maintenance"}
+
+const (
+	// maxSiteImportRows bounds a single import file
+	maxSiteImportRows = 10000
+	// siteImportProgressInterval is how many rows pass between progress updates
+	siteImportProgressInterval = 50
+)
+
+// SiteImportService bulk imports and exports sites as CSV or XLSX
+type SiteImportService struct {
+	db *gorm.DB
+}
+
+func NewSiteImportService(db *gorm.DB) *SiteImportService {
+	return &SiteImportService{db: db}
+}
+
+// siteImportRow is one data row of an import file, keyed by site field. Custom fields are
+// keyed "custom.<key>" in Values and, once validated, by key in Custom.
+type siteImportRow struct {
+	Row       int
+	Values    map[string]string
+	Custom    map[string]interface{} // Validated custom field values; nil clears a field
+	TargetID  string                 // Existing site the row updates; empty to create
+	HasErrors bool
+}
+
+// =====================================================
+// PARSING AND VALIDATION
+// =====================================================
+
+// ParseSiteImport reads a CSV or XLSX file and maps its columns to site fields. mapping
+// maps a site field, or "custom.<key>" for a custom field, to the file's header for it;
+// unmapped fields are matched by header name.
+func (s *SiteImportService) ParseSiteImport(ctx context.Context, organizationID, format string, r io.Reader, mapping map[string]string) ([]siteImportRow, error) {
+	customFields, err := NewSiteCustomFieldService(s.db).GetFields(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	var records [][]string
+	switch format {
+	case "csv":
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		if records, err = reader.ReadAll(); err != nil {
+			return nil, fmt.Errorf("%w: failed to read CSV: %v", ErrInvalidSiteImport, err)
+		}
+	case "xlsx":
+		workbook, err := excelize.OpenReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to read XLSX: %v", ErrInvalidSiteImport, err)
+		}
+		defer workbook.Close()
+		if records, err = workbook.GetRows(workbook.GetSheetName(0)); err != nil {
+			return nil, fmt.Errorf("%w: failed to read XLSX: %v", ErrInvalidSiteImport, err)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported format %q", ErrInvalidSiteImport, format)
+	}
+
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%w: file is empty", ErrInvalidSiteImport)
+	}
+	if len(records)-1 > maxSiteImportRows {
+		return nil, fmt.Errorf("%w: at most %d rows can be imported at once", ErrInvalidSiteImport, maxSiteImportRows)
+	}
+
+	columns, err := resolveSiteImportColumns(records[0], mapping, customFields)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]siteImportRow, 0, len(records)-1)
+	for i, record := range records[1:] {
+		row := siteImportRow{Row: i + 2, Values: make(map[string]string, len(columns))}
+		blank := true
+		for field, index := range columns {
+			if index < len(record) {
+				row.Values[field] = strings.TrimSpace(record[index])
+				blank = blank && row.Values[field] == ""
+			}
+		}
+		if !blank {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+// resolveSiteImportColumns returns the column index of each mapped site field
+func resolveSiteImportColumns(header []string, mapping map[string]string, customFields []models.SiteCustomField) (map[string]int, error) {
+	byHeader := make(map[string]int, len(header))
+	for i, name := range header {
+		byHeader[normalizeImportHeader(name)] = i
+	}
+
+	fields := append([]string{}, siteImportFields...)
+	for _, field := range customFields {
+		fields = append(fields, customFieldColumnPrefix+field.Key)
+	}
+
+	columns := make(map[string]int)
+	for field, source := range mapping {
+		if !containsString(fields, field) {
+			return nil, fmt.Errorf("%w: unknown site field %q in mapping", ErrInvalidSiteImport, field)
+		}
+		index, ok := byHeader[normalizeImportHeader(source)]
+		if !ok {
+			return nil, fmt.Errorf("%w: column %q mapped to %s is not in the file", ErrInvalidSiteImport, source, field)
+		}
+		columns[field] = index
+	}
+	for _, field := range fields {
+		if _, mapped := mapping[field]; mapped {
+			continue
+		}
+		if index, ok := byHeader[field]; ok {
+			columns[field] = index
+		}
+	}
+
+	for _, field := range []string{"name", "address"} {
+		if _, ok := columns[field]; !ok {
+			return nil, fmt.Errorf("%w: no column for required field %s", ErrInvalidSiteImport, field)
+		}
+	}
+	return columns, nil
+}
+
+// ValidateSiteImport checks every row and finds duplicates by name or address, both within
+// the file and against the organization's existing sites. Rows are matched to the site they
+// update by external reference, or by site ID for files produced by ExportSites.
+func (s *SiteImportService) ValidateSiteImport(ctx context.Context, organizationID string, rows []siteImportRow) (*models.SiteImportPreview, error) {
+	customFields, err := NewSiteCustomFieldService(s.db).GetFields(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	var existing []models.Site
+	if err := database.Conn(ctx, s.db).
+		Select("id, external_ref, name, address, city, metadata").
+		Where("organization_id = ?", organizationID).
+		Find(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to load existing sites: %v", err)
+	}
+
+	byRef := make(map[string]string)
+	byID := make(map[string]bool)
+	existingByName := make(map[string]string)
+	existingByAddress := make(map[string]string)
+	for _, site := range existing {
+		byID[site.ID] = true
+		if site.ExternalRef != nil && *site.ExternalRef != "" {
+			byRef[*site.ExternalRef] = site.ID
+		}
+		existingByName[normalizeImportKey(site.Name)] = site.ID
+		existingByAddress[normalizeImportKey(site.Address+" "+site.City)] = site.ID
+	}
+	existingUnique := existingCustomFieldValues(existing, customFields)
+	uniqueRows := make(map[string]int)
+
+	preview := &models.SiteImportPreview{
+		TotalRows:  len(rows),
+		Errors:     []models.SiteImportRowError{},
+		Duplicates: []models.SiteImportDuplicate{},
+	}
+	refRows := make(map[string]int)
+	nameRows := make(map[string]int)
+	addressRows := make(map[string]int)
+
+	for i := range rows {
+		row := &rows[i]
+		rowErrors := validateSiteImportValues(row.Row, row.Values)
+
+		if ref := row.Values["external_ref"]; ref != "" {
+			if other, seen := refRows[ref]; seen {
+				rowErrors = append(rowErrors, models.SiteImportRowError{Row: row.Row, Field: "external_ref", Message: fmt.Sprintf("external_ref %q also appears on row %d", ref, other)})
+			}
+			refRows[ref] = row.Row
+			if id, ok := byRef[ref]; ok {
+				row.TargetID = id
+			} else if byID[ref] {
+				row.TargetID = ref
+			}
+		}
+
+		rowErrors = append(rowErrors, validateSiteImportCustomFields(row, customFields, existingUnique, uniqueRows)...)
+
+		if name := normalizeImportKey(row.Values["name"]); name != "" {
+			if id, ok := existingByName[name]; ok && id != row.TargetID {
+				preview.Duplicates = append(preview.Duplicates, models.SiteImportDuplicate{Row: row.Row, MatchedOn: "name", ExistingSiteID: id})
+			} else if other, ok := nameRows[name]; ok {
+				preview.Duplicates = append(preview.Duplicates, models.SiteImportDuplicate{Row: row.Row, MatchedOn: "name", OtherRow: other})
+			}
+			nameRows[name] = row.Row
+		}
+		if address := normalizeImportKey(row.Values["address"] + " " + row.Values["city"]); row.Values["address"] != "" {
+			if id, ok := existingByAddress[address]; ok && id != row.TargetID {
+				preview.Duplicates = append(preview.Duplicates, models.SiteImportDuplicate{Row: row.Row, MatchedOn: "address", ExistingSiteID: id})
+			} else if other, ok := addressRows[address]; ok {
+				preview.Duplicates = append(preview.Duplicates, models.SiteImportDuplicate{Row: row.Row, MatchedOn: "address", OtherRow: other})
+			}
+			addressRows[address] = row.Row
+		}
+
+		row.HasErrors = len(rowErrors) > 0
+		preview.Errors = append(preview.Errors, rowErrors...)
+		if row.HasErrors {
+			continue
+		}
+		preview.ValidRows++
+		if row.TargetID != "" {
+			preview.Updates++
+		} else {
+			preview.Creates++
+		}
+	}
+	return preview, nil
+}
+
+func validateSiteImportValues(row int, values map[string]string) []models.SiteImportRowError {
+	var rowErrors []models.SiteImportRowError
+	fail := func(field, message string) {
+		rowErrors = append(rowErrors, models.SiteImportRowError{Row: row, Field: field, Message: message})
+	}
+
+	for _, field := range []string{"name", "address"} {
+		if values[field] == "" {
+			fail(field, field+" is required")
+		}
+	}
+
+	latitude, hasLatitude := values["latitude"]
+	longitude, hasLongitude := values["longitude"]
+	if (latitude == "") != (longitude == "") && (hasLatitude || hasLongitude) {
+		fail("latitude", "latitude and longitude must be given together")
+	} else if latitude != "" {
+		lat, latErr := strconv.ParseFloat(latitude, 64)
+		lng, lngErr := strconv.ParseFloat(longitude, 64)
+		if latErr != nil || lngErr != nil || validateGeoPoint(models.GeoPoint{Latitude: lat, Longitude: lng}) != nil {
+			fail("latitude", "latitude and longitude must be valid decimal degrees")
+		}
+	}
+
+	if status := values["status"]; status != "" && !containsString(validSiteStatuses, strings.ToLower(status)) {
+		fail("status", "status must be one of "+strings.Join(validSiteStatuses, ", "))
+	}
+	if email := values["contact_email"]; email != "" {
+		if _, err := mail.ParseAddress(email); err != nil {
+			fail("contact_email", "contact_email is not a valid email address")
+		}
+	}
+	if radius := values["geofence_radius_meters"]; radius != "" {
+		if value, err := strconv.Atoi(radius); err != nil || value <= 0 {
+			fail("geofence_radius_meters", "geofence_radius_meters must be a positive whole number")
+		}
+	}
+	if len(values["external_ref"]) > 100 {
+		fail("external_ref", "external_ref must be at most 100 characters")
+	}
+	return rowErrors
+}
+
+// validateSiteImportCustomFields converts a row's custom field cells into typed values in
+// row.Custom. New sites are checked against every field; updates only against the columns
+// in the file, so a partial file can't clear fields it doesn't mention.
+func validateSiteImportCustomFields(row *siteImportRow, customFields []models.SiteCustomField, existingUnique map[string]string, uniqueRows map[string]int) []models.SiteImportRowError {
+	var rowErrors []models.SiteImportRowError
+	row.Custom = make(map[string]interface{})
+
+	for i := range customFields {
+		field := &customFields[i]
+		column := customFieldColumnPrefix + field.Key
+		raw, present := row.Values[column]
+		if !present && row.TargetID != "" {
+			continue
+		}
+
+		var value interface{}
+		if raw != "" {
+			value = raw
+			if coerced, ok := coerceCustomFieldValue(field, raw); ok {
+				value = coerced
+			}
+		}
+		normalized, message := checkCustomFieldValue(field, value)
+		if message != "" {
+			rowErrors = append(rowErrors, models.SiteImportRowError{Row: row.Row, Field: column, Message: field.Label + " " + message})
+			continue
+		}
+		row.Custom[field.Key] = normalized
+
+		if field.Unique && normalized != nil {
+			key := field.Key + "=" + customFieldText(normalized)
+			if siteID, ok := existingUnique[key]; ok && siteID != row.TargetID {
+				rowErrors = append(rowErrors, models.SiteImportRowError{Row: row.Row, Field: column, Message: fmt.Sprintf("%s %q is already used by another site", field.Label, customFieldText(normalized))})
+			} else if other, ok := uniqueRows[key]; ok {
+				rowErrors = append(rowErrors, models.SiteImportRowError{Row: row.Row, Field: column, Message: fmt.Sprintf("%s %q also appears on row %d", field.Label, customFieldText(normalized), other)})
+			}
+			uniqueRows[key] = row.Row
+		}
+	}
+	return rowErrors
+}
+
+// existingCustomFieldValues indexes existing sites by their unique custom field values,
+// keyed "<field>=<value>"
+func existingCustomFieldValues(sites []models.Site, customFields []models.SiteCustomField) map[string]string {
+	values := make(map[string]string)
+	for _, site := range sites {
+		metadata := decodeSiteMetadata(site.Metadata)
+		for _, field := range customFields {
+			if value, ok := metadata[field.Key]; ok && field.Unique && !isEmptyCustomFieldValue(value) {
+				values[field.Key+"="+customFieldText(value)] = site.ID
+			}
+		}
+	}
+	return values
+}
+
+// =====================================================
+// IMPORT JOBS
+// =====================================================
+
+// StartImport validates the rows and, when they are all valid, queues a background job that
+// writes them in a single transaction. Poll GetImportJob for progress. Re-running the same
+// file is safe: rows with an external reference update the site they created the first time.
+func (s *SiteImportService) StartImport(ctx context.Context, organizationID, userID, format, fileName string, rows []siteImportRow) (*models.SiteImportJob, *models.SiteImportPreview, error) {
+	preview, err := s.ValidateSiteImport(ctx, organizationID, rows)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(preview.Errors) > 0 {
+		return nil, preview, ErrSiteImportHasErrors
+	}
+
+	job := &models.SiteImportJob{
+		OrganizationID: organizationID,
+		Status:         models.SiteImportStatusPending,
+		Format:         format,
+		FileName:       fileName,
+		TotalRows:      len(rows),
+		CreatedBy:      userID,
+	}
+	// The job outlives the request, so it must not inherit its context or transaction.
+	// Its row is committed right away, or the import couldn't see it before the request ends.
+	if err := s.db.WithContext(ctx).Create(job).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to create import job: %v", err)
+	}
+
+	go s.runImport(context.Background(), job.ID, organizationID, userID, rows)
+
+	return job, preview, nil
+}
+
+// GetImportJob returns an import job and its progress
+func (s *SiteImportService) GetImportJob(ctx context.Context, organizationID, jobID string) (*models.SiteImportJob, error) {
+	var job models.SiteImportJob
+	if err := database.Conn(ctx, s.db).
+		Where("id = ? AND organization_id = ?", jobID, organizationID).
+		First(&job).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSiteImportJobNotFound
+		}
+		return nil, fmt.Errorf("failed to get import job: %v", err)
+	}
+	return &job, nil
+}
+
+// FailInterruptedImports marks import jobs left pending or running by a process that
+// stopped as failed. Call it at startup, before this process starts any jobs itself;
+// their transactions were rolled back, so nothing but the job status was written.
+func (s *SiteImportService) FailInterruptedImports(ctx context.Context) (int64, error) {
+	result := s.db.WithContext(ctx).Model(&models.SiteImportJob{}).
+		Where("status IN ?", []string{models.SiteImportStatusPending, models.SiteImportStatusRunning}).
+		Updates(siteImportFailure("import was interrupted by a server restart"))
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to fail interrupted imports: %v", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// runImport writes every row in one transaction. Progress is recorded outside the
+// transaction so pollers can see it; on failure nothing is written but the job status.
+func (s *SiteImportService) runImport(ctx context.Context, jobID, organizationID, userID string, rows []siteImportRow) {
+	jobs := s.db.WithContext(ctx).Model(&models.SiteImportJob{}).Where("id = ?", jobID)
+
+	// A panic would otherwise leave the job running forever
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Site import %s panicked: %v", jobID, r)
+			if err := updateImportJob(jobs, siteImportFailure(fmt.Sprintf("import stopped unexpectedly: %v", r))); err != nil {
+				log.Printf("Failed to record result of site import %s: %v", jobID, err)
+			}
+		}
+	}()
+
+	startedAt := time.Now()
+	if err := updateImportJob(jobs, map[string]interface{}{"status": models.SiteImportStatusRunning, "started_at": startedAt}); err != nil {
+		// Nobody could follow or find the result of an import without its job
+		log.Printf("Site import %s not started: %v", jobID, err)
+		return
+	}
+
+	var created, updated int
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i, row := range rows {
+			if row.TargetID != "" {
+				updates := siteImportUpdates(row.Values, userID)
+				if len(row.Custom) > 0 {
+					var site models.Site
+					if err := tx.Select("id, metadata").Where("id = ?", row.TargetID).First(&site).Error; err != nil {
+						return fmt.Errorf("row %d: failed to load site: %v", row.Row, err)
+					}
+					updates["metadata"] = mergeSiteMetadata(site.Metadata, row.Custom)
+				}
+				result := tx.Model(&models.Site{}).
+					Where("id = ? AND organization_id = ?", row.TargetID, organizationID).
+					Updates(updates)
+				if result.Error != nil {
+					return fmt.Errorf("row %d: failed to update site: %v", row.Row, result.Error)
+				}
+				updated++
+			} else {
+				site := siteFromImportRow(row.Values)
+				site.Metadata = mergeSiteMetadata(nil, row.Custom)
+				site.OrganizationID = organizationID
+				site.CreatedBy = userID
+				site.UpdatedBy = userID
+				if err := tx.Create(site).Error; err != nil {
+					return fmt.Errorf("row %d: failed to create site: %v", row.Row, err)
+				}
+				created++
+			}
+
+			if (i+1)%siteImportProgressInterval == 0 {
+				jobs.Session(&gorm.Session{}).Update("processed_rows", i+1)
+			}
+		}
+		return nil
+	})
+
+	var result map[string]interface{}
+	if err != nil {
+		log.Printf("Site import %s failed: %v", jobID, err)
+		result = siteImportFailure(err.Error())
+	} else {
+		result = map[string]interface{}{
+			"status":         models.SiteImportStatusCompleted,
+			"finished_at":    time.Now(),
+			"processed_rows": len(rows),
+			"created_count":  created,
+			"updated_count":  updated,
+		}
+	}
+	if err := updateImportJob(jobs, result); err != nil {
+		log.Printf("Failed to record result of site import %s: %v", jobID, err)
+	}
+}
+
+// updateImportJob applies updates to the job jobs selects, failing when its row is gone
+func updateImportJob(jobs *gorm.DB, updates map[string]interface{}) error {
+	result := jobs.Session(&gorm.Session{}).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSiteImportJobNotFound
+	}
+	return nil
+}
+
+// siteImportFailure is the job update recording that an import failed and wrote nothing
+func siteImportFailure(message string) map[string]interface{} {
+	errorsJSON, _ := json.Marshal([]models.SiteImportRowError{{Message: message}})
+	return map[string]interface{}{
+		"status":         models.SiteImportStatusFailed,
+		"finished_at":    time.Now(),
+		"error_message":  message,
+		"errors":         datatypes.JSON(errorsJSON),
+		"processed_rows": 0,
+	}
+}
+
+// siteFromImportRow builds a new site from a validated row
+func siteFromImportRow(values map[string]string) *models.Site {
+	site := &models.Site{
+		ID:           uuid.NewString(),
+		Name:         values["name"],
+		Address:      values["address"],
+		City:         values["city"],
+		State:        values["state"],
+		ZipCode:      values["zip_code"],
+		Country:      values["country"],
+		Type:         values["type"],
+		Status:       strings.ToLower(values["status"]),
+		ContactName:  values["contact_name"],
+		ContactEmail: values["contact_email"],
+		ContactPhone: values["contact_phone"],
+		Notes:        values["notes"],
+	}
+	if site.Status == "" {
+		site.Status = "active"
+	}
+	if site.Country == "" {
+		site.Country = "USA"
+	}
+	if ref := values["external_ref"]; ref != "" {
+		site.ExternalRef = &ref
+	}
+	if values["latitude"] != "" {
+		lat, _ := strconv.ParseFloat(values["latitude"], 64)
+		lng, _ := strconv.ParseFloat(values["longitude"], 64)
+		site.Latitude = &lat
+		site.Longitude = &lng
+		site.CoordinatesSource = models.CoordinatesSourceManual
+	}
+	if values["geofence_radius_meters"] != "" {
+		radius, _ := strconv.Atoi(values["geofence_radius_meters"])
+		site.GeofenceRadius = &radius
+	}
+	return site
+}
+
+// siteImportUpdates returns the columns an update row sets. Only columns present in the file
+// are touched; an empty cell clears optional text fields.
+func siteImportUpdates(values map[string]string, userID string) map[string]interface{} {
+	updates := map[string]interface{}{"updated_by": userID}
+	for field, value := range values {
+		if strings.HasPrefix(field, customFieldColumnPrefix) {
+			continue // Merged into metadata by the caller
+		}
+		switch field {
+		case "external_ref":
+			// The key the row was matched on; set it so exported ID rows gain a reference
+			updates[field] = value
+		case "latitude", "longitude":
+			// Imported coordinates are manual overrides, as if typed into the site form
+			updates["geocoded_at"] = nil
+			if value == "" {
+				updates[field] = nil
+				updates["coordinates_source"] = ""
+			} else {
+				coord, _ := strconv.ParseFloat(value, 64)
+				updates[field] = coord
+				updates["coordinates_source"] = models.CoordinatesSourceManual
+			}
+		case "geofence_radius_meters":
+			if value == "" {
+				updates[field] = nil
+			} else {
+				radius, _ := strconv.Atoi(value)
+				updates[field] = radius
+			}
+		case "status":
+			if value != "" {
+				updates[field] = strings.ToLower(value)
+			}
+		default:
+			updates[field] = value
+		}
+	}
+	return updates
+}
+
+// mergeSiteMetadata sets custom field values on existing metadata, removing cleared ones
+func mergeSiteMetadata(metadata datatypes.JSON, custom map[string]interface{}) datatypes.JSON {
+	values := decodeSiteMetadata(metadata)
+	for key, value := range custom {
+		if value == nil {
+			delete(values, key)
+		} else {
+			values[key] = value
+		}
+	}
+	encoded, _ := json.Marshal(values)
+	return datatypes.JSON(encoded)
+}
+
+// =====================================================
+// EXPORT
+// =====================================================
+
+// ExportSites writes the organization's sites in the import format, so an exported file can
+// be edited and imported back. Sites without an external reference are keyed by their ID.
+// Custom fields follow the standard columns as "custom.<key>".
+func (s *SiteImportService) ExportSites(ctx context.Context, organizationID, format string) ([]byte, string, string, error) {
+	customFields, err := NewSiteCustomFieldService(s.db).GetFields(ctx, organizationID)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	var sites []models.Site
+	if err := database.Conn(ctx, s.db).
+		Where("organization_id = ?", organizationID).
+		Order("name ASC").
+		Find(&sites).Error; err != nil {
+		return nil, "", "", fmt.Errorf("failed to get sites: %v", err)
+	}
+
+	header := append([]string{}, siteImportFields...)
+	for _, field := range customFields {
+		header = append(header, customFieldColumnPrefix+field.Key)
+	}
+	records := [][]string{header}
+	for _, site := range sites {
+		record := siteExportRecord(&site)
+		metadata := decodeSiteMetadata(site.Metadata)
+		for _, field := range customFields {
+			record = append(record, customFieldText(metadata[field.Key]))
+		}
+		records = append(records, record)
+	}
+
+	filename := fmt.Sprintf("sites_%s.%s", time.Now().Format("20060102_150405"), format)
+	switch format {
+	case "csv":
+		var buf bytes.Buffer
+		writer := csv.NewWriter(&buf)
+		if err := writer.WriteAll(records); err != nil {
+			return nil, "", "", fmt.Errorf("failed to write CSV: %v", err)
+		}
+		return buf.Bytes(), "text/csv", filename, nil
+	case "xlsx":
+		workbook := excelize.NewFile()
+		defer workbook.Close()
+		sheet := workbook.GetSheetName(0)
+		for i, record := range records {
+			cells := make([]interface{}, len(record))
+			for j, value := range record {
+				cells[j] = value
+			}
+			cell, _ := excelize.CoordinatesToCellName(1, i+1)
+			if err := workbook.SetSheetRow(sheet, cell, &cells); err != nil {
+				return nil, "", "", fmt.Errorf("failed to write XLSX: %v", err)
+			}
+		}
+		buf, err := workbook.WriteToBuffer()
+		if err != nil {
+			return nil, "", "", fmt.Errorf("failed to write XLSX: %v", err)
+		}
+		return buf.Bytes(), "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", filename, nil
+	default:
+		return nil, "", "", fmt.Errorf("%w: unsupported format %q", ErrInvalidSiteImport, format)
+	}
+}
+
+func siteExportRecord(site *models.Site) []string {
+	ref := site.ID
+	if site.ExternalRef != nil && *site.ExternalRef != "" {
+		ref = *site.ExternalRef
+	}
+	formatFloat := func(value *float64) string {
+		if value == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*value, 'f', -1, 64)
+	}
+	radius := ""
+	if site.GeofenceRadius != nil {
+		radius = strconv.Itoa(*site.GeofenceRadius)
+	}
+
+	return []string{
+		ref, site.Name, site.Address, site.City, site.State, site.ZipCode, site.Country,
+		formatFloat(site.Latitude), formatFloat(site.Longitude), site.Type, site.Status,
+		site.ContactName, site.ContactEmail, site.ContactPhone, site.Notes, radius,
+	}
+}
+
+// =====================================================
+// HELPERS
+// =====================================================
+
+// normalizeImportHeader turns "Zip Code" or "zip-code" into "zip_code"
+func normalizeImportHeader(header string) string {
+	header = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(header, "\ufeff")))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(header)
+}
+
+// normalizeImportKey compares names and addresses ignoring case, punctuation and spacing
+func normalizeImportKey(value string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(value) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r > 127 {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,277 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"resource-mgmt/models"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// siteImportTestFixture is an import service for org-a, which has one site already
+type siteImportTestFixture struct {
+	db       *gorm.DB
+	service  *SiteImportService
+	existing *models.Site
+}
+
+func newSiteImportTestFixture(t *testing.T) *siteImportTestFixture {
+	db := setupServiceTestDB(t, &models.Site{}, &models.SiteImportJob{}, &models.SiteCustomField{})
+	f := &siteImportTestFixture{db: db, service: NewSiteImportService(db)}
+	f.existing = &models.Site{ID: uuid.NewString(), OrganizationID: "org-a", Name: "Harbour Depot", Address: "1 Quay St", City: "Leith", Status: "active"}
+	require.NoError(t, db.Create(f.existing).Error)
+	return f
+}
+
+// preview parses a CSV file with the standard columns and validates it
+func (f *siteImportTestFixture) preview(t *testing.T, content string) *models.SiteImportPreview {
+	rows, err := f.service.ParseSiteImport(context.Background(), "org-a", "csv", strings.NewReader(content), nil)
+	require.NoError(t, err)
+	preview, err := f.service.ValidateSiteImport(context.Background(), "org-a", rows)
+	require.NoError(t, err)
+	return preview
+}
+
+// runImport imports a clean CSV file and returns the finished job
+func (f *siteImportTestFixture) runImport(t *testing.T, content string) *models.SiteImportJob {
+	ctx := context.Background()
+	rows, err := f.service.ParseSiteImport(ctx, "org-a", "csv", strings.NewReader(content), nil)
+	require.NoError(t, err)
+	preview, err := f.service.ValidateSiteImport(ctx, "org-a", rows)
+	require.NoError(t, err)
+	require.Empty(t, preview.Errors)
+
+	job := &models.SiteImportJob{ID: uuid.NewString(), OrganizationID: "org-a", Status: models.SiteImportStatusPending, TotalRows: len(rows)}
+	require.NoError(t, f.db.Create(job).Error)
+	f.service.runImport(ctx, job.ID, "org-a", "admin-1", rows)
+
+	stored, err := f.service.GetImportJob(ctx, "org-a", job.ID)
+	require.NoError(t, err)
+	return stored
+}
+
+const cleanSiteImport = "external_ref,name,address,city,latitude,longitude,geofence_radius_meters\n" +
+	"N-1,North Yard,10 High St,Perth,56.39,-3.43,150\n" +
+	"N-2,West Yard,5 Mill Ln,Perth,,,\n"
+
+func TestSiteImportService_ParseSiteImportRejectsFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		content string
+		mapping map[string]string
+	}{
+		{"name and address not mapped", "csv", "Ref,City\nA,Perth", nil},
+		{"mapping to a missing column", "csv", "Ref,Street\nA,1 High St", map[string]string{"name": "Site Name", "address": "Street"}},
+		{"empty file", "csv", "", nil},
+		{"unreadable spreadsheet", "xlsx", "name,address\nA,1 High St", nil},
+		{"unsupported format", "json", "[]", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSiteImportTestFixture(t)
+			_, err := f.service.ParseSiteImport(context.Background(), "org-a", tt.format, strings.NewReader(tt.content), tt.mapping)
+			assert.ErrorIs(t, err, ErrInvalidSiteImport)
+		})
+	}
+}
+
+func TestSiteImportService_ParseSiteImportMapsColumnsAndSkipsBlankRows(t *testing.T) {
+	f := newSiteImportTestFixture(t)
+	file := strings.Join([]string{
+		"Ref,Site Name,Street,City",
+		"N-1,North Yard,10 High St,Perth",
+		",,,",
+		"N-2,South Yard,2 Low St,Perth",
+	}, "\n")
+	mapping := map[string]string{"external_ref": "Ref", "name": "Site Name", "address": "Street"}
+
+	rows, err := f.service.ParseSiteImport(context.Background(), "org-a", "csv", strings.NewReader(file), mapping)
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, 4, rows[1].Row, "rows are numbered as in the file")
+	assert.Equal(t, "South Yard", rows[1].Values["name"])
+	assert.Equal(t, "N-2", rows[1].Values["external_ref"])
+}
+
+func TestSiteImportService_ValidateSiteImportRowErrors(t *testing.T) {
+	header := "external_ref,name,address,latitude,longitude,status,contact_email,geofence_radius_meters\n"
+	tests := []struct {
+		name      string
+		rows      string
+		wantField string
+	}{
+		{"missing name", "N-1,,10 High St,,,,,", "name"},
+		{"missing address", "N-1,North Yard,,,,,,", "address"},
+		{"latitude without longitude", "N-1,North Yard,10 High St,56.39,,,,", "latitude"},
+		{"latitude out of range", "N-1,North Yard,10 High St,91,-3.4,,,", "latitude"},
+		{"coordinates not numbers", "N-1,North Yard,10 High St,north,west,,,", "latitude"},
+		{"unknown status", "N-1,North Yard,10 High St,,,closed,,", "status"},
+		{"invalid email", "N-1,North Yard,10 High St,,,,not-an-email,", "contact_email"},
+		{"zero geofence radius", "N-1,North Yard,10 High St,,,,,0", "geofence_radius_meters"},
+		{"fractional geofence radius", "N-1,North Yard,10 High St,,,,,12.5", "geofence_radius_meters"},
+		{"external_ref too long", strings.Repeat("x", 101) + ",North Yard,10 High St,,,,,", "external_ref"},
+		{"repeated external_ref", "N-1,North Yard,10 High St,,,,,\nN-1,East Yard,12 High St,,,,,", "external_ref"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSiteImportTestFixture(t)
+			preview := f.preview(t, header+tt.rows)
+
+			require.Len(t, preview.Errors, 1)
+			assert.Equal(t, tt.wantField, preview.Errors[0].Field)
+			lastRow := strings.Count(tt.rows, "\n") + 2
+			assert.Equal(t, lastRow, preview.Errors[0].Row)
+			assert.Equal(t, lastRow-2, preview.ValidRows)
+		})
+	}
+}
+
+func TestSiteImportService_ValidateSiteImportFindsDuplicates(t *testing.T) {
+	f := newSiteImportTestFixture(t)
+	preview := f.preview(t, "name,address,city\n"+
+		"North Yard,10 High St,Perth\n"+
+		"harbour depot,22 Dock Rd,Leith\n"+
+		"East Yard,10 High St,Perth\n")
+	assert.Equal(t, 3, preview.ValidRows, "duplicates are warnings, not errors")
+
+	matches := map[string]models.SiteImportDuplicate{}
+	for _, duplicate := range preview.Duplicates {
+		matches[fmt.Sprintf("%s:%d", duplicate.MatchedOn, duplicate.Row)] = duplicate
+	}
+	assert.Equal(t, f.existing.ID, matches["name:3"].ExistingSiteID, "names match an existing site ignoring case")
+	assert.Equal(t, 2, matches["address:4"].OtherRow, "addresses match earlier rows")
+}
+
+func TestSiteImportService_ImportIsIdempotentOnExternalRefs(t *testing.T) {
+	f := newSiteImportTestFixture(t)
+
+	job := f.runImport(t, cleanSiteImport)
+	assert.Equal(t, models.SiteImportStatusCompleted, job.Status)
+	assert.Equal(t, 2, job.CreatedCount)
+	assert.Equal(t, 100, job.Progress())
+
+	job = f.runImport(t, strings.Replace(cleanSiteImport, "West Yard", "West Yard Annex", 1))
+	assert.Equal(t, 0, job.CreatedCount)
+	assert.Equal(t, 2, job.UpdatedCount)
+
+	count := countRows(t, f.db.Model(&models.Site{}).Where("organization_id = ?", "org-a"))
+	assert.Equal(t, int64(3), count)
+
+	var north models.Site
+	require.NoError(t, f.db.Where("external_ref = ?", "N-1").First(&north).Error)
+	require.NotNil(t, north.GeofenceRadius)
+	assert.Equal(t, 150, *north.GeofenceRadius)
+	assert.Equal(t, "active", north.Status)
+}
+
+func TestSiteImportService_GetImportJobIsScopedToOrganization(t *testing.T) {
+	f := newSiteImportTestFixture(t)
+	job := f.runImport(t, cleanSiteImport)
+
+	_, err := f.service.GetImportJob(context.Background(), "org-b", job.ID)
+	assert.ErrorIs(t, err, ErrSiteImportJobNotFound)
+}
+
+func TestSiteImportService_ExportRoundTrips(t *testing.T) {
+	tests := []struct {
+		format      string
+		contentType string
+	}{
+		{"csv", "text/csv"},
+		{"xlsx", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			f := newSiteImportTestFixture(t)
+			ctx := context.Background()
+			f.runImport(t, cleanSiteImport)
+
+			// Exports use the import columns, keying sites without a reference by ID
+			data, contentType, _, err := f.service.ExportSites(ctx, "org-a", tt.format)
+			require.NoError(t, err)
+			assert.Equal(t, tt.contentType, contentType)
+			exported, err := f.service.ParseSiteImport(ctx, "org-a", tt.format, bytes.NewReader(data), nil)
+			require.NoError(t, err)
+			require.Len(t, exported, 3)
+
+			preview, err := f.service.ValidateSiteImport(ctx, "org-a", exported)
+			require.NoError(t, err)
+			assert.Equal(t, 3, preview.Updates, "a re-imported export updates every site in place")
+			assert.Empty(t, preview.Duplicates)
+		})
+	}
+}
+
+func TestSiteImportService_PanickingImportFailsJob(t *testing.T) {
+	db := setupServiceTestDB(t, &models.Site{}, &models.SiteImportJob{}, &models.SiteCustomField{})
+	service := NewSiteImportService(db)
+
+	job := &models.SiteImportJob{OrganizationID: "org-a", Status: models.SiteImportStatusPending, Format: "csv", TotalRows: 1}
+	require.NoError(t, db.Create(job).Error)
+
+	require.NoError(t, db.Callback().Create().Before("gorm:create").Register("test:panic", func(tx *gorm.DB) {
+		if tx.Statement.Table == "sites" {
+			panic("disk on fire")
+		}
+	}))
+	rows := []siteImportRow{{Row: 2, Values: map[string]string{"name": "North Yard", "address": "10 High St"}}}
+	assert.NotPanics(t, func() { service.runImport(context.Background(), job.ID, "org-a", "user-1", rows) })
+
+	var stored models.SiteImportJob
+	require.NoError(t, db.First(&stored, "id = ?", job.ID).Error)
+	assert.Equal(t, models.SiteImportStatusFailed, stored.Status)
+	assert.Contains(t, stored.ErrorMessage, "disk on fire")
+	assert.NotNil(t, stored.FinishedAt)
+
+	sites := countRows(t, db.Unscoped().Model(&models.Site{}))
+	assert.Zero(t, sites)
+}
+
+func TestSiteImportService_ImportWithoutJobWritesNothing(t *testing.T) {
+	f := newSiteImportTestFixture(t)
+	rows := []siteImportRow{{Row: 2, Values: map[string]string{"name": "North Yard", "address": "10 High St"}}}
+
+	f.service.runImport(context.Background(), uuid.NewString(), "org-a", "admin-1", rows)
+
+	sites := countRows(t, f.db.Model(&models.Site{}).Where("organization_id = ?", "org-a"))
+	assert.Equal(t, int64(1), sites, "only the existing site")
+}
+
+func TestSiteImportService_FailInterruptedImports(t *testing.T) {
+	db := setupServiceTestDB(t, &models.SiteImportJob{})
+	service := NewSiteImportService(db)
+
+	tests := []struct {
+		status string
+		want   string
+	}{
+		{models.SiteImportStatusPending, models.SiteImportStatusFailed},
+		{models.SiteImportStatusRunning, models.SiteImportStatusFailed},
+		{models.SiteImportStatusCompleted, models.SiteImportStatusCompleted},
+		{models.SiteImportStatusFailed, models.SiteImportStatusFailed},
+	}
+	jobs := make([]*models.SiteImportJob, len(tests))
+	for i, tt := range tests {
+		jobs[i] = &models.SiteImportJob{OrganizationID: "org-a", Status: tt.status, Format: "csv"}
+		require.NoError(t, db.Create(jobs[i]).Error)
+	}
+
+	failed, err := service.FailInterruptedImports(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), failed)
+
+	for i, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			var stored models.SiteImportJob
+			require.NoError(t, db.First(&stored, "id = ?", jobs[i].ID).Error)
+			assert.Equal(t, tt.want, stored.Status)
+		})
+	}
+}
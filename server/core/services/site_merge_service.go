@@ -0,0 +1,592 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"resource-mgmt/config"
+	"resource-mgmt/models"
+	"resource-mgmt/pkg/database"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidSiteMerge is returned when a merge request names the same site twice
+	ErrInvalidSiteMerge = errors.New("invalid site merge")
+	// ErrSiteMergeSiteNotFound is returned when either site doesn't exist in the organization
+	ErrSiteMergeSiteNotFound = errors.New("site not found")
+	// ErrSiteMergeNotFound is returned when a merge doesn't exist in the caller's organization
+	ErrSiteMergeNotFound = errors.New("site merge not found")
+	// ErrSiteMergeNotUndoable is returned when a merge was already undone, is past its undo
+	// window, or its survivor has since been merged away or deleted
+	ErrSiteMergeNotUndoable = errors.New("site merge can no longer be undone")
+)
+
+const (
+	// DefaultSiteDuplicateThreshold is the lowest score reported as a duplicate candidate
+	DefaultSiteDuplicateThreshold = 0.75
+	// siteDuplicateMaxBlock skips name and address blocks so common that comparing every
+	// pair in them would cost more than it finds
+	siteDuplicateMaxBlock = 500
+	// siteDuplicateCellDegrees sizes the grid nearby sites are grouped by (about 550 m)
+	siteDuplicateCellDegrees = 0.005
+	// siteDuplicateNearMeters and siteDuplicateFarMeters bound the distance score: sites
+	// closer than near score 1, sites farther than far score 0
+	siteDuplicateNearMeters = 50.0
+	siteDuplicateFarMeters  = 500.0
+)
+
+// siteMergeReference is a column that points at a site and is re-pointed by a merge
+type siteMergeReference struct {
+	table  string
+	column string
+	where  string // Extra condition, for polymorphic references
+}
+
+// siteMergeReferences are the site references a merge moves to the survivor. Site stats
+// are computed from inspections, so they follow. Custom field violations describe the
+// merged site's own metadata and stay with it.
+var siteMergeReferences = []siteMergeReference{
+	{table: "inspections", column: "site_id"},
+	{table: "inspection_check_events", column: "site_id"},
+	{table: "location_nodes", column: "site_id"},
+	{table: "location_node_moves", column: "site_id"},
+	{table: "assets", column: "site_id"},
+	{table: "site_documents", column: "site_id"},
+	{table: "scan_tags", column: "entity_id", where: "entity_type = 'site'"},
+}
+
+// siteNameNoise are words that don't help tell two site names apart
+var siteNameNoise = map[string]bool{
+	"the": true, "inc": true, "llc": true, "ltd": true, "co": true, "corp": true, "company": true, "and": true,
+}
+
+// siteAddressAbbreviations folds common spellings of address words into one form
+var siteAddressAbbreviations = map[string]string{
+	"street": "st", "avenue": "ave", "av": "ave", "road": "rd", "drive": "dr", "boulevard": "blvd",
+	"lane": "ln", "court": "ct", "place": "pl", "highway": "hwy", "parkway": "pkwy", "square": "sq",
+	"suite": "ste", "building": "bldg", "floor": "fl", "north": "n", "south": "s", "east": "e",
+	"west": "w", "first": "1st", "second": "2nd", "third": "3rd",
+}
+
+type SiteMergeService struct {
+	db           *gorm.DB
+	auditService *AuditService
+}
+
+func NewSiteMergeService(db *gorm.DB) *SiteMergeService {
+	return &SiteMergeService{
+		db:           db,
+		auditService: NewAuditService(),
+	}
+}
+
+// siteMergeUndoWindow returns config.SiteMergeUndoWindow as a duration
+func siteMergeUndoWindow() time.Duration {
+	window, err := time.ParseDuration(config.SiteMergeUndoWindow)
+	if err != nil || window < 0 {
+		return 30 * 24 * time.Hour
+	}
+	return window
+}
+
+// =====================================================
+// DUPLICATE DETECTION
+// =====================================================
+
+// FindDuplicates returns pairs of the organization's sites scoring at least threshold,
+// best first. Sites are only compared when they share a name word, a street address or
+// a patch of map, so large organizations don't need every pair scored.
+func (s *SiteMergeService) FindDuplicates(ctx context.Context, organizationID string, threshold float64, limit int) ([]models.SiteDuplicateCandidate, error) {
+	var sites []models.Site
+	if err := database.Conn(ctx, s.db).Where("organization_id = ?", organizationID).Order("created_at, id").Find(&sites).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch sites: %v", err)
+	}
+
+	names := make([]string, len(sites))
+	addresses := make([]string, len(sites))
+	blocks := make(map[string][]int)
+	cells := make(map[[2]int][]int)
+	for i, site := range sites {
+		names[i] = normalizeSiteName(site.Name)
+		addresses[i] = normalizeSiteAddress(site.GetFullAddress())
+		for _, key := range siteDuplicateBlockKeys(names[i], normalizeSiteAddress(site.Address), site.ZipCode) {
+			blocks[key] = append(blocks[key], i)
+		}
+		if cell, ok := siteDuplicateCell(&site); ok {
+			cells[cell] = append(cells[cell], i)
+		}
+	}
+
+	pairs := make(map[[2]int]bool)
+	addPair := func(i, j int) {
+		if i == j {
+			return
+		}
+		if i > j {
+			i, j = j, i
+		}
+		pairs[[2]int{i, j}] = true
+	}
+	for _, members := range blocks {
+		if len(members) > siteDuplicateMaxBlock {
+			continue
+		}
+		for a := range members {
+			for b := a + 1; b < len(members); b++ {
+				addPair(members[a], members[b])
+			}
+		}
+	}
+	for i := range sites {
+		cell, ok := siteDuplicateCell(&sites[i])
+		if !ok {
+			continue
+		}
+		for dLat := -1; dLat <= 1; dLat++ {
+			for dLng := -1; dLng <= 1; dLng++ {
+				for _, j := range cells[[2]int{cell[0] + dLat, cell[1] + dLng}] {
+					addPair(i, j)
+				}
+			}
+		}
+	}
+
+	candidates := make([]models.SiteDuplicateCandidate, 0)
+	for pair := range pairs {
+		i, j := pair[0], pair[1]
+		candidate := scoreSiteDuplicate(&sites[i], &sites[j], names[i], names[j], addresses[i], addresses[j])
+		if candidate.Score >= threshold {
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	sort.Slice(candidates, func(a, b int) bool {
+		if candidates[a].Score != candidates[b].Score {
+			return candidates[a].Score > candidates[b].Score
+		}
+		if candidates[a].Site.ID != candidates[b].Site.ID {
+			return candidates[a].Site.ID < candidates[b].Site.ID
+		}
+		return candidates[a].Duplicate.ID < candidates[b].Duplicate.ID
+	})
+	if limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+
+	return candidates, nil
+}
+
+// scoreSiteDuplicate scores how likely two sites are the same place. Name and address
+// similarity always count; distance counts when both sites have coordinates.
+func scoreSiteDuplicate(site, other *models.Site, name, otherName, address, otherAddress string) models.SiteDuplicateCandidate {
+	candidate := models.SiteDuplicateCandidate{
+		Site:         *site,
+		Duplicate:    *other,
+		NameScore:    textSimilarity(name, otherName),
+		AddressScore: textSimilarity(address, otherAddress),
+	}
+
+	score := 0.4*candidate.NameScore + 0.35*candidate.AddressScore
+	if site.Latitude != nil && site.Longitude != nil && other.Latitude != nil && other.Longitude != nil {
+		meters := HaversineKm(
+			models.GeoPoint{Latitude: *site.Latitude, Longitude: *site.Longitude},
+			models.GeoPoint{Latitude: *other.Latitude, Longitude: *other.Longitude},
+		) * 1000
+		candidate.DistanceMeters = &meters
+
+		distanceScore := (siteDuplicateFarMeters - meters) / (siteDuplicateFarMeters - siteDuplicateNearMeters)
+		candidate.Score = score + 0.25*math.Max(0, math.Min(1, distanceScore))
+	} else {
+		candidate.Score = score / 0.75
+	}
+	candidate.Score = math.Round(candidate.Score*1000) / 1000
+
+	return candidate
+}
+
+// siteDuplicateBlockKeys returns the keys a site is grouped under for comparison: its
+// first significant name word, its street address and its zip code
+func siteDuplicateBlockKeys(name, street, zipCode string) []string {
+	var keys []string
+	if words := strings.Fields(name); len(words) > 0 {
+		keys = append(keys, "name:"+words[0])
+	}
+	if street != "" {
+		keys = append(keys, "street:"+street)
+	}
+	if zip := normalizeImportKey(zipCode); zip != "" {
+		keys = append(keys, "zip:"+zip)
+	}
+	return keys
+}
+
+// siteDuplicateCell returns the grid cell a site's coordinates fall in
+func siteDuplicateCell(site *models.Site) ([2]int, bool) {
+	if site.Latitude == nil || site.Longitude == nil {
+		return [2]int{}, false
+	}
+	return [2]int{
+		int(math.Floor(*site.Latitude / siteDuplicateCellDegrees)),
+		int(math.Floor(*site.Longitude / siteDuplicateCellDegrees)),
+	}, true
+}
+
+// siteTextWords lowercases text and splits it into words of letters and digits
+func siteTextWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !((r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r > 127)
+	})
+}
+
+// normalizeSiteName drops punctuation and words like "Inc" from a site name
+func normalizeSiteName(name string) string {
+	var words []string
+	for _, word := range siteTextWords(name) {
+		if !siteNameNoise[word] {
+			words = append(words, word)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// normalizeSiteAddress drops punctuation and abbreviates common address words
+func normalizeSiteAddress(address string) string {
+	words := siteTextWords(address)
+	for i, word := range words {
+		if short, ok := siteAddressAbbreviations[word]; ok {
+			words[i] = short
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// textSimilarity scores two normalized strings from 0 to 1, taking the better of edit
+// distance (typos) and shared words (reordering)
+func textSimilarity(a, b string) float64 {
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+
+	ra, rb := []rune(a), []rune(b)
+	editScore := 1 - float64(levenshteinDistance(ra, rb))/math.Max(float64(len(ra)), float64(len(rb)))
+
+	wordsA, wordsB := strings.Fields(a), strings.Fields(b)
+	seen := make(map[string]bool, len(wordsA))
+	for _, word := range wordsA {
+		seen[word] = true
+	}
+	shared, union := 0, len(seen)
+	for _, word := range wordsB {
+		if seen[word] {
+			shared++
+			delete(seen, word)
+		} else {
+			union++
+		}
+	}
+	wordScore := float64(shared) / float64(union)
+
+	return math.Max(editScore, wordScore)
+}
+
+// levenshteinDistance returns the number of single-rune edits between a and b
+func levenshteinDistance(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// =====================================================
+// MERGE AND UNDO
+// =====================================================
+
+// MergeSites folds mergedID into survivorID: inspections, assignments, open pool items,
+// locations, assets, documents and scan tags are re-pointed at the survivor and the merged site is
+// soft-deleted. The merge and its audit entry commit together or not at all.
+func (s *SiteMergeService) MergeSites(ctx context.Context, organizationID, userID, survivorID, mergedID string) (*models.SiteMerge, error) {
+	if survivorID == mergedID {
+		return nil, fmt.Errorf("%w: a site can't be merged into itself", ErrInvalidSiteMerge)
+	}
+
+	now := time.Now()
+	merge := &models.SiteMerge{
+		OrganizationID: organizationID,
+		SurvivorID:     survivorID,
+		MergedID:       mergedID,
+		Status:         models.SiteMergeStatusMerged,
+		MergedBy:       userID,
+		MergedAt:       now,
+		UndoDeadline:   now.Add(siteMergeUndoWindow()),
+	}
+
+	err := database.Conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		var sites []models.Site
+		if err := tx.Where("id IN ? AND organization_id = ?", []string{survivorID, mergedID}, organizationID).Find(&sites).Error; err != nil {
+			return err
+		}
+		if len(sites) != 2 {
+			return ErrSiteMergeSiteNotFound
+		}
+		merged := sites[0]
+		if merged.ID != mergedID {
+			merged = sites[1]
+		}
+
+		moved := make(map[string][]string)
+		counts := make(map[string]int)
+		for _, ref := range siteMergeReferences {
+			ids, err := repointSiteReference(tx, ref, organizationID, mergedID, survivorID, nil)
+			if err != nil {
+				return fmt.Errorf("failed to move %s: %v", ref.table, err)
+			}
+			moved[ref.table] = ids
+			counts[ref.table] = len(ids)
+		}
+
+		previous, err := repointSiteLists(tx, "inspection_assignments", organizationID, mergedID, survivorID)
+		if err != nil {
+			return fmt.Errorf("failed to move inspection_assignments: %v", err)
+		}
+		counts["inspection_assignments"] = len(previous)
+
+		previousPool, err := repointSiteLists(tx, "open_pool_items", organizationID, mergedID, survivorID)
+		if err != nil {
+			return fmt.Errorf("failed to move open_pool_items: %v", err)
+		}
+		counts["open_pool_items"] = len(previousPool)
+
+		if err := tx.Delete(&models.Site{}, "id = ?", mergedID).Error; err != nil {
+			return err
+		}
+
+		merge.MovedRows, _ = json.Marshal(moved)
+		merge.PreviousSiteIDs, _ = json.Marshal(previous)
+		merge.PreviousPoolSiteIDs, _ = json.Marshal(previousPool)
+		merge.Counts, _ = json.Marshal(counts)
+		if err := tx.Create(merge).Error; err != nil {
+			return err
+		}
+
+		return s.auditService.LogEntityChange(database.ContextWithTx(ctx, tx), organizationID, userID, SitesMerged, "site", mergedID,
+			merged, map[string]interface{}{"merged_into": survivorID, "merge_id": merge.ID, "counts": counts})
+	})
+	if err != nil {
+		if errors.Is(err, ErrSiteMergeSiteNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to merge sites: %v", err)
+	}
+
+	return merge, nil
+}
+
+// UndoMerge restores a merged site and moves back what the merge re-pointed. Records
+// created on the survivor since the merge stay with it.
+func (s *SiteMergeService) UndoMerge(ctx context.Context, organizationID, userID, mergeID string) (*models.SiteMerge, error) {
+	var merge models.SiteMerge
+	err := database.Conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND organization_id = ?", mergeID, organizationID).First(&merge).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrSiteMergeNotFound
+			}
+			return err
+		}
+
+		now := time.Now()
+		if !merge.CanUndo(now) {
+			return ErrSiteMergeNotUndoable
+		}
+		var survivors int64
+		if err := tx.Model(&models.Site{}).Where("id = ?", merge.SurvivorID).Count(&survivors).Error; err != nil {
+			return err
+		}
+		if survivors == 0 {
+			return fmt.Errorf("%w: the surviving site was merged or deleted since", ErrSiteMergeNotUndoable)
+		}
+
+		if err := tx.Unscoped().Model(&models.Site{}).
+			Where("id = ? AND organization_id = ?", merge.MergedID, organizationID).
+			Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+
+		moved := make(map[string][]string)
+		if len(merge.MovedRows) > 0 {
+			if err := json.Unmarshal(merge.MovedRows, &moved); err != nil {
+				return err
+			}
+		}
+		for _, ref := range siteMergeReferences {
+			if _, err := repointSiteReference(tx, ref, organizationID, merge.SurvivorID, merge.MergedID, moved[ref.table]); err != nil {
+				return fmt.Errorf("failed to restore %s: %v", ref.table, err)
+			}
+		}
+
+		if err := restoreSiteLists(tx, "inspection_assignments", merge.PreviousSiteIDs, merge.SurvivorID); err != nil {
+			return fmt.Errorf("failed to restore inspection_assignments: %v", err)
+		}
+		if err := restoreSiteLists(tx, "open_pool_items", merge.PreviousPoolSiteIDs, merge.SurvivorID); err != nil {
+			return fmt.Errorf("failed to restore open_pool_items: %v", err)
+		}
+
+		merge.Status = models.SiteMergeStatusUndone
+		merge.UndoneBy = &userID
+		merge.UndoneAt = &now
+		if err := tx.Save(&merge).Error; err != nil {
+			return err
+		}
+
+		return s.auditService.LogEntityChange(database.ContextWithTx(ctx, tx), organizationID, userID, SiteMergeUndone, "site", merge.MergedID,
+			map[string]interface{}{"merged_into": merge.SurvivorID, "merge_id": merge.ID}, map[string]interface{}{"restored": true})
+	})
+	if err != nil {
+		if errors.Is(err, ErrSiteMergeNotFound) || errors.Is(err, ErrSiteMergeNotUndoable) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to undo site merge: %v", err)
+	}
+
+	return &merge, nil
+}
+
+// GetMerges returns the organization's merges, newest first. Filters: site_id (either side), status.
+func (s *SiteMergeService) GetMerges(ctx context.Context, organizationID string, filters map[string]interface{}) ([]models.SiteMerge, error) {
+	query := database.Conn(ctx, s.db).Where("organization_id = ?", organizationID)
+	if siteID, ok := filters["site_id"]; ok {
+		query = query.Where("survivor_id = ? OR merged_id = ?", siteID, siteID)
+	}
+	if status, ok := filters["status"]; ok {
+		query = query.Where("status = ?", status)
+	}
+
+	var merges []models.SiteMerge
+	if err := query.Order("merged_at DESC").Find(&merges).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch site merges: %v", err)
+	}
+	return merges, nil
+}
+
+// repointSiteReference moves rows of one reference from site from to site to and returns
+// their IDs. With only set, just those rows are moved, and only if they still point at from.
+func repointSiteReference(tx *gorm.DB, ref siteMergeReference, organizationID, from, to string, only []string) ([]string, error) {
+	query := func() *gorm.DB {
+		q := tx.Table(ref.table).Where("organization_id = ? AND "+ref.column+" = ?", organizationID, from)
+		if ref.where != "" {
+			q = q.Where(ref.where)
+		}
+		if only != nil {
+			q = q.Where("id IN ?", only)
+		}
+		return q
+	}
+
+	if only != nil && len(only) == 0 {
+		return []string{}, nil
+	}
+
+	var ids []string
+	if err := query().Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return []string{}, nil
+	}
+	if err := query().Update(ref.column, to).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// siteListRow is a row of a table that lists its sites in a site_ids JSON array
+type siteListRow struct {
+	ID      string
+	SiteIDs datatypes.JSON
+}
+
+// repointSiteLists swaps site from for site to in the site_ids list of every row of table
+// (inspection_assignments or open_pool_items), and returns each changed row's list as it was
+func repointSiteLists(tx *gorm.DB, table, organizationID, from, to string) (map[string]datatypes.JSON, error) {
+	var rows []siteListRow
+	if err := tx.Table(table).Select("id", "site_ids").
+		Where("organization_id = ? AND CAST(site_ids AS TEXT) LIKE ?", organizationID, "%\""+from+"\"%").
+		Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	previous := make(map[string]datatypes.JSON)
+	for _, row := range rows {
+		var siteIDs []string
+		if err := json.Unmarshal(row.SiteIDs, &siteIDs); err != nil || !containsString(siteIDs, from) {
+			continue
+		}
+
+		updated := make([]string, 0, len(siteIDs))
+		for _, siteID := range siteIDs {
+			if siteID == from {
+				siteID = to
+			}
+			if !containsString(updated, siteID) {
+				updated = append(updated, siteID)
+			}
+		}
+		encoded, _ := json.Marshal(updated)
+		if err := tx.Table(table).Where("id = ?", row.ID).
+			UpdateColumn("site_ids", datatypes.JSON(encoded)).Error; err != nil {
+			return nil, err
+		}
+		previous[row.ID] = row.SiteIDs
+	}
+	return previous, nil
+}
+
+// restoreSiteLists puts back the site lists a merge changed in table, skipping rows that
+// no longer include the survivor
+func restoreSiteLists(tx *gorm.DB, table string, raw datatypes.JSON, survivorID string) error {
+	previous := make(map[string]datatypes.JSON)
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &previous); err != nil {
+			return err
+		}
+	}
+
+	for id, siteIDs := range previous {
+		var row siteListRow
+		if err := tx.Table(table).Select("id", "site_ids").Where("id = ?", id).Limit(1).Find(&row).Error; err != nil {
+			return err
+		}
+		var current []string
+		if row.ID == "" || json.Unmarshal(row.SiteIDs, &current) != nil || !containsString(current, survivorID) {
+			continue
+		}
+		if err := tx.Table(table).Where("id = ?", id).
+			UpdateColumn("site_ids", siteIDs).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"resource-mgmt/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// siteMergeTestFixture is a depot with a near-identical duplicate created an hour later, a
+// copy of the depot without coordinates, and an unrelated office nearby
+type siteMergeTestFixture struct {
+	db                     *gorm.DB
+	service                *SiteMergeService
+	depot, dupe, riverside *models.Site
+}
+
+func newSiteMergeTestFixture(t *testing.T) *siteMergeTestFixture {
+	db := setupAuditTestDB(t, &models.Site{}, &models.Inspection{}, &models.InspectionAssignment{}, &models.OpenPoolItem{}, &models.InspectionCheckEvent{}, &models.LocationNode{}, &models.LocationNodeMove{},
+		&models.Asset{}, &models.SiteDocument{}, &models.ScanTag{}, &models.SiteMerge{})
+	f := &siteMergeTestFixture{db: db, service: NewSiteMergeService(db)}
+
+	coords := func(lat, lng float64) (*float64, *float64) { return &lat, &lng }
+	site := func(name, address, zip string, lat, lng *float64, age int) *models.Site {
+		s := &models.Site{ID: uuid.NewString(), OrganizationID: "org-a", Name: name, Address: address, City: "Springfield", ZipCode: zip,
+			Latitude: lat, Longitude: lng, Status: "active", CreatedAt: time.Now().Add(-time.Duration(age) * time.Hour)}
+		require.NoError(t, db.Create(s).Error)
+		return s
+	}
+
+	lat, lng := coords(39.7817, -89.6501)
+	f.depot = site("Acme Depot", "100 Main Street", "62701", lat, lng, 3)
+	lat, lng = coords(39.7818, -89.6502)
+	f.dupe = site("ACME Depot, Inc.", "100 Main St", "62701", lat, lng, 2)
+	lat, lng = coords(39.80, -89.70)
+	f.riverside = site("Riverside Office", "9 River Road", "62702", lat, lng, 1)
+	site("Acme Depot Annex", "100 Main St.", "62701", nil, nil, 0)
+	return f
+}
+
+// addHistory gives the duplicate an inspection, an asset, a document, a scan tag and a
+// share of an assignment it has with the depot
+func (f *siteMergeTestFixture) addHistory(t *testing.T) (*models.Inspection, *models.InspectionAssignment) {
+	inspection := &models.Inspection{OrganizationID: "org-a", TemplateID: uuid.New(), InspectorID: "inspector-1", SiteID: f.dupe.ID, Status: "completed"}
+	require.NoError(t, f.db.Create(inspection).Error)
+	require.NoError(t, f.db.Create(&models.Asset{OrganizationID: "org-a", SiteID: f.dupe.ID, Name: "Boiler", Type: "boiler"}).Error)
+	require.NoError(t, f.db.Create(&models.SiteDocument{OrganizationID: "org-a", SiteID: f.dupe.ID, Category: models.SiteDocumentPermit, Title: "Permit"}).Error)
+	require.NoError(t, f.db.Create(&models.ScanTag{OrganizationID: "org-a", EntityType: "site", EntityID: &f.dupe.ID, Status: "bound"}).Error)
+	both := &models.InspectionAssignment{OrganizationID: "org-a", AssignedBy: "admin-1", AssignedTo: "inspector-1", TemplateID: uuid.NewString(),
+		SiteIDs: datatypes.JSON(`["` + f.depot.ID + `","` + f.dupe.ID + `"]`)}
+	require.NoError(t, f.db.Create(both).Error)
+	return inspection, both
+}
+
+func (f *siteMergeTestFixture) merge(t *testing.T) *models.SiteMerge {
+	merge, err := f.service.MergeSites(context.Background(), "org-a", "admin-1", f.depot.ID, f.dupe.ID)
+	require.NoError(t, err)
+	return merge
+}
+
+func (f *siteMergeTestFixture) siteIDOf(t *testing.T, inspectionID uuid.UUID) string {
+	var stored models.Inspection
+	require.NoError(t, f.db.First(&stored, "id = ?", inspectionID).Error)
+	return stored.SiteID
+}
+
+func (f *siteMergeTestFixture) dupeExists(t *testing.T) bool {
+	remaining := countRows(t, f.db.Model(&models.Site{}).Where("id = ?", f.dupe.ID))
+	return remaining == 1
+}
+
+func TestSiteMergeService_FindDuplicates(t *testing.T) {
+	f := newSiteMergeTestFixture(t)
+
+	// The near-identical pair scores highest; the copy without coordinates still matches on text
+	candidates, err := f.service.FindDuplicates(context.Background(), "org-a", DefaultSiteDuplicateThreshold, 10)
+	require.NoError(t, err)
+	require.Len(t, candidates, 3)
+	assert.Equal(t, f.depot.ID, candidates[0].Site.ID, "the older site is the suggested survivor")
+	assert.Equal(t, f.dupe.ID, candidates[0].Duplicate.ID)
+	require.NotNil(t, candidates[0].DistanceMeters)
+	assert.Less(t, *candidates[0].DistanceMeters, 20.0)
+	for _, candidate := range candidates {
+		assert.NotEqual(t, f.riverside.ID, candidate.Duplicate.ID)
+		assert.NotEqual(t, f.riverside.ID, candidate.Site.ID)
+	}
+}
+
+func TestSiteMergeService_MergeSitesValidation(t *testing.T) {
+	tests := []struct {
+		name         string
+		organization string
+		sites        func(f *siteMergeTestFixture) (string, string)
+		wantErr      error
+	}{
+		{"site into itself", "org-a", func(f *siteMergeTestFixture) (string, string) { return f.depot.ID, f.depot.ID }, ErrInvalidSiteMerge},
+		{"other organization", "org-b", func(f *siteMergeTestFixture) (string, string) { return f.depot.ID, f.dupe.ID }, ErrSiteMergeSiteNotFound},
+		{"unknown survivor", "org-a", func(f *siteMergeTestFixture) (string, string) { return uuid.NewString(), f.dupe.ID }, ErrSiteMergeSiteNotFound},
+		{"unknown duplicate", "org-a", func(f *siteMergeTestFixture) (string, string) { return f.depot.ID, uuid.NewString() }, ErrSiteMergeSiteNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSiteMergeTestFixture(t)
+			inspection, _ := f.addHistory(t)
+			survivorID, mergedID := tt.sites(f)
+
+			_, err := f.service.MergeSites(context.Background(), tt.organization, "admin-1", survivorID, mergedID)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.True(t, f.dupeExists(t), "nothing is merged")
+			assert.Equal(t, f.dupe.ID, f.siteIDOf(t, inspection.ID))
+		})
+	}
+}
+
+func TestSiteMergeService_MergeMovesHistoryToSurvivor(t *testing.T) {
+	f := newSiteMergeTestFixture(t)
+	inspection, both := f.addHistory(t)
+
+	merge := f.merge(t)
+	counts := map[string]int{}
+	require.NoError(t, json.Unmarshal(merge.Counts, &counts))
+	assert.Equal(t, 1, counts["inspections"])
+	assert.Equal(t, 1, counts["scan_tags"])
+	assert.Equal(t, 1, counts["inspection_assignments"])
+
+	assert.Equal(t, f.depot.ID, f.siteIDOf(t, inspection.ID))
+	var assignment models.InspectionAssignment
+	require.NoError(t, f.db.First(&assignment, "id = ?", both.ID).Error)
+	assert.JSONEq(t, `["`+f.depot.ID+`"]`, string(assignment.SiteIDs))
+	assert.False(t, f.dupeExists(t), "the merged site is soft-deleted")
+
+	audits := countRows(t, f.db.Model(&AuditLog{}).Where("organization_id = ? AND action = ?", "org-a", SitesMerged))
+	assert.Equal(t, int64(1), audits)
+}
+
+func TestSiteMergeService_UndoMergeRestoresHistory(t *testing.T) {
+	f := newSiteMergeTestFixture(t)
+	inspection, both := f.addHistory(t)
+	merge := f.merge(t)
+
+	// An inspection added to the survivor after the merge stays there on undo
+	later := &models.Inspection{OrganizationID: "org-a", TemplateID: uuid.New(), InspectorID: "inspector-1", SiteID: f.depot.ID, Status: "assigned"}
+	require.NoError(t, f.db.Create(later).Error)
+
+	undone, err := f.service.UndoMerge(context.Background(), "org-a", "admin-1", merge.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.SiteMergeStatusUndone, undone.Status)
+
+	assert.Equal(t, f.dupe.ID, f.siteIDOf(t, inspection.ID))
+	assert.Equal(t, f.depot.ID, f.siteIDOf(t, later.ID))
+	var assignment models.InspectionAssignment
+	require.NoError(t, f.db.First(&assignment, "id = ?", both.ID).Error)
+	assert.JSONEq(t, `["`+f.depot.ID+`","`+f.dupe.ID+`"]`, string(assignment.SiteIDs))
+	var tag models.ScanTag
+	require.NoError(t, f.db.First(&tag, "entity_type = ?", "site").Error)
+	assert.Equal(t, f.dupe.ID, *tag.EntityID)
+	assert.True(t, f.dupeExists(t))
+}
+
+func TestSiteMergeService_UndoMergeRejections(t *testing.T) {
+	tests := []struct {
+		name         string
+		organization string
+		prepare      func(t *testing.T, f *siteMergeTestFixture, merge *models.SiteMerge) string
+		wantErr      error
+	}{
+		{"already undone", "org-a", func(t *testing.T, f *siteMergeTestFixture, merge *models.SiteMerge) string {
+			_, err := f.service.UndoMerge(context.Background(), "org-a", "admin-1", merge.ID)
+			require.NoError(t, err)
+			_, err = f.service.MergeSites(context.Background(), "org-a", "admin-1", f.depot.ID, f.dupe.ID)
+			require.NoError(t, err)
+			return merge.ID
+		}, ErrSiteMergeNotUndoable},
+		{"past the retention window", "org-a", func(t *testing.T, f *siteMergeTestFixture, merge *models.SiteMerge) string {
+			require.NoError(t, f.db.Model(&models.SiteMerge{}).Where("id = ?", merge.ID).Update("undo_deadline", time.Now().Add(-time.Minute)).Error)
+			return merge.ID
+		}, ErrSiteMergeNotUndoable},
+		{"survivor deleted since", "org-a", func(t *testing.T, f *siteMergeTestFixture, merge *models.SiteMerge) string {
+			require.NoError(t, f.db.Delete(&models.Site{}, "id = ?", f.depot.ID).Error)
+			return merge.ID
+		}, ErrSiteMergeNotUndoable},
+		{"unknown merge", "org-a", func(t *testing.T, f *siteMergeTestFixture, merge *models.SiteMerge) string {
+			return uuid.NewString()
+		}, ErrSiteMergeNotFound},
+		{"other organization", "org-b", func(t *testing.T, f *siteMergeTestFixture, merge *models.SiteMerge) string {
+			return merge.ID
+		}, ErrSiteMergeNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSiteMergeTestFixture(t)
+			inspection, _ := f.addHistory(t)
+			mergeID := tt.prepare(t, f, f.merge(t))
+
+			_, err := f.service.UndoMerge(context.Background(), tt.organization, "admin-1", mergeID)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.False(t, f.dupeExists(t), "the merged site stays merged")
+			assert.Equal(t, f.depot.ID, f.siteIDOf(t, inspection.ID))
+		})
+	}
+}
+
+func TestSiteMergeService_GetMergesForSite(t *testing.T) {
+	f := newSiteMergeTestFixture(t)
+	ctx := context.Background()
+
+	merge := f.merge(t)
+	_, err := f.service.UndoMerge(ctx, "org-a", "admin-1", merge.ID)
+	require.NoError(t, err)
+	f.merge(t)
+
+	merges, err := f.service.GetMerges(ctx, "org-a", map[string]interface{}{"site_id": f.dupe.ID})
+	require.NoError(t, err)
+	assert.Len(t, merges, 2)
+	merges, err = f.service.GetMerges(ctx, "org-a", map[string]interface{}{"site_id": f.riverside.ID})
+	require.NoError(t, err)
+	assert.Empty(t, merges)
+}
+
+func TestSiteMergeService_MergeRepointsSiteLists(t *testing.T) {
+	tests := []struct {
+		name  string
+		table string
+		list  func(db *gorm.DB, siteIDs datatypes.JSON) (string, error)
+		read  func(db *gorm.DB, id string) (datatypes.JSON, error)
+	}{
+		{"assignment", "inspection_assignments",
+			func(db *gorm.DB, siteIDs datatypes.JSON) (string, error) {
+				assignment := &models.InspectionAssignment{OrganizationID: "org-a", AssignedBy: "admin-1", AssignedTo: "inspector-1", TemplateID: uuid.NewString(), SiteIDs: siteIDs}
+				return assignment.ID, db.Create(assignment).Error
+			},
+			func(db *gorm.DB, id string) (datatypes.JSON, error) {
+				var assignment models.InspectionAssignment
+				return assignment.SiteIDs, db.First(&assignment, "id = ?", id).Error
+			}},
+		{"open pool item", "open_pool_items",
+			func(db *gorm.DB, siteIDs datatypes.JSON) (string, error) {
+				item := &models.OpenPoolItem{ID: uuid.NewString(), OrganizationID: "org-a", TemplateID: uuid.NewString(), SiteIDs: siteIDs,
+					Title: "Depot sweep", Status: models.OpenPoolItemOpen, PublishedBy: "admin-1"}
+				return item.ID, db.Create(item).Error
+			},
+			func(db *gorm.DB, id string) (datatypes.JSON, error) {
+				var item models.OpenPoolItem
+				return item.SiteIDs, db.First(&item, "id = ?", id).Error
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSiteMergeTestFixture(t)
+			ctx := context.Background()
+			depot, dupe, office := f.depot.ID, f.dupe.ID, f.riverside.ID
+			original := `["` + dupe + `","` + office + `","` + depot + `"]`
+			id, err := tt.list(f.db, datatypes.JSON(original))
+			require.NoError(t, err)
+
+			merge, err := f.service.MergeSites(ctx, "org-a", "admin-1", depot, dupe)
+			require.NoError(t, err)
+			counts := map[string]int{}
+			require.NoError(t, json.Unmarshal(merge.Counts, &counts))
+			assert.Equal(t, 1, counts[tt.table])
+
+			siteIDs, err := tt.read(f.db, id)
+			require.NoError(t, err)
+			assert.JSONEq(t, `["`+depot+`","`+office+`"]`, string(siteIDs))
+
+			_, err = f.service.UndoMerge(ctx, "org-a", "admin-1", merge.ID)
+			require.NoError(t, err)
+			siteIDs, err = tt.read(f.db, id)
+			require.NoError(t, err)
+			assert.JSONEq(t, original, string(siteIDs))
+		})
+	}
+}
@@ -1,32 +1,55 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"resource-mgmt/models"
+	"resource-mgmt/pkg/database"
+	"strings"
 
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
 type SiteService struct {
-	db *gorm.DB
+	db       *gorm.DB
+	geocoder Geocoder // nil when geocoding is off
 }
 
 func NewSiteService(db *gorm.DB) *SiteService {
-	return &SiteService{db: db}
+	return &SiteService{db: db, geocoder: DefaultGeocoder()}
 }
 
 // GetSites retrieves sites with filtering, search, and pagination
-func (s *SiteService) GetSites(filters map[string]interface{}, search string, page, limit int) ([]models.Site, int64, error) {
+func (s *SiteService) GetSites(ctx context.Context, filters map[string]interface{}, search string, page, limit int) ([]models.Site, int64, error) {
+	db := database.Conn(ctx, s.db)
+
 	var sites []models.Site
 	var total int64
 
-	query := s.db.Model(&models.Site{})
+	query := db.Model(&models.Site{})
 
-	// Apply filters
+	// Apply filters; keys prefixed "custom." filter on custom fields
+	customFilters := make(map[string]string)
 	for key, value := range filters {
+		if strings.HasPrefix(key, customFieldColumnPrefix) {
+			customFilters[strings.TrimPrefix(key, customFieldColumnPrefix)] = fmt.Sprint(value)
+			continue
+		}
 		query = query.Where(fmt.Sprintf("%s = ?", key), value)
 	}
+	if len(customFilters) > 0 {
+		organizationID, _ := filters["organization_id"].(string)
+		fields, err := NewSiteCustomFieldService(s.db).GetFields(ctx, organizationID)
+		if err != nil {
+			return nil, 0, err
+		}
+		if query, err = applyCustomFieldFilters(query, fields, customFilters); err != nil {
+			return nil, 0, err
+		}
+	}
 
 	// Apply search
 	if search != "" {
@@ -56,9 +79,11 @@ func (s *SiteService) GetSites(filters map[string]interface{}, search string, pa
 }
 
 // GetSite retrieves a single site by ID
-func (s *SiteService) GetSite(siteID, organizationID string) (*models.Site, error) {
+func (s *SiteService) GetSite(ctx context.Context, siteID, organizationID string) (*models.Site, error) {
+	db := database.Conn(ctx, s.db)
+
 	var site models.Site
-	if err := s.db.
+	if err := db.
 		Where("id = ? AND organization_id = ?", siteID, organizationID).
 		First(&site).Error; err != nil {
 		return nil, err
@@ -67,7 +92,9 @@ func (s *SiteService) GetSite(siteID, organizationID string) (*models.Site, erro
 }
 
 // CreateSite creates a new site
-func (s *SiteService) CreateSite(site *models.Site) (*models.Site, error) {
+func (s *SiteService) CreateSite(ctx context.Context, site *models.Site) (*models.Site, error) {
+	db := database.Conn(ctx, s.db)
+
 	// Validate required fields
 	if site.Name == "" || site.Address == "" {
 		return nil, errors.New("name and address are required")
@@ -81,37 +108,132 @@ func (s *SiteService) CreateSite(site *models.Site) (*models.Site, error) {
 		site.Country = "USA"
 	}
 
+	if err := validateTimezone(site.Timezone); err != nil {
+		return nil, err
+	}
+
+	// Custom fields must satisfy the organization's schema
+	metadata, err := NewSiteCustomFieldService(s.db).ValidateMetadata(ctx, site.OrganizationID, "", site.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	site.Metadata = metadata
+
+	// Coordinates given with the site are manual overrides; otherwise look them up
+	site.GeocodedAt = nil
+	if site.Latitude != nil && site.Longitude != nil {
+		site.CoordinatesSource = models.CoordinatesSourceManual
+	} else {
+		site.Latitude, site.Longitude, site.CoordinatesSource = nil, nil, ""
+		s.geocodeOnSave(site)
+	}
+
 	// Create site
-	if err := s.db.Create(site).Error; err != nil {
+	if err := db.Create(site).Error; err != nil {
 		return nil, err
 	}
 
 	// Reload with relationships
-	return s.GetSite(site.ID, site.OrganizationID)
+	return s.GetSite(ctx, site.ID, site.OrganizationID)
 }
 
-// UpdateSite updates an existing site
-func (s *SiteService) UpdateSite(siteID, organizationID string, updates map[string]interface{}) (*models.Site, error) {
+// UpdateSite updates an existing site. Setting latitude and longitude makes them a manual
+// override that geocoding never replaces; clearing them hands the site back to the geocoder.
+// Geocoded coordinates are refreshed when the address changes.
+func (s *SiteService) UpdateSite(ctx context.Context, siteID, organizationID string, updates map[string]interface{}) (*models.Site, error) {
+	db := database.Conn(ctx, s.db)
+
 	// Check if site exists
 	var site models.Site
-	if err := s.db.Where("id = ? AND organization_id = ?", siteID, organizationID).First(&site).Error; err != nil {
+	if err := db.Where("id = ? AND organization_id = ?", siteID, organizationID).First(&site).Error; err != nil {
 		return nil, err
 	}
 
+	// Coordinate provenance is derived, never taken from the caller
+	delete(updates, "coordinates_source")
+	delete(updates, "geocoded_at")
+
+	if timezone, ok := updates["timezone"]; ok {
+		name, isString := timezone.(string)
+		if !isString {
+			return nil, fmt.Errorf("%w: timezone must be a string", ErrInvalidTimezone)
+		}
+		if err := validateTimezone(name); err != nil {
+			return nil, err
+		}
+	}
+
+	// A metadata update replaces the whole object, which must satisfy the schema
+	customFields := NewSiteCustomFieldService(s.db)
+	_, setsMetadata := updates["metadata"]
+	if setsMetadata {
+		encoded, err := json.Marshal(updates["metadata"])
+		if err != nil {
+			return nil, fmt.Errorf("%w: metadata must be a JSON object", ErrInvalidSiteMetadata)
+		}
+		metadata, err := customFields.ValidateMetadata(ctx, organizationID, siteID, datatypes.JSON(encoded))
+		if err != nil {
+			return nil, err
+		}
+		updates["metadata"] = metadata
+	}
+
+	regeocode := false
+	_, setsLatitude := updates["latitude"]
+	_, setsLongitude := updates["longitude"]
+	if setsLatitude || setsLongitude {
+		if !setsLatitude || !setsLongitude || (updates["latitude"] == nil) != (updates["longitude"] == nil) {
+			return nil, fmt.Errorf("%w: latitude and longitude must be set or cleared together", ErrInvalidCoordinates)
+		}
+		if updates["latitude"] != nil {
+			updates["coordinates_source"] = models.CoordinatesSourceManual
+		} else {
+			updates["coordinates_source"] = ""
+			regeocode = true
+		}
+		updates["geocoded_at"] = nil
+	} else if site.CoordinatesSource != models.CoordinatesSourceManual {
+		for _, column := range siteAddressColumns {
+			if value, ok := updates[column].(string); ok && !strings.EqualFold(value, siteAddressValue(&site, column)) {
+				regeocode = true
+			}
+		}
+	}
+
 	// Update site
-	if err := s.db.Model(&site).Updates(updates).Error; err != nil {
+	if err := db.Model(&site).Updates(updates).Error; err != nil {
 		return nil, err
 	}
 
+	if setsMetadata {
+		if err := customFields.ClearViolations(ctx, organizationID, siteID); err != nil {
+			return nil, err
+		}
+	}
+
+	if regeocode {
+		updated, err := s.GetSite(ctx, siteID, organizationID)
+		if err != nil {
+			return nil, err
+		}
+		if s.geocodeOnSave(updated) {
+			if _, err := saveGeocodedCoordinates(db, updated); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	// Return updated site
-	return s.GetSite(siteID, organizationID)
+	return s.GetSite(ctx, siteID, organizationID)
 }
 
 // DeleteSite soft deletes a site
-func (s *SiteService) DeleteSite(siteID, organizationID string) error {
+func (s *SiteService) DeleteSite(ctx context.Context, siteID, organizationID string) error {
+	db := database.Conn(ctx, s.db)
+
 	// Check if site has active inspections
 	var inspectionCount int64
-	if err := s.db.Model(&models.Inspection{}).
+	if err := db.Model(&models.Inspection{}).
 		Where("site_id = ? AND status IN ('draft', 'in_progress', 'requires_review')", siteID).
 		Count(&inspectionCount).Error; err != nil {
 		return err
@@ -122,16 +244,18 @@ func (s *SiteService) DeleteSite(siteID, organizationID string) error {
 	}
 
 	// Soft delete site
-	return s.db.Where("id = ? AND organization_id = ?", siteID, organizationID).Delete(&models.Site{}).Error
+	return db.Where("id = ? AND organization_id = ?", siteID, organizationID).Delete(&models.Site{}).Error
 }
 
 // GetSiteStats retrieves statistics for a site
-func (s *SiteService) GetSiteStats(siteID, organizationID string) (*models.SiteStats, error) {
+func (s *SiteService) GetSiteStats(ctx context.Context, siteID, organizationID string) (*models.SiteStats, error) {
+	db := database.Conn(ctx, s.db)
+
 	var stats models.SiteStats
 
 	// Verify site exists
 	var site models.Site
-	if err := s.db.Where("id = ? AND organization_id = ?", siteID, organizationID).First(&site).Error; err != nil {
+	if err := db.Where("id = ? AND organization_id = ?", siteID, organizationID).First(&site).Error; err != nil {
 		return nil, err
 	}
 
@@ -140,30 +264,30 @@ func (s *SiteService) GetSiteStats(siteID, organizationID string) (*models.SiteS
 	// Get inspection counts (using temporary int64 variables for Count)
 	var totalCount, completedCount, pendingCount int64
 
-	s.db.Model(&models.Inspection{}).
+	db.Model(&models.Inspection{}).
 		Where("site_id = ?", siteID).
 		Count(&totalCount)
 	stats.TotalInspections = int(totalCount)
 
-	s.db.Model(&models.Inspection{}).
+	db.Model(&models.Inspection{}).
 		Where("site_id = ? AND status IN ('completed', 'approved')", siteID).
 		Count(&completedCount)
 	stats.CompletedInspections = int(completedCount)
 
-	s.db.Model(&models.Inspection{}).
+	db.Model(&models.Inspection{}).
 		Where("site_id = ? AND status IN ('draft', 'in_progress', 'requires_review')", siteID).
 		Count(&pendingCount)
 	stats.PendingInspections = int(pendingCount)
 
 	// Get last inspection date
-	s.db.Model(&models.Inspection{}).
+	db.Model(&models.Inspection{}).
 		Where("site_id = ? AND completed_at IS NOT NULL", siteID).
 		Order("completed_at DESC").
 		Limit(1).
 		Pluck("completed_at", &stats.LastInspectionDate)
 
 	// Get next scheduled inspection
-	s.db.Model(&models.Inspection{}).
+	db.Model(&models.Inspection{}).
 		Where("site_id = ? AND scheduled_for > NOW()", siteID).
 		Order("scheduled_for ASC").
 		Limit(1).
@@ -172,26 +296,35 @@ func (s *SiteService) GetSiteStats(siteID, organizationID string) (*models.SiteS
 	// Count critical issues (would need inspection_data analysis)
 	// This is a simplified version - in reality, you'd analyze inspection_data
 	var criticalCount int64
-	s.db.Model(&models.Inspection{}).
+	db.Model(&models.Inspection{}).
 		Where("site_id = ? AND notes ILIKE '%critical%'", siteID).
 		Count(&criticalCount)
 	stats.CriticalIssues = int(criticalCount)
 
+	// Break the counts down by location, rolled up the hierarchy
+	locations, err := NewLocationService(s.db).GetSiteLocationStats(ctx, organizationID, siteID)
+	if err != nil {
+		return nil, err
+	}
+	stats.Locations = locations
+
 	return &stats, nil
 }
 
 // GetSiteInspections retrieves inspections for a site
-func (s *SiteService) GetSiteInspections(siteID, organizationID string, page, limit int) ([]models.Inspection, int64, error) {
+func (s *SiteService) GetSiteInspections(ctx context.Context, siteID, organizationID string, page, limit int) ([]models.Inspection, int64, error) {
+	db := database.Conn(ctx, s.db)
+
 	var inspections []models.Inspection
 	var total int64
 
 	// Verify site exists
 	var site models.Site
-	if err := s.db.Where("id = ? AND organization_id = ?", siteID, organizationID).First(&site).Error; err != nil {
+	if err := db.Where("id = ? AND organization_id = ?", siteID, organizationID).First(&site).Error; err != nil {
 		return nil, 0, err
 	}
 
-	query := s.db.Model(&models.Inspection{}).Where("site_id = ?", siteID)
+	query := db.Model(&models.Inspection{}).Where("site_id = ?", siteID)
 
 	// Count total
 	if err := query.Count(&total).Error; err != nil {
@@ -213,9 +346,11 @@ func (s *SiteService) GetSiteInspections(siteID, organizationID string, page, li
 }
 
 // GetActiveSites retrieves all active sites for dropdown use
-func (s *SiteService) GetActiveSites(organizationID string) ([]models.Site, error) {
+func (s *SiteService) GetActiveSites(ctx context.Context, organizationID string) ([]models.Site, error) {
+	db := database.Conn(ctx, s.db)
+
 	var sites []models.Site
-	if err := s.db.
+	if err := db.
 		Where("organization_id = ? AND status = 'active'", organizationID).
 		Order("name ASC").
 		Find(&sites).Error; err != nil {
@@ -225,11 +360,13 @@ func (s *SiteService) GetActiveSites(organizationID string) ([]models.Site, erro
 }
 
 // GetRecentSites retrieves recently used sites for a user
-func (s *SiteService) GetRecentSites(organizationID string, limit int) ([]models.Site, error) {
+func (s *SiteService) GetRecentSites(ctx context.Context, organizationID string, limit int) ([]models.Site, error) {
+	db := database.Conn(ctx, s.db)
+
 	var siteIDs []string
 
 	// Get site IDs from recent inspections
-	if err := s.db.Model(&models.Inspection{}).
+	if err := db.Model(&models.Inspection{}).
 		Where("organization_id = ? AND site_id IS NOT NULL", organizationID).
 		Order("created_at DESC").
 		Limit(limit * 2). // Get more to account for duplicates
@@ -244,7 +381,7 @@ func (s *SiteService) GetRecentSites(organizationID string, limit int) ([]models
 
 	// Get the actual sites
 	var sites []models.Site
-	if err := s.db.
+	if err := db.
 		Where("id IN ? AND status = 'active'", siteIDs).
 		Order("name ASC").
 		Limit(limit).
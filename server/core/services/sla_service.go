@@ -0,0 +1,948 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"resource-mgmt/models"
+	"resource-mgmt/pkg/database"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// SLA errors
+var (
+	// ErrInvalidSLAPolicy is returned when a policy's targets or escalation ladder are malformed
+	ErrInvalidSLAPolicy = errors.New("invalid SLA policy")
+	// ErrSLAPolicyNotFound is returned when the policy doesn't exist in the organization
+	ErrSLAPolicyNotFound = errors.New("SLA policy not found")
+	// ErrSLAPolicyExists is returned when another policy already covers the priority and inspection type
+	ErrSLAPolicyExists = errors.New("an SLA policy already covers this priority and inspection type")
+	// ErrSLAPolicyInUse is returned when deleting a policy that has timed work; deactivate it instead
+	ErrSLAPolicyInUse = errors.New("SLA policy has clocks and can only be deactivated")
+	// ErrInvalidSLATarget is returned when clocks are asked for something other than an
+	// assignment or inspection
+	ErrInvalidSLATarget = errors.New("SLA clocks are kept for assignments and inspections only")
+	// ErrSLAClockNotRunning is returned when there are no clocks to pause or resume
+	ErrSLAClockNotRunning = errors.New("no SLA clocks to pause or resume")
+)
+
+// slaPriorities are the priorities a policy may be scoped to; empty matches any
+var slaPriorities = []string{"", "low", "medium", "high", "critical"}
+
+// slaTargetTypes are the kinds of work that carry SLA clocks
+var slaTargetTypes = []string{models.SLATargetAssignment, models.SLATargetInspection}
+
+// slaRecipients are who an escalation step may notify
+var slaRecipients = []string{models.SLANotifyAssignee, models.SLANotifySupervisor, models.SLANotifyProjectManager}
+
+// defaultSLAEscalation reminds the assignee an hour before the deadline, tells the
+// supervisor when it's missed and the project manager four hours later
+var defaultSLAEscalation = []models.SLAEscalationStep{
+	{Notify: models.SLANotifyAssignee, OffsetMinutes: -60},
+	{Notify: models.SLANotifySupervisor, OffsetMinutes: 0},
+	{Notify: models.SLANotifyProjectManager, OffsetMinutes: 240},
+}
+
+// slaAlertType is the WorkflowAlert type raised when a clock breaches
+const slaAlertType = "overdue"
+
+// SLAService keeps clocks on assignments and inspections against the organization's SLA
+// policies, escalates as deadlines approach and pass, and records breaches
+type SLAService struct {
+	db                  *gorm.DB
+	notificationService *NotificationService
+}
+
+func NewSLAService(db *gorm.DB, notificationService *NotificationService) *SLAService {
+	return &SLAService{db: db, notificationService: notificationService}
+}
+
+// =====================================================
+// POLICIES
+// =====================================================
+
+// GetPolicies lists the organization's policies, most general first
+func (s *SLAService) GetPolicies(ctx context.Context, organizationID string) ([]models.SLAPolicy, error) {
+	var policies []models.SLAPolicy
+	if err := database.Conn(ctx, s.db).Where("organization_id = ?", organizationID).
+		Order("priority ASC, inspection_type ASC").Find(&policies).Error; err != nil {
+		return nil, fmt.Errorf("failed to get SLA policies: %v", err)
+	}
+	return policies, nil
+}
+
+// GetPolicy returns one of the organization's policies
+func (s *SLAService) GetPolicy(ctx context.Context, organizationID, policyID string) (*models.SLAPolicy, error) {
+	var policy models.SLAPolicy
+	if err := database.Conn(ctx, s.db).Where("id = ? AND organization_id = ?", policyID, organizationID).First(&policy).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSLAPolicyNotFound
+		}
+		return nil, fmt.Errorf("failed to get SLA policy: %v", err)
+	}
+	return &policy, nil
+}
+
+// CreatePolicy adds a policy for a priority and inspection type not covered yet
+func (s *SLAService) CreatePolicy(ctx context.Context, organizationID, userID string, req *models.SLAPolicyRequest) (*models.SLAPolicy, error) {
+	policy := &models.SLAPolicy{OrganizationID: organizationID, CreatedBy: userID}
+	if err := s.applyPolicyRequest(ctx, policy, req); err != nil {
+		return nil, err
+	}
+	if err := database.Conn(ctx, s.db).Create(policy).Error; err != nil {
+		return nil, fmt.Errorf("failed to save SLA policy: %v", err)
+	}
+	return policy, nil
+}
+
+// UpdatePolicy replaces a policy's scope, targets and ladder. Running clocks keep the
+// deadlines they started with; new clocks use the new targets.
+func (s *SLAService) UpdatePolicy(ctx context.Context, organizationID, policyID string, req *models.SLAPolicyRequest) (*models.SLAPolicy, error) {
+	policy, err := s.GetPolicy(ctx, organizationID, policyID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyPolicyRequest(ctx, policy, req); err != nil {
+		return nil, err
+	}
+	if err := database.Conn(ctx, s.db).Save(policy).Error; err != nil {
+		return nil, fmt.Errorf("failed to save SLA policy: %v", err)
+	}
+	return policy, nil
+}
+
+// DeletePolicy removes a policy that never timed any work
+func (s *SLAService) DeletePolicy(ctx context.Context, organizationID, policyID string) (*models.SLAPolicy, error) {
+	policy, err := s.GetPolicy(ctx, organizationID, policyID)
+	if err != nil {
+		return nil, err
+	}
+
+	db := database.Conn(ctx, s.db)
+	var clocks int64
+	if err := db.Model(&models.SLAClock{}).Where("policy_id = ?", policy.ID).Count(&clocks).Error; err != nil {
+		return nil, fmt.Errorf("failed to count SLA clocks: %v", err)
+	}
+	if clocks > 0 {
+		return nil, ErrSLAPolicyInUse
+	}
+	if err := db.Delete(policy).Error; err != nil {
+		return nil, fmt.Errorf("failed to delete SLA policy: %v", err)
+	}
+	return policy, nil
+}
+
+// applyPolicyRequest validates req and copies it onto policy
+func (s *SLAService) applyPolicyRequest(ctx context.Context, policy *models.SLAPolicy, req *models.SLAPolicyRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidSLAPolicy)
+	}
+	priority := strings.ToLower(strings.TrimSpace(req.Priority))
+	if !containsString(slaPriorities, priority) {
+		return fmt.Errorf("%w: priority must be low, medium, high or critical", ErrInvalidSLAPolicy)
+	}
+	targets := []int{req.TimeToAcceptMinutes, req.TimeToStartMinutes, req.TimeToCompleteMinutes, req.TimeToReviewMinutes}
+	tracked := false
+	for _, minutes := range targets {
+		if minutes < 0 {
+			return fmt.Errorf("%w: targets can't be negative", ErrInvalidSLAPolicy)
+		}
+		tracked = tracked || minutes > 0
+	}
+	if !tracked {
+		return fmt.Errorf("%w: at least one target is required", ErrInvalidSLAPolicy)
+	}
+
+	ladder := req.Escalation
+	if len(ladder) == 0 {
+		ladder = defaultSLAEscalation
+	}
+	for i, step := range ladder {
+		if !containsString(slaRecipients, step.Notify) {
+			return fmt.Errorf("%w: escalation step %d must notify assignee, supervisor or project_manager", ErrInvalidSLAPolicy, i+1)
+		}
+		if i > 0 && step.OffsetMinutes < ladder[i-1].OffsetMinutes {
+			return fmt.Errorf("%w: escalation steps must be in order of their offsets", ErrInvalidSLAPolicy)
+		}
+	}
+	escalation, err := json.Marshal(ladder)
+	if err != nil {
+		return fmt.Errorf("failed to encode escalation ladder: %v", err)
+	}
+
+	inspectionType := strings.TrimSpace(req.InspectionType)
+	var overlapping int64
+	query := database.Conn(ctx, s.db).Model(&models.SLAPolicy{}).
+		Where("organization_id = ? AND priority = ? AND inspection_type = ?", policy.OrganizationID, priority, inspectionType)
+	if policy.ID != "" {
+		query = query.Where("id <> ?", policy.ID)
+	}
+	if err := query.Count(&overlapping).Error; err != nil {
+		return fmt.Errorf("failed to check SLA policies: %v", err)
+	}
+	if overlapping > 0 {
+		return ErrSLAPolicyExists
+	}
+
+	policy.Name = name
+	policy.Priority = priority
+	policy.InspectionType = inspectionType
+	policy.TimeToAcceptMinutes = req.TimeToAcceptMinutes
+	policy.TimeToStartMinutes = req.TimeToStartMinutes
+	policy.TimeToCompleteMinutes = req.TimeToCompleteMinutes
+	policy.TimeToReviewMinutes = req.TimeToReviewMinutes
+	policy.Escalation = datatypes.JSON(escalation)
+	policy.IsActive = req.IsActive == nil || *req.IsActive
+	return nil
+}
+
+// policyFor returns the most specific active policy for work of the priority and
+// inspection type, or nil when none applies. Priority outranks inspection type.
+func (s *SLAService) policyFor(ctx context.Context, organizationID, priority, inspectionType string) (*models.SLAPolicy, error) {
+	var policies []models.SLAPolicy
+	if err := database.Conn(ctx, s.db).
+		Where("organization_id = ? AND is_active = ? AND priority IN ? AND LOWER(inspection_type) IN ?",
+			organizationID, true, []string{"", strings.ToLower(priority)}, []string{"", strings.ToLower(inspectionType)}).
+		Find(&policies).Error; err != nil {
+		return nil, fmt.Errorf("failed to get SLA policies: %v", err)
+	}
+
+	var best *models.SLAPolicy
+	bestRank := -1
+	for i := range policies {
+		rank := 0
+		if policies[i].Priority != "" {
+			rank += 2
+		}
+		if policies[i].InspectionType != "" {
+			rank++
+		}
+		if rank > bestRank {
+			best, bestRank = &policies[i], rank
+		}
+	}
+	return best, nil
+}
+
+// slaTargetMinutes is the policy's target for a stage, 0 when it isn't tracked
+func slaTargetMinutes(policy *models.SLAPolicy, stage string) int {
+	switch stage {
+	case models.SLAStageAccept:
+		return policy.TimeToAcceptMinutes
+	case models.SLAStageStart:
+		return policy.TimeToStartMinutes
+	case models.SLAStageComplete:
+		return policy.TimeToCompleteMinutes
+	case models.SLAStageReview:
+		return policy.TimeToReviewMinutes
+	}
+	return 0
+}
+
+// slaEscalation decodes a policy's ladder
+func slaEscalation(policy *models.SLAPolicy) []models.SLAEscalationStep {
+	var ladder []models.SLAEscalationStep
+	if err := json.Unmarshal(policy.Escalation, &ladder); err != nil {
+		log.Printf("Failed to read escalation ladder of SLA policy %s: %v", policy.ID, err)
+	}
+	return ladder
+}
+
+// =====================================================
+// CLOCKS
+// =====================================================
+
+// slaStage is where one stage of a piece of work stands. A stage that isn't ready hasn't
+// begun; its clock starts at origin, or when it's first seen if that is later.
+type slaStage struct {
+	name   string
+	ready  bool
+	origin *time.Time
+	met    *time.Time
+}
+
+// slaWork is an assignment or inspection as the SLA clocks see it
+type slaWork struct {
+	organizationID   string
+	targetType       string
+	targetID         string
+	siteID           string // Whose time zone the deadlines are worked out in; empty for the organization's
+	priority         string
+	inspectionType   string
+	assigneeID       string
+	supervisorID     string
+	projectManagerID string
+	cancelled        bool
+	stages           []slaStage
+}
+
+// TrackInspection brings an inspection's start, complete and review clocks up to date.
+// Deleted and cancelled inspections stop their clocks.
+func (s *SLAService) TrackInspection(ctx context.Context, organizationID, inspectionID string) error {
+	db := database.Conn(ctx, s.db)
+	var inspection models.Inspection
+	if err := db.Unscoped().Where("id = ? AND organization_id = ?", inspectionID, organizationID).First(&inspection).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			gone := &slaWork{organizationID: organizationID, targetType: models.SLATargetInspection, targetID: inspectionID, cancelled: true}
+			return s.track(ctx, gone, time.Now())
+		}
+		return fmt.Errorf("failed to get inspection: %v", err)
+	}
+
+	work := &slaWork{
+		organizationID: organizationID,
+		targetType:     models.SLATargetInspection,
+		targetID:       inspection.ID.String(),
+		siteID:         inspection.SiteID,
+		priority:       inspection.Priority,
+		assigneeID:     inspection.InspectorID,
+		cancelled:      inspection.DeletedAt.Valid || inspection.Status == "cancelled",
+	}
+	if inspection.AssignedBy != nil {
+		work.supervisorID = *inspection.AssignedBy
+	}
+	var template models.Template
+	if err := db.Select("id", "category").Where("id = ?", inspection.TemplateID).First(&template).Error; err == nil {
+		work.inspectionType = template.Category
+	}
+	if inspection.AssignmentID != nil {
+		var assignment models.InspectionAssignment
+		if err := db.Select("id", "project_id").Where("id = ?", *inspection.AssignmentID).First(&assignment).Error; err == nil {
+			work.projectManagerID = s.projectManager(ctx, assignment.ProjectID)
+		}
+	}
+
+	assigned := inspection.InspectorID != ""
+	finished := containsString(finishedInspectionStatuses, inspection.Status)
+	started := inspection.StartedAt
+	if started == nil && finished {
+		started = inspection.CompletedAt
+	}
+	var completed, reviewed *time.Time
+	if finished {
+		completed = inspection.CompletedAt
+		if completed == nil {
+			completed = &inspection.UpdatedAt
+		}
+	}
+	if inspection.Status == "approved" || inspection.Status == "rejected" {
+		reviewed = &inspection.UpdatedAt
+	}
+
+	// A submitted review also ends the review stage. While it waits for one, reminders go
+	// to whoever is reviewing it, or to the supervisor when nobody is yet.
+	if finished && reviewed == nil {
+		var reviews []models.InspectionReview
+		if err := db.Select("id", "reviewer_id", "escalated_to", "completed_at").
+			Where("inspection_id = ?", work.targetID).Order("assigned_at DESC").Find(&reviews).Error; err != nil {
+			return fmt.Errorf("failed to get inspection reviews: %v", err)
+		}
+		work.assigneeID = work.supervisorID
+		for _, review := range reviews {
+			if review.CompletedAt != nil {
+				reviewed = review.CompletedAt
+				break
+			}
+		}
+		if reviewed == nil && len(reviews) > 0 {
+			work.assigneeID = reviews[0].ReviewerID
+			if reviews[0].EscalatedTo != nil {
+				work.assigneeID = *reviews[0].EscalatedTo
+			}
+		}
+	}
+
+	work.stages = []slaStage{
+		{name: models.SLAStageStart, ready: assigned, origin: inspection.ScheduledFor, met: started},
+		{name: models.SLAStageComplete, ready: assigned, origin: inspection.ScheduledFor, met: completed},
+		{name: models.SLAStageReview, ready: finished, origin: completed, met: reviewed},
+	}
+	return s.track(ctx, work, time.Now())
+}
+
+// TrackAssignment brings an assignment's accept, start and complete clocks up to date.
+// The assignment starts with its first inspection and completes with its last.
+func (s *SLAService) TrackAssignment(ctx context.Context, organizationID, assignmentID string) error {
+	db := database.Conn(ctx, s.db)
+	var assignment models.InspectionAssignment
+	if err := db.Where("id = ? AND organization_id = ?", assignmentID, organizationID).First(&assignment).Error; err != nil {
+		return fmt.Errorf("failed to get assignment: %v", err)
+	}
+
+	work := &slaWork{
+		organizationID:   organizationID,
+		targetType:       models.SLATargetAssignment,
+		targetID:         assignment.ID,
+		priority:         assignment.Priority,
+		assigneeID:       assignment.AssignedTo,
+		supervisorID:     assignment.AssignedBy,
+		projectManagerID: s.projectManager(ctx, assignment.ProjectID),
+		cancelled:        assignment.Status == "rejected" || assignment.Status == "cancelled",
+	}
+	var template models.Template
+	if err := db.Select("id", "category").Where("id = ?", assignment.TemplateID).First(&template).Error; err == nil {
+		work.inspectionType = template.Category
+	}
+
+	var inspections []models.Inspection
+	if err := db.Select("id", "status", "started_at", "completed_at").
+		Where("assignment_id = ?", assignment.ID).Find(&inspections).Error; err != nil {
+		return fmt.Errorf("failed to get assignment inspections: %v", err)
+	}
+	var started, completed *time.Time
+	allClosed := len(inspections) > 0
+	for _, inspection := range inspections {
+		first := inspection.StartedAt
+		if first == nil {
+			first = inspection.CompletedAt
+		}
+		if first != nil && (started == nil || first.Before(*started)) {
+			started = first
+		}
+		if !containsString(closedInspectionStatuses, inspection.Status) {
+			allClosed = false
+		} else if inspection.CompletedAt != nil && (completed == nil || inspection.CompletedAt.After(*completed)) {
+			completed = inspection.CompletedAt
+		}
+	}
+	if !allClosed {
+		completed = nil
+	} else if completed == nil {
+		now := time.Now()
+		completed = &now
+	}
+
+	accepted := !assignment.RequiresAcceptance || assignment.AcceptedAt != nil
+	origin := assignment.StartDate
+	work.stages = []slaStage{
+		{name: models.SLAStageAccept, ready: assignment.RequiresAcceptance, origin: &assignment.AssignedAt, met: assignment.AcceptedAt},
+		{name: models.SLAStageStart, ready: accepted, origin: origin, met: started},
+		{name: models.SLAStageComplete, ready: accepted, origin: origin, met: completed},
+	}
+	return s.track(ctx, work, time.Now())
+}
+
+// InspectionChanged tracks an inspection that was created, updated or deleted, and the
+// assignment it belongs to. It runs in a savepoint, so callers can log a failure without
+// it aborting the request transaction the change is part of.
+func (s *SLAService) InspectionChanged(ctx context.Context, inspection *models.Inspection) error {
+	return database.Conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		txCtx := database.ContextWithTx(ctx, tx)
+		if err := s.TrackInspection(txCtx, inspection.OrganizationID, inspection.ID.String()); err != nil {
+			return err
+		}
+		if inspection.AssignmentID != nil {
+			return s.TrackAssignment(txCtx, inspection.OrganizationID, *inspection.AssignmentID)
+		}
+		return nil
+	})
+}
+
+// projectManager returns the manager of the project, if any
+func (s *SLAService) projectManager(ctx context.Context, projectID *string) string {
+	if projectID == nil {
+		return ""
+	}
+	var project models.InspectionProject
+	if err := database.Conn(ctx, s.db).Select("id", "project_manager").Where("id = ?", *projectID).First(&project).Error; err != nil {
+		return ""
+	}
+	return project.ProjectManager
+}
+
+// track brings the work's clocks up to date in a savepoint, so a failure leaves them
+// and the request transaction as they were
+func (s *SLAService) track(ctx context.Context, work *slaWork, now time.Time) error {
+	return database.Conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		return s.trackClocks(database.ContextWithTx(ctx, tx), work, now)
+	})
+}
+
+// trackClocks starts, stops and restarts the work's clocks to match its stages. A stage that
+// is reached again after being undone, like a reopened inspection, gets a fresh clock.
+func (s *SLAService) trackClocks(ctx context.Context, work *slaWork, now time.Time) error {
+	db := database.Conn(ctx, s.db)
+
+	var existing []models.SLAClock
+	if err := db.Where("target_type = ? AND target_id = ?", work.targetType, work.targetID).Find(&existing).Error; err != nil {
+		return fmt.Errorf("failed to get SLA clocks: %v", err)
+	}
+	clocks := make(map[string]*models.SLAClock, len(existing))
+	for i := range existing {
+		clocks[existing[i].Stage] = &existing[i]
+	}
+
+	if work.cancelled {
+		for _, clock := range clocks {
+			if err := s.stopClock(ctx, clock, models.SLAClockCancelled, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	policy, err := s.policyFor(ctx, work.organizationID, work.priority, work.inspectionType)
+	if err != nil {
+		return err
+	}
+
+	var calendar *businessCalendar
+	for _, stage := range work.stages {
+		clock := clocks[stage.name]
+		open := clock != nil && (clock.Status == models.SLAClockRunning || clock.Status == models.SLAClockPaused)
+
+		switch {
+		case !stage.ready:
+			if open {
+				err = s.stopClock(ctx, clock, models.SLAClockCancelled, now)
+			}
+		case stage.met != nil:
+			if open {
+				err = s.stopClock(ctx, clock, models.SLAClockMet, *stage.met)
+			}
+		case open:
+			err = s.updateRecipients(ctx, clock, work)
+		case policy != nil && slaTargetMinutes(policy, stage.name) > 0:
+			// Not started yet, or reached before and undone since
+			start := now
+			if stage.origin != nil && stage.origin.After(now) {
+				start = *stage.origin
+			}
+			if calendar == nil {
+				if calendar, err = loadBusinessCalendar(ctx, s.db, work.organizationID, work.siteID); err != nil {
+					return err
+				}
+			}
+			err = s.startClock(ctx, clock, policy, calendar, work, stage.name, start)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startClock starts a stage's clock, reusing the row of an earlier, stopped clock. The
+// target counts business hours, so the deadline skips nights, weekends and holidays.
+func (s *SLAService) startClock(ctx context.Context, clock *models.SLAClock, policy *models.SLAPolicy, calendar *businessCalendar, work *slaWork, stage string, start time.Time) error {
+	if clock == nil {
+		clock = &models.SLAClock{OrganizationID: work.organizationID, TargetType: work.targetType, TargetID: work.targetID, Stage: stage}
+	}
+	clock.PolicyID = policy.ID
+	clock.Status = models.SLAClockRunning
+	clock.Priority = work.priority
+	clock.InspectionType = work.inspectionType
+	clock.StartedAt = start
+	clock.DeadlineAt = calendar.AddWorkingTime(start, time.Duration(slaTargetMinutes(policy, stage))*time.Minute)
+	clock.PausedAt = nil
+	clock.PauseReason = ""
+	clock.PausedSeconds = 0
+	clock.EscalationLevel = 0
+	clock.BreachedAt = nil
+	clock.StoppedAt = nil
+	clock.AssigneeID = work.assigneeID
+	clock.SupervisorID = work.supervisorID
+	clock.ProjectManagerID = work.projectManagerID
+	if err := database.Conn(ctx, s.db).Save(clock).Error; err != nil {
+		return fmt.Errorf("failed to start SLA clock: %v", err)
+	}
+	return nil
+}
+
+// stopClock stops an open clock as met or cancelled at the given time. A clock met
+// after its deadline without having been seen to breach records the breach now.
+func (s *SLAService) stopClock(ctx context.Context, clock *models.SLAClock, status string, at time.Time) error {
+	if clock.Status != models.SLAClockRunning && clock.Status != models.SLAClockPaused {
+		return nil
+	}
+	db := database.Conn(ctx, s.db)
+
+	if clock.PausedAt != nil {
+		if paused := at.Sub(*clock.PausedAt); paused > 0 {
+			calendar, err := s.clockCalendar(ctx, clock)
+			if err != nil {
+				return err
+			}
+			clock.DeadlineAt = calendar.AddWorkingTime(clock.DeadlineAt, calendar.WorkingTimeBetween(*clock.PausedAt, at))
+			clock.PausedSeconds += int64(paused / time.Second)
+		}
+		clock.PausedAt = nil
+	}
+	clock.Status = status
+	clock.StoppedAt = &at
+
+	if status == models.SLAClockMet {
+		if clock.BreachedAt == nil && at.After(clock.DeadlineAt) {
+			breachedAt := clock.DeadlineAt
+			clock.BreachedAt = &breachedAt
+			if err := db.Create(slaBreachFor(clock, &at)).Error; err != nil {
+				return fmt.Errorf("failed to record SLA breach: %v", err)
+			}
+		} else if clock.BreachedAt != nil {
+			if err := db.Model(&models.SLABreach{}).
+				Where("clock_id = ? AND met_at IS NULL", clock.ID).Update("met_at", at).Error; err != nil {
+				return fmt.Errorf("failed to update SLA breach: %v", err)
+			}
+		}
+	}
+
+	if err := db.Save(clock).Error; err != nil {
+		return fmt.Errorf("failed to stop SLA clock: %v", err)
+	}
+	return nil
+}
+
+// updateRecipients points an open clock's escalations at whoever holds the work now
+func (s *SLAService) updateRecipients(ctx context.Context, clock *models.SLAClock, work *slaWork) error {
+	if clock.AssigneeID == work.assigneeID && clock.SupervisorID == work.supervisorID && clock.ProjectManagerID == work.projectManagerID {
+		return nil
+	}
+	clock.AssigneeID = work.assigneeID
+	clock.SupervisorID = work.supervisorID
+	clock.ProjectManagerID = work.projectManagerID
+	if err := database.Conn(ctx, s.db).Model(clock).Updates(map[string]interface{}{
+		"assignee_id":        clock.AssigneeID,
+		"supervisor_id":      clock.SupervisorID,
+		"project_manager_id": clock.ProjectManagerID,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to update SLA clock: %v", err)
+	}
+	return nil
+}
+
+// clockCalendar loads the business calendar a clock's deadline is worked out in: the
+// inspection's site's, or the organization's for assignments
+func (s *SLAService) clockCalendar(ctx context.Context, clock *models.SLAClock) (*businessCalendar, error) {
+	siteID := ""
+	if clock.TargetType == models.SLATargetInspection {
+		var inspection models.Inspection
+		if err := database.Conn(ctx, s.db).Unscoped().Select("id", "site_id").Where("id = ?", clock.TargetID).First(&inspection).Error; err == nil {
+			siteID = inspection.SiteID
+		}
+	}
+	return loadBusinessCalendar(ctx, s.db, clock.OrganizationID, siteID)
+}
+
+func slaBreachFor(clock *models.SLAClock, metAt *time.Time) *models.SLABreach {
+	return &models.SLABreach{
+		OrganizationID: clock.OrganizationID,
+		ClockID:        clock.ID,
+		PolicyID:       clock.PolicyID,
+		TargetType:     clock.TargetType,
+		TargetID:       clock.TargetID,
+		Stage:          clock.Stage,
+		Priority:       clock.Priority,
+		InspectionType: clock.InspectionType,
+		AssigneeID:     clock.AssigneeID,
+		DeadlineAt:     clock.DeadlineAt,
+		BreachedAt:     *clock.BreachedAt,
+		MetAt:          metAt,
+	}
+}
+
+// GetClocks returns the clocks of an assignment or inspection
+func (s *SLAService) GetClocks(ctx context.Context, organizationID, targetType, targetID string) ([]models.SLAClock, error) {
+	if !containsString(slaTargetTypes, targetType) {
+		return nil, ErrInvalidSLATarget
+	}
+	var clocks []models.SLAClock
+	if err := database.Conn(ctx, s.db).
+		Where("organization_id = ? AND target_type = ? AND target_id = ?", organizationID, targetType, targetID).
+		Order("started_at ASC").Find(&clocks).Error; err != nil {
+		return nil, fmt.Errorf("failed to get SLA clocks: %v", err)
+	}
+	return clocks, nil
+}
+
+// PauseClocks stops the running clocks of an assignment or inspection while it waits on
+// an external party, like a site owner who has to grant access
+func (s *SLAService) PauseClocks(ctx context.Context, organizationID, targetType, targetID, reason string, now time.Time) ([]models.SLAClock, error) {
+	if !containsString(slaTargetTypes, targetType) {
+		return nil, ErrInvalidSLATarget
+	}
+	result := database.Conn(ctx, s.db).Model(&models.SLAClock{}).
+		Where("organization_id = ? AND target_type = ? AND target_id = ? AND status = ?", organizationID, targetType, targetID, models.SLAClockRunning).
+		Updates(map[string]interface{}{"status": models.SLAClockPaused, "paused_at": now, "pause_reason": strings.TrimSpace(reason)})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to pause SLA clocks: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrSLAClockNotRunning
+	}
+	return s.GetClocks(ctx, organizationID, targetType, targetID)
+}
+
+// ResumeClocks restarts paused clocks, moving their deadlines by the business time spent
+// paused
+func (s *SLAService) ResumeClocks(ctx context.Context, organizationID, targetType, targetID string, now time.Time) ([]models.SLAClock, error) {
+	if !containsString(slaTargetTypes, targetType) {
+		return nil, ErrInvalidSLATarget
+	}
+	db := database.Conn(ctx, s.db)
+	var paused []models.SLAClock
+	if err := db.Where("organization_id = ? AND target_type = ? AND target_id = ? AND status = ?", organizationID, targetType, targetID, models.SLAClockPaused).
+		Find(&paused).Error; err != nil {
+		return nil, fmt.Errorf("failed to get SLA clocks: %v", err)
+	}
+	if len(paused) == 0 {
+		return nil, ErrSLAClockNotRunning
+	}
+
+	calendar, err := s.clockCalendar(ctx, &paused[0])
+	if err != nil {
+		return nil, err
+	}
+	for i := range paused {
+		clock := &paused[i]
+		var pausedFor, businessPaused time.Duration
+		if clock.PausedAt != nil && now.After(*clock.PausedAt) {
+			pausedFor = now.Sub(*clock.PausedAt)
+			businessPaused = calendar.WorkingTimeBetween(*clock.PausedAt, now)
+		}
+		if err := db.Model(clock).Updates(map[string]interface{}{
+			"status":         models.SLAClockRunning,
+			"paused_at":      nil,
+			"deadline_at":    calendar.AddWorkingTime(clock.DeadlineAt, businessPaused),
+			"paused_seconds": clock.PausedSeconds + int64(pausedFor/time.Second),
+		}).Error; err != nil {
+			return nil, fmt.Errorf("failed to resume SLA clock: %v", err)
+		}
+	}
+	return s.GetClocks(ctx, organizationID, targetType, targetID)
+}
+
+// =====================================================
+// ESCALATION
+// =====================================================
+
+// CheckClocks records breaches of running clocks and fires the escalation steps that are
+// due, each once. It returns how many escalation notifications were sent.
+func (s *SLAService) CheckClocks(ctx context.Context, now time.Time) (int, error) {
+	db := database.Conn(ctx, s.db)
+
+	var clocks []models.SLAClock
+	if err := db.Where("status = ?", models.SLAClockRunning).Order("organization_id, deadline_at").Find(&clocks).Error; err != nil {
+		return 0, fmt.Errorf("failed to get running SLA clocks: %v", err)
+	}
+
+	ladders := make(map[string][]models.SLAEscalationStep)
+	escalations := 0
+	for i := range clocks {
+		clock := &clocks[i]
+
+		if clock.BreachedAt == nil && !now.Before(clock.DeadlineAt) {
+			if err := s.recordBreach(ctx, clock, now); err != nil {
+				return escalations, err
+			}
+		}
+
+		ladder, ok := ladders[clock.PolicyID]
+		if !ok {
+			var policy models.SLAPolicy
+			if err := db.Where("id = ?", clock.PolicyID).First(&policy).Error; err != nil {
+				log.Printf("Failed to get SLA policy %s: %v", clock.PolicyID, err)
+			} else {
+				ladder = slaEscalation(&policy)
+			}
+			ladders[clock.PolicyID] = ladder
+		}
+
+		for level := clock.EscalationLevel; level < len(ladder); level++ {
+			step := ladder[level]
+			if now.Before(clock.DeadlineAt.Add(time.Duration(step.OffsetMinutes) * time.Minute)) {
+				break
+			}
+
+			// Claim the step so another server running the check skips it
+			claim := db.Model(&models.SLAClock{}).
+				Where("id = ? AND status = ? AND escalation_level = ?", clock.ID, models.SLAClockRunning, level).
+				Update("escalation_level", level+1)
+			if claim.Error != nil {
+				return escalations, fmt.Errorf("failed to mark SLA escalation: %v", claim.Error)
+			}
+			if claim.RowsAffected == 0 {
+				break
+			}
+			clock.EscalationLevel = level + 1
+
+			if recipient := slaRecipient(clock, step.Notify); recipient != "" {
+				s.notifyEscalation(clock, recipient, step, now)
+				escalations++
+			}
+		}
+	}
+	return escalations, nil
+}
+
+// recordBreach marks a clock as breached once, records the breach and alerts the
+// supervisor, or whoever else is responsible when there is none
+func (s *SLAService) recordBreach(ctx context.Context, clock *models.SLAClock, now time.Time) error {
+	db := database.Conn(ctx, s.db)
+	breachedAt := clock.DeadlineAt
+	claim := db.Model(&models.SLAClock{}).
+		Where("id = ? AND status = ? AND breached_at IS NULL", clock.ID, models.SLAClockRunning).
+		Update("breached_at", breachedAt)
+	if claim.Error != nil {
+		return fmt.Errorf("failed to mark SLA breach: %v", claim.Error)
+	}
+	if claim.RowsAffected == 0 {
+		return nil
+	}
+	clock.BreachedAt = &breachedAt
+	if err := db.Create(slaBreachFor(clock, nil)).Error; err != nil {
+		return fmt.Errorf("failed to record SLA breach: %v", err)
+	}
+
+	recipient := clock.SupervisorID
+	for _, fallback := range []string{clock.ProjectManagerID, clock.AssigneeID} {
+		if recipient == "" {
+			recipient = fallback
+		}
+	}
+	if recipient == "" {
+		return nil
+	}
+
+	details, _ := json.Marshal(map[string]interface{}{
+		"clock_id":    clock.ID,
+		"stage":       clock.Stage,
+		"deadline_at": clock.DeadlineAt,
+		"assignee_id": clock.AssigneeID,
+		"policy_id":   clock.PolicyID,
+	})
+	notifyUsers, _ := json.Marshal(uniqueStrings([]string{clock.AssigneeID, clock.ProjectManagerID}))
+	if err := db.Create(&models.WorkflowAlert{
+		OrganizationID: clock.OrganizationID,
+		AlertType:      slaAlertType,
+		Severity:       "high",
+		Status:         "active",
+		TargetType:     clock.TargetType,
+		TargetID:       clock.TargetID,
+		Title:          "SLA Breached",
+		Message:        fmt.Sprintf("The %s target for %s %s was missed at %s", clock.Stage, clock.TargetType, clock.TargetID, clock.DeadlineAt.Format("2006-01-02 15:04")),
+		Details:        datatypes.JSON(details),
+		AssignedTo:     recipient,
+		NotifyUsers:    datatypes.JSON(notifyUsers),
+		TriggeredAt:    now,
+		AutoResolve:    true,
+	}).Error; err != nil {
+		log.Printf("Failed to raise alert for SLA clock %s: %v", clock.ID, err)
+	}
+	return nil
+}
+
+// slaRecipient is who an escalation step notifies for the clock
+func slaRecipient(clock *models.SLAClock, notify string) string {
+	switch notify {
+	case models.SLANotifyAssignee:
+		return clock.AssigneeID
+	case models.SLANotifySupervisor:
+		return clock.SupervisorID
+	case models.SLANotifyProjectManager:
+		return clock.ProjectManagerID
+	}
+	return ""
+}
+
+// notifyEscalation sends one escalation step, logging rather than failing when it can't
+func (s *SLAService) notifyEscalation(clock *models.SLAClock, recipient string, step models.SLAEscalationStep, now time.Time) {
+	deadline := clock.DeadlineAt.Format("2006-01-02 15:04")
+	title := "SLA Escalation"
+	message := fmt.Sprintf("The %s target for %s %s was missed at %s", clock.Stage, clock.TargetType, clock.TargetID, deadline)
+	if now.Before(clock.DeadlineAt) {
+		title = "SLA Reminder"
+		message = fmt.Sprintf("The %s target for %s %s is due at %s", clock.Stage, clock.TargetType, clock.TargetID, deadline)
+	}
+
+	if _, err := s.notificationService.CreateNotification(&models.CreateNotificationRequest{
+		OrganizationID: clock.OrganizationID,
+		UserID:         recipient,
+		Title:          title,
+		Message:        message,
+		Type:           "sla",
+	}); err != nil {
+		log.Printf("Failed to send SLA %s escalation for clock %s: %v", step.Notify, clock.ID, err)
+	}
+}
+
+// StartSLAChecker runs CheckClocks now and then every interval until ctx is done
+func (s *SLAService) StartSLAChecker(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			escalations, err := s.CheckClocks(ctx, time.Now())
+			if err != nil {
+				log.Printf("SLA check failed: %v", err)
+			} else if escalations > 0 {
+				log.Printf("SLA check sent %d escalations", escalations)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// =====================================================
+// REPORTING
+// =====================================================
+
+// GetBreachReport lists the breaches recorded between from and to, newest first, with
+// totals by stage, priority and inspection type
+func (s *SLAService) GetBreachReport(ctx context.Context, organizationID string, from, to time.Time, stage string) (*models.SLABreachReport, error) {
+	query := database.Conn(ctx, s.db).Where("organization_id = ? AND breached_at >= ? AND breached_at < ?", organizationID, from, to)
+	if stage != "" {
+		query = query.Where("stage = ?", stage)
+	}
+	var breaches []models.SLABreach
+	if err := query.Order("breached_at DESC").Find(&breaches).Error; err != nil {
+		return nil, fmt.Errorf("failed to get SLA breaches: %v", err)
+	}
+
+	report := &models.SLABreachReport{From: from, To: to, Total: len(breaches), Breaches: breaches}
+	report.ByStage = summarizeSLABreaches(breaches, func(b models.SLABreach) string { return b.Stage })
+	report.ByPriority = summarizeSLABreaches(breaches, func(b models.SLABreach) string { return b.Priority })
+	report.ByInspectionType = summarizeSLABreaches(breaches, func(b models.SLABreach) string { return b.InspectionType })
+	return report, nil
+}
+
+func summarizeSLABreaches(breaches []models.SLABreach, key func(models.SLABreach) string) []models.SLABreachSummary {
+	totals := make(map[string]*models.SLABreachSummary)
+	for _, breach := range breaches {
+		k := key(breach)
+		summary, ok := totals[k]
+		if !ok {
+			summary = &models.SLABreachSummary{Key: k}
+			totals[k] = summary
+		}
+		summary.Breaches++
+		if breach.MetAt == nil {
+			summary.Open++
+		}
+	}
+
+	summaries := make([]models.SLABreachSummary, 0, len(totals))
+	for _, summary := range totals {
+		summaries = append(summaries, *summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Breaches != summaries[j].Breaches {
+			return summaries[i].Breaches > summaries[j].Breaches
+		}
+		return summaries[i].Key < summaries[j].Key
+	})
+	return summaries
+}
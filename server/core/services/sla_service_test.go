@@ -0,0 +1,359 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"resource-mgmt/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// slaTestFixture is a standard eight-hour policy, a stricter one for high priority fire
+// work, and a fire safety template on a project managed by manager-1
+type slaTestFixture struct {
+	db              *gorm.DB
+	service         *SLAService
+	general, urgent *models.SLAPolicy
+	template        *models.Template
+	project         *models.InspectionProject
+}
+
+func newSLATestFixture(t *testing.T) *slaTestFixture {
+	db := setupServiceTestDB(t, &models.SLAPolicy{}, &models.SLAClock{}, &models.SLABreach{}, &models.Template{}, &models.Inspection{},
+		&models.InspectionAssignment{}, &models.InspectionProject{}, &models.InspectionReview{}, &models.Notification{}, &models.WorkflowAlert{},
+		&models.OrganizationBusinessHours{}, &models.OrganizationHoliday{})
+	ctx := context.Background()
+	f := &slaTestFixture{db: db, service: NewSLAService(db, NewNotificationService())}
+
+	var err error
+	f.general, err = f.service.CreatePolicy(ctx, "org-a", "admin-1", &models.SLAPolicyRequest{Name: "Standard", TimeToCompleteMinutes: 8 * 60})
+	require.NoError(t, err)
+	f.urgent, err = f.service.CreatePolicy(ctx, "org-a", "admin-1", &models.SLAPolicyRequest{Name: "Urgent fire", Priority: "High", InspectionType: "Fire",
+		TimeToAcceptMinutes: 30, TimeToStartMinutes: 60, TimeToCompleteMinutes: 240, TimeToReviewMinutes: 120})
+	require.NoError(t, err)
+
+	f.template = &models.Template{ID: uuid.New(), OrganizationID: "org-a", Name: "Fire safety", Category: "Fire", FieldsSchema: datatypes.JSON(`{}`)}
+	require.NoError(t, db.Create(f.template).Error)
+	f.project = &models.InspectionProject{ID: uuid.NewString(), OrganizationID: "org-a", Name: "Fire audit", ProjectCode: "FA-1", ProjectManager: "manager-1"}
+	require.NoError(t, db.Create(f.project).Error)
+	return f
+}
+
+// trackAssignment tracks a high priority assignment to insp-1 awaiting acceptance
+func (f *slaTestFixture) trackAssignment(t *testing.T) *models.InspectionAssignment {
+	assignment := &models.InspectionAssignment{OrganizationID: "org-a", ProjectID: &f.project.ID, Name: "Downtown", Priority: "high", Status: "pending",
+		AssignedBy: "super-1", AssignedTo: "insp-1", AssignedAt: time.Now(), RequiresAcceptance: true, SiteIDs: datatypes.JSON(`[]`), TemplateID: f.template.ID.String()}
+	require.NoError(t, f.db.Create(assignment).Error)
+	require.NoError(t, f.service.TrackAssignment(context.Background(), "org-a", assignment.ID))
+	return assignment
+}
+
+// acceptAssignment accepts assignment at acceptedAt and tracks it again
+func (f *slaTestFixture) acceptAssignment(t *testing.T, assignment *models.InspectionAssignment, acceptedAt time.Time) {
+	require.NoError(t, f.db.Model(assignment).Updates(map[string]interface{}{"status": "active", "accepted_at": acceptedAt}).Error)
+	require.NoError(t, f.service.TrackAssignment(context.Background(), "org-a", assignment.ID))
+}
+
+// trackInspection tracks a high priority inspection assigned to insp-1 by super-1
+func (f *slaTestFixture) trackInspection(t *testing.T) *models.Inspection {
+	inspection := &models.Inspection{OrganizationID: "org-a", TemplateID: f.template.ID, InspectorID: "insp-1", AssignedBy: strPtr("super-1"),
+		SiteID: uuid.NewString(), Status: "assigned", Priority: "high"}
+	require.NoError(t, f.db.Create(inspection).Error)
+	f.track(t, inspection, nil)
+	return inspection
+}
+
+// track applies updates to inspection, if any, and tracks it again
+func (f *slaTestFixture) track(t *testing.T, inspection *models.Inspection, updates map[string]interface{}) {
+	if updates != nil {
+		require.NoError(t, f.db.Model(inspection).Updates(updates).Error)
+	}
+	require.NoError(t, f.service.TrackInspection(context.Background(), "org-a", inspection.ID.String()))
+}
+
+// completeInspection completes inspection half an hour after starting it now
+func (f *slaTestFixture) completeInspection(t *testing.T, inspection *models.Inspection) {
+	started, completed := time.Now(), time.Now().Add(30*time.Minute)
+	f.track(t, inspection, map[string]interface{}{"status": "completed", "started_at": started, "completed_at": completed})
+}
+
+func (f *slaTestFixture) clocks(t *testing.T, targetType, targetID string) map[string]models.SLAClock {
+	clocks, err := f.service.GetClocks(context.Background(), "org-a", targetType, targetID)
+	require.NoError(t, err)
+	stages := make(map[string]models.SLAClock)
+	for _, clock := range clocks {
+		stages[clock.Stage] = clock
+	}
+	return stages
+}
+
+func TestSLAService_CreatePolicyValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     models.SLAPolicyRequest
+		wantErr error
+	}{
+		{"no targets", models.SLAPolicyRequest{Name: "Nothing"}, ErrInvalidSLAPolicy},
+		{"missing name", models.SLAPolicyRequest{TimeToStartMinutes: 60}, ErrInvalidSLAPolicy},
+		{"escalation ladder out of order", models.SLAPolicyRequest{Name: "Bad ladder", TimeToStartMinutes: 60,
+			Escalation: []models.SLAEscalationStep{{Notify: models.SLANotifySupervisor, OffsetMinutes: 60}, {Notify: models.SLANotifyAssignee, OffsetMinutes: 0}}}, ErrInvalidSLAPolicy},
+		{"same priority and type", models.SLAPolicyRequest{Name: "Again", Priority: "high", InspectionType: "Fire", TimeToStartMinutes: 10}, ErrSLAPolicyExists},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSLATestFixture(t)
+			req := tt.req
+			_, err := f.service.CreatePolicy(context.Background(), "org-a", "admin-1", &req)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestSLAService_PolicyForPicksMostSpecific(t *testing.T) {
+	f := newSLATestFixture(t)
+	assert.Equal(t, "high", f.urgent.Priority, "priorities are normalized")
+
+	tests := []struct {
+		name           string
+		priority       string
+		inspectionType string
+		want           *models.SLAPolicy
+	}{
+		{"priority and type match", "high", "fire", f.urgent},
+		{"type matches case-insensitively", "high", "FIRE", f.urgent},
+		{"falls back to the general policy", "low", "Fire", f.general},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := f.service.policyFor(context.Background(), "org-a", tt.priority, tt.inspectionType)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want.ID, policy.ID)
+		})
+	}
+}
+
+func TestSLAService_AcceptClockEscalatesUpTheLadder(t *testing.T) {
+	f := newSLATestFixture(t)
+	ctx := context.Background()
+	assignment := f.trackAssignment(t)
+
+	clocks := f.clocks(t, models.SLATargetAssignment, assignment.ID)
+	require.Len(t, clocks, 1, "work can't start before it's accepted")
+	accept := clocks[models.SLAStageAccept]
+	assert.Equal(t, "manager-1", accept.ProjectManagerID)
+	assert.WithinDuration(t, time.Now().Add(30*time.Minute), accept.DeadlineAt, time.Minute)
+
+	steps := []struct {
+		name string
+		at   time.Time
+		want int
+	}{
+		{"the assignee is reminded an hour ahead", time.Now(), 1},
+		{"the supervisor hears about the breach", accept.DeadlineAt.Add(time.Minute), 1},
+		{"each step fires once", accept.DeadlineAt.Add(time.Minute), 0},
+		{"then the project manager", accept.DeadlineAt.Add(5 * time.Hour), 1},
+	}
+	for _, step := range steps {
+		escalations, err := f.service.CheckClocks(ctx, step.at)
+		require.NoError(t, err)
+		assert.Equal(t, step.want, escalations, step.name)
+	}
+
+	var notices []models.Notification
+	require.NoError(t, f.db.Order("created_at").Find(&notices).Error)
+	require.Len(t, notices, 3)
+	assert.Equal(t, []string{"insp-1", "super-1", "manager-1"}, []string{notices[0].UserID, notices[1].UserID, notices[2].UserID})
+	var alert models.WorkflowAlert
+	require.NoError(t, f.db.Where("target_id = ?", assignment.ID).First(&alert).Error)
+	assert.Equal(t, "super-1", alert.AssignedTo)
+}
+
+func TestSLAService_AcceptingAssignmentStartsWorkClocks(t *testing.T) {
+	f := newSLATestFixture(t)
+	assignment := f.trackAssignment(t)
+
+	f.acceptAssignment(t, assignment, time.Now().Add(10*time.Minute))
+	clocks := f.clocks(t, models.SLATargetAssignment, assignment.ID)
+	require.Len(t, clocks, 3)
+	assert.Equal(t, models.SLAClockMet, clocks[models.SLAStageAccept].Status)
+	assert.Equal(t, models.SLAClockRunning, clocks[models.SLAStageStart].Status)
+	assert.Equal(t, models.SLAClockRunning, clocks[models.SLAStageComplete].Status)
+}
+
+func TestSLAService_PauseAndResumeRejections(t *testing.T) {
+	tests := []struct {
+		name    string
+		call    func(f *slaTestFixture, inspectionID string) error
+		wantErr error
+	}{
+		{"pause an unsupported target", func(f *slaTestFixture, inspectionID string) error {
+			_, err := f.service.PauseClocks(context.Background(), "org-a", "site", inspectionID, "no access", time.Now())
+			return err
+		}, ErrInvalidSLATarget},
+		{"resume an unsupported target", func(f *slaTestFixture, inspectionID string) error {
+			_, err := f.service.ResumeClocks(context.Background(), "org-a", "site", inspectionID, time.Now())
+			return err
+		}, ErrInvalidSLATarget},
+		{"resume running clocks", func(f *slaTestFixture, inspectionID string) error {
+			_, err := f.service.ResumeClocks(context.Background(), "org-a", models.SLATargetInspection, inspectionID, time.Now())
+			return err
+		}, ErrSLAClockNotRunning},
+		{"pause paused clocks", func(f *slaTestFixture, inspectionID string) error {
+			_, err := f.service.PauseClocks(context.Background(), "org-a", models.SLATargetInspection, inspectionID, "keys", time.Now())
+			if err != nil {
+				return err
+			}
+			_, err = f.service.PauseClocks(context.Background(), "org-a", models.SLATargetInspection, inspectionID, "keys", time.Now())
+			return err
+		}, ErrSLAClockNotRunning},
+		{"pause another organization's clocks", func(f *slaTestFixture, inspectionID string) error {
+			_, err := f.service.PauseClocks(context.Background(), "org-b", models.SLATargetInspection, inspectionID, "keys", time.Now())
+			return err
+		}, ErrSLAClockNotRunning},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSLATestFixture(t)
+			inspection := f.trackInspection(t)
+			assert.ErrorIs(t, tt.call(f, inspection.ID.String()), tt.wantErr)
+		})
+	}
+}
+
+func TestSLAService_PausedClocksDontBreach(t *testing.T) {
+	f := newSLATestFixture(t)
+	ctx := context.Background()
+	inspection := f.trackInspection(t)
+	clocks := f.clocks(t, models.SLATargetInspection, inspection.ID.String())
+	require.Len(t, clocks, 2)
+	startDeadline := clocks[models.SLAStageStart].DeadlineAt
+
+	// The inspection waits on the site owner for two hours
+	paused, err := f.service.PauseClocks(ctx, "org-a", models.SLATargetInspection, inspection.ID.String(), "Waiting for the landlord's keys", time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, models.SLAClockPaused, paused[0].Status)
+
+	_, err = f.service.CheckClocks(ctx, time.Now().Add(24*time.Hour))
+	require.NoError(t, err)
+	breaches := countRows(t, f.db.Model(&models.SLABreach{}).Where("target_id = ?", inspection.ID.String()))
+	assert.Equal(t, int64(0), breaches, "paused clocks don't breach")
+
+	resumed, err := f.service.ResumeClocks(ctx, "org-a", models.SLATargetInspection, inspection.ID.String(), time.Now().Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, models.SLAClockRunning, resumed[0].Status)
+	assert.WithinDuration(t, startDeadline.Add(2*time.Hour), resumed[0].DeadlineAt, time.Minute)
+	assert.InDelta(t, 7200, resumed[0].PausedSeconds, 60)
+}
+
+func TestSLAService_CompletionStartsReviewClock(t *testing.T) {
+	f := newSLATestFixture(t)
+	inspection := f.trackInspection(t)
+
+	f.completeInspection(t, inspection)
+	clocks := f.clocks(t, models.SLATargetInspection, inspection.ID.String())
+	require.Len(t, clocks, 3)
+	assert.Equal(t, models.SLAClockMet, clocks[models.SLAStageStart].Status)
+	assert.Equal(t, models.SLAClockMet, clocks[models.SLAStageComplete].Status)
+	assert.Nil(t, clocks[models.SLAStageComplete].BreachedAt)
+	assert.Equal(t, models.SLAClockRunning, clocks[models.SLAStageReview].Status)
+	assert.Equal(t, "super-1", clocks[models.SLAStageReview].AssigneeID, "nobody is reviewing it yet")
+
+	review := &models.InspectionReview{OrganizationID: "org-a", InspectionID: strPtr(inspection.ID.String()), ReviewType: "quality", Status: "pending",
+		ReviewerID: "reviewer-1", AssignedBy: "super-1", AssignedAt: time.Now()}
+	require.NoError(t, f.db.Create(review).Error)
+	f.track(t, inspection, nil)
+	assert.Equal(t, "reviewer-1", f.clocks(t, models.SLATargetInspection, inspection.ID.String())[models.SLAStageReview].AssigneeID)
+}
+
+func TestSLAService_ReopenedOrDeletedInspections(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(t *testing.T, f *slaTestFixture, inspection *models.Inspection)
+		want   map[string]string
+	}{
+		{"reopening restarts the complete clock", func(t *testing.T, f *slaTestFixture, inspection *models.Inspection) {
+			f.track(t, inspection, map[string]interface{}{"status": "assigned", "completed_at": nil})
+		}, map[string]string{models.SLAStageComplete: models.SLAClockRunning, models.SLAStageReview: models.SLAClockCancelled}},
+		{"deleting stops every clock", func(t *testing.T, f *slaTestFixture, inspection *models.Inspection) {
+			require.NoError(t, f.db.Delete(inspection).Error)
+			f.track(t, inspection, nil)
+		}, map[string]string{models.SLAStageReview: models.SLAClockCancelled}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSLATestFixture(t)
+			inspection := f.trackInspection(t)
+			f.completeInspection(t, inspection)
+
+			tt.change(t, f, inspection)
+			clocks := f.clocks(t, models.SLATargetInspection, inspection.ID.String())
+			for stage, status := range tt.want {
+				assert.Equal(t, status, clocks[stage].Status, stage)
+			}
+			if tt.want[models.SLAStageComplete] == "" {
+				for _, clock := range clocks {
+					assert.NotEqual(t, models.SLAClockRunning, clock.Status, clock.Stage)
+				}
+			}
+		})
+	}
+}
+
+func TestSLAService_BreachReportKeepsResolvedBreaches(t *testing.T) {
+	f := newSLATestFixture(t)
+	ctx := context.Background()
+	assignment := f.trackAssignment(t)
+	accept := f.clocks(t, models.SLATargetAssignment, assignment.ID)[models.SLAStageAccept]
+	_, err := f.service.CheckClocks(ctx, accept.DeadlineAt.Add(time.Minute))
+	require.NoError(t, err)
+	f.acceptAssignment(t, assignment, accept.DeadlineAt.Add(6*time.Hour))
+
+	breaches := countRows(t, f.db.Model(&models.SLABreach{}))
+	require.NotZero(t, breaches)
+	report, err := f.service.GetBreachReport(ctx, "org-a", time.Now().Add(-time.Hour), time.Now().Add(48*time.Hour), "")
+	require.NoError(t, err)
+	assert.Equal(t, int(breaches), report.Total)
+	require.NotEmpty(t, report.ByStage)
+	assert.Equal(t, models.SLAStageAccept, report.ByStage[0].Key)
+	assert.Equal(t, 0, report.ByStage[0].Open, "the assignment was accepted in the end")
+}
+
+func TestSLAService_DeletePolicy(t *testing.T) {
+	tests := []struct {
+		name         string
+		organization string
+		policy       func(f *slaTestFixture) *models.SLAPolicy
+		wantErr      error
+	}{
+		{"policy with clocks", "org-a", func(f *slaTestFixture) *models.SLAPolicy { return f.urgent }, ErrSLAPolicyInUse},
+		{"other organization", "org-b", func(f *slaTestFixture) *models.SLAPolicy { return f.general }, ErrSLAPolicyNotFound},
+		{"unused policy", "org-a", func(f *slaTestFixture) *models.SLAPolicy { return f.general }, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSLATestFixture(t)
+			ctx := context.Background()
+			f.trackInspection(t)
+			policyID := tt.policy(f).ID
+
+			_, err := f.service.DeletePolicy(ctx, tt.organization, policyID)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			_, err = f.service.DeletePolicy(ctx, "org-a", policyID)
+			assert.ErrorIs(t, err, ErrSLAPolicyNotFound)
+		})
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
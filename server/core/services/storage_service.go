@@ -222,6 +222,14 @@ func (s *StorageService) GetFileURL(path string) string {
 	return ""
 }
 
+// GetLocalFilePath returns where a stored file lives on disk, or "" when files are kept in R2
+func (s *StorageService) GetLocalFilePath(path string) string {
+	if s.config.Provider != StorageLocal {
+		return ""
+	}
+	return filepath.Join(s.config.LocalPath, path)
+}
+
 func (s *StorageService) GeneratePresignedURL(ctx context.Context, path string, expiration time.Duration) (string, error) {
 	if s.config.Provider != StorageR2 {
 		return "", fmt.Errorf("presigned URLs only supported for R2 storage")
@@ -0,0 +1,714 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"resource-mgmt/models"
+	"resource-mgmt/pkg/database"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrInvalidTeam is returned when a team, its members or queued work fail validation
+	ErrInvalidTeam = errors.New("invalid team")
+	// ErrTeamNotFound is returned when a team doesn't exist in the organization
+	ErrTeamNotFound = errors.New("team not found")
+	// ErrTeamInUse is returned when deleting a team that work was queued for
+	ErrTeamInUse = errors.New("team has work queued for it")
+	// ErrTeamForbidden is returned when someone other than the team's lead, members or a
+	// supervisor looks at or handles its work
+	ErrTeamForbidden = errors.New("not allowed to handle this team's work")
+	// ErrTeamQueueConflict is returned when work can't be queued because it has started, or
+	// can't be taken because it is no longer in the queue
+	ErrTeamQueueConflict = errors.New("team queue conflict")
+)
+
+// queueableAssignmentStatuses are the assignment statuses that can still go to a team queue
+var queueableAssignmentStatuses = []string{"pending", "active", "rejected"}
+
+// queueableInspectionStatuses are the inspection statuses that can still go to a team queue
+var queueableInspectionStatuses = []string{"draft", "assigned"}
+
+// TeamService manages teams, their members and the work queued for them
+type TeamService struct {
+	db                  *gorm.DB
+	workflowService     *WorkflowService
+	notificationService *NotificationService
+}
+
+func NewTeamService(db *gorm.DB, workflowService *WorkflowService) *TeamService {
+	return &TeamService{
+		db:                  db,
+		workflowService:     workflowService,
+		notificationService: workflowService.notificationService,
+	}
+}
+
+// =====================================================
+// TEAMS
+// =====================================================
+
+// GetTeams lists the organization's teams by name, with their leads and members
+func (s *TeamService) GetTeams(ctx context.Context, organizationID string, includeInactive bool) ([]models.Team, error) {
+	query := database.Conn(ctx, s.db).Where("organization_id = ?", organizationID)
+	if !includeInactive {
+		query = query.Where("is_active = ?", true)
+	}
+	var teams []models.Team
+	if err := query.Preload("Lead").Preload("Members.User").Order("name").Find(&teams).Error; err != nil {
+		return nil, fmt.Errorf("failed to get teams: %v", err)
+	}
+	return teams, nil
+}
+
+// GetTeam returns a team with its lead and members
+func (s *TeamService) GetTeam(ctx context.Context, organizationID, teamID string) (*models.Team, error) {
+	var team models.Team
+	if err := database.Conn(ctx, s.db).Preload("Lead").Preload("Members", func(db *gorm.DB) *gorm.DB { return db.Order("joined_at") }).
+		Preload("Members.User").
+		Where("id = ? AND organization_id = ?", teamID, organizationID).First(&team).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTeamNotFound
+		}
+		return nil, fmt.Errorf("failed to get team: %v", err)
+	}
+	return &team, nil
+}
+
+// CreateTeam adds a team. Its lead, if given, becomes its first member.
+func (s *TeamService) CreateTeam(ctx context.Context, organizationID, userID string, req *models.TeamRequest) (*models.Team, error) {
+	team := &models.Team{OrganizationID: organizationID, IsActive: true, CreatedBy: userID}
+	if err := s.applyTeam(ctx, team, req); err != nil {
+		return nil, err
+	}
+
+	err := database.Conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(team).Error; err != nil {
+			return err
+		}
+		return addTeamMembers(tx, team, userID, nil)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save team: %v", err)
+	}
+	return s.GetTeam(ctx, organizationID, team.ID)
+}
+
+// UpdateTeam changes a team's name, description, lead or whether it is active. A new lead
+// joins the team if they aren't a member yet.
+func (s *TeamService) UpdateTeam(ctx context.Context, organizationID, teamID, userID string, req *models.TeamRequest) (*models.Team, error) {
+	team, err := s.GetTeam(ctx, organizationID, teamID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyTeam(ctx, team, req); err != nil {
+		return nil, err
+	}
+
+	err = database.Conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Team{}).Where("id = ?", team.ID).Updates(map[string]interface{}{
+			"name":        team.Name,
+			"description": team.Description,
+			"lead_id":     team.LeadID,
+			"is_active":   team.IsActive,
+			"updated_at":  time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		return addTeamMembers(tx, team, userID, nil)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save team: %v", err)
+	}
+	return s.GetTeam(ctx, organizationID, team.ID)
+}
+
+// DeleteTeam removes a team and its memberships. Teams that work was ever queued for can
+// only be deactivated, so that work keeps its history.
+func (s *TeamService) DeleteTeam(ctx context.Context, organizationID, teamID string) error {
+	team, err := s.GetTeam(ctx, organizationID, teamID)
+	if err != nil {
+		return err
+	}
+
+	db := database.Conn(ctx, s.db)
+	for _, model := range []interface{}{&models.InspectionAssignment{}, &models.Inspection{}} {
+		var queued int64
+		if err := db.Model(model).Where("team_id = ?", team.ID).Count(&queued).Error; err != nil {
+			return fmt.Errorf("failed to check team work: %v", err)
+		}
+		if queued > 0 {
+			return ErrTeamInUse
+		}
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("team_id = ?", team.ID).Delete(&models.TeamMember{}).Error; err != nil {
+			return fmt.Errorf("failed to delete team members: %v", err)
+		}
+		if err := tx.Delete(&models.Team{}, "id = ?", team.ID).Error; err != nil {
+			return fmt.Errorf("failed to delete team: %v", err)
+		}
+		return nil
+	})
+}
+
+// SetMembers replaces a team's members. The lead stays a member.
+func (s *TeamService) SetMembers(ctx context.Context, organizationID, teamID, userID string, userIDs []string) (*models.Team, error) {
+	team, err := s.GetTeam(ctx, organizationID, teamID)
+	if err != nil {
+		return nil, err
+	}
+	userIDs = uniqueStrings(userIDs)
+	if err := requireActiveMembers(ctx, s.db, organizationID, userIDs); err != nil {
+		return nil, err
+	}
+
+	err = database.Conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		keep := userIDs
+		if team.LeadID != nil {
+			keep = append(keep, *team.LeadID)
+		}
+		removed := tx.Where("team_id = ?", team.ID)
+		if len(keep) > 0 {
+			removed = removed.Where("user_id NOT IN ?", keep)
+		}
+		if err := removed.Delete(&models.TeamMember{}).Error; err != nil {
+			return err
+		}
+		return addTeamMembers(tx, team, userID, userIDs)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save team members: %v", err)
+	}
+	return s.GetTeam(ctx, organizationID, team.ID)
+}
+
+// applyTeam validates the request and copies it onto the team
+func (s *TeamService) applyTeam(ctx context.Context, team *models.Team, req *models.TeamRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidTeam)
+	}
+	var taken int64
+	sameName := database.Conn(ctx, s.db).Model(&models.Team{}).
+		Where("organization_id = ? AND LOWER(name) = LOWER(?)", team.OrganizationID, name)
+	if team.ID != "" {
+		sameName = sameName.Where("id <> ?", team.ID)
+	}
+	if err := sameName.Count(&taken).Error; err != nil {
+		return fmt.Errorf("failed to check team name: %v", err)
+	}
+	if taken > 0 {
+		return fmt.Errorf("%w: a team named %s already exists", ErrInvalidTeam, name)
+	}
+
+	if req.LeadID != nil {
+		if *req.LeadID == "" {
+			team.LeadID = nil
+		} else {
+			if err := requireActiveMembers(ctx, s.db, team.OrganizationID, []string{*req.LeadID}); err != nil {
+				return err
+			}
+			leadID := *req.LeadID
+			team.LeadID = &leadID
+		}
+	}
+
+	team.Name = name
+	team.Description = req.Description
+	if req.IsActive != nil {
+		team.IsActive = *req.IsActive
+	}
+	return nil
+}
+
+// requireActiveMembers checks every user is an active member of the organization
+func requireActiveMembers(ctx context.Context, db *gorm.DB, organizationID string, userIDs []string) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	var found []string
+	if err := database.Conn(ctx, db).Model(&models.OrganizationMember{}).
+		Where("organization_id = ? AND user_id IN ? AND status = ?", organizationID, userIDs, "active").
+		Pluck("user_id", &found).Error; err != nil {
+		return fmt.Errorf("failed to check members: %v", err)
+	}
+	for _, userID := range userIDs {
+		if !containsString(found, userID) {
+			return fmt.Errorf("%w: %s is not an active member of the organization", ErrInvalidTeam, userID)
+		}
+	}
+	return nil
+}
+
+// addTeamMembers adds the users, and the team's lead, to the team unless they already belong
+func addTeamMembers(tx *gorm.DB, team *models.Team, addedBy string, userIDs []string) error {
+	if team.LeadID != nil {
+		userIDs = append(userIDs, *team.LeadID)
+	}
+	userIDs = uniqueStrings(userIDs)
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	var existing []string
+	if err := tx.Model(&models.TeamMember{}).Where("team_id = ? AND user_id IN ?", team.ID, userIDs).
+		Pluck("user_id", &existing).Error; err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, userID := range userIDs {
+		if containsString(existing, userID) {
+			continue
+		}
+		if err := tx.Create(&models.TeamMember{
+			OrganizationID: team.OrganizationID,
+			TeamID:         team.ID,
+			UserID:         userID,
+			AddedBy:        addedBy,
+			JoinedAt:       now,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// =====================================================
+// VISIBILITY
+// =====================================================
+
+// teamScope is the work a user sees through their teams: that of the teams they lead and
+// their members, and what waits in the queues of the teams they belong to
+type teamScope struct {
+	userID        string
+	ledTeamIDs    []string
+	ledMemberIDs  []string
+	memberTeamIDs []string
+}
+
+// loadTeamScope finds the active teams the user leads or belongs to
+func loadTeamScope(ctx context.Context, db *gorm.DB, organizationID, userID string) (*teamScope, error) {
+	scope := &teamScope{userID: userID}
+	conn := database.Conn(ctx, db)
+	if err := conn.Model(&models.Team{}).
+		Where("organization_id = ? AND lead_id = ? AND is_active = ?", organizationID, userID, true).
+		Pluck("id", &scope.ledTeamIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to get led teams: %v", err)
+	}
+	if err := conn.Model(&models.TeamMember{}).
+		Where("team_members.organization_id = ? AND team_members.user_id = ?", organizationID, userID).
+		Joins("JOIN teams ON teams.id = team_members.team_id AND teams.is_active = ?", true).
+		Pluck("team_members.team_id", &scope.memberTeamIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to get teams: %v", err)
+	}
+	if len(scope.ledTeamIDs) > 0 {
+		if err := conn.Model(&models.TeamMember{}).Where("team_id IN ?", scope.ledTeamIDs).
+			Pluck("user_id", &scope.ledMemberIDs).Error; err != nil {
+			return nil, fmt.Errorf("failed to get team members: %v", err)
+		}
+		scope.ledMemberIDs = uniqueStrings(scope.ledMemberIDs)
+	}
+	return scope, nil
+}
+
+// condition matches the work the user may see, given the column holding its assignee:
+// their own, that of the teams they lead, and what waits in their teams' queues
+func (s *teamScope) condition(assigneeColumn string) clause.Expr {
+	return gorm.Expr(fmt.Sprintf("(%[1]s IN ? OR team_id IN ? OR (%[1]s IS NULL AND team_id IN ?))", assigneeColumn),
+		append([]string{s.userID}, s.ledMemberIDs...), s.ledTeamIDs, s.memberTeamIDs)
+}
+
+// sees reports whether the user may see work held by the assignee, or queued for the team
+func (s *teamScope) sees(assigneeID string, teamID *string) bool {
+	if assigneeID != "" && (assigneeID == s.userID || containsString(s.ledMemberIDs, assigneeID)) {
+		return true
+	}
+	if teamID == nil {
+		return false
+	}
+	return containsString(s.ledTeamIDs, *teamID) || (assigneeID == "" && containsString(s.memberTeamIDs, *teamID))
+}
+
+// TeamWorkCondition matches work, given the column holding its assignee, that was queued
+// for the team or is held by one of its members. Analytics and workload views filter by it.
+func TeamWorkCondition(assigneeColumn, teamID string) clause.Expr {
+	return gorm.Expr(fmt.Sprintf("(team_id = ? OR %s IN (SELECT user_id FROM team_members WHERE team_id = ?))", assigneeColumn), teamID, teamID)
+}
+
+// VisibleInspections returns the condition limiting an inspection query to what the user
+// sees through their teams, for users who can't view all inspections
+func VisibleInspections(ctx context.Context, db *gorm.DB, organizationID, userID string) (clause.Expr, error) {
+	scope, err := loadTeamScope(ctx, db, organizationID, userID)
+	if err != nil {
+		return clause.Expr{}, err
+	}
+	return scope.condition("inspector_id"), nil
+}
+
+// =====================================================
+// TEAM QUEUES
+// =====================================================
+
+// requireTeamAccess returns the team if the user may handle its work: supervisors and the
+// lead always, members when allowed
+func (s *TeamService) requireTeamAccess(ctx context.Context, organizationID, teamID, userID, userRole string, allowMembers bool) (*models.Team, error) {
+	team, err := s.GetTeam(ctx, organizationID, teamID)
+	if err != nil {
+		return nil, err
+	}
+	if isSupervisorRole(userRole) || (team.LeadID != nil && *team.LeadID == userID) {
+		return team, nil
+	}
+	if allowMembers && teamHasMember(team, userID) {
+		return team, nil
+	}
+	return nil, ErrTeamForbidden
+}
+
+func teamHasMember(team *models.Team, userID string) bool {
+	for _, member := range team.Members {
+		if member.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// Queue moves assignments and inspections that haven't started into the team's queue,
+// taking them from whoever held them, and lets the team's members know
+func (s *TeamService) Queue(ctx context.Context, organizationID, teamID, userID, userRole string, req *models.QueueToTeamRequest) (*models.TeamQueue, error) {
+	team, err := s.requireTeamAccess(ctx, organizationID, teamID, userID, userRole, false)
+	if err != nil {
+		return nil, err
+	}
+	if !team.IsActive {
+		return nil, fmt.Errorf("%w: %s is not active", ErrInvalidTeam, team.Name)
+	}
+	assignmentIDs, inspectionIDs := uniqueStrings(req.AssignmentIDs), uniqueStrings(req.InspectionIDs)
+	if len(assignmentIDs) == 0 && len(inspectionIDs) == 0 {
+		return nil, fmt.Errorf("%w: assignment_ids or inspection_ids is required", ErrInvalidTeam)
+	}
+
+	// Check everything can be queued before moving anything
+	db := database.Conn(ctx, s.db)
+	var assignments []models.InspectionAssignment
+	if len(assignmentIDs) > 0 {
+		if err := db.Where("id IN ? AND organization_id = ?", assignmentIDs, organizationID).Find(&assignments).Error; err != nil {
+			return nil, fmt.Errorf("failed to get assignments: %v", err)
+		}
+		if len(assignments) != len(assignmentIDs) {
+			return nil, fmt.Errorf("%w: some assignments don't exist", ErrInvalidTeam)
+		}
+		var started int64
+		if err := db.Model(&models.Inspection{}).Where("assignment_id IN ? AND status NOT IN ?", assignmentIDs, queueableInspectionStatuses).
+			Count(&started).Error; err != nil {
+			return nil, fmt.Errorf("failed to check assignment inspections: %v", err)
+		}
+		for _, assignment := range assignments {
+			if !containsString(queueableAssignmentStatuses, assignment.Status) || assignment.StartedAt != nil {
+				started++
+			}
+		}
+		if started > 0 {
+			return nil, fmt.Errorf("%w: some assignments have already started", ErrTeamQueueConflict)
+		}
+	}
+	var inspections []models.Inspection
+	if len(inspectionIDs) > 0 {
+		if err := db.Where("id IN ? AND organization_id = ?", inspectionIDs, organizationID).Find(&inspections).Error; err != nil {
+			return nil, fmt.Errorf("failed to get inspections: %v", err)
+		}
+		if len(inspections) != len(inspectionIDs) {
+			return nil, fmt.Errorf("%w: some inspections don't exist", ErrInvalidTeam)
+		}
+		for _, inspection := range inspections {
+			if inspection.AssignmentID != nil {
+				return nil, fmt.Errorf("%w: inspection %s belongs to an assignment; queue the assignment", ErrInvalidTeam, inspection.ID)
+			}
+			if !containsString(queueableInspectionStatuses, inspection.Status) {
+				return nil, fmt.Errorf("%w: inspection %s has already started", ErrTeamQueueConflict, inspection.ID)
+			}
+		}
+	}
+
+	for i := range assignments {
+		if err := s.queueAssignment(ctx, team, &assignments[i], userID, req.Reason); err != nil {
+			return nil, err
+		}
+	}
+	for i := range inspections {
+		if err := s.queueInspection(ctx, team, &inspections[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	message := fmt.Sprintf("%d new item(s) are waiting in the %s queue", len(assignments)+len(inspections), team.Name)
+	for _, member := range team.Members {
+		if member.UserID != userID {
+			s.notify(organizationID, member.UserID, "New Team Work", message)
+		}
+	}
+
+	return s.GetQueue(ctx, organizationID, team.ID, userID, userRole)
+}
+
+// queueAssignment takes the assignment and its inspections from their inspector and puts
+// them in the team's queue
+func (s *TeamService) queueAssignment(ctx context.Context, team *models.Team, assignment *models.InspectionAssignment, userID, reason string) error {
+	db := database.Conn(ctx, s.db)
+	previous := assignment.AssignedTo
+
+	result := db.Model(&models.InspectionAssignment{}).
+		Where("id = ? AND status IN ? AND started_at IS NULL", assignment.ID, queueableAssignmentStatuses).
+		Updates(map[string]interface{}{
+			"assigned_to": nil,
+			"team_id":     team.ID,
+			"status":      "pending",
+			"accepted_at": nil,
+			"updated_at":  time.Now(),
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to queue assignment: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: assignment %s has already started", ErrTeamQueueConflict, assignment.ID)
+	}
+
+	var moved []models.Inspection
+	if err := db.Where("assignment_id = ?", assignment.ID).Find(&moved).Error; err != nil {
+		return fmt.Errorf("failed to get assignment inspections: %v", err)
+	}
+	if err := db.Model(&models.Inspection{}).Where("assignment_id = ?", assignment.ID).
+		Updates(map[string]interface{}{"inspector_id": nil, "team_id": team.ID, "status": "assigned", "updated_at": time.Now()}).Error; err != nil {
+		return fmt.Errorf("failed to queue assignment inspections: %v", err)
+	}
+	for i := range moved {
+		after := moved[i]
+		after.InspectorID = ""
+		after.TeamID = &team.ID
+		after.Status = "assigned"
+		if err := s.workflowService.workloadMetrics.InspectionChanged(ctx, &moved[i], &after); err != nil {
+			log.Printf("Failed to update workload metrics for inspection %s: %v", moved[i].ID, err)
+		}
+	}
+
+	var fromUserID *string
+	if previous != "" {
+		fromUserID = &previous
+	}
+	s.workflowService.recordEvent(ctx, &models.AssignmentEvent{
+		OrganizationID: assignment.OrganizationID,
+		AssignmentID:   assignment.ID,
+		EventType:      models.AssignmentEventQueued,
+		ActorID:        userID,
+		FromUserID:     fromUserID,
+		Reason:         reason,
+	})
+	s.workflowService.refreshWorkloads(ctx, assignment.OrganizationID, previous)
+	s.workflowService.trackSLA(ctx, assignment.OrganizationID, assignment.ID)
+	return nil
+}
+
+// queueInspection takes a standalone inspection from its inspector and puts it in the
+// team's queue
+func (s *TeamService) queueInspection(ctx context.Context, team *models.Team, before *models.Inspection) error {
+	result := database.Conn(ctx, s.db).Model(&models.Inspection{}).
+		Where("id = ? AND status IN ?", before.ID, queueableInspectionStatuses).
+		Updates(map[string]interface{}{"inspector_id": nil, "team_id": team.ID, "updated_at": time.Now()})
+	if result.Error != nil {
+		return fmt.Errorf("failed to queue inspection: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: inspection %s has already started", ErrTeamQueueConflict, before.ID)
+	}
+
+	after := *before
+	after.InspectorID = ""
+	after.TeamID = &team.ID
+	s.inspectionChanged(ctx, before, &after)
+	return nil
+}
+
+// GetQueue lists the work waiting in the team's queue, oldest first. The team's lead,
+// members and supervisors can see it.
+func (s *TeamService) GetQueue(ctx context.Context, organizationID, teamID, userID, userRole string) (*models.TeamQueue, error) {
+	team, err := s.requireTeamAccess(ctx, organizationID, teamID, userID, userRole, true)
+	if err != nil {
+		return nil, err
+	}
+
+	db := database.Conn(ctx, s.db)
+	queue := &models.TeamQueue{TeamID: team.ID, Assignments: []models.InspectionAssignment{}, Inspections: []models.Inspection{}}
+	if err := db.Where("organization_id = ? AND team_id = ? AND assigned_to IS NULL", organizationID, team.ID).
+		Preload("Template").Preload("Project").Order("due_date IS NULL, due_date, created_at").
+		Find(&queue.Assignments).Error; err != nil {
+		return nil, fmt.Errorf("failed to get queued assignments: %v", err)
+	}
+	if err := db.Where("organization_id = ? AND team_id = ? AND inspector_id IS NULL AND assignment_id IS NULL", organizationID, team.ID).
+		Preload("Template").Preload("Site").Order("due_date IS NULL, due_date, created_at").
+		Find(&queue.Inspections).Error; err != nil {
+		return nil, fmt.Errorf("failed to get queued inspections: %v", err)
+	}
+	for i := range queue.Assignments {
+		if err := s.workflowService.populateSiteNames(ctx, &queue.Assignments[i]); err != nil {
+			log.Printf("Failed to get site names for assignment %s: %v", queue.Assignments[i].ID, err)
+		}
+	}
+	return queue, nil
+}
+
+// Take hands one assignment or inspection from the team's queue to a member. Members take
+// work for themselves, which accepts it; the lead and supervisors can hand it to any member,
+// who is notified and accepts it as usual.
+func (s *TeamService) Take(ctx context.Context, organizationID, teamID, userID, userRole string, req *models.TakeFromQueueRequest) (*models.InspectionAssignment, *models.Inspection, error) {
+	if (req.AssignmentID == "") == (req.InspectionID == "") {
+		return nil, nil, fmt.Errorf("%w: give either assignment_id or inspection_id", ErrInvalidTeam)
+	}
+	inspectorID := req.InspectorID
+	if inspectorID == "" {
+		inspectorID = userID
+	}
+	team, err := s.requireTeamAccess(ctx, organizationID, teamID, userID, userRole, inspectorID == userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !teamHasMember(team, inspectorID) {
+		return nil, nil, fmt.Errorf("%w: %s is not a member of %s", ErrInvalidTeam, inspectorID, team.Name)
+	}
+
+	if req.AssignmentID != "" {
+		assignment, err := s.takeAssignment(ctx, team, req.AssignmentID, userID, inspectorID)
+		return assignment, nil, err
+	}
+	inspection, err := s.takeInspection(ctx, team, req.InspectionID, userID, inspectorID)
+	return nil, inspection, err
+}
+
+func (s *TeamService) takeAssignment(ctx context.Context, team *models.Team, assignmentID, userID, inspectorID string) (*models.InspectionAssignment, error) {
+	var assignment models.InspectionAssignment
+	if err := database.Conn(ctx, s.db).Where("id = ? AND organization_id = ? AND team_id = ?", assignmentID, team.OrganizationID, team.ID).
+		First(&assignment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: assignment is not in the team queue", ErrTeamQueueConflict)
+		}
+		return nil, fmt.Errorf("failed to get assignment: %v", err)
+	}
+	if assignment.AssignedTo != "" {
+		return nil, fmt.Errorf("%w: assignment was already taken", ErrTeamQueueConflict)
+	}
+
+	dispatched := inspectorID != userID
+	taken, err := s.workflowService.moveAssignment(ctx, &assignment, userID, inspectorID, models.AssignmentEventTaken, "", dispatched)
+	if errors.Is(err, ErrAssignmentChanged) {
+		return nil, fmt.Errorf("%w: assignment was already taken", ErrTeamQueueConflict)
+	}
+	if err != nil || dispatched {
+		return taken, err
+	}
+	return s.workflowService.AcceptAssignment(ctx, team.OrganizationID, assignment.ID, inspectorID)
+}
+
+func (s *TeamService) takeInspection(ctx context.Context, team *models.Team, inspectionID, userID, inspectorID string) (*models.Inspection, error) {
+	db := database.Conn(ctx, s.db)
+	var before models.Inspection
+	if err := db.Where("id = ? AND organization_id = ? AND team_id = ? AND assignment_id IS NULL", inspectionID, team.OrganizationID, team.ID).
+		First(&before).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: inspection is not in the team queue", ErrTeamQueueConflict)
+		}
+		return nil, fmt.Errorf("failed to get inspection: %v", err)
+	}
+	if before.InspectorID != "" {
+		return nil, fmt.Errorf("%w: inspection was already taken", ErrTeamQueueConflict)
+	}
+
+	scheduled := time.Now()
+	if before.ScheduledFor != nil && before.ScheduledFor.After(scheduled) {
+		scheduled = *before.ScheduledFor
+	}
+	if err := s.workflowService.availabilityService.EnsureAvailable(ctx, team.OrganizationID, inspectorID, scheduled); err != nil {
+		return nil, err
+	}
+	from, to := workPeriod(before.ScheduledFor, before.DueDate)
+	if err := requireQualified(ctx, s.db, team.OrganizationID, inspectorID, before.TemplateID.String(), from, to); err != nil {
+		return nil, err
+	}
+
+	result := db.Model(&models.Inspection{}).
+		Where("id = ? AND inspector_id IS NULL", before.ID).
+		Updates(map[string]interface{}{"inspector_id": inspectorID, "assigned_by": userID, "status": "assigned", "updated_at": time.Now()})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to assign inspection: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("%w: inspection was already taken", ErrTeamQueueConflict)
+	}
+
+	after := before
+	after.InspectorID = inspectorID
+	after.AssignedBy = &userID
+	after.Status = "assigned"
+	s.inspectionChanged(ctx, &before, &after)
+	if inspectorID != userID {
+		s.notify(team.OrganizationID, inspectorID, "New Inspection Assignment",
+			fmt.Sprintf("You have been assigned an inspection from the %s queue", team.Name))
+	}
+	return &after, nil
+}
+
+// GetWorkload returns the workloads of the team's members and what waits in its queue.
+// The team's lead and supervisors can see it.
+func (s *TeamService) GetWorkload(ctx context.Context, organizationID, teamID, userID, userRole string) (*models.TeamWorkload, error) {
+	team, err := s.requireTeamAccess(ctx, organizationID, teamID, userID, userRole, false)
+	if err != nil {
+		return nil, err
+	}
+
+	db := database.Conn(ctx, s.db)
+	workload := &models.TeamWorkload{TeamID: team.ID, Members: []models.InspectorWorkload{}}
+	if err := db.Where("organization_id = ? AND inspector_id IN (?)", organizationID,
+		db.Model(&models.TeamMember{}).Select("user_id").Where("team_id = ?", team.ID)).
+		Preload("Inspector").Find(&workload.Members).Error; err != nil {
+		return nil, fmt.Errorf("failed to get workloads: %v", err)
+	}
+	if err := db.Model(&models.InspectionAssignment{}).Where("team_id = ? AND assigned_to IS NULL", team.ID).
+		Count(&workload.QueuedAssignments).Error; err != nil {
+		return nil, fmt.Errorf("failed to count queued assignments: %v", err)
+	}
+	if err := db.Model(&models.Inspection{}).Where("team_id = ? AND inspector_id IS NULL AND assignment_id IS NULL", team.ID).
+		Count(&workload.QueuedInspections).Error; err != nil {
+		return nil, fmt.Errorf("failed to count queued inspections: %v", err)
+	}
+	return workload, nil
+}
+
+// inspectionChanged brings workloads and SLA clocks up to date after a queue moved an
+// inspection. The change is already saved, so a failure is logged.
+func (s *TeamService) inspectionChanged(ctx context.Context, before, after *models.Inspection) {
+	if err := s.workflowService.workloadMetrics.InspectionChanged(ctx, before, after); err != nil {
+		log.Printf("Failed to update workload metrics for inspection %s: %v", after.ID, err)
+	}
+	if err := s.workflowService.slaService.InspectionChanged(ctx, after); err != nil {
+		log.Printf("Failed to update SLA clocks for inspection %s: %v", after.ID, err)
+	}
+}
+
+func (s *TeamService) notify(organizationID, userID, title, message string) {
+	if _, err := s.notificationService.CreateNotification(&models.CreateNotificationRequest{
+		OrganizationID: organizationID,
+		UserID:         userID,
+		Title:          title,
+		Message:        message,
+		Type:           "team_queue",
+	}); err != nil {
+		log.Printf("Failed to notify %s about team work: %v", userID, err)
+	}
+}
@@ -0,0 +1,321 @@
+package services
+
+import (
+	"context"
+	"resource-mgmt/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// teamTestFixture is the North team, led by lead-1 with insp-1 and insp-2 as members, and
+// an assignment and a standalone inspection held by insp-3, who is outside the team
+type teamTestFixture struct {
+	db         *gorm.DB
+	service    *TeamService
+	workflow   *WorkflowService
+	team       *models.Team
+	template   *models.Template
+	site       *models.Site
+	assignment models.InspectionAssignment
+	standalone *models.Inspection
+}
+
+func newTeamTestFixture(t *testing.T) *teamTestFixture {
+	db := setupWorkflowTestDB(t, &models.Team{}, &models.TeamMember{})
+	ctx := context.Background()
+	workflow := NewWorkflowService(db, NewNotificationService())
+	f := &teamTestFixture{db: db, service: NewTeamService(db, workflow), workflow: workflow}
+	createTestMembers(t, db, "org-a", "supervisor", "super-1")
+	createTestMembers(t, db, "org-a", "inspector", "lead-1", "insp-1", "insp-2", "insp-3")
+
+	lead := "lead-1"
+	team, err := f.service.CreateTeam(ctx, "org-a", "super-1", &models.TeamRequest{Name: "North", LeadID: &lead})
+	require.NoError(t, err)
+	f.team, err = f.service.SetMembers(ctx, "org-a", team.ID, "super-1", []string{"insp-1", "insp-2"})
+	require.NoError(t, err)
+
+	f.template = createTestTemplate(t, db, "org-a", "Fire safety")
+	f.site = createTestSite(t, db, "org-a", "Depot", "4 Yard Rd")
+	assignments, err := workflow.CreateBulkAssignment(context.Background(), "org-a", "super-1", map[string]interface{}{
+		"name": "Quarterly", "template_id": f.template.ID.String(), "site_ids": []string{f.site.ID}, "due_date": time.Now().AddDate(0, 0, 3),
+		"inspector_assignments": []map[string]interface{}{{"inspector_id": "insp-3", "site_ids": []string{f.site.ID}}},
+	})
+	require.NoError(t, err)
+	require.Len(t, assignments, 1)
+	f.assignment = assignments[0]
+	f.standalone = &models.Inspection{OrganizationID: "org-a", TemplateID: f.template.ID, InspectorID: "insp-3", SiteID: f.site.ID, Status: "assigned"}
+	require.NoError(t, db.Create(f.standalone).Error)
+	return f
+}
+
+func (f *teamTestFixture) queueRequest() *models.QueueToTeamRequest {
+	return &models.QueueToTeamRequest{AssignmentIDs: []string{f.assignment.ID}, InspectionIDs: []string{f.standalone.ID.String()}, Reason: "Closer crew"}
+}
+
+// queue has the lead move the assignment and the standalone inspection into the team's queue
+func (f *teamTestFixture) queue(t *testing.T) *models.TeamQueue {
+	queue, err := f.service.Queue(context.Background(), "org-a", f.team.ID, "lead-1", "inspector", f.queueRequest())
+	require.NoError(t, err)
+	return queue
+}
+
+func (f *teamTestFixture) take(userID string, req *models.TakeFromQueueRequest) (*models.InspectionAssignment, *models.Inspection, error) {
+	return f.service.Take(context.Background(), "org-a", f.team.ID, userID, "inspector", req)
+}
+
+// visible counts the assignments the user sees
+func (f *teamTestFixture) visible(t *testing.T, userID string) int64 {
+	_, total, err := f.workflow.GetInspectionAssignments(context.Background(), "org-a", userID, AssignmentFilters{})
+	require.NoError(t, err)
+	return total
+}
+
+func TestTeamService_CreateTeamValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		req  models.TeamRequest
+	}{
+		{"missing name", models.TeamRequest{Name: " "}},
+		{"name already used in another case", models.TeamRequest{Name: "north"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTeamTestFixture(t)
+			req := tt.req
+
+			_, err := f.service.CreateTeam(context.Background(), "org-a", "super-1", &req)
+			assert.ErrorIs(t, err, ErrInvalidTeam)
+		})
+	}
+}
+
+func TestTeamService_SetMembersKeepsLead(t *testing.T) {
+	tests := []struct {
+		name        string
+		members     []string
+		wantErr     error
+		wantMembers []string
+	}{
+		{"replaces members", []string{"insp-3"}, nil, []string{"insp-3", "lead-1"}},
+		{"no members leaves the lead", nil, nil, []string{"lead-1"}},
+		{"someone outside the organization", []string{"insp-3", "stranger"}, ErrInvalidTeam, []string{"insp-1", "insp-2", "lead-1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTeamTestFixture(t)
+			ctx := context.Background()
+
+			_, err := f.service.SetMembers(ctx, "org-a", f.team.ID, "super-1", tt.members)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			team, err := f.service.GetTeam(ctx, "org-a", f.team.ID)
+			require.NoError(t, err)
+			var members []string
+			for _, member := range team.Members {
+				members = append(members, member.UserID)
+			}
+			assert.ElementsMatch(t, tt.wantMembers, members)
+		})
+	}
+}
+
+func TestTeamService_QueueRejections(t *testing.T) {
+	tests := []struct {
+		name    string
+		userID  string
+		prepare func(t *testing.T, f *teamTestFixture, req *models.QueueToTeamRequest)
+		wantErr error
+	}{
+		{"member who isn't the lead", "insp-1", nil, ErrTeamForbidden},
+		{"nothing to queue", "lead-1", func(t *testing.T, f *teamTestFixture, req *models.QueueToTeamRequest) {
+			req.AssignmentIDs, req.InspectionIDs = nil, nil
+		}, ErrInvalidTeam},
+		{"unknown assignment", "lead-1", func(t *testing.T, f *teamTestFixture, req *models.QueueToTeamRequest) {
+			req.AssignmentIDs = append(req.AssignmentIDs, uuid.NewString())
+		}, ErrInvalidTeam},
+		{"inactive team", "lead-1", func(t *testing.T, f *teamTestFixture, req *models.QueueToTeamRequest) {
+			require.NoError(t, f.db.Model(&models.Team{}).Where("id = ?", f.team.ID).Update("is_active", false).Error)
+		}, ErrInvalidTeam},
+		{"inspection already started", "lead-1", func(t *testing.T, f *teamTestFixture, req *models.QueueToTeamRequest) {
+			require.NoError(t, f.db.Model(&models.Inspection{}).Where("id = ?", f.standalone.ID).Update("status", "in_progress").Error)
+		}, ErrTeamQueueConflict},
+		{"assignment already completed", "lead-1", func(t *testing.T, f *teamTestFixture, req *models.QueueToTeamRequest) {
+			require.NoError(t, f.db.Model(&models.InspectionAssignment{}).Where("id = ?", f.assignment.ID).Update("status", "completed").Error)
+		}, ErrTeamQueueConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTeamTestFixture(t)
+			req := f.queueRequest()
+			if tt.prepare != nil {
+				tt.prepare(t, f, req)
+			}
+
+			_, err := f.service.Queue(context.Background(), "org-a", f.team.ID, tt.userID, "inspector", req)
+			assert.ErrorIs(t, err, tt.wantErr)
+
+			// Nothing moves when any of the work can't be queued
+			var assignment models.InspectionAssignment
+			require.NoError(t, f.db.First(&assignment, "id = ?", f.assignment.ID).Error)
+			assert.Equal(t, "insp-3", assignment.AssignedTo)
+			var inspection models.Inspection
+			require.NoError(t, f.db.First(&inspection, "id = ?", f.standalone.ID).Error)
+			assert.Equal(t, "insp-3", inspection.InspectorID)
+		})
+	}
+}
+
+func TestTeamService_QueueMovesWorkToTeam(t *testing.T) {
+	f := newTeamTestFixture(t)
+
+	queue := f.queue(t)
+	assert.Len(t, queue.Assignments, 1)
+	assert.Len(t, queue.Inspections, 1)
+
+	// Members see their team's queue; the previous inspector no longer sees the work
+	assert.Equal(t, int64(1), f.visible(t, "insp-1"))
+	assert.Equal(t, int64(0), f.visible(t, "insp-3"))
+}
+
+func TestTeamService_TakeRejections(t *testing.T) {
+	tests := []struct {
+		name    string
+		userID  string
+		req     func(f *teamTestFixture) *models.TakeFromQueueRequest
+		wantErr error
+	}{
+		{"assignment already taken", "insp-2", func(f *teamTestFixture) *models.TakeFromQueueRequest {
+			return &models.TakeFromQueueRequest{AssignmentID: f.assignment.ID}
+		}, ErrTeamQueueConflict},
+		{"member dispatching to someone else", "insp-2", func(f *teamTestFixture) *models.TakeFromQueueRequest {
+			return &models.TakeFromQueueRequest{InspectionID: f.standalone.ID.String(), InspectorID: "insp-1"}
+		}, ErrTeamForbidden},
+		{"lead dispatching outside the team", "lead-1", func(f *teamTestFixture) *models.TakeFromQueueRequest {
+			return &models.TakeFromQueueRequest{InspectionID: f.standalone.ID.String(), InspectorID: "insp-3"}
+		}, ErrInvalidTeam},
+		{"both assignment and inspection", "insp-2", func(f *teamTestFixture) *models.TakeFromQueueRequest {
+			return &models.TakeFromQueueRequest{AssignmentID: f.assignment.ID, InspectionID: f.standalone.ID.String()}
+		}, ErrInvalidTeam},
+		{"inspection not in the queue", "insp-2", func(f *teamTestFixture) *models.TakeFromQueueRequest {
+			return &models.TakeFromQueueRequest{InspectionID: uuid.NewString()}
+		}, ErrTeamQueueConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTeamTestFixture(t)
+			f.queue(t)
+			_, _, err := f.take("insp-1", &models.TakeFromQueueRequest{AssignmentID: f.assignment.ID})
+			require.NoError(t, err)
+
+			_, _, err = f.take(tt.userID, tt.req(f))
+			assert.ErrorIs(t, err, tt.wantErr)
+
+			// The earlier take stands and the inspection stays queued
+			var assignment models.InspectionAssignment
+			require.NoError(t, f.db.First(&assignment, "id = ?", f.assignment.ID).Error)
+			assert.Equal(t, "insp-1", assignment.AssignedTo)
+			var inspection models.Inspection
+			require.NoError(t, f.db.First(&inspection, "id = ?", f.standalone.ID).Error)
+			assert.Empty(t, inspection.InspectorID)
+		})
+	}
+}
+
+func TestTeamService_TakenWorkLeavesTeamView(t *testing.T) {
+	f := newTeamTestFixture(t)
+	f.queue(t)
+
+	taken, _, err := f.take("insp-1", &models.TakeFromQueueRequest{AssignmentID: f.assignment.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "insp-1", taken.AssignedTo)
+
+	// Taken work is seen by its inspector and the lead, not the rest of the team
+	assert.Equal(t, int64(1), f.visible(t, "insp-1"))
+	assert.Equal(t, int64(1), f.visible(t, "lead-1"))
+	assert.Equal(t, int64(0), f.visible(t, "insp-2"))
+}
+
+func TestTeamService_LeadDispatchesInspection(t *testing.T) {
+	f := newTeamTestFixture(t)
+	ctx := context.Background()
+	f.queue(t)
+
+	_, inspection, err := f.take("lead-1", &models.TakeFromQueueRequest{InspectionID: f.standalone.ID.String(), InspectorID: "insp-2"})
+	require.NoError(t, err)
+	assert.Equal(t, "insp-2", inspection.InspectorID)
+
+	scope, err := loadTeamScope(ctx, f.db, "org-a", "insp-2")
+	require.NoError(t, err)
+	assert.True(t, scope.sees(inspection.InspectorID, inspection.TeamID))
+	assert.False(t, scope.sees("insp-1", inspection.TeamID))
+	scope, err = loadTeamScope(ctx, f.db, "org-a", "lead-1")
+	require.NoError(t, err)
+	assert.True(t, scope.sees("insp-1", nil))
+}
+
+func TestTeamService_GetWorkloadAccess(t *testing.T) {
+	tests := []struct {
+		name    string
+		userID  string
+		role    string
+		wantErr error
+	}{
+		{"lead", "lead-1", "inspector", nil},
+		{"supervisor", "super-1", "supervisor", nil},
+		{"member", "insp-1", "inspector", ErrTeamForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTeamTestFixture(t)
+			f.queue(t)
+
+			workload, err := f.service.GetWorkload(context.Background(), "org-a", f.team.ID, tt.userID, tt.role)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), workload.QueuedAssignments)
+			assert.Equal(t, int64(1), workload.QueuedInspections)
+		})
+	}
+}
+
+func TestTeamService_DeleteTeam(t *testing.T) {
+	tests := []struct {
+		name    string
+		queue   bool
+		wantErr error
+	}{
+		{"work was queued for it", true, ErrTeamInUse},
+		{"never used", false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTeamTestFixture(t)
+			ctx := context.Background()
+			if tt.queue {
+				f.queue(t)
+			}
+
+			err := f.service.DeleteTeam(ctx, "org-a", f.team.ID)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				_, err = f.service.GetTeam(ctx, "org-a", f.team.ID)
+				assert.NoError(t, err, "the team is kept")
+				return
+			}
+			require.NoError(t, err)
+			_, err = f.service.GetTeam(ctx, "org-a", f.team.ID)
+			assert.ErrorIs(t, err, ErrTeamNotFound)
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"reflect"
+	"resource-mgmt/config"
+	"resource-mgmt/models"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// setupServiceTestDB opens an in-memory sqlite database with the given models migrated
+// and installs it as config.DB for the duration of the test.
+//
+// Postgres-only column defaults such as gen_random_uuid() are stripped from the
+// schema, and string primary keys are filled with UUIDs on create instead.
+func setupServiceTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	// Related models get migrated too, so strip their defaults as well
+	visited := make(map[*schema.Schema]bool)
+	var stripDefaults func(s *schema.Schema)
+	stripDefaults = func(s *schema.Schema) {
+		if s == nil || visited[s] {
+			return
+		}
+		visited[s] = true
+		for _, field := range s.Fields {
+			if strings.Contains(field.DefaultValue, "(") {
+				field.DefaultValue = ""
+				field.DefaultValueInterface = nil
+				field.HasDefaultValue = false
+			}
+		}
+		for _, rel := range s.Relationships.Relations {
+			stripDefaults(rel.FieldSchema)
+		}
+	}
+
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		require.NoError(t, stmt.Parse(model))
+		stripDefaults(stmt.Schema)
+	}
+	require.NoError(t, db.AutoMigrate(models...))
+
+	err = db.Callback().Create().Before("gorm:create").Register("test:uuid_primary_key", func(tx *gorm.DB) {
+		if tx.Statement.Schema == nil || tx.Statement.Schema.PrioritizedPrimaryField == nil {
+			return
+		}
+		field := tx.Statement.Schema.PrioritizedPrimaryField
+		isString := field.FieldType.Kind() == reflect.String
+		if !isString && field.FieldType != reflect.TypeOf(uuid.UUID{}) {
+			return
+		}
+
+		setID := func(rv reflect.Value) {
+			if _, zero := field.ValueOf(tx.Statement.Context, rv); zero {
+				if isString {
+					field.Set(tx.Statement.Context, rv, uuid.NewString())
+				} else {
+					field.Set(tx.Statement.Context, rv, uuid.New())
+				}
+			}
+		}
+
+		rv := tx.Statement.ReflectValue
+		switch rv.Kind() {
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < rv.Len(); i++ {
+				setID(reflect.Indirect(rv.Index(i)))
+			}
+		case reflect.Struct:
+			setID(rv)
+		}
+	})
+	require.NoError(t, err)
+
+	originalDB := config.DB
+	config.DB = db
+	t.Cleanup(func() { config.DB = originalDB })
+
+	return db
+}
+
+// workflowTestModels are the tables WorkflowService reads and writes when it creates
+// and moves assignments.
+var workflowTestModels = []interface{}{
+	&models.Template{}, &models.Site{}, &models.Inspection{}, &models.InspectionAssignment{}, &models.AssignmentEvent{},
+	&models.InspectorWorkload{}, &models.InspectorWorkingHours{}, &models.InspectorTimeOff{},
+	&models.OrganizationHoliday{}, &models.OrganizationBusinessHours{},
+	&models.CertificationType{}, &models.TemplateQualification{}, &models.InspectorCertification{},
+	&models.GlobalUser{}, &models.OrganizationMember{}, &models.Notification{},
+}
+
+// setupWorkflowTestDB is setupServiceTestDB with the workflow tables plus the given models.
+func setupWorkflowTestDB(t *testing.T, extra ...interface{}) *gorm.DB {
+	return setupServiceTestDB(t, append(append([]interface{}{}, workflowTestModels...), extra...)...)
+}
+
+// createTestMembers adds active members with the given role, each with a user account.
+func createTestMembers(t *testing.T, db *gorm.DB, orgID, role string, userIDs ...string) {
+	for _, userID := range userIDs {
+		require.NoError(t, db.Create(&models.GlobalUser{ID: userID, Email: userID + "@" + orgID + ".test", Name: userID}).Error)
+		require.NoError(t, db.Create(&models.OrganizationMember{UserID: userID, OrganizationID: orgID, Role: role, Status: "active"}).Error)
+	}
+}
+
+// createTestTemplate creates a template with no fields.
+func createTestTemplate(t *testing.T, db *gorm.DB, orgID, name string) *models.Template {
+	template := &models.Template{ID: uuid.New(), OrganizationID: orgID, Name: name, FieldsSchema: datatypes.JSON(`{}`)}
+	require.NoError(t, db.Create(template).Error)
+	return template
+}
+
+// createTestSite creates an active site.
+func createTestSite(t *testing.T, db *gorm.DB, orgID, name, address string) *models.Site {
+	site := &models.Site{ID: uuid.NewString(), OrganizationID: orgID, Name: name, Address: address, Status: "active"}
+	require.NoError(t, db.Create(site).Error)
+	return site
+}
+
+// countRows counts the rows the query matches.
+func countRows(t *testing.T, query *gorm.DB) int64 {
+	var count int64
+	require.NoError(t, query.Count(&count).Error)
+	return count
+}
+
+// setupAuditTestDB is setupServiceTestDB with the audit tables migrated and an audit
+// chain secret installed, for tests that look at the audit entries a service writes.
+func setupAuditTestDB(t *testing.T, extra ...interface{}) *gorm.DB {
+	previous := config.AuditChainSecret
+	config.AuditChainSecret = "test-audit-chain-secret"
+	t.Cleanup(func() { config.AuditChainSecret = previous })
+
+	return setupServiceTestDB(t, append([]interface{}{&AuditLog{}, &AuditChainHead{}}, extra...)...)
+}
@@ -1,27 +1,36 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
 	"resource-mgmt/models"
+	"resource-mgmt/pkg/database"
 	"resource-mgmt/utils"
 )
 
 type WorkflowService struct {
 	db                  *gorm.DB
 	notificationService *NotificationService
+	availabilityService *AvailabilityService
+	workloadMetrics     *WorkloadMetricsService
+	slaService          *SLAService
 }
 
 func NewWorkflowService(db *gorm.DB, notificationService *NotificationService) *WorkflowService {
 	return &WorkflowService{
 		db:                  db,
 		notificationService: notificationService,
+		availabilityService: NewAvailabilityService(db, notificationService),
+		workloadMetrics:     NewWorkloadMetricsService(db),
+		slaService:          NewSLAService(db, notificationService),
 	}
 }
 
@@ -240,13 +249,14 @@ type AssignmentFilters struct {
 	Priority   string
 	AssignedTo string
 	ProjectID  string
+	TeamID     string
 	Search     string
 	Overdue    bool
 	Page       int
 	Limit      int
 }
 
-func (s *WorkflowService) CreateBulkAssignment(orgID, userID string, req interface{}) ([]models.InspectionAssignment, error) {
+func (s *WorkflowService) CreateBulkAssignment(ctx context.Context, orgID, userID string, req interface{}) ([]models.InspectionAssignment, error) {
 	// Convert request to proper structure
 	reqData, _ := json.Marshal(req)
 	var assignmentReq struct {
@@ -267,17 +277,18 @@ func (s *WorkflowService) CreateBulkAssignment(orgID, userID string, req interfa
 		Metadata             map[string]interface{}      `json:"metadata"`
 	}
 	json.Unmarshal(reqData, &assignmentReq)
+	db := database.Conn(ctx, s.db)
 
 	// Validate template exists and belongs to organization
 	var template models.Template
-	if err := s.db.Where("id = ? AND organization_id = ?", assignmentReq.TemplateID, orgID).
+	if err := db.Where("id = ? AND organization_id = ?", assignmentReq.TemplateID, orgID).
 		First(&template).Error; err != nil {
 		return nil, errors.New("invalid template")
 	}
 
 	// Validate sites exist and belong to organization
 	var sites []models.Site
-	if err := s.db.Where("id IN ? AND organization_id = ?", assignmentReq.SiteIDs, orgID).
+	if err := db.Where("id IN ? AND organization_id = ?", assignmentReq.SiteIDs, orgID).
 		Find(&sites).Error; err != nil {
 		return nil, errors.New("invalid sites")
 	}
@@ -289,7 +300,7 @@ func (s *WorkflowService) CreateBulkAssignment(orgID, userID string, req interfa
 	for _, assignment := range assignmentReq.InspectorAssignments {
 		inspectorID := assignment["inspector_id"].(string)
 		var member models.OrganizationMember
-		if err := s.db.Where("user_id = ? AND organization_id = ? AND status = 'active'",
+		if err := db.Where("user_id = ? AND organization_id = ? AND status = 'active'",
 			inspectorID, orgID).First(&member).Error; err != nil {
 			return nil, fmt.Errorf("invalid inspector: %s", inspectorID)
 		}
@@ -306,6 +317,41 @@ func (s *WorkflowService) CreateBulkAssignment(orgID, userID string, req interfa
 		assignmentReq.EstimatedHours = 4
 	}
 
+	// Without a due date the work is due its estimated hours of business time after it starts
+	if assignmentReq.DueDate == nil {
+		start := time.Now()
+		if assignmentReq.StartDate != nil && assignmentReq.StartDate.After(start) {
+			start = *assignmentReq.StartDate
+		}
+		calendar, err := loadBusinessCalendar(ctx, db, orgID, "")
+		if err != nil {
+			return nil, err
+		}
+		dueDate := calendar.AddWorkingTime(start, time.Duration(assignmentReq.EstimatedHours)*time.Hour)
+		assignmentReq.DueDate = &dueDate
+	}
+
+	// Inspectors must be working on the start date, and each inspector's due date, given or
+	// computed, is pulled back to their last working day before it
+	dueDates := make(map[string]*time.Time)
+	for _, assignment := range assignmentReq.InspectorAssignments {
+		inspectorID := assignment["inspector_id"].(string)
+		dueDate, err := s.inspectorSchedule(ctx, orgID, inspectorID, assignmentReq.StartDate, assignmentReq.DueDate)
+		if err != nil {
+			return nil, err
+		}
+		dueDates[inspectorID] = dueDate
+	}
+
+	// Inspectors have to hold the template's certifications from the start through the due date
+	for _, assignment := range assignmentReq.InspectorAssignments {
+		inspectorID := assignment["inspector_id"].(string)
+		from, to := workPeriod(assignmentReq.StartDate, dueDates[inspectorID])
+		if err := requireQualified(ctx, db, orgID, inspectorID, assignmentReq.TemplateID, from, to); err != nil {
+			return nil, err
+		}
+	}
+
 	batchID := uuid.New().String()
 	var assignments []models.InspectionAssignment
 
@@ -334,7 +380,7 @@ func (s *WorkflowService) CreateBulkAssignment(orgID, userID string, req interfa
 			AssignedBy:         userID,
 			AssignedTo:         inspectorID,
 			StartDate:          assignmentReq.StartDate,
-			DueDate:            assignmentReq.DueDate,
+			DueDate:            dueDates[inspectorID],
 			EstimatedHours:     assignmentReq.EstimatedHours,
 			RequiresAcceptance: assignmentReq.RequiresAcceptance,
 			AllowReassignment:  assignmentReq.AllowReassignment,
@@ -345,9 +391,16 @@ func (s *WorkflowService) CreateBulkAssignment(orgID, userID string, req interfa
 			Metadata:           datatypes.JSON(metadataJSON),
 		}
 
-		if err := s.db.Create(&inspectorAssignment).Error; err != nil {
+		if err := db.Create(&inspectorAssignment).Error; err != nil {
 			return nil, fmt.Errorf("failed to create assignment for inspector %s: %v", inspectorID, err)
 		}
+		s.recordEvent(ctx, &models.AssignmentEvent{
+			OrganizationID: orgID,
+			AssignmentID:   inspectorAssignment.ID,
+			EventType:      models.AssignmentEventCreated,
+			ActorID:        userID,
+			ToUserID:       &inspectorID,
+		})
 
 		assignments = append(assignments, inspectorAssignment)
 
@@ -364,16 +417,16 @@ func (s *WorkflowService) CreateBulkAssignment(orgID, userID string, req interfa
 				Status:          "assigned",
 				Priority:        assignmentReq.Priority,
 				ScheduledFor:    assignmentReq.StartDate,
-				DueDate:         assignmentReq.DueDate,
+				DueDate:         dueDates[inspectorID],
 			}
 
-			if err := s.db.Create(&inspection).Error; err != nil {
+			if err := db.Create(&inspection).Error; err != nil {
 				return nil, fmt.Errorf("failed to create inspection for site %s: %v", siteID, err)
 			}
 		}
 
 		// Send notification to inspector
-		s.notificationService.CreateNotification(&models.CreateNotificationRequest{
+		s.notificationService.CreateNotificationWithContext(ctx, &models.CreateNotificationRequest{
 			OrganizationID: orgID,
 			UserID:         inspectorID,
 			Title:          "New Inspection Assignment",
@@ -382,28 +435,77 @@ func (s *WorkflowService) CreateBulkAssignment(orgID, userID string, req interfa
 	}
 
 	// Update inspector workloads
+	var inspectorIDs []string
 	for _, assignment := range assignmentReq.InspectorAssignments {
-		inspectorID := assignment["inspector_id"].(string)
-		s.updateInspectorWorkload(orgID, inspectorID)
+		inspectorIDs = append(inspectorIDs, assignment["inspector_id"].(string))
+	}
+	s.refreshWorkloads(ctx, orgID, inspectorIDs...)
+	for _, assignment := range assignments {
+		s.trackSLA(ctx, orgID, assignment.ID)
 	}
 
 	return assignments, nil
 }
 
-func (s *WorkflowService) GetInspectionAssignments(orgID, userID string, filters AssignmentFilters) ([]models.InspectionAssignment, int64, error) {
+// inspectorSchedule checks the inspector works on the start date, or today when there is
+// none, and returns the due date moved back to the inspector's last working day on or
+// before it
+func (s *WorkflowService) inspectorSchedule(ctx context.Context, orgID, inspectorID string, startDate, dueDate *time.Time) (*time.Time, error) {
+	earliest := time.Now()
+	if startDate != nil {
+		earliest = *startDate
+	}
+	if err := s.availabilityService.EnsureAvailable(ctx, orgID, inspectorID, earliest); err != nil {
+		return nil, err
+	}
+	if dueDate == nil || civilDate(*dueDate).Before(civilDate(earliest)) {
+		return dueDate, nil
+	}
+
+	day, ok, err := s.availabilityService.LatestAvailableDay(ctx, orgID, inspectorID, *dueDate, earliest)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %s has no working day between %s and %s", ErrInspectorUnavailable, inspectorID,
+			earliest.Format(civilDateLayout), dueDate.Format(civilDateLayout))
+	}
+	// Keep the time of day of the requested due date
+	adjusted := dueDate.AddDate(0, 0, -int(civilDate(*dueDate).Sub(day).Hours()/24))
+	return &adjusted, nil
+}
+
+// StepDueDate returns when a workflow step started at start is due: its duration counts
+// the organization's business hours only
+func (s *WorkflowService) StepDueDate(ctx context.Context, orgID string, step *models.WorkflowStep, start time.Time) (time.Time, error) {
+	calendar, err := loadBusinessCalendar(ctx, s.db, orgID, "")
+	if err != nil {
+		return time.Time{}, err
+	}
+	return calendar.AddWorkingTime(start, time.Duration(step.DurationHours)*time.Hour), nil
+}
+
+func (s *WorkflowService) GetInspectionAssignments(ctx context.Context, orgID, userID string, filters AssignmentFilters) ([]models.InspectionAssignment, int64, error) {
 	var assignments []models.InspectionAssignment
 	var total int64
+	db := database.Conn(ctx, s.db)
 
-	query := s.db.Where("organization_id = ?", orgID)
+	query := db.Where("organization_id = ?", orgID)
 
 	// Check user permissions - inspectors see only their assignments
 	var userMember models.OrganizationMember
-	if err := s.db.Where("user_id = ? AND organization_id = ?", userID, orgID).First(&userMember).Error; err != nil {
+	if err := db.Where("user_id = ? AND organization_id = ?", userID, orgID).First(&userMember).Error; err != nil {
 		return nil, 0, errors.New("user not found in organization")
 	}
 
-	if userMember.Role == "inspector" {
-		query = query.Where("assigned_to = ?", userID)
+	// Below supervisors, users see their own work, that of the teams they lead and their
+	// teams' queues
+	if !isSupervisorRole(userMember.Role) {
+		scope, err := loadTeamScope(ctx, db, orgID, userID)
+		if err != nil {
+			return nil, 0, err
+		}
+		query = query.Where(scope.condition("assigned_to"))
 	}
 
 	// Apply filters
@@ -413,12 +515,15 @@ func (s *WorkflowService) GetInspectionAssignments(orgID, userID string, filters
 	if filters.Priority != "" {
 		query = query.Where("priority = ?", filters.Priority)
 	}
-	if filters.AssignedTo != "" && userMember.Role != "inspector" {
+	if filters.AssignedTo != "" && isSupervisorRole(userMember.Role) {
 		query = query.Where("assigned_to = ?", filters.AssignedTo)
 	}
 	if filters.ProjectID != "" {
 		query = query.Where("project_id = ?", filters.ProjectID)
 	}
+	if filters.TeamID != "" {
+		query = query.Where(TeamWorkCondition("assigned_to", filters.TeamID))
+	}
 	if filters.Search != "" {
 		query = query.Where("name ILIKE ? OR description ILIKE ?", "%"+filters.Search+"%", "%"+filters.Search+"%")
 	}
@@ -441,7 +546,7 @@ func (s *WorkflowService) GetInspectionAssignments(orgID, userID string, filters
 
 	// Populate site names for each assignment
 	for i := range assignments {
-		err := s.populateSiteNames(&assignments[i])
+		err := s.populateSiteNames(ctx, &assignments[i])
 		if err != nil {
 			// Log error but don't fail the whole request
 			continue
@@ -461,9 +566,10 @@ func (s *WorkflowService) GetInspectionAssignment(orgID, assignmentID string) (*
 	return &assignment, nil
 }
 
-func (s *WorkflowService) AcceptAssignment(orgID, assignmentID, userID string) (*models.InspectionAssignment, error) {
+func (s *WorkflowService) AcceptAssignment(ctx context.Context, orgID, assignmentID, userID string) (*models.InspectionAssignment, error) {
+	db := database.Conn(ctx, s.db)
 	var assignment models.InspectionAssignment
-	if err := s.db.Where("organization_id = ? AND id = ? AND assigned_to = ? AND status = 'pending'",
+	if err := db.Where("organization_id = ? AND id = ? AND assigned_to = ? AND status = 'pending'",
 		orgID, assignmentID, userID).First(&assignment).Error; err != nil {
 		return nil, err
 	}
@@ -472,41 +578,58 @@ func (s *WorkflowService) AcceptAssignment(orgID, assignmentID, userID string) (
 	assignment.Status = "active"
 	assignment.AcceptedAt = &now
 
-	if err := s.db.Save(&assignment).Error; err != nil {
+	if err := db.Save(&assignment).Error; err != nil {
 		return nil, err
 	}
+	s.recordEvent(ctx, &models.AssignmentEvent{
+		OrganizationID: orgID,
+		AssignmentID:   assignment.ID,
+		EventType:      models.AssignmentEventAccepted,
+		ActorID:        userID,
+		ToUserID:       &userID,
+		OccurredAt:     now,
+	})
 
 	// Update related inspections
-	s.db.Model(&models.Inspection{}).Where("assignment_id = ?", assignmentID).
+	db.Model(&models.Inspection{}).Where("assignment_id = ?", assignmentID).
 		Updates(map[string]interface{}{
 			"status":     "assigned",
 			"updated_at": now,
 		})
+	s.refreshWorkloads(ctx, orgID, userID)
+	s.trackSLA(ctx, orgID, assignment.ID)
 
 	return &assignment, nil
 }
 
-func (s *WorkflowService) RejectAssignment(orgID, assignmentID, userID, reason string) (*models.InspectionAssignment, error) {
+// RejectAssignment refuses an assignment. The reason code, one of rejectionReasonCodes,
+// feeds the rejection report; the reason explains it in the inspector's words.
+func (s *WorkflowService) RejectAssignment(ctx context.Context, orgID, assignmentID, userID, reasonCode, reason string) (*models.InspectionAssignment, error) {
+	reasonCode, err := normalizeRejectionReason(reasonCode)
+	if err != nil {
+		return nil, err
+	}
+
+	db := database.Conn(ctx, s.db)
 	var assignment models.InspectionAssignment
-	if err := s.db.Where("organization_id = ? AND id = ? AND assigned_to = ?",
+	if err := db.Where("organization_id = ? AND id = ? AND assigned_to = ?",
 		orgID, assignmentID, userID).First(&assignment).Error; err != nil {
 		return nil, err
 	}
 
 	assignment.Status = "rejected"
-	// Add rejection reason to metadata
-	metadata := make(map[string]interface{})
-	if assignment.Metadata != nil {
-		json.Unmarshal(assignment.Metadata, &metadata)
-	}
-	metadata["rejection_reason"] = reason
-	metadata["rejected_at"] = time.Now()
-	metadataJSON, _ := json.Marshal(metadata)
-	assignment.Metadata = datatypes.JSON(metadataJSON)
-
-	if err := s.db.Save(&assignment).Error; err != nil {
+	if err := db.Save(&assignment).Error; err != nil {
 		return nil, err
 	}
+	s.recordEvent(ctx, &models.AssignmentEvent{
+		OrganizationID: orgID,
+		AssignmentID:   assignment.ID,
+		EventType:      models.AssignmentEventRejected,
+		ActorID:        userID,
+		FromUserID:     &userID,
+		ReasonCode:     reasonCode,
+		Reason:         reason,
+	})
 
 	// Notify assigner
 	s.notificationService.CreateNotification(&models.CreateNotificationRequest{
@@ -515,19 +638,32 @@ func (s *WorkflowService) RejectAssignment(orgID, assignmentID, userID, reason s
 		Title:          "Assignment Rejected",
 		Message:        fmt.Sprintf("Assignment '%s' was rejected by inspector. Reason: %s", assignment.Name, reason),
 	})
+	s.refreshWorkloads(ctx, orgID, userID)
+	s.trackSLA(ctx, orgID, assignment.ID)
 
 	return &assignment, nil
 }
 
-func (s *WorkflowService) ReassignInspection(orgID, assignmentID, userID, newInspectorID, reason string, notifyInspector bool) (*models.InspectionAssignment, error) {
+func (s *WorkflowService) ReassignInspection(ctx context.Context, orgID, assignmentID, userID, newInspectorID, reason string, notifyInspector bool) (*models.InspectionAssignment, error) {
 	var assignment models.InspectionAssignment
-	if err := s.db.Where("organization_id = ? AND id = ?", orgID, assignmentID).First(&assignment).Error; err != nil {
+	if err := database.Conn(ctx, s.db).Where("organization_id = ? AND id = ?", orgID, assignmentID).First(&assignment).Error; err != nil {
 		return nil, err
 	}
+	return s.moveAssignment(ctx, &assignment, userID, newInspectorID, models.AssignmentEventReassigned, reason, notifyInspector)
+}
+
+// ErrAssignmentChanged is returned when an assignment changed hands while it was being moved
+var ErrAssignmentChanged = errors.New("assignment was moved by someone else")
+
+// moveAssignment hands the assignment and its inspections to another inspector, who has to
+// accept it again, and records the hop as a reassigned or delegated event
+func (s *WorkflowService) moveAssignment(ctx context.Context, assignment *models.InspectionAssignment, userID, newInspectorID, eventType, reason string, notifyInspector bool) (*models.InspectionAssignment, error) {
+	orgID, assignmentID := assignment.OrganizationID, assignment.ID
+	db := database.Conn(ctx, s.db)
 
 	// Validate new inspector
 	var member models.OrganizationMember
-	if err := s.db.Where("user_id = ? AND organization_id = ? AND status = 'active'",
+	if err := db.Where("user_id = ? AND organization_id = ? AND status = 'active'",
 		newInspectorID, orgID).First(&member).Error; err != nil {
 		return nil, errors.New("invalid inspector")
 	}
@@ -535,39 +671,83 @@ func (s *WorkflowService) ReassignInspection(orgID, assignmentID, userID, newIns
 		return nil, errors.New("user does not have inspector privileges")
 	}
 
+	// The new inspector has to be working when the assignment starts, or today if it
+	// already has
+	startDay := time.Now()
+	if assignment.StartDate != nil && assignment.StartDate.After(startDay) {
+		startDay = *assignment.StartDate
+	}
+	if err := s.availabilityService.EnsureAvailable(ctx, orgID, newInspectorID, startDay); err != nil {
+		return nil, err
+	}
+	from, to := workPeriod(assignment.StartDate, assignment.DueDate)
+	if err := requireQualified(ctx, db, orgID, newInspectorID, assignment.TemplateID, from, to); err != nil {
+		return nil, err
+	}
+
+	// Work taken from a team queue had nobody to move it from
 	oldInspectorID := assignment.AssignedTo
+	var fromUserID *string
+	if oldInspectorID != "" {
+		fromUserID = &oldInspectorID
+	}
+
+	// Only move it away from whoever held it when it was read, so two moves can't both win
+	held := db.Model(&models.InspectionAssignment{}).Where("id = ?", assignmentID)
+	if oldInspectorID == "" {
+		held = held.Where("assigned_to IS NULL")
+	} else {
+		held = held.Where("assigned_to = ?", oldInspectorID)
+	}
+	result := held.Updates(map[string]interface{}{
+		"assigned_to":    newInspectorID,
+		"delegated_from": fromUserID,
+		"status":         "pending",
+		"accepted_at":    nil,
+		"updated_at":     time.Now(),
+	})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrAssignmentChanged
+	}
 	assignment.AssignedTo = newInspectorID
-	assignment.DelegatedFrom = &oldInspectorID
+	assignment.DelegatedFrom = fromUserID
 	assignment.Status = "pending"
 	assignment.AcceptedAt = nil
 
-	// Add reassignment info to metadata
-	metadata := make(map[string]interface{})
-	if assignment.Metadata != nil {
-		json.Unmarshal(assignment.Metadata, &metadata)
-	}
-	metadata["reassignment_reason"] = reason
-	metadata["reassigned_at"] = time.Now()
-	metadata["reassigned_by"] = userID
-	metadata["previous_inspector"] = oldInspectorID
-	metadataJSON, _ := json.Marshal(metadata)
-	assignment.Metadata = datatypes.JSON(metadataJSON)
-
-	if err := s.db.Save(&assignment).Error; err != nil {
-		return nil, err
-	}
+	s.recordEvent(ctx, &models.AssignmentEvent{
+		OrganizationID: orgID,
+		AssignmentID:   assignmentID,
+		EventType:      eventType,
+		ActorID:        userID,
+		FromUserID:     fromUserID,
+		ToUserID:       &newInspectorID,
+		Reason:         reason,
+	})
 
 	// Update related inspections
-	s.db.Model(&models.Inspection{}).Where("assignment_id = ?", assignmentID).
+	var moved []models.Inspection
+	db.Where("assignment_id = ?", assignmentID).Find(&moved)
+	db.Model(&models.Inspection{}).Where("assignment_id = ?", assignmentID).
 		Updates(map[string]interface{}{
 			"inspector_id": newInspectorID,
 			"status":       "assigned",
 			"updated_at":   time.Now(),
 		})
 
-	// Update workloads
-	s.updateInspectorWorkload(orgID, oldInspectorID)
-	s.updateInspectorWorkload(orgID, newInspectorID)
+	// Update workloads: finished inspections leave the old inspector's totals
+	for i := range moved {
+		after := moved[i]
+		after.InspectorID = newInspectorID
+		after.Status = "assigned"
+		if err := s.workloadMetrics.InspectionChanged(ctx, &moved[i], &after); err != nil {
+			log.Printf("Failed to update workload metrics for inspection %s: %v", moved[i].ID, err)
+		}
+	}
+	s.refreshWorkloads(ctx, orgID, oldInspectorID, newInspectorID)
+	s.trackSLA(ctx, orgID, assignment.ID)
 
 	if notifyInspector {
 		s.notificationService.CreateNotification(&models.CreateNotificationRequest{
@@ -578,7 +758,7 @@ func (s *WorkflowService) ReassignInspection(orgID, assignmentID, userID, newIns
 		})
 	}
 
-	return &assignment, nil
+	return assignment, nil
 }
 
 // =====================================================
@@ -606,9 +786,111 @@ func (s *WorkflowService) GetInspectionReviews(orgID, userID string, filters Rev
 	return nil, 0, errors.New("not implemented")
 }
 
-func (s *WorkflowService) SubmitInspectionReview(orgID, reviewID, userID string, req interface{}) (*models.InspectionReview, error) {
-	// Implementation for submitting reviews
-	return nil, errors.New("not implemented")
+// GetInspectionReview retrieves a single inspection review
+func (s *WorkflowService) GetInspectionReview(orgID, reviewID string) (*models.InspectionReview, error) {
+	var review models.InspectionReview
+	err := s.db.Where("id = ? AND organization_id = ?", reviewID, orgID).First(&review).Error
+	if err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+// SubmitInspectionReview records the reviewer's decision. A scored review counts towards
+// the reviewed inspector's quality score once it is completed; escalated reviews stay
+// open for the person they were escalated to.
+func (s *WorkflowService) SubmitInspectionReview(ctx context.Context, orgID, reviewID, userID string, req interface{}) (*models.InspectionReview, error) {
+	// Convert request to proper structure
+	reqData, _ := json.Marshal(req)
+	var submitReq struct {
+		Decision         string                   `json:"decision"`
+		Comments         string                   `json:"comments"`
+		RequiredChanges  []map[string]interface{} `json:"required_changes"`
+		QualityScore     *float64                 `json:"quality_score"`
+		ComplianceIssues []map[string]interface{} `json:"compliance_issues"`
+		Recommendations  string                   `json:"recommendations"`
+		EscalatedTo      *string                  `json:"escalated_to"`
+		EscalationReason string                   `json:"escalation_reason"`
+		Attachments      []string                 `json:"attachments"`
+	}
+	json.Unmarshal(reqData, &submitReq)
+	db := database.Conn(ctx, s.db)
+
+	var review models.InspectionReview
+	if err := db.Where("organization_id = ? AND id = ?", orgID, reviewID).First(&review).Error; err != nil {
+		return nil, err
+	}
+	if review.ReviewerID != userID && (review.EscalatedTo == nil || *review.EscalatedTo != userID) {
+		return nil, errors.New("only the assigned reviewer can submit this review")
+	}
+	if review.CompletedAt != nil {
+		return nil, errors.New("review has already been submitted")
+	}
+	if submitReq.QualityScore != nil && (*submitReq.QualityScore < 0 || *submitReq.QualityScore > 100) {
+		return nil, errors.New("quality score must be between 0 and 100")
+	}
+
+	now := time.Now()
+	switch submitReq.Decision {
+	case "approved":
+		review.Status = "approved"
+		review.CompletedAt = &now
+	case "rejected", "requires_changes":
+		review.Status = "rejected"
+		review.CompletedAt = &now
+	case "escalated":
+		if submitReq.EscalatedTo == nil || *submitReq.EscalatedTo == "" {
+			return nil, errors.New("escalated_to is required to escalate a review")
+		}
+		review.Status = "escalated"
+		review.EscalatedTo = submitReq.EscalatedTo
+		review.EscalationReason = submitReq.EscalationReason
+		review.EscalatedAt = &now
+	default:
+		return nil, fmt.Errorf("invalid decision: %s", submitReq.Decision)
+	}
+
+	review.Decision = submitReq.Decision
+	review.Comments = submitReq.Comments
+	review.QualityScore = submitReq.QualityScore
+	review.Recommendations = submitReq.Recommendations
+	if review.StartedAt == nil {
+		review.StartedAt = &now
+	}
+	if submitReq.RequiredChanges != nil {
+		requiredChanges, _ := json.Marshal(submitReq.RequiredChanges)
+		review.RequiredChanges = datatypes.JSON(requiredChanges)
+	}
+	if submitReq.ComplianceIssues != nil {
+		complianceIssues, _ := json.Marshal(submitReq.ComplianceIssues)
+		review.ComplianceIssues = datatypes.JSON(complianceIssues)
+	}
+	if submitReq.Attachments != nil {
+		attachments, _ := json.Marshal(submitReq.Attachments)
+		review.Attachments = datatypes.JSON(attachments)
+	}
+
+	if err := db.Save(&review).Error; err != nil {
+		return nil, err
+	}
+
+	// Update the reviewed inspector's quality score
+	if review.CompletedAt != nil && review.QualityScore != nil {
+		inspectorID, err := s.workloadMetrics.ReviewInspector(ctx, &review)
+		if err == nil {
+			err = s.workloadMetrics.ReviewScored(ctx, orgID, inspectorID, nil, review.QualityScore)
+		}
+		if err != nil {
+			log.Printf("Failed to update quality score for review %s: %v", review.ID, err)
+		}
+	}
+	if review.InspectionID != nil {
+		if err := s.slaService.TrackInspection(ctx, orgID, *review.InspectionID); err != nil {
+			log.Printf("Failed to update SLA clocks for inspection %s: %v", *review.InspectionID, err)
+		}
+	}
+
+	return &review, nil
 }
 
 // =====================================================
@@ -619,6 +901,7 @@ type WorkloadFilters struct {
 	Available  string
 	Overloaded bool
 	Search     string
+	TeamID     string
 }
 
 func (s *WorkflowService) GetInspectorWorkloads(orgID string, filters WorkloadFilters) ([]models.InspectorWorkload, error) {
@@ -638,6 +921,10 @@ func (s *WorkflowService) GetInspectorWorkloads(orgID string, filters WorkloadFi
 		query = query.Where("current_daily_load >= max_daily_inspections OR current_weekly_load >= max_weekly_inspections")
 	}
 
+	if filters.TeamID != "" {
+		query = query.Where("inspector_id IN (SELECT user_id FROM team_members WHERE team_id = ?)", filters.TeamID)
+	}
+
 	if err := query.Preload("Inspector").Find(&workloads).Error; err != nil {
 		return nil, err
 	}
@@ -668,6 +955,9 @@ func (s *WorkflowService) UpdateInspectorWorkload(orgID, inspectorID string, req
 	var updateReq map[string]interface{}
 	json.Unmarshal(reqData, &updateReq)
 
+	// Time off is managed through time-off requests, which keep this column in sync
+	delete(updateReq, "scheduled_time_off")
+
 	if err := s.db.Model(&workload).Updates(updateReq).Error; err != nil {
 		return nil, err
 	}
@@ -675,57 +965,40 @@ func (s *WorkflowService) UpdateInspectorWorkload(orgID, inspectorID string, req
 	return &workload, nil
 }
 
-// Helper function to update inspector workload
-func (s *WorkflowService) updateInspectorWorkload(orgID, inspectorID string) error {
-	// This would be called automatically by database triggers in production
-	// For now, we'll implement basic logic
-
-	var workload models.InspectorWorkload
-	err := s.db.Where("organization_id = ? AND inspector_id = ?", orgID, inspectorID).
-		First(&workload).Error
-	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			// Create new workload record with defaults
-			workload = models.InspectorWorkload{
-				OrganizationID:       orgID,
-				InspectorID:          inspectorID,
-				MaxDailyInspections:  8,
-				MaxWeeklyInspections: 40,
-				MaxConcurrentProjects: 5,
-				WorkingHoursPerDay:   8,
-				IsAvailable:          true,
-			}
-			s.db.Create(&workload)
-		} else {
-			return err
-		}
+// refreshWorkloads recounts inspectors' loads after their assignments changed. The
+// change is already saved, so a failure is logged and left to the nightly reconciliation.
+func (s *WorkflowService) refreshWorkloads(ctx context.Context, orgID string, inspectorIDs ...string) {
+	if err := s.workloadMetrics.AssignmentsChanged(ctx, orgID, inspectorIDs...); err != nil {
+		log.Printf("Failed to update inspector workloads: %v", err)
 	}
+}
 
-	// Calculate current loads (would be done by triggers in production)
-	var dailyLoad, weeklyLoad, overdueCount int64
-
-	now := time.Now()
-	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-	weekStart := today.AddDate(0, 0, -int(today.Weekday()))
-
-	s.db.Model(&models.Inspection{}).Where("inspector_id = ? AND DATE(scheduled_for) = ? AND status IN ?",
-		inspectorID, today, []string{"assigned", "in_progress"}).Count(&dailyLoad)
-
-	s.db.Model(&models.Inspection{}).Where("inspector_id = ? AND DATE(scheduled_for) >= ? AND DATE(scheduled_for) < ? AND status IN ?",
-		inspectorID, weekStart, weekStart.AddDate(0, 0, 7), []string{"assigned", "in_progress"}).Count(&weeklyLoad)
-
-	s.db.Model(&models.Inspection{}).Where("inspector_id = ? AND due_date < ? AND status NOT IN ?",
-		inspectorID, now, []string{"completed", "cancelled"}).Count(&overdueCount)
+// recordEvent adds an entry to an assignment's history
+func (s *WorkflowService) recordEvent(ctx context.Context, event *models.AssignmentEvent) {
+	if err := recordAssignmentEvent(ctx, s.db, event); err != nil {
+		log.Printf("Failed to record %s event for assignment %s: %v", event.EventType, event.AssignmentID, err)
+	}
+}
 
-	// Update workload
-	s.db.Model(&workload).Updates(map[string]interface{}{
-		"current_daily_load":   dailyLoad,
-		"current_weekly_load":  weeklyLoad,
-		"overdue_inspections":  overdueCount,
-		"last_updated":         time.Now(),
-	})
+// trackSLA brings the SLA clocks of assignments and their inspections up to date. The
+// change is already saved, so a failure is logged and the clocks catch up on the next change.
+func (s *WorkflowService) trackSLA(ctx context.Context, orgID string, assignmentIDs ...string) {
+	for _, assignmentID := range assignmentIDs {
+		if err := s.slaService.TrackAssignment(ctx, orgID, assignmentID); err != nil {
+			log.Printf("Failed to update SLA clocks for assignment %s: %v", assignmentID, err)
+		}
 
-	return nil
+		var inspectionIDs []string
+		if err := database.Conn(ctx, s.db).Model(&models.Inspection{}).Where("assignment_id = ?", assignmentID).Pluck("id", &inspectionIDs).Error; err != nil {
+			log.Printf("Failed to get inspections of assignment %s: %v", assignmentID, err)
+			continue
+		}
+		for _, inspectionID := range inspectionIDs {
+			if err := s.slaService.TrackInspection(ctx, orgID, inspectionID); err != nil {
+				log.Printf("Failed to update SLA clocks for inspection %s: %v", inspectionID, err)
+			}
+		}
+	}
 }
 
 // =====================================================
@@ -758,9 +1031,14 @@ func (s *WorkflowService) GetWorkflowAnalytics(orgID string, filters AnalyticsFi
 	return analytics, nil
 }
 
-func (s *WorkflowService) GetProjectProgress(orgID, projectID string) (map[string]interface{}, error) {
-	// Implementation for project progress
-	return nil, errors.New("not implemented")
+// GetProjectProgress summarizes how much of the project is done, its velocity and when it
+// is projected to finish
+func (s *WorkflowService) GetProjectProgress(orgID, projectID string) (*models.ProjectProgress, error) {
+	timeline, err := buildProjectTimeline(context.Background(), s.db, orgID, projectID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &timeline.Progress, nil
 }
 
 // =====================================================
@@ -850,7 +1128,7 @@ func (s *WorkflowService) ResolveAlert(orgID, alertID, userID, resolution string
 }
 
 // Helper function to populate site names from site IDs JSON array
-func (s *WorkflowService) populateSiteNames(assignment *models.InspectionAssignment) error {
+func (s *WorkflowService) populateSiteNames(ctx context.Context, assignment *models.InspectionAssignment) error {
 	if assignment.SiteIDs == nil {
 		return nil
 	}
@@ -867,7 +1145,7 @@ func (s *WorkflowService) populateSiteNames(assignment *models.InspectionAssignm
 
 	// Query sites for names
 	var sites []models.Site
-	if err := s.db.Where("id IN ? AND organization_id = ?", siteIDs, assignment.OrganizationID).
+	if err := database.Conn(ctx, s.db).Where("id IN ? AND organization_id = ?", siteIDs, assignment.OrganizationID).
 		Select("id, name").Find(&sites).Error; err != nil {
 		return err
 	}
@@ -0,0 +1,583 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"resource-mgmt/models"
+	"resource-mgmt/pkg/database"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Workload metrics errors
+var (
+	// ErrInvalidWorkloadRange is returned when a history range is empty or too long
+	ErrInvalidWorkloadRange = errors.New("invalid workload history range")
+)
+
+var (
+	// openInspectionStatuses count towards an inspector's daily and weekly load
+	openInspectionStatuses = []string{"assigned", "in_progress"}
+
+	// finishedInspectionStatuses are inspections the inspector has finished, whatever
+	// the review made of them
+	finishedInspectionStatuses = []string{"completed", "approved", "rejected"}
+)
+
+// defaultAverageInspectionTime is reported until an inspector has a timed inspection
+const defaultAverageInspectionTime = 240
+
+// maxWorkloadHistoryDays caps how much history one request returns
+const maxWorkloadHistoryDays = 366
+
+// WorkloadMetricsService keeps inspector workloads current as inspections, assignments
+// and reviews change. Loads depend on the day, so they are recounted for the inspectors a
+// change touches; the performance metrics come from running totals adjusted by each
+// change. A nightly reconciliation recounts everything, corrects any drift and records
+// each workload in the history.
+type WorkloadMetricsService struct {
+	db *gorm.DB
+}
+
+func NewWorkloadMetricsService(db *gorm.DB) *WorkloadMetricsService {
+	return &WorkloadMetricsService{db: db}
+}
+
+// performanceTotals are the running totals behind an inspector's performance metrics
+type performanceTotals struct {
+	DueCompletions    int
+	OnTimeCompletions int
+	TimedInspections  int
+	InspectionMinutes float64
+	ScoredReviews     int
+	QualityScoreTotal float64
+}
+
+// add returns t plus sign times o
+func (t performanceTotals) add(o performanceTotals, sign int) performanceTotals {
+	return performanceTotals{
+		DueCompletions:    t.DueCompletions + sign*o.DueCompletions,
+		OnTimeCompletions: t.OnTimeCompletions + sign*o.OnTimeCompletions,
+		TimedInspections:  t.TimedInspections + sign*o.TimedInspections,
+		InspectionMinutes: t.InspectionMinutes + float64(sign)*o.InspectionMinutes,
+		ScoredReviews:     t.ScoredReviews + sign*o.ScoredReviews,
+		QualityScoreTotal: t.QualityScoreTotal + float64(sign)*o.QualityScoreTotal,
+	}
+}
+
+// inspectionPerformance is what one inspection adds to its inspector's totals: completions
+// against a due date, and the time from start to completion
+func inspectionPerformance(inspection *models.Inspection) performanceTotals {
+	var totals performanceTotals
+	if inspection == nil || inspection.CompletedAt == nil || !containsString(finishedInspectionStatuses, inspection.Status) {
+		return totals
+	}
+	if inspection.DueDate != nil {
+		totals.DueCompletions = 1
+		if !inspection.CompletedAt.After(*inspection.DueDate) {
+			totals.OnTimeCompletions = 1
+		}
+	}
+	if inspection.StartedAt != nil && inspection.CompletedAt.After(*inspection.StartedAt) {
+		totals.TimedInspections = 1
+		totals.InspectionMinutes = inspection.CompletedAt.Sub(*inspection.StartedAt).Minutes()
+	}
+	return totals
+}
+
+// reviewPerformance is what one review adds to its inspector's totals
+func reviewPerformance(qualityScore *float64) performanceTotals {
+	if qualityScore == nil {
+		return performanceTotals{}
+	}
+	return performanceTotals{ScoredReviews: 1, QualityScoreTotal: *qualityScore}
+}
+
+// workloadTotals reads the running totals stored on a workload
+func workloadTotals(workload *models.InspectorWorkload) performanceTotals {
+	return performanceTotals{
+		DueCompletions:    workload.DueCompletions,
+		OnTimeCompletions: workload.OnTimeCompletions,
+		TimedInspections:  workload.TimedInspections,
+		InspectionMinutes: workload.InspectionMinutes,
+		ScoredReviews:     workload.ScoredReviews,
+		QualityScoreTotal: workload.QualityScoreTotal,
+	}
+}
+
+// performanceMetrics turns running totals into the completion rate, average inspection
+// time and quality score columns
+func performanceMetrics(totals performanceTotals) map[string]interface{} {
+	completionRate, averageTime, qualityScore := 0.0, defaultAverageInspectionTime, 0.0
+	if totals.DueCompletions > 0 {
+		completionRate = float64(max(totals.OnTimeCompletions, 0)) * 100 / float64(totals.DueCompletions)
+	}
+	if totals.TimedInspections > 0 {
+		averageTime = int(max(totals.InspectionMinutes, 0)/float64(totals.TimedInspections) + 0.5)
+	}
+	if totals.ScoredReviews > 0 {
+		qualityScore = totals.QualityScoreTotal / float64(totals.ScoredReviews)
+	}
+	return map[string]interface{}{
+		"completion_rate":         completionRate,
+		"average_inspection_time": averageTime,
+		"quality_score":           qualityScore,
+	}
+}
+
+// =====================================================
+// CHANGE EVENTS
+// =====================================================
+
+// InspectionChanged updates the workloads an inspection change touches. before is nil for
+// a new inspection and after is nil for a deleted one; when the inspector changed, the
+// inspection moves from one workload to the other.
+func (s *WorkloadMetricsService) InspectionChanged(ctx context.Context, before, after *models.Inspection) error {
+	organizationID := ""
+	deltas := make(map[string]performanceTotals)
+	if before != nil && before.InspectorID != "" {
+		organizationID = before.OrganizationID
+		deltas[before.InspectorID] = deltas[before.InspectorID].add(inspectionPerformance(before), -1)
+	}
+	if after != nil && after.InspectorID != "" {
+		organizationID = after.OrganizationID
+		deltas[after.InspectorID] = deltas[after.InspectorID].add(inspectionPerformance(after), 1)
+	}
+	if len(deltas) == 0 {
+		return nil
+	}
+
+	inspectorIDs := make([]string, 0, len(deltas))
+	for inspectorID := range deltas {
+		inspectorIDs = append(inspectorIDs, inspectorID)
+	}
+	sort.Strings(inspectorIDs) // Lock workloads in a stable order
+
+	now := time.Now()
+	return database.Conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		for _, inspectorID := range inspectorIDs {
+			workload, err := s.ensureWorkload(tx, organizationID, inspectorID)
+			if err != nil {
+				return err
+			}
+			if err := s.applyPerformance(tx, workload, deltas[inspectorID]); err != nil {
+				return err
+			}
+			if err := s.refreshLoad(tx, workload, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// AssignmentsChanged recounts the loads of inspectors whose assignments were created,
+// accepted, rejected or moved
+func (s *WorkloadMetricsService) AssignmentsChanged(ctx context.Context, organizationID string, inspectorIDs ...string) error {
+	inspectorIDs = uniqueStrings(inspectorIDs)
+	sort.Strings(inspectorIDs)
+
+	now := time.Now()
+	return database.Conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		for _, inspectorID := range inspectorIDs {
+			if inspectorID == "" {
+				continue
+			}
+			workload, err := s.ensureWorkload(tx, organizationID, inspectorID)
+			if err != nil {
+				return err
+			}
+			if err := s.refreshLoad(tx, workload, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ReviewScored moves the inspector's quality score from a review's previous score to its
+// new one. Either may be nil for a review that wasn't, or no longer is, scored.
+func (s *WorkloadMetricsService) ReviewScored(ctx context.Context, organizationID, inspectorID string, before, after *float64) error {
+	if inspectorID == "" {
+		return nil
+	}
+	delta := reviewPerformance(after).add(reviewPerformance(before), -1)
+
+	return database.Conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		workload, err := s.ensureWorkload(tx, organizationID, inspectorID)
+		if err != nil {
+			return err
+		}
+		return s.applyPerformance(tx, workload, delta)
+	})
+}
+
+// ReviewInspector returns who a review's quality score counts for: the inspector of the
+// reviewed inspection, or the assignee of the reviewed assignment
+func (s *WorkloadMetricsService) ReviewInspector(ctx context.Context, review *models.InspectionReview) (string, error) {
+	db := database.Conn(ctx, s.db)
+	switch {
+	case review.InspectionID != nil:
+		var inspection models.Inspection
+		if err := db.Select("inspector_id").Where("organization_id = ? AND id = ?", review.OrganizationID, *review.InspectionID).
+			First(&inspection).Error; err != nil {
+			return "", fmt.Errorf("failed to get reviewed inspection: %v", err)
+		}
+		return inspection.InspectorID, nil
+	case review.AssignmentID != nil:
+		var assignment models.InspectionAssignment
+		if err := db.Select("assigned_to").Where("organization_id = ? AND id = ?", review.OrganizationID, *review.AssignmentID).
+			First(&assignment).Error; err != nil {
+			return "", fmt.Errorf("failed to get reviewed assignment: %v", err)
+		}
+		return assignment.AssignedTo, nil
+	default:
+		return "", nil
+	}
+}
+
+// ensureWorkload returns the inspector's workload, creating one with the default
+// capacity if they don't have one yet
+func (s *WorkloadMetricsService) ensureWorkload(tx *gorm.DB, organizationID, inspectorID string) (*models.InspectorWorkload, error) {
+	var workload models.InspectorWorkload
+	err := tx.Where("organization_id = ? AND inspector_id = ?", organizationID, inspectorID).First(&workload).Error
+	if err == nil {
+		return &workload, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get workload: %v", err)
+	}
+
+	workload = models.InspectorWorkload{
+		OrganizationID:        organizationID,
+		InspectorID:           inspectorID,
+		MaxDailyInspections:   8,
+		MaxWeeklyInspections:  40,
+		MaxConcurrentProjects: 5,
+		WorkingHoursPerDay:    8,
+		IsAvailable:           true,
+		AverageInspectionTime: defaultAverageInspectionTime,
+		LastUpdated:           time.Now(),
+	}
+	if err := tx.Create(&workload).Error; err != nil {
+		return nil, fmt.Errorf("failed to create workload: %v", err)
+	}
+	return &workload, nil
+}
+
+// applyPerformance adds delta to the workload's running totals and recalculates the
+// metrics from them
+func (s *WorkloadMetricsService) applyPerformance(tx *gorm.DB, workload *models.InspectorWorkload, delta performanceTotals) error {
+	if delta == (performanceTotals{}) {
+		return nil
+	}
+
+	if err := tx.Model(&models.InspectorWorkload{}).Where("id = ?", workload.ID).Updates(map[string]interface{}{
+		"due_completions":     gorm.Expr("due_completions + ?", delta.DueCompletions),
+		"on_time_completions": gorm.Expr("on_time_completions + ?", delta.OnTimeCompletions),
+		"timed_inspections":   gorm.Expr("timed_inspections + ?", delta.TimedInspections),
+		"inspection_minutes":  gorm.Expr("inspection_minutes + ?", delta.InspectionMinutes),
+		"scored_reviews":      gorm.Expr("scored_reviews + ?", delta.ScoredReviews),
+		"quality_score_total": gorm.Expr("quality_score_total + ?", delta.QualityScoreTotal),
+	}).Error; err != nil {
+		return fmt.Errorf("failed to update workload totals: %v", err)
+	}
+	if err := tx.Where("id = ?", workload.ID).First(workload).Error; err != nil {
+		return fmt.Errorf("failed to get workload: %v", err)
+	}
+
+	updates := performanceMetrics(workloadTotals(workload))
+	updates["last_updated"] = time.Now()
+	if err := tx.Model(workload).Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to update workload metrics: %v", err)
+	}
+	return nil
+}
+
+// refreshLoad recounts the inspector's open work as of now: inspections scheduled today
+// and this week, overdue inspections, assignments awaiting acceptance and projects with
+// open assignments. Days and weeks are the organization's, in its time zone.
+func (s *WorkloadMetricsService) refreshLoad(tx *gorm.DB, workload *models.InspectorWorkload, now time.Time) error {
+	local := now.In(organizationLocation(context.Background(), tx, workload.OrganizationID))
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+	weekStart := today.AddDate(0, 0, -int(today.Weekday()))
+
+	inspections := func() *gorm.DB {
+		return tx.Model(&models.Inspection{}).Where("organization_id = ? AND inspector_id = ?", workload.OrganizationID, workload.InspectorID)
+	}
+	assignments := func() *gorm.DB {
+		return tx.Model(&models.InspectionAssignment{}).Where("organization_id = ? AND assigned_to = ?", workload.OrganizationID, workload.InspectorID)
+	}
+
+	var dailyLoad, weeklyLoad, overdue, pending, activeProjects int64
+	if err := inspections().Where("status IN ? AND scheduled_for >= ? AND scheduled_for < ?", openInspectionStatuses, today, today.AddDate(0, 0, 1)).
+		Count(&dailyLoad).Error; err != nil {
+		return fmt.Errorf("failed to count daily load: %v", err)
+	}
+	if err := inspections().Where("status IN ? AND scheduled_for >= ? AND scheduled_for < ?", openInspectionStatuses, weekStart, weekStart.AddDate(0, 0, 7)).
+		Count(&weeklyLoad).Error; err != nil {
+		return fmt.Errorf("failed to count weekly load: %v", err)
+	}
+	if err := inspections().Where("status NOT IN ? AND due_date < ?", closedInspectionStatuses, now).Count(&overdue).Error; err != nil {
+		return fmt.Errorf("failed to count overdue inspections: %v", err)
+	}
+	if err := assignments().Where("status = ?", "pending").Count(&pending).Error; err != nil {
+		return fmt.Errorf("failed to count pending assignments: %v", err)
+	}
+	if err := assignments().Where("status IN ? AND project_id IS NOT NULL", openAssignmentStatuses).
+		Distinct("project_id").Count(&activeProjects).Error; err != nil {
+		return fmt.Errorf("failed to count active projects: %v", err)
+	}
+
+	workload.CurrentDailyLoad = int(dailyLoad)
+	workload.CurrentWeeklyLoad = int(weeklyLoad)
+	workload.OverdueInspections = int(overdue)
+	workload.PendingAssignments = int(pending)
+	workload.ActiveProjects = int(activeProjects)
+	workload.LastUpdated = now
+
+	if err := tx.Model(workload).Updates(map[string]interface{}{
+		"current_daily_load":  workload.CurrentDailyLoad,
+		"current_weekly_load": workload.CurrentWeeklyLoad,
+		"overdue_inspections": workload.OverdueInspections,
+		"pending_assignments": workload.PendingAssignments,
+		"active_projects":     workload.ActiveProjects,
+		"last_updated":        now,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to update workload load: %v", err)
+	}
+	return nil
+}
+
+// =====================================================
+// RECONCILIATION
+// =====================================================
+
+// recountPerformance works the inspector's running totals out from scratch
+func (s *WorkloadMetricsService) recountPerformance(tx *gorm.DB, organizationID, inspectorID string) (performanceTotals, error) {
+	var totals performanceTotals
+
+	var completed []models.Inspection
+	if err := tx.Select("status", "due_date", "started_at", "completed_at").
+		Where("organization_id = ? AND inspector_id = ? AND status IN ? AND completed_at IS NOT NULL", organizationID, inspectorID, finishedInspectionStatuses).
+		Find(&completed).Error; err != nil {
+		return totals, fmt.Errorf("failed to get completed inspections: %v", err)
+	}
+	for i := range completed {
+		totals = totals.add(inspectionPerformance(&completed[i]), 1)
+	}
+
+	// Reviews count for the reviewed inspection's inspector, or for the assignee when a
+	// whole assignment was reviewed
+	var scores []float64
+	if err := tx.Model(&models.InspectionReview{}).
+		Where("organization_id = ? AND completed_at IS NOT NULL AND quality_score IS NOT NULL", organizationID).
+		Where("inspection_id IN (?) OR (inspection_id IS NULL AND assignment_id IN (?))",
+			tx.Model(&models.Inspection{}).Select("id").Where("organization_id = ? AND inspector_id = ?", organizationID, inspectorID),
+			tx.Model(&models.InspectionAssignment{}).Select("id").Where("organization_id = ? AND assigned_to = ?", organizationID, inspectorID)).
+		Pluck("quality_score", &scores).Error; err != nil {
+		return totals, fmt.Errorf("failed to get review scores: %v", err)
+	}
+	for i := range scores {
+		totals = totals.add(reviewPerformance(&scores[i]), 1)
+	}
+
+	return totals, nil
+}
+
+// Reconcile recounts every workload as of now, correcting any running totals that
+// drifted, and records the day's snapshot in the history. Inspectors with inspections but
+// no workload yet get one. One inspector failing doesn't stop the others.
+func (s *WorkloadMetricsService) Reconcile(ctx context.Context, now time.Time) (*models.WorkloadReconcileResult, error) {
+	return s.reconcile(ctx, "", now)
+}
+
+// ReconcileOrganization is Reconcile for one organization's workloads
+func (s *WorkloadMetricsService) ReconcileOrganization(ctx context.Context, organizationID string, now time.Time) (*models.WorkloadReconcileResult, error) {
+	return s.reconcile(ctx, organizationID, now)
+}
+
+// reconcile reconciles the organization's workloads, or everyone's when organizationID
+// is empty
+func (s *WorkloadMetricsService) reconcile(ctx context.Context, organizationID string, now time.Time) (*models.WorkloadReconcileResult, error) {
+	db := database.Conn(ctx, s.db)
+	scoped := func(query *gorm.DB) *gorm.DB {
+		if organizationID != "" {
+			query = query.Where("organization_id = ?", organizationID)
+		}
+		return query
+	}
+
+	type inspectorKey struct {
+		OrganizationID string
+		InspectorID    string
+	}
+	var fromWorkloads, fromInspections []inspectorKey
+	if err := scoped(db.Model(&models.InspectorWorkload{})).Select("organization_id", "inspector_id").Scan(&fromWorkloads).Error; err != nil {
+		return nil, fmt.Errorf("failed to get workloads: %v", err)
+	}
+	if err := scoped(db.Model(&models.Inspection{})).Distinct("organization_id", "inspector_id").
+		Where("inspector_id IS NOT NULL AND inspector_id <> ''").Scan(&fromInspections).Error; err != nil {
+		return nil, fmt.Errorf("failed to get inspectors: %v", err)
+	}
+
+	seen := make(map[inspectorKey]bool)
+	var keys []inspectorKey
+	for _, key := range append(fromWorkloads, fromInspections...) {
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	result := &models.WorkloadReconcileResult{}
+	var errs []error
+	for _, key := range keys {
+		corrected, err := s.reconcileInspector(db, key.OrganizationID, key.InspectorID, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("inspector %s: %w", key.InspectorID, err))
+			continue
+		}
+		result.Inspectors++
+		result.Snapshots++
+		if corrected {
+			result.Corrected++
+		}
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// reconcileInspector recounts one workload and snapshots it, reporting whether its
+// running totals had drifted
+func (s *WorkloadMetricsService) reconcileInspector(db *gorm.DB, organizationID, inspectorID string, now time.Time) (bool, error) {
+	corrected := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		workload, err := s.ensureWorkload(tx, organizationID, inspectorID)
+		if err != nil {
+			return err
+		}
+
+		totals, err := s.recountPerformance(tx, organizationID, inspectorID)
+		if err != nil {
+			return err
+		}
+		corrected = totals != workloadTotals(workload)
+
+		updates := performanceMetrics(totals)
+		updates["due_completions"] = totals.DueCompletions
+		updates["on_time_completions"] = totals.OnTimeCompletions
+		updates["timed_inspections"] = totals.TimedInspections
+		updates["inspection_minutes"] = totals.InspectionMinutes
+		updates["scored_reviews"] = totals.ScoredReviews
+		updates["quality_score_total"] = totals.QualityScoreTotal
+		if err := tx.Model(workload).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update workload metrics: %v", err)
+		}
+		if err := s.refreshLoad(tx, workload, now); err != nil {
+			return err
+		}
+
+		return s.snapshot(tx, workload, now)
+	})
+	return corrected, err
+}
+
+// snapshot records the workload as the day's entry in the history, replacing an earlier
+// snapshot of the same day
+func (s *WorkloadMetricsService) snapshot(tx *gorm.DB, workload *models.InspectorWorkload, now time.Time) error {
+	utilization := 0.0
+	if workload.MaxWeeklyInspections > 0 {
+		utilization = float64(workload.CurrentWeeklyLoad) * 100 / float64(workload.MaxWeeklyInspections)
+	}
+
+	entry := models.InspectorWorkloadHistory{
+		OrganizationID:        workload.OrganizationID,
+		InspectorID:           workload.InspectorID,
+		Date:                  civilDate(now),
+		DailyLoad:             workload.CurrentDailyLoad,
+		WeeklyLoad:            workload.CurrentWeeklyLoad,
+		MaxDailyInspections:   workload.MaxDailyInspections,
+		MaxWeeklyInspections:  workload.MaxWeeklyInspections,
+		Utilization:           utilization,
+		ActiveProjects:        workload.ActiveProjects,
+		PendingAssignments:    workload.PendingAssignments,
+		OverdueInspections:    workload.OverdueInspections,
+		CompletionRate:        workload.CompletionRate,
+		AverageInspectionTime: workload.AverageInspectionTime,
+		QualityScore:          workload.QualityScore,
+	}
+	if err := tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "organization_id"}, {Name: "inspector_id"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"daily_load", "weekly_load", "max_daily_inspections", "max_weekly_inspections", "utilization", "active_projects",
+			"pending_assignments", "overdue_inspections", "completion_rate", "average_inspection_time", "quality_score",
+		}),
+	}).Create(&entry).Error; err != nil {
+		return fmt.Errorf("failed to record workload history: %v", err)
+	}
+	return nil
+}
+
+// StartNightlyReconciliation runs Reconcile every day at the given time after local
+// midnight until ctx is done
+func (s *WorkloadMetricsService) StartNightlyReconciliation(ctx context.Context, at time.Duration) {
+	go func() {
+		for {
+			timer := time.NewTimer(time.Until(nextDailyRun(time.Now(), at)))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+
+			result, err := s.Reconcile(ctx, time.Now())
+			if err != nil {
+				log.Printf("Workload reconciliation failed: %v", err)
+			}
+			if result != nil {
+				log.Printf("Workload reconciliation recalculated %d workloads, %d had drifted", result.Inspectors, result.Corrected)
+			}
+		}
+	}()
+}
+
+// nextDailyRun returns the first time after now that falls at the given offset from a
+// local midnight
+func nextDailyRun(now time.Time, at time.Duration) time.Time {
+	run := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).Add(at)
+	if !run.After(now) {
+		run = time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location()).Add(at)
+	}
+	return run
+}
+
+// =====================================================
+// HISTORY
+// =====================================================
+
+// GetHistory returns the inspector's daily workload snapshots from one day to another,
+// inclusive, oldest first
+func (s *WorkloadMetricsService) GetHistory(ctx context.Context, organizationID, inspectorID string, from, to time.Time) ([]models.InspectorWorkloadHistory, error) {
+	from, to = civilDate(from), civilDate(to)
+	if to.Before(from) {
+		return nil, fmt.Errorf("%w: to is before from", ErrInvalidWorkloadRange)
+	}
+	if to.Sub(from) > maxWorkloadHistoryDays*24*time.Hour {
+		return nil, fmt.Errorf("%w: at most %d days at a time", ErrInvalidWorkloadRange, maxWorkloadHistoryDays)
+	}
+
+	history := []models.InspectorWorkloadHistory{}
+	if err := database.Conn(ctx, s.db).
+		Where("organization_id = ? AND inspector_id = ? AND date >= ? AND date <= ?", organizationID, inspectorID, from, to).
+		Order("date").Find(&history).Error; err != nil {
+		return nil, fmt.Errorf("failed to get workload history: %v", err)
+	}
+	return history, nil
+}
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"context"
+	"resource-mgmt/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// workloadMetricsTestFixture records inspection changes through the incremental metrics hook
+type workloadMetricsTestFixture struct {
+	db       *gorm.DB
+	service  *WorkloadMetricsService
+	workflow *WorkflowService
+	now      time.Time
+}
+
+func newWorkloadMetricsTestFixture(t *testing.T) *workloadMetricsTestFixture {
+	db := setupServiceTestDB(t, &models.Inspection{}, &models.InspectionAssignment{}, &models.InspectionReview{}, &models.InspectorWorkload{},
+		&models.InspectorWorkloadHistory{}, &models.OrganizationMember{}, &models.Notification{})
+	return &workloadMetricsTestFixture{db: db, service: NewWorkloadMetricsService(db), workflow: NewWorkflowService(db, NewNotificationService()), now: time.Now()}
+}
+
+func (f *workloadMetricsTestFixture) workload(t *testing.T, inspectorID string) models.InspectorWorkload {
+	var w models.InspectorWorkload
+	require.NoError(t, f.db.Where("organization_id = ? AND inspector_id = ?", "org-a", inspectorID).First(&w).Error)
+	return w
+}
+
+func (f *workloadMetricsTestFixture) create(t *testing.T, inspection models.Inspection) models.Inspection {
+	inspection.OrganizationID, inspection.TemplateID, inspection.SiteID = "org-a", uuid.New(), uuid.NewString()
+	require.NoError(t, f.db.Create(&inspection).Error)
+	require.NoError(t, f.service.InspectionChanged(context.Background(), nil, &inspection))
+	return inspection
+}
+
+func (f *workloadMetricsTestFixture) change(t *testing.T, before models.Inspection, update func(after *models.Inspection)) models.Inspection {
+	after := before
+	update(&after)
+	require.NoError(t, f.db.Save(&after).Error)
+	require.NoError(t, f.service.InspectionChanged(context.Background(), &before, &after))
+	return after
+}
+
+func (f *workloadMetricsTestFixture) complete(t *testing.T, inspection models.Inspection, took time.Duration) models.Inspection {
+	return f.change(t, inspection, func(after *models.Inspection) {
+		started, completed := f.now.Add(-took), f.now
+		after.Status, after.StartedAt, after.CompletedAt = "completed", &started, &completed
+	})
+}
+
+// scheduleToday gives insp-1 two inspections scheduled today, one due tomorrow and one
+// already overdue
+func (f *workloadMetricsTestFixture) scheduleToday(t *testing.T) (onTime, late models.Inspection) {
+	tomorrow, yesterday := f.now.AddDate(0, 0, 1), f.now.AddDate(0, 0, -1)
+	onTime = f.create(t, models.Inspection{InspectorID: "insp-1", Status: "assigned", ScheduledFor: &f.now, DueDate: &tomorrow})
+	late = f.create(t, models.Inspection{InspectorID: "insp-1", Status: "assigned", ScheduledFor: &f.now, DueDate: &yesterday})
+	return onTime, late
+}
+
+// completeToday completes both of today's inspections, taking 90 and 60 minutes
+func (f *workloadMetricsTestFixture) completeToday(t *testing.T) (onTime, late models.Inspection) {
+	onTime, late = f.scheduleToday(t)
+	return f.complete(t, onTime, 90*time.Minute), f.complete(t, late, 60*time.Minute)
+}
+
+// moveToInsp2 hands the late inspection to insp-2 after it was completed
+func (f *workloadMetricsTestFixture) moveToInsp2(t *testing.T, late models.Inspection) {
+	f.change(t, late, func(after *models.Inspection) { after.InspectorID = "insp-2" })
+}
+
+// assignReview asks super-1 for a quality review of inspection
+func (f *workloadMetricsTestFixture) assignReview(t *testing.T, inspection models.Inspection) *models.InspectionReview {
+	inspectionID := inspection.ID.String()
+	review := &models.InspectionReview{OrganizationID: "org-a", InspectionID: &inspectionID, ReviewType: "quality", ReviewerID: "super-1", AssignedBy: "super-1", AssignedAt: f.now}
+	require.NoError(t, f.db.Create(review).Error)
+	return review
+}
+
+// review has super-1 approve inspection with score
+func (f *workloadMetricsTestFixture) review(t *testing.T, inspection models.Inspection, score float64) {
+	_, err := f.workflow.SubmitInspectionReview(context.Background(), "org-a", f.assignReview(t, inspection).ID, "super-1", map[string]interface{}{"decision": "approved", "quality_score": score})
+	require.NoError(t, err)
+}
+
+func TestWorkloadMetricsService_NewWorkCountsTowardsLoad(t *testing.T) {
+	f := newWorkloadMetricsTestFixture(t)
+	f.scheduleToday(t)
+
+	// The workload is created on demand with defaults
+	w := f.workload(t, "insp-1")
+	assert.Equal(t, 2, w.CurrentDailyLoad)
+	assert.Equal(t, 1, w.OverdueInspections)
+	assert.Equal(t, 8, w.MaxDailyInspections)
+	assert.Equal(t, defaultAverageInspectionTime, w.AverageInspectionTime)
+}
+
+func TestWorkloadMetricsService_CompletionsFeedRateAndAverage(t *testing.T) {
+	f := newWorkloadMetricsTestFixture(t)
+	f.completeToday(t)
+
+	w := f.workload(t, "insp-1")
+	assert.Equal(t, 0, w.CurrentDailyLoad)
+	assert.Equal(t, 0, w.OverdueInspections)
+	assert.Equal(t, 50.0, w.CompletionRate)
+	assert.Equal(t, 75, w.AverageInspectionTime)
+}
+
+func TestWorkloadMetricsService_ReassignmentMovesTotals(t *testing.T) {
+	f := newWorkloadMetricsTestFixture(t)
+	_, late := f.completeToday(t)
+
+	f.moveToInsp2(t, late)
+	w := f.workload(t, "insp-1")
+	assert.Equal(t, 100.0, w.CompletionRate)
+	assert.Equal(t, 90, w.AverageInspectionTime)
+	assert.Equal(t, 0.0, f.workload(t, "insp-2").CompletionRate)
+}
+
+func TestWorkloadMetricsService_SubmittedReviewScoresInspector(t *testing.T) {
+	tests := []struct {
+		name      string
+		userID    string
+		score     float64
+		wantErr   bool
+		wantScore float64
+	}{
+		{"reviewer submits", "super-1", 80, false, 80},
+		{"score out of range", "super-1", 120, true, 0},
+		{"someone other than the reviewer", "insp-1", 80, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newWorkloadMetricsTestFixture(t)
+			onTime, _ := f.completeToday(t)
+			review := f.assignReview(t, onTime)
+
+			submitted, err := f.workflow.SubmitInspectionReview(context.Background(), "org-a", review.ID, tt.userID, map[string]interface{}{"decision": "approved", "quality_score": tt.score})
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, "approved", submitted.Status)
+			}
+			assert.Equal(t, tt.wantScore, f.workload(t, "insp-1").QualityScore)
+		})
+	}
+}
+
+func TestWorkloadMetricsService_ReconcileCorrectsDrift(t *testing.T) {
+	f := newWorkloadMetricsTestFixture(t)
+	onTime, late := f.completeToday(t)
+	f.moveToInsp2(t, late)
+	f.review(t, onTime, 80)
+
+	require.NoError(t, f.db.Model(&models.InspectorWorkload{}).Where("inspector_id = ?", "insp-1").
+		Updates(map[string]interface{}{"on_time_completions": 7, "completion_rate": 700, "current_daily_load": 3}).Error)
+	result, err := f.service.Reconcile(context.Background(), f.now)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Inspectors)
+	assert.Equal(t, 1, result.Corrected)
+
+	w := f.workload(t, "insp-1")
+	assert.Equal(t, 1, w.OnTimeCompletions)
+	assert.Equal(t, 100.0, w.CompletionRate)
+	assert.Equal(t, 0, w.CurrentDailyLoad)
+	assert.Equal(t, 80.0, w.QualityScore)
+	assert.Equal(t, 0.0, f.workload(t, "insp-2").CompletionRate, "the late inspection was due before it was completed")
+}
+
+func TestWorkloadMetricsService_GetHistory(t *testing.T) {
+	tests := []struct {
+		name         string
+		organization string
+		fromDays     int
+		toDays       int
+		wantLen      int
+		wantErr      error
+	}{
+		{"one snapshot per day", "org-a", -7, 0, 1, nil},
+		{"other organization", "org-b", -7, 0, 0, nil},
+		{"range ends before it starts", "org-a", 0, -1, 0, ErrInvalidWorkloadRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newWorkloadMetricsTestFixture(t)
+			ctx := context.Background()
+			_, late := f.completeToday(t)
+			f.moveToInsp2(t, late)
+
+			// Reconciling twice on the same day keeps one snapshot
+			for i := 0; i < 2; i++ {
+				_, err := f.service.Reconcile(ctx, f.now)
+				require.NoError(t, err)
+			}
+
+			history, err := f.service.GetHistory(ctx, tt.organization, "insp-1", f.now.AddDate(0, 0, tt.fromDays), f.now.AddDate(0, 0, tt.toDays))
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, history, tt.wantLen)
+			if tt.wantLen > 0 {
+				assert.Equal(t, 100.0, history[0].CompletionRate)
+				assert.Equal(t, 90, history[0].AverageInspectionTime)
+			}
+		})
+	}
+}
+
+func TestNextDailyRun(t *testing.T) {
+	at := 15 * time.Minute
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{"before today's run", time.Date(2026, 3, 4, 0, 10, 0, 0, time.UTC), time.Date(2026, 3, 4, 0, 15, 0, 0, time.UTC)},
+		{"at today's run", time.Date(2026, 3, 4, 0, 15, 0, 0, time.UTC), time.Date(2026, 3, 5, 0, 15, 0, 0, time.UTC)},
+		{"across a month end", time.Date(2026, 2, 28, 23, 0, 0, 0, time.UTC), time.Date(2026, 3, 1, 0, 15, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, nextDailyRun(tt.now, at))
+		})
+	}
+}
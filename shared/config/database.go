@@ -19,6 +19,13 @@ type OAuthConfig struct {
 	MicrosoftClientID     string
 	MicrosoftClientSecret string
 	MicrosoftRedirectURL  string
+
+	// Generic OpenID Connect provider (Okta, Keycloak, ...) resolved via discovery
+	OIDCProviderName string
+	OIDCIssuerURL    string
+	OIDCClientID     string
+	OIDCClientSecret string
+	OIDCRedirectURL  string
 }
 
 var DB *gorm.DB
@@ -133,4 +140,126 @@ var (
 	MicrosoftClientID     = os.Getenv("MICROSOFT_CLIENT_ID")
 	MicrosoftClientSecret = os.Getenv("MICROSOFT_CLIENT_SECRET")
 	MicrosoftRedirectURL  = os.Getenv("MICROSOFT_REDIRECT_URL")
+
+	OIDCProviderName = getEnv("OIDC_PROVIDER_NAME", "oidc")
+	OIDCIssuerURL    = os.Getenv("OIDC_ISSUER_URL")
+	OIDCClientID     = os.Getenv("OIDC_CLIENT_ID")
+	OIDCClientSecret = os.Getenv("OIDC_CLIENT_SECRET")
+	OIDCRedirectURL  = os.Getenv("OIDC_REDIRECT_URL")
+)
+
+// GetOAuthConfig returns the external authentication provider configuration
+func GetOAuthConfig() *OAuthConfig {
+	return &OAuthConfig{
+		GoogleClientID:        GoogleClientID,
+		GoogleClientSecret:    GoogleClientSecret,
+		GoogleRedirectURL:     GoogleRedirectURL,
+		MicrosoftClientID:     MicrosoftClientID,
+		MicrosoftClientSecret: MicrosoftClientSecret,
+		MicrosoftRedirectURL:  MicrosoftRedirectURL,
+		OIDCProviderName:      OIDCProviderName,
+		OIDCIssuerURL:         OIDCIssuerURL,
+		OIDCClientID:          OIDCClientID,
+		OIDCClientSecret:      OIDCClientSecret,
+		OIDCRedirectURL:       OIDCRedirectURL,
+	}
+}
+
+// Login throttling and rate limiting
+var (
+	// LoginLockoutStore selects where lockout state lives: "memory" (per instance) or "db" (shared)
+	LoginLockoutStore = getEnv("LOGIN_LOCKOUT_STORE", "memory")
+
+	// Rate limit buckets as "<requests>/<window>", e.g. "20/1m"
+	RateLimitAuth    = getEnv("RATE_LIMIT_AUTH", "20/1m")
+	RateLimitUploads = getEnv("RATE_LIMIT_UPLOADS", "60/1m")
+	RateLimitExports = getEnv("RATE_LIMIT_EXPORTS", "10/1m")
+)
+
+// QR scan tags
+var (
+	// ScanTagSecret signs printed tag codes. It falls back to JWT_SECRET, but a dedicated
+	// secret lets the JWT secret rotate without invalidating tags already on the wall.
+	ScanTagSecret = os.Getenv("SCAN_TAG_SECRET")
+
+	// ScanTagBaseURL, when set, makes tags encode "<base>/<code>" so phone cameras open the app
+	ScanTagBaseURL = os.Getenv("SCAN_TAG_BASE_URL")
+)
+
+// Audit trail
+var (
+	// AuditChainSecret keys the audit log hash chain. It falls back to JWT_SECRET; either
+	// way it must stay fixed once entries exist, or every chain fails verification.
+	AuditChainSecret = os.Getenv("AUDIT_CHAIN_SECRET")
+)
+
+// Site geocoding
+var (
+	// GeocoderProvider selects how site addresses become coordinates: "none", "nominatim"
+	// (any Nominatim-compatible HTTP API) or "static" (a local lookup file, for tests and
+	// air-gapped installs)
+	GeocoderProvider = getEnv("GEOCODER_PROVIDER", "none")
+
+	// GeocoderURL is the Nominatim-compatible API base URL
+	GeocoderURL = getEnv("GEOCODER_URL", "https://nominatim.openstreetmap.org")
+
+	// GeocoderUserAgent and GeocoderEmail identify us to the provider, as its usage policy requires
+	GeocoderUserAgent = getEnv("GEOCODER_USER_AGENT", "resource-mgmt")
+	GeocoderEmail     = os.Getenv("GEOCODER_EMAIL")
+
+	// GeocoderMinInterval spaces out requests; the public Nominatim allows one per second
+	GeocoderMinInterval = getEnv("GEOCODER_MIN_INTERVAL", "1s")
+
+	// GeocoderStaticFile is the JSON or CSV lookup file for the static provider
+	GeocoderStaticFile = os.Getenv("GEOCODER_STATIC_FILE")
+)
+
+// Site documents
+var (
+	// SiteDocumentReminderDays is how many days before expiry site managers are reminded,
+	// for documents that don't set their own
+	SiteDocumentReminderDays = getEnv("SITE_DOCUMENT_REMINDER_DAYS", "30")
+
+	// SiteDocumentCheckInterval is how often the expiry check runs; "0" turns it off
+	SiteDocumentCheckInterval = getEnv("SITE_DOCUMENT_CHECK_INTERVAL", "1h")
+)
+
+// Inspector qualifications
+var (
+	// CertificationReminderDays is how many days before expiry holders and supervisors are
+	// warned, for certification types that don't set their own
+	CertificationReminderDays = getEnv("CERTIFICATION_REMINDER_DAYS", "30")
+
+	// CertificationCheckInterval is how often the certification expiry check runs; "0" turns it off
+	CertificationCheckInterval = getEnv("CERTIFICATION_CHECK_INTERVAL", "1h")
+)
+
+// Site merges
+var (
+	// SiteMergeUndoWindow is how long a site merge can be undone
+	SiteMergeUndoWindow = getEnv("SITE_MERGE_UNDO_WINDOW", "720h")
+)
+
+// Inspector workloads
+var (
+	// WorkloadReconcileTime is the local time of day (HH:MM) the nightly workload
+	// reconciliation runs; "off" turns it off
+	WorkloadReconcileTime = getEnv("WORKLOAD_RECONCILE_TIME", "00:15")
+)
+
+// Open pool
+var (
+	// OpenPoolEscalationWindow is how long before its due date an unclaimed open pool
+	// item is escalated to the project manager
+	OpenPoolEscalationWindow = getEnv("OPEN_POOL_ESCALATION_WINDOW", "48h")
+
+	// OpenPoolCheckInterval is how often unclaimed items are checked; "0" turns it off
+	OpenPoolCheckInterval = getEnv("OPEN_POOL_CHECK_INTERVAL", "1h")
+)
+
+// SLA policies
+var (
+	// SLACheckInterval is how often SLA clocks are checked for escalations and breaches;
+	// "0" turns it off
+	SLACheckInterval = getEnv("SLA_CHECK_INTERVAL", "5m")
 )
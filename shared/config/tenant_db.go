@@ -8,6 +8,12 @@ import (
 	"gorm.io/gorm"
 )
 
+// TenantRole is the non-bypass database role used inside tenant transactions.
+// Row-level security policies only expose rows of app.current_organization_id to it.
+const TenantRole = "app_tenant"
+
+type txContextKey struct{}
+
 // TenantDB wraps GORM DB with tenant context
 type TenantDB struct {
 	*gorm.DB
@@ -18,26 +24,38 @@ func NewTenantDB(db *gorm.DB) *TenantDB {
 	return &TenantDB{DB: db}
 }
 
-// WithContext returns a database instance with tenant context
+// WithContext returns the request's tenant transaction if one is active,
+// otherwise the plain connection pool bound to ctx.
+//
+// Tenant settings are never applied at session level here: a pooled connection
+// would carry them into whichever request uses it next.
 func (tdb *TenantDB) WithContext(ctx context.Context) *gorm.DB {
-	db := tdb.DB.WithContext(ctx)
+	return Conn(ctx, tdb.DB)
+}
 
-	// Set organization_id in session for RLS
-	if organizationID, err := tenant.GetOrganizationID(ctx); err == nil {
-		db = db.Exec("SELECT set_config('app.current_organization_id', ?, false)", organizationID)
+// Transaction executes a function within a database transaction with tenant context.
+// When the request already runs in a tenant transaction, fn runs in a nested savepoint.
+func (tdb *TenantDB) Transaction(ctx context.Context, fn func(*gorm.DB) error) error {
+	if tx := TxFromContext(ctx); tx != nil {
+		return tx.WithContext(ctx).Transaction(fn)
 	}
 
-	return db
-}
+	organizationID, err := tenant.GetOrganizationID(ctx)
+	if err != nil {
+		return fmt.Errorf("tenant validation failed: %w", err)
+	}
 
-// WithTenant returns a database instance with specific tenant context
-func (tdb *TenantDB) WithTenant(organizationID string) *gorm.DB {
-	return tdb.DB.Exec("SELECT set_config('app.current_organization_id', ?, false)", organizationID)
+	return tdb.TransactionForTenant(ctx, organizationID, fn)
 }
 
-// Transaction executes a function within a database transaction with tenant context
-func (tdb *TenantDB) Transaction(ctx context.Context, fn func(*gorm.DB) error) error {
-	return tdb.WithContext(ctx).Transaction(fn)
+// TransactionForTenant runs fn in a transaction restricted to a specific organization
+func (tdb *TenantDB) TransactionForTenant(ctx context.Context, organizationID string, fn func(*gorm.DB) error) error {
+	return tdb.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := SetOrganizationContext(tx, organizationID).Error; err != nil {
+			return fmt.Errorf("failed to set tenant context: %w", err)
+		}
+		return fn(tx)
+	})
 }
 
 // Scoped returns a scoped database instance with organization filter
@@ -56,14 +74,44 @@ func (tdb *TenantDB) UnscopedWithContext(ctx context.Context) *gorm.DB {
 	return tdb.DB.WithContext(ctx)
 }
 
-// SetOrganizationContext sets the organization context for RLS
-func SetOrganizationContext(db *gorm.DB, organizationID string) *gorm.DB {
-	return db.Exec("SELECT set_config('app.current_organization_id', ?, false)", organizationID)
+// SetOrganizationContext switches the transaction to the tenant role and sets the
+// organization for RLS. Both settings are transaction-local, so tx must be a transaction.
+// A failed role switch is returned as is, so callers never go on as the owning role.
+func SetOrganizationContext(tx *gorm.DB, organizationID string) *gorm.DB {
+	if result := tx.Exec("SET LOCAL ROLE " + TenantRole); result.Error != nil {
+		return result
+	}
+	return tx.Exec("SELECT set_config('app.current_organization_id', ?, true)", organizationID)
 }
 
-// ClearOrganizationContext clears the organization context
-func ClearOrganizationContext(db *gorm.DB) *gorm.DB {
-	return db.Exec("SELECT set_config('app.current_organization_id', '', false)")
+// ClearOrganizationContext clears the organization context of the current transaction
+func ClearOrganizationContext(tx *gorm.DB) *gorm.DB {
+	if result := tx.Exec("RESET ROLE"); result.Error != nil {
+		return result
+	}
+	return tx.Exec("SELECT set_config('app.current_organization_id', '', true)")
+}
+
+// ContextWithTx attaches a tenant transaction to the request context
+func ContextWithTx(ctx context.Context, tx *gorm.DB) context.Context {
+	return context.WithValue(ctx, txContextKey{}, tx)
+}
+
+// TxFromContext returns the tenant transaction attached to ctx, if any
+func TxFromContext(ctx context.Context) *gorm.DB {
+	if ctx == nil {
+		return nil
+	}
+	tx, _ := ctx.Value(txContextKey{}).(*gorm.DB)
+	return tx
+}
+
+// Conn returns the tenant transaction attached to ctx, or db bound to ctx
+func Conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx := TxFromContext(ctx); tx != nil {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
 }
 
 // ValidateTenantAccess ensures the query includes organization_id filter
@@ -73,4 +121,4 @@ func ValidateTenantAccess(ctx context.Context) error {
 		return fmt.Errorf("tenant validation failed: %w", err)
 	}
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,215 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TenantIsolationTestSuite proves row-level security keeps organizations apart,
+// even for raw SQL that forgets the organization_id filter
+type TenantIsolationTestSuite struct {
+	suite.Suite
+	db       *gorm.DB
+	tenantDB *TenantDB
+	ctx      context.Context
+	testDB   string
+	orgA     string
+	orgB     string
+}
+
+func testPostgresDSN(dbName string) string {
+	if dsn := os.Getenv("TEST_DATABASE_DSN"); dsn != "" {
+		return fmt.Sprintf("%s dbname=%s", dsn, dbName)
+	}
+	return fmt.Sprintf("host=localhost user=postgres password=password dbname=%s port=5432 sslmode=disable", dbName)
+}
+
+// SetupSuite creates a throwaway database with minimal tenant tables and applies the RLS migration
+func (suite *TenantIsolationTestSuite) SetupSuite() {
+	suite.ctx = context.Background()
+	suite.testDB = "resource_mgmt_rls_test"
+
+	mainDB, err := gorm.Open(postgres.Open(testPostgresDSN("postgres")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		suite.T().Skip("PostgreSQL not available for testing")
+		return
+	}
+	rawDB, _ := mainDB.DB()
+	if err := rawDB.Ping(); err != nil {
+		suite.T().Skip("PostgreSQL not available for testing")
+		return
+	}
+	rawDB.Exec(fmt.Sprintf("DROP DATABASE IF EXISTS %s", suite.testDB))
+	if _, err := rawDB.Exec(fmt.Sprintf("CREATE DATABASE %s", suite.testDB)); err != nil {
+		suite.T().Skip("Cannot create test database")
+		return
+	}
+	rawDB.Close()
+
+	suite.db, err = gorm.Open(postgres.New(postgres.Config{
+		DSN:                  testPostgresDSN(suite.testDB),
+		PreferSimpleProtocol: true, // the migration contains multiple statements
+	}), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	suite.Require().NoError(err)
+
+	// A single pooled connection makes any leaked session setting visible to the next query
+	sqlDB, _ := suite.db.DB()
+	sqlDB.SetMaxOpenConns(1)
+
+	schema := `
+		CREATE TABLE organizations (id UUID PRIMARY KEY, name TEXT);
+		CREATE TABLE global_users (id UUID PRIMARY KEY, email TEXT);
+		CREATE TABLE organization_members (id UUID PRIMARY KEY DEFAULT gen_random_uuid(), user_id UUID, organization_id UUID);
+		CREATE TABLE templates (id UUID PRIMARY KEY DEFAULT gen_random_uuid(), organization_id UUID NOT NULL, name TEXT);
+		CREATE TABLE sites (id UUID PRIMARY KEY DEFAULT gen_random_uuid(), organization_id UUID NOT NULL, name TEXT);
+		CREATE TABLE inspections (id UUID PRIMARY KEY DEFAULT gen_random_uuid(), organization_id UUID NOT NULL, notes TEXT);
+		CREATE TABLE inspection_data (id SERIAL PRIMARY KEY, inspection_id UUID NOT NULL REFERENCES inspections(id), value TEXT);
+		CREATE TABLE attachments (id UUID PRIMARY KEY DEFAULT gen_random_uuid(), organization_id UUID NOT NULL);
+		CREATE TABLE notifications (id UUID PRIMARY KEY DEFAULT gen_random_uuid(), organization_id UUID NOT NULL);
+		CREATE TABLE inspection_projects (id UUID PRIMARY KEY DEFAULT gen_random_uuid(), organization_id UUID NOT NULL);
+		CREATE TABLE inspection_assignments (id UUID PRIMARY KEY DEFAULT gen_random_uuid(), organization_id UUID NOT NULL);
+		CREATE TABLE inspector_workloads (id UUID PRIMARY KEY DEFAULT gen_random_uuid(), organization_id UUID NOT NULL);
+	`
+	suite.Require().NoError(suite.db.Exec(schema).Error)
+	suite.Require().NoError(suite.db.Exec(readMigrationUp(suite.T(), "033_enable_row_level_security.sql")).Error)
+
+	suite.tenantDB = NewTenantDB(suite.db)
+	suite.orgA = uuid.NewString()
+	suite.orgB = uuid.NewString()
+
+	for _, org := range []string{suite.orgA, suite.orgB} {
+		suite.Require().NoError(suite.db.Exec("INSERT INTO organizations (id, name) VALUES (?, ?)", org, "org "+org).Error)
+		for _, table := range []string{"templates", "sites", "attachments", "notifications", "inspection_projects", "inspection_assignments", "inspector_workloads"} {
+			suite.Require().NoError(suite.db.Exec(fmt.Sprintf("INSERT INTO %s (organization_id) VALUES (?)", table), org).Error)
+		}
+		inspectionID := uuid.NewString()
+		suite.Require().NoError(suite.db.Exec("INSERT INTO inspections (id, organization_id, notes) VALUES (?, ?, ?)", inspectionID, org, "secret of "+org).Error)
+		suite.Require().NoError(suite.db.Exec("INSERT INTO inspection_data (inspection_id, value) VALUES (?, ?)", inspectionID, "data of "+org).Error)
+	}
+}
+
+// TearDownSuite drops the test database
+func (suite *TenantIsolationTestSuite) TearDownSuite() {
+	if suite.db != nil {
+		sqlDB, _ := suite.db.DB()
+		sqlDB.Close()
+	}
+
+	mainDB, err := gorm.Open(postgres.Open(testPostgresDSN("postgres")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err == nil {
+		rawDB, _ := mainDB.DB()
+		rawDB.Exec(fmt.Sprintf("DROP DATABASE IF EXISTS %s", suite.testDB))
+		rawDB.Close()
+	}
+}
+
+// readMigrationUp returns the goose Up section of a migration file
+func readMigrationUp(t *testing.T, name string) string {
+	_, file, _, _ := runtime.Caller(0)
+	if resolved, err := filepath.EvalSymlinks(file); err == nil {
+		file = resolved
+	}
+	path := filepath.Join(filepath.Dir(file), "..", "..", "database", "migrations", name)
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read migration %s: %v", path, err)
+	}
+
+	up := strings.SplitN(string(content), "-- +goose Down", 2)[0]
+	up = strings.ReplaceAll(up, "-- +goose StatementBegin", "")
+	return strings.ReplaceAll(up, "-- +goose StatementEnd", "")
+}
+
+func (suite *TenantIsolationTestSuite) TestRawQueriesOnlySeeOwnOrganization() {
+	tables := []string{"templates", "sites", "inspections", "inspection_data", "attachments", "notifications", "inspection_projects", "inspection_assignments", "inspector_workloads"}
+
+	err := suite.tenantDB.TransactionForTenant(suite.ctx, suite.orgA, func(tx *gorm.DB) error {
+		for _, table := range tables {
+			var count int64
+			suite.Require().NoError(tx.Raw(fmt.Sprintf("SELECT COUNT(*) FROM %s", table)).Scan(&count).Error)
+			suite.Equal(int64(1), count, "table %s should only expose org A rows", table)
+		}
+
+		// Explicitly asking for another organization's rows returns nothing
+		var notes []string
+		suite.Require().NoError(tx.Raw("SELECT notes FROM inspections WHERE organization_id = ?", suite.orgB).Scan(&notes).Error)
+		suite.Empty(notes)
+
+		var values []string
+		suite.Require().NoError(tx.Raw("SELECT value FROM inspection_data").Scan(&values).Error)
+		suite.Equal([]string{"data of " + suite.orgA}, values)
+		return nil
+	})
+	suite.NoError(err)
+}
+
+func (suite *TenantIsolationTestSuite) TestWritesCannotTouchOtherOrganization() {
+	err := suite.tenantDB.TransactionForTenant(suite.ctx, suite.orgA, func(tx *gorm.DB) error {
+		result := tx.Exec("UPDATE inspections SET notes = 'hijacked' WHERE organization_id = ?", suite.orgB)
+		suite.NoError(result.Error)
+		suite.Equal(int64(0), result.RowsAffected)
+
+		result = tx.Exec("DELETE FROM sites WHERE organization_id = ?", suite.orgB)
+		suite.NoError(result.Error)
+		suite.Equal(int64(0), result.RowsAffected)
+		return nil
+	})
+	suite.NoError(err)
+
+	err = suite.tenantDB.TransactionForTenant(suite.ctx, suite.orgA, func(tx *gorm.DB) error {
+		return tx.Exec("INSERT INTO inspections (organization_id, notes) VALUES (?, 'planted')", suite.orgB).Error
+	})
+	suite.Error(err, "inserting a row for another organization must violate the policy")
+
+	var notes string
+	suite.Require().NoError(suite.db.Raw("SELECT notes FROM inspections WHERE organization_id = ?", suite.orgB).Scan(&notes).Error)
+	suite.Equal("secret of "+suite.orgB, notes)
+}
+
+func (suite *TenantIsolationTestSuite) TestTenantContextDoesNotLeakToPooledConnection() {
+	err := suite.tenantDB.TransactionForTenant(suite.ctx, suite.orgA, func(tx *gorm.DB) error {
+		return nil
+	})
+	suite.Require().NoError(err)
+
+	// Same single connection, outside any transaction
+	var setting string
+	suite.Require().NoError(suite.db.Raw("SELECT COALESCE(current_setting('app.current_organization_id', true), '')").Scan(&setting).Error)
+	suite.Empty(setting)
+
+	var role string
+	suite.Require().NoError(suite.db.Raw("SELECT current_user").Scan(&role).Error)
+	suite.NotEqual(TenantRole, role)
+}
+
+func (suite *TenantIsolationTestSuite) TestTenantRoleWithoutOrganizationSeesNothing() {
+	err := suite.db.Transaction(func(tx *gorm.DB) error {
+		suite.Require().NoError(tx.Exec("SET LOCAL ROLE " + TenantRole).Error)
+
+		var count int64
+		suite.Require().NoError(tx.Raw("SELECT COUNT(*) FROM inspections").Scan(&count).Error)
+		suite.Equal(int64(0), count)
+		return nil
+	})
+	suite.NoError(err)
+}
+
+func TestTenantIsolationTestSuite(t *testing.T) {
+	suite.Run(t, new(TenantIsolationTestSuite))
+}